@@ -0,0 +1,253 @@
+// Command loadgen drives mixed create/redirect traffic against a running
+// short-link server and reports throughput and latency percentiles.
+//
+// Usage:
+//
+//	loadgen [flags]
+//
+// It first creates -links links, then runs -concurrency workers for
+// -duration. Each request creates a new link with probability -create-ratio
+// and otherwise follows an existing one, picked with a Zipf distribution
+// (-skew) so a few links get most of the traffic, as in production. Cache hit
+// rates come from the server's /metrics, scraped before and after the run.
+//
+// The server's rate limits apply; raise them or send an API key with a
+// higher limit (-api-key). Redirects count as bot visits.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+)
+
+// userAgent identifies loadgen; the "bot/" token keeps its redirects out
+// of human click counts
+const userAgent = "loadgen-bot/1.0"
+
+// options are the command-line settings
+type options struct {
+	addr        string
+	apiKey      string
+	duration    time.Duration
+	concurrency int
+	createRatio float64
+	links       int
+	skew        float64
+	timeout     time.Duration
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.addr, "addr", "http://localhost:8080", "server address")
+	flag.StringVar(&opts.apiKey, "api-key", os.Getenv("LOADGEN_API_KEY"), "API key sent as X-API-Key")
+	flag.DurationVar(&opts.duration, "duration", 30*time.Second, "how long to generate traffic")
+	flag.IntVar(&opts.concurrency, "concurrency", 16, "concurrent workers")
+	flag.Float64Var(&opts.createRatio, "create-ratio", 0.1, "share of requests that create a link (0-1)")
+	flag.IntVar(&opts.links, "links", 1000, "links created before the run, followed by redirects")
+	flag.Float64Var(&opts.skew, "skew", 1.1, "Zipf exponent of redirect popularity (> 1; higher is more skewed)")
+	flag.DurationVar(&opts.timeout, "timeout", 10*time.Second, "per-request timeout")
+	flag.Parse()
+
+	if err := opts.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, opts, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// validate checks the flags
+func (o options) validate() error {
+	switch {
+	case o.duration <= 0:
+		return fmt.Errorf("-duration must be positive")
+	case o.concurrency < 1:
+		return fmt.Errorf("-concurrency must be at least 1")
+	case o.createRatio < 0 || o.createRatio > 1:
+		return fmt.Errorf("-create-ratio must be between 0 and 1")
+	case o.links < 1:
+		return fmt.Errorf("-links must be at least 1")
+	case o.skew <= 1:
+		return fmt.Errorf("-skew must be greater than 1")
+	}
+	return nil
+}
+
+// run seeds the links, generates traffic and prints the report
+func run(ctx context.Context, opts options, out io.Writer) error {
+	g := &generator{
+		addr:   strings.TrimRight(opts.addr, "/"),
+		apiKey: opts.apiKey,
+		http: &http.Client{
+			Timeout: opts.timeout,
+			// Redirects are measured, not followed
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+			Transport: &http.Transport{MaxIdleConnsPerHost: opts.concurrency},
+		},
+	}
+
+	fmt.Fprintf(out, "Creating %d links...\n", opts.links)
+	codes := make([]string, 0, opts.links)
+	for i := 0; i < opts.links; i++ {
+		code, err := g.create(ctx)
+		if err != nil {
+			return fmt.Errorf("seeding links: %w", err)
+		}
+		codes = append(codes, code)
+	}
+
+	before, metricsErr := g.scrapeMetrics(ctx)
+	if metricsErr != nil {
+		fmt.Fprintf(out, "Cache hit rates unavailable: %v\n", metricsErr)
+	}
+
+	fmt.Fprintf(out, "Running %d workers for %s (%.0f%% creates)...\n", opts.concurrency, opts.duration, opts.createRatio*100)
+	runCtx, cancel := context.WithTimeout(ctx, opts.duration)
+	defer cancel()
+
+	creates, redirects := newRecorder(), newRecorder()
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < opts.concurrency; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			zipf := rand.NewZipf(rng, opts.skew, 1, uint64(len(codes)-1))
+			for runCtx.Err() == nil {
+				if rng.Float64() < opts.createRatio {
+					began := time.Now()
+					_, err := g.create(runCtx)
+					creates.record(runCtx, time.Since(began), err)
+				} else {
+					code := codes[zipf.Uint64()]
+					began := time.Now()
+					err := g.redirect(runCtx, code)
+					redirects.record(runCtx, time.Since(began), err)
+				}
+			}
+		}(time.Now().UnixNano() + int64(w))
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	fmt.Fprintln(out)
+	printReport(out, elapsed, map[string]*recorder{"create": creates, "redirect": redirects})
+
+	if metricsErr == nil {
+		after, err := g.scrapeMetrics(ctx)
+		if err != nil {
+			fmt.Fprintf(out, "Cache hit rates unavailable: %v\n", err)
+			return nil
+		}
+		printCacheReport(out, before, after)
+	}
+	return nil
+}
+
+// generator sends requests to the server
+type generator struct {
+	addr   string
+	apiKey string
+	http   *http.Client
+	seq    sync.Mutex
+	next   int
+}
+
+// create shortens a unique URL and returns its short code
+func (g *generator) create(ctx context.Context) (string, error) {
+	g.seq.Lock()
+	g.next++
+	n := g.next
+	g.seq.Unlock()
+
+	body, _ := json.Marshal(map[string]string{
+		"url": fmt.Sprintf("https://example.com/loadgen/%d/%d", time.Now().UnixNano(), n),
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.addr+"/api/v1/shorten", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := g.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("create: HTTP %d", resp.StatusCode)
+	}
+
+	var created struct {
+		Data struct {
+			ShortCode string `json:"short_code"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("create: %w", err)
+	}
+	return created.Data.ShortCode, nil
+}
+
+// redirect requests a short code and expects a redirect
+func (g *generator) redirect(ctx context.Context, code string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.addr+"/"+code, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := g.do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("redirect: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// scrapeMetrics reads the server's /metrics
+func (g *generator) scrapeMetrics(ctx context.Context) (map[string]float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.addr+"/metrics", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := g.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("/metrics: HTTP %d", resp.StatusCode)
+	}
+	return parseMetrics(resp.Body)
+}
+
+// do sends a request with loadgen's headers
+func (g *generator) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("User-Agent", userAgent)
+	if g.apiKey != "" {
+		req.Header.Set("X-API-Key", g.apiKey)
+	}
+	return g.http.Do(req)
+}
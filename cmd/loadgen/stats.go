@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recorder collects the latencies and errors of one kind of request
+type recorder struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+	errors    map[string]int
+}
+
+// newRecorder creates an empty recorder
+func newRecorder() *recorder {
+	return &recorder{errors: make(map[string]int)}
+}
+
+// record adds one request; requests cut off by the end of the run (ctx
+// done) are not counted
+func (r *recorder) record(ctx context.Context, latency time.Duration, err error) {
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.errors[err.Error()]++
+		return
+	}
+	r.latencies = append(r.latencies, latency)
+}
+
+// percentile returns the p-th percentile (0-100) of sorted latencies,
+// nearest-rank
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted)) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// printReport prints throughput and latency percentiles per request kind
+func printReport(out io.Writer, elapsed time.Duration, recorders map[string]*recorder) {
+	names := make([]string, 0, len(recorders))
+	for name := range recorders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(out, "%-10s %8s %8s %9s %9s %9s %9s\n", "", "ok", "errors", "req/s", "p50", "p95", "p99")
+	for _, name := range names {
+		r := recorders[name]
+		r.mu.Lock()
+		sorted := append([]time.Duration(nil), r.latencies...)
+		failed := 0
+		for _, n := range r.errors {
+			failed += n
+		}
+		r.mu.Unlock()
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+		fmt.Fprintf(out, "%-10s %8d %8d %9.1f %9s %9s %9s\n", name, len(sorted), failed,
+			float64(len(sorted))/elapsed.Seconds(),
+			percentile(sorted, 50).Round(time.Microsecond),
+			percentile(sorted, 95).Round(time.Microsecond),
+			percentile(sorted, 99).Round(time.Microsecond))
+		for msg, n := range r.errors {
+			fmt.Fprintf(out, "  %d × %s\n", n, msg)
+		}
+	}
+}
+
+// parseMetrics reads unlabeled samples from the Prometheus text format
+func parseMetrics(r io.Reader) (map[string]float64, error) {
+	metrics := make(map[string]float64)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			continue
+		}
+		metrics[fields[0]] = value
+	}
+	return metrics, scanner.Err()
+}
+
+// printCacheReport prints the cache hit rates between two /metrics scrapes
+// They cover every lookup on the server in that time, not only loadgen's.
+func printCacheReport(out io.Writer, before, after map[string]float64) {
+	delta := func(name string) float64 { return after[name] - before[name] }
+	local := delta("short_link_cache_local_hits_total")
+	redis := delta("short_link_cache_redis_hits_total")
+	misses := delta("short_link_cache_misses_total")
+	total := local + redis + misses
+	if total == 0 {
+		fmt.Fprintln(out, "\nNo cache lookups recorded")
+		return
+	}
+	fmt.Fprintf(out, "\nCache lookups: %.0f (local %.1f%%, redis %.1f%%, miss %.1f%%)\n",
+		total, local/total*100, redis/total*100, misses/total*100)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPercentile tests nearest-rank percentiles
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+	assert.Equal(t, 50*time.Millisecond, percentile(sorted, 50))
+	assert.Equal(t, 95*time.Millisecond, percentile(sorted, 95))
+	assert.Equal(t, 99*time.Millisecond, percentile(sorted, 99))
+	assert.Equal(t, time.Duration(0), percentile(nil, 50))
+	assert.Equal(t, 7*time.Millisecond, percentile([]time.Duration{7 * time.Millisecond}, 99))
+}
+
+// TestCacheReport tests hit rates from /metrics scrapes
+func TestCacheReport(t *testing.T) {
+	before, err := parseMetrics(strings.NewReader("# HELP x\nshort_link_cache_local_hits_total 10\nshort_link_cache_misses_total 5\n"))
+	assert.NoError(t, err)
+	after, err := parseMetrics(strings.NewReader("short_link_cache_local_hits_total 90\n" +
+		"short_link_cache_redis_hits_total 15\nshort_link_cache_misses_total 10\n"))
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	printCacheReport(&out, before, after)
+	assert.Contains(t, out.String(), "Cache lookups: 100 (local 80.0%, redis 15.0%, miss 5.0%)")
+}
@@ -2,180 +2,57 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"log"
-	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/Monthlyaway/short-link/config"
-	"github.com/Monthlyaway/short-link/internal/cache"
-	"github.com/Monthlyaway/short-link/internal/filter"
-	"github.com/Monthlyaway/short-link/internal/handler"
-	"github.com/Monthlyaway/short-link/internal/middleware"
-	"github.com/Monthlyaway/short-link/internal/repository"
-	"github.com/Monthlyaway/short-link/internal/service"
-	"github.com/Monthlyaway/short-link/internal/utils"
-	"github.com/gin-gonic/gin"
+	"github.com/Monthlyaway/short-link/internal/app"
 )
 
+// configPath is the configuration file loaded at startup and on reload
+const configPath = "config/config.yaml"
+
 func main() {
 	// Load configuration
-	cfg, err := config.Load("config/config.yaml")
+	cfg, err := config.Load(configPath)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	// Initialize Snowflake ID generator
-	if err := utils.InitSnowflake(cfg.Snowflake.DatacenterID, cfg.Snowflake.WorkerID); err != nil {
-		log.Fatalf("Failed to initialize Snowflake: %v", err)
-	}
-
-	// Initialize MySQL repository
-	repo, err := repository.NewURLRepository(
-		cfg.MySQL.DSN(),
-		cfg.MySQL.MaxIdleConns,
-		cfg.MySQL.MaxOpenConns,
-	)
-	if err != nil {
-		log.Fatalf("Failed to initialize repository: %v", err)
-	}
-	defer repo.Close()
-
-	// Initialize Redis cache
-	redisCache, err := cache.NewRedisCache(
-		cfg.Redis.Addr(),
-		cfg.Redis.Password,
-		cfg.Redis.DB,
-		cfg.Redis.PoolSize,
-	)
-	if err != nil {
-		log.Fatalf("Failed to initialize Redis cache: %v", err)
-	}
-	defer redisCache.Close()
-
-	// Initialize Bloom filter
-	bloomFilter := filter.NewBloomFilter(
-		cfg.BloomFilter.Capacity,
-		cfg.BloomFilter.FalsePositiveRate,
-	)
-
-	// Initialize URL service
-	urlService := service.NewURLService(repo, redisCache, bloomFilter)
-
-	// Load all short codes into bloom filter
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-	if err := urlService.InitBloomFilter(ctx); err != nil {
-		log.Printf("Warning: Failed to initialize bloom filter: %v", err)
-	}
-
-	// Set Gin mode
-	gin.SetMode(cfg.Server.Mode)
-
-	// Initialize Gin router
-	router := gin.Default()
-
-	// Build base URL
-	baseURL := fmt.Sprintf("http://localhost:%d", cfg.Server.Port)
-
-	// Initialize handler
-	urlHandler := handler.NewURLHandler(urlService, baseURL)
-
-	// ========================================================================
-	// MIDDLEWARE SETUP - Rate Limiting
-	// ========================================================================
-	// This demonstrates how to apply middleware in Gin
-	if cfg.RateLimit.Enabled {
-		log.Println("Rate limiting enabled with strategy:", cfg.RateLimit.Strategy)
-
-		// Convert strategy string to enum
-		var strategy middleware.RateLimitStrategy
-		switch cfg.RateLimit.Strategy {
-		case "fixed_window":
-			strategy = middleware.FixedWindow
-		case "sliding_window":
-			strategy = middleware.SlidingWindow
-		case "token_bucket":
-			strategy = middleware.TokenBucket
-		default:
-			strategy = middleware.SlidingWindow
+	// "migrate" subcommand applies schema migrations and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
 		}
-
-		// Global rate limiter (applies to all routes)
-		globalLimiter := middleware.NewRateLimiter(redisCache.GetClient(), &middleware.RateLimitConfig{
-			Strategy: strategy,
-			Limit:    cfg.RateLimit.Global.Limit,
-			Window:   time.Duration(cfg.RateLimit.Global.Window) * time.Second,
-			SkipFunc: middleware.SkipHealthCheck, // Don't rate limit health checks
-		})
-
-		// Apply global rate limiter to all routes
-		router.Use(globalLimiter.Middleware())
+		return
 	}
 
-	// Register routes
-	router.GET("/health", urlHandler.HealthCheck)
-
-	// ========================================================================
-	// ENDPOINT-SPECIFIC RATE LIMITING EXAMPLE
-	// ========================================================================
-	// You can also apply different rate limits to specific endpoints
-	if cfg.RateLimit.Enabled {
-		// Find rate limit config for redirect endpoint
-		for _, endpoint := range cfg.RateLimit.Endpoints {
-			if endpoint.Path == "/:short_code" {
-				redirectLimiter := middleware.NewRateLimiter(redisCache.GetClient(), &middleware.RateLimitConfig{
-					Strategy: middleware.SlidingWindow,
-					Limit:    endpoint.Limit,
-					Window:   time.Duration(endpoint.Window) * time.Second,
-				})
-				router.GET("/:short_code", redirectLimiter.Middleware(), urlHandler.RedirectToOriginalURL)
-				goto apiRoutes // Skip the default route registration
-			}
+	// "rebalance" subcommand moves links between MySQL shards and exits
+	if len(os.Args) > 1 && os.Args[1] == "rebalance" {
+		if err := runRebalance(cfg, os.Args[2:]); err != nil {
+			log.Fatalf("Rebalance failed: %v", err)
 		}
+		return
 	}
-	router.GET("/:short_code", urlHandler.RedirectToOriginalURL)
-
-apiRoutes:
-	api := router.Group("/api/v1")
-	{
-		// Apply endpoint-specific rate limit to /shorten if configured
-		if cfg.RateLimit.Enabled {
-			for _, endpoint := range cfg.RateLimit.Endpoints {
-				if endpoint.Path == "/api/v1/shorten" {
-					shortenLimiter := middleware.NewRateLimiter(redisCache.GetClient(), &middleware.RateLimitConfig{
-						Strategy: middleware.SlidingWindow,
-						Limit:    endpoint.Limit,
-						Window:   time.Duration(endpoint.Window) * time.Second,
-					})
-					api.POST("/shorten", shortenLimiter.Middleware(), urlHandler.CreateShortURL)
-					goto infoRoute
-				}
-			}
-		}
-		api.POST("/shorten", urlHandler.CreateShortURL)
 
-	infoRoute:
-		api.GET("/info/:short_code", urlHandler.GetURLInfo)
+	// Wire everything up (see internal/app)
+	server, err := app.New(cfg, configPath)
+	if err != nil {
+		log.Fatalf("Failed to initialize: %v", err)
 	}
-
-	// Create HTTP server
-	srv := &http.Server{
-		Addr:           fmt.Sprintf(":%d", cfg.Server.Port),
-		Handler:        router,
-		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
-		MaxHeaderBytes: 1 << 20,
+	if err := server.Run(); err != nil {
+		log.Fatalf("Failed to start: %v", err)
 	}
 
-	// Start server in goroutine
+	// Reload rate limits and reserved codes on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
 	go func() {
-		log.Printf("Server starting on port %d...", cfg.Server.Port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+		for range hup {
+			server.Reload()
 		}
 	}()
 
@@ -185,11 +62,10 @@ apiRoutes:
 	<-quit
 	log.Println("Shutting down server...")
 
-	// Graceful shutdown with 5 second timeout
-	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	// Give in-flight requests server.shutdown_timeout to finish
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout)*time.Second)
 	defer cancel()
-
-	if err := srv.Shutdown(ctx); err != nil {
+	if err := server.Shutdown(ctx); err != nil {
 		log.Printf("Server forced to shutdown: %v", err)
 	}
 
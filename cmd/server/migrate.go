@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Monthlyaway/short-link/config"
+	"github.com/Monthlyaway/short-link/internal/app"
+	"github.com/Monthlyaway/short-link/internal/repository"
+)
+
+// migratePool is the connection pool used by the command line tools
+var migratePool = repository.PoolConfig{MaxIdleConns: 1, MaxOpenConns: 1}
+
+// runMigrate handles the "migrate" subcommand
+// Usage: shortlink migrate [up|down|status|version|...] [args]
+// With mysql.shards set, it runs against the primary and then every shard.
+func runMigrate(cfg *config.Config, args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+		args = args[1:]
+	}
+
+	// Migrations always run against the primary
+	repo, err := repository.NewURLRepository(cfg.MySQL.DSN(), nil, migratePool)
+	if err != nil {
+		return fmt.Errorf("failed to initialize repository: %w", err)
+	}
+	defer repo.Close()
+
+	if len(cfg.MySQL.Shards) == 0 {
+		return repo.Migrate(context.Background(), command, args...)
+	}
+
+	sharded, err := app.OpenShards(&cfg.MySQL, repo, migratePool)
+	if err != nil {
+		return err
+	}
+	defer sharded.Close()
+
+	fmt.Println("== primary")
+	if err := repo.Migrate(context.Background(), command, args...); err != nil {
+		return err
+	}
+	for _, s := range sharded.Shards() {
+		if s.Repo == repo {
+			continue
+		}
+		fmt.Printf("== shard %s\n", s.Name)
+		if err := s.Repo.Migrate(context.Background(), command, args...); err != nil {
+			return fmt.Errorf("shard %s: %w", s.Name, err)
+		}
+	}
+	return nil
+}
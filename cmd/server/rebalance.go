@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Monthlyaway/short-link/config"
+	"github.com/Monthlyaway/short-link/internal/app"
+	"github.com/Monthlyaway/short-link/internal/repository"
+)
+
+// runRebalance handles the "rebalance" subcommand, which moves links to
+// the shard mysql.shards places them on
+// Usage: shortlink rebalance [-dry-run]
+func runRebalance(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("rebalance", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "count the links to move without moving them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(cfg.MySQL.Shards) == 0 {
+		return errors.New("mysql.shards is not configured")
+	}
+
+	repo, err := repository.NewURLRepository(cfg.MySQL.DSN(), nil, migratePool)
+	if err != nil {
+		return fmt.Errorf("failed to initialize repository: %w", err)
+	}
+	defer repo.Close()
+	sharded, err := app.OpenShards(&cfg.MySQL, repo, migratePool)
+	if err != nil {
+		return err
+	}
+	defer sharded.Close()
+
+	// Stop between links on interrupt; the rebalance can be run again
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	stats, err := sharded.Rebalance(ctx, *dryRun)
+	verb := "moved"
+	if *dryRun {
+		verb = "to move"
+	}
+	fmt.Printf("Scanned %d links, %d %s\n", stats.Scanned, stats.Moved, verb)
+	return err
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// userAgent identifies shortctl to the server
+// The "bot/" token makes resolves count as bot visits, not human clicks
+const userAgent = "shortctl-bot/1.0"
+
+// Header names understood by the server
+const (
+	apiKeyHeader     = "X-API-Key"
+	adminTokenHeader = "X-Admin-Token"
+)
+
+// client calls the short-link REST API
+type client struct {
+	addr       string
+	apiKey     string
+	adminToken string
+	http       *http.Client
+}
+
+// newClient creates an API client for a server address like http://localhost:8080
+func newClient(addr, apiKey, adminToken string) *client {
+	return &client{
+		addr:       strings.TrimRight(addr, "/"),
+		apiKey:     apiKey,
+		adminToken: adminToken,
+		http: &http.Client{
+			Timeout: 30 * time.Second,
+			// Resolve reads the redirect target instead of following it
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// apiResponse mirrors handler.Response with the payload left raw
+type apiResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// newRequest builds a request with the API key and admin token headers
+func (c *client) newRequest(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	target := c.addr + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", userAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set(apiKeyHeader, c.apiKey)
+	}
+	if c.adminToken != "" {
+		req.Header.Set(adminTokenHeader, c.adminToken)
+	}
+	return req, nil
+}
+
+// call sends a JSON API request and decodes the response data into out
+func (c *client) call(ctx context.Context, method, path string, query url.Values, body, out interface{}) (*apiResponse, error) {
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("unexpected response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, envelope.Message)
+	}
+
+	if out != nil && len(envelope.Data) > 0 {
+		if err := json.Unmarshal(envelope.Data, out); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return &envelope, nil
+}
+
+// resolve returns the redirect target of a short code
+func (c *client) resolve(ctx context.Context, shortCode string) (string, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/"+url.PathEscape(shortCode), nil, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if location := resp.Header.Get("Location"); resp.StatusCode/100 == 3 && location != "" {
+		return location, nil
+	}
+
+	var envelope apiResponse
+	_ = json.NewDecoder(resp.Body).Decode(&envelope)
+	return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, envelope.Message)
+}
+
+// download streams the response body of a GET (e.g. an export) to w
+func (c *client) download(ctx context.Context, path string, query url.Values, w io.Writer) error {
+	req, err := c.newRequest(ctx, http.MethodGet, path, query, nil)
+	if err != nil {
+		return err
+	}
+
+	// Exports can be large; rely on ctx instead of the client timeout
+	streaming := *c.http
+	streaming.Timeout = 0
+	resp, err := streaming.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var envelope apiResponse
+		_ = json.NewDecoder(resp.Body).Decode(&envelope)
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, envelope.Message)
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
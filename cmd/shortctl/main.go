@@ -0,0 +1,415 @@
+// Command shortctl is a command-line client for the short-link REST API.
+//
+// Usage:
+//
+//	shortctl [global flags] <command> [flags] [args]
+//
+// Global flags fall back to environment variables:
+//
+//	-addr         SHORTCTL_ADDR         (default http://localhost:8080)
+//	-api-key      SHORTCTL_API_KEY      sent as X-API-Key
+//	-admin-token  SHORTCTL_ADMIN_TOKEN  sent as X-Admin-Token (delete, restore, export-links)
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// usage is printed for -h and on missing commands
+const usage = `Usage: shortctl [global flags] <command> [flags] [args]
+
+Commands:
+  shorten [-expires TIME] [-domain HOST] <url>        Create a short URL
+  resolve <short_code>                                Print the original URL
+  info <short_code>                                   Show mapping and visit counts
+  delete <short_code>                                 Delete a short URL (admin token)
+  restore <short_code>                                Restore a deleted short URL (admin token)
+  stats [-from TIME] [-to TIME] <short_code>          Show visit breakdowns
+  export [-format csv|ndjson] [-from TIME] [-to TIME] [-o FILE] <short_code>
+                                                      Download visit logs
+  export-links [-format csv|ndjson] [-domain HOST] [-include-deleted] [-o FILE]
+                                                      Download all links (admin token)
+
+TIME is RFC3339 or YYYY-MM-DD; -expires also accepts a duration like 72h.
+
+Global flags:
+`
+
+// shortURL mirrors the create response
+type shortURL struct {
+	ShortCode   string     `json:"short_code"`
+	ShortURL    string     `json:"short_url"`
+	OriginalURL string     `json:"original_url"`
+	ExpiredAt   *time.Time `json:"expired_at,omitempty"`
+}
+
+// urlInfo mirrors the info response
+type urlInfo struct {
+	ShortCode   string     `json:"short_code"`
+	OriginalURL string     `json:"original_url"`
+	VisitCount  uint64     `json:"visit_count"`
+	BotVisits   uint64     `json:"bot_visit_count"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiredAt   *time.Time `json:"expired_at,omitempty"`
+}
+
+// visitStat is one row of a breakdown
+type visitStat struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// visitStats mirrors the stats response
+type visitStats struct {
+	ShortCode  string      `json:"short_code"`
+	VisitCount uint64      `json:"visit_count"`
+	BotVisits  uint64      `json:"bot_visit_count"`
+	Countries  []visitStat `json:"countries"`
+	Devices    []visitStat `json:"devices"`
+	Browsers   []visitStat `json:"browsers"`
+	Referrers  []visitStat `json:"referrers"`
+}
+
+// app holds global options shared by all commands
+type app struct {
+	client  *client
+	jsonOut bool
+	stdout  io.Writer
+}
+
+func main() {
+	global := flag.NewFlagSet("shortctl", flag.ExitOnError)
+	addr := global.String("addr", envOr("SHORTCTL_ADDR", "http://localhost:8080"), "server address")
+	apiKey := global.String("api-key", os.Getenv("SHORTCTL_API_KEY"), "API key")
+	adminToken := global.String("admin-token", os.Getenv("SHORTCTL_ADMIN_TOKEN"), "admin token")
+	jsonOut := global.Bool("json", false, "print responses as JSON")
+	global.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		global.PrintDefaults()
+	}
+	global.Parse(os.Args[1:])
+
+	if global.NArg() == 0 {
+		global.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	a := &app{
+		client:  newClient(*addr, *apiKey, *adminToken),
+		jsonOut: *jsonOut,
+		stdout:  os.Stdout,
+	}
+	if err := a.run(ctx, global.Arg(0), global.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// run dispatches a command
+func (a *app) run(ctx context.Context, command string, args []string) error {
+	switch command {
+	case "shorten":
+		return a.shorten(ctx, args)
+	case "resolve":
+		return a.resolve(ctx, args)
+	case "info":
+		return a.info(ctx, args)
+	case "delete":
+		return a.delete(ctx, args)
+	case "restore":
+		return a.restore(ctx, args)
+	case "stats":
+		return a.stats(ctx, args)
+	case "export":
+		return a.export(ctx, args)
+	case "export-links":
+		return a.exportLinks(ctx, args)
+	default:
+		return fmt.Errorf("unknown command %q (run shortctl -h for usage)", command)
+	}
+}
+
+// shorten creates a short URL
+func (a *app) shorten(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("shorten", flag.ContinueOnError)
+	expires := fs.String("expires", "", "expiration time or duration (e.g. 72h)")
+	domain := fs.String("domain", "", "serving domain (default: server's default domain)")
+	rest, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	body := map[string]interface{}{"url": rest[0]}
+	if *domain != "" {
+		body["domain"] = *domain
+	}
+	if *expires != "" {
+		expiredAt, err := parseExpires(*expires, time.Now())
+		if err != nil {
+			return err
+		}
+		body["expired_at"] = expiredAt
+	}
+
+	var created shortURL
+	if _, err := a.client.call(ctx, http.MethodPost, "/api/v1/shorten", nil, body, &created); err != nil {
+		return err
+	}
+	if a.jsonOut {
+		return a.printJSON(created)
+	}
+	fmt.Fprintln(a.stdout, created.ShortURL)
+	return nil
+}
+
+// resolve prints the original URL of a short code
+func (a *app) resolve(ctx context.Context, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("resolve", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	originalURL, err := a.client.resolve(ctx, rest[0])
+	if err != nil {
+		return err
+	}
+	if a.jsonOut {
+		return a.printJSON(map[string]string{"original_url": originalURL})
+	}
+	fmt.Fprintln(a.stdout, originalURL)
+	return nil
+}
+
+// info prints a short code's mapping
+func (a *app) info(ctx context.Context, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("info", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	var info urlInfo
+	if _, err := a.client.call(ctx, http.MethodGet, "/api/v1/info/"+url.PathEscape(rest[0]), nil, nil, &info); err != nil {
+		return err
+	}
+	if a.jsonOut {
+		return a.printJSON(info)
+	}
+
+	fmt.Fprintf(a.stdout, "Short code:   %s\n", info.ShortCode)
+	fmt.Fprintf(a.stdout, "Original URL: %s\n", info.OriginalURL)
+	fmt.Fprintf(a.stdout, "Visits:       %d (+%d bots)\n", info.VisitCount, info.BotVisits)
+	fmt.Fprintf(a.stdout, "Created:      %s\n", info.CreatedAt.Format(time.RFC3339))
+	if info.ExpiredAt != nil {
+		fmt.Fprintf(a.stdout, "Expires:      %s\n", info.ExpiredAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// delete deletes a short code
+func (a *app) delete(ctx context.Context, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("delete", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.client.call(ctx, http.MethodDelete, "/admin/links/"+url.PathEscape(rest[0]), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	if a.jsonOut {
+		return a.printJSON(map[string]string{"message": resp.Message})
+	}
+	fmt.Fprintln(a.stdout, resp.Message)
+	return nil
+}
+
+// restore restores a deleted short code
+func (a *app) restore(ctx context.Context, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("restore", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	var info urlInfo
+	if _, err := a.client.call(ctx, http.MethodPost, "/admin/links/"+url.PathEscape(rest[0])+"/restore", nil, nil, &info); err != nil {
+		return err
+	}
+	if a.jsonOut {
+		return a.printJSON(info)
+	}
+	fmt.Fprintf(a.stdout, "Restored %s -> %s\n", info.ShortCode, info.OriginalURL)
+	return nil
+}
+
+// stats prints visit breakdowns of a short code
+func (a *app) stats(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	from := fs.String("from", "", "start time (inclusive)")
+	to := fs.String("to", "", "end time (exclusive)")
+	rest, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	var stats visitStats
+	query := rangeQuery(*from, *to)
+	if _, err := a.client.call(ctx, http.MethodGet, "/api/v1/stats/"+url.PathEscape(rest[0]), query, nil, &stats); err != nil {
+		return err
+	}
+	if a.jsonOut {
+		return a.printJSON(stats)
+	}
+
+	fmt.Fprintf(a.stdout, "Visits: %d (+%d bots)\n", stats.VisitCount, stats.BotVisits)
+	printBreakdown(a.stdout, "Countries", stats.Countries)
+	printBreakdown(a.stdout, "Devices", stats.Devices)
+	printBreakdown(a.stdout, "Browsers", stats.Browsers)
+	printBreakdown(a.stdout, "Referrers", stats.Referrers)
+	return nil
+}
+
+// export downloads visit logs to a file or stdout
+func (a *app) export(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "csv", "csv or ndjson")
+	from := fs.String("from", "", "start time (inclusive)")
+	to := fs.String("to", "", "end time (exclusive)")
+	output := fs.String("o", "", "output file (default stdout)")
+	rest, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	query := rangeQuery(*from, *to)
+	query.Set("format", *format)
+	return a.download(ctx, "/api/v1/export/"+url.PathEscape(rest[0]), query, *output)
+}
+
+// exportLinks downloads all URL mappings to a file or stdout
+func (a *app) exportLinks(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export-links", flag.ContinueOnError)
+	format := fs.String("format", "csv", "csv or ndjson")
+	domain := fs.String("domain", "", "only links on this serving domain")
+	includeDeleted := fs.Bool("include-deleted", false, "include soft-deleted links")
+	output := fs.String("o", "", "output file (default stdout)")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("format", *format)
+	if *domain != "" {
+		query.Set("domain", *domain)
+	}
+	if *includeDeleted {
+		query.Set("include_deleted", "true")
+	}
+	return a.download(ctx, "/admin/links/export", query, *output)
+}
+
+// download writes a streamed response to output, or stdout when empty
+func (a *app) download(ctx context.Context, path string, query url.Values, output string) error {
+	w := a.stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return a.client.download(ctx, path, query, w)
+}
+
+// printJSON writes v as indented JSON
+func (a *app) printJSON(v interface{}) error {
+	enc := json.NewEncoder(a.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printBreakdown writes one stats section
+func printBreakdown(w io.Writer, title string, rows []visitStat) {
+	fmt.Fprintf(w, "\n%s:\n", title)
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "  (none)")
+		return
+	}
+	for _, row := range rows {
+		value := row.Value
+		if value == "" {
+			value = "(unknown)"
+		}
+		fmt.Fprintf(w, "  %-40s %d\n", value, row.Count)
+	}
+}
+
+// parseArgs parses subcommand flags and requires exactly n positional args
+// Flags may appear before or after the positional arguments
+func parseArgs(fs *flag.FlagSet, args []string, n int) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, fmt.Errorf("%s: %w", fs.Name(), err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	if len(positional) != n {
+		return nil, fmt.Errorf("%s: expected %d argument(s), got %d", fs.Name(), n, len(positional))
+	}
+	return positional, nil
+}
+
+// parseExpires accepts an absolute time or a duration from now
+func parseExpires(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid -expires %q (use RFC3339, YYYY-MM-DD or a duration)", value)
+}
+
+// rangeQuery builds from/to query parameters
+func rangeQuery(from, to string) url.Values {
+	query := url.Values{}
+	if from != "" {
+		query.Set("from", from)
+	}
+	if to != "" {
+		query.Set("to", to)
+	}
+	return query
+}
+
+// envOr returns an environment variable or a default
+func envOr(key, fallback string) string {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		return value
+	}
+	return fallback
+}
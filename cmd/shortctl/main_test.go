@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupTestApp runs an app against a fake server
+func setupTestApp(handler http.HandlerFunc) (*app, *bytes.Buffer) {
+	server := httptest.NewServer(handler)
+	out := &bytes.Buffer{}
+	a := &app{
+		client: newClient(server.URL+"/", "key-1", "admin-1"),
+		stdout: out,
+	}
+	return a, out
+}
+
+// TestShorten tests the request body, headers and output
+func TestShorten(t *testing.T) {
+	a, out := setupTestApp(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v1/shorten", r.URL.Path)
+		assert.Equal(t, "key-1", r.Header.Get(apiKeyHeader))
+
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "https://example.com", body["url"])
+		assert.NotEmpty(t, body["expired_at"])
+
+		w.Write([]byte(`{"code":200,"data":{"short_code":"abc123","short_url":"http://s.test/abc123"}}`))
+	})
+
+	err := a.run(context.Background(), "shorten", []string{"https://example.com", "-expires", "24h"})
+	assert.NoError(t, err)
+	assert.Equal(t, "http://s.test/abc123\n", out.String())
+}
+
+// TestResolve tests that the redirect target is printed, not followed
+func TestResolve(t *testing.T) {
+	a, out := setupTestApp(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/abc123", r.URL.Path)
+		http.Redirect(w, r, "https://example.com/target", http.StatusFound)
+	})
+
+	assert.NoError(t, a.run(context.Background(), "resolve", []string{"abc123"}))
+	assert.Equal(t, "https://example.com/target\n", out.String())
+}
+
+// TestDelete tests that the admin token is sent
+func TestDelete(t *testing.T) {
+	a, out := setupTestApp(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		assert.Equal(t, "/admin/links/abc123", r.URL.Path)
+		assert.Equal(t, "admin-1", r.Header.Get(adminTokenHeader))
+		w.Write([]byte(`{"code":200,"message":"Short URL deleted"}`))
+	})
+
+	assert.NoError(t, a.run(context.Background(), "delete", []string{"abc123"}))
+	assert.Equal(t, "Short URL deleted\n", out.String())
+}
+
+// TestRestore tests the restore endpoint and output
+func TestRestore(t *testing.T) {
+	a, out := setupTestApp(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/admin/links/abc123/restore", r.URL.Path)
+		assert.Equal(t, "admin-1", r.Header.Get(adminTokenHeader))
+		w.Write([]byte(`{"code":200,"data":{"short_code":"abc123","original_url":"https://example.com"}}`))
+	})
+
+	assert.NoError(t, a.run(context.Background(), "restore", []string{"abc123"}))
+	assert.Equal(t, "Restored abc123 -> https://example.com\n", out.String())
+}
+
+// TestAPIError tests that server error messages are surfaced
+func TestAPIError(t *testing.T) {
+	a, _ := setupTestApp(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"code":404,"message":"Short URL not found"}`))
+	})
+
+	err := a.run(context.Background(), "info", []string{"missing"})
+	assert.EqualError(t, err, "HTTP 404: Short URL not found")
+}
+
+// TestExport tests that the export is streamed with its query
+func TestExport(t *testing.T) {
+	a, out := setupTestApp(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/export/abc123", r.URL.Path)
+		assert.Equal(t, "ndjson", r.URL.Query().Get("format"))
+		assert.Equal(t, "2024-01-01", r.URL.Query().Get("from"))
+		w.Write([]byte("{\"id\":1}\n{\"id\":2}\n"))
+	})
+
+	err := a.run(context.Background(), "export", []string{"-format", "ndjson", "-from", "2024-01-01", "abc123"})
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"id\":1}\n{\"id\":2}\n", out.String())
+}
+
+// TestExportLinks tests the link export query and admin token
+func TestExportLinks(t *testing.T) {
+	a, out := setupTestApp(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/admin/links/export", r.URL.Path)
+		assert.Equal(t, "admin-1", r.Header.Get(adminTokenHeader))
+		assert.Equal(t, "csv", r.URL.Query().Get("format"))
+		assert.Equal(t, "promo.example.com", r.URL.Query().Get("domain"))
+		assert.Equal(t, "true", r.URL.Query().Get("include_deleted"))
+		w.Write([]byte("short_code,original_url\nabc123,https://example.com\n"))
+	})
+
+	err := a.run(context.Background(), "export-links", []string{"-domain", "promo.example.com", "-include-deleted"})
+	assert.NoError(t, err)
+	assert.Equal(t, "short_code,original_url\nabc123,https://example.com\n", out.String())
+}
+
+// TestParseArgs tests flags before and after positional arguments
+func TestParseArgs(t *testing.T) {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	from := fs.String("from", "", "")
+	rest, err := parseArgs(fs, []string{"abc123", "-from", "2024-01-01"}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"abc123"}, rest)
+	assert.Equal(t, "2024-01-01", *from)
+
+	_, err = parseArgs(flag.NewFlagSet("info", flag.ContinueOnError), nil, 1)
+	assert.Error(t, err)
+}
+
+// TestParseExpires tests durations, timestamps and dates
+func TestParseExpires(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	got, err := parseExpires("72h", now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(72*time.Hour), got)
+
+	got, err = parseExpires("2024-02-01", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), got)
+
+	_, err = parseExpires("soon", now)
+	assert.Error(t, err)
+}
@@ -1,26 +1,122 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the application configuration
 type Config struct {
-	Server      ServerConfig      `yaml:"server"`
-	MySQL       MySQLConfig       `yaml:"mysql"`
-	Redis       RedisConfig       `yaml:"redis"`
-	BloomFilter BloomFilterConfig `yaml:"bloom_filter"`
-	Snowflake   SnowflakeConfig   `yaml:"snowflake"`
-	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Server         ServerConfig         `yaml:"server"`
+	MySQL          MySQLConfig          `yaml:"mysql"`
+	Redis          RedisConfig          `yaml:"redis"`
+	Cache          CacheConfig          `yaml:"cache"`
+	BloomFilter    BloomFilterConfig    `yaml:"bloom_filter"`
+	Snowflake      SnowflakeConfig      `yaml:"snowflake"`
+	IDGenerator    IDGeneratorConfig    `yaml:"id_generator"`
+	RateLimit      RateLimitConfig      `yaml:"rate_limit"`
+	Admin          AdminConfig          `yaml:"admin"`
+	Auth           AuthConfig           `yaml:"auth"`
+	VisitLog       VisitLogConfig       `yaml:"visit_log"`
+	Privacy        PrivacyConfig        `yaml:"privacy"`
+	Captcha        CaptchaConfig        `yaml:"captcha"`
+	SignedLinks    SignedLinksConfig    `yaml:"signed_links"`
+	Events         EventsConfig         `yaml:"events"`
+	GRPC           GRPCConfig           `yaml:"grpc"`
+	Tracing        TracingConfig        `yaml:"tracing"`
+	Debug          DebugConfig          `yaml:"debug"`
+	DeletedLinks   DeletedLinkConfig    `yaml:"deleted_links"`
+	ShortCodes     ShortCodeConfig      `yaml:"short_codes"`
+	Import         ImportConfig         `yaml:"import"`
+	Destinations   DestinationConfig    `yaml:"destinations"`
+	RedirectDelay  RedirectDelayConfig  `yaml:"redirect_delay"`
+	Timeouts       TimeoutConfig        `yaml:"timeouts"`
+	DegradedMode   DegradedConfig       `yaml:"degraded_mode"`
+	WriteBehind    WriteBehindConfig    `yaml:"write_behind"`
+	Outbox         OutboxConfig         `yaml:"outbox"`
+	Abuse          AbuseConfig          `yaml:"abuse"`
+	ScanProtection ScanProtectionConfig `yaml:"scan_protection"`
+	Leaderboard    LeaderboardConfig    `yaml:"leaderboard"`
+	Reports        ReportsConfig        `yaml:"reports"`
+	LinkHealth     LinkHealthConfig     `yaml:"link_health"`
+	LinkTitles     LinkTitleConfig      `yaml:"link_titles"`
+	Idempotency    IdempotencyConfig    `yaml:"idempotency"`
+	Concurrency    ConcurrencyConfig    `yaml:"concurrency"`
+	AccessLog      AccessLogConfig      `yaml:"access_log"`
 }
 
 // ServerConfig represents server configuration
 type ServerConfig struct {
-	Port int    `yaml:"port"`
-	Mode string `yaml:"mode"`
+	Port            int      `yaml:"port"`
+	Mode            string   `yaml:"mode"`
+	TrustedProxies  []string `yaml:"trusted_proxies"`   // IPs/CIDRs allowed to set client IP headers
+	RemoteIPHeaders []string `yaml:"remote_ip_headers"` // Headers checked for the client IP, in order
+
+	// BaseURL is the public URL short links are built from, e.g.
+	// https://s.example.com; it becomes the default domain
+	BaseURL string `yaml:"base_url"`
+	// UseForwardedHeaders derives the public URL from X-Forwarded-Proto/Host
+	// (trusted proxies only) when no base URL or domains are configured
+	UseForwardedHeaders bool `yaml:"use_forwarded_headers"`
+
+	// Domains short links are served on; the first is the default for new links
+	Domains []DomainConfig `yaml:"domains"`
+
+	TLS TLSConfig `yaml:"tls"`
+	H2C bool      `yaml:"h2c"` // Cleartext HTTP/2 (behind an HTTP/2 proxy); ignored with TLS
+
+	// MaxBodyBytes limits request bodies (except imports, see import.max_bytes)
+	MaxBodyBytes int64 `yaml:"max_body_bytes"`
+
+	// Connection limits of the HTTP listener, in seconds; 0 means no limit
+	// (except read_header_timeout, which is required)
+	ReadTimeout       int `yaml:"read_timeout"`        // Reading a whole request, body included
+	ReadHeaderTimeout int `yaml:"read_header_timeout"` // Reading request headers
+	WriteTimeout      int `yaml:"write_timeout"`       // From the end of the headers to the end of the response
+	IdleTimeout       int `yaml:"idle_timeout"`        // Keep-alive connections waiting for the next request
+	MaxHeaderBytes    int `yaml:"max_header_bytes"`
+	// ShutdownTimeout is how long in-flight requests get to finish on shutdown
+	ShutdownTimeout int `yaml:"shutdown_timeout"`
+
+	// RedirectPrefix serves redirects under a path, e.g. /r/{short_code},
+	// leaving the root free for other pages; empty serves them at the root
+	RedirectPrefix string `yaml:"redirect_prefix"`
+	// LegacyRedirects also serves /{short_code} while a prefix is set, so
+	// short URLs handed out before it keep working
+	LegacyRedirects bool `yaml:"legacy_redirects"`
+
+	// RobotsTxt is served at /robots.txt
+	RobotsTxt string `yaml:"robots_txt"`
+	// Favicon is an icon file served at /favicon.ico; empty answers 204
+	Favicon string `yaml:"favicon"`
+}
+
+// TLSConfig represents HTTPS termination in the server
+type TLSConfig struct {
+	Enabled      bool           `yaml:"enabled"`
+	CertFile     string         `yaml:"cert_file"`
+	KeyFile      string         `yaml:"key_file"`
+	Autocert     AutocertConfig `yaml:"autocert"`
+	RedirectHTTP bool           `yaml:"redirect_http"` // Serve HTTP -> HTTPS redirects on http_port
+	HTTPPort     int            `yaml:"http_port"`
+}
+
+// AutocertConfig represents automatic Let's Encrypt certificates
+type AutocertConfig struct {
+	Enabled  bool     `yaml:"enabled"`
+	Domains  []string `yaml:"domains"`   // Hosts certificates may be issued for
+	CacheDir string   `yaml:"cache_dir"` // Where certificates are stored
+	Email    string   `yaml:"email"`     // Contact for expiry notices
+}
+
+// DomainConfig represents a serving domain
+type DomainConfig struct {
+	Host   string `yaml:"host"`   // e.g. s.example.com (include the port if not 80/443)
+	Scheme string `yaml:"scheme"` // http or https (default https)
 }
 
 // MySQLConfig represents MySQL configuration
@@ -32,6 +128,50 @@ type MySQLConfig struct {
 	Database     string `yaml:"database"`
 	MaxIdleConns int    `yaml:"max_idle_conns"`
 	MaxOpenConns int    `yaml:"max_open_conns"`
+
+	// Connections are closed after ConnMaxLifetime seconds (keep it below
+	// the server's wait_timeout) or ConnMaxIdleTime seconds unused
+	ConnMaxLifetime int `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime int `yaml:"conn_max_idle_time"`
+	PingInterval    int `yaml:"ping_interval"` // Seconds between keepalive pings; 0 disables them
+
+	// MigrateOnStartup runs pending migrations when the server starts
+	// Prefer running "migrate up" as a separate deploy step in production
+	MigrateOnStartup bool `yaml:"migrate_on_startup"`
+
+	// Replicas receive read queries; they share credentials and database
+	// name with the primary
+	Replicas []MySQLReplicaConfig `yaml:"replicas"`
+
+	// Shards split the links over several databases by short code (see
+	// repository.ShardedRepository); empty keeps every link here. This
+	// database always holds organizations, campaigns, aliases and the
+	// other tables that aren't per link.
+	Shards []MySQLShardConfig `yaml:"shards"`
+
+	// Rebalancing looks a link up on every shard when it isn't on the one
+	// the ring places it on; set it from changing the shards until the
+	// rebalance subcommand has moved the links
+	Rebalancing bool `yaml:"rebalancing"`
+}
+
+// MySQLReplicaConfig represents a MySQL read replica
+type MySQLReplicaConfig struct {
+	Host string `yaml:"host"`
+	Port int    `yaml:"port"`
+}
+
+// MySQLShardConfig represents a database holding a share of the links
+// Host, port and database default to the primary's; credentials are shared
+// with it. A shard without them is the primary database itself.
+type MySQLShardConfig struct {
+	Name     string `yaml:"name"` // Placement on the ring; renaming a shard moves its links
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Database string `yaml:"database"`
+
+	// Draining shards get no links; rebalance moves theirs to the others
+	Draining bool `yaml:"draining"`
 }
 
 // RedisConfig represents Redis configuration
@@ -41,45 +181,512 @@ type RedisConfig struct {
 	Password string `yaml:"password"`
 	DB       int    `yaml:"db"`
 	PoolSize int    `yaml:"pool_size"`
+
+	// SlowCommandThreshold logs commands taking at least this many
+	// milliseconds; 0 disables the log
+	SlowCommandThreshold int `yaml:"slow_command_threshold"`
+}
+
+// CacheConfig represents caching configuration
+type CacheConfig struct {
+	Backend   string           `yaml:"backend"`    // Where link entries are cached: redis or memcached
+	TTL       int              `yaml:"ttl"`        // Shared cache TTL in seconds
+	TTLJitter int              `yaml:"ttl_jitter"` // Random extra TTL in seconds, [0, ttl_jitter)
+	Local     LocalCacheConfig `yaml:"local"`
+	Disk      DiskCacheConfig  `yaml:"disk"`
+	Warmup    WarmupConfig     `yaml:"warmup"`
+	Memcached MemcachedConfig  `yaml:"memcached"`
+}
+
+// DiskCacheConfig represents the on-disk tier serving hot links while the
+// shared cache is unavailable
+type DiskCacheConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	Path         string `yaml:"path"`          // Journal the entries are written to; one per instance
+	MaxEntries   int    `yaml:"max_entries"`   // Maximum number of short codes kept
+	MaxBytes     int    `yaml:"max_bytes"`     // Maximum size of the stored entries
+	SyncInterval int    `yaml:"sync_interval"` // Seconds between flushes of the journal
+}
+
+// MemcachedConfig represents the Memcached servers used by the memcached
+// cache backend
+type MemcachedConfig struct {
+	Servers      []string `yaml:"servers"`        // host:port of each server; keys are spread across them
+	Timeout      int      `yaml:"timeout"`        // Per-command timeout in milliseconds
+	MaxIdleConns int      `yaml:"max_idle_conns"` // Idle connections kept open per server
+}
+
+// WarmupConfig represents preloading the most visited links at startup
+type WarmupConfig struct {
+	Enabled     bool `yaml:"enabled"`
+	Links       int  `yaml:"links"`       // How many of the most visited links to load
+	Concurrency int  `yaml:"concurrency"` // Parallel cache writes
+}
+
+// LocalCacheConfig represents the in-process LRU cache tier
+type LocalCacheConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Size    int  `yaml:"size"` // Maximum number of short codes
+	TTL     int  `yaml:"ttl"`  // TTL in seconds
 }
 
 // BloomFilterConfig represents Bloom filter configuration
 type BloomFilterConfig struct {
 	Capacity          uint    `yaml:"capacity"`
 	FalsePositiveRate float64 `yaml:"false_positive_rate"`
+	RebuildInterval   int     `yaml:"rebuild_interval"` // Seconds between rebuilds from MySQL; 0 disables them
+	AutoResize        bool    `yaml:"auto_resize"`      // Rebuild into a larger filter once capacity is exceeded
 }
 
 // SnowflakeConfig represents Snowflake ID generator configuration
 type SnowflakeConfig struct {
-	DatacenterID int64 `yaml:"datacenter_id"`
-	WorkerID     int64 `yaml:"worker_id"`
+	DatacenterID int64  `yaml:"datacenter_id"`
+	WorkerID     int64  `yaml:"worker_id"`
+	Epoch        string `yaml:"epoch"`          // RFC3339 time IDs count from; must not move later once codes exist
+	AutoWorkerID bool   `yaml:"auto_worker_id"` // Lease a free worker ID in Redis instead of using worker_id
+	LeaseTTL     int    `yaml:"lease_ttl"`      // Seconds a leased worker ID survives without a heartbeat
+
+	// ClockTolerance is how far, in milliseconds, the clock may be behind
+	// the last ID of this node at startup before IDs are held back rather
+	// than waited for
+	ClockTolerance int `yaml:"clock_tolerance"`
+}
+
+// EpochTime parses Epoch; empty returns the zero time
+func (s SnowflakeConfig) EpochTime() (time.Time, error) {
+	if s.Epoch == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s.Epoch)
+}
+
+// IDGeneratorConfig represents how short codes are generated
+type IDGeneratorConfig struct {
+	Strategy       string `yaml:"strategy"`        // snowflake, random, sequence
+	RandomLength   int    `yaml:"random_length"`   // Code length for random
+	SequenceOffset int64  `yaml:"sequence_offset"` // Added to the sequence before encoding
+
+	// Obfuscate permutes snowflake/sequence IDs with ObfuscationKey so codes
+	// don't reveal creation order; ObfuscationBits is the sequence ID space
+	Obfuscate       bool   `yaml:"obfuscate"`
+	ObfuscationKey  string `yaml:"obfuscation_key"`
+	ObfuscationBits int    `yaml:"obfuscation_bits"`
 }
 
 // RateLimitConfig represents rate limiting configuration
 type RateLimitConfig struct {
-	Enabled   bool                     `yaml:"enabled"`
-	Strategy  string                   `yaml:"strategy"`
-	Global    RateLimitRule            `yaml:"global"`
-	Endpoints []EndpointRateLimitRule  `yaml:"endpoints"`
+	Enabled       bool                     `yaml:"enabled"`
+	Strategy      string                   `yaml:"strategy"`
+	Backend       string                   `yaml:"backend"`        // redis, memory
+	FailureMode   string                   `yaml:"failure_mode"`   // open, closed
+	LocalFallback bool                     `yaml:"local_fallback"` // In-memory limiter while Redis is down
+	KeyBy         string                   `yaml:"key_by"`         // ip, api_key, user
+	Global        RateLimitRule            `yaml:"global"`
+	Endpoints     []EndpointRateLimitRule  `yaml:"endpoints"`
+	Tiers         map[string]RateLimitRule `yaml:"tiers"`         // Per-tier global limits (e.g., free, pro)
+	APIKeyTiers   map[string]string        `yaml:"api_key_tiers"` // API key -> tier name
+
+	// StandardHeaders also sends the IETF draft RateLimit-Limit,
+	// RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers
+	StandardHeaders bool `yaml:"standard_headers"`
 }
 
 // RateLimitRule defines a rate limit rule
 type RateLimitRule struct {
-	Limit  int `yaml:"limit"`   // Maximum requests
-	Window int `yaml:"window"`  // Time window in seconds
+	Limit  int `yaml:"limit"`  // Maximum requests
+	Window int `yaml:"window"` // Time window in seconds
+	Burst  int `yaml:"burst"`  // token_bucket capacity; 0 uses limit
 }
 
 // EndpointRateLimitRule defines endpoint-specific rate limits
 type EndpointRateLimitRule struct {
-	Path   string `yaml:"path"`
-	Limit  int    `yaml:"limit"`
-	Window int    `yaml:"window"`
+	Path        string `yaml:"path"`     // Full route path, e.g. /api/v1/shorten
+	Method      string `yaml:"method"`   // HTTP method; empty matches all methods
+	Strategy    string `yaml:"strategy"` // Overrides the global strategy
+	Limit       int    `yaml:"limit"`
+	Window      int    `yaml:"window"`
+	Burst       int    `yaml:"burst"`        // token_bucket capacity; 0 uses limit
+	FailureMode string `yaml:"failure_mode"` // Overrides the global failure mode
+}
+
+// EndpointFailureMode returns the failure mode for an endpoint rule,
+// falling back to the global setting when the rule doesn't override it
+func (r *RateLimitConfig) EndpointFailureMode(endpoint EndpointRateLimitRule) string {
+	if endpoint.FailureMode != "" {
+		return endpoint.FailureMode
+	}
+	return r.FailureMode
+}
+
+// AdminConfig represents admin API configuration
+type AdminConfig struct {
+	Token string `yaml:"token"` // Required in X-Admin-Token; admin routes are disabled when empty
+}
+
+// AuthConfig represents API caller authentication
+// Callers are identified by user ID for organization permissions; requests
+// without a known key are anonymous
+type AuthConfig struct {
+	APIKeys map[string]string `yaml:"api_keys"` // API key (X-API-Key) -> user ID
+
+	// Namespaces gives users their own aliases, served at
+	// /{namespace}/{alias} (user ID -> namespace)
+	Namespaces map[string]string `yaml:"namespaces"`
+}
+
+// VisitLogConfig represents visit log retention configuration
+type VisitLogConfig struct {
+	RetentionDays      int `yaml:"retention_days"`       // Older visit logs are removed; 0 keeps them forever
+	PartitionDaysAhead int `yaml:"partition_days_ahead"` // Daily partitions created in advance
+	CleanupInterval    int `yaml:"cleanup_interval"`     // Seconds between retention runs; 0 disables the job
+
+	// RollupInterval is the number of seconds between runs of the job that
+	// aggregates finished days into the daily stats tables; 0 disables it
+	RollupInterval int `yaml:"rollup_interval"`
+	// RollupLookbackDays finished days are rolled up again on every run, to
+	// pick up late visits; must be shorter than RetentionDays
+	RollupLookbackDays int `yaml:"rollup_lookback_days"`
+
+	// GeoIPDatabase is the path to a MaxMind City .mmdb file used to fill
+	// country/city; geo enrichment is skipped when empty
+	GeoIPDatabase string `yaml:"geoip_database"`
+}
+
+// PrivacyConfig represents what visit logs keep about visitors
+type PrivacyConfig struct {
+	IPStorage  string `yaml:"ip_storage"`   // full, truncate (/24 and /48) or hash
+	IPHashSalt string `yaml:"ip_hash_salt"` // Salt for ip_storage: hash
+	// RespectDNT leaves visits sent with DNT: 1 or Sec-GPC: 1 out of the
+	// visit logs and click events; they are still counted
+	RespectDNT bool `yaml:"respect_dnt"`
+	// Export and deletion requests of data subjects (admin API)
+	DataRequests DataRequestConfig `yaml:"data_requests"`
+}
+
+// ScanProtectionConfig represents the tarpit for clients enumerating short
+// codes (many 404s from one IP), separate from rate limits
+type ScanProtectionConfig struct {
+	Enabled     bool `yaml:"enabled"`
+	Threshold   int  `yaml:"threshold"`    // 404s in window before requests are delayed
+	Window      int  `yaml:"window"`       // Seconds
+	BaseDelay   int  `yaml:"base_delay"`   // Milliseconds, doubled per further 404
+	MaxDelay    int  `yaml:"max_delay"`    // Milliseconds
+	BanAfter    int  `yaml:"ban_after"`    // 404s in window that ban the IP; 0 never bans
+	BanDuration int  `yaml:"ban_duration"` // Seconds
+}
+
+// CaptchaConfig represents the captcha anonymous callers (without an API
+// key) must solve to create links
+type CaptchaConfig struct {
+	Provider string  `yaml:"provider"`  // hcaptcha or recaptcha; empty disables
+	Secret   string  `yaml:"secret"`    // Provider secret key
+	MinScore float64 `yaml:"min_score"` // reCAPTCHA v3 tokens scored lower fail, 0-1
+	Timeout  int     `yaml:"timeout"`   // Milliseconds to wait for the provider
+}
+
+// SignedLinksConfig represents links that only redirect with an HMAC
+// signature and expiry in the query string
+type SignedLinksConfig struct {
+	Secret     string `yaml:"secret"`      // HMAC key, at least 32 characters; empty disables
+	MaxTTL     int    `yaml:"max_ttl"`     // Seconds; signatures expiring later are refused
+	RequireAll bool   `yaml:"require_all"` // Every link needs a signature, not just flagged ones
+}
+
+// DataRequestConfig represents GDPR export/deletion jobs
+type DataRequestConfig struct {
+	JobTTL         int    `yaml:"job_ttl"`         // Seconds a finished request (and its export) is kept
+	WebhookURL     string `yaml:"webhook_url"`     // Receives a POST when a request finishes; empty disables
+	WebhookTimeout int    `yaml:"webhook_timeout"` // Milliseconds
+}
+
+// ShortCodeConfig represents short codes that may not be used for links
+// Built-in route names are always reserved; see service.DefaultReservedCodes
+type ShortCodeConfig struct {
+	Reserved     []string `yaml:"reserved"`      // Exact codes, case-insensitive
+	BlockedWords []string `yaml:"blocked_words"` // Codes containing any of these are rejected
+	// Reuse codes of long-expired links that never received traffic
+	Recycling CodeRecyclingConfig `yaml:"recycling"`
+}
+
+// CodeRecyclingConfig represents reclaiming short codes of expired links
+type CodeRecyclingConfig struct {
+	Enabled        bool `yaml:"enabled"`
+	QuarantineDays int  `yaml:"quarantine_days"` // How long a link must have been expired; at least 30
+	Interval       int  `yaml:"interval"`        // Seconds between recycling runs
+	BatchSize      int  `yaml:"batch_size"`      // Links recycled per run
+}
+
+// DeletedLinkConfig represents soft-deleted link purging
+type DeletedLinkConfig struct {
+	PurgeAfterDays int `yaml:"purge_after_days"` // Deleted links can be restored for this long
+	PurgeInterval  int `yaml:"purge_interval"`   // Seconds between purge runs; 0 disables the job
+}
+
+// ImportConfig represents bulk CSV imports (POST /api/v1/import)
+type ImportConfig struct {
+	MaxBytes       int64 `yaml:"max_bytes"`       // Largest accepted file
+	AsyncThreshold int64 `yaml:"async_threshold"` // Larger files run as background jobs
+	JobTTL         int   `yaml:"job_ttl"`         // Seconds a finished job's report is kept
+}
+
+// DestinationConfig represents which hosts links may point to
+type DestinationConfig struct {
+	// BlockPrivate rejects hosts that resolve to loopback, private,
+	// link-local or cloud metadata addresses
+	BlockPrivate   bool     `yaml:"block_private"`
+	AllowedHosts   []string `yaml:"allowed_hosts"`   // Exempt host names, e.g. wiki.corp.example.com
+	AllowedCIDRs   []string `yaml:"allowed_cidrs"`   // Exempt networks, e.g. 10.20.0.0/16
+	ResolveTimeout int      `yaml:"resolve_timeout"` // Milliseconds per DNS lookup
+
+	// UpgradeHTTPS stores new http:// links as https:// when the destination
+	// answers over https; links can opt out with no_https_upgrade
+	UpgradeHTTPS   bool `yaml:"upgrade_https"`
+	UpgradeTimeout int  `yaml:"upgrade_timeout"` // Milliseconds for the https probe
+}
+
+// RedirectDelayConfig represents the countdown page shown before
+// redirecting; links can set their own delay
+type RedirectDelayConfig struct {
+	Seconds    int    `yaml:"seconds"`    // Delay for links without their own; 0 redirects at once
+	Disclaimer string `yaml:"disclaimer"` // Text shown on the page; empty for none
+	// Template is an html/template file for the page; empty uses the
+	// built-in one
+	Template string `yaml:"template"`
+}
+
+// LinkTitleConfig represents fetching the destination page title of new
+// links in the background
+type LinkTitleConfig struct {
+	Enabled      bool  `yaml:"enabled"`
+	Timeout      int   `yaml:"timeout"`        // Milliseconds per page
+	MaxBodyBytes int64 `yaml:"max_body_bytes"` // Bytes of a page searched for its title
+	Concurrency  int   `yaml:"concurrency"`    // Fetches at once; new links beyond it get no title
+}
+
+// TimeoutConfig represents per-operation deadlines in milliseconds
+// A slow MySQL or Redis then fails requests quickly instead of piling them up
+type TimeoutConfig struct {
+	Redirect   int `yaml:"redirect"`    // Resolving a short code (cache and MySQL)
+	VisitWrite int `yaml:"visit_write"` // Each background visit count / visit log write
+	Request    int `yaml:"request"`     // Every other request, except streamed imports and exports
+}
+
+// DegradedConfig represents serving from cache while MySQL is down
+type DegradedConfig struct {
+	Enabled        bool `yaml:"enabled"`
+	CheckInterval  int  `yaml:"check_interval"`  // Seconds between MySQL pings
+	ReplayInterval int  `yaml:"replay_interval"` // Seconds between replays of visits queued in Redis
+}
+
+// ConcurrencyConfig represents the cap on requests in flight per instance
+type ConcurrencyConfig struct {
+	Enabled      bool `yaml:"enabled"`
+	MaxInFlight  int  `yaml:"max_in_flight"` // Requests handled at a time
+	QueueTimeout int  `yaml:"queue_timeout"` // Milliseconds a request waits for a slot before 503; 0 doesn't wait
+}
+
+// AccessLogConfig represents the structured per-request log, which
+// replaces Gin's text logger when enabled
+type AccessLogConfig struct {
+	Enabled            bool    `yaml:"enabled"`
+	SampleRate         float64 `yaml:"sample_rate"`          // Fraction of requests logged, 0-1
+	RedirectSampleRate float64 `yaml:"redirect_sample_rate"` // Fraction of redirects logged, 0-1
+}
+
+// WriteBehindConfig represents queued link creation: new links are cached
+// and queued in Redis, and inserted into MySQL by background workers
+type WriteBehindConfig struct {
+	Enabled           bool `yaml:"enabled"`
+	Workers           int  `yaml:"workers"`            // Concurrent inserts per instance
+	PollInterval      int  `yaml:"poll_interval"`      // Milliseconds to wait after finding the queue empty
+	MaxAttempts       int  `yaml:"max_attempts"`       // Inserts tried before an entry moves to the failed list
+	ReconcileInterval int  `yaml:"reconcile_interval"` // Seconds; unfinished inserts older than this are requeued
+}
+
+// OutboxConfig represents the transactional outbox: side effects of new
+// links are stored with them in MySQL and applied by a background dispatcher
+type OutboxConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	Interval       int    `yaml:"interval"`        // Milliseconds between polls for due events
+	BatchSize      int    `yaml:"batch_size"`      // Events claimed per poll
+	MaxAttempts    int    `yaml:"max_attempts"`    // Attempts before an event is marked failed
+	Lease          int    `yaml:"lease"`           // Seconds a dispatcher holds the events it claimed
+	RetentionDays  int    `yaml:"retention_days"`  // Dispatched and failed events are purged after this; 0 keeps them
+	WebhookURL     string `yaml:"webhook_url"`     // Receives a POST for every new link; empty disables
+	WebhookTimeout int    `yaml:"webhook_timeout"` // Milliseconds
+}
+
+// LeaderboardConfig represents the top-links leaderboard (GET /api/v1/stats/top)
+type LeaderboardConfig struct {
+	Enabled      bool `yaml:"enabled"`
+	Size         int  `yaml:"size"`          // Links kept per hourly/daily bucket when trimming
+	TrimInterval int  `yaml:"trim_interval"` // Seconds between trims
+}
+
+// IdempotencyConfig represents Idempotency-Key handling on POST /api/v1/shorten
+type IdempotencyConfig struct {
+	Enabled     bool `yaml:"enabled"`
+	TTL         int  `yaml:"ttl"`          // Seconds a response is replayed for retries with its key
+	LockTimeout int  `yaml:"lock_timeout"` // Seconds a key stays reserved while its first request runs
+}
+
+// ReportsConfig represents scheduled summary reports (POST /api/v1/summaries)
+type ReportsConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	CheckInterval int    `yaml:"check_interval"` // Seconds between checks for due summaries
+	DailyAt       string `yaml:"daily_at"`       // HH:MM (UTC) daily and weekly periods end at
+	WeeklyDay     string `yaml:"weekly_day"`     // Day weekly periods end on, e.g. monday
+	TopLinks      int    `yaml:"top_links"`      // Top and new links listed per summary
+
+	// Template is a text/template file for email bodies, executed with the
+	// summary; empty uses the built-in one
+	Template       string `yaml:"template"`
+	WebhookTimeout int    `yaml:"webhook_timeout"` // Milliseconds, also used for SMTP
+
+	SMTP SMTPConfig `yaml:"smtp"` // Email summaries are unavailable without a host
+}
+
+// SMTPConfig represents the mail server summary emails are sent through
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"` // PLAIN auth when set
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+// LinkHealthConfig represents the periodic checks of link destinations
+type LinkHealthConfig struct {
+	Enabled     bool `yaml:"enabled"`
+	Interval    int  `yaml:"interval"`     // Seconds between runs
+	Recheck     int  `yaml:"recheck"`      // Hours before a link is checked again
+	BatchSize   int  `yaml:"batch_size"`   // Links checked per run
+	Concurrency int  `yaml:"concurrency"`  // Destinations checked at once
+	Timeout     int  `yaml:"timeout"`      // Milliseconds per destination
+	NotifyAfter int  `yaml:"notify_after"` // Consecutive failed checks before notifying; 0 never
+
+	WebhookURL     string `yaml:"webhook_url"`     // Receives a POST when a link's destination looks dead; empty disables
+	WebhookTimeout int    `yaml:"webhook_timeout"` // Milliseconds
+}
+
+// AbuseConfig represents detection of anomalous traffic to links
+type AbuseConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// ASNDatabase is the path to a MaxMind ASN .mmdb file used to recognize
+	// datacenter traffic; the datacenter policy is skipped when empty
+	ASNDatabase    string `yaml:"asn_database"`
+	DatacenterASNs []uint `yaml:"datacenter_asns"` // Added to the built-in hosting provider ASNs
+
+	FlagCooldown     int `yaml:"flag_cooldown"`     // Seconds before a link is flagged again for the same reason
+	ThrottleLimit    int `yaml:"throttle_limit"`    // Redirects per minute a throttled link still serves
+	ThrottleDuration int `yaml:"throttle_duration"` // Seconds a link stays throttled
+
+	WebhookURL     string `yaml:"webhook_url"`     // Receives a POST for every flag; empty disables
+	WebhookTimeout int    `yaml:"webhook_timeout"` // Milliseconds
+
+	// ReportThreshold applies ReportAction to a link once this many different
+	// IPs have reported it; 0 never does. Reports work without Enabled.
+	ReportThreshold int    `yaml:"report_threshold"`
+	ReportAction    string `yaml:"report_action"` // warn or disable
+
+	// InterstitialTemplate is an html/template file for the warning shown
+	// before redirecting through flagged links; empty uses the built-in page
+	InterstitialTemplate string `yaml:"interstitial_template"`
+
+	Policies AbusePoliciesConfig `yaml:"policies"`
+}
+
+// AbusePoliciesConfig holds one policy per kind of anomalous traffic
+type AbusePoliciesConfig struct {
+	Spike      AbusePolicyConfig `yaml:"spike"`      // Sudden jump over the link's recent traffic
+	SingleIP   AbusePolicyConfig `yaml:"single_ip"`  // One IP hammering a link
+	Datacenter AbusePolicyConfig `yaml:"datacenter"` // Traffic mostly from hosting providers
+}
+
+// AbusePolicyConfig represents when a policy triggers and what it does
+type AbusePolicyConfig struct {
+	Enabled   bool    `yaml:"enabled"`
+	Window    int     `yaml:"window"`    // Seconds; clicks are counted per window
+	Threshold int     `yaml:"threshold"` // Clicks in a window before the policy applies
+	Factor    float64 `yaml:"factor"`    // spike: times the average of the previous windows
+	Ratio     float64 `yaml:"ratio"`     // datacenter: share of clicks from datacenter networks
+	Action    string  `yaml:"action"`    // flag, throttle, warn or disable
+}
+
+// EventsConfig represents click event publishing configuration
+type EventsConfig struct {
+	Backend    string      `yaml:"backend"`      // none, kafka, nats
+	IPHashSalt string      `yaml:"ip_hash_salt"` // Salt for hashing visitor IPs in events
+	Kafka      KafkaConfig `yaml:"kafka"`
+	NATS       NATSConfig  `yaml:"nats"`
+}
+
+// KafkaConfig represents the Kafka event publisher
+type KafkaConfig struct {
+	Brokers []string `yaml:"brokers"`
+	Topic   string   `yaml:"topic"`
+}
+
+// NATSConfig represents the NATS event publisher
+type NATSConfig struct {
+	URL     string `yaml:"url"`
+	Subject string `yaml:"subject"`
+}
+
+// GRPCConfig represents the gRPC API server configuration
+type GRPCConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Port    int  `yaml:"port"`
+}
+
+// TracingConfig represents OpenTelemetry tracing configuration
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled"`
+	ServiceName string  `yaml:"service_name"`
+	Endpoint    string  `yaml:"endpoint"`     // OTLP/gRPC collector, e.g. localhost:4317
+	Insecure    bool    `yaml:"insecure"`     // Plaintext connection to the collector
+	SampleRatio float64 `yaml:"sample_ratio"` // Fraction of new traces recorded, 0..1
+}
+
+// DebugConfig represents the pprof/expvar debug listener
+type DebugConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Addr    string `yaml:"addr"` // Keep on localhost; profiles expose internals
 }
 
 // DSN returns MySQL data source name
 func (m *MySQLConfig) DSN() string {
+	return m.dsnFor(m.Host, m.Port, m.Database)
+}
+
+// ReplicaDSNs returns the data source names of all read replicas
+func (m *MySQLConfig) ReplicaDSNs() []string {
+	dsns := make([]string, 0, len(m.Replicas))
+	for _, replica := range m.Replicas {
+		dsns = append(dsns, m.dsnFor(replica.Host, replica.Port, m.Database))
+	}
+	return dsns
+}
+
+// ShardDSN returns the data source name of a shard
+func (m *MySQLConfig) ShardDSN(s MySQLShardConfig) string {
+	host, port, database := m.Host, m.Port, m.Database
+	if s.Host != "" {
+		host = s.Host
+	}
+	if s.Port != 0 {
+		port = s.Port
+	}
+	if s.Database != "" {
+		database = s.Database
+	}
+	return m.dsnFor(host, port, database)
+}
+
+// dsnFor builds a data source name for the given host, port and database
+func (m *MySQLConfig) dsnFor(host string, port int, database string) string {
 	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		m.Username, m.Password, m.Host, m.Port, m.Database)
+		m.Username, m.Password, host, port, database)
 }
 
 // Addr returns Redis address
@@ -89,28 +696,271 @@ func (r *RedisConfig) Addr() string {
 
 var globalConfig *Config
 
-// Load loads configuration from file
+// Default returns the configuration used for settings absent from both the
+// config file and the environment
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:                8080,
+			Mode:                "release",
+			UseForwardedHeaders: true,
+			MaxBodyBytes:        1 << 20,
+			ReadTimeout:         10,
+			ReadHeaderTimeout:   5,
+			WriteTimeout:        10,
+			IdleTimeout:         120,
+			MaxHeaderBytes:      1 << 20,
+			ShutdownTimeout:     5,
+			RobotsTxt:           "User-agent: *\nDisallow: /\n",
+			TLS: TLSConfig{
+				HTTPPort: 80,
+				Autocert: AutocertConfig{CacheDir: "certs"},
+			},
+		},
+		MySQL: MySQLConfig{
+			Host:            "localhost",
+			Port:            3306,
+			Username:        "root",
+			Database:        "url_shortener",
+			MaxIdleConns:    10,
+			MaxOpenConns:    100,
+			ConnMaxLifetime: 240,
+			ConnMaxIdleTime: 60,
+			PingInterval:    30,
+		},
+		Redis: RedisConfig{
+			Host:     "localhost",
+			Port:     6379,
+			PoolSize: 100,
+
+			SlowCommandThreshold: 100,
+		},
+		Cache: CacheConfig{
+			Backend:   "redis",
+			TTL:       86400,
+			TTLJitter: 3600,
+			Local:     LocalCacheConfig{Enabled: true, Size: 10000, TTL: 60},
+			Disk: DiskCacheConfig{
+				Path:         "data/link-cache.jsonl",
+				MaxEntries:   100000,
+				MaxBytes:     64 << 20,
+				SyncInterval: 30,
+			},
+			Warmup:    WarmupConfig{Links: 10000, Concurrency: 8},
+			Memcached: MemcachedConfig{Timeout: 500, MaxIdleConns: 16},
+		},
+		BloomFilter: BloomFilterConfig{
+			Capacity:          10000000,
+			FalsePositiveRate: 0.01,
+			RebuildInterval:   86400,
+			AutoResize:        true,
+		},
+		Snowflake: SnowflakeConfig{
+			DatacenterID: 1,
+			WorkerID:     1,
+			Epoch:        "2010-11-04T01:42:54.657Z",
+			LeaseTTL:     30,
+
+			ClockTolerance: 5000,
+		},
+		IDGenerator: IDGeneratorConfig{
+			Strategy:        "snowflake",
+			RandomLength:    7,
+			SequenceOffset:  916132832, // 62^5: codes start at 6 characters
+			ObfuscationBits: 36,        // 2^36 ≈ 6.9e10: 6-7 character codes
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:       true,
+			Strategy:      "sliding_window",
+			Backend:       "redis",
+			FailureMode:   "open",
+			LocalFallback: true,
+			KeyBy:         "ip",
+			Global:        RateLimitRule{Limit: 100, Window: 60},
+		},
+		VisitLog: VisitLogConfig{
+			RetentionDays:      90,
+			PartitionDaysAhead: 7,
+			CleanupInterval:    3600,
+			RollupLookbackDays: 2,
+		},
+		Privacy: PrivacyConfig{
+			IPStorage: "full",
+			DataRequests: DataRequestConfig{
+				JobTTL:         86400,
+				WebhookTimeout: 5000,
+			},
+		},
+		ScanProtection: ScanProtectionConfig{
+			Threshold:   20,
+			Window:      600,
+			BaseDelay:   250,
+			MaxDelay:    5000,
+			BanAfter:    200,
+			BanDuration: 3600,
+		},
+		Captcha: CaptchaConfig{
+			MinScore: 0.5,
+			Timeout:  3000,
+		},
+		SignedLinks: SignedLinksConfig{
+			MaxTTL: 7 * 86400,
+		},
+		DeletedLinks: DeletedLinkConfig{
+			PurgeAfterDays: 30,
+			PurgeInterval:  3600,
+		},
+		ShortCodes: ShortCodeConfig{
+			Recycling: CodeRecyclingConfig{
+				QuarantineDays: 365,
+				Interval:       3600,
+				BatchSize:      500,
+			},
+		},
+		Import: ImportConfig{
+			MaxBytes:       50 << 20,
+			AsyncThreshold: 1 << 20,
+			JobTTL:         86400,
+		},
+		Destinations: DestinationConfig{
+			BlockPrivate:   true,
+			ResolveTimeout: 2000,
+			UpgradeTimeout: 3000,
+		},
+		DegradedMode: DegradedConfig{
+			Enabled:        true,
+			CheckInterval:  2,
+			ReplayInterval: 10,
+		},
+		WriteBehind: WriteBehindConfig{
+			Workers:           4,
+			PollInterval:      100,
+			MaxAttempts:       10,
+			ReconcileInterval: 60,
+		},
+		Outbox: OutboxConfig{
+			Interval:       1000,
+			BatchSize:      100,
+			MaxAttempts:    10,
+			Lease:          60,
+			RetentionDays:  7,
+			WebhookTimeout: 5000,
+		},
+		Abuse: AbuseConfig{
+			Enabled:          false,
+			FlagCooldown:     3600,
+			ThrottleLimit:    60,
+			ThrottleDuration: 3600,
+			WebhookTimeout:   5000,
+			ReportAction:     "disable",
+			Policies: AbusePoliciesConfig{
+				Spike:      AbusePolicyConfig{Enabled: true, Window: 60, Threshold: 1000, Factor: 10, Action: "flag"},
+				SingleIP:   AbusePolicyConfig{Enabled: true, Window: 60, Threshold: 100, Action: "throttle"},
+				Datacenter: AbusePolicyConfig{Enabled: true, Window: 300, Threshold: 200, Ratio: 0.8, Action: "flag"},
+			},
+		},
+		Leaderboard: LeaderboardConfig{
+			Enabled:      true,
+			Size:         1000,
+			TrimInterval: 300,
+		},
+		Reports: ReportsConfig{
+			Enabled:        false,
+			CheckInterval:  60,
+			DailyAt:        "08:00",
+			WeeklyDay:      "monday",
+			TopLinks:       5,
+			WebhookTimeout: 5000,
+			SMTP:           SMTPConfig{Port: 587},
+		},
+		Concurrency: ConcurrencyConfig{
+			Enabled:      false,
+			MaxInFlight:  1000,
+			QueueTimeout: 100,
+		},
+		AccessLog: AccessLogConfig{
+			Enabled:            false,
+			SampleRate:         1,
+			RedirectSampleRate: 1,
+		},
+		Idempotency: IdempotencyConfig{
+			Enabled:     true,
+			TTL:         86400,
+			LockTimeout: 60,
+		},
+		LinkHealth: LinkHealthConfig{
+			Enabled:        false,
+			Interval:       300,
+			Recheck:        24,
+			BatchSize:      500,
+			Concurrency:    8,
+			Timeout:        10000,
+			NotifyAfter:    3,
+			WebhookTimeout: 5000,
+		},
+		LinkTitles: LinkTitleConfig{
+			Enabled:      false,
+			Timeout:      3000,
+			MaxBodyBytes: 262144,
+			Concurrency:  16,
+		},
+		Timeouts: TimeoutConfig{
+			Redirect:   1000,
+			VisitWrite: 5000,
+			Request:    10000,
+		},
+		Events: EventsConfig{
+			Backend: "none",
+			Kafka:   KafkaConfig{Topic: "short-link.clicks"},
+			NATS:    NATSConfig{URL: "nats://localhost:4222", Subject: "short-link.clicks"},
+		},
+		GRPC: GRPCConfig{Port: 9090},
+		Tracing: TracingConfig{
+			ServiceName: "short-link",
+			Endpoint:    "localhost:4317",
+			Insecure:    true,
+			SampleRatio: 1,
+		},
+		Debug: DebugConfig{Addr: "127.0.0.1:6060"},
+	}
+}
+
+// Load loads configuration from defaults, the config file and the
+// environment (in increasing precedence), then validates it.
+// A missing config file is allowed so containers can be configured
+// through the environment alone.
 func Load(configPath string) (*Config, error) {
+	cfg := Default()
+
 	data, err := os.ReadFile(configPath)
-	if err != nil {
+	if err != nil && !os.IsNotExist(err) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
+	if err == nil {
+		// Keys absent from the file keep their defaults
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	// Override with environment variables (see env.go)
+	problems := []string{}
+	for _, err := range applyEnv(cfg) {
+		problems = append(problems, err.Error())
 	}
 
-	// Override with environment variables if present
-	if host := os.Getenv("MYSQL_HOST"); host != "" {
-		cfg.MySQL.Host = host
+	if err := cfg.Validate(); err != nil {
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			problems = append(problems, verr.Problems...)
+		}
 	}
-	if host := os.Getenv("REDIS_HOST"); host != "" {
-		cfg.Redis.Host = host
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
 	}
 
-	globalConfig = &cfg
-	return &cfg, nil
+	globalConfig = cfg
+	return cfg, nil
 }
 
 // Get returns the global configuration
@@ -0,0 +1,516 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeConfig writes a config file into a temp dir and returns its path
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// TestLoadRepoConfig tests that the shipped config file is valid
+func TestLoadRepoConfig(t *testing.T) {
+	cfg, err := Load("config.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, 8080, cfg.Server.Port)
+	assert.Equal(t, "root123", cfg.MySQL.Password)
+}
+
+// TestLoadDefaultsWithoutFile tests that a missing file falls back to defaults
+func TestLoadDefaultsWithoutFile(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+}
+
+// TestLoadKeepsDefaultsForMissingKeys tests that partial files are merged onto defaults
+func TestLoadKeepsDefaultsForMissingKeys(t *testing.T) {
+	path := writeConfig(t, "server:\n  port: 9000\nmysql:\n  password: secret\n")
+
+	cfg, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 9000, cfg.Server.Port)
+	assert.Equal(t, "release", cfg.Server.Mode)
+	assert.Equal(t, "secret", cfg.MySQL.Password)
+	assert.Equal(t, 3306, cfg.MySQL.Port)
+}
+
+// TestLoadEnvOverrides tests that environment variables win over the file
+func TestLoadEnvOverrides(t *testing.T) {
+	path := writeConfig(t, "server:\n  port: 9000\nmysql:\n  password: secret\n")
+	t.Setenv("SERVER_PORT", "8081")
+	t.Setenv("MYSQL_PASSWORD", "from-env")
+	t.Setenv("REDIS_HOST", "redis")
+	t.Setenv("CACHE_LOCAL_ENABLED", "false")
+	t.Setenv("RATE_LIMIT_GLOBAL_LIMIT", "500")
+	t.Setenv("BLOOM_FILTER_FALSE_POSITIVE_RATE", "0.001")
+	t.Setenv("SNOWFLAKE_WORKER_ID", "7")
+	t.Setenv("SERVER_TRUSTED_PROXIES", "10.0.0.0/8, ::1")
+
+	cfg, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 8081, cfg.Server.Port)
+	assert.Equal(t, "from-env", cfg.MySQL.Password)
+	assert.Equal(t, "redis", cfg.Redis.Host)
+	assert.False(t, cfg.Cache.Local.Enabled)
+	assert.Equal(t, 500, cfg.RateLimit.Global.Limit)
+	assert.Equal(t, 0.001, cfg.BloomFilter.FalsePositiveRate)
+	assert.Equal(t, int64(7), cfg.Snowflake.WorkerID)
+	assert.Equal(t, []string{"10.0.0.0/8", "::1"}, cfg.Server.TrustedProxies)
+}
+
+// TestLoadReportsAllProblems tests that every problem is reported at once
+func TestLoadReportsAllProblems(t *testing.T) {
+	path := writeConfig(t, "server:\n  mode: prod\nmysql:\n  host: \"\"\n")
+	t.Setenv("SERVER_PORT", "eighty")
+	t.Setenv("REDIS_PORT", "70000")
+	t.Setenv("SNOWFLAKE_WORKER_ID", "32")
+
+	_, err := Load(path)
+	var verr *ValidationError
+	assert.True(t, errors.As(err, &verr))
+	assert.Len(t, verr.Problems, 5)
+	assert.Contains(t, err.Error(), "SERVER_PORT: invalid integer")
+	assert.Contains(t, err.Error(), "server.mode")
+	assert.Contains(t, err.Error(), "mysql.host: required (env MYSQL_HOST)")
+	assert.Contains(t, err.Error(), "redis.port")
+	assert.Contains(t, err.Error(), "snowflake.worker_id")
+}
+
+// TestValidateTLS tests that TLS needs a certificate source
+func TestValidateTLS(t *testing.T) {
+	cfg := Default()
+	cfg.Server.TLS.Enabled = true
+	assert.Error(t, cfg.Validate())
+
+	cfg.Server.TLS.CertFile = "tls.crt"
+	cfg.Server.TLS.KeyFile = "tls.key"
+	assert.NoError(t, cfg.Validate())
+
+	cfg = Default()
+	cfg.Server.TLS.Enabled = true
+	cfg.Server.TLS.Autocert.Enabled = true
+	cfg.Server.TLS.Autocert.Domains = []string{"s.example.com"}
+	assert.NoError(t, cfg.Validate())
+}
+
+// TestValidateShards tests the shard list checks
+func TestValidateShards(t *testing.T) {
+	cfg := Default()
+	cfg.MySQL.Shards = []MySQLShardConfig{{Name: "main"}, {Name: "s2", Host: "mysql2"}}
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, "root:@tcp(mysql2:3306)/url_shortener?charset=utf8mb4&parseTime=True&loc=Local",
+		cfg.MySQL.ShardDSN(cfg.MySQL.Shards[1]))
+
+	// Same name, or the same database under two names
+	cfg.MySQL.Shards = []MySQLShardConfig{{Name: "main"}, {Name: "main", Host: "mysql2"}}
+	assert.Error(t, cfg.Validate())
+	cfg.MySQL.Shards = []MySQLShardConfig{{Name: "main"}, {Name: "s2", Host: "localhost"}}
+	assert.Error(t, cfg.Validate())
+
+	// Draining needs rebalancing and a shard left to move to
+	cfg.MySQL.Shards = []MySQLShardConfig{{Name: "main", Draining: true}, {Name: "s2", Host: "mysql2"}}
+	assert.Error(t, cfg.Validate())
+	cfg.MySQL.Rebalancing = true
+	assert.NoError(t, cfg.Validate())
+	cfg.MySQL.Shards[1].Draining = true
+	assert.Error(t, cfg.Validate())
+}
+
+// TestValidateRedirectPrefix tests which redirect prefixes are accepted
+func TestValidateRedirectPrefix(t *testing.T) {
+	for prefix, valid := range map[string]bool{
+		"":         true,
+		"/r":       true,
+		"/go/to":   true,
+		"r":        false,
+		"/r/":      false,
+		"/r/:code": false,
+		"/api":     false,
+		"/admin/r": false,
+	} {
+		cfg := Default()
+		cfg.Server.RedirectPrefix = prefix
+		if valid {
+			assert.NoError(t, cfg.Validate(), prefix)
+		} else {
+			assert.Error(t, cfg.Validate(), prefix)
+		}
+	}
+}
+
+// TestValidateRollupLookback tests that re-rolled days must still have logs
+func TestValidateRollupLookback(t *testing.T) {
+	cfg := Default()
+	cfg.VisitLog.RollupInterval = 3600
+	assert.NoError(t, cfg.Validate())
+
+	cfg.VisitLog.RetentionDays = 2
+	assert.Error(t, cfg.Validate())
+
+	// Logs kept forever
+	cfg.VisitLog.RetentionDays = 0
+	assert.NoError(t, cfg.Validate())
+}
+
+// TestValidateReports tests the summary report settings
+func TestValidateReports(t *testing.T) {
+	cfg := Default()
+	cfg.Reports.Enabled = true
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Reports.DailyAt = "8am"
+	cfg.Reports.WeeklyDay = "someday"
+	assert.Error(t, cfg.Validate())
+
+	// Email needs a sender address
+	cfg = Default()
+	cfg.Reports.Enabled = true
+	cfg.Reports.WeeklyDay = "Friday"
+	cfg.Reports.SMTP.Host = "smtp.example.com"
+	assert.Error(t, cfg.Validate())
+	cfg.Reports.SMTP.From = "links@example.com"
+	assert.NoError(t, cfg.Validate())
+}
+
+// TestValidateLinkHealth tests the link health check settings
+func TestValidateLinkHealth(t *testing.T) {
+	cfg := Default()
+	cfg.LinkHealth.Enabled = true
+	assert.NoError(t, cfg.Validate())
+
+	cfg.LinkHealth.Concurrency = 0
+	cfg.LinkHealth.WebhookURL = "hooks.example.com/dead-links"
+	assert.Error(t, cfg.Validate())
+
+	cfg = Default()
+	cfg.LinkHealth.Enabled = true
+	cfg.LinkHealth.WebhookURL = "https://hooks.example.com/dead-links"
+	assert.NoError(t, cfg.Validate())
+}
+
+// TestValidateLinkTitles tests the title fetch settings
+func TestValidateLinkTitles(t *testing.T) {
+	cfg := Default()
+	cfg.LinkTitles.Timeout = 0
+	assert.NoError(t, cfg.Validate(), "checked only when enabled")
+
+	cfg.LinkTitles.Enabled = true
+	cfg.LinkTitles.MaxBodyBytes = 0
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "link_titles.timeout")
+	assert.ErrorContains(t, err, "link_titles.max_body_bytes")
+}
+
+// TestValidateCodeRecycling tests the short code recycling settings
+func TestValidateCodeRecycling(t *testing.T) {
+	cfg := Default()
+	cfg.ShortCodes.Recycling.Enabled = true
+	assert.NoError(t, cfg.Validate())
+
+	cfg.ShortCodes.Recycling.QuarantineDays = 7
+	assert.Error(t, cfg.Validate(), "quarantine too short")
+
+	cfg = Default()
+	cfg.ShortCodes.Recycling.QuarantineDays = 0
+	assert.NoError(t, cfg.Validate(), "ignored while disabled")
+}
+
+// TestValidateWriteBehind tests the write-behind creation settings
+func TestValidateWriteBehind(t *testing.T) {
+	cfg := Default()
+	cfg.WriteBehind.Enabled = true
+	assert.NoError(t, cfg.Validate())
+
+	cfg.WriteBehind.MaxAttempts = 0
+	assert.Error(t, cfg.Validate())
+}
+
+// TestValidateOutbox tests the outbox settings
+func TestValidateOutbox(t *testing.T) {
+	cfg := Default()
+	cfg.Outbox.Enabled = true
+	cfg.Outbox.WebhookURL = "https://hooks.example.com/links"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Outbox.BatchSize = 0
+	cfg.Outbox.WebhookURL = "hooks.example.com"
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "outbox.batch_size")
+	assert.ErrorContains(t, err, "outbox.webhook_url")
+
+	cfg = Default()
+	cfg.Outbox.Enabled = true
+	cfg.Outbox.WebhookURL = "https://hooks.example.com/links"
+	cfg.Outbox.Lease = 10
+	assert.ErrorContains(t, cfg.Validate(), "outbox.webhook_timeout", "calls must end within the lease")
+}
+
+// TestValidateServerTimeouts tests the HTTP listener limits
+func TestValidateServerTimeouts(t *testing.T) {
+	cfg := Default()
+	cfg.Server.ReadTimeout = 0
+	cfg.Server.WriteTimeout = 0
+	cfg.Server.IdleTimeout = 0
+	assert.NoError(t, cfg.Validate(), "0 disables a limit")
+
+	cfg = Default()
+	cfg.Timeouts.Request = 30000
+	cfg.Server.WriteTimeout = 20
+	cfg.Server.ReadHeaderTimeout = 0
+	cfg.Server.ShutdownTimeout = -1
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "server.write_timeout")
+	assert.ErrorContains(t, err, "server.read_header_timeout")
+	assert.ErrorContains(t, err, "server.shutdown_timeout")
+
+	cfg.Server.WriteTimeout = 30
+	cfg.Server.ReadHeaderTimeout = 5
+	cfg.Server.ShutdownTimeout = 30
+	assert.NoError(t, cfg.Validate())
+}
+
+// TestValidateIdempotency tests the Idempotency-Key settings
+func TestValidateIdempotency(t *testing.T) {
+	cfg := Default()
+	cfg.Idempotency.TTL = 0
+	cfg.Idempotency.LockTimeout = 5
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "idempotency.ttl")
+	assert.ErrorContains(t, err, "idempotency.lock_timeout")
+
+	cfg.Idempotency.Enabled = false
+	assert.NoError(t, cfg.Validate())
+}
+
+// TestValidateRateLimitBurst tests that only token buckets take a burst
+func TestValidateRateLimitBurst(t *testing.T) {
+	cfg := Default()
+	cfg.RateLimit.Strategy = "token_bucket"
+	cfg.RateLimit.Global.Burst = 50
+	assert.NoError(t, cfg.Validate())
+
+	cfg.RateLimit.Strategy = "fixed_window"
+	cfg.RateLimit.Endpoints = []EndpointRateLimitRule{
+		{Path: "/api/v1/shorten", Strategy: "token_bucket", Limit: 10, Window: 60, Burst: 20},
+		{Path: "/:short_code", Limit: 10, Window: 60, Burst: -1},
+	}
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "rate_limit.global.burst: only applies to the token_bucket strategy")
+	assert.NotContains(t, err.Error(), "rate_limit.endpoints[0]")
+	assert.ErrorContains(t, err, "rate_limit.endpoints[1].burst: must not be negative")
+}
+
+// TestValidateConcurrency tests the in-flight request cap
+func TestValidateConcurrency(t *testing.T) {
+	cfg := Default()
+	cfg.Concurrency.MaxInFlight = 0
+	assert.NoError(t, cfg.Validate(), "checked only when enabled")
+
+	cfg.Concurrency.Enabled = true
+	cfg.Concurrency.QueueTimeout = -1
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "concurrency.max_in_flight")
+	assert.ErrorContains(t, err, "concurrency.queue_timeout")
+}
+
+// TestValidateAccessLog tests the sample rates
+func TestValidateAccessLog(t *testing.T) {
+	cfg := Default()
+	cfg.AccessLog.SampleRate = 2
+	assert.NoError(t, cfg.Validate(), "checked only when enabled")
+
+	cfg.AccessLog.Enabled = true
+	cfg.AccessLog.RedirectSampleRate = -0.5
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "access_log.sample_rate: must be between 0 and 1, got 2")
+	assert.ErrorContains(t, err, "access_log.redirect_sample_rate: must be between 0 and 1, got -0.5")
+}
+
+// TestValidateSnowflake tests the epoch and worker ID settings
+func TestValidateSnowflake(t *testing.T) {
+	cfg := Default()
+	cfg.Snowflake.Epoch = "2010-11-04"
+	cfg.Snowflake.WorkerID = 40
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "snowflake.epoch: must be an RFC3339 time")
+	assert.ErrorContains(t, err, "snowflake.worker_id")
+
+	// A leased worker ID replaces worker_id
+	cfg.Snowflake.Epoch = time.Now().Add(time.Hour).Format(time.RFC3339)
+	cfg.Snowflake.AutoWorkerID = true
+	cfg.Snowflake.LeaseTTL = 0
+	cfg.Snowflake.ClockTolerance = -1
+	err = cfg.Validate()
+	assert.ErrorContains(t, err, "snowflake.epoch: must not be in the future")
+	assert.ErrorContains(t, err, "snowflake.lease_ttl")
+	assert.ErrorContains(t, err, "snowflake.clock_tolerance")
+	assert.NotContains(t, err.Error(), "snowflake.worker_id")
+
+	cfg.Snowflake.Epoch = "2024-01-01T00:00:00Z"
+	cfg.Snowflake.LeaseTTL = 30
+	cfg.Snowflake.ClockTolerance = 0
+	assert.NoError(t, cfg.Validate())
+}
+
+// TestEnvName tests env variable naming from YAML paths
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "MYSQL_PASSWORD", envNameForPath("mysql.password"))
+	assert.Equal(t, "RATE_LIMIT_GLOBAL_LIMIT", envNameForPath("rate_limit.global.limit"))
+}
+
+// TestValidateMemcached tests the Memcached cache backend settings
+func TestValidateMemcached(t *testing.T) {
+	cfg := Default()
+	cfg.Cache.Backend = "memcache"
+	assert.ErrorContains(t, cfg.Validate(), "cache.backend")
+
+	cfg.Cache.Backend = "memcached"
+	assert.ErrorContains(t, cfg.Validate(), "cache.memcached.servers is required")
+
+	cfg.Cache.Memcached.Servers = []string{"memcached:11211", "memcached-2"}
+	cfg.Cache.Memcached.Timeout = 0
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "cache.memcached.servers[1]")
+	assert.ErrorContains(t, err, "cache.memcached.timeout")
+
+	cfg.Cache.Memcached.Servers = []string{"memcached:11211"}
+	cfg.Cache.Memcached.Timeout = 500
+	assert.NoError(t, cfg.Validate())
+}
+
+// TestValidateDiskCache tests the disk tier settings
+func TestValidateDiskCache(t *testing.T) {
+	cfg := Default()
+	cfg.Cache.Disk.Path = ""
+	assert.NoError(t, cfg.Validate(), "checked only when enabled")
+
+	cfg.Cache.Disk.Enabled = true
+	cfg.Cache.Disk.MaxBytes = 0
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "cache.disk.path")
+	assert.ErrorContains(t, err, "cache.disk.max_bytes")
+}
+
+// TestValidatePrivacy tests the visitor IP storage settings
+func TestValidatePrivacy(t *testing.T) {
+	cfg := Default()
+	cfg.Privacy.IPStorage = "mask"
+	assert.ErrorContains(t, cfg.Validate(), "privacy.ip_storage")
+
+	cfg.Privacy.IPStorage = "hash"
+	assert.ErrorContains(t, cfg.Validate(), "privacy.ip_hash_salt")
+
+	cfg.Privacy.IPHashSalt = "pepper"
+	assert.NoError(t, cfg.Validate())
+}
+
+// TestValidateDataRequests tests the data subject request settings
+func TestValidateDataRequests(t *testing.T) {
+	cfg := Default()
+	cfg.Privacy.DataRequests.JobTTL = 0
+	cfg.Privacy.DataRequests.WebhookURL = "hooks.example.com/gdpr"
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "privacy.data_requests.job_ttl")
+	assert.ErrorContains(t, err, "privacy.data_requests.webhook_url")
+
+	cfg.Privacy.DataRequests.JobTTL = 3600
+	cfg.Privacy.DataRequests.WebhookURL = "https://hooks.example.com/gdpr"
+	assert.NoError(t, cfg.Validate())
+}
+
+// TestValidateCaptcha tests the captcha settings
+func TestValidateCaptcha(t *testing.T) {
+	cfg := Default()
+	cfg.Captcha.Secret = ""
+	assert.NoError(t, cfg.Validate(), "checked only when a provider is set")
+
+	cfg.Captcha.Provider = "turnstile"
+	cfg.Captcha.MinScore = 2
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "captcha.provider")
+	assert.ErrorContains(t, err, "captcha.secret")
+	assert.ErrorContains(t, err, "captcha.min_score")
+
+	cfg.Captcha.Provider = "hcaptcha"
+	cfg.Captcha.Secret = "0x0000"
+	cfg.Captcha.MinScore = 0.5
+	assert.NoError(t, cfg.Validate())
+}
+
+// TestValidateScanProtection tests the enumeration tarpit settings
+func TestValidateScanProtection(t *testing.T) {
+	cfg := Default()
+	cfg.ScanProtection.Threshold = 0
+	assert.NoError(t, cfg.Validate(), "checked only when enabled")
+
+	cfg.ScanProtection.Enabled = true
+	cfg.ScanProtection.BaseDelay = 20000
+	cfg.ScanProtection.MaxDelay = cfg.Timeouts.Request
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "scan_protection.threshold")
+	assert.ErrorContains(t, err, "scan_protection.base_delay")
+	assert.ErrorContains(t, err, "scan_protection.max_delay")
+
+	cfg = Default()
+	cfg.ScanProtection.Enabled = true
+	cfg.ScanProtection.BanAfter = cfg.ScanProtection.Threshold
+	assert.ErrorContains(t, cfg.Validate(), "scan_protection.ban_after")
+
+	cfg.ScanProtection.BanAfter = 0
+	cfg.ScanProtection.BanDuration = 0
+	assert.NoError(t, cfg.Validate(), "ban_duration is unused without bans")
+}
+
+// TestValidateRedirectDelay tests the global countdown bounds
+func TestValidateRedirectDelay(t *testing.T) {
+	cfg := Default()
+	cfg.RedirectDelay.Seconds = 61
+	assert.ErrorContains(t, cfg.Validate(), "redirect_delay.seconds")
+
+	cfg.RedirectDelay.Seconds = 5
+	assert.NoError(t, cfg.Validate())
+}
+
+// TestValidateSignedLinks tests the signed link settings
+func TestValidateSignedLinks(t *testing.T) {
+	cfg := Default()
+	cfg.SignedLinks.RequireAll = true
+	assert.ErrorContains(t, cfg.Validate(), "signed_links.require_all")
+
+	cfg.SignedLinks.Secret = "too-short"
+	cfg.SignedLinks.MaxTTL = 0
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "signed_links.secret")
+	assert.ErrorContains(t, err, "signed_links.max_ttl")
+
+	cfg.SignedLinks.Secret = "0123456789abcdef0123456789abcdef"
+	cfg.SignedLinks.MaxTTL = 3600
+	assert.NoError(t, cfg.Validate())
+}
+
+// TestValidateNamespaces tests alias namespace names and reuse
+func TestValidateNamespaces(t *testing.T) {
+	cfg := Default()
+	cfg.Server.RedirectPrefix = "/go"
+	cfg.Auth.Namespaces = map[string]string{"alice": "Acme", "bob": "api", "carol": "go"}
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, `"Acme"`)
+	assert.ErrorContains(t, err, `"api" is used by another route`)
+	assert.ErrorContains(t, err, `"go" is used by another route`)
+
+	cfg.Auth.Namespaces = map[string]string{"alice": "acme", "bob": "acme"}
+	assert.ErrorContains(t, cfg.Validate(), `"acme" is assigned to both`)
+
+	cfg.Auth.Namespaces = map[string]string{"alice": "acme", "bob": "globex"}
+	assert.NoError(t, cfg.Validate())
+}
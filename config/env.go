@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// Environment Variable Overrides
+// ============================================================================
+// Every scalar field can be set from the environment. The variable name is
+// the field's YAML path, upper-cased and joined with underscores:
+//
+//   mysql.password           -> MYSQL_PASSWORD
+//   server.port              -> SERVER_PORT
+//   rate_limit.global.limit  -> RATE_LIMIT_GLOBAL_LIMIT
+//
+// String lists are comma-separated (SERVER_TRUSTED_PROXIES=10.0.0.0/8,::1).
+// Maps and lists of objects (rate_limit.endpoints, mysql.replicas, ...) can
+// only be set in the config file.
+//
+// Precedence: environment > config file > defaults.
+// ============================================================================
+
+// applyEnv overrides cfg fields with environment variables
+// Returns one error per variable that couldn't be parsed
+func applyEnv(cfg *Config) []error {
+	return applyEnvStruct(reflect.ValueOf(cfg).Elem(), "")
+}
+
+// applyEnvStruct walks the fields of a struct, building env names from prefix
+func applyEnvStruct(v reflect.Value, prefix string) []error {
+	var errs []error
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name := EnvName(prefix, tag)
+		fv := v.Field(i)
+
+		if fv.Kind() == reflect.Struct {
+			errs = append(errs, applyEnvStruct(fv, name)...)
+			continue
+		}
+
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setFromString(fv, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errs
+}
+
+// EnvName returns the environment variable name for a YAML key under prefix
+func EnvName(prefix, key string) string {
+	name := strings.ToUpper(key)
+	if prefix == "" {
+		return name
+	}
+	return prefix + "_" + name
+}
+
+// setFromString parses raw into a scalar or string-list field
+func setFromString(fv reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", raw)
+		}
+		fv.SetUint(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("can only be set in the config file")
+		}
+		items := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		fv.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("can only be set in the config file")
+	}
+	return nil
+}
@@ -0,0 +1,669 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// redirectPrefixPattern matches server.redirect_prefix: one or more path
+// segments, without a trailing slash
+var redirectPrefixPattern = regexp.MustCompile(`^(/[A-Za-z0-9_-]+)+$`)
+
+// namespacePattern matches an alias namespace in auth.namespaces
+var namespacePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// reservedNamespaces are first path segments taken by other routes
+var reservedNamespaces = map[string]bool{
+	"api": true, "admin": true, "health": true, "healthz": true, "readyz": true, "metrics": true,
+}
+
+// minRecyclingQuarantineDays is the shortest time a link must have been
+// expired before its code may be recycled
+const minRecyclingQuarantineDays = 30
+
+// maxMySQLShards is the most shards mysql.shards may list
+const maxMySQLShards = 256
+
+// ValidationError lists every invalid or missing setting found by Validate
+type ValidationError struct {
+	Problems []string
+}
+
+// Error implements error, one problem per line
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration (%d problems):\n  - %s",
+		len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+// Validate checks the whole configuration and reports all problems at once,
+// so a misconfigured deployment fails on the first start rather than one
+// field at a time
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	// Server
+	v.port("server.port", c.Server.Port)
+	v.oneOf("server.mode", c.Server.Mode, "debug", "release", "test")
+	v.positive("server.max_body_bytes", int(c.Server.MaxBodyBytes))
+	v.nonNegative("server.read_timeout", c.Server.ReadTimeout)
+	v.positive("server.read_header_timeout", c.Server.ReadHeaderTimeout)
+	v.nonNegative("server.write_timeout", c.Server.WriteTimeout)
+	v.nonNegative("server.idle_timeout", c.Server.IdleTimeout)
+	v.positive("server.max_header_bytes", c.Server.MaxHeaderBytes)
+	v.positive("server.shutdown_timeout", c.Server.ShutdownTimeout)
+	// A shorter write timeout would cut off the 503 of a timed-out request
+	if w := c.Server.WriteTimeout; w > 0 && w*1000 < c.Timeouts.Request {
+		v.add("server.write_timeout: must be at least timeouts.request (%dms), got %ds", c.Timeouts.Request, w)
+	}
+	if c.Server.BaseURL != "" {
+		if u, err := url.Parse(c.Server.BaseURL); err != nil || u.Host == "" ||
+			(u.Scheme != "http" && u.Scheme != "https") {
+			v.add("server.base_url: must be an absolute http(s) URL, got %q", c.Server.BaseURL)
+		}
+	}
+	if p := c.Server.RedirectPrefix; p != "" {
+		first := strings.Split(strings.TrimPrefix(p, "/"), "/")[0]
+		switch {
+		case !redirectPrefixPattern.MatchString(p):
+			v.add("server.redirect_prefix: must be a path like /r (letters, digits, - and _), got %q", p)
+		case first == "api" || first == "admin":
+			v.add("server.redirect_prefix: %q is used by the API", p)
+		}
+	}
+	for i, d := range c.Server.Domains {
+		if d.Host == "" {
+			v.add("server.domains[%d].host: required", i)
+		}
+		if d.Scheme != "" {
+			v.oneOf(fmt.Sprintf("server.domains[%d].scheme", i), d.Scheme, "http", "https")
+		}
+	}
+	if tls := c.Server.TLS; tls.Enabled {
+		if tls.Autocert.Enabled {
+			if len(tls.Autocert.Domains) == 0 {
+				v.add("server.tls.autocert.domains: required when autocert is enabled")
+			}
+			if tls.Autocert.CacheDir == "" {
+				v.add("server.tls.autocert.cache_dir: required when autocert is enabled")
+			}
+		} else if tls.CertFile == "" || tls.KeyFile == "" {
+			v.add("server.tls: cert_file and key_file are required unless autocert is enabled")
+		}
+		if tls.RedirectHTTP {
+			v.port("server.tls.http_port", tls.HTTPPort)
+		}
+	}
+
+	// MySQL
+	v.required("mysql.host", c.MySQL.Host)
+	v.port("mysql.port", c.MySQL.Port)
+	v.required("mysql.username", c.MySQL.Username)
+	v.required("mysql.database", c.MySQL.Database)
+	v.positive("mysql.max_idle_conns", c.MySQL.MaxIdleConns)
+	v.positive("mysql.max_open_conns", c.MySQL.MaxOpenConns)
+	v.nonNegative("mysql.conn_max_lifetime", c.MySQL.ConnMaxLifetime)
+	v.nonNegative("mysql.conn_max_idle_time", c.MySQL.ConnMaxIdleTime)
+	v.nonNegative("mysql.ping_interval", c.MySQL.PingInterval)
+	for i, r := range c.MySQL.Replicas {
+		v.required(fmt.Sprintf("mysql.replicas[%d].host", i), r.Host)
+		v.port(fmt.Sprintf("mysql.replicas[%d].port", i), r.Port)
+	}
+	if len(c.MySQL.Shards) > 0 {
+		// Outbox event IDs carry the shard's position in one byte
+		if len(c.MySQL.Shards) > maxMySQLShards {
+			v.add("mysql.shards: at most %d shards, got %d", maxMySQLShards, len(c.MySQL.Shards))
+		}
+		names := make(map[string]bool, len(c.MySQL.Shards))
+		dsns := make(map[string]string, len(c.MySQL.Shards))
+		placed := 0
+		for i, s := range c.MySQL.Shards {
+			field := fmt.Sprintf("mysql.shards[%d]", i)
+			v.required(field+".name", s.Name)
+			if s.Name != "" && names[s.Name] {
+				v.add("%s.name: duplicate shard %q", field, s.Name)
+			}
+			names[s.Name] = true
+			if s.Port != 0 {
+				v.port(field+".port", s.Port)
+			}
+			dsn := c.MySQL.ShardDSN(s)
+			if other, ok := dsns[dsn]; ok {
+				v.add("%s: same database as shard %q", field, other)
+			}
+			dsns[dsn] = s.Name
+			if s.Draining {
+				if !c.MySQL.Rebalancing {
+					v.add("%s.draining: requires mysql.rebalancing until its links are moved", field)
+				}
+			} else {
+				placed++
+			}
+		}
+		if placed == 0 {
+			v.add("mysql.shards: at least one shard must not be draining")
+		}
+	}
+
+	// Redis
+	v.required("redis.host", c.Redis.Host)
+	v.port("redis.port", c.Redis.Port)
+	v.nonNegative("redis.db", c.Redis.DB)
+	v.positive("redis.pool_size", c.Redis.PoolSize)
+	v.nonNegative("redis.slow_command_threshold", c.Redis.SlowCommandThreshold)
+
+	// Cache
+	v.oneOf("cache.backend", c.Cache.Backend, "redis", "memcached")
+	if c.Cache.Backend == "memcached" {
+		if len(c.Cache.Memcached.Servers) == 0 {
+			v.add("cache.memcached.servers is required when cache.backend is memcached")
+		}
+		for i, server := range c.Cache.Memcached.Servers {
+			if _, _, err := net.SplitHostPort(server); err != nil {
+				v.add("cache.memcached.servers[%d] must be host:port, got %q", i, server)
+			}
+		}
+		v.positive("cache.memcached.timeout", c.Cache.Memcached.Timeout)
+		v.nonNegative("cache.memcached.max_idle_conns", c.Cache.Memcached.MaxIdleConns)
+	}
+	v.positive("cache.ttl", c.Cache.TTL)
+	v.nonNegative("cache.ttl_jitter", c.Cache.TTLJitter)
+	if c.Cache.Local.Enabled {
+		v.positive("cache.local.size", c.Cache.Local.Size)
+		v.positive("cache.local.ttl", c.Cache.Local.TTL)
+	}
+	if c.Cache.Disk.Enabled {
+		v.required("cache.disk.path", c.Cache.Disk.Path)
+		v.positive("cache.disk.max_entries", c.Cache.Disk.MaxEntries)
+		v.positive("cache.disk.max_bytes", c.Cache.Disk.MaxBytes)
+		v.positive("cache.disk.sync_interval", c.Cache.Disk.SyncInterval)
+	}
+	if c.Cache.Warmup.Enabled {
+		v.positive("cache.warmup.links", c.Cache.Warmup.Links)
+		v.positive("cache.warmup.concurrency", c.Cache.Warmup.Concurrency)
+	}
+
+	// Bloom filter
+	if c.BloomFilter.Capacity == 0 {
+		v.add("bloom_filter.capacity: must be greater than 0")
+	}
+	if c.BloomFilter.FalsePositiveRate <= 0 || c.BloomFilter.FalsePositiveRate >= 1 {
+		v.add("bloom_filter.false_positive_rate: must be between 0 and 1, got %v", c.BloomFilter.FalsePositiveRate)
+	}
+	v.nonNegative("bloom_filter.rebuild_interval", c.BloomFilter.RebuildInterval)
+
+	// Short code generation
+	v.oneOf("id_generator.strategy", c.IDGenerator.Strategy, "snowflake", "random", "sequence")
+	switch c.IDGenerator.Strategy {
+	case "snowflake":
+		// 5 bits each
+		v.between("snowflake.datacenter_id", c.Snowflake.DatacenterID, 0, 31)
+		if c.Snowflake.AutoWorkerID {
+			v.positive("snowflake.lease_ttl", c.Snowflake.LeaseTTL)
+		} else {
+			v.between("snowflake.worker_id", c.Snowflake.WorkerID, 0, 31)
+		}
+		v.nonNegative("snowflake.clock_tolerance", c.Snowflake.ClockTolerance)
+		if epoch, err := c.Snowflake.EpochTime(); err != nil {
+			v.add("snowflake.epoch: must be an RFC3339 time, got %q", c.Snowflake.Epoch)
+		} else if epoch.After(time.Now()) {
+			v.add("snowflake.epoch: must not be in the future, got %s", c.Snowflake.Epoch)
+		}
+	case "random":
+		v.between("id_generator.random_length", int64(c.IDGenerator.RandomLength), 4, 15)
+	case "sequence":
+		if c.IDGenerator.SequenceOffset < 0 {
+			v.add("id_generator.sequence_offset: must not be negative, got %d", c.IDGenerator.SequenceOffset)
+		}
+		if c.IDGenerator.Obfuscate {
+			bits := c.IDGenerator.ObfuscationBits
+			v.between("id_generator.obfuscation_bits", int64(bits), 8, 63)
+			if bits >= 8 && bits <= 63 && c.IDGenerator.SequenceOffset >= int64(1)<<bits {
+				v.add("id_generator.sequence_offset: must be below 2^obfuscation_bits")
+			}
+		}
+	}
+	if c.IDGenerator.Obfuscate {
+		if c.IDGenerator.Strategy == "random" {
+			v.add("id_generator.obfuscate: only applies to the snowflake and sequence strategies")
+		}
+		v.required("id_generator.obfuscation_key", c.IDGenerator.ObfuscationKey)
+	}
+
+	// Rate limiting
+	if rl := c.RateLimit; rl.Enabled {
+		strategies := []string{"fixed_window", "sliding_window", "token_bucket", "sliding_window_counter"}
+		v.oneOf("rate_limit.strategy", rl.Strategy, strategies...)
+		v.oneOf("rate_limit.backend", rl.Backend, "redis", "memory")
+		v.oneOf("rate_limit.failure_mode", rl.FailureMode, "open", "closed")
+		v.oneOf("rate_limit.key_by", rl.KeyBy, "ip", "api_key", "user")
+		v.positive("rate_limit.global.limit", rl.Global.Limit)
+		v.positive("rate_limit.global.window", rl.Global.Window)
+		v.burst("rate_limit.global.burst", rl.Global.Burst, rl.Strategy)
+		for i, e := range rl.Endpoints {
+			name := fmt.Sprintf("rate_limit.endpoints[%d]", i)
+			v.required(name+".path", e.Path)
+			if e.Strategy != "" {
+				v.oneOf(name+".strategy", e.Strategy, strategies...)
+			}
+			if e.FailureMode != "" {
+				v.oneOf(name+".failure_mode", e.FailureMode, "open", "closed")
+			}
+			v.positive(name+".limit", e.Limit)
+			v.positive(name+".window", e.Window)
+			strategy := rl.Strategy
+			if e.Strategy != "" {
+				strategy = e.Strategy
+			}
+			v.burst(name+".burst", e.Burst, strategy)
+		}
+		for name, tier := range rl.Tiers {
+			v.burst("rate_limit.tiers."+name+".burst", tier.Burst, rl.Strategy)
+		}
+		for key, tier := range rl.APIKeyTiers {
+			if _, ok := rl.Tiers[tier]; !ok {
+				v.add("rate_limit.api_key_tiers: key %q uses undefined tier %q", maskSecret(key), tier)
+			}
+		}
+	}
+
+	// Auth
+	for key, userID := range c.Auth.APIKeys {
+		if userID == "" || len(userID) > 128 {
+			v.add("auth.api_keys: key %q must map to a user ID of 1-128 characters", maskSecret(key))
+		}
+	}
+	owners := make(map[string]string, len(c.Auth.Namespaces))
+	for userID, namespace := range c.Auth.Namespaces {
+		switch {
+		case !namespacePattern.MatchString(namespace):
+			v.add("auth.namespaces: user %q must map to a namespace of 1-64 lowercase letters, digits, - and _, got %q", userID, namespace)
+		case reservedNamespaces[namespace] || "/"+namespace == c.Server.RedirectPrefix ||
+			strings.HasPrefix(c.Server.RedirectPrefix, "/"+namespace+"/"):
+			v.add("auth.namespaces: %q is used by another route", namespace)
+		case owners[namespace] != "":
+			v.add("auth.namespaces: %q is assigned to both %q and %q", namespace, owners[namespace], userID)
+		default:
+			owners[namespace] = userID
+		}
+	}
+
+	// Privacy
+	v.oneOf("privacy.ip_storage", c.Privacy.IPStorage, "full", "truncate", "hash")
+	if c.Privacy.IPStorage == "hash" {
+		v.required("privacy.ip_hash_salt", c.Privacy.IPHashSalt)
+	}
+	d := c.Privacy.DataRequests
+	v.positive("privacy.data_requests.job_ttl", d.JobTTL)
+	if d.WebhookURL != "" {
+		if u, err := url.Parse(d.WebhookURL); err != nil || u.Host == "" ||
+			(u.Scheme != "http" && u.Scheme != "https") {
+			v.add("privacy.data_requests.webhook_url: must be an absolute http(s) URL, got %q", d.WebhookURL)
+		}
+		v.positive("privacy.data_requests.webhook_timeout", d.WebhookTimeout)
+	}
+
+	// Enumeration tarpit
+	if s := c.ScanProtection; s.Enabled {
+		v.positive("scan_protection.threshold", s.Threshold)
+		v.positive("scan_protection.window", s.Window)
+		v.positive("scan_protection.base_delay", s.BaseDelay)
+		v.positive("scan_protection.max_delay", s.MaxDelay)
+		if s.BaseDelay > s.MaxDelay {
+			v.add("scan_protection.base_delay: must not exceed max_delay (%dms), got %dms", s.MaxDelay, s.BaseDelay)
+		}
+		v.nonNegative("scan_protection.ban_after", s.BanAfter)
+		if s.BanAfter > 0 {
+			v.positive("scan_protection.ban_duration", s.BanDuration)
+			if s.BanAfter <= s.Threshold {
+				v.add("scan_protection.ban_after: must be greater than threshold (%d), got %d", s.Threshold, s.BanAfter)
+			}
+		}
+		if s.MaxDelay > 0 && s.MaxDelay >= c.Timeouts.Request {
+			v.add("scan_protection.max_delay: must be less than timeouts.request (%dms), got %dms", c.Timeouts.Request, s.MaxDelay)
+		}
+	}
+
+	// Captcha
+	if c.Captcha.Provider != "" {
+		v.oneOf("captcha.provider", c.Captcha.Provider, "hcaptcha", "recaptcha")
+		v.required("captcha.secret", c.Captcha.Secret)
+		v.positive("captcha.timeout", c.Captcha.Timeout)
+		if s := c.Captcha.MinScore; s < 0 || s > 1 {
+			v.add("captcha.min_score: must be between 0 and 1, got %v", s)
+		}
+	}
+
+	// Signed links
+	if s := c.SignedLinks; s.Secret != "" {
+		if len(s.Secret) < 32 {
+			v.add("signed_links.secret: must be at least 32 characters, got %d", len(s.Secret))
+		}
+		v.positive("signed_links.max_ttl", s.MaxTTL)
+	} else if s.RequireAll {
+		v.add("signed_links.require_all: needs signed_links.secret")
+	}
+
+	// Visit logs
+	v.nonNegative("visit_log.retention_days", c.VisitLog.RetentionDays)
+	v.nonNegative("visit_log.partition_days_ahead", c.VisitLog.PartitionDaysAhead)
+	v.nonNegative("visit_log.cleanup_interval", c.VisitLog.CleanupInterval)
+	v.nonNegative("visit_log.rollup_interval", c.VisitLog.RollupInterval)
+	v.nonNegative("visit_log.rollup_lookback_days", c.VisitLog.RollupLookbackDays)
+	if c.VisitLog.RollupInterval > 0 && c.VisitLog.RetentionDays > 0 &&
+		c.VisitLog.RollupLookbackDays >= c.VisitLog.RetentionDays {
+		// Re-rolling a day whose logs were removed would empty its rollups
+		v.add("visit_log.rollup_lookback_days must be less than visit_log.retention_days")
+	}
+
+	// Deleted links
+	v.nonNegative("deleted_links.purge_after_days", c.DeletedLinks.PurgeAfterDays)
+	v.nonNegative("deleted_links.purge_interval", c.DeletedLinks.PurgeInterval)
+
+	// Short code recycling
+	if r := c.ShortCodes.Recycling; r.Enabled {
+		if r.QuarantineDays < minRecyclingQuarantineDays {
+			// Shorter and people may still have the old link bookmarked
+			v.add("short_codes.recycling.quarantine_days: must be at least %d, got %d", minRecyclingQuarantineDays, r.QuarantineDays)
+		}
+		v.positive("short_codes.recycling.interval", r.Interval)
+		v.positive("short_codes.recycling.batch_size", r.BatchSize)
+	}
+
+	// Import
+	v.positive("import.max_bytes", int(c.Import.MaxBytes))
+	v.nonNegative("import.async_threshold", int(c.Import.AsyncThreshold))
+	v.positive("import.job_ttl", c.Import.JobTTL)
+
+	// Destinations
+	if c.Destinations.BlockPrivate {
+		v.positive("destinations.resolve_timeout", c.Destinations.ResolveTimeout)
+		for i, cidr := range c.Destinations.AllowedCIDRs {
+			if _, err := netip.ParsePrefix(cidr); err != nil {
+				v.add("destinations.allowed_cidrs[%d]: must be a CIDR like 10.0.0.0/8, got %q", i, cidr)
+			}
+		}
+	}
+	if c.Destinations.UpgradeHTTPS {
+		v.positive("destinations.upgrade_timeout", c.Destinations.UpgradeTimeout)
+	}
+
+	// Redirect countdown
+	v.between("redirect_delay.seconds", int64(c.RedirectDelay.Seconds), 0, 60)
+
+	// Timeouts
+	v.positive("timeouts.redirect", c.Timeouts.Redirect)
+	v.positive("timeouts.visit_write", c.Timeouts.VisitWrite)
+	v.positive("timeouts.request", c.Timeouts.Request)
+
+	// Degraded mode
+	if c.DegradedMode.Enabled {
+		v.positive("degraded_mode.check_interval", c.DegradedMode.CheckInterval)
+		v.positive("degraded_mode.replay_interval", c.DegradedMode.ReplayInterval)
+	}
+
+	// Write-behind creation
+	if w := c.WriteBehind; w.Enabled {
+		v.positive("write_behind.workers", w.Workers)
+		v.positive("write_behind.poll_interval", w.PollInterval)
+		v.positive("write_behind.max_attempts", w.MaxAttempts)
+		v.positive("write_behind.reconcile_interval", w.ReconcileInterval)
+	}
+
+	// Transactional outbox
+	if o := c.Outbox; o.Enabled {
+		v.positive("outbox.interval", o.Interval)
+		v.positive("outbox.batch_size", o.BatchSize)
+		v.positive("outbox.max_attempts", o.MaxAttempts)
+		v.positive("outbox.lease", o.Lease)
+		v.nonNegative("outbox.retention_days", o.RetentionDays)
+		if o.WebhookURL != "" {
+			if u, err := url.Parse(o.WebhookURL); err != nil || u.Host == "" ||
+				(u.Scheme != "http" && u.Scheme != "https") {
+				v.add("outbox.webhook_url: must be an absolute http(s) URL, got %q", o.WebhookURL)
+			}
+			v.positive("outbox.webhook_timeout", o.WebhookTimeout)
+			// A dispatcher stops starting calls at half the lease; the last
+			// one must finish before another dispatcher can claim the event
+			if o.Lease > 0 && o.WebhookTimeout >= o.Lease*1000/2 {
+				v.add("outbox.webhook_timeout: must be below half of outbox.lease (%d ms), got %d", o.Lease*1000/2, o.WebhookTimeout)
+			}
+		}
+	}
+
+	// Abuse detection and reports
+	a := c.Abuse
+	if a.WebhookURL != "" {
+		if u, err := url.Parse(a.WebhookURL); err != nil || u.Host == "" ||
+			(u.Scheme != "http" && u.Scheme != "https") {
+			v.add("abuse.webhook_url: must be an absolute http(s) URL, got %q", a.WebhookURL)
+		}
+		v.positive("abuse.webhook_timeout", a.WebhookTimeout)
+	}
+	v.nonNegative("abuse.report_threshold", a.ReportThreshold)
+	if a.ReportThreshold > 0 {
+		v.oneOf("abuse.report_action", a.ReportAction, "warn", "disable")
+	}
+	if a.Enabled {
+		v.positive("abuse.flag_cooldown", a.FlagCooldown)
+		v.positive("abuse.throttle_limit", a.ThrottleLimit)
+		v.positive("abuse.throttle_duration", a.ThrottleDuration)
+		for _, policy := range []struct {
+			name string
+			AbusePolicyConfig
+		}{
+			{"spike", a.Policies.Spike},
+			{"single_ip", a.Policies.SingleIP},
+			{"datacenter", a.Policies.Datacenter},
+		} {
+			p := policy.AbusePolicyConfig
+			if !p.Enabled {
+				continue
+			}
+			prefix := "abuse.policies." + policy.name
+			v.positive(prefix+".window", p.Window)
+			v.positive(prefix+".threshold", p.Threshold)
+			v.oneOf(prefix+".action", p.Action, "flag", "throttle", "warn", "disable")
+		}
+		if a.Policies.Spike.Enabled && a.Policies.Spike.Factor <= 1 {
+			v.add("abuse.policies.spike.factor: must be greater than 1, got %v", a.Policies.Spike.Factor)
+		}
+		if d := a.Policies.Datacenter; d.Enabled && (d.Ratio <= 0 || d.Ratio > 1) {
+			v.add("abuse.policies.datacenter.ratio: must be in (0, 1], got %v", d.Ratio)
+		}
+	}
+
+	// Leaderboard
+	if c.Leaderboard.Enabled {
+		v.positive("leaderboard.size", c.Leaderboard.Size)
+		v.positive("leaderboard.trim_interval", c.Leaderboard.TrimInterval)
+	}
+
+	// Concurrency limit
+	if c.Concurrency.Enabled {
+		v.positive("concurrency.max_in_flight", c.Concurrency.MaxInFlight)
+		v.nonNegative("concurrency.queue_timeout", c.Concurrency.QueueTimeout)
+	}
+
+	// Access log
+	if c.AccessLog.Enabled {
+		if r := c.AccessLog.SampleRate; r < 0 || r > 1 {
+			v.add("access_log.sample_rate: must be between 0 and 1, got %v", r)
+		}
+		if r := c.AccessLog.RedirectSampleRate; r < 0 || r > 1 {
+			v.add("access_log.redirect_sample_rate: must be between 0 and 1, got %v", r)
+		}
+	}
+
+	// Idempotency keys
+	if c.Idempotency.Enabled {
+		v.positive("idempotency.ttl", c.Idempotency.TTL)
+		v.positive("idempotency.lock_timeout", c.Idempotency.LockTimeout)
+		if l := c.Idempotency.LockTimeout; l > 0 && l*1000 < c.Timeouts.Request {
+			v.add("idempotency.lock_timeout: must be at least timeouts.request (%dms), got %ds", c.Timeouts.Request, l)
+		}
+	}
+
+	// Summary reports
+	if r := c.Reports; r.Enabled {
+		v.positive("reports.check_interval", r.CheckInterval)
+		v.positive("reports.top_links", r.TopLinks)
+		v.positive("reports.webhook_timeout", r.WebhookTimeout)
+		if _, err := time.Parse("15:04", r.DailyAt); err != nil {
+			v.add("reports.daily_at: must be HH:MM, got %q", r.DailyAt)
+		}
+		v.oneOf("reports.weekly_day", strings.ToLower(r.WeeklyDay),
+			"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday")
+		if r.SMTP.Host != "" {
+			v.port("reports.smtp.port", r.SMTP.Port)
+			v.required("reports.smtp.from", r.SMTP.From)
+		}
+	}
+
+	// Link health checks
+	if h := c.LinkHealth; h.Enabled {
+		v.positive("link_health.interval", h.Interval)
+		v.positive("link_health.recheck", h.Recheck)
+		v.positive("link_health.batch_size", h.BatchSize)
+		v.positive("link_health.concurrency", h.Concurrency)
+		v.positive("link_health.timeout", h.Timeout)
+		v.nonNegative("link_health.notify_after", h.NotifyAfter)
+		if h.WebhookURL != "" {
+			if u, err := url.Parse(h.WebhookURL); err != nil || u.Host == "" ||
+				(u.Scheme != "http" && u.Scheme != "https") {
+				v.add("link_health.webhook_url: must be an absolute http(s) URL, got %q", h.WebhookURL)
+			}
+			v.positive("link_health.webhook_timeout", h.WebhookTimeout)
+		}
+	}
+
+	// Link titles
+	if t := c.LinkTitles; t.Enabled {
+		v.positive("link_titles.timeout", t.Timeout)
+		v.positive("link_titles.max_body_bytes", int(t.MaxBodyBytes))
+		v.positive("link_titles.concurrency", t.Concurrency)
+	}
+
+	// Events
+	switch c.Events.Backend {
+	case "", "none":
+	case "kafka":
+		if len(c.Events.Kafka.Brokers) == 0 {
+			v.add("events.kafka.brokers: required when events.backend is kafka")
+		}
+		v.required("events.kafka.topic", c.Events.Kafka.Topic)
+	case "nats":
+		v.required("events.nats.url", c.Events.NATS.URL)
+		v.required("events.nats.subject", c.Events.NATS.Subject)
+	default:
+		v.add("events.backend: must be one of none, kafka, nats, got %q", c.Events.Backend)
+	}
+
+	// gRPC
+	if c.GRPC.Enabled {
+		v.port("grpc.port", c.GRPC.Port)
+		if c.GRPC.Port == c.Server.Port {
+			v.add("grpc.port: must differ from server.port (%d)", c.Server.Port)
+		}
+	}
+
+	// Tracing
+	if c.Tracing.Enabled {
+		v.required("tracing.service_name", c.Tracing.ServiceName)
+		v.required("tracing.endpoint", c.Tracing.Endpoint)
+		if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+			v.add("tracing.sample_ratio: must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+		}
+	}
+
+	// Debug listener
+	if c.Debug.Enabled {
+		v.required("debug.addr", c.Debug.Addr)
+	}
+
+	return v.err()
+}
+
+// validator collects problems found by Validate
+type validator struct {
+	problems []string
+}
+
+func (v *validator) add(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+func (v *validator) required(name, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add("%s: required (env %s)", name, envNameForPath(name))
+	}
+}
+
+func (v *validator) port(name string, port int) {
+	if port < 1 || port > 65535 {
+		v.add("%s: must be a port between 1 and 65535, got %d", name, port)
+	}
+}
+
+func (v *validator) positive(name string, n int) {
+	if n <= 0 {
+		v.add("%s: must be greater than 0, got %d", name, n)
+	}
+}
+
+func (v *validator) nonNegative(name string, n int) {
+	if n < 0 {
+		v.add("%s: must not be negative, got %d", name, n)
+	}
+}
+
+func (v *validator) between(name string, n, min, max int64) {
+	if n < min || n > max {
+		v.add("%s: must be between %d and %d, got %d", name, min, max, n)
+	}
+}
+
+func (v *validator) oneOf(name, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.add("%s: must be one of %s, got %q", name, strings.Join(allowed, ", "), value)
+}
+
+// burst checks a rate limit burst, which only token buckets have
+func (v *validator) burst(name string, burst int, strategy string) {
+	v.nonNegative(name, burst)
+	if burst > 0 && strategy != "token_bucket" {
+		v.add("%s: only applies to the token_bucket strategy, not %s", name, strategy)
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: v.problems}
+}
+
+// envNameForPath converts a dotted YAML path to its environment variable
+func envNameForPath(path string) string {
+	name := ""
+	for _, key := range strings.Split(path, ".") {
+		name = EnvName(name, key)
+	}
+	return name
+}
+
+// maskSecret hides all but the first characters of a secret in messages
+func maskSecret(s string) string {
+	if len(s) <= 4 {
+		return "****"
+	}
+	return s[:4] + "****"
+}
@@ -0,0 +1,211 @@
+//go:build integration
+
+// Package integration runs the HTTP API end to end against real MySQL and
+// Redis servers.
+//
+// TestMain starts MySQL and Redis in Docker containers for the run:
+//
+//	go test -tags integration ./integration/
+//
+// The tests are skipped when Docker isn't available. To use running servers
+// instead, set SHORTLINK_TEST_MYSQL_DSN to a MySQL database the tests may
+// migrate and write to and SHORTLINK_TEST_REDIS_ADDR to a Redis server.
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/config"
+	"github.com/Monthlyaway/short-link/internal/cache"
+	"github.com/Monthlyaway/short-link/internal/filter"
+	"github.com/Monthlyaway/short-link/internal/handler"
+	"github.com/Monthlyaway/short-link/internal/middleware"
+	"github.com/Monthlyaway/short-link/internal/repository"
+	"github.com/Monthlyaway/short-link/internal/router"
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// redirectLimit is the per-client redirect limit of the test server
+const redirectLimit = 20
+
+// server is the API under test with the dependencies it was built from
+type server struct {
+	engine *gin.Engine
+	repo   *repository.URLRepository
+	cache  *cache.RedisCache
+}
+
+// newServer wires the service like cmd/server does, with the routes the
+// tests use, or skips the test when no databases are available
+func newServer(t *testing.T) *server {
+	t.Helper()
+	if skipReason != "" {
+		t.Skip(skipReason)
+	}
+	ctx := context.Background()
+
+	repo, err := repository.NewURLRepository(mysqlDSN, nil, repository.PoolConfig{MaxIdleConns: 5, MaxOpenConns: 20})
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	require.NoError(t, repo.Migrate(ctx, "up"))
+
+	redisCache, err := cache.NewRedisCache(redisAddr, "", 0, 20)
+	require.NoError(t, err)
+	t.Cleanup(func() { redisCache.Close() })
+
+	urlService := service.NewURLService(repo, redisCache, filter.NewBloomFilter(100000, 0.01))
+	require.NoError(t, urlService.InitBloomFilter(ctx))
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	// A fresh in-memory store per server, so runs don't share limits
+	routes := router.NewBuilder(engine, middleware.NewMemoryStore(), &config.RateLimitConfig{
+		Enabled:     true,
+		Strategy:    "fixed_window",
+		FailureMode: "open",
+		KeyBy:       "ip",
+		Global:      config.RateLimitRule{Limit: 10000, Window: 60},
+		Endpoints: []config.EndpointRateLimitRule{
+			{Path: "/:short_code", Method: "GET", Limit: redirectLimit, Window: 60},
+		},
+	})
+
+	urlHandler := handler.NewURLHandler(urlService)
+	api := routes.Group("/api/v1")
+	api.POST("/shorten", urlHandler.CreateShortURL)
+	api.GET("/info/:short_code", urlHandler.GetURLInfo)
+	api.GET("/urls", urlHandler.ListURLs)
+	api.GET("/stats/:short_code", urlHandler.GetVisitStats)
+	routes.GET("/:short_code", urlHandler.RedirectToOriginalURL)
+
+	return &server{engine: engine, repo: repo, cache: redisCache}
+}
+
+// do sends a request from clientIP and returns the recorded response
+func (s *server) do(method, path, body, clientIP string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.RemoteAddr = clientIP + ":40000"
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36")
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, req)
+	return w
+}
+
+// create shortens url and returns the short code
+func (s *server) create(t *testing.T, body string) string {
+	t.Helper()
+	w := s.do(http.MethodPost, "/api/v1/shorten", body, "192.0.2.1")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		Data struct {
+			ShortCode string `json:"short_code"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotEmpty(t, resp.Data.ShortCode)
+	return resp.Data.ShortCode
+}
+
+// uniqueURL returns a destination no earlier run created
+func uniqueURL(name string) string {
+	return fmt.Sprintf("https://example.com/integration/%s/%d", name, time.Now().UnixNano())
+}
+
+// TestCreateRedirectStats tests the main flow: a new link redirects, and the
+// visit shows up in its stats
+func TestCreateRedirectStats(t *testing.T) {
+	s := newServer(t)
+	destination := uniqueURL("flow")
+	code := s.create(t, fmt.Sprintf(`{"url": %q}`, destination))
+
+	// Creating the same URL again returns the same link
+	assert.Equal(t, code, s.create(t, fmt.Sprintf(`{"url": %q}`, destination)))
+
+	w := s.do(http.MethodGet, "/"+code, "", "198.51.100.1")
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, destination, w.Header().Get("Location"))
+
+	// The second redirect is served from the cache
+	w = s.do(http.MethodGet, "/"+code, "", "198.51.100.1")
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Greater(t, s.cache.Stats().LocalHits+s.cache.Stats().RedisHits, uint64(0))
+
+	// Visits are recorded after the redirect is sent
+	assert.Eventually(t, func() bool {
+		w := s.do(http.MethodGet, "/api/v1/stats/"+code, "", "192.0.2.1")
+		var resp struct {
+			Data struct {
+				VisitCount uint64 `json:"visit_count"`
+			} `json:"data"`
+		}
+		return w.Code == http.StatusOK && json.Unmarshal(w.Body.Bytes(), &resp) == nil && resp.Data.VisitCount == 2
+	}, 5*time.Second, 100*time.Millisecond)
+
+	w = s.do(http.MethodGet, "/api/v1/info/"+code, "", "192.0.2.1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), destination)
+}
+
+// TestUnknownCode tests that unknown codes are 404s
+func TestUnknownCode(t *testing.T) {
+	s := newServer(t)
+	w := s.do(http.MethodGet, "/zzzzzzzzzz", "", "198.51.100.2")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// TestExpiry tests that a link stops redirecting once it expires, also when
+// it's cached
+func TestExpiry(t *testing.T) {
+	s := newServer(t)
+	expiredAt := time.Now().Add(2 * time.Second).UTC().Format(time.RFC3339)
+	code := s.create(t, fmt.Sprintf(`{"url": %q, "expired_at": %q}`, uniqueURL("expiry"), expiredAt))
+
+	assert.Equal(t, http.StatusFound, s.do(http.MethodGet, "/"+code, "", "198.51.100.3").Code)
+
+	time.Sleep(3 * time.Second)
+	assert.Equal(t, http.StatusNotFound, s.do(http.MethodGet, "/"+code, "", "198.51.100.3").Code)
+}
+
+// TestRedirectRateLimit tests the per-client redirect limit
+func TestRedirectRateLimit(t *testing.T) {
+	s := newServer(t)
+	code := s.create(t, fmt.Sprintf(`{"url": %q}`, uniqueURL("ratelimit")))
+
+	for i := 0; i < redirectLimit; i++ {
+		require.Equal(t, http.StatusFound, s.do(http.MethodGet, "/"+code, "", "198.51.100.4").Code, "request %d", i+1)
+	}
+	w := s.do(http.MethodGet, "/"+code, "", "198.51.100.4")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// Other clients have their own budget
+	assert.Equal(t, http.StatusFound, s.do(http.MethodGet, "/"+code, "", "198.51.100.5").Code)
+}
+
+// TestListHidesUnlisted tests that the anonymous listing leaves out
+// unlisted links
+func TestListHidesUnlisted(t *testing.T) {
+	s := newServer(t)
+	run := fmt.Sprint(time.Now().UnixNano())
+	public := s.create(t, fmt.Sprintf(`{"url": %q, "metadata": {"run": %q}}`, uniqueURL("public"), run))
+	unlisted := s.create(t, fmt.Sprintf(`{"url": %q, "metadata": {"run": %q}, "visibility": "unlisted"}`, uniqueURL("unlisted"), run))
+
+	w := s.do(http.MethodGet, "/api/v1/urls?meta.run="+run, "", "192.0.2.1")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), public)
+	assert.NotContains(t, w.Body.String(), unlisted)
+}
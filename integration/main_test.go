@@ -0,0 +1,134 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/cache"
+	"github.com/Monthlyaway/short-link/internal/repository"
+)
+
+// startTimeout bounds how long the containers may take to accept connections
+const startTimeout = 3 * time.Minute
+
+// Databases the tests run against, set up by TestMain
+var (
+	mysqlDSN   string
+	redisAddr  string
+	skipReason string // Set when no databases are available
+)
+
+// TestMain starts throwaway MySQL and Redis containers for the tests and
+// removes them afterwards; SHORTLINK_TEST_MYSQL_DSN and
+// SHORTLINK_TEST_REDIS_ADDR point the tests at running servers instead
+func TestMain(m *testing.M) {
+	mysqlDSN = os.Getenv("SHORTLINK_TEST_MYSQL_DSN")
+	redisAddr = os.Getenv("SHORTLINK_TEST_REDIS_ADDR")
+	if mysqlDSN != "" && redisAddr != "" {
+		os.Exit(m.Run())
+	}
+
+	if err := exec.Command("docker", "info").Run(); err != nil {
+		skipReason = fmt.Sprintf("Docker is not available (%v)", err)
+		os.Exit(m.Run())
+	}
+
+	var containers []string
+	code := func() int {
+		defer func() {
+			for _, id := range containers {
+				_ = exec.Command("docker", "rm", "-f", "-v", id).Run()
+			}
+		}()
+
+		mysqlID, mysqlPort, err := startContainer("3306/tcp",
+			"-e", "MYSQL_ROOT_PASSWORD=test", "-e", "MYSQL_DATABASE=short_link_test",
+			"--tmpfs", "/var/lib/mysql", "mysql:8.0")
+		if mysqlID != "" {
+			containers = append(containers, mysqlID)
+		}
+		if err != nil {
+			log.Printf("Failed to start MySQL: %v", err)
+			return 1
+		}
+		redisID, redisPort, err := startContainer("6379/tcp", "redis:7.0-alpine")
+		if redisID != "" {
+			containers = append(containers, redisID)
+		}
+		if err != nil {
+			log.Printf("Failed to start Redis: %v", err)
+			return 1
+		}
+
+		mysqlDSN = fmt.Sprintf("root:test@tcp(127.0.0.1:%s)/short_link_test?charset=utf8mb4&parseTime=True&loc=Local", mysqlPort)
+		redisAddr = "127.0.0.1:" + redisPort
+		if err := waitForDatabases(); err != nil {
+			log.Print(err)
+			return 1
+		}
+		return m.Run()
+	}()
+	os.Exit(code)
+}
+
+// startContainer runs an image with port published on a random local port
+// and returns the container ID and that port
+func startContainer(port string, args ...string) (string, string, error) {
+	runArgs := append([]string{"run", "-d", "-p", "127.0.0.1::" + port}, args...)
+	id, err := docker(runArgs...)
+	if err != nil {
+		return "", "", err
+	}
+	published, err := docker("port", id, port)
+	if err != nil {
+		return id, "", err
+	}
+	// "127.0.0.1:49153"
+	first, _, _ := strings.Cut(published, "\n")
+	return id, first[strings.LastIndex(first, ":")+1:], nil
+}
+
+// docker runs a docker command and returns its trimmed output
+func docker(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("docker %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// waitForDatabases waits until MySQL and Redis accept connections
+func waitForDatabases() error {
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+
+	for {
+		repo, err := repository.NewURLRepository(mysqlDSN, nil, repository.PoolConfig{MaxIdleConns: 1, MaxOpenConns: 1})
+		if err == nil {
+			repo.Close()
+			var redisCache *cache.RedisCache
+			if redisCache, err = cache.NewRedisCache(redisAddr, "", 0, 1); err == nil {
+				redisCache.Close()
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("databases did not start within %s: %w", startTimeout, err)
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package abuse
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// DefaultDatacenterASNs are autonomous systems of large cloud and hosting
+// providers; people rarely click links from them, scripts often do
+var DefaultDatacenterASNs = []uint{
+	14618, 16509, // Amazon AWS
+	396982, // Google Cloud
+	8075,   // Microsoft Azure
+	14061,  // DigitalOcean
+	16276,  // OVH
+	24940,  // Hetzner
+	63949,  // Linode (Akamai)
+	20473,  // Vultr
+	45102,  // Alibaba Cloud
+	31898,  // Oracle Cloud
+	132203, // Tencent Cloud
+	12876,  // Scaleway
+	51167,  // Contabo
+	60781,  // Leaseweb
+}
+
+// DatacenterMatcher recognizes IPs announced by datacenter ASNs
+type DatacenterMatcher struct {
+	asns map[uint]bool
+
+	// lookup returns the ASN announcing an IP (replaced in tests)
+	lookup func(ip net.IP) (uint, bool)
+	close  func() error
+}
+
+// NewDatacenterMatcher opens a MaxMind ASN database and matches IPs against
+// DefaultDatacenterASNs plus extraASNs
+func NewDatacenterMatcher(path string, extraASNs []uint) (*DatacenterMatcher, error) {
+	db, err := geoip2.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ASN database: %w", err)
+	}
+	m := newDatacenterMatcher(extraASNs, func(ip net.IP) (uint, bool) {
+		record, err := db.ASN(ip)
+		if err != nil {
+			return 0, false
+		}
+		return record.AutonomousSystemNumber, true
+	})
+	m.close = db.Close
+	return m, nil
+}
+
+// newDatacenterMatcher creates a matcher using lookup
+func newDatacenterMatcher(extraASNs []uint, lookup func(ip net.IP) (uint, bool)) *DatacenterMatcher {
+	m := &DatacenterMatcher{
+		asns:   make(map[uint]bool, len(DefaultDatacenterASNs)+len(extraASNs)),
+		lookup: lookup,
+		close:  func() error { return nil },
+	}
+	for _, asn := range append(DefaultDatacenterASNs, extraASNs...) {
+		m.asns[asn] = true
+	}
+	return m
+}
+
+// Contains reports whether ip belongs to a datacenter network
+func (m *DatacenterMatcher) Contains(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	asn, ok := m.lookup(parsed)
+	return ok && m.asns[asn]
+}
+
+// Close closes the ASN database
+func (m *DatacenterMatcher) Close() error {
+	return m.close()
+}
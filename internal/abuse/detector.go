@@ -0,0 +1,275 @@
+package abuse
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ============================================================================
+// ABUSE DETECTION
+// ============================================================================
+// Every redirect is counted in fixed windows in Redis, and three policies
+// look for traffic that doesn't come from people clicking a shared link:
+// - spike:      clicks in a window are Factor times the average of the
+//               previous windows (checked every Threshold clicks)
+// - single_ip:  one IP sends Threshold clicks to a link within a window
+// - datacenter: at least Ratio of a window's clicks (checked every Threshold
+//               clicks) come from hosting providers, i.e. scripts and bots
+//
+// A policy that triggers takes its action: flag (record and notify),
+// throttle (also cap the link at ThrottleLimit redirects per minute for
+// ThrottleDuration), warn (also show visitors an unsafe-link warning before
+// redirecting) or disable (also turn the link off). A link is flagged at
+// most once per FlagCooldown for the same reason.
+//
+// Counting costs one pipelined round trip per redirect; the rare checks
+// that need earlier windows cost a second one.
+// ============================================================================
+
+// Reasons a link is flagged, one per policy, plus visitor reports
+const (
+	ReasonSpike      = "spike"
+	ReasonSingleIP   = "single_ip"
+	ReasonDatacenter = "datacenter"
+	ReasonReports    = "reports"
+)
+
+// Actions a policy takes when it triggers
+const (
+	ActionFlag     = "flag"
+	ActionThrottle = "throttle"
+	ActionWarn     = "warn"
+	ActionDisable  = "disable"
+)
+
+// keyPrefix namespaces every abuse detection key in Redis
+const keyPrefix = "abuse:"
+
+// spikeBaselineWindows is how many earlier windows a spike is compared with
+const spikeBaselineWindows = 6
+
+// Policy decides when one kind of anomalous traffic triggers
+type Policy struct {
+	Enabled   bool
+	Window    time.Duration
+	Threshold int64
+	Factor    float64 // spike only
+	Ratio     float64 // datacenter only
+	Action    string
+}
+
+// Config configures a Detector
+type Config struct {
+	Spike      Policy
+	SingleIP   Policy
+	Datacenter Policy
+
+	FlagCooldown     time.Duration
+	ThrottleLimit    int64 // Redirects per minute
+	ThrottleDuration time.Duration
+}
+
+// Trigger is a policy that fired for a link
+type Trigger struct {
+	Reason string
+	Action string
+	Detail string // The numbers behind it, for people reading the flag
+}
+
+// Result is the outcome of observing one redirect
+type Result struct {
+	Triggers  []Trigger // Policies that fired and weren't cooling down
+	Throttled bool      // The link is throttled and over its limit; refuse the redirect
+}
+
+// Detector counts redirects per link and evaluates the policies
+type Detector struct {
+	client     *redis.Client
+	cfg        Config
+	datacenter *DatacenterMatcher // nil skips the datacenter policy
+	now        func() time.Time
+}
+
+// NewDetector creates a detector storing its counters in Redis
+func NewDetector(client *redis.Client, cfg Config, datacenter *DatacenterMatcher) *Detector {
+	return &Detector{client: client, cfg: cfg, datacenter: datacenter, now: time.Now}
+}
+
+// Observe counts a redirect of shortCode from ip and returns the policies it
+// triggered
+func (d *Detector) Observe(ctx context.Context, shortCode, ip string) (Result, error) {
+	var result Result
+	now := d.now()
+
+	pipe := d.client.Pipeline()
+	throttled := pipe.Exists(ctx, throttleKey(shortCode))
+	perMinute := d.incr(ctx, pipe, "rate:"+shortCode, now, time.Minute, 1)
+
+	var spike, perIP, dcTotal *redis.IntCmd
+	spikePolicy := d.cfg.Spike
+	if spikePolicy.Enabled {
+		spike = d.incr(ctx, pipe, "spike:"+shortCode, now, spikePolicy.Window, spikeBaselineWindows+1)
+	}
+	if d.cfg.SingleIP.Enabled && ip != "" {
+		perIP = d.incr(ctx, pipe, "ip:"+shortCode+":"+ip, now, d.cfg.SingleIP.Window, 1)
+	}
+	dcPolicy := d.cfg.Datacenter
+	if dcPolicy.Enabled && d.datacenter != nil {
+		dcTotal = d.incr(ctx, pipe, "dc_total:"+shortCode, now, dcPolicy.Window, 1)
+		if d.datacenter.Contains(ip) {
+			d.incr(ctx, pipe, "dc:"+shortCode, now, dcPolicy.Window, 1)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return result, fmt.Errorf("failed to count redirect: %w", err)
+	}
+
+	result.Throttled = throttled.Val() > 0 && perMinute.Val() > d.cfg.ThrottleLimit
+
+	var triggers []Trigger
+	if spike != nil && reachedStep(spike.Val(), spikePolicy.Threshold) {
+		previous, err := d.previousWindows(ctx, "spike:"+shortCode, now, spikePolicy.Window)
+		if err != nil {
+			return result, err
+		}
+		if average, ok := spikeDetected(spike.Val(), previous, spikePolicy.Factor); ok {
+			triggers = append(triggers, Trigger{
+				Reason: ReasonSpike,
+				Action: spikePolicy.Action,
+				Detail: fmt.Sprintf("%d clicks in %s, previous average %.1f", spike.Val(), spikePolicy.Window, average),
+			})
+		}
+	}
+	if perIP != nil && perIP.Val() == d.cfg.SingleIP.Threshold {
+		triggers = append(triggers, Trigger{
+			Reason: ReasonSingleIP,
+			Action: d.cfg.SingleIP.Action,
+			Detail: fmt.Sprintf("%d clicks from %s in %s", perIP.Val(), ip, d.cfg.SingleIP.Window),
+		})
+	}
+	if dcTotal != nil && reachedStep(dcTotal.Val(), dcPolicy.Threshold) {
+		dc, err := d.count(ctx, "dc:"+shortCode, now, dcPolicy.Window)
+		if err != nil {
+			return result, err
+		}
+		if share := float64(dc) / float64(dcTotal.Val()); share >= dcPolicy.Ratio {
+			triggers = append(triggers, Trigger{
+				Reason: ReasonDatacenter,
+				Action: dcPolicy.Action,
+				Detail: fmt.Sprintf("%d of %d clicks in %s from datacenter networks", dc, dcTotal.Val(), dcPolicy.Window),
+			})
+		}
+	}
+
+	for _, trigger := range triggers {
+		fresh, err := d.startCooldown(ctx, shortCode, trigger)
+		if err != nil {
+			return result, err
+		}
+		if fresh {
+			result.Triggers = append(result.Triggers, trigger)
+		}
+	}
+	return result, nil
+}
+
+// startCooldown marks a link flagged for a reason, applying a throttle
+// action; returns false if it already was within the cooldown
+func (d *Detector) startCooldown(ctx context.Context, shortCode string, trigger Trigger) (bool, error) {
+	key := keyPrefix + "flagged:" + shortCode + ":" + trigger.Reason
+	fresh, err := d.client.SetNX(ctx, key, 1, d.cfg.FlagCooldown).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to record flag: %w", err)
+	}
+	if fresh && trigger.Action == ActionThrottle {
+		if err := d.client.Set(ctx, throttleKey(shortCode), 1, d.cfg.ThrottleDuration).Err(); err != nil {
+			return false, fmt.Errorf("failed to throttle link: %w", err)
+		}
+	}
+	return fresh, nil
+}
+
+// Unthrottle lifts a throttle and the flag cooldowns of a link, e.g. when an
+// admin re-enables it
+func (d *Detector) Unthrottle(ctx context.Context, shortCode string) error {
+	keys := []string{throttleKey(shortCode)}
+	for _, reason := range []string{ReasonSpike, ReasonSingleIP, ReasonDatacenter} {
+		keys = append(keys, keyPrefix+"flagged:"+shortCode+":"+reason)
+	}
+	if err := d.client.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("failed to unthrottle link: %w", err)
+	}
+	return nil
+}
+
+// incr adds a click to the counter of the window containing now, keeping it
+// long enough to serve as a baseline for keep windows
+func (d *Detector) incr(ctx context.Context, pipe redis.Pipeliner, name string, now time.Time, window time.Duration, keep int) *redis.IntCmd {
+	key := windowKey(name, now, window)
+	cmd := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, window*time.Duration(keep+1))
+	return cmd
+}
+
+// count returns the counter of the window containing now
+func (d *Detector) count(ctx context.Context, name string, now time.Time, window time.Duration) (int64, error) {
+	n, err := d.client.Get(ctx, windowKey(name, now, window)).Int64()
+	if err != nil && err != redis.Nil {
+		return 0, fmt.Errorf("failed to read counter: %w", err)
+	}
+	return n, nil
+}
+
+// previousWindows returns the counters of the spikeBaselineWindows windows
+// before the one containing now; missing windows count as zero
+func (d *Detector) previousWindows(ctx context.Context, name string, now time.Time, window time.Duration) ([]int64, error) {
+	keys := make([]string, 0, spikeBaselineWindows)
+	for i := 1; i <= spikeBaselineWindows; i++ {
+		keys = append(keys, windowKey(name, now.Add(-time.Duration(i)*window), window))
+	}
+	values, err := d.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read counters: %w", err)
+	}
+	counts := make([]int64, len(values))
+	for i, value := range values {
+		if s, ok := value.(string); ok {
+			counts[i], _ = strconv.ParseInt(s, 10, 64)
+		}
+	}
+	return counts, nil
+}
+
+// windowKey returns the key of the fixed window containing t
+func windowKey(name string, t time.Time, window time.Duration) string {
+	return fmt.Sprintf("%s%s:%d", keyPrefix, name, t.UnixNano()/int64(window))
+}
+
+// throttleKey marks a throttled link
+func throttleKey(shortCode string) string {
+	return keyPrefix + "throttle:" + shortCode
+}
+
+// reachedStep reports whether count just reached a multiple of step, so a
+// check runs once per step clicks rather than on every click
+func reachedStep(count, step int64) bool {
+	return step > 0 && count >= step && count%step == 0
+}
+
+// spikeDetected reports whether current is at least factor times the
+// average of previous (taken as at least 1), and returns that average
+func spikeDetected(current int64, previous []int64, factor float64) (float64, bool) {
+	var sum int64
+	for _, n := range previous {
+		sum += n
+	}
+	average := 0.0
+	if len(previous) > 0 {
+		average = float64(sum) / float64(len(previous))
+	}
+	return average, float64(current) >= factor*max(average, 1)
+}
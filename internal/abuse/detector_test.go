@@ -0,0 +1,64 @@
+package abuse
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReachedStep(t *testing.T) {
+	assert.False(t, reachedStep(99, 100))
+	assert.True(t, reachedStep(100, 100))
+	assert.False(t, reachedStep(150, 100))
+	assert.True(t, reachedStep(300, 100))
+	assert.False(t, reachedStep(5, 0), "a zero threshold never triggers")
+}
+
+func TestSpikeDetected(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  int64
+		previous []int64
+		factor   float64
+		want     bool
+	}{
+		{"steady traffic", 1000, []int64{900, 1100, 1000, 950, 1050, 1000}, 10, false},
+		{"ten times the average", 10000, []int64{900, 1100, 1000, 950, 1050, 1000}, 10, true},
+		{"new link going viral", 1000, []int64{0, 0, 0, 0, 0, 0}, 10, true},
+		{"short spike in quiet history", 1000, []int64{200, 0, 0, 0, 0, 0}, 10, true},
+		{"busy history", 1000, []int64{300, 300, 300, 0, 0, 0}, 10, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got := spikeDetected(tt.current, tt.previous, tt.factor)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWindowKey(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, windowKey("spike:abc", start, time.Minute), windowKey("spike:abc", start.Add(59*time.Second), time.Minute))
+	assert.NotEqual(t, windowKey("spike:abc", start, time.Minute), windowKey("spike:abc", start.Add(time.Minute), time.Minute))
+	assert.Contains(t, windowKey("spike:abc", start, time.Minute), "abuse:spike:abc:")
+}
+
+func TestDatacenterMatcher(t *testing.T) {
+	asns := map[string]uint{
+		"52.95.110.1": 16509, // AWS
+		"81.2.69.160": 20712, // Residential ISP
+		"203.0.113.9": 64500, // Configured extra ASN
+	}
+	m := newDatacenterMatcher([]uint{64500}, func(ip net.IP) (uint, bool) {
+		asn, ok := asns[ip.String()]
+		return asn, ok
+	})
+
+	assert.True(t, m.Contains("52.95.110.1"))
+	assert.True(t, m.Contains("203.0.113.9"))
+	assert.False(t, m.Contains("81.2.69.160"))
+	assert.False(t, m.Contains("198.51.100.1"), "unknown IPs are not datacenters")
+	assert.False(t, m.Contains("not-an-ip"))
+}
@@ -0,0 +1,58 @@
+package abuse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// Notifier tells someone about a flagged link
+type Notifier interface {
+	Notify(ctx context.Context, flag *model.AbuseFlag) error
+}
+
+// NoopNotifier notifies nobody
+type NoopNotifier struct{}
+
+// Notify implements Notifier
+func (NoopNotifier) Notify(context.Context, *model.AbuseFlag) error { return nil }
+
+// WebhookNotifier POSTs flags as JSON to a URL
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier posting to url, giving up on a
+// request after timeout
+func NewWebhookNotifier(url string, timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Notify implements Notifier
+func (w *WebhookNotifier) Notify(ctx context.Context, flag *model.AbuseFlag) error {
+	body, err := json.Marshal(flag)
+	if err != nil {
+		return fmt.Errorf("failed to encode abuse flag: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call abuse webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("abuse webhook returned %s", resp.Status)
+	}
+	return nil
+}
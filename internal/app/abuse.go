@@ -0,0 +1,32 @@
+package app
+
+import (
+	"time"
+
+	"github.com/Monthlyaway/short-link/config"
+	"github.com/Monthlyaway/short-link/internal/abuse"
+)
+
+// abuseDetectorConfig converts the abuse settings to detector settings
+func abuseDetectorConfig(cfg config.AbuseConfig) abuse.Config {
+	return abuse.Config{
+		Spike:            abusePolicy(cfg.Policies.Spike),
+		SingleIP:         abusePolicy(cfg.Policies.SingleIP),
+		Datacenter:       abusePolicy(cfg.Policies.Datacenter),
+		FlagCooldown:     time.Duration(cfg.FlagCooldown) * time.Second,
+		ThrottleLimit:    int64(cfg.ThrottleLimit),
+		ThrottleDuration: time.Duration(cfg.ThrottleDuration) * time.Second,
+	}
+}
+
+// abusePolicy converts one policy's settings
+func abusePolicy(cfg config.AbusePolicyConfig) abuse.Policy {
+	return abuse.Policy{
+		Enabled:   cfg.Enabled,
+		Window:    time.Duration(cfg.Window) * time.Second,
+		Threshold: int64(cfg.Threshold),
+		Factor:    cfg.Factor,
+		Ratio:     cfg.Ratio,
+		Action:    cfg.Action,
+	}
+}
@@ -0,0 +1,267 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Monthlyaway/short-link/config"
+	"github.com/Monthlyaway/short-link/internal/cache"
+	"github.com/Monthlyaway/short-link/internal/filter"
+	grpcapi "github.com/Monthlyaway/short-link/internal/grpc"
+	"github.com/Monthlyaway/short-link/internal/repository"
+	"github.com/Monthlyaway/short-link/internal/router"
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/Monthlyaway/short-link/internal/summary"
+	"github.com/Monthlyaway/short-link/internal/tracing"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+)
+
+// ============================================================================
+// APPLICATION WIRING
+// ============================================================================
+// New builds the server from its config in dependency order:
+//   tracing → MySQL, Redis, bloom filter (storage.go)
+//           → URLService and its collaborators (service.go)
+//           → background jobs (jobs.go)
+//           → handlers and routes (routes.go)
+// Nothing listens or runs in the background until Run, which loads the
+// bloom filter, warms the cache, starts the jobs and then the HTTP, gRPC
+// and debug listeners. Shutdown stops them and releases everything New
+// opened, in reverse order.
+//
+// cmd/server only loads the config, handles the migrate subcommand and
+// turns signals into Reload and Shutdown calls.
+// ============================================================================
+
+// readinessTimeout bounds each dependency ping in /readyz
+const readinessTimeout = 2 * time.Second
+
+// App is a wired short link server
+type App struct {
+	cfg        *config.Config
+	configPath string // Re-read by Reload
+
+	repo       *repository.URLRepository // Home database; organizations, campaigns, aliases, ...
+	redisCache *cache.RedisCache
+	memcached  *cache.MemcachedBackend // Link cache backend when cache.backend is memcached
+	bloom      *filter.BloomFilter
+	service    *service.URLService
+
+	// Link storage (see storage.go): repo, or repo and the MySQL shards
+	links  service.Repository
+	shards []*repository.URLRepository // Databases holding links
+
+	// Shared by the service, jobs and routes (see service.go)
+	destinations    *service.DestinationPolicy
+	leaderboard     *cache.Leaderboard
+	summarySender   *summary.Sender
+	summarySchedule summary.Schedule
+
+	// Background jobs, started by Run (see jobs.go)
+	jobs     []func(context.Context)
+	stopJobs context.CancelFunc
+
+	// HTTP routes (see routes.go)
+	engine *gin.Engine
+	routes *router.Builder
+
+	// Listeners, started by Run
+	srv         *http.Server
+	redirectSrv *http.Server
+	debugSrv    *http.Server
+	grpcServer  *grpc.Server
+
+	// Release what New opened; Shutdown calls them in reverse
+	closers []func()
+}
+
+// New wires the server described by cfg
+// configPath is the file cfg was loaded from; Reload reads it again.
+func New(cfg *config.Config, configPath string) (_ *App, err error) {
+	a := &App{cfg: cfg, configPath: configPath}
+	defer func() {
+		if err != nil {
+			a.close()
+		}
+	}()
+
+	for _, step := range []func() error{
+		a.initTracing,
+		a.initStorage,
+		a.initService,
+		a.initJobs,
+		a.initRoutes,
+	} {
+		if err := step(); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// Service returns the URL service, e.g. for tests
+func (a *App) Service() *service.URLService {
+	return a.service
+}
+
+// Handler returns the HTTP handler of the main listener
+func (a *App) Handler() http.Handler {
+	return a.engine
+}
+
+// Run prepares the caches, starts the background jobs and starts serving
+// It returns once the listeners are started; listener failures after that
+// are fatal.
+func (a *App) Run() error {
+	cfg := a.cfg
+
+	// Load all short codes into bloom filter
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := a.service.InitBloomFilter(ctx); err != nil {
+		log.Printf("Warning: Failed to initialize bloom filter: %v", err)
+	}
+
+	// Preload hot links so a cold restart doesn't send a burst of misses to MySQL
+	if cfg.Cache.Warmup.Enabled {
+		warmCtx, cancelWarm := context.WithTimeout(context.Background(), 60*time.Second)
+		start := time.Now()
+		warmed, err := a.service.WarmCache(warmCtx, cfg.Cache.Warmup.Links, cfg.Cache.Warmup.Concurrency)
+		cancelWarm()
+		if err != nil {
+			log.Printf("Warning: cache warm-up incomplete: %v", err)
+		}
+		log.Printf("Warmed cache with %d links in %s", warmed, time.Since(start).Round(time.Millisecond))
+	}
+
+	// Jobs stop when the server shuts down
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	a.stopJobs = stopJobs
+	for _, job := range a.jobs {
+		go job(jobCtx)
+	}
+
+	a.srv = &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:           a.engine,
+		ReadTimeout:       time.Duration(cfg.Server.ReadTimeout) * time.Second,
+		ReadHeaderTimeout: time.Duration(cfg.Server.ReadHeaderTimeout) * time.Second,
+		WriteTimeout:      time.Duration(cfg.Server.WriteTimeout) * time.Second,
+		IdleTimeout:       time.Duration(cfg.Server.IdleTimeout) * time.Second,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+	}
+
+	// Start server (HTTPS when configured, plus an optional HTTP redirect)
+	redirectSrv, err := startServer(&cfg.Server, a.srv)
+	if err != nil {
+		return fmt.Errorf("failed to configure server: %w", err)
+	}
+	a.redirectSrv = redirectSrv
+
+	// Start gRPC server on its own port; it shares URLService with REST
+	if cfg.GRPC.Enabled {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPC.Port))
+		if err != nil {
+			return fmt.Errorf("failed to listen for gRPC: %w", err)
+		}
+		a.grpcServer = grpc.NewServer(grpc.UnaryInterceptor(grpcapi.TracingInterceptor()))
+		grpcapi.NewServer(a.service).Register(a.grpcServer)
+
+		go func() {
+			log.Printf("gRPC server starting on port %d...", cfg.GRPC.Port)
+			if err := a.grpcServer.Serve(lis); err != nil {
+				log.Fatalf("Failed to start gRPC server: %v", err)
+			}
+		}()
+	}
+
+	// Opt-in pprof/expvar listener for diagnosing the live process
+	if cfg.Debug.Enabled {
+		a.debugSrv = startDebugServer(cfg.Debug.Addr)
+	}
+	return nil
+}
+
+// Reload re-reads the rate limits from the config file and the reserved
+// codes from the database
+func (a *App) Reload() {
+	if err := a.reloadRateLimits(); err != nil {
+		log.Printf("Failed to reload rate limits: %v", err)
+	}
+	if err := a.service.ReloadReservedCodes(context.Background()); err != nil {
+		log.Printf("Failed to reload reserved codes: %v", err)
+	}
+}
+
+// Shutdown stops the background jobs, drains the listeners until ctx is
+// done and releases all resources
+func (a *App) Shutdown(ctx context.Context) error {
+	if a.stopJobs != nil {
+		a.stopJobs()
+	}
+
+	var err error
+	if a.srv != nil {
+		err = a.srv.Shutdown(ctx)
+	}
+	if a.redirectSrv != nil {
+		a.redirectSrv.Shutdown(ctx)
+	}
+	if a.grpcServer != nil {
+		a.grpcServer.GracefulStop()
+	}
+	if a.debugSrv != nil {
+		a.debugSrv.Shutdown(ctx)
+	}
+
+	a.close()
+	return err
+}
+
+// onClose registers fn to run on Shutdown
+func (a *App) onClose(fn func()) {
+	a.closers = append(a.closers, fn)
+}
+
+// close runs the registered closers, last registered first
+func (a *App) close() {
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		a.closers[i]()
+	}
+	a.closers = nil
+}
+
+// initTracing sets up trace propagation and, when enabled, span export
+// It runs first, before anything that creates spans.
+func (a *App) initTracing() error {
+	// Trace context is always propagated, even when spans aren't exported
+	tracing.SetupPropagation()
+	cfg := a.cfg.Tracing
+	if !cfg.Enabled {
+		return nil
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		ServiceName: cfg.ServiceName,
+		Endpoint:    cfg.Endpoint,
+		Insecure:    cfg.Insecure,
+		SampleRatio: cfg.SampleRatio,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+	a.onClose(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Printf("Failed to flush traces: %v", err)
+		}
+	})
+	log.Printf("Tracing enabled, exporting to %s", cfg.Endpoint)
+	return nil
+}
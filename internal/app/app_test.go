@@ -0,0 +1,23 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestClose tests that resources are released in reverse order, once
+func TestClose(t *testing.T) {
+	a := &App{}
+	var closed []string
+	a.onClose(func() { closed = append(closed, "repo") })
+	a.onClose(func() { closed = append(closed, "cache") })
+	a.onClose(func() { closed = append(closed, "publisher") })
+
+	assert.NoError(t, a.Shutdown(context.Background()), "shutting down before Run")
+	assert.Equal(t, []string{"publisher", "cache", "repo"}, closed)
+
+	a.close()
+	assert.Len(t, closed, 3)
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"expvar"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"time"
+)
+
+// ============================================================================
+// Debug Listener
+// ============================================================================
+// An opt-in listener on its own port (localhost by default) for diagnosing
+// a live process without exposing anything on the public port:
+// - /debug/pprof/    CPU, heap, goroutine, mutex and block profiles
+//                    (go tool pprof http://127.0.0.1:6060/debug/pprof/heap)
+// - /debug/vars      expvar: memstats, cmdline, goroutine count
+// - /debug/goroutines full stack dump of every goroutine, as plain text
+// ============================================================================
+
+func init() {
+	expvar.Publish("goroutines", expvar.Func(func() interface{} {
+		return runtime.NumGoroutine()
+	}))
+}
+
+// newDebugMux returns the handlers served by the debug listener
+func newDebugMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/goroutines", dumpGoroutines)
+	return mux
+}
+
+// dumpGoroutines writes the stack of every goroutine
+func dumpGoroutines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	// debug=2 prints full stacks in the same format as an unrecovered panic
+	if err := runtimepprof.Lookup("goroutine").WriteTo(w, 2); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// startDebugServer starts the debug listener on addr
+func startDebugServer(addr string) *http.Server {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           newDebugMux(),
+		ReadHeaderTimeout: 5 * time.Second,
+		// No WriteTimeout: CPU profiles and traces stream for their full duration
+	}
+	go func() {
+		log.Printf("Debug server starting on %s...", addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("Debug server stopped: %v", err)
+		}
+	}()
+	return srv
+}
@@ -0,0 +1,166 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/linkcheck"
+	"github.com/Monthlyaway/short-link/internal/service"
+)
+
+// initJobs creates the enabled background jobs; Run starts them
+// Jobs working through links run once per database holding links.
+func (a *App) initJobs() error {
+	cfg := a.cfg
+
+	// Partition and prune visit logs
+	if cfg.VisitLog.CleanupInterval > 0 {
+		for _, db := range a.shards {
+			retention := service.NewVisitLogRetention(
+				db,
+				time.Duration(cfg.VisitLog.RetentionDays)*24*time.Hour,
+				cfg.VisitLog.PartitionDaysAhead,
+				time.Duration(cfg.VisitLog.CleanupInterval)*time.Second,
+			)
+			a.addJob(retention.Run)
+		}
+	}
+
+	// Aggregate finished days of visit logs for the stats API
+	if cfg.VisitLog.RollupInterval > 0 {
+		for _, db := range a.shards {
+			rollup := service.NewVisitRollup(
+				db,
+				cfg.VisitLog.RollupLookbackDays,
+				time.Duration(cfg.VisitLog.RollupInterval)*time.Second,
+			)
+			a.addJob(rollup.Run)
+		}
+	}
+
+	// Keep the leaderboard buckets to their top links
+	if a.leaderboard != nil {
+		trim := service.NewLeaderboardTrim(a.leaderboard, time.Duration(cfg.Leaderboard.TrimInterval)*time.Second)
+		a.addJob(trim.Run)
+	}
+
+	// Send daily/weekly summary reports
+	if a.summarySender != nil {
+		scheduler := service.NewSummaryScheduler(a.service, a.summarySender, a.summarySchedule,
+			time.Duration(cfg.Reports.CheckInterval)*time.Second)
+		a.addJob(scheduler.Run)
+	}
+
+	// Check that link destinations still answer and notify owners of dead ones
+	if cfg.LinkHealth.Enabled {
+		// Destinations may have started resolving to internal addresses
+		checker := linkcheck.NewChecker(time.Duration(cfg.LinkHealth.Timeout)*time.Millisecond, a.dialControl())
+		var notifier linkcheck.Notifier
+		if cfg.LinkHealth.WebhookURL != "" {
+			notifier = linkcheck.NewWebhookNotifier(cfg.LinkHealth.WebhookURL, time.Duration(cfg.LinkHealth.WebhookTimeout)*time.Millisecond)
+		}
+		for _, db := range a.shards {
+			healthCheck := service.NewLinkHealthCheck(db, checker, notifier, service.LinkHealthOptions{
+				Interval:    time.Duration(cfg.LinkHealth.Interval) * time.Second,
+				Recheck:     time.Duration(cfg.LinkHealth.Recheck) * time.Hour,
+				BatchSize:   cfg.LinkHealth.BatchSize,
+				Concurrency: cfg.LinkHealth.Concurrency,
+				NotifyAfter: cfg.LinkHealth.NotifyAfter,
+			})
+			a.addJob(healthCheck.Run)
+		}
+	}
+
+	// Permanently remove soft-deleted links after the grace period
+	if cfg.DeletedLinks.PurgeInterval > 0 {
+		for _, db := range a.shards {
+			purge := service.NewLinkPurge(
+				db,
+				a.service.LinkRemoved,
+				time.Duration(cfg.DeletedLinks.PurgeAfterDays)*24*time.Hour,
+				time.Duration(cfg.DeletedLinks.PurgeInterval)*time.Second,
+			)
+			a.addJob(purge.Run)
+		}
+	}
+
+	// Rebuild the bloom filter so codes of deleted links drop out of it, and
+	// into a larger one once it's saturated
+	if cfg.BloomFilter.RebuildInterval > 0 || cfg.BloomFilter.AutoResize {
+		rebuild := service.NewBloomRebuild(
+			a.service,
+			time.Duration(cfg.BloomFilter.RebuildInterval)*time.Second,
+			cfg.BloomFilter.AutoResize,
+		)
+		a.addJob(rebuild.Run)
+	}
+
+	// Reclaim codes of long-expired links that were never visited
+	if r := cfg.ShortCodes.Recycling; r.Enabled {
+		for _, db := range a.shards {
+			recycler := service.NewCodeRecycler(
+				db,
+				a.redisCache,
+				a.service.LinkRemoved,
+				time.Duration(r.QuarantineDays)*24*time.Hour,
+				time.Duration(r.Interval)*time.Second,
+				r.BatchSize,
+			)
+			a.addJob(recycler.Run)
+		}
+	}
+
+	// Ping MySQL periodically so broken pooled connections are replaced
+	// before a request picks them up
+	if cfg.MySQL.PingInterval > 0 {
+		interval := time.Duration(cfg.MySQL.PingInterval) * time.Second
+		for _, db := range a.databases() {
+			a.addJob(func(ctx context.Context) {
+				db.KeepAlive(ctx, interval, readinessTimeout)
+			})
+		}
+	}
+
+	// Degraded mode: while MySQL is down, serve cached redirects, queue
+	// visits in Redis for replay and reject writes
+	if cfg.DegradedMode.Enabled {
+		monitor := service.NewDatabaseMonitor(a.pingMySQL, time.Duration(cfg.DegradedMode.CheckInterval)*time.Second, readinessTimeout)
+		a.service.SetDatabaseMonitor(monitor)
+		a.addJob(monitor.Run)
+		replay := service.NewVisitReplay(a.service, time.Duration(cfg.DegradedMode.ReplayInterval)*time.Second)
+		a.addJob(replay.Run)
+	}
+
+	// Write-behind creation: flush queued inserts of new links to MySQL
+	if cfg.WriteBehind.Enabled {
+		flush := service.NewCreationFlush(a.service, service.WriteBehindOptions{
+			Workers:           cfg.WriteBehind.Workers,
+			PollInterval:      time.Duration(cfg.WriteBehind.PollInterval) * time.Millisecond,
+			MaxAttempts:       cfg.WriteBehind.MaxAttempts,
+			ReconcileInterval: time.Duration(cfg.WriteBehind.ReconcileInterval) * time.Second,
+		})
+		a.addJob(flush.Run)
+	}
+
+	// Transactional outbox: apply the side effects stored with new links
+	if o := cfg.Outbox; o.Enabled {
+		var webhook *service.OutboxWebhook
+		if o.WebhookURL != "" {
+			webhook = service.NewOutboxWebhook(o.WebhookURL, time.Duration(o.WebhookTimeout)*time.Millisecond)
+		}
+		dispatcher := service.NewOutboxDispatcher(a.service, webhook, service.OutboxOptions{
+			Interval:    time.Duration(o.Interval) * time.Millisecond,
+			BatchSize:   o.BatchSize,
+			MaxAttempts: o.MaxAttempts,
+			Lease:       time.Duration(o.Lease) * time.Second,
+			Retention:   time.Duration(o.RetentionDays) * 24 * time.Hour,
+		})
+		a.addJob(dispatcher.Run)
+	}
+	return nil
+}
+
+// addJob registers a background job; it runs until the context is done
+func (a *App) addJob(run func(context.Context)) {
+	a.jobs = append(a.jobs, run)
+}
@@ -0,0 +1,374 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Monthlyaway/short-link/config"
+	"github.com/Monthlyaway/short-link/internal/captcha"
+	"github.com/Monthlyaway/short-link/internal/handler"
+	"github.com/Monthlyaway/short-link/internal/middleware"
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/router"
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// streamingRoutes move large bodies and enforce their own size and time limits
+// API routes are listed in their v1 form and match every version.
+var streamingRoutes = map[string]bool{
+	"/api/v1/import":             true,
+	"/api/v1/export/:short_code": true,
+	"/admin/links/export":        true,
+}
+
+// isStreamingRoute reports whether a request is for one of streamingRoutes
+func isStreamingRoute(c *gin.Context) bool {
+	return streamingRoutes[middleware.CanonicalAPIPath(c.FullPath())]
+}
+
+// skipRequestTimeout returns the routes the request timeout leaves alone:
+// streamed imports and exports, and every redirect route (redirects have
+// their own, shorter deadline in URLService)
+func skipRequestTimeout(redirectRoutes map[string]bool) func(c *gin.Context) bool {
+	return func(c *gin.Context) bool {
+		return isStreamingRoute(c) || redirectRoutes[c.FullPath()]
+	}
+}
+
+// initRoutes creates the Gin engine with its middleware, the handlers and
+// the routes
+func (a *App) initRoutes() error {
+	cfg := a.cfg
+	urlService := a.service
+
+	// Set Gin mode
+	gin.SetMode(cfg.Server.Mode)
+
+	// Initialize Gin engine; the access log replaces Gin's text logger and
+	// runs first so it sees the status of recovered panics
+	engine := gin.New()
+	a.engine = engine
+	// Route templates of redirects, by short code or by namespaced alias
+	redirectRoutes := map[string]bool{
+		"/:short_code": true,
+		cfg.Server.RedirectPrefix + "/:short_code": true,
+	}
+	for _, namespace := range cfg.Auth.Namespaces {
+		redirectRoutes["/"+namespace+"/:alias"] = true
+		redirectRoutes[cfg.Server.RedirectPrefix+"/"+namespace+"/:alias"] = true
+	}
+	if cfg.AccessLog.Enabled {
+		engine.Use(middleware.AccessLog(os.Stdout, func(c *gin.Context) float64 {
+			if redirectRoutes[c.FullPath()] {
+				return cfg.AccessLog.RedirectSampleRate
+			}
+			return cfg.AccessLog.SampleRate
+		}))
+	} else {
+		engine.Use(gin.Logger())
+	}
+	engine.Use(gin.Recovery())
+
+	// Trace every request; runs before rate limiting so rejected requests show up too
+	engine.Use(middleware.Tracing())
+
+	// Slow down and ban clients scanning for short codes; runs before the
+	// concurrency limit so delayed scanners don't hold its slots
+	if s := cfg.ScanProtection; s.Enabled {
+		engine.Use(middleware.Tarpit(middleware.NewRedisScanStore(a.redisCache.GetClient()), middleware.TarpitConfig{
+			Threshold:   s.Threshold,
+			Window:      time.Duration(s.Window) * time.Second,
+			BaseDelay:   time.Duration(s.BaseDelay) * time.Millisecond,
+			MaxDelay:    time.Duration(s.MaxDelay) * time.Millisecond,
+			BanAfter:    s.BanAfter,
+			BanDuration: time.Duration(s.BanDuration) * time.Second,
+		}, func(c *gin.Context) bool {
+			return !redirectRoutes[c.FullPath()]
+		}))
+	}
+
+	// Cap the requests handled at once, whoever sends them
+	var concurrencyLimiter *middleware.ConcurrencyLimiter
+	if cfg.Concurrency.Enabled {
+		concurrencyLimiter = middleware.NewConcurrencyLimiter(cfg.Concurrency.MaxInFlight,
+			time.Duration(cfg.Concurrency.QueueTimeout)*time.Millisecond)
+		engine.Use(concurrencyLimiter.Middleware())
+	}
+
+	// Bound request bodies and durations; imports and exports stream large
+	// files and enforce their own limits
+	engine.Use(middleware.BodyLimit(cfg.Server.MaxBodyBytes, isStreamingRoute))
+	engine.Use(middleware.Timeout(time.Duration(cfg.Timeouts.Request)*time.Millisecond, skipRequestTimeout(redirectRoutes)))
+
+	// Reject writes while MySQL is down (degraded mode)
+	engine.Use(middleware.ReadOnly(urlService.DatabaseAvailable))
+
+	// Identify callers by API key; runs before rate limiting so per-user
+	// limits see the user ID. GET /shorten serves clients that can only
+	// send a URL, and browsers following private links can't set headers,
+	// so both take the key as a query parameter too.
+	engine.Use(middleware.APIKeyAuth(cfg.Auth.APIKeys, func(c *gin.Context) bool {
+		if c.Request.Method != http.MethodGet {
+			return false
+		}
+		route := c.FullPath()
+		return middleware.CanonicalAPIPath(route) == "/api/v1/shorten" || redirectRoutes[route]
+	}))
+
+	// Only honor client IP headers from trusted proxies so rate limiting and
+	// visit logs see the real client IP behind a load balancer
+	if err := engine.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	if len(cfg.Server.RemoteIPHeaders) > 0 {
+		engine.RemoteIPHeaders = cfg.Server.RemoteIPHeaders
+	}
+
+	// Initialize handlers
+	urlHandler := handler.NewURLHandler(urlService)
+	campaignHandler := handler.NewCampaignHandler(urlService)
+	orgHandler := handler.NewOrgHandler(urlService)
+	importJobs := service.NewImportJobs(time.Duration(cfg.Import.JobTTL) * time.Second)
+	importHandler := handler.NewImportHandler(urlService, importJobs, cfg.Import.MaxBytes, cfg.Import.AsyncThreshold)
+	if cfg.Server.UseForwardedHeaders {
+		if err := urlHandler.SetForwardedHeaders(cfg.Server.TrustedProxies); err != nil {
+			return fmt.Errorf("invalid trusted proxies: %w", err)
+		}
+	}
+	siteHandler, err := handler.NewSiteHandler(cfg.Server.RobotsTxt, cfg.Server.Favicon)
+	if err != nil {
+		return fmt.Errorf("failed to initialize site files: %w", err)
+	}
+	if cfg.Abuse.InterstitialTemplate != "" {
+		if err := urlHandler.SetInterstitialTemplate(cfg.Abuse.InterstitialTemplate); err != nil {
+			return fmt.Errorf("invalid unsafe-link warning page: %w", err)
+		}
+	}
+	urlHandler.SetRedirectDelay(cfg.RedirectDelay.Seconds, cfg.RedirectDelay.Disclaimer)
+	if cfg.RedirectDelay.Template != "" {
+		if err := urlHandler.SetCountdownTemplate(cfg.RedirectDelay.Template); err != nil {
+			return fmt.Errorf("invalid redirect countdown page: %w", err)
+		}
+	}
+
+	// ========================================================================
+	// MIDDLEWARE SETUP - Rate Limiting
+	// ========================================================================
+	// All limiters share one store so state lives in a single place
+	// Changing the backend requires a restart; everything else can be reloaded
+	var rateLimitStore middleware.Store
+	if cfg.RateLimit.Backend == "memory" {
+		rateLimitStore = middleware.NewMemoryStore()
+	} else {
+		rateLimitStore = middleware.NewRedisStore(a.redisCache.GetClient())
+	}
+
+	// The route builder attaches the global limiter and a limiter per route
+	// from cfg.RateLimit.Endpoints (matched by method and full path)
+	routes := router.NewBuilder(engine, rateLimitStore, &cfg.RateLimit)
+	a.routes = routes
+
+	if cfg.RateLimit.Enabled {
+		log.Println("Rate limiting enabled with strategy:", cfg.RateLimit.Strategy)
+	}
+
+	// Register routes
+	// Liveness never touches dependencies; readiness pings MySQL and Redis (and Memcached)
+	healthHandler := handler.NewHealthHandler(readinessTimeout)
+	// In degraded mode cached redirects survive a MySQL outage, so it
+	// doesn't take the pod out of the load balancer
+	if cfg.DegradedMode.Enabled {
+		healthHandler.AddOptionalCheck("mysql", a.pingMySQL)
+	} else {
+		healthHandler.AddCheck("mysql", a.pingMySQL)
+	}
+	healthHandler.AddCheck("redis", a.redisCache.Ping)
+	if a.memcached != nil {
+		healthHandler.AddCheck("memcached", a.memcached.Ping)
+	}
+	routes.GET("/health", healthHandler.Liveness)
+	routes.GET("/healthz", healthHandler.Liveness)
+	routes.GET("/readyz", healthHandler.Readiness)
+	// Prometheus scrape endpoint
+	metricsHandler := handler.NewMetricsHandler(urlService)
+	if concurrencyLimiter != nil {
+		metricsHandler.SetConcurrencyLimiter(concurrencyLimiter)
+	}
+	routes.GET("/metrics", metricsHandler.Metrics)
+	// Crawlers and browsers request these on their own; answer them here
+	// rather than looking them up as short codes
+	routes.GET("/robots.txt", siteHandler.RobotsTxt)
+	routes.GET("/favicon.ico", siteHandler.Favicon)
+	// Redirects live at the root, or under server.redirect_prefix (the
+	// tarpit, mounted above, covers them by redirectRoutes)
+	if cfg.Server.RedirectPrefix == "" || cfg.Server.LegacyRedirects {
+		routes.GET("/:short_code", urlHandler.RedirectToOriginalURL)
+	}
+	if cfg.Server.RedirectPrefix != "" {
+		routes.GET(cfg.Server.RedirectPrefix+"/:short_code", urlHandler.RedirectToOriginalURL)
+	}
+	// Each alias namespace gets its own path next to the short codes
+	for _, namespace := range cfg.Auth.Namespaces {
+		if cfg.Server.RedirectPrefix == "" || cfg.Server.LegacyRedirects {
+			routes.GET("/"+namespace+"/:alias", urlHandler.RedirectAlias(namespace))
+		}
+		if cfg.Server.RedirectPrefix != "" {
+			routes.GET(cfg.Server.RedirectPrefix+"/"+namespace+"/:alias", urlHandler.RedirectAlias(namespace))
+		}
+	}
+
+	// The JSON API, under each version (see middleware/version.go)
+	api := apiHandlers{url: urlHandler, campaigns: campaignHandler, orgs: orgHandler, imports: importHandler}
+	if cfg.Idempotency.Enabled {
+		api.idempotency = middleware.Idempotency(
+			middleware.NewRedisIdempotencyStore(a.redisCache.GetClient()),
+			time.Duration(cfg.Idempotency.TTL)*time.Second,
+			time.Duration(cfg.Idempotency.LockTimeout)*time.Second,
+		)
+	}
+	if cfg.Captcha.Provider != "" {
+		verifier, err := captcha.NewVerifier(cfg.Captcha.Provider, cfg.Captcha.Secret,
+			cfg.Captcha.MinScore, time.Duration(cfg.Captcha.Timeout)*time.Millisecond)
+		if err != nil {
+			return fmt.Errorf("failed to initialize captcha: %w", err)
+		}
+		api.captcha = middleware.RequireCaptcha(verifier)
+	}
+	for _, prefix := range []string{"/api/v1", "/api/v2"} {
+		a.registerAPI(routes.Group(prefix, middleware.Versioned()), api)
+	}
+
+	// Admin routes are only exposed when a token is configured
+	if cfg.Admin.Token != "" {
+		adminHandler := handler.NewAdminHandler(a.reloadRateLimits)
+		dataRequestHandler := handler.NewDataRequestHandler(a.dataRequests())
+		admin := routes.Group("/admin", middleware.AdminAuth(cfg.Admin.Token))
+		{
+			admin.POST("/rate-limit/reload", adminHandler.ReloadRateLimits)
+			admin.GET("/links/export", urlHandler.ExportURLMappings)
+			admin.DELETE("/links/:short_code", urlHandler.DeleteURL)
+			admin.POST("/links/:short_code/restore", urlHandler.RestoreURL)
+			admin.POST("/links/:short_code/enable", urlHandler.EnableURL)
+			admin.POST("/links/:short_code/warn", urlHandler.WarnURL)
+			admin.POST("/links/:short_code/disable", urlHandler.DisableURL)
+			admin.POST("/links/:short_code/sign", urlHandler.SignURL)
+			admin.GET("/abuse/flags", urlHandler.ListAbuseFlags)
+			admin.GET("/abuse/reports", urlHandler.ListReportedURLs)
+			admin.GET("/abuse/reports/:short_code", urlHandler.ListAbuseReports)
+			admin.POST("/data-requests", dataRequestHandler.CreateDataRequest)
+			admin.GET("/data-requests/:id", dataRequestHandler.GetDataRequest)
+			admin.GET("/data-requests/:id/export", dataRequestHandler.GetDataExport)
+		}
+	}
+
+	for _, rule := range routes.UnmatchedRules() {
+		log.Printf("Warning: rate limit rule %s %s matches no route", rule.Method, rule.Path)
+	}
+	return nil
+}
+
+// dataRequests creates the registry of GDPR export and deletion requests
+func (a *App) dataRequests() *service.DataRequests {
+	cfg := a.cfg.Privacy.DataRequests
+	var notifier service.DataRequestNotifier
+	if cfg.WebhookURL != "" {
+		notifier = service.NewDataRequestWebhook(cfg.WebhookURL, time.Duration(cfg.WebhookTimeout)*time.Millisecond)
+	}
+	return service.NewDataRequests(a.service, time.Duration(cfg.JobTTL)*time.Second, notifier)
+}
+
+// apiHandlers serve the JSON API
+type apiHandlers struct {
+	url       *handler.URLHandler
+	campaigns *handler.CampaignHandler
+	orgs      *handler.OrgHandler
+	imports   *handler.ImportHandler
+
+	// idempotency replays retried creations; nil when disabled
+	idempotency gin.HandlerFunc
+	// captcha checks anonymous creations; nil when disabled
+	captcha gin.HandlerFunc
+}
+
+// registerAPI registers the JSON API routes in api; they are the same in
+// every version
+func (a *App) registerAPI(api *router.RouteGroup, h apiHandlers) {
+	cfg := a.cfg
+
+	// Links owned by an organization need a member with the given role;
+	// changing a link needs an editor, or its creator for links without one
+	canView := h.orgs.RequireLinkRole(model.RoleViewer)
+	canEdit := h.orgs.RequireLinkEditor()
+
+	// withCaptcha puts the captcha check, if enabled, before handlers
+	withCaptcha := func(handlers ...gin.HandlerFunc) []gin.HandlerFunc {
+		if h.captcha == nil {
+			return handlers
+		}
+		return append([]gin.HandlerFunc{h.captcha}, handlers...)
+	}
+
+	if h.idempotency != nil {
+		// Retries are replayed before their (single-use) captcha token is
+		// checked; captcha rejections release the key rather than being kept
+		api.POST("/shorten", append([]gin.HandlerFunc{h.idempotency}, withCaptcha(h.url.CreateShortURL)...)...)
+	} else {
+		api.POST("/shorten", withCaptcha(h.url.CreateShortURL)...)
+	}
+	api.GET("/shorten", withCaptcha(h.url.ShortenViaGET)...)
+	api.POST("/validate", h.url.ValidateURL)
+	api.GET("/info/:short_code", canView, h.url.GetURLInfo)
+	api.GET("/export/:short_code", canView, h.url.ExportVisitLogs)
+	if cfg.Leaderboard.Enabled {
+		api.GET("/stats/top", h.url.GetTopLinks)
+	}
+	api.GET("/stats/:short_code", canView, h.url.GetVisitStats)
+	api.GET("/urls", h.url.ListURLs)
+	api.GET("/aliases", h.url.ListAliases)
+	api.PUT("/aliases/:alias", h.url.SetAlias)
+	api.DELETE("/aliases/:alias", h.url.DeleteAlias)
+	api.PATCH("/urls/:short_code", canEdit, h.url.UpdateURL)
+	api.PUT("/urls/:short_code/tags", canEdit, h.url.SetTags)
+	api.POST("/urls/:short_code/clone", canEdit, h.url.CloneURL)
+	api.GET("/urls/:short_code/history", canView, h.url.GetURLHistory)
+	api.POST("/import", withCaptcha(h.imports.Import)...)
+	api.GET("/import/:job_id", h.imports.GetImportJob)
+	api.POST("/report/:short_code", h.url.ReportURL)
+
+	campaigns := api.Group("/campaigns")
+	campaigns.POST("", h.campaigns.CreateCampaign)
+	campaigns.GET("/:id", h.campaigns.GetCampaign)
+	campaigns.POST("/:id/links", h.campaigns.AttachLinks)
+	campaigns.DELETE("/:id/links/:short_code", h.campaigns.DetachLink)
+	campaigns.GET("/:id/stats", h.campaigns.GetCampaignStats)
+
+	orgs := api.Group("/orgs")
+	orgs.POST("", h.orgs.CreateOrg)
+	orgs.GET("", h.orgs.ListOrgs)
+	orgs.GET("/:id", h.orgs.GetOrg)
+	orgs.PUT("/:id/members/:user_id", h.orgs.SetMember)
+	orgs.DELETE("/:id/members/:user_id", h.orgs.RemoveMember)
+
+	if cfg.Reports.Enabled {
+		summaries := api.Group("/summaries")
+		summaries.POST("", h.url.CreateSummarySubscription)
+		summaries.GET("", h.url.ListSummarySubscriptions)
+		summaries.DELETE("/:id", h.url.DeleteSummarySubscription)
+	}
+}
+
+// reloadRateLimits re-reads the rate_limit section of the config file and
+// swaps it into the running limiters
+func (a *App) reloadRateLimits() error {
+	newCfg, err := config.Load(a.configPath)
+	if err != nil {
+		return err
+	}
+	a.routes.Reload(&newCfg.RateLimit)
+	log.Println("Rate limit configuration reloaded")
+	return nil
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Monthlyaway/short-link/config"
+	"github.com/Monthlyaway/short-link/internal/middleware"
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestEngine builds the routes of an app with the given configuration
+// and no storage; only requests rejected before reaching storage work
+func newTestEngine(t *testing.T, configure func(cfg *config.Config)) *gin.Engine {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Server.Mode = gin.TestMode
+	cfg.RateLimit.Backend = "memory"
+	cfg.Admin.Token = "admin-secret"
+	// Both keep their state in Redis
+	cfg.Idempotency.Enabled = false
+	cfg.ScanProtection.Enabled = false
+	if configure != nil {
+		configure(cfg)
+	}
+	a := &App{cfg: cfg, service: service.NewURLService(nil, nil, nil)}
+	require.NoError(t, a.initRoutes())
+	return a.engine
+}
+
+// TestRestoreNeedsAdmin tests that deleted links can only be restored with
+// the admin token, like they are deleted
+func TestRestoreNeedsAdmin(t *testing.T) {
+	engine := newTestEngine(t, nil)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/links/abc123/restore", nil))
+	assert.Contains(t, []int{http.StatusUnauthorized, http.StatusForbidden}, w.Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/links/abc123/restore", nil)
+	req.Header.Set(middleware.AdminTokenHeader, "wrong")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	assert.Contains(t, []int{http.StatusUnauthorized, http.StatusForbidden}, w.Code)
+
+	// The API no longer has a restore route anyone can call
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/urls/abc123/restore", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// TestLinkChangesNeedUser tests that anonymous callers can't edit, tag or
+// clone links, including links without an organization
+func TestLinkChangesNeedUser(t *testing.T) {
+	engine := newTestEngine(t, nil)
+
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodPatch, "/api/v1/urls/abc123"},
+		{http.MethodPut, "/api/v1/urls/abc123/tags"},
+		{http.MethodPost, "/api/v1/urls/abc123/clone"},
+	} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, strings.NewReader(`{"url": "https://evil.example"}`)))
+		assert.Equal(t, http.StatusUnauthorized, w.Code, tc.path)
+	}
+}
+
+// TestSkipRequestTimeout tests that every redirect route, with or without
+// the redirect prefix, keeps its own deadline instead of the request timeout
+func TestSkipRequestTimeout(t *testing.T) {
+	skip := skipRequestTimeout(map[string]bool{
+		"/:short_code":    true,
+		"/go/:short_code": true,
+		"/go/acme/:alias": true,
+		"/acme/:alias":    true,
+	})
+	engine := gin.New()
+	skipped := map[string]bool{}
+	record := func(c *gin.Context) { skipped[c.Request.URL.Path] = skip(c) }
+	engine.GET("/:short_code", record)
+	engine.GET("/go/:short_code", record)
+	engine.GET("/go/acme/:alias", record)
+	engine.GET("/api/v1/info/:short_code", record)
+	engine.GET("/api/v1/export/:short_code", record)
+
+	for path, want := range map[string]bool{
+		"/abc123":               true,
+		"/go/abc123":            true,
+		"/go/acme/spring":       true,
+		"/api/v1/info/abc123":   false,
+		"/api/v1/export/abc123": true,
+	} {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, want, skipped[path], path)
+	}
+}
@@ -0,0 +1,341 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/abuse"
+	"github.com/Monthlyaway/short-link/internal/cache"
+	"github.com/Monthlyaway/short-link/internal/enrich"
+	"github.com/Monthlyaway/short-link/internal/events"
+	"github.com/Monthlyaway/short-link/internal/linkcheck"
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/Monthlyaway/short-link/internal/summary"
+	"github.com/Monthlyaway/short-link/internal/utils"
+)
+
+// initService creates the URL service and configures it
+func (a *App) initService() error {
+	a.service = service.NewURLService(a.links, a.redisCache, a.bloom)
+
+	for _, step := range []func() error{
+		a.initDomains,
+		a.initIDGenerator,
+		a.initDestinations,
+		a.initReservedCodes,
+		a.initEvents,
+		a.initAbuse,
+		a.initReports,
+	} {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+
+	a.service.SetTimeouts(service.Timeouts{
+		Redirect:   time.Duration(a.cfg.Timeouts.Redirect) * time.Millisecond,
+		VisitWrite: time.Duration(a.cfg.Timeouts.VisitWrite) * time.Millisecond,
+	})
+	if a.cfg.Leaderboard.Enabled {
+		a.leaderboard = cache.NewLeaderboard(a.redisCache.GetClient(), a.cfg.Leaderboard.Size)
+		a.service.SetLeaderboard(a.leaderboard)
+	}
+	a.service.SetWriteBehind(a.cfg.WriteBehind.Enabled)
+	a.service.SetOutbox(a.cfg.Outbox.Enabled, a.cfg.Outbox.WebhookURL != "")
+
+	// Compiled-in plugins add their hooks last, to a configured service
+	names, err := a.service.LoadPlugins()
+	if err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		log.Printf("Loaded plugins: %s", strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// initDomains sets the serving domains; short URLs are built from each
+// link's domain and server.base_url, when set, is the default domain
+func (a *App) initDomains() error {
+	cfg := a.cfg.Server
+	var domains []service.Domain
+	if cfg.BaseURL != "" {
+		base, err := service.ParseBaseURL(cfg.BaseURL)
+		if err != nil {
+			return fmt.Errorf("invalid server.base_url: %w", err)
+		}
+		domains = append(domains, base)
+	}
+	for _, d := range cfg.Domains {
+		if len(domains) > 0 && strings.EqualFold(d.Host, domains[0].Host) {
+			continue
+		}
+		domains = append(domains, service.Domain{Host: d.Host, Scheme: d.Scheme})
+	}
+	a.service.SetDomains(domains)
+	a.service.SetRedirectPrefix(cfg.RedirectPrefix)
+	return nil
+}
+
+// initIDGenerator sets the short code generation strategy
+// Obfuscation permutes snowflake/sequence IDs so codes don't reveal creation order
+func (a *App) initIDGenerator() error {
+	cfg := a.cfg.IDGenerator
+	var perm *utils.Permutation
+	if cfg.Obfuscate {
+		bits := cfg.ObfuscationBits
+		if cfg.Strategy == utils.StrategySnowflake {
+			bits = utils.SnowflakeBits
+		}
+		var err error
+		perm, err = utils.NewPermutation(cfg.ObfuscationKey, bits)
+		if err != nil {
+			return fmt.Errorf("invalid ID obfuscation settings: %w", err)
+		}
+	}
+	switch cfg.Strategy {
+	case utils.StrategySnowflake:
+		gen, err := a.snowflakeGenerator()
+		if err != nil {
+			return fmt.Errorf("failed to initialize Snowflake: %w", err)
+		}
+		gen.SetPermutation(perm)
+		a.service.SetIDGenerator(gen)
+	case utils.StrategyRandom:
+		a.service.SetIDGenerator(utils.NewRandomGenerator(cfg.RandomLength))
+	case utils.StrategySequence:
+		gen := utils.NewSequenceGenerator(a.repo, cfg.SequenceOffset)
+		gen.SetPermutation(perm)
+		a.service.SetIDGenerator(gen)
+	}
+	log.Printf("Short codes generated with strategy: %s", cfg.Strategy)
+	return nil
+}
+
+// snowflakeGenerator creates the snowflake generator, with a worker ID
+// leased in Redis when snowflake.auto_worker_id is set
+func (a *App) snowflakeGenerator() (*utils.SnowflakeGenerator, error) {
+	cfg := a.cfg.Snowflake
+	epoch, err := cfg.EpochTime()
+	if err != nil {
+		return nil, fmt.Errorf("invalid epoch: %w", err)
+	}
+
+	workerID := cfg.WorkerID
+	var lease *cache.WorkerIDLease
+	if cfg.AutoWorkerID {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		lease, err = cache.LeaseWorkerID(ctx, a.redisCache.GetClient(), cfg.DatacenterID,
+			utils.MaxSnowflakeID+1, time.Duration(cfg.LeaseTTL)*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		a.onClose(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			lease.Release(ctx)
+		})
+		workerID = lease.WorkerID()
+		log.Printf("Leased snowflake worker ID %d (datacenter %d)", workerID, cfg.DatacenterID)
+	}
+
+	gen, err := utils.NewSnowflakeGenerator(cfg.DatacenterID, workerID, epoch)
+	if err != nil {
+		return nil, err
+	}
+	if lease != nil {
+		gen.SetLease(lease)
+		a.addJob(lease.Run)
+	}
+
+	clock := cache.NewSnowflakeClock(a.redisCache.GetClient(), utils.SnowflakeNodeID(cfg.DatacenterID, workerID))
+	if err := a.checkSnowflakeClock(gen, clock); err != nil {
+		return nil, err
+	}
+	return gen, nil
+}
+
+// checkSnowflakeClock compares the clock with the newest ID generated with
+// the same node ID before (see cache/snowflake_clock.go): a clock behind by
+// up to snowflake.clock_tolerance is waited out, a clock further behind
+// holds back IDs until it catches up
+func (a *App) checkSnowflakeClock(gen *utils.SnowflakeGenerator, clock *cache.SnowflakeClock) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	last, err := clock.Last(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read the snowflake clock: %w", err)
+	}
+
+	// The next ID must be at least a millisecond after the last one
+	notBefore := last.Add(time.Millisecond)
+	behind := time.Until(notBefore)
+	tolerance := time.Duration(a.cfg.Snowflake.ClockTolerance) * time.Millisecond
+	switch {
+	case last.IsZero() || behind <= 0:
+	case behind <= tolerance:
+		log.Printf("Clock is %v behind the last snowflake ID of this node, waiting", behind.Round(time.Millisecond))
+		time.Sleep(behind)
+	default:
+		log.Printf("Warning: clock is %v behind the last snowflake ID of this node; "+
+			"short codes can't be generated until it catches up", behind.Round(time.Millisecond))
+		gen.SetNotBefore(notBefore)
+	}
+
+	a.addJob(func(ctx context.Context) { clock.Keep(ctx, gen.LastTimestamp) })
+	a.onClose(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		clock.Record(ctx, gen.LastTimestamp())
+	})
+	return nil
+}
+
+// initDestinations keeps links from pointing at internal addresses,
+// upgrades http:// destinations to https:// when they support it and
+// fetches destination page titles
+func (a *App) initDestinations() error {
+	cfg := a.cfg.Destinations
+	if cfg.BlockPrivate {
+		policy, err := service.NewDestinationPolicy(
+			cfg.AllowedHosts,
+			cfg.AllowedCIDRs,
+			time.Duration(cfg.ResolveTimeout)*time.Millisecond,
+		)
+		if err != nil {
+			return fmt.Errorf("invalid destinations settings: %w", err)
+		}
+		a.service.SetDestinationPolicy(policy)
+		a.destinations = policy
+	}
+	if cfg.UpgradeHTTPS {
+		a.service.SetHTTPSUpgrade(linkcheck.NewChecker(time.Duration(cfg.UpgradeTimeout)*time.Millisecond, a.dialControl()))
+	}
+	if titles := a.cfg.LinkTitles; titles.Enabled {
+		fetcher := linkcheck.NewTitleFetcher(time.Duration(titles.Timeout)*time.Millisecond, titles.MaxBodyBytes, a.dialControl())
+		a.service.SetTitleFetcher(fetcher, titles.Concurrency)
+	}
+	return nil
+}
+
+// dialControl returns the destination policy's check for outgoing
+// connections to user-chosen URLs, or nil when private addresses are allowed
+func (a *App) dialControl() func(network, address string, c syscall.RawConn) error {
+	if a.destinations == nil {
+		return nil
+	}
+	return a.destinations.Control
+}
+
+// initReservedCodes loads the reserved codes: built-in route names, config
+// and the reserved_codes table
+func (a *App) initReservedCodes() error {
+	cfg := a.cfg.ShortCodes
+	a.service.SetReservedCodes(cfg.Reserved, cfg.BlockedWords)
+	a.service.SetCodeRecycling(cfg.Recycling.Enabled)
+	if err := a.service.ReloadReservedCodes(context.Background()); err != nil {
+		log.Printf("Warning: failed to load reserved codes from database: %v", err)
+	}
+	return nil
+}
+
+// initEvents publishes click events to Kafka/NATS when configured and
+// resolves visitor country/city when a GeoIP database is configured
+func (a *App) initEvents() error {
+	cfg := a.cfg
+	var publisher events.Publisher = events.NoopPublisher{}
+	var err error
+	switch cfg.Events.Backend {
+	case events.BackendKafka:
+		publisher, err = events.NewKafkaPublisher(cfg.Events.Kafka.Brokers, cfg.Events.Kafka.Topic)
+	case events.BackendNATS:
+		publisher, err = events.NewNATSPublisher(cfg.Events.NATS.URL, cfg.Events.NATS.Subject)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to initialize event publisher: %w", err)
+	}
+	a.onClose(func() { publisher.Close() })
+	a.service.SetEventPublisher(publisher, cfg.Events.IPHashSalt)
+	a.service.SetPrivacy(service.Privacy{
+		IPStorage:  cfg.Privacy.IPStorage,
+		IPHashSalt: cfg.Privacy.IPHashSalt,
+		RespectDNT: cfg.Privacy.RespectDNT,
+	})
+	a.service.SetLinkSigning(service.LinkSigning{
+		Secret:     cfg.SignedLinks.Secret,
+		MaxTTL:     time.Duration(cfg.SignedLinks.MaxTTL) * time.Second,
+		RequireAll: cfg.SignedLinks.RequireAll,
+	})
+	a.service.SetNamespaces(cfg.Auth.Namespaces)
+
+	if cfg.VisitLog.GeoIPDatabase != "" {
+		geo, err := enrich.NewMaxMindGeoLocator(cfg.VisitLog.GeoIPDatabase)
+		if err != nil {
+			return fmt.Errorf("failed to initialize GeoIP: %w", err)
+		}
+		a.onClose(func() { geo.Close() })
+		a.service.SetGeoLocator(geo)
+	}
+	return nil
+}
+
+// initAbuse flags links with anomalous traffic or many reports, and
+// throttles or disables them
+func (a *App) initAbuse() error {
+	cfg := a.cfg.Abuse
+	if cfg.Enabled {
+		var datacenters *abuse.DatacenterMatcher
+		if cfg.ASNDatabase != "" {
+			var err error
+			datacenters, err = abuse.NewDatacenterMatcher(cfg.ASNDatabase, cfg.DatacenterASNs)
+			if err != nil {
+				return fmt.Errorf("failed to initialize abuse detection: %w", err)
+			}
+			a.onClose(func() { datacenters.Close() })
+		}
+		detector := abuse.NewDetector(a.redisCache.GetClient(), abuseDetectorConfig(cfg), datacenters)
+		a.service.SetAbuseDetection(detector)
+	}
+	if cfg.WebhookURL != "" {
+		a.service.SetAbuseNotifier(abuse.NewWebhookNotifier(cfg.WebhookURL, time.Duration(cfg.WebhookTimeout)*time.Millisecond))
+	}
+	a.service.SetReportThreshold(cfg.ReportThreshold, cfg.ReportAction)
+	return nil
+}
+
+// initReports sets up sending daily/weekly summary reports
+func (a *App) initReports() error {
+	cfg := a.cfg.Reports
+	if !cfg.Enabled {
+		return nil
+	}
+	var smtpConfig *summary.SMTPConfig
+	if cfg.SMTP.Host != "" {
+		smtpConfig = &summary.SMTPConfig{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.SMTP.From,
+		}
+	}
+	// Users choose webhook URLs, so they get the same checks as links
+	sender, err := summary.NewSender(smtpConfig, cfg.Template,
+		time.Duration(cfg.WebhookTimeout)*time.Millisecond, a.dialControl())
+	if err != nil {
+		return fmt.Errorf("invalid reports settings: %w", err)
+	}
+	schedule, err := summary.ParseSchedule(cfg.DailyAt, cfg.WeeklyDay)
+	if err != nil {
+		return fmt.Errorf("invalid reports settings: %w", err)
+	}
+	a.summarySender = sender
+	a.summarySchedule = schedule
+	a.service.SetSummaryOptions(cfg.TopLinks, sender.EmailEnabled())
+	return nil
+}
@@ -0,0 +1,180 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Monthlyaway/short-link/config"
+	"github.com/Monthlyaway/short-link/internal/cache"
+	"github.com/Monthlyaway/short-link/internal/filter"
+	"github.com/Monthlyaway/short-link/internal/repository"
+	"github.com/Monthlyaway/short-link/internal/shard"
+)
+
+// initStorage connects to MySQL and Redis and creates the bloom filter
+func (a *App) initStorage() error {
+	cfg := a.cfg
+
+	// Initialize MySQL repository
+	pool := repository.PoolConfig{
+		MaxIdleConns:    cfg.MySQL.MaxIdleConns,
+		MaxOpenConns:    cfg.MySQL.MaxOpenConns,
+		ConnMaxLifetime: time.Duration(cfg.MySQL.ConnMaxLifetime) * time.Second,
+		ConnMaxIdleTime: time.Duration(cfg.MySQL.ConnMaxIdleTime) * time.Second,
+	}
+	repo, err := repository.NewURLRepository(cfg.MySQL.DSN(), cfg.MySQL.ReplicaDSNs(), pool)
+	if err != nil {
+		return fmt.Errorf("failed to initialize repository: %w", err)
+	}
+	a.onClose(func() { repo.Close() })
+	a.repo = repo
+	a.links = repo
+	a.shards = []*repository.URLRepository{repo}
+
+	// Spread links over the MySQL shards
+	if len(cfg.MySQL.Shards) > 0 {
+		sharded, err := OpenShards(&cfg.MySQL, repo, pool)
+		if err != nil {
+			return err
+		}
+		a.onClose(func() { sharded.Close() })
+		a.links = sharded
+		a.shards = a.shards[:0]
+		for _, s := range sharded.Shards() {
+			a.shards = append(a.shards, s.Repo)
+		}
+		log.Printf("Links are sharded over %d MySQL databases", len(a.shards))
+	}
+
+	// Optionally apply pending migrations at startup (development convenience)
+	if cfg.MySQL.MigrateOnStartup {
+		for _, db := range a.databases() {
+			if err := db.Migrate(context.Background(), "up"); err != nil {
+				return fmt.Errorf("failed to migrate database: %w", err)
+			}
+		}
+	}
+
+	// Initialize Redis cache
+	redisCache, err := cache.NewRedisCache(
+		cfg.Redis.Addr(),
+		cfg.Redis.Password,
+		cfg.Redis.DB,
+		cfg.Redis.PoolSize,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize Redis cache: %w", err)
+	}
+	a.onClose(func() { redisCache.Close() })
+	a.redisCache = redisCache
+	redisCache.SetSlowCommandThreshold(time.Duration(cfg.Redis.SlowCommandThreshold) * time.Millisecond)
+	redisCache.ConfigureTTL(
+		time.Duration(cfg.Cache.TTL)*time.Second,
+		time.Duration(cfg.Cache.TTLJitter)*time.Second,
+	)
+
+	// Keep link entries in Memcached; Redis still carries everything else
+	if cfg.Cache.Backend == "memcached" {
+		memcached, err := cache.NewMemcachedBackend(
+			cfg.Cache.Memcached.Servers,
+			time.Duration(cfg.Cache.Memcached.Timeout)*time.Millisecond,
+			cfg.Cache.Memcached.MaxIdleConns,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to initialize Memcached cache: %w", err)
+		}
+		a.onClose(func() { memcached.Close() })
+		a.memcached = memcached
+		redisCache.UseBackend(memcached)
+	}
+
+	// Add the local LRU tier for hot short codes
+	if cfg.Cache.Local.Enabled {
+		redisCache.EnableLocalCache(cfg.Cache.Local.Size, time.Duration(cfg.Cache.Local.TTL)*time.Second)
+	}
+
+	// Add the disk tier serving hot links while Redis is down
+	if cfg.Cache.Disk.Enabled {
+		disk, err := cache.OpenDiskCache(cfg.Cache.Disk.Path, cfg.Cache.Disk.MaxEntries, int64(cfg.Cache.Disk.MaxBytes))
+		if err != nil {
+			return fmt.Errorf("failed to open disk cache: %w", err)
+		}
+		redisCache.EnableDiskCache(disk)
+		a.addJob(func(ctx context.Context) {
+			disk.Keep(ctx, time.Duration(cfg.Cache.Disk.SyncInterval)*time.Second)
+		})
+		a.onClose(func() {
+			if err := disk.Close(); err != nil {
+				log.Printf("Warning: failed to write disk cache: %v", err)
+			}
+		})
+	}
+
+	// Initialize Bloom filter
+	a.bloom = filter.NewBloomFilter(
+		cfg.BloomFilter.Capacity,
+		cfg.BloomFilter.FalsePositiveRate,
+	)
+	a.bloom.SetAutoResize(cfg.BloomFilter.AutoResize)
+	return nil
+}
+
+// OpenShards connects to the shards of mysql.shards and returns the
+// repository routing links over them, with everything else in home
+// A shard configured as the primary database uses home's connections.
+// Close releases the others.
+func OpenShards(mysql *config.MySQLConfig, home *repository.URLRepository, pool repository.PoolConfig) (_ *repository.ShardedRepository, err error) {
+	var shards []repository.Shard
+	var placed []string
+	defer func() {
+		if err != nil {
+			for _, s := range shards {
+				if s.Repo != home {
+					s.Repo.Close()
+				}
+			}
+		}
+	}()
+
+	for _, s := range mysql.Shards {
+		db := home
+		if dsn := mysql.ShardDSN(s); dsn != mysql.DSN() {
+			if db, err = repository.NewURLRepository(dsn, nil, pool); err != nil {
+				return nil, fmt.Errorf("failed to connect to shard %s: %w", s.Name, err)
+			}
+		}
+		shards = append(shards, repository.Shard{Name: s.Name, Repo: db})
+		if !s.Draining {
+			placed = append(placed, s.Name)
+		}
+	}
+
+	ring, err := shard.NewRing(placed, shard.DefaultVirtualNodes)
+	if err != nil {
+		return nil, err
+	}
+	return repository.NewShardedRepository(home, ring, shards, mysql.Rebalancing)
+}
+
+// databases returns every MySQL database the server uses, home first
+func (a *App) databases() []*repository.URLRepository {
+	databases := []*repository.URLRepository{a.repo}
+	for _, db := range a.shards {
+		if db != a.repo {
+			databases = append(databases, db)
+		}
+	}
+	return databases
+}
+
+// pingMySQL checks that every MySQL database is reachable
+func (a *App) pingMySQL(ctx context.Context) error {
+	for _, db := range a.databases() {
+		if err := db.Ping(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
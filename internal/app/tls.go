@@ -0,0 +1,114 @@
+package app
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Monthlyaway/short-link/config"
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// ============================================================================
+// TLS / HTTP/2
+// ============================================================================
+// Small deployments without a reverse proxy can terminate HTTPS here:
+// - cert_file/key_file: static certificate
+// - autocert:           Let's Encrypt certificates, obtained and renewed
+//                       automatically (needs port 80 or 443 reachable)
+// - redirect_http:      plain HTTP listener that redirects to HTTPS (and
+//                       answers ACME http-01 challenges for autocert)
+//
+// HTTP/2 is negotiated automatically over TLS. Behind a proxy that speaks
+// HTTP/2 in cleartext, enable h2c instead.
+// ============================================================================
+
+// startServer starts srv according to the TLS config
+// Returns the HTTP redirect server, if any, so it can be shut down too
+func startServer(cfg *config.ServerConfig, srv *http.Server) (*http.Server, error) {
+	tlsCfg := cfg.TLS
+	if !tlsCfg.Enabled {
+		if cfg.H2C {
+			srv.Handler = h2c.NewHandler(srv.Handler, &http2.Server{})
+		}
+		go func() {
+			log.Printf("Server starting on port %d...", cfg.Port)
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Failed to start server: %v", err)
+			}
+		}()
+		return nil, nil
+	}
+
+	var challengeHandler func(http.Handler) http.Handler
+	switch {
+	case tlsCfg.Autocert.Enabled:
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(tlsCfg.Autocert.Domains...),
+			Cache:      autocert.DirCache(tlsCfg.Autocert.CacheDir),
+			Email:      tlsCfg.Autocert.Email,
+		}
+		srv.TLSConfig = manager.TLSConfig()
+		challengeHandler = manager.HTTPHandler
+	case tlsCfg.CertFile != "" && tlsCfg.KeyFile != "":
+		cert, err := tls.LoadX509KeyPair(tlsCfg.CertFile, tlsCfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	default:
+		return nil, fmt.Errorf("tls.enabled requires cert_file and key_file, or autocert")
+	}
+	srv.TLSConfig.MinVersion = tls.VersionTLS12
+
+	go func() {
+		log.Printf("HTTPS server starting on port %d...", cfg.Port)
+		// Certificates come from TLSConfig; HTTP/2 is enabled automatically
+		if err := srv.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to start HTTPS server: %v", err)
+		}
+	}()
+
+	if !tlsCfg.RedirectHTTP {
+		return nil, nil
+	}
+
+	var handler http.Handler = redirectToHTTPS(cfg.Port)
+	if challengeHandler != nil {
+		handler = challengeHandler(handler)
+	}
+	redirect := &http.Server{
+		Addr:         fmt.Sprintf(":%d", tlsCfg.HTTPPort),
+		Handler:      handler,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+	}
+	go func() {
+		log.Printf("HTTP redirect server starting on port %d...", tlsCfg.HTTPPort)
+		if err := redirect.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to start HTTP redirect server: %v", err)
+		}
+	}()
+	return redirect, nil
+}
+
+// redirectToHTTPS redirects every request to the same URL over HTTPS
+func redirectToHTTPS(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
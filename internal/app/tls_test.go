@@ -0,0 +1,31 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRedirectToHTTPS tests that the redirect keeps host, path and query
+func TestRedirectToHTTPS(t *testing.T) {
+	cases := []struct {
+		port     int
+		host     string
+		expected string
+	}{
+		{443, "s.example.com", "https://s.example.com/abc123?x=1"},
+		{443, "s.example.com:80", "https://s.example.com/abc123?x=1"},
+		{8443, "s.example.com:8080", "https://s.example.com:8443/abc123?x=1"},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", "http://"+tc.host+"/abc123?x=1", nil)
+		w := httptest.NewRecorder()
+		redirectToHTTPS(tc.port).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusMovedPermanently, w.Code)
+		assert.Equal(t, tc.expected, w.Header().Get("Location"))
+	}
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ============================================================================
+// LINK CACHE BACKENDS
+// ============================================================================
+// The encoded link entries (short:code:<code>) can live in Redis, the
+// default, or in Memcached (cache.backend). Everything else in this package
+// - the local tier's invalidations, queues, counters, leases - stays in
+// Redis, so Redis is required either way.
+// ============================================================================
+
+// Backend stores encoded link cache entries under their keys
+type Backend interface {
+	// Get returns the value of key; found is false on a miss
+	Get(ctx context.Context, key string) (value string, found bool, err error)
+	// Set stores value under key for ttl
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	// SetIfNewer stores value unless the current entry is a newer version
+	// of the link than updatedAt (Unix ms); stored reports which happened
+	SetIfNewer(ctx context.Context, key, value string, ttl time.Duration, updatedAt int64) (stored bool, err error)
+	// Delete removes key; deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+	// Ping checks that the backend is reachable
+	Ping(ctx context.Context) error
+}
+
+// redisBackend keeps link entries in Redis
+type redisBackend struct {
+	client *redis.Client
+}
+
+// setIfNewerScript stores a cache entry unless the current one is a newer
+// version of the link, so a fill from a stale read (a lagging replica, cache
+// warmup racing an edit) can't overwrite a fresher entry
+// KEYS[1] = cache key
+// ARGV[1] = encoded entry, ARGV[2] = TTL in milliseconds,
+// ARGV[3] = schema version, ARGV[4] = entry's updated_at (Unix ms)
+// Returns 1 if stored, 0 if a newer entry was kept
+var setIfNewerScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if current then
+  local ok, entry = pcall(cjson.decode, current)
+  if ok and type(entry) == 'table' and tonumber(entry['v']) == tonumber(ARGV[3])
+    and tonumber(entry['upd'] or 0) > tonumber(ARGV[4]) then
+    return 0
+  end
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return 1
+`)
+
+func (b *redisBackend) Get(ctx context.Context, key string) (string, bool, error) {
+	val, err := b.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get from Redis: %w", err)
+	}
+	return val, true, nil
+}
+
+func (b *redisBackend) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	if err := b.client.Set(ctx, key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to set in Redis: %w", err)
+	}
+	return nil
+}
+
+func (b *redisBackend) SetIfNewer(ctx context.Context, key, value string, ttl time.Duration, updatedAt int64) (bool, error) {
+	stored, err := setIfNewerScript.Run(ctx, b.client, []string{key},
+		value, ttl.Milliseconds(), CachedMappingVersion, updatedAt).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to set in Redis: %w", err)
+	}
+	return stored == 1, nil
+}
+
+func (b *redisBackend) Delete(ctx context.Context, key string) error {
+	if err := b.client.Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("failed to delete from Redis: %w", err)
+	}
+	return nil
+}
+
+func (b *redisBackend) Ping(ctx context.Context) error {
+	return b.client.Ping(ctx).Err()
+}
@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis lists of the write-behind creation queue
+// Entries move from pending to processing while a worker inserts them, and
+// to failed once they can't be inserted.
+const (
+	CreationQueueKey      = "short:creations:pending"
+	CreationProcessingKey = "short:creations:processing"
+	CreationFailedKey     = "short:creations:failed"
+)
+
+// PendingCreation is a link accepted by the API but not yet in MySQL
+type PendingCreation struct {
+	Mapping  model.URLMapping `json:"mapping"`
+	Tags     []string         `json:"tags,omitempty"`
+	Actor    string           `json:"actor,omitempty"` // For the first revision
+	Attempts int              `json:"attempts,omitempty"`
+	QueuedAt time.Time        `json:"queued_at"`
+}
+
+// QueueCreation appends a link to the pending queue
+func (r *RedisCache) QueueCreation(ctx context.Context, creation *PendingCreation) error {
+	data, err := json.Marshal(creation)
+	if err != nil {
+		return fmt.Errorf("failed to encode pending creation: %w", err)
+	}
+	if err := r.client.RPush(ctx, CreationQueueKey, data).Err(); err != nil {
+		return fmt.Errorf("failed to queue creation: %w", err)
+	}
+	return nil
+}
+
+// ClaimCreation moves the oldest pending link to the processing list and
+// returns it with its raw entry (to complete, retry or fail it with)
+// Returns nil when the queue is empty. Entries that can't be decoded are
+// moved to the failed list.
+func (r *RedisCache) ClaimCreation(ctx context.Context) (*PendingCreation, string, error) {
+	for {
+		raw, err := r.client.LMove(ctx, CreationQueueKey, CreationProcessingKey, "LEFT", "RIGHT").Result()
+		if err == redis.Nil {
+			return nil, "", nil
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to claim creation: %w", err)
+		}
+
+		var creation PendingCreation
+		if err := json.Unmarshal([]byte(raw), &creation); err != nil {
+			fmt.Printf("Moving undecodable pending creation to %s: %v\n", CreationFailedKey, err)
+			if err := r.FailCreation(ctx, raw); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+		return &creation, raw, nil
+	}
+}
+
+// CompleteCreation removes a claimed entry once the link is in MySQL
+func (r *RedisCache) CompleteCreation(ctx context.Context, raw string) error {
+	if err := r.client.LRem(ctx, CreationProcessingKey, 1, raw).Err(); err != nil {
+		return fmt.Errorf("failed to complete creation: %w", err)
+	}
+	return nil
+}
+
+// RetryCreation puts a claimed entry back at the end of the queue with its
+// attempt count raised, so a failing entry doesn't hold up the others
+func (r *RedisCache) RetryCreation(ctx context.Context, raw string, creation *PendingCreation) error {
+	retry := *creation
+	retry.Attempts++
+	data, err := json.Marshal(&retry)
+	if err != nil {
+		return fmt.Errorf("failed to encode pending creation: %w", err)
+	}
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LRem(ctx, CreationProcessingKey, 1, raw)
+		pipe.RPush(ctx, CreationQueueKey, data)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to requeue creation: %w", err)
+	}
+	return nil
+}
+
+// FailCreation moves a claimed entry to the failed list for an operator
+func (r *RedisCache) FailCreation(ctx context.Context, raw string) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LRem(ctx, CreationProcessingKey, 1, raw)
+		pipe.RPush(ctx, CreationFailedKey, raw)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to move creation to the failed list: %w", err)
+	}
+	return nil
+}
+
+// ProcessingCreations returns the raw entries currently claimed by workers
+func (r *RedisCache) ProcessingCreations(ctx context.Context) ([]string, error) {
+	values, err := r.client.LRange(ctx, CreationProcessingKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processing creations: %w", err)
+	}
+	return values, nil
+}
+
+// requeueStaleScript moves an entry from processing back to pending unless
+// a worker completed it in the meantime
+// KEYS[1] = processing list, KEYS[2] = pending list, ARGV[1] = raw entry
+// Returns 1 if requeued
+var requeueStaleScript = redis.NewScript(`
+if redis.call('LREM', KEYS[1], 1, ARGV[1]) > 0 then
+  redis.call('LPUSH', KEYS[2], ARGV[1])
+  return 1
+end
+return 0
+`)
+
+// RequeueStaleCreation puts an entry whose worker went away (crashed or was
+// stopped mid-insert) back at the front of the queue
+// Reports whether it was still claimed.
+func (r *RedisCache) RequeueStaleCreation(ctx context.Context, raw string) (bool, error) {
+	n, err := requeueStaleScript.Run(ctx, r.client, []string{CreationProcessingKey, CreationQueueKey}, raw).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to requeue stale creation: %w", err)
+	}
+	return n == 1, nil
+}
+
+// QueuedCreationCodes returns the short codes of links not yet in MySQL
+func (r *RedisCache) QueuedCreationCodes(ctx context.Context) ([]string, error) {
+	var codes []string
+	for _, key := range []string{CreationQueueKey, CreationProcessingKey} {
+		values, err := r.client.LRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list queued creations: %w", err)
+		}
+		for _, value := range values {
+			var creation PendingCreation
+			if json.Unmarshal([]byte(value), &creation) == nil {
+				codes = append(codes, creation.Mapping.ShortCode)
+			}
+		}
+	}
+	return codes, nil
+}
+
+// PendingCreations returns the number of links waiting to be inserted
+func (r *RedisCache) PendingCreations(ctx context.Context) (int64, error) {
+	n, err := r.client.LLen(ctx, CreationQueueKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count pending creations: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,432 @@
+package cache
+
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// DISK TIER
+// ============================================================================
+// DiskCache keeps the link entries this node recently read from Redis in a
+// journal file, one JSON object per line:
+//
+//   {"k":"abc123","v":"{\"v\":5,\"url\":\"https://...\"}","exp":1718000000123}
+//   {"k":"abc123","del":true}
+//
+// It is only read when Redis fails, so a node keeps redirecting its hot
+// links through a Redis outage (and, with degraded mode, a MySQL outage
+// too), even if it restarts meanwhile. The entry count and the bytes of
+// keys and values are capped; the least recently used entries go first.
+//
+// New and changed entries are appended and flushed by Sync; deletes are
+// written at once, so a restart doesn't bring back a deleted link. Once the
+// journal is mostly replaced entries, Sync rewrites it with the live ones.
+// A lock file keeps a second process from opening the same journal.
+//
+// Entries expire with the cache TTL they were read with. RedisCache drops
+// them on Delete (even while Redis is down), on invalidations published by
+// other instances and when Redis no longer has the link.
+// ============================================================================
+
+// diskCompactMinRecords is how many replaced records the journal may hold
+// beyond twice the live entries before Sync rewrites it
+const diskCompactMinRecords = 1024
+
+// ErrDiskCacheLocked is returned when another process has the disk cache open
+var ErrDiskCacheLocked = errors.New("disk cache is in use by another process")
+
+// DiskCache is a size-bounded LRU of cache entries persisted to a journal
+type DiskCache struct {
+	path       string
+	maxEntries int
+	maxBytes   int64
+	lock       *os.File // Held until Close
+
+	// syncMu serializes Sync and Close, which may replace file
+	syncMu sync.Mutex
+
+	mu      sync.Mutex
+	items   map[string]*list.Element
+	order   *list.List // Front = most recently used
+	bytes   int64      // Key and value bytes of all entries
+	file    *os.File   // The journal; nil while loading
+	journal *bufio.Writer
+	records int      // Lines in the journal
+	since   [][]byte // Lines journaled during a rewrite, nil otherwise
+
+	// now returns the current time (overridable in tests)
+	now func() time.Time
+}
+
+// diskEntry is a single entry, in memory and in the journal
+type diskEntry struct {
+	Key       string `json:"k"`
+	Value     string `json:"v,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"` // Unix ms
+	Deleted   bool   `json:"del,omitempty"` // Journal only
+
+	journaled int64 // ExpiresAt of the entry's last record
+}
+
+func (e *diskEntry) size() int64 {
+	return int64(len(e.Key) + len(e.Value))
+}
+
+// OpenDiskCache loads the entries stored at path, if any, and keeps at most
+// maxEntries entries of at most maxBytes in total
+// Unreadable lines (a write cut short by a crash) are skipped. Close
+// releases the file for other processes.
+func OpenDiskCache(path string, maxEntries int, maxBytes int64) (_ *DiskCache, err error) {
+	d := &DiskCache{
+		path:       path,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		items:      make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create disk cache directory: %w", err)
+	}
+	if d.lock, err = lockFile(path + ".lock"); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			d.lock.Close()
+		}
+	}()
+
+	torn, err := d.load()
+	if err != nil {
+		return nil, err
+	}
+	if torn {
+		// Start from a clean file, so the next record doesn't land on
+		// the end of the broken line
+		if err := d.compact(); err != nil {
+			return nil, err
+		}
+		return d, nil
+	}
+	if d.file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to open disk cache: %w", err)
+	}
+	d.journal = bufio.NewWriter(d.file)
+	return d, nil
+}
+
+// load replays the journal into memory and reports whether it had
+// unreadable lines
+func (d *DiskCache) load() (bool, error) {
+	f, err := os.Open(d.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open disk cache: %w", err)
+	}
+	defer f.Close()
+
+	// Records are in the order they were written
+	torn := false
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	nowMillis := d.now().UnixMilli()
+	for scanner.Scan() {
+		var entry diskEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Key == "" {
+			torn = true
+			continue
+		}
+		d.records++
+		if entry.Deleted || entry.ExpiresAt <= nowMillis {
+			if elem, ok := d.items[entry.Key]; ok {
+				d.unlink(elem)
+			}
+			continue
+		}
+		entry.journaled = entry.ExpiresAt
+		d.put(&entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read disk cache: %w", err)
+	}
+	return torn, nil
+}
+
+// Get returns the value stored for key, if present and not expired
+func (d *DiskCache) Get(key string) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	elem, ok := d.items[key]
+	if !ok {
+		return "", false
+	}
+	entry := elem.Value.(*diskEntry)
+	if entry.ExpiresAt <= d.now().UnixMilli() {
+		d.removeElement(elem)
+		return "", false
+	}
+	d.order.MoveToFront(elem)
+	return entry.Value, true
+}
+
+// Set stores a value for ttl, evicting the least recently used entries
+// beyond the caps
+func (d *DiskCache) Set(key, value string, ttl time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.store(&diskEntry{Key: key, Value: value, ExpiresAt: d.now().Add(ttl).UnixMilli()})
+}
+
+// Update replaces the value of key if it is stored, so entries don't go
+// stale when the link is cached anew
+func (d *DiskCache) Update(key, value string, ttl time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.items[key]; ok {
+		d.store(&diskEntry{Key: key, Value: value, ExpiresAt: d.now().Add(ttl).UnixMilli()})
+	}
+}
+
+// Delete removes a key, writing the delete to the journal at once
+func (d *DiskCache) Delete(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if elem, ok := d.items[key]; ok {
+		d.removeElement(elem)
+		// A failed write sticks to the journal and is reported by Sync
+		_ = d.journal.Flush()
+	}
+}
+
+// Len returns the number of entries (including expired ones not yet evicted)
+func (d *DiskCache) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.order.Len()
+}
+
+// Sync writes the journaled records to disk, and rewrites the journal with
+// just the live entries once it is mostly replaced ones
+func (d *DiskCache) Sync() error {
+	d.syncMu.Lock()
+	defer d.syncMu.Unlock()
+	return d.sync()
+}
+
+// sync implements Sync; d.syncMu is held
+func (d *DiskCache) sync() error {
+	d.mu.Lock()
+	compact := d.records > 2*d.order.Len()+diskCompactMinRecords
+	err := d.journal.Flush()
+	d.mu.Unlock()
+
+	// A failed write can't be retried on the same journal; rewriting it
+	// starts a new one
+	if compact || err != nil {
+		return d.compact()
+	}
+	if err := d.file.Sync(); err != nil {
+		return fmt.Errorf("failed to write disk cache: %w", err)
+	}
+	return nil
+}
+
+// Close writes the journal to disk and releases the file
+func (d *DiskCache) Close() error {
+	d.syncMu.Lock()
+	defer d.syncMu.Unlock()
+
+	err := d.sync()
+	d.mu.Lock()
+	if closeErr := d.file.Close(); err == nil {
+		err = closeErr
+	}
+	d.mu.Unlock()
+	d.lock.Close()
+	return err
+}
+
+// Keep calls Sync every interval until ctx is done
+func (d *DiskCache) Keep(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.Sync(); err != nil {
+				log.Printf("Warning: failed to write disk cache: %v", err)
+			}
+		}
+	}
+}
+
+// compact rewrites the journal with the live entries, from least to most
+// recently used, in a temporary file renamed over it
+// Records journaled meanwhile are carried over. d.syncMu is held (or d is
+// not shared yet).
+func (d *DiskCache) compact() error {
+	d.mu.Lock()
+	nowMillis := d.now().UnixMilli()
+	entries := make([]diskEntry, 0, d.order.Len())
+	for elem := d.order.Back(); elem != nil; elem = elem.Prev() {
+		if entry := elem.Value.(*diskEntry); entry.ExpiresAt > nowMillis {
+			entries = append(entries, *entry)
+		}
+	}
+	d.since = [][]byte{}
+	d.mu.Unlock()
+
+	tmp, w, err := d.writeSnapshot(entries)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	since := d.since
+	d.since = nil
+	if err != nil {
+		return err
+	}
+	if err := d.replaceJournal(tmp, w, since); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if d.file != nil {
+		d.file.Close()
+	}
+	d.file, d.journal = tmp, w
+	d.records = len(entries) + len(since)
+	return nil
+}
+
+// writeSnapshot writes entries to a new temporary file next to the journal
+func (d *DiskCache) writeSnapshot(entries []diskEntry) (*os.File, *bufio.Writer, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(d.path), filepath.Base(d.path)+".*.tmp")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create disk cache file: %w", err)
+	}
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for i := range entries {
+		if err = enc.Encode(&entries[i]); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, nil, fmt.Errorf("failed to write disk cache: %w", err)
+	}
+	return tmp, w, nil
+}
+
+// replaceJournal appends the records journaled during a rewrite to tmp and
+// renames it over the journal; d.mu is held
+func (d *DiskCache) replaceJournal(tmp *os.File, w *bufio.Writer, since [][]byte) error {
+	for _, line := range since {
+		w.Write(line)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write disk cache: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to write disk cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), d.path); err != nil {
+		return fmt.Errorf("failed to replace disk cache: %w", err)
+	}
+	return nil
+}
+
+// store adds or replaces an entry and journals it; d.mu is held
+// Hot links are read back with the same value on every request: their
+// expiry is only journaled again once half their TTL has passed.
+func (d *DiskCache) store(entry *diskEntry) {
+	if elem, ok := d.items[entry.Key]; ok {
+		stored := elem.Value.(*diskEntry)
+		remaining := entry.ExpiresAt - d.now().UnixMilli()
+		if stored.Value == entry.Value && entry.ExpiresAt-stored.journaled < remaining/2 {
+			stored.ExpiresAt = entry.ExpiresAt
+			d.order.MoveToFront(elem)
+			return
+		}
+	}
+	if d.put(entry) {
+		entry.journaled = entry.ExpiresAt
+		d.append(entry)
+	}
+}
+
+// put adds or replaces an entry as the most recently used and reports
+// whether it was kept; d.mu is held (or d is not shared yet)
+func (d *DiskCache) put(entry *diskEntry) bool {
+	if elem, ok := d.items[entry.Key]; ok {
+		d.unlink(elem)
+	}
+	// An entry over the byte cap on its own would evict everything else
+	if entry.size() > d.maxBytes {
+		d.append(&diskEntry{Key: entry.Key, Deleted: true})
+		return false
+	}
+	d.items[entry.Key] = d.order.PushFront(entry)
+	d.bytes += entry.size()
+	for d.order.Len() > d.maxEntries || d.bytes > d.maxBytes {
+		d.removeElement(d.order.Back())
+	}
+	return true
+}
+
+// removeElement removes an entry and journals the delete, so replaying the
+// journal doesn't bring it back; d.mu is held
+func (d *DiskCache) removeElement(elem *list.Element) {
+	d.unlink(elem)
+	d.append(&diskEntry{Key: elem.Value.(*diskEntry).Key, Deleted: true})
+}
+
+// unlink unlinks an element from both the list and the index
+func (d *DiskCache) unlink(elem *list.Element) {
+	entry := elem.Value.(*diskEntry)
+	d.order.Remove(elem)
+	delete(d.items, entry.Key)
+	d.bytes -= entry.size()
+}
+
+// append writes a record to the journal buffer; d.mu is held
+// Records aren't written while the journal is being loaded.
+func (d *DiskCache) append(entry *diskEntry) {
+	if d.journal == nil {
+		return
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+	// Errors stick to the writer and are reported by Sync
+	d.journal.Write(line)
+	d.records++
+	if d.since != nil {
+		d.since = append(d.since, line)
+	}
+}
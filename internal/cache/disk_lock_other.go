@@ -0,0 +1,17 @@
+//go:build !unix
+
+package cache
+
+import (
+	"fmt"
+	"os"
+)
+
+// lockFile opens path; other processes aren't kept out on this platform
+func lockFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open disk cache lock: %w", err)
+	}
+	return f, nil
+}
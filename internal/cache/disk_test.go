@@ -0,0 +1,208 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDiskCachePersistence tests that entries survive reopening the file
+func TestDiskCachePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "links.jsonl")
+	d, err := OpenDiskCache(path, 10, 1<<20)
+	require.NoError(t, err)
+	assert.Equal(t, 0, d.Len())
+
+	d.Set("a", "1", time.Hour)
+	d.Set("b", "2", time.Hour)
+	d.Set("gone", "3", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, d.Close())
+
+	reopened, err := OpenDiskCache(path, 10, 1<<20)
+	require.NoError(t, err)
+	assert.Equal(t, 2, reopened.Len(), "expired entries are left behind")
+	val, ok := reopened.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, "2", val)
+	require.NoError(t, reopened.Close())
+
+	// A line cut short by a crash is skipped, and records written after
+	// it can be read back
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"k":"c","v":"tru`)
+	require.NoError(t, err)
+	f.Close()
+	reopened, err = OpenDiskCache(path, 10, 1<<20)
+	require.NoError(t, err)
+	assert.Equal(t, 2, reopened.Len())
+	reopened.Set("d", "4", time.Hour)
+	require.NoError(t, reopened.Close())
+	reopened, err = OpenDiskCache(path, 10, 1<<20)
+	require.NoError(t, err)
+	assert.Equal(t, 3, reopened.Len())
+	require.NoError(t, reopened.Close())
+}
+
+// TestDiskCacheJournal tests that deletes are written at once and that the
+// journal is rewritten once it is mostly replaced entries
+func TestDiskCacheJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.jsonl")
+	d, err := OpenDiskCache(path, 10, 1<<20)
+	require.NoError(t, err)
+
+	d.Set("a", "1", time.Hour)
+	d.Set("b", "2", time.Hour)
+	require.NoError(t, d.Sync())
+	d.Delete("a")
+
+	// A crash: the process exits without Sync or Close
+	d.file.Close()
+	d.lock.Close()
+	d, err = OpenDiskCache(path, 10, 1<<20)
+	require.NoError(t, err)
+	_, ok := d.Get("a")
+	assert.False(t, ok, "deleted entries don't come back")
+	_, ok = d.Get("b")
+	assert.True(t, ok)
+
+	for i := 0; i < 2*diskCompactMinRecords; i++ {
+		d.Set("b", strconv.Itoa(i), time.Hour)
+	}
+	require.NoError(t, d.Sync())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, bytes.Count(data, []byte("\n")), "only the live entry is left")
+	require.NoError(t, d.Close())
+}
+
+// TestDiskCacheLock tests that only one process at a time opens the file
+func TestDiskCacheLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.jsonl")
+	d, err := OpenDiskCache(path, 10, 1<<20)
+	require.NoError(t, err)
+
+	_, err = OpenDiskCache(path, 10, 1<<20)
+	assert.ErrorIs(t, err, ErrDiskCacheLocked)
+
+	require.NoError(t, d.Close())
+	d, err = OpenDiskCache(path, 10, 1<<20)
+	require.NoError(t, err)
+	require.NoError(t, d.Close())
+}
+
+// TestDiskCacheLimits tests eviction by entry count and by bytes
+func TestDiskCacheLimits(t *testing.T) {
+	d, err := OpenDiskCache(filepath.Join(t.TempDir(), "links.jsonl"), 2, 20)
+	require.NoError(t, err)
+	defer d.Close()
+
+	d.Set("a", "1", time.Hour)
+	d.Set("b", "2", time.Hour)
+	_, ok := d.Get("a") // "b" is now the least recently used
+	assert.True(t, ok)
+	d.Set("c", "3", time.Hour)
+	_, ok = d.Get("b")
+	assert.False(t, ok, "evicted by the entry cap")
+	assert.Equal(t, 2, d.Len())
+
+	d.Set("d", "0123456789abcdefgh", time.Hour)
+	assert.Equal(t, 1, d.Len(), "19 of 20 bytes leave room for nothing else")
+	d.Set("e", "0123456789abcdefghijklmn", time.Hour)
+	_, ok = d.Get("e")
+	assert.False(t, ok, "entries over the byte cap aren't kept")
+	_, ok = d.Get("d")
+	assert.True(t, ok)
+
+	d.Update("x", "1", time.Hour)
+	_, ok = d.Get("x")
+	assert.False(t, ok, "Update only replaces stored entries")
+	d.Update("d", "new", time.Hour)
+	val, _ := d.Get("d")
+	assert.Equal(t, "new", val)
+}
+
+// failingBackend fails every command, like Redis during an outage
+type failingBackend struct{}
+
+var errBackendDown = errors.New("connection refused")
+
+func (failingBackend) Get(context.Context, string) (string, bool, error) {
+	return "", false, errBackendDown
+}
+func (failingBackend) Set(context.Context, string, string, time.Duration) error {
+	return errBackendDown
+}
+func (failingBackend) SetIfNewer(context.Context, string, string, time.Duration, int64) (bool, error) {
+	return false, errBackendDown
+}
+func (failingBackend) Delete(context.Context, string) error { return errBackendDown }
+func (failingBackend) Ping(context.Context) error           { return errBackendDown }
+
+// TestRedisCacheDiskFallback tests that the disk tier serves lookups only
+// while the shared backend fails
+func TestRedisCacheDiskFallback(t *testing.T) {
+	server := startFakeMemcached(t)
+	backend, err := NewMemcachedBackend([]string{server.addr()}, time.Second, 2)
+	require.NoError(t, err)
+	defer backend.Close()
+	disk, err := OpenDiskCache(filepath.Join(t.TempDir(), "links.jsonl"), 10, 1<<20)
+	require.NoError(t, err)
+	defer disk.Close()
+
+	r := &RedisCache{ttl: DefaultTTL, links: backend, disk: disk}
+	ctx := context.Background()
+	require.NoError(t, r.Set(ctx, &model.URLMapping{ShortCode: "abc123", OriginalURL: "https://example.com", Status: 1}))
+	assert.Equal(t, 0, disk.Len(), "only links read back are mirrored")
+
+	_, err = r.Get(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, 1, disk.Len())
+
+	r.links = failingBackend{}
+	mapping, err := r.Get(ctx, "abc123")
+	require.NoError(t, err)
+	require.NotNil(t, mapping)
+	assert.Equal(t, "https://example.com", mapping.OriginalURL)
+	assert.Equal(t, uint64(1), r.Stats().DiskHits)
+
+	_, err = r.Get(ctx, "other")
+	assert.ErrorIs(t, err, errBackendDown, "codes not on disk still fail")
+
+	// Deleting drops the disk and local copies even while the backend is
+	// down, and deletes the backend's once it is back
+	r.local = NewLocalCache(10, time.Minute)
+	r.local.Set("abc123", "cached")
+	assert.Error(t, r.Delete(ctx, "abc123"))
+	assert.Equal(t, 0, disk.Len())
+	_, ok := r.local.Get("abc123")
+	assert.False(t, ok)
+
+	r.links = backend
+	_, found, err := backend.Get(ctx, ShortCodePrefix+"abc123")
+	require.NoError(t, err)
+	assert.True(t, found, "still stale")
+	require.NoError(t, r.Delete(ctx, "other"))
+	_, found, err = backend.Get(ctx, ShortCodePrefix+"abc123")
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Empty(t, r.pending)
+
+	// A link another instance deleted drops out of the disk tier when the
+	// backend no longer has it
+	disk.Set("gone", "stale", time.Hour)
+	mapping, err = r.Get(ctx, "gone")
+	require.NoError(t, err)
+	assert.Nil(t, mapping)
+	assert.Equal(t, 0, disk.Len())
+}
@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ============================================================================
+// TOP-LINKS LEADERBOARD
+// ============================================================================
+// Human clicks are counted per link in Redis sorted sets, one per UTC hour
+// (kept 26 hours) and one per UTC day (kept 31 days):
+//
+//   short:top:h:2024031015  {aB3xY9: 120, x7Kq2: 95, ...}
+//   short:top:d:20240310    {aB3xY9: 2040, ...}
+//
+// A period's ranking is the union of its buckets: the last 24 hourly ones
+// for 24h, the last 7 or 30 daily ones for 7d and 30d, including the
+// current (partial) hour or day. The union is stored for a minute so
+// dashboards polling the endpoint don't recompute it on every request.
+//
+// Buckets are trimmed to their top entries periodically, which keeps memory
+// bounded; links dropped from a bucket lose those clicks, so counts far
+// down the ranking are approximate.
+// ============================================================================
+
+const (
+	// leaderboardPrefix is the prefix for leaderboard keys in Redis
+	leaderboardPrefix = "short:top:"
+	// leaderboardResultTTL is how long a computed period ranking is reused
+	leaderboardResultTTL = time.Minute
+	// leaderboardHourlyTTL and leaderboardDailyTTL keep buckets a little
+	// longer than the longest period that reads them
+	leaderboardHourlyTTL = 26 * time.Hour
+	leaderboardDailyTTL  = 31 * 24 * time.Hour
+)
+
+// Leaderboard periods
+const (
+	Period24h = "24h"
+	Period7d  = "7d"
+	Period30d = "30d"
+)
+
+// LeaderboardEntry is one link in a ranking
+type LeaderboardEntry struct {
+	ShortCode string
+	Clicks    int64
+}
+
+// Leaderboard ranks links by recent human clicks
+type Leaderboard struct {
+	client *redis.Client
+	size   int64 // Entries kept per bucket when trimming
+}
+
+// NewLeaderboard creates a leaderboard whose buckets are trimmed to size entries
+func NewLeaderboard(client *redis.Client, size int) *Leaderboard {
+	return &Leaderboard{client: client, size: int64(size)}
+}
+
+// Record counts one click of a short code at time at
+func (l *Leaderboard) Record(ctx context.Context, shortCode string, at time.Time) error {
+	hourly, daily := hourlyKey(at), dailyKey(at)
+	pipe := l.client.Pipeline()
+	pipe.ZIncrBy(ctx, hourly, 1, shortCode)
+	pipe.Expire(ctx, hourly, leaderboardHourlyTTL)
+	pipe.ZIncrBy(ctx, daily, 1, shortCode)
+	pipe.Expire(ctx, daily, leaderboardDailyTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record click in leaderboard: %w", err)
+	}
+	return nil
+}
+
+// Top returns up to n links with the most clicks in period, most clicked first
+func (l *Leaderboard) Top(ctx context.Context, period string, now time.Time, n int) ([]LeaderboardEntry, error) {
+	keys, err := periodKeys(period, now)
+	if err != nil {
+		return nil, err
+	}
+
+	result := leaderboardPrefix + "p:" + period
+	exists, err := l.client.Exists(ctx, result).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read leaderboard: %w", err)
+	}
+	if exists == 0 {
+		pipe := l.client.TxPipeline()
+		pipe.ZUnionStore(ctx, result, &redis.ZStore{Keys: keys, Aggregate: "SUM"})
+		pipe.Expire(ctx, result, leaderboardResultTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("failed to compute leaderboard: %w", err)
+		}
+	}
+
+	members, err := l.client.ZRevRangeWithScores(ctx, result, 0, int64(n)-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read leaderboard: %w", err)
+	}
+	entries := make([]LeaderboardEntry, 0, len(members))
+	for _, m := range members {
+		code, _ := m.Member.(string)
+		entries = append(entries, LeaderboardEntry{ShortCode: code, Clicks: int64(m.Score)})
+	}
+	return entries, nil
+}
+
+// Trim keeps the top entries of every live bucket and returns how many
+// entries were removed
+func (l *Leaderboard) Trim(ctx context.Context, now time.Time) (int64, error) {
+	var keys []string
+	for i := 0; i < int(leaderboardHourlyTTL/time.Hour); i++ {
+		keys = append(keys, hourlyKey(now.Add(-time.Duration(i)*time.Hour)))
+	}
+	for i := 0; i < int(leaderboardDailyTTL/(24*time.Hour)); i++ {
+		keys = append(keys, dailyKey(now.AddDate(0, 0, -i)))
+	}
+
+	pipe := l.client.Pipeline()
+	cmds := make([]*redis.IntCmd, 0, len(keys))
+	for _, key := range keys {
+		// Ranks are ascending, so this removes everything below the top size
+		cmds = append(cmds, pipe.ZRemRangeByRank(ctx, key, 0, -l.size-1))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to trim leaderboard: %w", err)
+	}
+
+	var removed int64
+	for _, cmd := range cmds {
+		removed += cmd.Val()
+	}
+	return removed, nil
+}
+
+// periodKeys returns the buckets making up a period ending at now
+func periodKeys(period string, now time.Time) ([]string, error) {
+	var keys []string
+	switch period {
+	case Period24h:
+		for i := 0; i < 24; i++ {
+			keys = append(keys, hourlyKey(now.Add(-time.Duration(i)*time.Hour)))
+		}
+	case Period7d, Period30d:
+		days := 7
+		if period == Period30d {
+			days = 30
+		}
+		for i := 0; i < days; i++ {
+			keys = append(keys, dailyKey(now.AddDate(0, 0, -i)))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported period %q (use 24h, 7d or 30d)", period)
+	}
+	return keys, nil
+}
+
+// hourlyKey returns the hourly bucket holding t
+func hourlyKey(t time.Time) string {
+	return leaderboardPrefix + "h:" + t.UTC().Format("2006010215")
+}
+
+// dailyKey returns the daily bucket holding t
+func dailyKey(t time.Time) string {
+	return leaderboardPrefix + "d:" + t.UTC().Format("20060102")
+}
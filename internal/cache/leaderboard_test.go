@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPeriodKeys tests which buckets make up each leaderboard period
+func TestPeriodKeys(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 30, 0, 0, time.UTC)
+
+	keys, err := periodKeys(Period24h, now)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 24)
+	assert.Equal(t, "short:top:h:2024031015", keys[0])
+	assert.Equal(t, "short:top:h:2024030916", keys[23])
+
+	keys, err = periodKeys(Period7d, now)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"short:top:d:20240310", "short:top:d:20240309", "short:top:d:20240308", "short:top:d:20240307",
+		"short:top:d:20240306", "short:top:d:20240305", "short:top:d:20240304",
+	}, keys)
+
+	keys, err = periodKeys(Period30d, now)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 30)
+	assert.Equal(t, "short:top:d:20240210", keys[29])
+
+	// Buckets are UTC regardless of the caller's zone
+	assert.Equal(t, "short:top:h:2024031015", hourlyKey(now.In(time.FixedZone("UTC+8", 8*3600))))
+
+	_, err = periodKeys("1h", now)
+	assert.Error(t, err)
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LocalCache is a size-bounded in-process LRU cache with per-entry TTL
+// It sits in front of Redis for the hottest short codes so redirects can
+// be served without a network round trip
+type LocalCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	items    map[string]*list.Element
+	order    *list.List // Front = most recently used
+
+	// now returns the current time (overridable in tests)
+	now func() time.Time
+}
+
+// localEntry is a single cached value
+type localEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+// NewLocalCache creates a new LRU cache holding at most capacity entries
+func NewLocalCache(capacity int, ttl time.Duration) *LocalCache {
+	return &LocalCache{
+		capacity: capacity,
+		ttl:      ttl,
+		items:    make(map[string]*list.Element, capacity),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// Get returns the cached value for key, if present and not expired
+func (l *LocalCache) Get(key string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	elem, ok := l.items[key]
+	if !ok {
+		return "", false
+	}
+
+	entry := elem.Value.(*localEntry)
+	if l.now().After(entry.expiresAt) {
+		l.removeElement(elem)
+		return "", false
+	}
+
+	l.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Set stores a value, evicting the least recently used entry when full
+func (l *LocalCache) Set(key, value string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	expiresAt := l.now().Add(l.ttl)
+
+	if elem, ok := l.items[key]; ok {
+		entry := elem.Value.(*localEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		l.order.MoveToFront(elem)
+		return
+	}
+
+	elem := l.order.PushFront(&localEntry{
+		key:       key,
+		value:     value,
+		expiresAt: expiresAt,
+	})
+	l.items[key] = elem
+
+	if l.order.Len() > l.capacity {
+		l.removeElement(l.order.Back())
+	}
+}
+
+// Delete removes a key from the cache
+func (l *LocalCache) Delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.items[key]; ok {
+		l.removeElement(elem)
+	}
+}
+
+// Len returns the number of cached entries (including expired ones not yet evicted)
+func (l *LocalCache) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
+
+// removeElement unlinks an element from both the list and the index
+func (l *LocalCache) removeElement(elem *list.Element) {
+	l.order.Remove(elem)
+	delete(l.items, elem.Value.(*localEntry).key)
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLocalCacheEviction tests that the least recently used entry is evicted
+func TestLocalCacheEviction(t *testing.T) {
+	lc := NewLocalCache(2, time.Minute)
+
+	lc.Set("a", "1")
+	lc.Set("b", "2")
+
+	// Touch "a" so "b" becomes the least recently used
+	_, ok := lc.Get("a")
+	assert.True(t, ok)
+
+	lc.Set("c", "3")
+
+	_, ok = lc.Get("b")
+	assert.False(t, ok, "b should have been evicted")
+
+	val, ok := lc.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", val)
+	assert.Equal(t, 2, lc.Len())
+}
+
+// TestLocalCacheTTL tests that entries expire after the TTL
+func TestLocalCacheTTL(t *testing.T) {
+	lc := NewLocalCache(10, time.Minute)
+	now := time.Now()
+	lc.now = func() time.Time { return now }
+
+	lc.Set("a", "1")
+	_, ok := lc.Get("a")
+	assert.True(t, ok)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = lc.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, lc.Len())
+}
+
+// TestLocalCacheDelete tests explicit invalidation
+func TestLocalCacheDelete(t *testing.T) {
+	lc := NewLocalCache(10, time.Minute)
+
+	lc.Set("a", "1")
+	lc.Delete("a")
+
+	_, ok := lc.Get("a")
+	assert.False(t, ok)
+}
+
+// BenchmarkLocalCacheGet measures parallel hits on the local tier
+func BenchmarkLocalCacheGet(b *testing.B) {
+	lc := NewLocalCache(10000, time.Hour)
+	for i := 0; i < 10000; i++ {
+		lc.Set(fmt.Sprintf("code%d", i), "value")
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			lc.Get(fmt.Sprintf("code%d", i%10000))
+			i++
+		}
+	})
+}
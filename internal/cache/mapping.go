@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// CachedMappingVersion is the schema version of CachedMapping
+// Bump it whenever fields change meaning; entries with another version are
+// treated as cache misses, so old and new instances can run side by side
+const CachedMappingVersion = 10
+
+// CachedMapping is the subset of model.URLMapping needed on the redirect
+// hot path, so expiration and status checks don't have to hit MySQL
+type CachedMapping struct {
+	Version     int                `json:"v"`
+	OriginalURL string             `json:"url"`
+	Domain      string             `json:"d,omitempty"`
+	ExpiredAt   *time.Time         `json:"exp,omitempty"`
+	Status      int8               `json:"st"`
+	CacheTTL    int                `json:"ttl,omitempty"` // Per-link TTL in seconds, 0 for the default
+	Rules       *model.AccessRules `json:"rules,omitempty"`
+	Warning     bool               `json:"warn,omitempty"`
+	DeepLinks   *model.DeepLinks   `json:"deep,omitempty"`
+	// DedupMinutes is the visit deduplication window, needed when the visit
+	// is recorded
+	DedupMinutes int `json:"dedup,omitempty"`
+	// NoAnalytics leaves visitor details out of the visit log
+	NoAnalytics bool `json:"na,omitempty"`
+	// Visibility and OrgID let private links be checked on cache hits
+	Visibility string `json:"vis,omitempty"`
+	OrgID      uint   `json:"org,omitempty"`
+	// RedirectDelay is the countdown before redirecting, in seconds
+	RedirectDelay int `json:"delay,omitempty"`
+	// RequireSignature only redirects signed requests
+	RequireSignature bool `json:"sign,omitempty"`
+	// UpdatedAt is the link's updated_at in Unix milliseconds, 0 if unknown
+	// Set refuses to replace an entry with an older one
+	UpdatedAt int64 `json:"upd,omitempty"`
+}
+
+// encodeMapping serializes a mapping for storage in the cache
+func encodeMapping(mapping *model.URLMapping) (string, error) {
+	data, err := json.Marshal(CachedMapping{
+		Version:          CachedMappingVersion,
+		OriginalURL:      mapping.OriginalURL,
+		Domain:           mapping.Domain,
+		ExpiredAt:        mapping.ExpiredAt,
+		Status:           mapping.Status,
+		CacheTTL:         mapping.CacheTTL,
+		Rules:            mapping.AccessRules,
+		Warning:          mapping.Warning,
+		DeepLinks:        mapping.DeepLinks,
+		DedupMinutes:     mapping.VisitDedupMinutes,
+		NoAnalytics:      mapping.NoAnalytics,
+		Visibility:       mapping.Visibility,
+		OrgID:            mapping.OrgID,
+		RedirectDelay:    mapping.RedirectDelay,
+		RequireSignature: mapping.RequireSignature,
+		UpdatedAt:        updatedAtMillis(mapping),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode mapping: %w", err)
+	}
+	return string(data), nil
+}
+
+// decodeMapping parses a cached value back into a mapping
+// Returns nil for values written with a different schema version
+func decodeMapping(shortCode, value string) (*model.URLMapping, error) {
+	var cached CachedMapping
+	if err := json.Unmarshal([]byte(value), &cached); err != nil {
+		return nil, fmt.Errorf("failed to decode mapping: %w", err)
+	}
+	if cached.Version != CachedMappingVersion {
+		return nil, nil
+	}
+	return &model.URLMapping{
+		ShortCode:         shortCode,
+		OriginalURL:       cached.OriginalURL,
+		Domain:            cached.Domain,
+		ExpiredAt:         cached.ExpiredAt,
+		Status:            cached.Status,
+		CacheTTL:          cached.CacheTTL,
+		AccessRules:       cached.Rules,
+		Warning:           cached.Warning,
+		DeepLinks:         cached.DeepLinks,
+		VisitDedupMinutes: cached.DedupMinutes,
+		NoAnalytics:       cached.NoAnalytics,
+		Visibility:        cached.Visibility,
+		OrgID:             cached.OrgID,
+		RedirectDelay:     cached.RedirectDelay,
+		RequireSignature:  cached.RequireSignature,
+		UpdatedAt:         updatedAtTime(cached.UpdatedAt),
+	}, nil
+}
+
+// updatedAtMillis returns a mapping's updated_at in Unix milliseconds, or 0
+// if it wasn't loaded
+func updatedAtMillis(mapping *model.URLMapping) int64 {
+	if mapping.UpdatedAt.IsZero() {
+		return 0
+	}
+	return mapping.UpdatedAt.UnixMilli()
+}
+
+// updatedAtTime is the inverse of updatedAtMillis
+func updatedAtTime(millis int64) time.Time {
+	if millis == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(millis)
+}
+
+// newerEntry reports whether the cached value is a newer version of the
+// link than updatedAt (Unix ms), mirroring setIfNewerScript for backends
+// without scripting
+func newerEntry(value string, updatedAt int64) bool {
+	var cached CachedMapping
+	if err := json.Unmarshal([]byte(value), &cached); err != nil {
+		return false
+	}
+	return cached.Version == CachedMappingVersion && cached.UpdatedAt > updatedAt
+}
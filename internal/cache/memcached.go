@@ -0,0 +1,379 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// MEMCACHED BACKEND
+// ============================================================================
+// MemcachedBackend keeps link entries in one or more memcached servers,
+// speaking the text protocol:
+//
+//   set short:code:abc123 0 86400 87\r\n{"v":5,"url":"https://...",...}\r\n
+//   get short:code:abc123\r\n
+//
+// Keys are spread over the servers by the CRC32 of the key, so adding or
+// removing a server moves most keys; they are refilled from MySQL on their
+// next miss. Memcached has no scripting, so SetIfNewer reads the entry
+// with gets and writes with cas (or add, if there is none), retrying when
+// another writer got in between.
+// ============================================================================
+
+const (
+	// maxMemcachedKeyLength is the longest key memcached accepts
+	maxMemcachedKeyLength = 250
+	// maxRelativeExptime is the longest expiration memcached reads as
+	// seconds from now; longer ones must be sent as a Unix time
+	maxRelativeExptime = 30 * 24 * time.Hour
+	// memcachedCASAttempts bounds SetIfNewer's rounds against other writers
+	memcachedCASAttempts = 3
+)
+
+// memcachedError is an error reported by the server (ERROR, CLIENT_ERROR,
+// SERVER_ERROR); the connection is still usable after one
+type memcachedError struct {
+	reply string
+}
+
+func (e *memcachedError) Error() string {
+	return "memcached: " + e.reply
+}
+
+// MemcachedBackend is a Backend on memcached servers
+type MemcachedBackend struct {
+	servers []*memcachedServer
+	timeout time.Duration
+}
+
+// memcachedServer pools the idle connections to one server
+type memcachedServer struct {
+	addr    string
+	maxIdle int
+
+	mu     sync.Mutex
+	idle   []*memcachedConn
+	closed bool
+}
+
+type memcachedConn struct {
+	nc net.Conn
+	rw *bufio.ReadWriter
+}
+
+// NewMemcachedBackend connects to the servers (host:port); each command
+// gives up after timeout, and up to maxIdle connections per server are kept
+// open between commands
+func NewMemcachedBackend(addrs []string, timeout time.Duration, maxIdle int) (*MemcachedBackend, error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("no memcached servers configured")
+	}
+	b := &MemcachedBackend{timeout: timeout}
+	for _, addr := range addrs {
+		b.servers = append(b.servers, &memcachedServer{addr: addr, maxIdle: maxIdle})
+	}
+
+	// Test connection
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := b.Ping(ctx); err != nil {
+		b.Close()
+		return nil, fmt.Errorf("failed to connect to Memcached: %w", err)
+	}
+	return b, nil
+}
+
+// Get returns the value of key
+func (b *MemcachedBackend) Get(ctx context.Context, key string) (string, bool, error) {
+	value, _, found, err := b.get(ctx, "get", key)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get from Memcached: %w", err)
+	}
+	return value, found, nil
+}
+
+// Set stores value under key for ttl
+func (b *MemcachedBackend) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	if _, err := b.store(ctx, "set", key, value, ttl, 0); err != nil {
+		return fmt.Errorf("failed to set in Memcached: %w", err)
+	}
+	return nil
+}
+
+// SetIfNewer stores value unless the current entry is newer than updatedAt
+// After memcachedCASAttempts lost races it leaves the other writers' entry
+func (b *MemcachedBackend) SetIfNewer(ctx context.Context, key, value string, ttl time.Duration, updatedAt int64) (bool, error) {
+	for attempt := 0; attempt < memcachedCASAttempts; attempt++ {
+		current, cas, found, err := b.get(ctx, "gets", key)
+		if err != nil {
+			return false, fmt.Errorf("failed to set in Memcached: %w", err)
+		}
+		if found && newerEntry(current, updatedAt) {
+			return false, nil
+		}
+
+		var stored bool
+		if found {
+			stored, err = b.store(ctx, "cas", key, value, ttl, cas)
+		} else {
+			stored, err = b.store(ctx, "add", key, value, ttl, 0)
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to set in Memcached: %w", err)
+		}
+		if stored {
+			return true, nil
+		}
+		// The entry changed (or appeared) since gets; look again
+	}
+	return false, nil
+}
+
+// Delete removes key
+func (b *MemcachedBackend) Delete(ctx context.Context, key string) error {
+	if err := checkMemcachedKey(key); err != nil {
+		return err
+	}
+	err := b.do(ctx, b.server(key), func(rw *bufio.ReadWriter) error {
+		if _, err := fmt.Fprintf(rw, "delete %s\r\n", key); err != nil {
+			return err
+		}
+		reply, err := readMemcachedReply(rw)
+		if err != nil {
+			return err
+		}
+		if reply != "DELETED" && reply != "NOT_FOUND" {
+			return fmt.Errorf("memcached: unexpected reply %q", reply)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete from Memcached: %w", err)
+	}
+	return nil
+}
+
+// Ping checks that every server answers
+func (b *MemcachedBackend) Ping(ctx context.Context) error {
+	for _, s := range b.servers {
+		err := b.do(ctx, s, func(rw *bufio.ReadWriter) error {
+			if _, err := rw.WriteString("version\r\n"); err != nil {
+				return err
+			}
+			reply, err := readMemcachedReply(rw)
+			if err != nil {
+				return err
+			}
+			if !strings.HasPrefix(reply, "VERSION ") {
+				return fmt.Errorf("memcached: unexpected reply %q", reply)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.addr, err)
+		}
+	}
+	return nil
+}
+
+// Close closes the idle connections; commands in flight finish first
+func (b *MemcachedBackend) Close() error {
+	for _, s := range b.servers {
+		s.mu.Lock()
+		s.closed = true
+		for _, conn := range s.idle {
+			conn.nc.Close()
+		}
+		s.idle = nil
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// get runs get or gets for key, returning the value and its CAS token
+func (b *MemcachedBackend) get(ctx context.Context, cmd, key string) (value string, cas uint64, found bool, err error) {
+	if err := checkMemcachedKey(key); err != nil {
+		return "", 0, false, err
+	}
+	err = b.do(ctx, b.server(key), func(rw *bufio.ReadWriter) error {
+		if _, err := fmt.Fprintf(rw, "%s %s\r\n", cmd, key); err != nil {
+			return err
+		}
+		for {
+			reply, err := readMemcachedReply(rw)
+			if err != nil {
+				return err
+			}
+			if reply == "END" {
+				return nil
+			}
+
+			// VALUE <key> <flags> <bytes> [<cas unique>]
+			fields := strings.Fields(reply)
+			if len(fields) < 4 || fields[0] != "VALUE" {
+				return fmt.Errorf("memcached: unexpected reply %q", reply)
+			}
+			size, err := strconv.Atoi(fields[3])
+			if err != nil || size < 0 {
+				return fmt.Errorf("memcached: bad value length in %q", reply)
+			}
+			if len(fields) > 4 {
+				if cas, err = strconv.ParseUint(fields[4], 10, 64); err != nil {
+					return fmt.Errorf("memcached: bad cas unique in %q", reply)
+				}
+			}
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(rw, data); err != nil {
+				return err
+			}
+			if string(data[size:]) != "\r\n" {
+				return errors.New("memcached: value not terminated by CRLF")
+			}
+			value, found = string(data[:size]), true
+		}
+	})
+	return value, cas, found, err
+}
+
+// store runs set, add or cas; stored is false when add found the key
+// taken or cas found it changed or gone
+func (b *MemcachedBackend) store(ctx context.Context, cmd, key, value string, ttl time.Duration, cas uint64) (stored bool, err error) {
+	if err := checkMemcachedKey(key); err != nil {
+		return false, err
+	}
+	err = b.do(ctx, b.server(key), func(rw *bufio.ReadWriter) error {
+		header := fmt.Sprintf("%s %s 0 %d %d", cmd, key, memcachedExptime(ttl), len(value))
+		if cmd == "cas" {
+			header += " " + strconv.FormatUint(cas, 10)
+		}
+		if _, err := rw.WriteString(header + "\r\n" + value + "\r\n"); err != nil {
+			return err
+		}
+		reply, err := readMemcachedReply(rw)
+		if err != nil {
+			return err
+		}
+		switch reply {
+		case "STORED":
+			stored = true
+		case "NOT_STORED", "EXISTS", "NOT_FOUND":
+		default:
+			return fmt.Errorf("memcached: unexpected reply %q", reply)
+		}
+		return nil
+	})
+	return stored, err
+}
+
+// server returns the server holding key
+func (b *MemcachedBackend) server(key string) *memcachedServer {
+	if len(b.servers) == 1 {
+		return b.servers[0]
+	}
+	return b.servers[crc32.ChecksumIEEE([]byte(key))%uint32(len(b.servers))]
+}
+
+// do runs one command on a connection to s; fn writes the request and
+// reads the whole reply. A connection that failed mid-command may be out of
+// step with the server, so it is closed rather than reused
+func (b *MemcachedBackend) do(ctx context.Context, s *memcachedServer, fn func(rw *bufio.ReadWriter) error) error {
+	conn, err := s.conn(ctx, b.timeout)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(b.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.nc.SetDeadline(deadline)
+
+	err = fn(conn.rw)
+	var serverErr *memcachedError
+	if err != nil && !errors.As(err, &serverErr) {
+		conn.nc.Close()
+		return err
+	}
+	s.release(conn)
+	return err
+}
+
+// conn returns an idle connection to the server or dials a new one
+func (s *memcachedServer) conn(ctx context.Context, timeout time.Duration) (*memcachedConn, error) {
+	s.mu.Lock()
+	if n := len(s.idle); n > 0 {
+		conn := s.idle[n-1]
+		s.idle = s.idle[:n-1]
+		s.mu.Unlock()
+		return conn, nil
+	}
+	s.mu.Unlock()
+
+	dialer := net.Dialer{Timeout: timeout}
+	nc, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, err
+	}
+	return &memcachedConn{nc: nc, rw: bufio.NewReadWriter(bufio.NewReader(nc), bufio.NewWriter(nc))}, nil
+}
+
+// release returns a connection to the pool, or closes it if the pool is
+// full or closed
+func (s *memcachedServer) release(conn *memcachedConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || len(s.idle) >= s.maxIdle {
+		conn.nc.Close()
+		return
+	}
+	s.idle = append(s.idle, conn)
+}
+
+// readMemcachedReply flushes the request and reads one reply line without
+// its CRLF; server errors are returned as *memcachedError
+func readMemcachedReply(rw *bufio.ReadWriter) (string, error) {
+	if err := rw.Flush(); err != nil {
+		return "", err
+	}
+	line, err := rw.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	if line == "ERROR" || strings.HasPrefix(line, "CLIENT_ERROR ") || strings.HasPrefix(line, "SERVER_ERROR ") {
+		return "", &memcachedError{reply: line}
+	}
+	return line, nil
+}
+
+// memcachedExptime converts a TTL to memcached's exptime: whole seconds,
+// rounded up so a short TTL doesn't become 0 (never expire), or a Unix
+// time beyond 30 days
+func memcachedExptime(ttl time.Duration) int64 {
+	if ttl > maxRelativeExptime {
+		return time.Now().Add(ttl).Unix()
+	}
+	return int64((ttl + time.Second - 1) / time.Second)
+}
+
+// checkMemcachedKey rejects keys the text protocol can't carry
+func checkMemcachedKey(key string) error {
+	if key == "" || len(key) > maxMemcachedKeyLength {
+		return fmt.Errorf("memcached: key length %d is out of range 1-%d", len(key), maxMemcachedKeyLength)
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return fmt.Errorf("memcached: key %q contains whitespace or control characters", key)
+		}
+	}
+	return nil
+}
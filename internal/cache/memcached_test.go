@@ -0,0 +1,248 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMemcached serves the commands MemcachedBackend uses from a map
+type fakeMemcached struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	items   map[string]fakeItem
+	nextCAS uint64
+	exptime map[string]int64 // Last exptime sent per key
+}
+
+type fakeItem struct {
+	value string
+	cas   uint64
+}
+
+func startFakeMemcached(t *testing.T) *fakeMemcached {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	m := &fakeMemcached{listener: listener, items: map[string]fakeItem{}, exptime: map[string]int64{}}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go m.serve(conn)
+		}
+	}()
+	return m
+}
+
+func (m *fakeMemcached) addr() string {
+	return m.listener.Addr().String()
+}
+
+func (m *fakeMemcached) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			fmt.Fprint(conn, "ERROR\r\n")
+			continue
+		}
+
+		m.mu.Lock()
+		switch cmd := fields[0]; cmd {
+		case "version":
+			fmt.Fprint(conn, "VERSION 1.6.21\r\n")
+		case "get", "gets":
+			if item, ok := m.items[fields[1]]; ok {
+				if cmd == "gets" {
+					fmt.Fprintf(conn, "VALUE %s 0 %d %d\r\n%s\r\n", fields[1], len(item.value), item.cas, item.value)
+				} else {
+					fmt.Fprintf(conn, "VALUE %s 0 %d\r\n%s\r\n", fields[1], len(item.value), item.value)
+				}
+			}
+			fmt.Fprint(conn, "END\r\n")
+		case "set", "add", "cas":
+			size, _ := strconv.Atoi(fields[4])
+			data := make([]byte, size+2)
+			m.mu.Unlock()
+			_, err := io.ReadFull(r, data)
+			m.mu.Lock()
+			if err != nil {
+				m.mu.Unlock()
+				return
+			}
+			key := fields[1]
+			item, exists := m.items[key]
+			reply := "STORED"
+			switch {
+			case cmd == "add" && exists:
+				reply = "NOT_STORED"
+			case cmd == "cas" && !exists:
+				reply = "NOT_FOUND"
+			case cmd == "cas" && strconv.FormatUint(item.cas, 10) != fields[5]:
+				reply = "EXISTS"
+			}
+			if reply == "STORED" {
+				m.nextCAS++
+				m.items[key] = fakeItem{value: string(data[:size]), cas: m.nextCAS}
+				m.exptime[key], _ = strconv.ParseInt(fields[3], 10, 64)
+			}
+			fmt.Fprint(conn, reply+"\r\n")
+		case "delete":
+			if _, ok := m.items[fields[1]]; ok {
+				delete(m.items, fields[1])
+				fmt.Fprint(conn, "DELETED\r\n")
+			} else {
+				fmt.Fprint(conn, "NOT_FOUND\r\n")
+			}
+		default:
+			fmt.Fprint(conn, "ERROR\r\n")
+		}
+		m.mu.Unlock()
+	}
+}
+
+// TestMemcachedBackend tests Get, Set and Delete against a fake server
+func TestMemcachedBackend(t *testing.T) {
+	server := startFakeMemcached(t)
+	b, err := NewMemcachedBackend([]string{server.addr()}, time.Second, 2)
+	require.NoError(t, err)
+	defer b.Close()
+	ctx := context.Background()
+
+	_, found, err := b.Get(ctx, "short:code:abc")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, b.Set(ctx, "short:code:abc", "hello\r\nworld", 90*time.Second))
+	value, found, err := b.Get(ctx, "short:code:abc")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "hello\r\nworld", value, "values are length-prefixed, not line-based")
+	assert.Equal(t, int64(90), server.exptime["short:code:abc"])
+
+	require.NoError(t, b.Delete(ctx, "short:code:abc"))
+	require.NoError(t, b.Delete(ctx, "short:code:abc"), "deleting a missing key is fine")
+	_, found, err = b.Get(ctx, "short:code:abc")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	assert.Error(t, b.Set(ctx, "bad key", "x", time.Minute))
+	assert.Error(t, b.Set(ctx, strings.Repeat("k", 251), "x", time.Minute))
+}
+
+// TestMemcachedSetIfNewer tests that older versions of a link don't
+// replace newer ones
+func TestMemcachedSetIfNewer(t *testing.T) {
+	server := startFakeMemcached(t)
+	b, err := NewMemcachedBackend([]string{server.addr()}, time.Second, 2)
+	require.NoError(t, err)
+	defer b.Close()
+	ctx := context.Background()
+
+	entry := func(updatedAt int64) string {
+		val, err := encodeMapping(&model.URLMapping{OriginalURL: "https://example.com", UpdatedAt: time.UnixMilli(updatedAt)})
+		require.NoError(t, err)
+		return val
+	}
+
+	stored, err := b.SetIfNewer(ctx, "k", entry(2000), time.Minute, 2000)
+	require.NoError(t, err)
+	assert.True(t, stored, "added when missing")
+
+	stored, err = b.SetIfNewer(ctx, "k", entry(1000), time.Minute, 1000)
+	require.NoError(t, err)
+	assert.False(t, stored, "older version kept out")
+
+	stored, err = b.SetIfNewer(ctx, "k", entry(3000), time.Minute, 3000)
+	require.NoError(t, err)
+	assert.True(t, stored, "newer version replaces")
+	value, _, _ := b.Get(ctx, "k")
+	assert.Equal(t, entry(3000), value)
+
+	// Entries of another schema version are replaced whatever their age
+	require.NoError(t, b.Set(ctx, "k", `{"v":1,"upd":9999}`, time.Minute))
+	stored, err = b.SetIfNewer(ctx, "k", entry(1000), time.Minute, 1000)
+	require.NoError(t, err)
+	assert.True(t, stored)
+}
+
+// TestMemcachedSharding tests that keys are spread over the servers
+func TestMemcachedSharding(t *testing.T) {
+	first, second := startFakeMemcached(t), startFakeMemcached(t)
+	b, err := NewMemcachedBackend([]string{first.addr(), second.addr()}, time.Second, 2)
+	require.NoError(t, err)
+	defer b.Close()
+
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("short:code:%d", i)
+		require.NoError(t, b.Set(context.Background(), key, "x", time.Minute))
+		value, found, err := b.Get(context.Background(), key)
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "x", value)
+	}
+	assert.NotEmpty(t, first.items)
+	assert.NotEmpty(t, second.items)
+	assert.Len(t, first.items, 50-len(second.items))
+}
+
+// TestMemcachedUnavailable tests that connecting fails when a server is down
+func TestMemcachedUnavailable(t *testing.T) {
+	server := startFakeMemcached(t)
+	addr := server.addr()
+	server.listener.Close()
+
+	_, err := NewMemcachedBackend([]string{addr}, 100*time.Millisecond, 2)
+	assert.ErrorContains(t, err, addr)
+}
+
+// TestMemcachedExptime tests TTL conversion to memcached expiration times
+func TestMemcachedExptime(t *testing.T) {
+	assert.Equal(t, int64(1), memcachedExptime(200*time.Millisecond), "never rounded down to 0 (no expiry)")
+	assert.Equal(t, int64(86400), memcachedExptime(24*time.Hour))
+	assert.Equal(t, int64(30*86400), memcachedExptime(maxRelativeExptime))
+
+	absolute := memcachedExptime(60 * 24 * time.Hour)
+	assert.InDelta(t, time.Now().Add(60*24*time.Hour).Unix(), absolute, 2, "Unix time beyond 30 days")
+}
+
+// TestRedisCacheWithBackend tests that link entries go to the configured
+// backend
+func TestRedisCacheWithBackend(t *testing.T) {
+	server := startFakeMemcached(t)
+	b, err := NewMemcachedBackend([]string{server.addr()}, time.Second, 2)
+	require.NoError(t, err)
+	defer b.Close()
+	r := &RedisCache{ttl: DefaultTTL}
+	r.UseBackend(b)
+	ctx := context.Background()
+
+	require.NoError(t, r.Set(ctx, &model.URLMapping{ShortCode: "abc123", OriginalURL: "https://example.com", Status: 1}))
+	assert.Contains(t, server.items, ShortCodePrefix+"abc123")
+
+	mapping, err := r.Get(ctx, "abc123")
+	require.NoError(t, err)
+	require.NotNil(t, mapping)
+	assert.Equal(t, "https://example.com", mapping.OriginalURL)
+	assert.Equal(t, uint64(1), r.Stats().RedisHits)
+}
@@ -0,0 +1,209 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ============================================================================
+// REDIS METRICS
+// ============================================================================
+// A hook on the client times every command, so everything sharing the
+// client (link cache, queues, rate limits, leaderboard) is covered:
+//
+//   - calls and errors per command name (redis.Nil, i.e. a missing key, is
+//     not an error)
+//   - a latency histogram over all commands; a pipeline counts once, for
+//     its round trip
+//   - commands slower than the slow-command threshold are logged
+//
+// Connection pool counters come straight from go-redis (see RedisStats).
+// ============================================================================
+
+// LatencyBuckets are the upper bounds of the Redis latency histogram
+var LatencyBuckets = []time.Duration{
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// RedisStats is a snapshot of the Redis client's metrics
+type RedisStats struct {
+	Commands []CommandStats // Sorted by name
+	Latency  LatencyHistogram
+	Pool     redis.PoolStats
+}
+
+// CommandStats counts the calls of one Redis command
+type CommandStats struct {
+	Name   string // Lowercase, e.g. "get"; pipelines are "pipeline"
+	Calls  uint64
+	Errors uint64
+}
+
+// LatencyHistogram counts commands by duration
+type LatencyHistogram struct {
+	Counts []uint64 // Cumulative, one per LatencyBuckets entry
+	Count  uint64
+	Sum    time.Duration
+}
+
+// commandMetrics is the client hook collecting RedisStats
+type commandMetrics struct {
+	commands sync.Map // name -> *commandCounters
+
+	buckets []atomic.Uint64 // Non-cumulative, one per LatencyBuckets entry and +Inf
+	count   atomic.Uint64
+	sum     atomic.Int64 // Nanoseconds
+
+	// slowThreshold is in nanoseconds; 0 disables the slow-command log
+	slowThreshold atomic.Int64
+}
+
+type commandCounters struct {
+	calls  atomic.Uint64
+	errors atomic.Uint64
+}
+
+var _ redis.Hook = (*commandMetrics)(nil)
+
+func newCommandMetrics() *commandMetrics {
+	return &commandMetrics{buckets: make([]atomic.Uint64, len(LatencyBuckets)+1)}
+}
+
+// DialHook implements redis.Hook
+func (m *commandMetrics) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+// ProcessHook implements redis.Hook
+func (m *commandMetrics) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		elapsed := time.Since(start)
+
+		m.countCall(cmd.Name(), err)
+		m.observe(elapsed)
+		if m.slow(elapsed) {
+			log.Printf("Slow Redis command: %s took %v", describeCommand(cmd), elapsed)
+		}
+		return err
+	}
+}
+
+// ProcessPipelineHook implements redis.Hook
+func (m *commandMetrics) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		elapsed := time.Since(start)
+
+		m.countCall("pipeline", err)
+		for _, cmd := range cmds {
+			m.countCall(cmd.Name(), cmd.Err())
+		}
+		m.observe(elapsed)
+		if m.slow(elapsed) && len(cmds) > 0 {
+			log.Printf("Slow Redis pipeline: %d commands starting with %s took %v",
+				len(cmds), describeCommand(cmds[0]), elapsed)
+		}
+		return err
+	}
+}
+
+// countCall counts a call of the named command
+func (m *commandMetrics) countCall(name string, err error) {
+	counters, ok := m.commands.Load(name)
+	if !ok {
+		counters, _ = m.commands.LoadOrStore(name, &commandCounters{})
+	}
+	c := counters.(*commandCounters)
+	c.calls.Add(1)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		c.errors.Add(1)
+	}
+}
+
+// observe adds a duration to the latency histogram
+func (m *commandMetrics) observe(elapsed time.Duration) {
+	i := sort.Search(len(LatencyBuckets), func(i int) bool { return elapsed <= LatencyBuckets[i] })
+	m.buckets[i].Add(1)
+	m.count.Add(1)
+	m.sum.Add(int64(elapsed))
+}
+
+// slow reports whether elapsed is over the slow-command threshold
+func (m *commandMetrics) slow(elapsed time.Duration) bool {
+	threshold := m.slowThreshold.Load()
+	return threshold > 0 && int64(elapsed) >= threshold
+}
+
+// snapshot returns the command and latency part of RedisStats
+func (m *commandMetrics) snapshot() ([]CommandStats, LatencyHistogram) {
+	var commands []CommandStats
+	m.commands.Range(func(name, counters any) bool {
+		c := counters.(*commandCounters)
+		commands = append(commands, CommandStats{Name: name.(string), Calls: c.calls.Load(), Errors: c.errors.Load()})
+		return true
+	})
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+
+	latency := LatencyHistogram{
+		Counts: make([]uint64, len(LatencyBuckets)),
+		Count:  m.count.Load(),
+		Sum:    time.Duration(m.sum.Load()),
+	}
+	var cumulative uint64
+	for i := range LatencyBuckets {
+		cumulative += m.buckets[i].Load()
+		latency.Counts[i] = cumulative
+	}
+	return commands, latency
+}
+
+// describeCommand names a command and its key for logs; values are left out
+func describeCommand(cmd redis.Cmder) string {
+	args := cmd.Args()
+	if len(args) < 2 {
+		return cmd.Name()
+	}
+	if key, ok := args[1].(string); ok {
+		return cmd.Name() + " " + key
+	}
+	return cmd.Name()
+}
+
+// SetSlowCommandThreshold logs commands taking threshold or longer; 0
+// disables the log
+func (r *RedisCache) SetSlowCommandThreshold(threshold time.Duration) {
+	r.metrics.slowThreshold.Store(int64(threshold))
+}
+
+// RedisStats returns the client's command, latency and pool metrics
+func (r *RedisCache) RedisStats() RedisStats {
+	commands, latency := r.metrics.snapshot()
+	return RedisStats{
+		Commands: commands,
+		Latency:  latency,
+		Pool:     *r.client.PoolStats(),
+	}
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCommandMetrics tests counting and timing commands and pipelines
+func TestCommandMetrics(t *testing.T) {
+	ctx := context.Background()
+	m := newCommandMetrics()
+
+	// Commands take delay to run
+	var delay time.Duration
+	process := m.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+		time.Sleep(delay)
+		return cmd.Err()
+	})
+
+	get := redis.NewStringCmd(ctx, "get", "short:code:abc")
+	get.SetErr(redis.Nil)
+	assert.ErrorIs(t, process(ctx, get), redis.Nil)
+
+	set := redis.NewStatusCmd(ctx, "set", "short:code:abc", "value")
+	set.SetErr(errors.New("READONLY"))
+	delay = 2 * time.Millisecond
+	process(ctx, set)
+
+	pipeline := m.ProcessPipelineHook(func(ctx context.Context, cmds []redis.Cmder) error { return nil })
+	pipeline(ctx, []redis.Cmder{
+		redis.NewIntCmd(ctx, "incr", "a"),
+		redis.NewIntCmd(ctx, "incr", "b"),
+	})
+
+	commands, latency := m.snapshot()
+	assert.Equal(t, []CommandStats{
+		{Name: "get", Calls: 1},
+		{Name: "incr", Calls: 2},
+		{Name: "pipeline", Calls: 1},
+		{Name: "set", Calls: 1, Errors: 1},
+	}, commands, "missing keys aren't errors")
+
+	assert.Equal(t, uint64(3), latency.Count, "a pipeline is one round trip")
+	assert.GreaterOrEqual(t, latency.Sum, 2*time.Millisecond)
+	last := len(LatencyBuckets) - 1
+	assert.Equal(t, uint64(3), latency.Counts[last], "buckets are cumulative")
+	assert.Equal(t, uint64(2), latency.Counts[0], "only set took over 0.5ms")
+}
+
+// TestSlowCommandThreshold tests the slow-command check
+func TestSlowCommandThreshold(t *testing.T) {
+	m := newCommandMetrics()
+	assert.False(t, m.slow(time.Hour), "0 disables the log")
+
+	m.slowThreshold.Store(int64(100 * time.Millisecond))
+	assert.False(t, m.slow(99*time.Millisecond))
+	assert.True(t, m.slow(100*time.Millisecond))
+}
+
+// TestDescribeCommand tests that logs name the key but not the value
+func TestDescribeCommand(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "set short:code:abc", describeCommand(redis.NewStatusCmd(ctx, "set", "short:code:abc", "secret")))
+	assert.Equal(t, "ping", describeCommand(redis.NewStatusCmd(ctx, "ping")))
+}
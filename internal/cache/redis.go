@@ -3,9 +3,16 @@ package cache
 import (
 	"context"
 	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/tracing"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -13,11 +20,66 @@ const (
 	ShortCodePrefix = "short:code:"
 	// DefaultTTL is the default TTL for cached items (24 hours)
 	DefaultTTL = 24 * time.Hour
+	// InvalidationChannel is the pub/sub channel used to evict short codes
+	// from every instance's local cache
+	InvalidationChannel = "short:invalidate"
+	// maxPendingInvalidations bounds the deletes kept for retrying while
+	// Redis is down; beyond it, entries are left to expire
+	maxPendingInvalidations = 10000
+	// invalidationRetryTimeout bounds a retry of the pending invalidations
+	invalidationRetryTimeout = 10 * time.Second
 )
 
 // RedisCache wraps the Redis client
 type RedisCache struct {
 	client *redis.Client
+
+	// links holds the link entries: Redis itself unless UseBackend
+	// replaced it
+	links Backend
+
+	// ttl is the base TTL used by Set; jitter adds a random [0, jitter)
+	// so links created together don't all expire together
+	ttl    time.Duration
+	jitter time.Duration
+
+	// local is an optional in-process LRU tier in front of Redis; disk an
+	// optional tier behind it, read only when Redis fails
+	local  *LocalCache
+	disk   *DiskCache
+	pubsub *redis.PubSub
+
+	// Short codes deleted while Redis was failing, still to be deleted
+	// there and published (see Delete)
+	pendingMu sync.Mutex
+	pending   map[string]struct{}
+
+	// Command, latency and pool metrics of the client (see metrics.go)
+	metrics *commandMetrics
+
+	// Outcomes of Get, for /metrics
+	localHits atomic.Uint64
+	redisHits atomic.Uint64
+	diskHits  atomic.Uint64
+	misses    atomic.Uint64
+}
+
+// LookupStats counts the outcomes of Get since startup
+type LookupStats struct {
+	LocalHits uint64 // Served by the in-process tier
+	RedisHits uint64 // Served by the shared backend (Redis or Memcached)
+	DiskHits  uint64 // Served by the disk tier while the shared backend failed
+	Misses    uint64 // Not cached (or an outdated entry); errors aren't counted
+}
+
+// Stats returns the lookup counters
+func (r *RedisCache) Stats() LookupStats {
+	return LookupStats{
+		LocalHits: r.localHits.Load(),
+		RedisHits: r.redisHits.Load(),
+		DiskHits:  r.diskHits.Load(),
+		Misses:    r.misses.Load(),
+	}
 }
 
 // NewRedisCache creates a new Redis cache instance
@@ -37,47 +99,284 @@ func NewRedisCache(addr, password string, db, poolSize int) (*RedisCache, error)
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	return &RedisCache{client: client}, nil
+	metrics := newCommandMetrics()
+	client.AddHook(metrics)
+
+	return &RedisCache{client: client, links: &redisBackend{client: client}, ttl: DefaultTTL, metrics: metrics}, nil
+}
+
+// UseBackend keeps link entries in backend instead of Redis
+// Invalidations of the local tier still go through Redis pub/sub
+func (r *RedisCache) UseBackend(backend Backend) {
+	r.links = backend
 }
 
-// Get retrieves the original URL for a given short code
-func (r *RedisCache) Get(ctx context.Context, shortCode string) (string, error) {
-	key := ShortCodePrefix + shortCode
-	val, err := r.client.Get(ctx, key).Result()
-	if err == redis.Nil {
-		return "", nil // Cache miss
+// ConfigureTTL sets the base TTL and random jitter applied by Set
+// A non-positive ttl leaves the base TTL unchanged (DefaultTTL initially)
+func (r *RedisCache) ConfigureTTL(ttl, jitter time.Duration) {
+	if ttl > 0 {
+		r.ttl = ttl
 	}
+	r.jitter = jitter
+}
+
+// EnableLocalCache adds an in-process LRU tier in front of Redis
+// Entries are evicted on every instance when Delete publishes an invalidation
+func (r *RedisCache) EnableLocalCache(size int, ttl time.Duration) {
+	r.local = NewLocalCache(size, ttl)
+	r.subscribeInvalidations()
+}
+
+// EnableDiskCache mirrors the entries read from Redis to disk, to be served
+// when Redis fails (see disk.go)
+// Entries are evicted on every instance when Delete publishes an invalidation
+func (r *RedisCache) EnableDiskCache(disk *DiskCache) {
+	r.disk = disk
+	r.subscribeInvalidations()
+}
+
+// subscribeInvalidations evicts short codes published by Delete on any
+// instance from the local and disk tiers; call it after enabling a tier
+// Each (re)subscription means Redis is reachable again, so the deletes
+// that failed meanwhile are retried.
+func (r *RedisCache) subscribeInvalidations() {
+	if r.pubsub != nil {
+		return
+	}
+	r.pubsub = r.client.Subscribe(context.Background(), InvalidationChannel)
+
+	go func() {
+		for msg := range r.pubsub.ChannelWithSubscriptions() {
+			switch msg := msg.(type) {
+			case *redis.Subscription:
+				ctx, cancel := context.WithTimeout(context.Background(), invalidationRetryTimeout)
+				r.retryInvalidations(ctx)
+				cancel()
+			case *redis.Message:
+				if r.local != nil {
+					r.local.Delete(msg.Payload)
+				}
+				if r.disk != nil {
+					r.disk.Delete(msg.Payload)
+				}
+			}
+		}
+	}()
+}
+
+// Get retrieves the cached mapping for a given short code
+// Checks the local tier first when enabled, and the disk tier when Redis
+// fails
+// Returns nil on a cache miss, including entries with an old schema version
+func (r *RedisCache) Get(ctx context.Context, shortCode string) (mapping *model.URLMapping, err error) {
+	ctx, span := tracing.Start(ctx, "cache.get", trace.WithAttributes(attribute.String("short_code", shortCode)))
+	defer func() {
+		span.SetAttributes(attribute.Bool("cache.hit", mapping != nil))
+		tracing.EndSpan(span, err)
+	}()
+
+	if r.local != nil {
+		if val, ok := r.local.Get(shortCode); ok {
+			span.SetAttributes(attribute.String("cache.tier", "local"))
+			r.localHits.Add(1)
+			return decodeMapping(shortCode, val)
+		}
+	}
+	span.SetAttributes(attribute.String("cache.tier", "redis"))
+
+	val, found, err := r.links.Get(ctx, ShortCodePrefix+shortCode)
 	if err != nil {
-		return "", fmt.Errorf("failed to get from Redis: %w", err)
+		if r.disk != nil {
+			if val, ok := r.disk.Get(shortCode); ok {
+				span.SetAttributes(attribute.String("cache.tier", "disk"))
+				r.diskHits.Add(1)
+				return decodeMapping(shortCode, val)
+			}
+		}
+		return nil, err
 	}
-	return val, nil
+	if !found {
+		// Deleted or changed while this node missed the invalidation
+		if r.disk != nil {
+			r.disk.Delete(shortCode)
+		}
+		r.misses.Add(1)
+		return nil, nil // Cache miss
+	}
+
+	mapping, err = decodeMapping(shortCode, val)
+	if err != nil || mapping == nil {
+		// Unreadable or outdated entry: treat as a miss so it gets refilled
+		r.misses.Add(1)
+		return nil, err
+	}
+	r.redisHits.Add(1)
+
+	if r.disk != nil {
+		r.disk.Set(shortCode, val, r.entryTTL(mapping))
+	}
+	if r.localAccepts(time.Duration(mapping.CacheTTL) * time.Second) {
+		r.local.Set(shortCode, val)
+	}
+	return mapping, nil
+}
+
+// Set stores the mapping for its short code with the configured TTL plus jitter
+func (r *RedisCache) Set(ctx context.Context, mapping *model.URLMapping) error {
+	return r.SetWithTTL(ctx, mapping, r.jitteredTTL())
 }
 
-// Set stores the original URL for a given short code with default TTL
-func (r *RedisCache) Set(ctx context.Context, shortCode, originalURL string) error {
-	return r.SetWithTTL(ctx, shortCode, originalURL, DefaultTTL)
+// jitteredTTL returns the base TTL plus a random jitter to avoid stampedes
+func (r *RedisCache) jitteredTTL() time.Duration {
+	if r.jitter <= 0 {
+		return r.ttl
+	}
+	return r.ttl + time.Duration(rand.Int63n(int64(r.jitter)))
 }
 
-// SetWithTTL stores the original URL for a given short code with custom TTL
-func (r *RedisCache) SetWithTTL(ctx context.Context, shortCode, originalURL string, ttl time.Duration) error {
-	key := ShortCodePrefix + shortCode
-	if err := r.client.Set(ctx, key, originalURL, ttl).Err(); err != nil {
-		return fmt.Errorf("failed to set in Redis: %w", err)
+// SetWithTTL stores the mapping for its short code with custom TTL
+// The TTL is capped at the link's expiration so expired links fall out of the cache
+// Mappings with UpdatedAt set never replace a newer cached version
+func (r *RedisCache) SetWithTTL(ctx context.Context, mapping *model.URLMapping, ttl time.Duration) (err error) {
+	ctx, span := tracing.Start(ctx, "cache.set", trace.WithAttributes(attribute.String("short_code", mapping.ShortCode)))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	if mapping.ExpiredAt != nil {
+		if untilExpiry := time.Until(*mapping.ExpiredAt); untilExpiry < ttl {
+			ttl = untilExpiry
+		}
+	}
+	if ttl <= 0 {
+		return nil // Already expired, nothing worth caching
+	}
+
+	val, err := encodeMapping(mapping)
+	if err != nil {
+		return err
+	}
+
+	key := ShortCodePrefix + mapping.ShortCode
+	if updatedAt := updatedAtMillis(mapping); updatedAt == 0 {
+		if err := r.links.Set(ctx, key, val, ttl); err != nil {
+			return err
+		}
+	} else {
+		stored, err := r.links.SetIfNewer(ctx, key, val, ttl, updatedAt)
+		if err != nil {
+			return err
+		}
+		if !stored {
+			span.SetAttributes(attribute.Bool("cache.stale", true))
+			return nil
+		}
+	}
+	if r.disk != nil {
+		r.disk.Update(mapping.ShortCode, val, ttl)
+	}
+	if r.localAccepts(ttl) {
+		r.local.Set(mapping.ShortCode, val)
 	}
 	return nil
 }
 
+// entryTTL returns the TTL a mapping is cached with, without jitter
+func (r *RedisCache) entryTTL(mapping *model.URLMapping) time.Duration {
+	if mapping.CacheTTL > 0 {
+		return time.Duration(mapping.CacheTTL) * time.Second
+	}
+	return r.ttl
+}
+
+// localAccepts reports whether an entry with the given TTL (0 for the
+// default) may go in the local tier
+// The local tier has one TTL for all entries, so links with a shorter TTL
+// stay out of it rather than being served longer than asked
+func (r *RedisCache) localAccepts(ttl time.Duration) bool {
+	return r.local != nil && (ttl == 0 || ttl >= r.local.ttl)
+}
+
 // Delete removes a short code from cache
-func (r *RedisCache) Delete(ctx context.Context, shortCode string) error {
-	key := ShortCodePrefix + shortCode
-	if err := r.client.Del(ctx, key).Err(); err != nil {
-		return fmt.Errorf("failed to delete from Redis: %w", err)
+// Call it whenever a mapping is updated or deleted so local tiers on other
+// instances drop their copy too. If Redis fails, the short code is deleted
+// there and published again once it is back.
+func (r *RedisCache) Delete(ctx context.Context, shortCode string) (err error) {
+	ctx, span := tracing.Start(ctx, "cache.delete", trace.WithAttributes(attribute.String("short_code", shortCode)))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	// The disk and local tiers are dropped first: they are what gets
+	// served if Redis is failing right now
+	if r.disk != nil {
+		r.disk.Delete(shortCode)
+	}
+	if r.local != nil {
+		r.local.Delete(shortCode)
+	}
+	if err := r.invalidate(ctx, shortCode); err != nil {
+		r.deferInvalidation(shortCode)
+		return err
 	}
+	r.retryInvalidations(ctx)
 	return nil
 }
 
+// invalidate deletes a short code from the shared backend and publishes it
+// to the other instances
+func (r *RedisCache) invalidate(ctx context.Context, shortCode string) error {
+	if err := r.links.Delete(ctx, ShortCodePrefix+shortCode); err != nil {
+		return err
+	}
+	if r.pubsub != nil {
+		if err := r.client.Publish(ctx, InvalidationChannel, shortCode).Err(); err != nil {
+			return fmt.Errorf("failed to publish invalidation: %w", err)
+		}
+	}
+	return nil
+}
+
+// deferInvalidation keeps a short code whose invalidation failed for
+// retryInvalidations
+func (r *RedisCache) deferInvalidation(shortCode string) {
+	r.pendingMu.Lock()
+	defer r.pendingMu.Unlock()
+	if r.pending == nil {
+		r.pending = make(map[string]struct{})
+	}
+	if len(r.pending) < maxPendingInvalidations {
+		r.pending[shortCode] = struct{}{}
+	}
+}
+
+// retryInvalidations invalidates the short codes deleted while Redis was
+// failing, stopping at the first failure
+func (r *RedisCache) retryInvalidations(ctx context.Context) {
+	r.pendingMu.Lock()
+	codes := make([]string, 0, len(r.pending))
+	for code := range r.pending {
+		codes = append(codes, code)
+	}
+	r.pending = nil
+	r.pendingMu.Unlock()
+
+	for i, code := range codes {
+		if err := r.invalidate(ctx, code); err != nil {
+			for _, code := range codes[i:] {
+				r.deferInvalidation(code)
+			}
+			return
+		}
+	}
+}
+
+// Ping checks that Redis is reachable
+func (r *RedisCache) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
 // Close closes the Redis connection
 func (r *RedisCache) Close() error {
+	if r.pubsub != nil {
+		r.pubsub.Close()
+	}
 	return r.client.Close()
 }
 
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestJitteredTTL tests that jitter stays within [ttl, ttl+jitter)
+func TestJitteredTTL(t *testing.T) {
+	r := &RedisCache{ttl: DefaultTTL}
+	assert.Equal(t, DefaultTTL, r.jitteredTTL())
+
+	r.ConfigureTTL(time.Hour, 10*time.Minute)
+	for i := 0; i < 100; i++ {
+		ttl := r.jitteredTTL()
+		assert.GreaterOrEqual(t, ttl, time.Hour)
+		assert.Less(t, ttl, time.Hour+10*time.Minute)
+	}
+
+	// Non-positive TTL keeps the current base TTL
+	r.ConfigureTTL(0, 0)
+	assert.Equal(t, time.Hour, r.jitteredTTL())
+}
+
+// TestMappingEncoding tests round-tripping mappings through the cache format
+func TestMappingEncoding(t *testing.T) {
+	expiredAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	val, err := encodeMapping(&model.URLMapping{
+		ShortCode:         "abc123",
+		OriginalURL:       "https://example.com",
+		Domain:            "s.example.com",
+		ExpiredAt:         &expiredAt,
+		Status:            1,
+		AccessRules:       &model.AccessRules{Referrers: []string{"example.com"}},
+		Warning:           true,
+		UpdatedAt:         time.UnixMilli(1700000000123),
+		VisitDedupMinutes: 30,
+		NoAnalytics:       true,
+		Visibility:        model.VisibilityPrivate,
+		OrgID:             7,
+	})
+	assert.NoError(t, err)
+
+	mapping, err := decodeMapping("abc123", val)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", mapping.ShortCode)
+	assert.Equal(t, "https://example.com", mapping.OriginalURL)
+	assert.Equal(t, "s.example.com", mapping.Domain)
+	assert.True(t, expiredAt.Equal(*mapping.ExpiredAt))
+	assert.True(t, mapping.IsActive())
+	assert.Equal(t, []string{"example.com"}, mapping.AccessRules.Referrers)
+	assert.True(t, mapping.Warning)
+	assert.Equal(t, int64(1700000000123), mapping.UpdatedAt.UnixMilli())
+	assert.Equal(t, 30, mapping.VisitDedupMinutes)
+	assert.True(t, mapping.NoAnalytics)
+	assert.True(t, mapping.IsPrivate())
+	assert.Equal(t, uint(7), mapping.OrgID)
+
+	// Entries from another schema version are misses
+	mapping, err = decodeMapping("abc123", `{"v":1,"url":"https://example.com","st":1}`)
+	assert.NoError(t, err)
+	assert.Nil(t, mapping)
+
+	// Legacy plain-string entries fail to decode
+	_, err = decodeMapping("abc123", "https://example.com")
+	assert.Error(t, err)
+}
+
+// TestLocalAccepts tests that links with a short TTL stay out of the local tier
+func TestLocalAccepts(t *testing.T) {
+	r := &RedisCache{ttl: DefaultTTL}
+	assert.False(t, r.localAccepts(0))
+
+	r.local = NewLocalCache(10, time.Minute)
+	assert.True(t, r.localAccepts(0))
+	assert.True(t, r.localAccepts(time.Hour))
+	assert.False(t, r.localAccepts(10*time.Second))
+
+	// The per-link TTL survives the round trip, so Redis hits can check it
+	val, err := encodeMapping(&model.URLMapping{ShortCode: "abc123", OriginalURL: "https://example.com", Status: 1, CacheTTL: 10})
+	assert.NoError(t, err)
+	mapping, err := decodeMapping("abc123", val)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, mapping.CacheTTL)
+}
+
+// BenchmarkDecodeMapping measures decoding a cached entry, done on every hit
+func BenchmarkDecodeMapping(b *testing.B) {
+	val, err := encodeMapping(&model.URLMapping{
+		ShortCode:   "abc123",
+		OriginalURL: "https://example.com/some/long/path?utm_source=newsletter",
+		Status:      1,
+		UpdatedAt:   time.UnixMilli(1700000000123),
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := decodeMapping("abc123", val); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
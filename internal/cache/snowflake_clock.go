@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ============================================================================
+// SNOWFLAKE CLOCK CHECK
+// ============================================================================
+// A snowflake node only generates increasing IDs while the clock moves
+// forward. Within a process that's guaranteed (IDs are timed with the
+// monotonic clock), but an instance restarted on a host whose clock is
+// behind would repeat the IDs it generated before the restart. So the
+// newest ID time of each node is kept in Redis:
+//
+//   snowflake:clock:33  1718000000123   (node ID 33 = datacenter 1, worker 1)
+//
+// At startup the instance compares its clock with it (see the app package)
+// and holds back IDs until the clock has caught up. The time is recorded
+// every second and on shutdown, so a crash can lose at most the last
+// second.
+// ============================================================================
+
+// snowflakeClockPrefix is the prefix of snowflake clock keys in Redis
+const snowflakeClockPrefix = "snowflake:clock:"
+
+// recordMaxScript stores ARGV[1] under KEYS[1] unless a larger number is
+// stored there
+var recordMaxScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]))
+if not current or current < tonumber(ARGV[1]) then
+  redis.call('SET', KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+// SnowflakeClock remembers the time of the newest ID of a snowflake node
+type SnowflakeClock struct {
+	client *redis.Client
+	key    string
+}
+
+// NewSnowflakeClock creates the clock of a node ID
+func NewSnowflakeClock(client *redis.Client, nodeID int64) *SnowflakeClock {
+	return &SnowflakeClock{client: client, key: fmt.Sprintf("%s%d", snowflakeClockPrefix, nodeID)}
+}
+
+// Last returns the recorded time, or the zero time if there is none
+func (c *SnowflakeClock) Last(ctx context.Context) (time.Time, error) {
+	millis, err := c.client.Get(ctx, c.key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(millis), nil
+}
+
+// Record raises the recorded time to t; the zero time is ignored
+func (c *SnowflakeClock) Record(ctx context.Context, t time.Time) error {
+	if t.IsZero() {
+		return nil
+	}
+	return recordMaxScript.Run(ctx, c.client, []string{c.key}, strconv.FormatInt(t.UnixMilli(), 10)).Err()
+}
+
+// Keep records last() every second until ctx is done
+func (c *SnowflakeClock) Keep(ctx context.Context, last func() time.Time) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	var recorded time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t := last()
+			if !t.After(recorded) {
+				continue
+			}
+			if err := c.Record(ctx, t); err != nil {
+				log.Printf("Warning: failed to record snowflake clock: %v", err)
+				continue
+			}
+			recorded = t
+		}
+	}
+}
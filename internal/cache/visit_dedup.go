@@ -0,0 +1,22 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// visitSeenPrefix is the prefix of the keys marking a visitor as counted,
+// short:visit:seen:<short code>:<visitor>
+const visitSeenPrefix = "short:visit:seen:"
+
+// FirstVisit marks visitor (e.g. a hashed IP) as counted for shortCode and
+// reports whether it wasn't already within the last window
+// The window starts at the counted visit; repeats don't extend it
+func (r *RedisCache) FirstVisit(ctx context.Context, shortCode, visitor string, window time.Duration) (bool, error) {
+	first, err := r.client.SetNX(ctx, visitSeenPrefix+shortCode+":"+visitor, 1, window).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check visit window: %w", err)
+	}
+	return first, nil
+}
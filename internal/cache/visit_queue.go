@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+// VisitQueueKey is the Redis list holding visits recorded while MySQL was
+// unavailable, oldest first
+const VisitQueueKey = "short:visits:pending"
+
+// PendingVisit is a visit waiting to be written to MySQL
+type PendingVisit struct {
+	Log     model.VisitLog `json:"log"`
+	Counted bool           `json:"counted,omitempty"` // visit_count was already incremented
+	// Duplicate repeats a counted visit from the same IP within the link's
+	// deduplication window; it counts in duplicate_visit_count instead
+	Duplicate bool `json:"duplicate,omitempty"`
+	// Untracked visits are only counted; Log holds no visitor details
+	Untracked bool `json:"untracked,omitempty"`
+}
+
+// QueueVisit appends a visit to the pending queue
+func (r *RedisCache) QueueVisit(ctx context.Context, visit *PendingVisit) error {
+	data, err := json.Marshal(visit)
+	if err != nil {
+		return fmt.Errorf("failed to encode pending visit: %w", err)
+	}
+	if err := r.client.RPush(ctx, VisitQueueKey, data).Err(); err != nil {
+		return fmt.Errorf("failed to queue visit: %w", err)
+	}
+	return nil
+}
+
+// DequeueVisits removes and returns up to n of the oldest pending visits
+// Entries that can't be decoded are dropped
+func (r *RedisCache) DequeueVisits(ctx context.Context, n int) ([]PendingVisit, error) {
+	values, err := r.client.LPopCount(ctx, VisitQueueKey, n).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to dequeue visits: %w", err)
+	}
+
+	visits := make([]PendingVisit, 0, len(values))
+	for _, value := range values {
+		var visit PendingVisit
+		if err := json.Unmarshal([]byte(value), &visit); err != nil {
+			fmt.Printf("Dropping undecodable pending visit: %v\n", err)
+			continue
+		}
+		visits = append(visits, visit)
+	}
+	return visits, nil
+}
+
+// RequeueVisits puts visits back at the front of the queue, in order
+// Used when replaying them failed part way
+func (r *RedisCache) RequeueVisits(ctx context.Context, visits []PendingVisit) error {
+	if len(visits) == 0 {
+		return nil
+	}
+	values := make([]interface{}, 0, len(visits))
+	// LPUSH prepends one at a time, so push the newest first
+	for i := len(visits) - 1; i >= 0; i-- {
+		data, err := json.Marshal(&visits[i])
+		if err != nil {
+			return fmt.Errorf("failed to encode pending visit: %w", err)
+		}
+		values = append(values, data)
+	}
+	if err := r.client.LPush(ctx, VisitQueueKey, values...).Err(); err != nil {
+		return fmt.Errorf("failed to requeue visits: %w", err)
+	}
+	return nil
+}
+
+// PendingVisits returns the number of queued visits
+func (r *RedisCache) PendingVisits(ctx context.Context) (int64, error) {
+	n, err := r.client.LLen(ctx, VisitQueueKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count pending visits: %w", err)
+	}
+	return n, nil
+}
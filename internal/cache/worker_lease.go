@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ============================================================================
+// SNOWFLAKE WORKER ID LEASES
+// ============================================================================
+// Two instances with the same datacenter/worker ID pair generate the same
+// snowflake IDs. Instead of configuring a unique worker ID per replica, an
+// instance can lease a free one from Redis at startup:
+//
+//   snowflake:worker:1:0  "host-a:4242:9f2c..."  (PX 30000)
+//   snowflake:worker:1:1  "host-b:17:03be..."    (PX 30000)
+//
+// Startup takes the first ID whose key can be SET NX. A heartbeat renews
+// the key every third of its TTL while this instance still owns it. The
+// lease counts as held only until its last successful renewal plus the TTL,
+// so when Redis can't be reached the instance stops generating codes before
+// its key can expire and be taken by another one. If another instance
+// takes the ID anyway, the lease is lost for good.
+// ============================================================================
+
+// workerLeasePrefix is the prefix of worker ID lease keys in Redis
+const workerLeasePrefix = "snowflake:worker:"
+
+// renewWorkerLeaseScript extends the lease if this instance still owns it,
+// or takes it again if it expired and is still free
+// KEYS[1] = lease key, ARGV[1] = owner, ARGV[2] = TTL in milliseconds
+// Returns 1 if the lease is held, 0 if another instance owns it
+var renewWorkerLeaseScript = redis.NewScript(`
+local owner = redis.call('GET', KEYS[1])
+if owner == ARGV[1] then
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+  return 1
+end
+if not owner then
+  redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+  return 1
+end
+return 0
+`)
+
+// releaseWorkerLeaseScript deletes the lease if this instance owns it
+// KEYS[1] = lease key, ARGV[1] = owner
+var releaseWorkerLeaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// WorkerIDLease is a snowflake worker ID leased in Redis
+type WorkerIDLease struct {
+	client   *redis.Client
+	key      string
+	owner    string
+	workerID int64
+	ttl      time.Duration
+
+	validUntil atomic.Int64 // Unix nanoseconds
+	lost       atomic.Bool
+}
+
+// LeaseWorkerID leases the first free worker ID below workers in a
+// datacenter for ttl; Run keeps it
+func LeaseWorkerID(ctx context.Context, client *redis.Client, datacenterID, workers int64, ttl time.Duration) (*WorkerIDLease, error) {
+	owner, err := leaseOwner()
+	if err != nil {
+		return nil, err
+	}
+	for id := int64(0); id < workers; id++ {
+		lease := &WorkerIDLease{
+			client:   client,
+			key:      fmt.Sprintf("%s%d:%d", workerLeasePrefix, datacenterID, id),
+			owner:    owner,
+			workerID: id,
+			ttl:      ttl,
+		}
+		start := time.Now()
+		ok, err := client.SetNX(ctx, lease.key, owner, ttl).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to lease a worker ID: %w", err)
+		}
+		if ok {
+			lease.validUntil.Store(start.Add(ttl).UnixNano())
+			return lease, nil
+		}
+	}
+	return nil, fmt.Errorf("all %d worker IDs of datacenter %d are leased", workers, datacenterID)
+}
+
+// leaseOwner identifies this process in lease keys
+func leaseOwner() (string, error) {
+	host, _ := os.Hostname()
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), hex.EncodeToString(nonce)), nil
+}
+
+// WorkerID returns the leased worker ID
+func (l *WorkerIDLease) WorkerID() int64 {
+	return l.workerID
+}
+
+// Held reports whether this instance still holds the worker ID
+// Implements utils.WorkerLease.
+func (l *WorkerIDLease) Held() bool {
+	return !l.lost.Load() && time.Now().UnixNano() < l.validUntil.Load()
+}
+
+// Run renews the lease every third of its TTL until ctx is done
+func (l *WorkerIDLease) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if l.lost.Load() {
+				return
+			}
+			if err := l.renew(ctx); err != nil {
+				log.Printf("Warning: failed to renew snowflake worker ID %d: %v", l.workerID, err)
+			}
+		}
+	}
+}
+
+// renew extends the lease once
+func (l *WorkerIDLease) renew(ctx context.Context) error {
+	start := time.Now()
+	held, err := renewWorkerLeaseScript.Run(ctx, l.client, []string{l.key}, l.owner, l.ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if held == 0 {
+		l.lost.Store(true)
+		log.Printf("Error: snowflake worker ID %d was taken by another instance; "+
+			"short codes can't be generated until this instance restarts", l.workerID)
+		return nil
+	}
+	l.validUntil.Store(start.Add(l.ttl).UnixNano())
+	return nil
+}
+
+// Release gives the worker ID back so another instance can lease it
+func (l *WorkerIDLease) Release(ctx context.Context) error {
+	l.lost.Store(true)
+	return releaseWorkerLeaseScript.Run(ctx, l.client, []string{l.key}, l.owner).Err()
+}
@@ -0,0 +1,58 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestVerify tests token checks against a fake siteverify endpoint
+func TestVerify(t *testing.T) {
+	var remoteIP string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "s3cret", r.PostForm.Get("secret"))
+		remoteIP = r.PostForm.Get("remoteip")
+		switch r.PostForm.Get("response") {
+		case "good":
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+		case "human":
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "score": 0.9})
+		case "bot":
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "score": 0.1})
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error-codes": []string{"invalid-input-response"}})
+		}
+	}))
+	defer server.Close()
+
+	v, err := NewVerifier(ProviderReCAPTCHA, "s3cret", 0.5, time.Second)
+	require.NoError(t, err)
+	v.verifyURL = server.URL
+	ctx := context.Background()
+
+	ok, err := v.Verify(ctx, "good", "203.0.113.7")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "203.0.113.7", remoteIP)
+	ok, _ = v.Verify(ctx, "human", "")
+	assert.True(t, ok)
+	ok, _ = v.Verify(ctx, "bot", "")
+	assert.False(t, ok, "scored below min_score")
+	ok, err = v.Verify(ctx, "forged", "")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	server.Close()
+	_, err = v.Verify(ctx, "good", "")
+	assert.Error(t, err, "provider unreachable")
+
+	_, err = NewVerifier("turnstile", "s3cret", 0, time.Second)
+	assert.Error(t, err)
+}
@@ -0,0 +1,37 @@
+package enrich
+
+import (
+	"strings"
+
+	"github.com/avct/uasurfer"
+)
+
+// botUserAgentTokens are lowercase User-Agent substrings of crawlers and
+// link-preview fetchers. Preview fetchers (Slack, Twitter, Facebook, ...)
+// request a link as soon as it's pasted, so they inflate visit counts the most.
+var botUserAgentTokens = []string{
+	// Link previews
+	"slackbot", "slack-imgproxy", "twitterbot", "facebookexternalhit", "facebot",
+	"linkedinbot", "discordbot", "telegrambot", "whatsapp", "skypeuripreview",
+	"redditbot", "pinterestbot", "embedly", "iframely", "mastodon",
+	// Search engines
+	"googlebot", "bingbot", "applebot", "yandexbot", "duckduckbot", "baiduspider",
+	// Generic
+	"bot/", "bot;", "crawler", "spider", "headlesschrome",
+}
+
+// IsBot reports whether a User-Agent belongs to a bot, crawler or preview fetcher
+// An empty User-Agent is treated as a bot; real browsers always send one
+func IsBot(userAgent string) bool {
+	if userAgent == "" {
+		return true
+	}
+
+	lower := strings.ToLower(userAgent)
+	for _, token := range botUserAgentTokens {
+		if strings.Contains(lower, token) {
+			return true
+		}
+	}
+	return uasurfer.Parse(userAgent).IsBot()
+}
@@ -0,0 +1,121 @@
+package enrich
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/avct/uasurfer"
+	"github.com/oschwald/geoip2-golang"
+)
+
+// ============================================================================
+// VISIT ENRICHMENT
+// ============================================================================
+// Raw visit logs (IP + User-Agent) are hard to report on. At logging time we
+// derive:
+// - Referrer:        the Referer header, truncated to the column size
+// - Country, City:   GeoIP lookup of the client IP (MaxMind GeoLite2/GeoIP2)
+// - Device, Browser, OS: parsed from the User-Agent
+// - IsBot:           crawler / link-preview detection (see bot.go)
+//
+// GeoIP is optional; without a database the geo fields stay empty.
+// ============================================================================
+
+// maxReferrerLength matches the visit_logs.referrer column
+const maxReferrerLength = 2048
+
+// GeoLocator resolves an IP address to a location
+type GeoLocator interface {
+	// Locate returns the ISO country code and city name; empty when unknown
+	Locate(ip string) (country, city string)
+	Close() error
+}
+
+// NoopGeoLocator resolves nothing
+type NoopGeoLocator struct{}
+
+// Locate implements GeoLocator
+func (NoopGeoLocator) Locate(ip string) (string, string) { return "", "" }
+
+// Close implements GeoLocator
+func (NoopGeoLocator) Close() error { return nil }
+
+// MaxMindGeoLocator looks up IPs in a MaxMind City database (.mmdb)
+type MaxMindGeoLocator struct {
+	db *geoip2.Reader
+}
+
+// NewMaxMindGeoLocator opens a MaxMind City database
+func NewMaxMindGeoLocator(path string) (*MaxMindGeoLocator, error) {
+	db, err := geoip2.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+	return &MaxMindGeoLocator{db: db}, nil
+}
+
+// Locate implements GeoLocator
+func (m *MaxMindGeoLocator) Locate(ip string) (string, string) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "", ""
+	}
+	record, err := m.db.City(parsed)
+	if err != nil {
+		return "", ""
+	}
+	return record.Country.IsoCode, record.City.Names["en"]
+}
+
+// Close implements GeoLocator
+func (m *MaxMindGeoLocator) Close() error {
+	return m.db.Close()
+}
+
+// Enricher fills in the derived fields of visit logs
+type Enricher struct {
+	geo GeoLocator
+}
+
+// NewEnricher creates an enricher; geo may be nil to skip geo lookups
+func NewEnricher(geo GeoLocator) *Enricher {
+	if geo == nil {
+		geo = NoopGeoLocator{}
+	}
+	return &Enricher{geo: geo}
+}
+
+// Enrich sets the referrer, geo and client fields of a visit log
+// IP and UserAgent must already be set
+func (e *Enricher) Enrich(log *model.VisitLog, referrer string) {
+	log.Referrer = normalizeReferrer(referrer)
+	log.Country, log.City = e.geo.Locate(log.IP)
+	log.DeviceType, log.Browser, log.OS = ParseUserAgent(log.UserAgent)
+	log.IsBot = IsBot(log.UserAgent)
+}
+
+// ParseUserAgent returns the device type, browser and OS names
+// e.g. ("Phone", "Safari", "iOS"); empty strings for an empty User-Agent
+func ParseUserAgent(userAgent string) (deviceType, browser, os string) {
+	if userAgent == "" {
+		return "", "", ""
+	}
+	ua := uasurfer.Parse(userAgent)
+	return ua.DeviceType.StringTrimPrefix(), ua.Browser.Name.StringTrimPrefix(), ua.OS.Name.StringTrimPrefix()
+}
+
+// normalizeReferrer drops unparseable referrers and truncates long ones
+func normalizeReferrer(referrer string) string {
+	if referrer == "" {
+		return ""
+	}
+	if _, err := url.Parse(referrer); err != nil {
+		return ""
+	}
+	if len(referrer) > maxReferrerLength {
+		return referrer[:maxReferrerLength]
+	}
+	return referrer
+}
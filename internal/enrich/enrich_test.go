@@ -0,0 +1,63 @@
+package enrich
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGeoLocator resolves every IP to a fixed location
+type fakeGeoLocator struct{}
+
+func (fakeGeoLocator) Locate(ip string) (string, string) { return "DE", "Berlin" }
+func (fakeGeoLocator) Close() error                      { return nil }
+
+// TestParseUserAgent tests device, browser and OS detection
+func TestParseUserAgent(t *testing.T) {
+	device, browser, os := ParseUserAgent("Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1")
+	assert.Equal(t, "Phone", device)
+	assert.Equal(t, "Safari", browser)
+	assert.Equal(t, "iOS", os)
+
+	device, browser, os = ParseUserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	assert.Equal(t, "Computer", device)
+	assert.Equal(t, "Chrome", browser)
+	assert.Equal(t, "Windows", os)
+
+	device, browser, os = ParseUserAgent("")
+	assert.Empty(t, device+browser+os)
+}
+
+// TestEnrich tests that all derived fields are filled
+func TestEnrich(t *testing.T) {
+	log := &model.VisitLog{
+		IP:        "203.0.113.7",
+		UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15",
+	}
+	NewEnricher(fakeGeoLocator{}).Enrich(log, "https://news.example.com/post")
+
+	assert.Equal(t, "https://news.example.com/post", log.Referrer)
+	assert.Equal(t, "DE", log.Country)
+	assert.Equal(t, "Berlin", log.City)
+	assert.Equal(t, "Computer", log.DeviceType)
+	assert.Equal(t, "Safari", log.Browser)
+	assert.Equal(t, "MacOSX", log.OS)
+}
+
+// TestEnrichWithoutGeoIP tests that geo fields stay empty without a database
+func TestEnrichWithoutGeoIP(t *testing.T) {
+	log := &model.VisitLog{IP: "203.0.113.7"}
+	NewEnricher(nil).Enrich(log, "")
+	assert.Empty(t, log.Country)
+	assert.Empty(t, log.City)
+	assert.Empty(t, log.Referrer)
+}
+
+// TestNormalizeReferrer tests truncation and invalid referrers
+func TestNormalizeReferrer(t *testing.T) {
+	assert.Empty(t, normalizeReferrer("http://[::1"))
+	long := "https://example.com/" + strings.Repeat("a", 3000)
+	assert.Len(t, normalizeReferrer(long), maxReferrerLength)
+}
@@ -0,0 +1,54 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaPublisher publishes click events to a Kafka topic
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher creates a Kafka publisher
+// Messages are batched and written asynchronously; delivery errors are logged
+func NewKafkaPublisher(brokers []string, topic string) (*KafkaPublisher, error) {
+	if len(brokers) == 0 || topic == "" {
+		return nil, fmt.Errorf("kafka brokers and topic are required")
+	}
+
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{}, // Same short code -> same partition
+		BatchTimeout: 100 * time.Millisecond,
+		Async:        true,
+		Completion: func(messages []kafka.Message, err error) {
+			if err != nil {
+				fmt.Printf("Failed to publish %d click events: %v\n", len(messages), err)
+			}
+		},
+	}
+	return &KafkaPublisher{writer: writer}, nil
+}
+
+// Publish implements Publisher
+func (p *KafkaPublisher) Publish(ctx context.Context, event *ClickEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode click event: %w", err)
+	}
+	return p.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(event.ShortCode),
+		Value: payload,
+	})
+}
+
+// Close implements Publisher
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
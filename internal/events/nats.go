@@ -0,0 +1,50 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSPublisher publishes click events to a NATS subject
+type NATSPublisher struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// NewNATSPublisher connects to NATS and creates a publisher
+// The client buffers while reconnecting, so short outages lose no events
+func NewNATSPublisher(url, subject string) (*NATSPublisher, error) {
+	if subject == "" {
+		return nil, fmt.Errorf("nats subject is required")
+	}
+
+	conn, err := nats.Connect(url, nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	return &NATSPublisher{conn: conn, subject: subject}, nil
+}
+
+// Publish implements Publisher
+func (p *NATSPublisher) Publish(ctx context.Context, event *ClickEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode click event: %w", err)
+	}
+	if err := p.conn.Publish(p.subject, payload); err != nil {
+		return fmt.Errorf("failed to publish click event: %w", err)
+	}
+	return nil
+}
+
+// Close implements Publisher
+func (p *NATSPublisher) Close() error {
+	if err := p.conn.Drain(); err != nil {
+		p.conn.Close()
+		return err
+	}
+	return nil
+}
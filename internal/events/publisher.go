@@ -0,0 +1,65 @@
+package events
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// ============================================================================
+// CLICK EVENT PUBLISHING
+// ============================================================================
+// Every redirect can be emitted as a ClickEvent to a message broker so
+// downstream analytics pipelines consume clicks without querying MySQL.
+//
+// Backends:
+// - kafka: one topic, keyed by short code (clicks for a link stay ordered)
+// - nats:  one subject, fire-and-forget
+// - none:  NoopPublisher (default)
+//
+// Publishing is asynchronous and best effort: a slow or unavailable broker
+// never delays a redirect. Visit logs in MySQL remain the source of truth.
+// ============================================================================
+
+// Event publisher backends
+const (
+	BackendNone  = "none"
+	BackendKafka = "kafka"
+	BackendNATS  = "nats"
+)
+
+// ClickEvent is the payload emitted for every redirect
+type ClickEvent struct {
+	ShortCode string    `json:"short_code"`
+	Timestamp time.Time `json:"timestamp"`
+	IPHash    string    `json:"ip_hash"` // Salted SHA-256, never the raw IP
+	UserAgent string    `json:"user_agent,omitempty"`
+	Referrer  string    `json:"referrer,omitempty"`
+	Country   string    `json:"country,omitempty"`
+	IsBot     bool      `json:"is_bot"`
+}
+
+// Publisher emits click events to a message broker
+type Publisher interface {
+	// Publish enqueues an event; it must not block on the network
+	Publish(ctx context.Context, event *ClickEvent) error
+	// Close flushes pending events and releases the connection
+	Close() error
+}
+
+// NoopPublisher discards all events
+type NoopPublisher struct{}
+
+// Publish implements Publisher
+func (NoopPublisher) Publish(ctx context.Context, event *ClickEvent) error { return nil }
+
+// Close implements Publisher
+func (NoopPublisher) Close() error { return nil }
+
+// HashIP returns the salted SHA-256 of an IP address as hex
+// The salt keeps hashes from being reversed with a lookup table of all IPv4s
+func HashIP(ip, salt string) string {
+	sum := sha256.Sum256([]byte(salt + ip))
+	return hex.EncodeToString(sum[:])
+}
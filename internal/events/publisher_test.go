@@ -0,0 +1,40 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHashIP tests that IPs are hashed deterministically with the salt
+func TestHashIP(t *testing.T) {
+	a := HashIP("203.0.113.7", "salt")
+	assert.Len(t, a, 64)
+	assert.Equal(t, a, HashIP("203.0.113.7", "salt"))
+	assert.NotEqual(t, a, HashIP("203.0.113.7", "other"))
+	assert.NotContains(t, a, "203.0.113.7")
+}
+
+// TestClickEventJSON tests the event wire format
+func TestClickEventJSON(t *testing.T) {
+	payload, err := json.Marshal(&ClickEvent{
+		ShortCode: "abc123",
+		Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		IPHash:    "h",
+		Referrer:  "https://example.com",
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t,
+		`{"short_code":"abc123","timestamp":"2024-01-01T10:00:00Z","ip_hash":"h","referrer":"https://example.com","is_bot":false}`,
+		string(payload))
+}
+
+// TestNoopPublisher tests that the default publisher accepts everything
+func TestNoopPublisher(t *testing.T) {
+	var p Publisher = NoopPublisher{}
+	assert.NoError(t, p.Publish(context.Background(), &ClickEvent{ShortCode: "abc123"}))
+	assert.NoError(t, p.Close())
+}
@@ -1,21 +1,61 @@
 package filter
 
 import (
+	"math"
 	"sync"
 
 	"github.com/bits-and-blooms/bloom/v3"
 )
 
 // BloomFilter wraps the bloom filter with thread-safety
+// Bloom filters can't remove entries, so codes of deleted links stay in the
+// filter until it is rebuilt from the database (see Rebuild).
 type BloomFilter struct {
-	filter *bloom.BloomFilter
-	mu     sync.RWMutex
+	filter   *bloom.BloomFilter
+	capacity uint
+	fpRate   float64
+	mu       sync.RWMutex
+
+	// Codes added while a rebuild loads its snapshot; replayed into the new
+	// filter so none are lost between the snapshot and the swap
+	recording bool
+	pending   []string
+	// Serializes rebuilds
+	rebuildMu sync.Mutex
+
+	// Grow the capacity on rebuilds once the codes no longer fit
+	autoResize bool
+	resizes    uint64
+}
+
+// growAt is the share of the capacity in use above which a rebuild with
+// auto-resize doubles the capacity
+const growAt = 0.9
+
+// Stats describes how full the filter is
+type Stats struct {
+	Capacity          uint    // Codes the filter was sized for
+	Bits              uint    // Size of the bit array
+	HashFunctions     uint    // Bits set per code
+	FillRatio         float64 // Share of bits set
+	EstimatedEntries  uint    // Distinct codes added, estimated from FillRatio
+	FalsePositiveRate float64 // Current rate, estimated from FillRatio
+	TargetRate        float64 // Configured rate at capacity
+	Resizes           uint64  // Rebuilds that grew the capacity
+}
+
+// Saturated reports whether more codes were added than the filter was sized
+// for, so its false positive rate is above the configured one
+func (s Stats) Saturated() bool {
+	return s.EstimatedEntries > s.Capacity
 }
 
 // NewBloomFilter creates a new Bloom filter with specified capacity and false positive rate
 func NewBloomFilter(capacity uint, fpRate float64) *BloomFilter {
 	return &BloomFilter{
-		filter: bloom.NewWithEstimates(capacity, fpRate),
+		filter:   bloom.NewWithEstimates(capacity, fpRate),
+		capacity: capacity,
+		fpRate:   fpRate,
 	}
 }
 
@@ -24,6 +64,9 @@ func (bf *BloomFilter) Add(shortCode string) {
 	bf.mu.Lock()
 	defer bf.mu.Unlock()
 	bf.filter.AddString(shortCode)
+	if bf.recording {
+		bf.pending = append(bf.pending, shortCode)
+	}
 }
 
 // Test checks if a short code might exist in the Bloom filter
@@ -42,6 +85,9 @@ func (bf *BloomFilter) AddBatch(shortCodes []string) {
 	for _, code := range shortCodes {
 		bf.filter.AddString(code)
 	}
+	if bf.recording {
+		bf.pending = append(bf.pending, shortCodes...)
+	}
 }
 
 // Clear clears the Bloom filter
@@ -50,3 +96,81 @@ func (bf *BloomFilter) Clear() {
 	defer bf.mu.Unlock()
 	bf.filter.ClearAll()
 }
+
+// SetAutoResize makes rebuilds double the capacity until the loaded codes
+// take up at most 90% of it; capacity never shrinks
+func (bf *BloomFilter) SetAutoResize(enabled bool) {
+	bf.rebuildMu.Lock()
+	defer bf.rebuildMu.Unlock()
+	bf.autoResize = enabled
+}
+
+// Stats returns the filter's current fill and estimated false positive rate
+// Counting the set bits is linear in the filter size (about a millisecond
+// for the default 10 million codes).
+func (bf *BloomFilter) Stats() Stats {
+	bf.mu.RLock()
+	defer bf.mu.RUnlock()
+
+	bits := bf.filter.Cap()
+	k := bf.filter.K()
+	fill := float64(bf.filter.BitSet().Count()) / float64(bits)
+	return Stats{
+		Capacity:          bf.capacity,
+		Bits:              bits,
+		HashFunctions:     k,
+		FillRatio:         fill,
+		EstimatedEntries:  uint(bf.filter.ApproximatedSize()),
+		FalsePositiveRate: math.Pow(fill, float64(k)),
+		TargetRate:        bf.fpRate,
+		Resizes:           bf.resizes,
+	}
+}
+
+// Rebuild replaces the filter with a fresh one holding the codes returned by
+// load plus any added while load ran, dropping codes that no longer exist
+// Lookups keep using the old filter until the new one is swapped in. On a
+// load error the old filter is kept. Returns the number of codes loaded.
+func (bf *BloomFilter) Rebuild(load func() ([]string, error)) (int, error) {
+	bf.rebuildMu.Lock()
+	defer bf.rebuildMu.Unlock()
+
+	bf.mu.Lock()
+	bf.recording = true
+	bf.pending = nil
+	bf.mu.Unlock()
+
+	shortCodes, err := load()
+	if err != nil {
+		bf.mu.Lock()
+		bf.recording = false
+		bf.pending = nil
+		bf.mu.Unlock()
+		return 0, err
+	}
+
+	capacity := bf.capacity
+	if bf.autoResize {
+		for float64(len(shortCodes)) > growAt*float64(capacity) {
+			capacity *= 2
+		}
+	}
+	fresh := bloom.NewWithEstimates(capacity, bf.fpRate)
+	for _, code := range shortCodes {
+		fresh.AddString(code)
+	}
+
+	bf.mu.Lock()
+	defer bf.mu.Unlock()
+	for _, code := range bf.pending {
+		fresh.AddString(code)
+	}
+	bf.filter = fresh
+	if capacity != bf.capacity {
+		bf.capacity = capacity
+		bf.resizes++
+	}
+	bf.recording = false
+	bf.pending = nil
+	return len(shortCodes), nil
+}
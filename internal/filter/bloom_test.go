@@ -0,0 +1,72 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBloomFilterRebuild tests that a rebuild drops removed codes but keeps
+// codes added while it loads
+func TestBloomFilterRebuild(t *testing.T) {
+	bf := NewBloomFilter(1000, 0.001)
+	bf.AddBatch([]string{"kept", "deleted"})
+
+	loaded, err := bf.Rebuild(func() ([]string, error) {
+		bf.Add("created")
+		return []string{"kept"}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, loaded)
+	assert.True(t, bf.Test("kept"))
+	assert.True(t, bf.Test("created"), "added during the load")
+	assert.False(t, bf.Test("deleted"))
+
+	_, err = bf.Rebuild(func() ([]string, error) {
+		return nil, errors.New("database down")
+	})
+	assert.Error(t, err)
+	assert.True(t, bf.Test("kept"), "the old filter is kept")
+}
+
+// TestBloomFilterAutoResize tests growing a saturated filter on rebuild
+func TestBloomFilterAutoResize(t *testing.T) {
+	codes := make([]string, 500)
+	for i := range codes {
+		codes[i] = fmt.Sprintf("code%d", i)
+	}
+
+	bf := NewBloomFilter(100, 0.01)
+	bf.AddBatch(codes)
+	stats := bf.Stats()
+	assert.True(t, stats.Saturated())
+	assert.Greater(t, stats.FalsePositiveRate, stats.TargetRate)
+
+	bf.SetAutoResize(true)
+	_, err := bf.Rebuild(func() ([]string, error) { return codes, nil })
+	assert.NoError(t, err)
+	stats = bf.Stats()
+	assert.Equal(t, uint(800), stats.Capacity)
+	assert.Equal(t, uint64(1), stats.Resizes)
+	assert.False(t, stats.Saturated())
+	assert.Less(t, stats.FalsePositiveRate, stats.TargetRate)
+	assert.InDelta(t, 500, stats.EstimatedEntries, 50)
+}
+
+// BenchmarkBloomFilterTest measures the check in front of every redirect
+func BenchmarkBloomFilterTest(b *testing.B) {
+	bf := NewBloomFilter(1000000, 0.01)
+	for i := 0; i < 100000; i++ {
+		bf.Add(fmt.Sprintf("code%d", i))
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			bf.Test(fmt.Sprintf("code%d", i%200000))
+			i++
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/service"
+	shortlinkv1 "github.com/Monthlyaway/short-link/proto/shortlink/v1"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ============================================================================
+// gRPC API
+// ============================================================================
+// Internal services can shorten and resolve links over gRPC instead of HTTP.
+// The server is a thin adapter over URLService - the same service the REST
+// handlers use - so caching, the Bloom filter and validation are shared.
+//
+// Service errors map to gRPC codes:
+// - ErrInvalidURL          -> InvalidArgument
+// - ErrUnknownDomain       -> InvalidArgument
+// - ErrRejectedByHook      -> InvalidArgument
+// - ErrShortCodeNotFound   -> NotFound
+// - ErrShortCodeInactive   -> FailedPrecondition
+// - ErrDatabaseUnavailable -> Unavailable
+// - deadline exceeded      -> DeadlineExceeded
+// - anything else          -> Internal
+// ============================================================================
+
+// Server implements shortlinkv1.ShortLinkServiceServer
+type Server struct {
+	shortlinkv1.UnimplementedShortLinkServiceServer
+
+	service *service.URLService
+}
+
+// NewServer creates a gRPC server adapter for URLService
+func NewServer(service *service.URLService) *Server {
+	return &Server{service: service}
+}
+
+// Register registers the service on a gRPC server
+func (s *Server) Register(registrar grpclib.ServiceRegistrar) {
+	shortlinkv1.RegisterShortLinkServiceServer(registrar, s)
+}
+
+// Shorten implements shortlinkv1.ShortLinkServiceServer
+func (s *Server) Shorten(ctx context.Context, req *shortlinkv1.ShortenRequest) (*shortlinkv1.ShortenResponse, error) {
+	var expiredAt *time.Time
+	if req.GetExpiredAt() != nil {
+		t := req.GetExpiredAt().AsTime()
+		expiredAt = &t
+	}
+
+	mapping, err := s.service.CreateShortURL(ctx, req.GetUrl(), req.GetDomain(), expiredAt, model.LinkOptions{})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &shortlinkv1.ShortenResponse{
+		ShortCode:   mapping.ShortCode,
+		ShortUrl:    s.service.ShortURL(mapping, ""),
+		OriginalUrl: mapping.OriginalURL,
+		ExpiredAt:   optionalTimestamp(mapping.ExpiredAt),
+		Domain:      mapping.Domain,
+	}, nil
+}
+
+// Resolve implements shortlinkv1.ShortLinkServiceServer
+func (s *Server) Resolve(ctx context.Context, req *shortlinkv1.ResolveRequest) (*shortlinkv1.ResolveResponse, error) {
+	if req.GetShortCode() == "" {
+		return nil, status.Error(codes.InvalidArgument, "short code is required")
+	}
+
+	// gRPC callers aren't visitors: no referrer or IP, so links restricted to
+	// referrers or allowed networks don't resolve. Links behind an unsafe-link
+	// warning do: the warning is for people following the link.
+	mapping, err := s.service.ResolveLink(ctx, req.GetHost(), req.GetShortCode(), model.Visitor{})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &shortlinkv1.ResolveResponse{OriginalUrl: mapping.OriginalURL}, nil
+}
+
+// GetInfo implements shortlinkv1.ShortLinkServiceServer
+func (s *Server) GetInfo(ctx context.Context, req *shortlinkv1.GetInfoRequest) (*shortlinkv1.GetInfoResponse, error) {
+	if req.GetShortCode() == "" {
+		return nil, status.Error(codes.InvalidArgument, "short code is required")
+	}
+
+	mapping, err := s.service.GetURLInfo(ctx, req.GetShortCode())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	// gRPC callers are anonymous, and organization links need a member
+	if mapping.OrgID != 0 {
+		return nil, toStatus(service.ErrForbidden)
+	}
+	return infoResponse(mapping, s.service.ShortURL(mapping, "")), nil
+}
+
+// infoResponse converts a mapping to a GetInfoResponse
+func infoResponse(mapping *model.URLMapping, shortURL string) *shortlinkv1.GetInfoResponse {
+	return &shortlinkv1.GetInfoResponse{
+		ShortCode:     mapping.ShortCode,
+		OriginalUrl:   mapping.OriginalURL,
+		VisitCount:    mapping.VisitCount,
+		BotVisitCount: mapping.BotVisitCount,
+		CreatedAt:     timestamppb.New(mapping.CreatedAt),
+		ExpiredAt:     optionalTimestamp(mapping.ExpiredAt),
+		Domain:        mapping.Domain,
+		ShortUrl:      shortURL,
+	}
+}
+
+// optionalTimestamp converts a nullable time; nil stays nil
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+// toStatus maps service errors to gRPC status errors
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidURL), errors.Is(err, service.ErrUnknownDomain),
+		errors.Is(err, service.ErrRejectedByHook):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrShortCodeNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrShortCodeInactive):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrForbidden), errors.Is(err, service.ErrAccessDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, service.ErrDatabaseUnavailable):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
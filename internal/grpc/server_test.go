@@ -0,0 +1,49 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestToStatus tests mapping of service errors to gRPC codes
+func TestToStatus(t *testing.T) {
+	cases := map[error]codes.Code{
+		fmt.Errorf("%w: URL cannot be empty", service.ErrInvalidURL): codes.InvalidArgument,
+		service.ErrShortCodeNotFound:                                 codes.NotFound,
+		service.ErrShortCodeInactive:                                 codes.FailedPrecondition,
+		service.ErrForbidden:                                         codes.PermissionDenied,
+		errors.New("connection refused"):                             codes.Internal,
+	}
+	for err, code := range cases {
+		assert.Equal(t, code, status.Code(toStatus(err)), err.Error())
+	}
+}
+
+// TestInfoResponse tests conversion of a mapping to the protobuf message
+func TestInfoResponse(t *testing.T) {
+	created := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	resp := infoResponse(&model.URLMapping{
+		ShortCode:     "abc123",
+		OriginalURL:   "https://example.com",
+		VisitCount:    10,
+		BotVisitCount: 2,
+		CreatedAt:     created,
+		Domain:        "s.example.com",
+	}, "https://s.example.com/abc123")
+
+	assert.Equal(t, "abc123", resp.GetShortCode())
+	assert.Equal(t, uint64(10), resp.GetVisitCount())
+	assert.Equal(t, uint64(2), resp.GetBotVisitCount())
+	assert.Equal(t, created, resp.GetCreatedAt().AsTime())
+	assert.Nil(t, resp.GetExpiredAt())
+	assert.Equal(t, "s.example.com", resp.GetDomain())
+	assert.Equal(t, "https://s.example.com/abc123", resp.GetShortUrl())
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// Abuse listing limits
+const (
+	defaultAbuseListLimit = 50
+	maxAbuseListLimit     = 500
+)
+
+// ReportURLRequest represents a report that a link is malicious
+type ReportURLRequest struct {
+	Category string `json:"category"` // phishing, malware, spam or other (default)
+	Details  string `json:"details"`
+}
+
+// ReportURL handles POST /api/v1/report/{short_code}
+// Open to anyone, so it is rate limited per IP in the config
+func (h *URLHandler) ReportURL(c *gin.Context) {
+	var req ReportURLRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	err := h.service.ReportLink(c.Request.Context(), c.Param("short_code"), req.Category, req.Details, c.ClientIP())
+	if errors.Is(err, service.ErrInvalidReport) {
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrShortCodeNotFound) {
+		respond(c, http.StatusNotFound, Response{
+			Code:    http.StatusNotFound,
+			Message: "Short URL not found",
+		})
+		return
+	}
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to store report: " + err.Error(),
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code:    http.StatusOK,
+		Message: "Report received",
+	})
+}
+
+// ListReportedURLs handles GET /admin/abuse/reports
+// Lists links with unresolved reports, most reported first
+// Query: limit (default 50, max 500)
+func (h *URLHandler) ListReportedURLs(c *gin.Context) {
+	limit, ok := abuseListLimit(c)
+	if !ok {
+		return
+	}
+
+	links, err := h.service.ListReportedLinks(c.Request.Context(), limit)
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to list reported links: " + err.Error(),
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: links,
+	})
+}
+
+// ListAbuseReports handles GET /admin/abuse/reports/{short_code}
+// Query: limit (default 50, max 500)
+func (h *URLHandler) ListAbuseReports(c *gin.Context) {
+	limit, ok := abuseListLimit(c)
+	if !ok {
+		return
+	}
+
+	reports, err := h.service.ListAbuseReports(c.Request.Context(), c.Param("short_code"), limit)
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to list abuse reports: " + err.Error(),
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: reports,
+	})
+}
+
+// ListAbuseFlags handles GET /admin/abuse/flags
+// Query: short_code (flags of one link), limit (default 50, max 500)
+func (h *URLHandler) ListAbuseFlags(c *gin.Context) {
+	limit, ok := abuseListLimit(c)
+	if !ok {
+		return
+	}
+
+	flags, err := h.service.ListAbuseFlags(c.Request.Context(), c.Query("short_code"), limit)
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to list abuse flags: " + err.Error(),
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: flags,
+	})
+}
+
+// EnableURL handles POST /admin/links/{short_code}/enable
+// Clears a warning too; also resolves the link's reports and lifts an
+// abuse throttle
+func (h *URLHandler) EnableURL(c *gin.Context) {
+	h.moderateURL(c, model.ModerationAllow, "Short URL enabled")
+}
+
+// WarnURL handles POST /admin/links/{short_code}/warn
+// The link stays up behind an unsafe-link warning; also resolves its
+// reports and lifts an abuse throttle
+func (h *URLHandler) WarnURL(c *gin.Context) {
+	h.moderateURL(c, model.ModerationWarn, "Short URL now shows a warning")
+}
+
+// DisableURL handles POST /admin/links/{short_code}/disable
+func (h *URLHandler) DisableURL(c *gin.Context) {
+	h.moderateURL(c, model.ModerationDisable, "Short URL disabled")
+}
+
+// moderateURL applies a moderation decision to the link named in the path
+func (h *URLHandler) moderateURL(c *gin.Context, decision, message string) {
+	err := h.service.ModerateLink(c.Request.Context(), c.Param("short_code"), decision)
+	if errors.Is(err, service.ErrShortCodeNotFound) {
+		respond(c, http.StatusNotFound, Response{
+			Code:    http.StatusNotFound,
+			Message: "Short URL not found",
+		})
+		return
+	}
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to update short URL: " + err.Error(),
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code:    http.StatusOK,
+		Message: message,
+	})
+}
+
+// abuseListLimit parses the limit query parameter of the abuse listings,
+// writing a 400 response if it is invalid
+func abuseListLimit(c *gin.Context) (int, bool) {
+	limit, err := queryInt(c, "limit")
+	if err != nil || limit < 0 || limit > maxAbuseListLimit {
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: limit must be between 1 and 500",
+		})
+		return 0, false
+	}
+	if limit == 0 {
+		limit = defaultAbuseListLimit
+	}
+	return limit, true
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler handles HTTP requests for operational tasks
+type AdminHandler struct {
+	reloadRateLimits func() error
+}
+
+// NewAdminHandler creates a new admin handler instance
+func NewAdminHandler(reloadRateLimits func() error) *AdminHandler {
+	return &AdminHandler{
+		reloadRateLimits: reloadRateLimits,
+	}
+}
+
+// ReloadRateLimits handles POST /admin/rate-limit/reload
+func (h *AdminHandler) ReloadRateLimits(c *gin.Context) {
+	if err := h.reloadRateLimits(); err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to reload rate limits: " + err.Error(),
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code:    http.StatusOK,
+		Message: "Rate limits reloaded",
+	})
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// SetAliasRequest represents the request body for creating or moving an
+// alias
+type SetAliasRequest struct {
+	ShortCode string `json:"short_code" binding:"required"`
+}
+
+// AliasResponse represents an alias in the caller's namespace
+type AliasResponse struct {
+	Namespace string `json:"namespace"`
+	Alias     string `json:"alias"`
+	ShortCode string `json:"short_code"`
+	URL       string `json:"url"`
+}
+
+// SetAlias handles PUT /api/v1/aliases/{alias}
+// Points the alias, in the caller's namespace, at a link
+func (h *URLHandler) SetAlias(c *gin.Context) {
+	userID, ok := requireUser(c)
+	if !ok {
+		return
+	}
+	var req SetAliasRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	alias, err := h.service.SetAlias(c.Request.Context(), userID, c.Param("alias"), req.ShortCode)
+	if err != nil {
+		writeAliasError(c, err, "Failed to set alias")
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: h.aliasResponse(c, alias),
+	})
+}
+
+// DeleteAlias handles DELETE /api/v1/aliases/{alias}
+func (h *URLHandler) DeleteAlias(c *gin.Context) {
+	userID, ok := requireUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteAlias(c.Request.Context(), userID, c.Param("alias")); err != nil {
+		writeAliasError(c, err, "Failed to delete alias")
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code:    http.StatusOK,
+		Message: "Alias deleted",
+	})
+}
+
+// ListAliases handles GET /api/v1/aliases
+func (h *URLHandler) ListAliases(c *gin.Context) {
+	userID, ok := requireUser(c)
+	if !ok {
+		return
+	}
+
+	aliases, err := h.service.ListAliases(c.Request.Context(), userID)
+	if err != nil {
+		writeAliasError(c, err, "Failed to list aliases")
+		return
+	}
+
+	data := make([]AliasResponse, 0, len(aliases))
+	for i := range aliases {
+		data = append(data, h.aliasResponse(c, &aliases[i]))
+	}
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: data,
+	})
+}
+
+// RedirectAlias returns the handler for GET /{namespace}/{alias}, which
+// redirects like the short code the alias points at
+func (h *URLHandler) RedirectAlias(namespace string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode, err := h.service.ResolveAlias(c.Request.Context(), namespace, c.Param("alias"))
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, service.ErrDatabaseUnavailable) {
+				respond(c, http.StatusServiceUnavailable, Response{
+					Code:    http.StatusServiceUnavailable,
+					Message: "Service temporarily unavailable",
+				})
+				return
+			}
+			respond(c, http.StatusNotFound, Response{
+				Code:    http.StatusNotFound,
+				Message: "Short URL not found or expired",
+			})
+			return
+		}
+		h.redirect(c, shortCode)
+	}
+}
+
+func (h *URLHandler) aliasResponse(c *gin.Context, alias *model.LinkAlias) AliasResponse {
+	return AliasResponse{
+		Namespace: alias.Namespace,
+		Alias:     alias.Alias,
+		ShortCode: alias.ShortCode,
+		URL:       h.service.AliasURL(h.requestOrigin(c), alias),
+	}
+}
+
+// writeAliasError maps alias service errors to HTTP responses
+func writeAliasError(c *gin.Context, err error, message string) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrNoNamespace), errors.Is(err, service.ErrForbidden):
+		code = http.StatusForbidden
+	case errors.Is(err, service.ErrAliasNotFound), errors.Is(err, service.ErrShortCodeNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidAlias):
+		code = http.StatusBadRequest
+	}
+	if code != http.StatusInternalServerError {
+		message = err.Error()
+	} else {
+		message += ": " + err.Error()
+	}
+	respond(c, code, Response{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// CampaignHandler handles HTTP requests for campaigns
+type CampaignHandler struct {
+	service *service.URLService
+}
+
+// NewCampaignHandler creates a new campaign handler instance
+func NewCampaignHandler(service *service.URLService) *CampaignHandler {
+	return &CampaignHandler{service: service}
+}
+
+// CreateCampaignRequest represents the request body for creating a campaign
+type CreateCampaignRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description,omitempty"`
+	OrgID       uint   `json:"org_id,omitempty"` // Owning organization; the caller must be an editor
+}
+
+// AttachLinksRequest represents the request body for attaching links
+type AttachLinksRequest struct {
+	ShortCodes []string `json:"short_codes" binding:"required"`
+}
+
+// CampaignStatsResponse represents the response for campaign statistics
+// Breakdowns count human visits only
+type CampaignStatsResponse struct {
+	Campaign   *model.Campaign          `json:"campaign"`
+	LinkCount  int                      `json:"link_count"`
+	VisitCount uint64                   `json:"visit_count"`
+	BotVisits  uint64                   `json:"bot_visit_count"`
+	Links      []model.CampaignLinkStat `json:"links"`
+	Countries  []model.VisitStat        `json:"countries"`
+	Referrers  []model.VisitStat        `json:"referrers"`
+}
+
+// CreateCampaign handles POST /api/v1/campaigns
+func (h *CampaignHandler) CreateCampaign(c *gin.Context) {
+	userID, ok := requireUser(c)
+	if !ok {
+		return
+	}
+	var req CreateCampaignRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	campaign, err := h.service.CreateCampaign(c.Request.Context(), req.Name, req.Description, userID, req.OrgID)
+	if err != nil {
+		h.writeError(c, err, "Failed to create campaign")
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: campaign,
+	})
+}
+
+// GetCampaign handles GET /api/v1/campaigns/{id}
+func (h *CampaignHandler) GetCampaign(c *gin.Context) {
+	userID, ok := requireUser(c)
+	if !ok {
+		return
+	}
+	id, ok := campaignID(c)
+	if !ok {
+		return
+	}
+
+	campaign, err := h.service.GetCampaign(c.Request.Context(), id, userID)
+	if err != nil {
+		h.writeError(c, err, "Failed to get campaign")
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: campaign,
+	})
+}
+
+// AttachLinks handles POST /api/v1/campaigns/{id}/links
+func (h *CampaignHandler) AttachLinks(c *gin.Context) {
+	userID, ok := requireUser(c)
+	if !ok {
+		return
+	}
+	id, ok := campaignID(c)
+	if !ok {
+		return
+	}
+	var req AttachLinksRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	missing, err := h.service.AttachLinks(c.Request.Context(), id, userID, req.ShortCodes)
+	if errors.Is(err, service.ErrShortCodeNotFound) {
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: "Unknown short codes; no links were attached",
+			Data:    gin.H{"missing": missing},
+		})
+		return
+	}
+	if err != nil {
+		h.writeError(c, err, "Failed to attach links")
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code:    http.StatusOK,
+		Message: "Links attached",
+	})
+}
+
+// DetachLink handles DELETE /api/v1/campaigns/{id}/links/{short_code}
+func (h *CampaignHandler) DetachLink(c *gin.Context) {
+	userID, ok := requireUser(c)
+	if !ok {
+		return
+	}
+	id, ok := campaignID(c)
+	if !ok {
+		return
+	}
+
+	err := h.service.DetachLink(c.Request.Context(), id, userID, c.Param("short_code"))
+	if errors.Is(err, service.ErrShortCodeNotFound) {
+		respond(c, http.StatusNotFound, Response{
+			Code:    http.StatusNotFound,
+			Message: "Short URL is not in this campaign",
+		})
+		return
+	}
+	if err != nil {
+		h.writeError(c, err, "Failed to detach link")
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code:    http.StatusOK,
+		Message: "Link detached",
+	})
+}
+
+// GetCampaignStats handles GET /api/v1/campaigns/{id}/stats
+// Query: from and to (RFC3339 or YYYY-MM-DD) limit the breakdowns
+func (h *CampaignHandler) GetCampaignStats(c *gin.Context) {
+	userID, ok := requireUser(c)
+	if !ok {
+		return
+	}
+	id, ok := campaignID(c)
+	if !ok {
+		return
+	}
+	var from, to time.Time
+	var err error
+	if from, err = parseExportTime(c.Query("from")); err != nil {
+		invalidParam(c, "from", err)
+		return
+	}
+	if to, err = parseExportTime(c.Query("to")); err != nil {
+		invalidParam(c, "to", err)
+		return
+	}
+
+	stats, err := h.service.GetCampaignStats(c.Request.Context(), id, userID, from, to)
+	if err != nil {
+		h.writeError(c, err, "Failed to get campaign stats")
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: CampaignStatsResponse{
+			Campaign:   stats.Campaign,
+			LinkCount:  len(stats.Links),
+			VisitCount: stats.VisitCount,
+			BotVisits:  stats.BotVisitCount,
+			Links:      stats.Links,
+			Countries:  stats.Countries,
+			Referrers:  stats.Referrers,
+		},
+	})
+}
+
+// campaignID parses the :id path parameter, writing a 400 if it's invalid
+func campaignID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid campaign ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// writeError maps campaign service errors to HTTP responses
+func (h *CampaignHandler) writeError(c *gin.Context, err error, message string) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrCampaignNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, service.ErrCampaignExists):
+		code = http.StatusConflict
+	case errors.Is(err, service.ErrInvalidCampaign):
+		code = http.StatusBadRequest
+	case errors.Is(err, service.ErrForbidden):
+		code = http.StatusForbidden
+	}
+	if code != http.StatusInternalServerError {
+		message = err.Error()
+	} else {
+		message += ": " + err.Error()
+	}
+	respond(c, code, Response{
+		Code:    code,
+		Message: message,
+	})
+}
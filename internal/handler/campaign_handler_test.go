@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCampaignID tests path parameter validation
+func TestCampaignID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		param string
+		ok    bool
+	}{
+		{"42", true},
+		{"0", false},
+		{"-1", false},
+		{"abc", false},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: tc.param}}
+
+		_, ok := campaignID(c)
+		assert.Equal(t, tc.ok, ok, tc.param)
+		if !tc.ok {
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+// TestCampaignsNeedUser tests that anonymous callers get a 401 before the
+// campaign is looked up
+func TestCampaignsNeedUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewCampaignHandler(nil)
+	router := gin.New()
+	router.POST("/campaigns", h.CreateCampaign)
+	router.GET("/campaigns/:id", h.GetCampaign)
+	router.POST("/campaigns/:id/links", h.AttachLinks)
+	router.DELETE("/campaigns/:id/links/:short_code", h.DetachLink)
+	router.GET("/campaigns/:id/stats", h.GetCampaignStats)
+
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodPost, "/campaigns"},
+		{http.MethodGet, "/campaigns/1"},
+		{http.MethodPost, "/campaigns/1/links"},
+		{http.MethodDelete, "/campaigns/1/links/abc123"},
+		{http.MethodGet, "/campaigns/1/stats"},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code, tc.path)
+	}
+}
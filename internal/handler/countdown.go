@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// REDIRECT COUNTDOWN
+// ============================================================================
+// Links can make visitors wait a few seconds on a page naming the
+// destination before they are sent on, e.g. for deployments that must show
+// a disclaimer first. The delay is per link (redirect_delay) or global
+// (redirect_delay.seconds, for links without their own); the page counts
+// down with a script and redirects with a meta refresh, so it works without
+// scripts too. It has no ads or tracking, is never cached, and has a link
+// to continue at once.
+//
+// Unsafe-link warnings take precedence, and mobile visitors opening a deep
+// link skip it. The page is an html/template executed with countdownData;
+// operators can replace the built-in one (redirect_delay.template).
+// ============================================================================
+
+// countdownSettings are the global countdown settings
+type countdownSettings struct {
+	seconds    int                // Delay for links without their own; 0 for none
+	disclaimer string             // Shown on the page; empty for none
+	tmpl       *template.Template // nil uses the built-in page
+}
+
+// countdownData is what the countdown template is executed with
+type countdownData struct {
+	ShortCode  string
+	URL        string // Destination
+	Host       string // Destination host, to show prominently
+	Seconds    int
+	Disclaimer string
+}
+
+// defaultCountdown is the built-in countdown page
+var defaultCountdown = template.Must(template.New("countdown").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex, nofollow">
+<meta http-equiv="refresh" content="{{.Seconds}};url={{.URL}}">
+<title>Redirecting to {{.Host}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 40rem; margin: 4rem auto; padding: 0 1rem; color: #222; }
+.destination { word-break: break-all; background: #f4f4f4; padding: .75rem; border-radius: 4px; }
+.disclaimer { border-left: 3px solid #ccc; padding-left: .75rem; color: #555; }
+</style>
+</head>
+<body>
+<h1>You are leaving for {{.Host}}</h1>
+<p class="destination">{{.URL}}</p>
+{{if .Disclaimer}}<p class="disclaimer">{{.Disclaimer}}</p>
+{{end}}<p>You will be redirected in <strong id="seconds">{{.Seconds}}</strong> seconds.</p>
+<p><a href="{{.URL}}" rel="noopener noreferrer">Continue now</a></p>
+<script>
+(function () {
+  var left = {{.Seconds}};
+  var label = document.getElementById("seconds");
+  var timer = setInterval(function () {
+    left--;
+    label.textContent = Math.max(left, 0);
+    if (left <= 0) {
+      clearInterval(timer);
+      window.location.replace({{.URL}});
+    }
+  }, 1000);
+})();
+</script>
+</body>
+</html>
+`))
+
+// SetRedirectDelay sets the countdown shown before redirecting through
+// links without their own delay (0 for none), and a disclaimer for the page
+func (h *URLHandler) SetRedirectDelay(seconds int, disclaimer string) {
+	h.countdown.seconds = seconds
+	h.countdown.disclaimer = disclaimer
+}
+
+// SetCountdownTemplate replaces the countdown page with the html/template
+// in path
+func (h *URLHandler) SetCountdownTemplate(path string) error {
+	tmpl, err := template.ParseFiles(path)
+	if err != nil {
+		return fmt.Errorf("failed to parse countdown template: %w", err)
+	}
+	// Fail at startup rather than on the first delayed redirect
+	sample := countdownData{ShortCode: "abc123", URL: "https://example.com/", Host: "example.com", Seconds: 5, Disclaimer: "Disclaimer"}
+	if err := tmpl.Execute(&bytes.Buffer{}, sample); err != nil {
+		return fmt.Errorf("failed to execute countdown template: %w", err)
+	}
+	h.countdown.tmpl = tmpl
+	return nil
+}
+
+// redirectDelay returns the seconds to count down before redirecting
+// through mapping; 0 redirects at once
+func (h *URLHandler) redirectDelay(mapping *model.URLMapping) int {
+	if mapping.RedirectDelay > 0 {
+		return mapping.RedirectDelay
+	}
+	return h.countdown.seconds
+}
+
+// renderCountdown writes the countdown page for a link to originalURL
+func (h *URLHandler) renderCountdown(c *gin.Context, shortCode, originalURL string, seconds int) {
+	tmpl := h.countdown.tmpl
+	if tmpl == nil {
+		tmpl = defaultCountdown
+	}
+	data := countdownData{
+		ShortCode:  shortCode,
+		URL:        originalURL,
+		Host:       originalURL,
+		Seconds:    seconds,
+		Disclaimer: h.countdown.disclaimer,
+	}
+	if u, err := url.Parse(originalURL); err == nil && u.Host != "" {
+		data.Host = u.Hostname()
+	}
+
+	var page bytes.Buffer
+	if err := tmpl.Execute(&page, data); err != nil {
+		// The destination still works
+		c.Redirect(http.StatusFound, originalURL)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Robots-Tag", "noindex, nofollow")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderCountdown tests the built-in countdown page
+func TestRenderCountdown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &URLHandler{}
+	h.SetRedirectDelay(0, "Links lead to <external> sites.")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	h.renderCountdown(c, "abc123", `https://example.com/page?q="><script>`, 5)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	body := w.Body.String()
+	assert.Contains(t, body, `<meta http-equiv="refresh" content="5;url=https://example.com/page?q=`)
+	assert.Contains(t, body, "Links lead to &lt;external&gt; sites.")
+	assert.NotContains(t, body, `"><script>`, "the destination is escaped")
+}
+
+// TestRedirectDelay tests per-link delays over the global one
+func TestRedirectDelay(t *testing.T) {
+	h := &URLHandler{}
+	assert.Equal(t, 0, h.redirectDelay(&model.URLMapping{}))
+	assert.Equal(t, 10, h.redirectDelay(&model.URLMapping{RedirectDelay: 10}))
+
+	h.SetRedirectDelay(3, "")
+	assert.Equal(t, 3, h.redirectDelay(&model.URLMapping{}))
+	assert.Equal(t, 10, h.redirectDelay(&model.URLMapping{RedirectDelay: 10}))
+}
+
+// TestSetCountdownTemplate tests loading a custom countdown page
+func TestSetCountdownTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	h := &URLHandler{}
+
+	path := filepath.Join(dir, "countdown.html")
+	require.NoError(t, os.WriteFile(path, []byte(`{{.Host}} in {{.Seconds}}`), 0o644))
+	require.NoError(t, h.SetCountdownTemplate(path))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	h.renderCountdown(c, "abc123", "https://example.com/page", 7)
+	assert.Equal(t, "example.com in 7", w.Body.String())
+
+	bad := filepath.Join(dir, "bad.html")
+	require.NoError(t, os.WriteFile(bad, []byte(`{{.Missing}}`), 0o644))
+	assert.Error(t, h.SetCountdownTemplate(bad))
+}
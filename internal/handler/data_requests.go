@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// DataRequestHandler handles GDPR export and deletion requests
+type DataRequestHandler struct {
+	requests *service.DataRequests
+}
+
+// NewDataRequestHandler creates a new data request handler instance
+func NewDataRequestHandler(requests *service.DataRequests) *DataRequestHandler {
+	return &DataRequestHandler{requests: requests}
+}
+
+// CreateDataRequestRequest represents the request body for a data subject
+// request; ip and/or user_id name the subject
+type CreateDataRequestRequest struct {
+	Kind   string `json:"kind" binding:"required"` // export or delete
+	IP     string `json:"ip,omitempty"`
+	UserID string `json:"user_id,omitempty"`
+}
+
+// CreateDataRequest handles POST /admin/data-requests
+// The request runs in the background; poll GET /admin/data-requests/{id}
+func (h *DataRequestHandler) CreateDataRequest(c *gin.Context) {
+	var req CreateDataRequestRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	request, err := h.requests.Start(req.Kind, model.DataSubject{IP: req.IP, UserID: req.UserID})
+	if errors.Is(err, service.ErrInvalidDataRequest) {
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to start data request: " + err.Error(),
+		})
+		return
+	}
+
+	respond(c, http.StatusAccepted, Response{
+		Code:    http.StatusAccepted,
+		Message: "Data request started",
+		Data:    request,
+	})
+}
+
+// GetDataRequest handles GET /admin/data-requests/{id}
+// The export or deletion counts are included once the request has finished;
+// the export itself is downloaded from GetDataExport
+func (h *DataRequestHandler) GetDataRequest(c *gin.Context) {
+	request, ok := h.requests.Get(c.Param("id"))
+	if !ok {
+		respond(c, http.StatusNotFound, Response{
+			Code:    http.StatusNotFound,
+			Message: "Data request not found",
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: request,
+	})
+}
+
+// GetDataExport handles GET /admin/data-requests/{id}/export
+// Streams the NDJSON file of a finished export
+func (h *DataRequestHandler) GetDataExport(c *gin.Context) {
+	file, err := h.requests.OpenExport(c.Param("id"))
+	if errors.Is(err, service.ErrDataRequestNotFound) {
+		respond(c, http.StatusNotFound, Response{
+			Code:    http.StatusNotFound,
+			Message: "Data request not found",
+		})
+		return
+	}
+	if errors.Is(err, service.ErrExportNotReady) {
+		respond(c, http.StatusConflict, Response{
+			Code:    http.StatusConflict,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to read data export: " + err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to read data export: " + err.Error(),
+		})
+		return
+	}
+	c.DataFromReader(http.StatusOK, info.Size(), "application/x-ndjson", file, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="data-export-%s.ndjson"`, c.Param("id")),
+	})
+}
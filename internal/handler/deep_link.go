@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// DEEP LINKS
+// ============================================================================
+// Links can carry app links for iOS and Android (model.DeepLinks) besides
+// the web destination. Mobile visitors whose platform has one get a small
+// page instead of a redirect: it tries to open the app straight away and
+// falls back to the web destination if the app doesn't take over, with
+// buttons for both in case scripts are off or the attempt is blocked.
+// Everyone else is redirected to the web destination as usual.
+//
+// A plain redirect to a custom scheme fails with an error page when the app
+// isn't installed, which is why the page is needed. Deep links are validated
+// on input (see service.normalizeDeepLinks), so they are marked safe for
+// html/template, which would otherwise blank out unknown schemes.
+// ============================================================================
+
+// deepLinkData is what the deep link page is executed with
+type deepLinkData struct {
+	AppURL template.URL // Deep link for the visitor's platform
+	WebURL string       // Web fallback (the link's destination)
+}
+
+// deepLinkPage tries the app, then the web destination after a moment
+var deepLinkPage = template.Must(template.New("deep_link").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex, nofollow">
+<title>Opening…</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 30rem; margin: 4rem auto; padding: 0 1rem; color: #222; text-align: center; }
+a.button { display: block; margin: 1rem 0; padding: .9rem; border-radius: 6px; text-decoration: none; }
+a.app { background: #1a73e8; color: #fff; }
+a.web { border: 1px solid #ccc; color: #1a73e8; }
+</style>
+</head>
+<body>
+<p>Opening the app…</p>
+<a class="button app" href="{{.AppURL}}">Open in the app</a>
+<a class="button web" href="{{.WebURL}}" rel="noopener noreferrer">Continue to the website</a>
+<script>
+(function () {
+  var web = {{.WebURL}};
+  var timer = setTimeout(function () { window.location.replace(web); }, 1500);
+  // The app opening hides the page; don't send the visitor on behind it
+  document.addEventListener("visibilitychange", function () {
+    if (document.hidden) { clearTimeout(timer); }
+  });
+  window.location.href = {{.AppURL}};
+})();
+</script>
+</body>
+</html>
+`))
+
+// renderDeepLink writes the page opening appURL with webURL as fallback
+func (h *URLHandler) renderDeepLink(c *gin.Context, appURL, webURL string) {
+	var page bytes.Buffer
+	if err := deepLinkPage.Execute(&page, deepLinkData{AppURL: template.URL(appURL), WebURL: webURL}); err != nil {
+		// The web destination still works
+		c.Redirect(http.StatusFound, webURL)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Robots-Tag", "noindex, nofollow")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRenderDeepLink tests the app-or-web page
+func TestRenderDeepLink(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &URLHandler{}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	h.renderDeepLink(c, "myapp://product/42", `https://example.com/product/42?ref="><script>`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	body := w.Body.String()
+	assert.Contains(t, body, `href="myapp://product/42"`, "the app scheme isn't blanked out")
+	assert.Contains(t, body, `window.location.href = "myapp://product/42"`, "the script gets the deep link as a string")
+	assert.NotContains(t, body, `"><script>`, "the destination is escaped")
+}
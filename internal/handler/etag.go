@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// CONDITIONAL GETS
+// ============================================================================
+// Read endpoints that clients poll (link info, lists, history) send an ETag
+// and answer If-None-Match with 304 Not Modified when nothing changed.
+//
+// The ETag is a hash of the response body rather than of an edit timestamp
+// or revision: visit counts change on every redirect without an edit, and a
+// tag derived from the body changes exactly when the response does. For the
+// same reason no Last-Modified is sent.
+// ============================================================================
+
+// writeJSONWithETag writes a 200 JSON response with an ETag, or 304 if the
+// request's If-None-Match already names it
+func writeJSONWithETag(c *gin.Context, resp Response) {
+	body, err := json.Marshal(successBody(c, resp))
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to encode response",
+		})
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	// Clients may keep the response but must check it is still current
+	c.Header("Cache-Control", "private, no-cache")
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// etagMatches reports whether an If-None-Match header names etag
+// Uses weak comparison, as RFC 9110 requires for If-None-Match
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
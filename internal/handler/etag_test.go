@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// etagRequest writes resp for a request with the given If-None-Match
+func etagRequest(ifNoneMatch string, resp Response) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/api/v1/info/abc123", nil)
+	if ifNoneMatch != "" {
+		c.Request.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	writeJSONWithETag(c, resp)
+	return w
+}
+
+// TestWriteJSONWithETag tests 304s for unchanged responses
+func TestWriteJSONWithETag(t *testing.T) {
+	resp := Response{Code: http.StatusOK, Data: map[string]int{"visit_count": 1}}
+
+	w := etagRequest("", resp)
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.JSONEq(t, `{"code":200,"data":{"visit_count":1}}`, w.Body.String())
+
+	// Same body, same tag
+	w = etagRequest(etag, resp)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	w = etagRequest(`"other", W/`+etag, resp)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	// A new visit changes the body and the tag
+	w = etagRequest(etag, Response{Code: http.StatusOK, Data: map[string]int{"visit_count": 2}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+}
+
+// TestEtagMatches tests If-None-Match parsing
+func TestEtagMatches(t *testing.T) {
+	assert.False(t, etagMatches("", `"a"`))
+	assert.True(t, etagMatches(`"a"`, `"a"`))
+	assert.True(t, etagMatches(`W/"a"`, `"a"`))
+	assert.True(t, etagMatches(`"b" , "a"`, `"a"`))
+	assert.True(t, etagMatches("*", `"a"`))
+	assert.False(t, etagMatches(`"b"`, `"a"`))
+}
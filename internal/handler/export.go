@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ============================================================================
+// VISIT LOG EXPORT FORMATS
+// ============================================================================
+// Exports are streamed: each batch read from MySQL is encoded, flushed to the
+// client and then dropped, so a multi-million-row export never sits in
+// memory. Supported formats:
+// - csv:    header row + one row per visit (spreadsheets, pandas)
+// - ndjson: one JSON object per line (jq, BigQuery, log pipelines)
+// ============================================================================
+
+// Export formats accepted by the format query parameter
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+)
+
+// visitLogWriter encodes visit logs in one export format
+type visitLogWriter interface {
+	// ContentType returns the HTTP Content-Type of the export
+	ContentType() string
+	// Begin writes anything that precedes the rows (e.g., a CSV header)
+	Begin() error
+	// Write encodes one visit log
+	Write(log *model.VisitLog) error
+	// Flush pushes buffered output to the underlying writer
+	Flush() error
+}
+
+// newVisitLogWriter returns the writer for a format
+func newVisitLogWriter(format string, w io.Writer) (visitLogWriter, error) {
+	switch format {
+	case "", ExportFormatCSV:
+		return &csvVisitLogWriter{w: csv.NewWriter(w)}, nil
+	case ExportFormatNDJSON:
+		buf := bufio.NewWriter(w)
+		return &ndjsonVisitLogWriter{buf: buf, enc: json.NewEncoder(buf)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q (use csv or ndjson)", format)
+	}
+}
+
+// csvVisitLogWriter writes visit logs as CSV
+type csvVisitLogWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvVisitLogWriter) ContentType() string { return "text/csv; charset=utf-8" }
+
+func (c *csvVisitLogWriter) Begin() error {
+	return c.w.Write([]string{
+		"id", "short_code", "visited_at", "ip", "user_agent",
+		"referrer", "country", "city", "device_type", "browser", "os", "is_bot",
+	})
+}
+
+func (c *csvVisitLogWriter) Write(log *model.VisitLog) error {
+	return c.w.Write([]string{
+		strconv.FormatUint(uint64(log.ID), 10),
+		log.ShortCode,
+		log.VisitedAt.UTC().Format(time.RFC3339),
+		log.IP,
+		log.UserAgent,
+		log.Referrer,
+		log.Country,
+		log.City,
+		log.DeviceType,
+		log.Browser,
+		log.OS,
+		strconv.FormatBool(log.IsBot),
+	})
+}
+
+func (c *csvVisitLogWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// ndjsonVisitLogWriter writes visit logs as newline-delimited JSON
+type ndjsonVisitLogWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func (n *ndjsonVisitLogWriter) ContentType() string { return "application/x-ndjson" }
+
+func (n *ndjsonVisitLogWriter) Begin() error { return nil }
+
+func (n *ndjsonVisitLogWriter) Write(log *model.VisitLog) error {
+	return n.enc.Encode(log)
+}
+
+func (n *ndjsonVisitLogWriter) Flush() error {
+	return n.buf.Flush()
+}
+
+// parseExportTime parses a from/to query value
+// Accepts RFC3339 timestamps or plain dates (YYYY-MM-DD, midnight UTC)
+func parseExportTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q (use RFC3339 or YYYY-MM-DD)", value)
+	}
+	return t, nil
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeVisitLogs encodes logs in the given format and returns the output
+func writeVisitLogs(t *testing.T, format string, logs []model.VisitLog) string {
+	var buf bytes.Buffer
+	writer, err := newVisitLogWriter(format, &buf)
+	assert.NoError(t, err)
+
+	assert.NoError(t, writer.Begin())
+	for i := range logs {
+		assert.NoError(t, writer.Write(&logs[i]))
+	}
+	assert.NoError(t, writer.Flush())
+	return buf.String()
+}
+
+var exportTestLogs = []model.VisitLog{
+	{
+		ID:        1,
+		ShortCode: "abc123",
+		VisitedAt: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		IP:        "10.0.0.1",
+		UserAgent: "Mozilla/5.0 (X11, Linux)",
+		Referrer:  "https://news.example.com/",
+		Country:   "DE",
+		City:      "Berlin",
+	},
+	{
+		ID:        2,
+		ShortCode: "abc123",
+		VisitedAt: time.Date(2024, 1, 2, 11, 30, 0, 0, time.UTC),
+		IP:        "10.0.0.2",
+		UserAgent: "curl/8.0",
+	},
+}
+
+// TestExportCSV tests CSV output, including quoting of fields with commas
+func TestExportCSV(t *testing.T) {
+	out := writeVisitLogs(t, ExportFormatCSV, exportTestLogs)
+	assert.Equal(t,
+		"id,short_code,visited_at,ip,user_agent,referrer,country,city,device_type,browser,os,is_bot\n"+
+			"1,abc123,2024-01-01T10:00:00Z,10.0.0.1,\"Mozilla/5.0 (X11, Linux)\",https://news.example.com/,DE,Berlin,,,,false\n"+
+			"2,abc123,2024-01-02T11:30:00Z,10.0.0.2,curl/8.0,,,,,,,false\n",
+		out)
+}
+
+// TestExportNDJSON tests one JSON object per line
+func TestExportNDJSON(t *testing.T) {
+	out := writeVisitLogs(t, ExportFormatNDJSON, exportTestLogs)
+	assert.Equal(t,
+		`{"id":1,"short_code":"abc123","visited_at":"2024-01-01T10:00:00Z","ip":"10.0.0.1","user_agent":"Mozilla/5.0 (X11, Linux)","referrer":"https://news.example.com/","country":"DE","city":"Berlin","is_bot":false}`+"\n"+
+			`{"id":2,"short_code":"abc123","visited_at":"2024-01-02T11:30:00Z","ip":"10.0.0.2","user_agent":"curl/8.0","is_bot":false}`+"\n",
+		out)
+}
+
+// TestExportUnknownFormat tests that unsupported formats are rejected
+func TestExportUnknownFormat(t *testing.T) {
+	_, err := newVisitLogWriter("xml", &bytes.Buffer{})
+	assert.Error(t, err)
+}
+
+// TestParseExportTime tests the accepted from/to formats
+func TestParseExportTime(t *testing.T) {
+	got, err := parseExportTime("")
+	assert.NoError(t, err)
+	assert.True(t, got.IsZero())
+
+	got, err = parseExportTime("2024-03-01")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), got)
+
+	got, err = parseExportTime("2024-03-01T12:00:00+02:00")
+	assert.NoError(t, err)
+	assert.True(t, got.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)))
+
+	_, err = parseExportTime("yesterday")
+	assert.Error(t, err)
+}
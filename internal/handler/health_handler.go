@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// Liveness and Readiness Probes
+// ============================================================================
+// /healthz (liveness): the process is up and serving HTTP. It never touches
+//   dependencies, so a database outage doesn't make Kubernetes restart
+//   every pod.
+// /readyz (readiness): every dependency answers a ping within the timeout.
+//   A failing probe takes the pod out of the load balancer until it
+//   recovers. Optional dependencies (e.g. MySQL in degraded mode, where
+//   cached redirects keep working) are reported but only mark the pod
+//   "degraded", which stays in the load balancer.
+// ============================================================================
+
+// HealthCheckFunc probes a single dependency
+type HealthCheckFunc func(ctx context.Context) error
+
+// HealthHandler handles liveness and readiness probes
+type HealthHandler struct {
+	timeout  time.Duration
+	names    []string
+	checks   map[string]HealthCheckFunc
+	optional map[string]bool
+}
+
+// DependencyStatus is the readiness result of one dependency
+type DependencyStatus struct {
+	Status    string `json:"status"` // up, down
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+	Optional  bool   `json:"optional,omitempty"` // Down doesn't make the pod unready
+}
+
+// ReadinessResponse represents the response for GET /readyz
+type ReadinessResponse struct {
+	Status       string                      `json:"status"` // ready, degraded, not_ready
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+}
+
+// NewHealthHandler creates a health handler; each readiness check must
+// finish within timeout
+func NewHealthHandler(timeout time.Duration) *HealthHandler {
+	return &HealthHandler{
+		timeout:  timeout,
+		checks:   make(map[string]HealthCheckFunc),
+		optional: make(map[string]bool),
+	}
+}
+
+// AddCheck registers a dependency probed by /readyz
+func (h *HealthHandler) AddCheck(name string, check HealthCheckFunc) {
+	if _, ok := h.checks[name]; !ok {
+		h.names = append(h.names, name)
+	}
+	h.checks[name] = check
+	delete(h.optional, name)
+}
+
+// AddOptionalCheck registers a dependency probed by /readyz whose failure
+// reports "degraded" instead of making the pod unready
+func (h *HealthHandler) AddOptionalCheck(name string, check HealthCheckFunc) {
+	h.AddCheck(name, check)
+	h.optional[name] = true
+}
+
+// Liveness handles GET /healthz (and the legacy GET /health)
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	respond(c, http.StatusOK, Response{
+		Code:    http.StatusOK,
+		Message: "OK",
+	})
+}
+
+// Readiness handles GET /readyz
+// Checks run concurrently; any required failure returns 503
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	result := ReadinessResponse{
+		Status:       "ready",
+		Dependencies: make(map[string]DependencyStatus, len(h.names)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, name := range h.names {
+		wg.Add(1)
+		go func(name string, check HealthCheckFunc) {
+			defer wg.Done()
+			status := h.probe(c.Request.Context(), check)
+			status.Optional = h.optional[name]
+			mu.Lock()
+			result.Dependencies[name] = status
+			mu.Unlock()
+		}(name, h.checks[name])
+	}
+	wg.Wait()
+
+	code := http.StatusOK
+	for _, status := range result.Dependencies {
+		switch {
+		case status.Status == "up":
+		case status.Optional:
+			if code == http.StatusOK {
+				result.Status = "degraded"
+			}
+		default:
+			result.Status = "not_ready"
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	respond(c, code, Response{
+		Code: code,
+		Data: result,
+	})
+}
+
+// probe runs one check with the handler timeout
+func (h *HealthHandler) probe(ctx context.Context, check HealthCheckFunc) DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	status := DependencyStatus{
+		Status:    "up",
+		LatencyMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		status.Status = "down"
+		status.Error = err.Error()
+	}
+	return status
+}
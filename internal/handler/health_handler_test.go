@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// readiness runs the readiness probe and decodes the response
+func readiness(t *testing.T, h *HealthHandler) (int, ReadinessResponse) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/readyz", nil)
+	h.Readiness(c)
+
+	var resp struct {
+		Data ReadinessResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp.Data
+}
+
+// TestReadinessAllUp tests that healthy dependencies report ready
+func TestReadinessAllUp(t *testing.T) {
+	h := NewHealthHandler(time.Second)
+	h.AddCheck("mysql", func(ctx context.Context) error { return nil })
+	h.AddCheck("redis", func(ctx context.Context) error { return nil })
+
+	code, resp := readiness(t, h)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ready", resp.Status)
+	assert.Equal(t, "up", resp.Dependencies["mysql"].Status)
+	assert.Equal(t, "up", resp.Dependencies["redis"].Status)
+}
+
+// TestReadinessDependencyDown tests that a failing or slow dependency returns 503
+func TestReadinessDependencyDown(t *testing.T) {
+	h := NewHealthHandler(20 * time.Millisecond)
+	h.AddCheck("mysql", func(ctx context.Context) error { return errors.New("connection refused") })
+	h.AddCheck("redis", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	code, resp := readiness(t, h)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "not_ready", resp.Status)
+	assert.Equal(t, "connection refused", resp.Dependencies["mysql"].Error)
+	assert.Equal(t, "down", resp.Dependencies["redis"].Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), resp.Dependencies["redis"].Error)
+}
+
+// TestReadinessOptionalDown tests that an optional dependency only degrades readiness
+func TestReadinessOptionalDown(t *testing.T) {
+	h := NewHealthHandler(time.Second)
+	h.AddOptionalCheck("mysql", func(ctx context.Context) error { return errors.New("connection refused") })
+	h.AddCheck("redis", func(ctx context.Context) error { return nil })
+
+	code, resp := readiness(t, h)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "degraded", resp.Status)
+	assert.True(t, resp.Dependencies["mysql"].Optional)
+	assert.Equal(t, "down", resp.Dependencies["mysql"].Status)
+
+	h.AddCheck("redis", func(ctx context.Context) error { return errors.New("timeout") })
+	code, resp = readiness(t, h)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "not_ready", resp.Status)
+}
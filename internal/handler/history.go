@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/middleware"
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateURLRequest represents the request body for editing a link
+// Omitted fields are left unchanged
+type UpdateURLRequest struct {
+	URL         *string    `json:"url,omitempty"`
+	ExpiredAt   *time.Time `json:"expired_at,omitempty"`
+	ClearExpiry bool       `json:"clear_expiry,omitempty"` // Remove the expiration
+	CacheTTL    *int       `json:"cache_ttl,omitempty"`    // Seconds; 0 for the default
+	NoCache     *bool      `json:"no_cache,omitempty"`
+	// AccessRules replace the link's rules; {} removes them
+	AccessRules *model.AccessRules `json:"access_rules,omitempty"`
+	// DeepLinks replace the link's deep links; {} removes them
+	DeepLinks *model.DeepLinks `json:"deep_links,omitempty"`
+	// Metadata replaces the link's metadata; {} removes it
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// VisitDedupMinutes counts one visit per IP per this many minutes; 0 turns it off
+	VisitDedupMinutes *int `json:"visit_dedup_minutes,omitempty"`
+	// NoAnalytics turns visit logging off or back on
+	NoAnalytics *bool `json:"no_analytics,omitempty"`
+	// Visibility is public, unlisted or private (organization links only)
+	Visibility *string `json:"visibility,omitempty"`
+	// RedirectDelay is the countdown in seconds; 0 returns to the global setting
+	RedirectDelay *int `json:"redirect_delay,omitempty"`
+	// RequireSignature turns signed-only redirects on or off
+	RequireSignature *bool `json:"require_signature,omitempty"`
+}
+
+// CloneURLRequest represents the optional request body for cloning a link
+type CloneURLRequest struct {
+	Domain    string     `json:"domain,omitempty"` // Defaults to the source link's domain
+	ExpiredAt *time.Time `json:"expired_at,omitempty"`
+}
+
+// requestActor identifies the caller for link history
+// Authenticated user, then API key fingerprint, then client IP
+func requestActor(c *gin.Context) string {
+	if userID := c.GetString(middleware.UserIDContextKey); userID != "" {
+		return "user:" + userID
+	}
+	if apiKey := c.GetHeader(middleware.APIKeyHeader); apiKey != "" {
+		return "api_key:" + middleware.APIKeyFingerprint(apiKey)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// UpdateURL handles PATCH /api/v1/urls/{short_code}
+// Changes the destination, expiry, cache settings and/or access rules; each
+// change is added to the history
+func (h *URLHandler) UpdateURL(c *gin.Context) {
+	var req UpdateURLRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	ctx := service.WithActor(c.Request.Context(), requestActor(c))
+	mapping, err := h.service.UpdateURL(ctx, c.Param("short_code"), model.URLUpdate{
+		OriginalURL:       req.URL,
+		ExpiredAt:         req.ExpiredAt,
+		ClearExpiry:       req.ClearExpiry,
+		CacheTTL:          req.CacheTTL,
+		NoCache:           req.NoCache,
+		AccessRules:       req.AccessRules,
+		DeepLinks:         req.DeepLinks,
+		Metadata:          req.Metadata,
+		VisitDedupMinutes: req.VisitDedupMinutes,
+		NoAnalytics:       req.NoAnalytics,
+		Visibility:        req.Visibility,
+		RedirectDelay:     req.RedirectDelay,
+		RequireSignature:  req.RequireSignature,
+	})
+	if errors.Is(err, service.ErrInvalidURL) || errors.Is(err, service.ErrInvalidCachePolicy) ||
+		errors.Is(err, service.ErrInvalidAccessRules) || errors.Is(err, service.ErrInvalidDeepLinks) ||
+		errors.Is(err, service.ErrInvalidMetadata) || errors.Is(err, service.ErrInvalidVisitDedup) ||
+		errors.Is(err, service.ErrInvalidVisibility) || errors.Is(err, service.ErrInvalidRedirectDelay) ||
+		errors.Is(err, service.ErrSigningDisabled) {
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrShortCodeNotFound) {
+		respond(c, http.StatusNotFound, Response{
+			Code:    http.StatusNotFound,
+			Message: "Short URL not found",
+		})
+		return
+	}
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to update short URL: " + err.Error(),
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: h.infoResponse(c, mapping),
+	})
+}
+
+// CloneURL handles POST /api/v1/urls/{short_code}/clone
+// Creates a new short code for the same destination, copying the tags
+func (h *URLHandler) CloneURL(c *gin.Context) {
+	var req CloneURLRequest
+	if c.Request.ContentLength != 0 {
+		if !bindJSON(c, &req) {
+			return
+		}
+	}
+
+	ctx := service.WithActor(c.Request.Context(), requestActor(c))
+	mapping, err := h.service.CloneURL(ctx, c.Param("short_code"), req.Domain, req.ExpiredAt)
+	if errors.Is(err, service.ErrUnknownDomain) || errors.Is(err, service.ErrRejectedByHook) {
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrShortCodeNotFound) {
+		respond(c, http.StatusNotFound, Response{
+			Code:    http.StatusNotFound,
+			Message: "Short URL not found",
+		})
+		return
+	}
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to clone short URL: " + err.Error(),
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: h.infoResponse(c, mapping),
+	})
+}
+
+// GetURLHistory handles GET /api/v1/urls/{short_code}/history
+// Supports If-None-Match (see etag.go)
+// Returns the link's revisions, newest first
+func (h *URLHandler) GetURLHistory(c *gin.Context) {
+	revisions, err := h.service.GetURLHistory(c.Request.Context(), c.Param("short_code"))
+	if errors.Is(err, service.ErrShortCodeNotFound) {
+		respond(c, http.StatusNotFound, Response{
+			Code:    http.StatusNotFound,
+			Message: "Short URL not found",
+		})
+		return
+	}
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get history: " + err.Error(),
+		})
+		return
+	}
+
+	writeJSONWithETag(c, Response{
+		Code: http.StatusOK,
+		Data: revisions,
+	})
+}
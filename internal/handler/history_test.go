@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Monthlyaway/short-link/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRequestActor tests how callers are identified in link history
+func TestRequestActor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newContext := func() *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("PATCH", "/api/v1/urls/abc123", nil)
+		c.Request.RemoteAddr = "203.0.113.7:5555"
+		return c
+	}
+
+	c := newContext()
+	assert.Equal(t, "ip:203.0.113.7", requestActor(c))
+
+	c = newContext()
+	c.Request.Header.Set(middleware.APIKeyHeader, "secret-key")
+	actor := requestActor(c)
+	assert.Equal(t, "api_key:"+middleware.APIKeyFingerprint("secret-key"), actor)
+	assert.NotContains(t, actor, "secret-key")
+
+	c.Set(middleware.UserIDContextKey, "42")
+	assert.Equal(t, "user:42", requestActor(c))
+}
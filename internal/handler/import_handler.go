@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ImportHandler handles bulk CSV imports
+type ImportHandler struct {
+	service        *service.URLService
+	jobs           *service.ImportJobs
+	maxBytes       int64
+	asyncThreshold int64
+}
+
+// NewImportHandler creates a new import handler instance
+// Files up to maxBytes are accepted; files over asyncThreshold bytes (or of
+// unknown size) are imported in the background
+func NewImportHandler(service *service.URLService, jobs *service.ImportJobs, maxBytes, asyncThreshold int64) *ImportHandler {
+	return &ImportHandler{
+		service:        service,
+		jobs:           jobs,
+		maxBytes:       maxBytes,
+		asyncThreshold: asyncThreshold,
+	}
+}
+
+// ImportJobResponse represents the response for a started import job
+type ImportJobResponse struct {
+	JobID string `json:"job_id"`
+}
+
+// Import handles POST /api/v1/import
+// The CSV is the request body (text/csv) or the "file" field of a
+// multipart form. Query: domain (serving domain), async (force a job)
+// Small files return the report (200); large ones return a job ID (202)
+func (h *ImportHandler) Import(c *gin.Context) {
+	body, size, err := h.importBody(c)
+	if err != nil {
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	defer body.Close()
+
+	if size > h.maxBytes {
+		respond(c, http.StatusRequestEntityTooLarge, Response{
+			Code:    http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("File is larger than %d bytes", h.maxBytes),
+		})
+		return
+	}
+
+	domain := c.Query("domain")
+	if err := h.service.CheckDomain(domain); err != nil {
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	async, _ := strconv.ParseBool(c.Query("async"))
+	if async || size < 0 || size > h.asyncThreshold {
+		h.startJob(c, body, domain)
+		return
+	}
+
+	ctx := service.WithActor(c.Request.Context(), requestActor(c))
+	report, err := h.service.ImportURLs(ctx, body, domain, nil)
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to import: " + err.Error(),
+			Data:    report,
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: report,
+	})
+}
+
+// GetImportJob handles GET /api/v1/import/{job_id}
+// The report is included once the job has finished
+func (h *ImportHandler) GetImportJob(c *gin.Context) {
+	job, ok := h.jobs.Get(c.Param("job_id"))
+	if !ok {
+		respond(c, http.StatusNotFound, Response{
+			Code:    http.StatusNotFound,
+			Message: "Import job not found",
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: job,
+	})
+}
+
+// importBody returns the uploaded CSV and its size (-1 if unknown)
+func (h *ImportHandler) importBody(c *gin.Context) (io.ReadCloser, int64, error) {
+	if c.ContentType() != "multipart/form-data" {
+		return c.Request.Body, c.Request.ContentLength, nil
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxBytes+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		return nil, 0, fmt.Errorf("missing CSV file: %w", err)
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	return file, header.Size, nil
+}
+
+// startJob spools the upload to a temporary file and imports it in the
+// background, so the request returns without waiting for the whole file
+func (h *ImportHandler) startJob(c *gin.Context, body io.Reader, domain string) {
+	spool, err := os.CreateTemp("", "short-link-import-*.csv")
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to store upload: " + err.Error(),
+		})
+		return
+	}
+
+	written, err := io.Copy(spool, io.LimitReader(body, h.maxBytes+1))
+	if err == nil {
+		_, err = spool.Seek(0, io.SeekStart)
+	}
+	if err != nil || written > h.maxBytes {
+		spool.Close()
+		os.Remove(spool.Name())
+		if err != nil {
+			respond(c, http.StatusBadRequest, Response{
+				Code:    http.StatusBadRequest,
+				Message: "Failed to read upload: " + err.Error(),
+			})
+			return
+		}
+		respond(c, http.StatusRequestEntityTooLarge, Response{
+			Code:    http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("File is larger than %d bytes", h.maxBytes),
+		})
+		return
+	}
+
+	// The job outlives the request
+	ctx := service.WithActor(context.Background(), requestActor(c))
+	id := h.jobs.Start(func(progress func(int)) (*model.ImportReport, error) {
+		defer os.Remove(spool.Name())
+		defer spool.Close()
+		return h.service.ImportURLs(ctx, spool, domain, progress)
+	})
+
+	respond(c, http.StatusAccepted, Response{
+		Code:    http.StatusAccepted,
+		Message: "Import started",
+		Data:    ImportJobResponse{JobID: id},
+	})
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// UNSAFE-LINK WARNING
+// ============================================================================
+// Links flagged as possibly unsafe (model.URLMapping.Warning) don't redirect
+// straight away: visitors get a page naming the destination and have to
+// click through to it. The page is served with 200 and is never cached, so
+// clearing the warning takes effect at once.
+//
+// The page is an html/template executed with interstitialData; operators can
+// replace the built-in one (abuse.interstitial_template).
+// ============================================================================
+
+// interstitialData is what the warning template is executed with
+type interstitialData struct {
+	ShortCode string
+	URL       string // Destination, for the click-through link
+	Host      string // Destination host, to show prominently
+}
+
+// defaultInterstitial is the built-in warning page
+var defaultInterstitial = template.Must(template.New("interstitial").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex, nofollow">
+<title>Warning: this link may be unsafe</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 40rem; margin: 4rem auto; padding: 0 1rem; color: #222; }
+h1 { color: #b3261e; font-size: 1.5rem; }
+.destination { word-break: break-all; background: #f4f4f4; padding: .75rem; border-radius: 4px; }
+a.continue { color: #b3261e; }
+</style>
+</head>
+<body>
+<h1>This link may be unsafe</h1>
+<p>The short link <strong>{{.ShortCode}}</strong> has been reported or flagged as possibly
+malicious. It leads to <strong>{{.Host}}</strong>:</p>
+<p class="destination">{{.URL}}</p>
+<p>Only continue if you trust this site. Never enter passwords or payment details on a
+page you reached through a link you weren't expecting.</p>
+<p><a class="continue" href="{{.URL}}" rel="noopener noreferrer nofollow">Continue to {{.Host}}</a></p>
+</body>
+</html>
+`))
+
+// SetInterstitialTemplate replaces the unsafe-link warning page with the
+// html/template in path
+func (h *URLHandler) SetInterstitialTemplate(path string) error {
+	tmpl, err := template.ParseFiles(path)
+	if err != nil {
+		return fmt.Errorf("failed to parse interstitial template: %w", err)
+	}
+	// Fail at startup rather than on the first flagged link
+	if err := tmpl.Execute(&bytes.Buffer{}, interstitialData{ShortCode: "abc123", URL: "https://example.com/", Host: "example.com"}); err != nil {
+		return fmt.Errorf("failed to execute interstitial template: %w", err)
+	}
+	h.interstitial = tmpl
+	return nil
+}
+
+// renderInterstitial writes the warning page for a link to originalURL
+func (h *URLHandler) renderInterstitial(c *gin.Context, shortCode, originalURL string) {
+	tmpl := h.interstitial
+	if tmpl == nil {
+		tmpl = defaultInterstitial
+	}
+	data := interstitialData{ShortCode: shortCode, URL: originalURL, Host: originalURL}
+	if u, err := url.Parse(originalURL); err == nil && u.Host != "" {
+		data.Host = u.Hostname()
+	}
+
+	var page bytes.Buffer
+	if err := tmpl.Execute(&page, data); err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to render warning page",
+		})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Robots-Tag", "noindex, nofollow")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
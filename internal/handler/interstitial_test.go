@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderInterstitial tests the built-in warning page
+func TestRenderInterstitial(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &URLHandler{}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	h.renderInterstitial(c, "abc123", `https://evil.example.com/login?next="><script>`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	body := w.Body.String()
+	assert.Contains(t, body, "evil.example.com")
+	assert.Contains(t, body, "abc123")
+	assert.NotContains(t, body, `"><script>`, "the destination is escaped")
+}
+
+// TestSetInterstitialTemplate tests loading a custom warning page
+func TestSetInterstitialTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	h := &URLHandler{}
+
+	path := filepath.Join(dir, "warning.html")
+	require.NoError(t, os.WriteFile(path, []byte(`<a href="{{.URL}}">{{.Host}}</a>`), 0o644))
+	require.NoError(t, h.SetInterstitialTemplate(path))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	h.renderInterstitial(c, "abc123", "https://example.com/page")
+	assert.Equal(t, `<a href="https://example.com/page">example.com</a>`, w.Body.String())
+
+	// Templates referring to unknown fields fail at startup
+	bad := filepath.Join(dir, "bad.html")
+	require.NoError(t, os.WriteFile(bad, []byte(`{{.Missing}}`), 0o644))
+	assert.Error(t, h.SetInterstitialTemplate(bad))
+	assert.Error(t, h.SetInterstitialTemplate(filepath.Join(dir, "none.html")))
+}
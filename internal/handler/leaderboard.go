@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Monthlyaway/short-link/internal/cache"
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultTopLinksLimit and maxTopLinksLimit bound the leaderboard size
+	defaultTopLinksLimit = 10
+	maxTopLinksLimit     = 100
+)
+
+// TopLinkResponse is one entry of the top-links leaderboard
+type TopLinkResponse struct {
+	ShortCode   string `json:"short_code"`
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+	Clicks      int64  `json:"clicks"`
+}
+
+// TopLinksResponse represents the response for the top-links leaderboard
+type TopLinksResponse struct {
+	Period string            `json:"period"`
+	Items  []TopLinkResponse `json:"items"`
+}
+
+// GetTopLinks handles GET /api/v1/stats/top
+// Query: period (24h|7d|30d, default 24h), limit (default 10, max 100)
+func (h *URLHandler) GetTopLinks(c *gin.Context) {
+	period := c.DefaultQuery("period", cache.Period24h)
+	limit, err := queryInt(c, "limit")
+	if err != nil || limit < 0 || limit > maxTopLinksLimit {
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: limit must be between 1 and 100",
+		})
+		return
+	}
+	if limit == 0 {
+		limit = defaultTopLinksLimit
+	}
+
+	top, err := h.service.TopLinks(c.Request.Context(), period, limit)
+	if errors.Is(err, service.ErrInvalidPeriod) {
+		invalidParam(c, "period", err)
+		return
+	}
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get top links: " + err.Error(),
+		})
+		return
+	}
+
+	items := make([]TopLinkResponse, 0, len(top))
+	for _, link := range top {
+		items = append(items, TopLinkResponse{
+			ShortCode:   link.Mapping.ShortCode,
+			ShortURL:    h.service.ShortURL(link.Mapping, h.requestOrigin(c)),
+			OriginalURL: link.Mapping.OriginalURL,
+			Clicks:      link.Clicks,
+		})
+	}
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: TopLinksResponse{Period: period, Items: items},
+	})
+}
@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// URL MAPPING EXPORT
+// ============================================================================
+// A full dump of the url_mappings table for backups or migrating away.
+// Like visit log exports it is streamed batch by batch (keyset pagination
+// on id), in the same formats. The CSV columns are a superset of what
+// POST /api/v1/import reads (original_url, short_code, expired_at), so an
+// export can be imported into another instance as-is.
+// ============================================================================
+
+// exportedMapping is the NDJSON representation of a URL mapping
+type exportedMapping struct {
+	ShortCode     string     `json:"short_code"`
+	OriginalURL   string     `json:"original_url"`
+	Domain        string     `json:"domain,omitempty"`
+	Status        int8       `json:"status"`
+	VisitCount    uint64     `json:"visit_count"`
+	BotVisitCount uint64     `json:"bot_visit_count"`
+	Tags          []string   `json:"tags"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	ExpiredAt     *time.Time `json:"expired_at,omitempty"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
+}
+
+// mappingWriter encodes URL mappings in one export format
+type mappingWriter interface {
+	ContentType() string
+	Begin() error
+	Write(mapping *model.URLMapping) error
+	Flush() error
+}
+
+// newMappingWriter returns the writer for a format
+func newMappingWriter(format string, w io.Writer) (mappingWriter, error) {
+	switch format {
+	case "", ExportFormatCSV:
+		return &csvMappingWriter{w: csv.NewWriter(w)}, nil
+	case ExportFormatNDJSON:
+		buf := bufio.NewWriter(w)
+		return &ndjsonMappingWriter{buf: buf, enc: json.NewEncoder(buf)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q (use csv or ndjson)", format)
+	}
+}
+
+// csvMappingWriter writes URL mappings as CSV; tags are joined with "|"
+type csvMappingWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvMappingWriter) ContentType() string { return "text/csv; charset=utf-8" }
+
+func (c *csvMappingWriter) Begin() error {
+	return c.w.Write([]string{
+		"short_code", "original_url", "domain", "status", "visit_count",
+		"bot_visit_count", "tags", "created_at", "updated_at", "expired_at",
+		"deleted_at",
+	})
+}
+
+func (c *csvMappingWriter) Write(mapping *model.URLMapping) error {
+	return c.w.Write([]string{
+		mapping.ShortCode,
+		mapping.OriginalURL,
+		mapping.Domain,
+		strconv.Itoa(int(mapping.Status)),
+		strconv.FormatUint(mapping.VisitCount, 10),
+		strconv.FormatUint(mapping.BotVisitCount, 10),
+		strings.Join(mapping.TagNames(), "|"),
+		mapping.CreatedAt.UTC().Format(time.RFC3339),
+		mapping.UpdatedAt.UTC().Format(time.RFC3339),
+		formatOptionalTime(mapping.ExpiredAt),
+		formatOptionalTime(deletedAt(mapping)),
+	})
+}
+
+func (c *csvMappingWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// ndjsonMappingWriter writes URL mappings as newline-delimited JSON
+type ndjsonMappingWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func (n *ndjsonMappingWriter) ContentType() string { return "application/x-ndjson" }
+
+func (n *ndjsonMappingWriter) Begin() error { return nil }
+
+func (n *ndjsonMappingWriter) Write(mapping *model.URLMapping) error {
+	return n.enc.Encode(exportedMapping{
+		ShortCode:     mapping.ShortCode,
+		OriginalURL:   mapping.OriginalURL,
+		Domain:        mapping.Domain,
+		Status:        mapping.Status,
+		VisitCount:    mapping.VisitCount,
+		BotVisitCount: mapping.BotVisitCount,
+		Tags:          mapping.TagNames(),
+		CreatedAt:     mapping.CreatedAt,
+		UpdatedAt:     mapping.UpdatedAt,
+		ExpiredAt:     mapping.ExpiredAt,
+		DeletedAt:     deletedAt(mapping),
+	})
+}
+
+func (n *ndjsonMappingWriter) Flush() error {
+	return n.buf.Flush()
+}
+
+// deletedAt returns when a mapping was soft-deleted, or nil
+func deletedAt(mapping *model.URLMapping) *time.Time {
+	if !mapping.DeletedAt.Valid {
+		return nil
+	}
+	return &mapping.DeletedAt.Time
+}
+
+// formatOptionalTime formats t as RFC3339 in UTC, or "" for nil
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// ExportURLMappings handles GET /admin/links/export
+// Query: format (csv|ndjson), domain (one serving domain),
+// include_deleted (also export soft-deleted links), updated_since (RFC3339
+// or YYYY-MM-DD; only links changed since then, deleted ones included)
+func (h *URLHandler) ExportURLMappings(c *gin.Context) {
+	format := c.DefaultQuery("format", ExportFormatCSV)
+	writer, err := newMappingWriter(format, c.Writer)
+	if err != nil {
+		invalidParam(c, "format", err)
+		return
+	}
+	includeDeleted, _ := strconv.ParseBool(c.Query("include_deleted"))
+	updatedSince, err := parseExportTime(c.Query("updated_since"))
+	if err != nil {
+		invalidParam(c, "updated_since", err)
+		return
+	}
+
+	c.Header("Content-Type", writer.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="links.%s"`, format))
+	c.Status(http.StatusOK)
+
+	rc := http.NewResponseController(c.Writer)
+	_ = rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+
+	if err := writer.Begin(); err != nil {
+		fmt.Printf("Failed to write export: %v\n", err)
+		return
+	}
+
+	err = h.service.ExportURLMappings(c.Request.Context(), c.Query("domain"), includeDeleted, updatedSince, func(batch []model.URLMapping) error {
+		for i := range batch {
+			if err := writer.Write(&batch[i]); err != nil {
+				return err
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		fmt.Printf("Failed to export URL mappings: %v\n", err)
+	}
+}
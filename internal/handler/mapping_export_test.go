@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// writeMappings encodes mappings in the given format and returns the output
+func writeMappings(t *testing.T, format string, mappings []model.URLMapping) string {
+	var buf bytes.Buffer
+	writer, err := newMappingWriter(format, &buf)
+	assert.NoError(t, err)
+
+	assert.NoError(t, writer.Begin())
+	for i := range mappings {
+		assert.NoError(t, writer.Write(&mappings[i]))
+	}
+	assert.NoError(t, writer.Flush())
+	return buf.String()
+}
+
+var exportTestMappings = []model.URLMapping{
+	{
+		ShortCode:   "abc123",
+		OriginalURL: "https://example.com/a?x=1,2",
+		Domain:      "s.example.com",
+		Status:      1,
+		VisitCount:  10,
+		CreatedAt:   time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		UpdatedAt:   time.Date(2024, 1, 5, 8, 30, 0, 0, time.UTC),
+		Tags:        []model.Tag{{Name: "email"}, {Name: "q1"}},
+	},
+	{
+		ShortCode:     "old1",
+		OriginalURL:   "https://example.com/b",
+		Status:        0,
+		BotVisitCount: 3,
+		CreatedAt:     time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		UpdatedAt:     time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		DeletedAt:     gorm.DeletedAt{Time: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+	},
+}
+
+// TestExportMappingsCSV tests the CSV columns of a link export
+func TestExportMappingsCSV(t *testing.T) {
+	out := writeMappings(t, ExportFormatCSV, exportTestMappings)
+	assert.Equal(t,
+		"short_code,original_url,domain,status,visit_count,bot_visit_count,tags,created_at,updated_at,expired_at,deleted_at\n"+
+			"abc123,\"https://example.com/a?x=1,2\",s.example.com,1,10,0,email|q1,2024-01-01T10:00:00Z,2024-01-05T08:30:00Z,,\n"+
+			"old1,https://example.com/b,,0,0,3,,2024-01-02T00:00:00Z,2024-02-01T00:00:00Z,,2024-02-01T00:00:00Z\n",
+		out)
+}
+
+// TestExportMappingsNDJSON tests one JSON object per link
+func TestExportMappingsNDJSON(t *testing.T) {
+	out := writeMappings(t, ExportFormatNDJSON, exportTestMappings)
+	assert.Equal(t,
+		`{"short_code":"abc123","original_url":"https://example.com/a?x=1,2","domain":"s.example.com","status":1,"visit_count":10,"bot_visit_count":0,"tags":["email","q1"],"created_at":"2024-01-01T10:00:00Z","updated_at":"2024-01-05T08:30:00Z"}`+"\n"+
+			`{"short_code":"old1","original_url":"https://example.com/b","status":0,"visit_count":0,"bot_visit_count":3,"tags":[],"created_at":"2024-01-02T00:00:00Z","updated_at":"2024-02-01T00:00:00Z","deleted_at":"2024-02-01T00:00:00Z"}`+"\n",
+		out)
+}
+
+// TestExportMappingsImportable tests that an export CSV reads back as import rows
+func TestExportMappingsImportable(t *testing.T) {
+	out := writeMappings(t, ExportFormatCSV, exportTestMappings[:1])
+
+	var rows []model.ImportRow
+	err := service.ReadImportCSV(strings.NewReader(out), func(row model.ImportRow, rowErr error) error {
+		assert.NoError(t, rowErr)
+		rows = append(rows, row)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.ImportRow{{Line: 2, OriginalURL: "https://example.com/a?x=1,2", Alias: "abc123"}}, rows)
+}
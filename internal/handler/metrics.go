@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/Monthlyaway/short-link/internal/cache"
+	"github.com/Monthlyaway/short-link/internal/middleware"
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsHandler serves /metrics in the Prometheus text format
+type MetricsHandler struct {
+	service     *service.URLService
+	concurrency *middleware.ConcurrencyLimiter
+}
+
+// NewMetricsHandler creates a metrics handler
+func NewMetricsHandler(service *service.URLService) *MetricsHandler {
+	return &MetricsHandler{service: service}
+}
+
+// SetConcurrencyLimiter adds the limiter's in-flight requests to the metrics
+func (h *MetricsHandler) SetConcurrencyLimiter(limiter *middleware.ConcurrencyLimiter) {
+	h.concurrency = limiter
+}
+
+// Metrics handles GET /metrics
+func (h *MetricsHandler) Metrics(c *gin.Context) {
+	var out bytes.Buffer
+
+	bloom := h.service.BloomFilterStats()
+	writeMetric(&out, "short_link_bloom_filter_capacity", "gauge",
+		"Codes the bloom filter is sized for", float64(bloom.Capacity))
+	writeMetric(&out, "short_link_bloom_filter_bits", "gauge",
+		"Size of the bloom filter's bit array", float64(bloom.Bits))
+	writeMetric(&out, "short_link_bloom_filter_fill_ratio", "gauge",
+		"Share of the bloom filter's bits that are set", bloom.FillRatio)
+	writeMetric(&out, "short_link_bloom_filter_estimated_entries", "gauge",
+		"Distinct codes in the bloom filter, estimated from the fill ratio", float64(bloom.EstimatedEntries))
+	writeMetric(&out, "short_link_bloom_filter_false_positive_rate", "gauge",
+		"Estimated current false positive rate of the bloom filter", bloom.FalsePositiveRate)
+	writeMetric(&out, "short_link_bloom_filter_target_false_positive_rate", "gauge",
+		"Configured false positive rate at capacity", bloom.TargetRate)
+	writeMetric(&out, "short_link_bloom_filter_resizes_total", "counter",
+		"Rebuilds that grew the bloom filter's capacity", float64(bloom.Resizes))
+
+	lookups := h.service.CacheStats()
+	writeMetric(&out, "short_link_cache_local_hits_total", "counter",
+		"Short code lookups served by the in-process cache", float64(lookups.LocalHits))
+	writeMetric(&out, "short_link_cache_redis_hits_total", "counter",
+		"Short code lookups served by the shared cache (Redis or Memcached)", float64(lookups.RedisHits))
+	writeMetric(&out, "short_link_cache_disk_hits_total", "counter",
+		"Short code lookups served by the disk tier while the shared cache failed", float64(lookups.DiskHits))
+	writeMetric(&out, "short_link_cache_misses_total", "counter",
+		"Short code lookups that missed both cache tiers", float64(lookups.Misses))
+
+	if h.concurrency != nil {
+		concurrency := h.concurrency.Stats()
+		writeMetric(&out, "short_link_http_requests_in_flight", "gauge",
+			"Requests being handled by this instance", float64(concurrency.InFlight))
+		writeMetric(&out, "short_link_http_requests_queued", "gauge",
+			"Requests waiting for a concurrency slot", float64(concurrency.Queued))
+		writeMetric(&out, "short_link_http_max_requests_in_flight", "gauge",
+			"Requests this instance handles at a time (concurrency.max_in_flight)", float64(concurrency.Limit))
+		writeMetric(&out, "short_link_http_requests_shed_total", "counter",
+			"Requests rejected with 503 because no concurrency slot freed up", float64(concurrency.Rejected))
+	}
+
+	writeRedisMetrics(&out, h.service.RedisStats())
+
+	// Skipped while Redis is unreachable rather than failing the scrape
+	if queued, err := h.service.QueuedCreations(c.Request.Context()); err == nil {
+		writeMetric(&out, "short_link_write_behind_queue_length", "gauge",
+			"Links created in write-behind mode waiting for their MySQL insert", float64(queued))
+	}
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", out.Bytes())
+}
+
+// writeRedisMetrics appends the Redis client's command, latency and pool
+// metrics
+func writeRedisMetrics(out *bytes.Buffer, stats cache.RedisStats) {
+	writeHeader(out, "short_link_redis_commands_total", "counter", "Redis commands sent, by command")
+	for _, cmd := range stats.Commands {
+		fmt.Fprintf(out, "short_link_redis_commands_total{command=%q} %d\n", cmd.Name, cmd.Calls)
+	}
+	writeHeader(out, "short_link_redis_command_errors_total", "counter",
+		"Redis commands that failed, by command (missing keys aren't failures)")
+	for _, cmd := range stats.Commands {
+		fmt.Fprintf(out, "short_link_redis_command_errors_total{command=%q} %d\n", cmd.Name, cmd.Errors)
+	}
+
+	const latency = "short_link_redis_command_duration_seconds"
+	writeHeader(out, latency, "histogram", "Redis command round trips; a pipeline counts once")
+	for i, bound := range cache.LatencyBuckets {
+		fmt.Fprintf(out, "%s_bucket{le=\"%g\"} %d\n", latency, bound.Seconds(), stats.Latency.Counts[i])
+	}
+	fmt.Fprintf(out, "%s_bucket{le=\"+Inf\"} %d\n", latency, stats.Latency.Count)
+	fmt.Fprintf(out, "%s_sum %g\n%s_count %d\n", latency, stats.Latency.Sum.Seconds(), latency, stats.Latency.Count)
+
+	pool := stats.Pool
+	writeMetric(out, "short_link_redis_pool_hits_total", "counter",
+		"Redis commands that found an idle connection in the pool", float64(pool.Hits))
+	writeMetric(out, "short_link_redis_pool_misses_total", "counter",
+		"Redis commands that had to open a connection", float64(pool.Misses))
+	writeMetric(out, "short_link_redis_pool_timeouts_total", "counter",
+		"Redis commands that gave up waiting for a connection from a full pool", float64(pool.Timeouts))
+	writeMetric(out, "short_link_redis_pool_connections", "gauge",
+		"Open Redis connections", float64(pool.TotalConns))
+	writeMetric(out, "short_link_redis_pool_idle_connections", "gauge",
+		"Idle Redis connections", float64(pool.IdleConns))
+	writeMetric(out, "short_link_redis_pool_stale_connections_total", "counter",
+		"Redis connections closed as stale", float64(pool.StaleConns))
+}
+
+// writeMetric appends one unlabeled sample with its HELP and TYPE lines
+func writeMetric(out *bytes.Buffer, name, kind, help string, value float64) {
+	writeHeader(out, name, kind, help)
+	fmt.Fprintf(out, "%s %g\n", name, value)
+}
+
+// writeHeader appends the HELP and TYPE lines of a metric
+func writeHeader(out *bytes.Buffer, name, kind, help string) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/cache"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWriteMetric tests the Prometheus text format of a sample
+func TestWriteMetric(t *testing.T) {
+	var out bytes.Buffer
+	writeMetric(&out, "short_link_bloom_filter_fill_ratio", "gauge", "Share of bits set", 0.25)
+	assert.Equal(t, "# HELP short_link_bloom_filter_fill_ratio Share of bits set\n"+
+		"# TYPE short_link_bloom_filter_fill_ratio gauge\n"+
+		"short_link_bloom_filter_fill_ratio 0.25\n", out.String())
+}
+
+// TestWriteRedisMetrics tests the labeled and histogram samples
+func TestWriteRedisMetrics(t *testing.T) {
+	var out bytes.Buffer
+	counts := make([]uint64, len(cache.LatencyBuckets))
+	for i := range counts {
+		counts[i] = 3
+	}
+	counts[0] = 1
+	writeRedisMetrics(&out, cache.RedisStats{
+		Commands: []cache.CommandStats{{Name: "get", Calls: 5, Errors: 1}},
+		Latency:  cache.LatencyHistogram{Counts: counts, Count: 4, Sum: 1500 * time.Millisecond},
+		Pool:     redis.PoolStats{Hits: 7, Timeouts: 2, IdleConns: 3},
+	})
+
+	metrics := out.String()
+	assert.Contains(t, metrics, "short_link_redis_commands_total{command=\"get\"} 5\n")
+	assert.Contains(t, metrics, "short_link_redis_command_errors_total{command=\"get\"} 1\n")
+	assert.Contains(t, metrics, "# TYPE short_link_redis_command_duration_seconds histogram\n")
+	assert.Contains(t, metrics, "short_link_redis_command_duration_seconds_bucket{le=\"0.0005\"} 1\n")
+	assert.Contains(t, metrics, "short_link_redis_command_duration_seconds_bucket{le=\"1\"} 3\n")
+	assert.Contains(t, metrics, "short_link_redis_command_duration_seconds_bucket{le=\"+Inf\"} 4\n")
+	assert.Contains(t, metrics, "short_link_redis_command_duration_seconds_sum 1.5\n")
+	assert.Contains(t, metrics, "short_link_redis_command_duration_seconds_count 4\n")
+	assert.Contains(t, metrics, "short_link_redis_pool_timeouts_total 2\n")
+	assert.Contains(t, metrics, "short_link_redis_pool_idle_connections 3\n")
+}
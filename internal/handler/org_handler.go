@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Monthlyaway/short-link/internal/middleware"
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// OrgHandler handles HTTP requests for organizations and enforces member
+// roles on links owned by them
+type OrgHandler struct {
+	service *service.URLService
+}
+
+// NewOrgHandler creates a new organization handler instance
+func NewOrgHandler(service *service.URLService) *OrgHandler {
+	return &OrgHandler{service: service}
+}
+
+// CreateOrgRequest represents the request body for creating an organization
+type CreateOrgRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// SetMemberRequest represents the request body for adding a member or
+// changing their role
+type SetMemberRequest struct {
+	Role string `json:"role" binding:"required"` // owner, editor or viewer
+}
+
+// CreateOrg handles POST /api/v1/orgs
+// The caller becomes the organization's owner
+func (h *OrgHandler) CreateOrg(c *gin.Context) {
+	userID, ok := requireUser(c)
+	if !ok {
+		return
+	}
+	var req CreateOrgRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	org, err := h.service.CreateOrg(c.Request.Context(), req.Name, userID)
+	if err != nil {
+		writeOrgError(c, err, "Failed to create organization")
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: org,
+	})
+}
+
+// ListOrgs handles GET /api/v1/orgs
+// Returns the organizations the caller is a member of
+func (h *OrgHandler) ListOrgs(c *gin.Context) {
+	userID, ok := requireUser(c)
+	if !ok {
+		return
+	}
+
+	orgs, err := h.service.ListUserOrgs(c.Request.Context(), userID)
+	if err != nil {
+		writeOrgError(c, err, "Failed to list organizations")
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: orgs,
+	})
+}
+
+// GetOrg handles GET /api/v1/orgs/{id}
+func (h *OrgHandler) GetOrg(c *gin.Context) {
+	id, ok := orgID(c)
+	if !ok {
+		return
+	}
+
+	org, err := h.service.GetOrg(c.Request.Context(), id, c.GetString(middleware.UserIDContextKey))
+	if err != nil {
+		writeOrgError(c, err, "Failed to get organization")
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: org,
+	})
+}
+
+// SetMember handles PUT /api/v1/orgs/{id}/members/{user_id}
+// Adds the user or changes their role; owners only
+func (h *OrgHandler) SetMember(c *gin.Context) {
+	id, ok := orgID(c)
+	if !ok {
+		return
+	}
+	var req SetMemberRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	role, err := service.ParseOrgRole(req.Role)
+	if err != nil {
+		writeOrgError(c, err, "")
+		return
+	}
+
+	member, err := h.service.SetOrgMember(c.Request.Context(), id,
+		c.GetString(middleware.UserIDContextKey), c.Param("user_id"), role)
+	if err != nil {
+		writeOrgError(c, err, "Failed to set member")
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: member,
+	})
+}
+
+// RemoveMember handles DELETE /api/v1/orgs/{id}/members/{user_id}
+// Owners may remove anyone; members may remove themselves
+func (h *OrgHandler) RemoveMember(c *gin.Context) {
+	id, ok := orgID(c)
+	if !ok {
+		return
+	}
+
+	err := h.service.RemoveOrgMember(c.Request.Context(), id,
+		c.GetString(middleware.UserIDContextKey), c.Param("user_id"))
+	if err != nil {
+		writeOrgError(c, err, "Failed to remove member")
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code:    http.StatusOK,
+		Message: "Member removed",
+	})
+}
+
+// RequireLinkRole returns middleware that lets a request through only if the
+// caller has at least role in the organization owning the :short_code link
+// Links without an organization are not restricted
+func (h *OrgHandler) RequireLinkRole(role model.OrgRole) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := h.service.AuthorizeLink(c.Request.Context(), c.Param("short_code"),
+			c.GetString(middleware.UserIDContextKey), role)
+		if err != nil {
+			writeOrgError(c, err, "Failed to check permissions")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireLinkEditor returns middleware that lets a request through only if
+// the caller may change the :short_code link: an editor in its organization
+// or, for links without one, its creator
+// Anonymous callers get a 401
+func (h *OrgHandler) RequireLinkEditor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := requireUser(c)
+		if !ok {
+			c.Abort()
+			return
+		}
+		if err := h.service.AuthorizeLinkEdit(c.Request.Context(), c.Param("short_code"), userID); err != nil {
+			writeOrgError(c, err, "Failed to check permissions")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireUser returns the authenticated user ID, writing a 401 if there is none
+func requireUser(c *gin.Context) (string, bool) {
+	userID := c.GetString(middleware.UserIDContextKey)
+	if userID == "" {
+		respond(c, http.StatusUnauthorized, Response{
+			Code:    http.StatusUnauthorized,
+			Message: "Authentication required",
+		})
+		return "", false
+	}
+	return userID, true
+}
+
+// orgID parses the :id path parameter, writing a 400 if it's invalid
+func orgID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid organization ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// writeOrgError maps organization service errors to HTTP responses
+func writeOrgError(c *gin.Context, err error, message string) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrForbidden):
+		code = http.StatusForbidden
+	case errors.Is(err, service.ErrOrgNotFound), errors.Is(err, service.ErrMemberNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, service.ErrOrgExists), errors.Is(err, service.ErrLastOwner):
+		code = http.StatusConflict
+	case errors.Is(err, service.ErrInvalidOrg):
+		code = http.StatusBadRequest
+	}
+	if code != http.StatusInternalServerError {
+		message = err.Error()
+	} else {
+		message += ": " + err.Error()
+	}
+	respond(c, code, Response{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Monthlyaway/short-link/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRequireUser tests that anonymous callers get a 401
+func TestRequireUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	_, ok := requireUser(c)
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Set(middleware.UserIDContextKey, "alice")
+	userID, ok := requireUser(c)
+	assert.True(t, ok)
+	assert.Equal(t, "alice", userID)
+}
+
+// TestRequireLinkEditorNeedsUser tests that anonymous edits get a 401 before
+// the link is looked up
+func TestRequireLinkEditorNeedsUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.PATCH("/urls/:short_code", NewOrgHandler(nil).RequireLinkEditor(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/urls/abc123", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// REQUEST ORIGIN
+// ============================================================================
+// When no base URL or domains are configured, short URLs are built from the
+// origin the client used. Behind a reverse proxy the Host header and TLS
+// state describe the proxy hop, so the origin comes from:
+// - X-Forwarded-Proto / X-Forwarded-Host, only from trusted proxies
+// - otherwise the request's own TLS state and Host header
+//
+// Forwarded headers from untrusted peers are ignored; anyone could send them.
+// ============================================================================
+
+// SetForwardedHeaders enables X-Forwarded-Proto/Host for requests whose peer
+// address is in trustedProxies (IPs or CIDRs, same as server.trusted_proxies)
+func (h *URLHandler) SetForwardedHeaders(trustedProxies []string) error {
+	nets := make([]*net.IPNet, 0, len(trustedProxies))
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	h.trustedProxies = nets
+	return nil
+}
+
+// requestOrigin returns scheme://host as seen by the client
+func (h *URLHandler) requestOrigin(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	host := c.Request.Host
+
+	if h.fromTrustedProxy(c) {
+		if proto := firstHeaderValue(c.GetHeader("X-Forwarded-Proto")); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		if forwardedHost := firstHeaderValue(c.GetHeader("X-Forwarded-Host")); forwardedHost != "" {
+			host = forwardedHost
+		}
+	}
+
+	if host == "" {
+		return ""
+	}
+	return scheme + "://" + host
+}
+
+// fromTrustedProxy reports whether the TCP peer is a trusted proxy
+func (h *URLHandler) fromTrustedProxy(c *gin.Context) bool {
+	if len(h.trustedProxies) == 0 {
+		return false
+	}
+	ip := net.ParseIP(c.RemoteIP())
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range h.trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstHeaderValue returns the first entry of a comma-separated header
+// Proxies append to X-Forwarded-*; the first entry is the client-facing one
+func firstHeaderValue(value string) string {
+	if i := strings.IndexByte(value, ','); i >= 0 {
+		value = value[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(value))
+}
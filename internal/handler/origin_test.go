@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// originFor returns the request origin computed for a request from remoteAddr
+func originFor(h *URLHandler, remoteAddr string, headers map[string]string) string {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "http://internal:8080/api/v1/shorten", nil)
+	c.Request.RemoteAddr = remoteAddr
+	for k, v := range headers {
+		c.Request.Header.Set(k, v)
+	}
+	return h.requestOrigin(c)
+}
+
+// TestRequestOrigin tests that forwarded headers are only trusted from proxies
+func TestRequestOrigin(t *testing.T) {
+	h := &URLHandler{}
+	assert.NoError(t, h.SetForwardedHeaders([]string{"10.0.0.0/8", "127.0.0.1"}))
+
+	forwarded := map[string]string{
+		"X-Forwarded-Proto": "https",
+		"X-Forwarded-Host":  "S.Example.com, internal:8080",
+	}
+
+	// Trusted proxy: use the forwarded origin
+	assert.Equal(t, "https://s.example.com", originFor(h, "10.1.2.3:5555", forwarded))
+	assert.Equal(t, "https://s.example.com", originFor(h, "127.0.0.1:5555", forwarded))
+
+	// Untrusted peer: ignore the headers
+	assert.Equal(t, "http://internal:8080", originFor(h, "203.0.113.7:5555", forwarded))
+
+	// Forwarded headers disabled
+	assert.Equal(t, "http://internal:8080", originFor(&URLHandler{}, "10.1.2.3:5555", forwarded))
+}
+
+// TestSetForwardedHeadersInvalid tests that bad proxy entries are rejected
+func TestSetForwardedHeadersInvalid(t *testing.T) {
+	assert.Error(t, (&URLHandler{}).SetForwardedHeaders([]string{"not-an-ip"}))
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Monthlyaway/short-link/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// Handlers build every JSON response as a Response and write it with
+// respond, which shapes it for the request's API version (see
+// middleware/version.go):
+//   v1  the Response as is
+//   v2  on success the data alone, or {"message": ...} if there is none;
+//       on errors an RFC 7807 problem with the message as detail and, for
+//       invalid requests, the field errors
+
+// respond writes resp with status in the request's API version
+func respond(c *gin.Context, status int, resp Response) {
+	if middleware.APIVersion(c) < middleware.APIv2 {
+		c.JSON(status, resp)
+		return
+	}
+	if status >= http.StatusBadRequest {
+		middleware.WriteProblem(c, problemFor(c, status, resp))
+		return
+	}
+	c.JSON(status, successBody(c, resp))
+}
+
+// successBody returns the body of a successful response in the request's
+// API version
+func successBody(c *gin.Context, resp Response) interface{} {
+	if middleware.APIVersion(c) < middleware.APIv2 {
+		return resp
+	}
+	if resp.Data != nil {
+		return resp.Data
+	}
+	return gin.H{"message": resp.Message}
+}
+
+// problemFor converts an error Response into a problem
+func problemFor(c *gin.Context, status int, resp Response) middleware.Problem {
+	kind := ""
+	if len(resp.Errors) > 0 {
+		kind = "invalid_request"
+	}
+	problem := middleware.NewProblem(c, status, kind, resp.Message)
+	if len(resp.Errors) > 0 {
+		problem.Errors = resp.Errors
+	}
+	return problem
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Monthlyaway/short-link/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// respondTo writes resp for a request to path and returns the recorder
+func respondTo(path string, status int, resp Response) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, path, nil)
+	respond(c, status, resp)
+	return w
+}
+
+// TestRespondVersions tests the response envelope of each API version
+func TestRespondVersions(t *testing.T) {
+	data := map[string]interface{}{"short_code": "abc123"}
+	ok := Response{Code: http.StatusOK, Message: "Success", Data: data}
+
+	// v1 keeps the envelope
+	w := respondTo("/api/v1/info/abc123", http.StatusOK, ok)
+	assert.JSONEq(t, `{"code": 200, "message": "Success", "data": {"short_code": "abc123"}}`, w.Body.String())
+
+	// v2 returns the data alone, or the message without data
+	w = respondTo("/api/v2/info/abc123", http.StatusOK, ok)
+	assert.JSONEq(t, `{"short_code": "abc123"}`, w.Body.String())
+	w = respondTo("/api/v2/urls/abc123/tags", http.StatusOK, Response{Code: http.StatusOK, Message: "Tags updated"})
+	assert.JSONEq(t, `{"message": "Tags updated"}`, w.Body.String())
+
+	// v2 errors are problems
+	w = respondTo("/api/v2/info/missing", http.StatusNotFound, Response{Code: http.StatusNotFound, Message: "Short URL not found"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), middleware.ProblemContentType)
+	assert.JSONEq(t, `{"type": "about:blank", "title": "Not Found", "status": 404,
+		"detail": "Short URL not found", "instance": "/api/v2/info/missing"}`, w.Body.String())
+}
+
+// TestInvalidRequestProblem tests that v2 invalid requests list field errors
+func TestInvalidRequestProblem(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v2/shorten", nil)
+	invalidRequest(c, FieldError{Field: "url", Rule: "required", Message: "url is required"})
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var problem struct {
+		middleware.Problem
+		Errors []FieldError `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, "urn:short-link:error:invalid_request", problem.Type)
+	assert.Equal(t, "Invalid request: url is required", problem.Detail)
+	assert.Equal(t, []FieldError{{Field: "url", Rule: "required", Message: "url is required"}}, problem.Errors)
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Monthlyaway/short-link/internal/middleware"
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// SHORTEN VIA GET
+// ============================================================================
+// Spreadsheets (IMPORTDATA), simple webhooks and chat bots can often only
+// fetch a URL, so GET /shorten creates a link from query parameters:
+//
+//   GET /api/v1/shorten?url=https%3A%2F%2Fexample.com%2Fa%3Fb%3D1&format=text
+//
+// The endpoint needs an API key (header or api_key parameter) and is parsed
+// strictly: a destination with a query string of its own must be
+// percent-encoded, or its parameters would silently be cut off. Unknown,
+// repeated or malformed parameters are rejected rather than guessed at.
+// ============================================================================
+
+// shortenQueryParams are the query parameters GET /shorten accepts
+var shortenQueryParams = map[string]bool{
+	"url":                       true,
+	"domain":                    true,
+	"format":                    true,
+	middleware.APIKeyQueryParam: true,
+}
+
+// ShortenViaGET handles GET /api/v1/shorten?url=...
+// It answers with the short URL alone (format=text, or Accept: text/plain)
+// or the response of POST /api/v1/shorten
+func (h *URLHandler) ShortenViaGET(c *gin.Context) {
+	query, err := url.ParseQuery(c.Request.URL.RawQuery)
+	if err != nil {
+		h.shortenError(c, http.StatusBadRequest, "Malformed query string: "+err.Error())
+		return
+	}
+	for name, values := range query {
+		if !shortenQueryParams[name] {
+			h.shortenError(c, http.StatusBadRequest, fmt.Sprintf(
+				"Unknown query parameter %q; percent-encode the url parameter so its query string stays part of it", name))
+			return
+		}
+		if len(values) > 1 {
+			h.shortenError(c, http.StatusBadRequest, fmt.Sprintf("Query parameter %q must be given once", name))
+			return
+		}
+	}
+	if format := query.Get("format"); format != "" && format != "text" && format != "json" {
+		h.shortenError(c, http.StatusBadRequest, "format must be text or json")
+		return
+	}
+
+	rawURL := query.Get("url")
+	if rawURL == "" {
+		h.shortenError(c, http.StatusBadRequest, "url is required")
+		return
+	}
+	if strings.Contains(rawURL, " ") {
+		h.shortenError(c, http.StatusBadRequest, "url contains a space; percent-encode it, including + as %2B")
+		return
+	}
+	if c.GetString(middleware.UserIDContextKey) == "" {
+		h.shortenError(c, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+	// Reads pass the read-only check; this one writes
+	if !h.service.DatabaseAvailable() {
+		c.Header("Retry-After", "30")
+		h.shortenError(c, http.StatusServiceUnavailable, "Service is read-only while the database is unavailable")
+		return
+	}
+
+	ctx := service.WithActor(c.Request.Context(), requestActor(c))
+	mapping, err := h.service.CreateShortURL(ctx, rawURL, query.Get("domain"), nil, model.LinkOptions{})
+	if isInvalidLink(err) {
+		h.shortenError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.shortenError(c, http.StatusInternalServerError, "Failed to create short URL: "+err.Error())
+		return
+	}
+
+	resp := h.createResponse(c, mapping)
+	if wantsText(c, query) {
+		c.String(http.StatusOK, resp.ShortURL)
+		return
+	}
+	respond(c, http.StatusOK, Response{Code: http.StatusOK, Data: resp})
+}
+
+// shortenError answers GET /shorten with an error in the requested format
+func (h *URLHandler) shortenError(c *gin.Context, status int, message string) {
+	query, _ := url.ParseQuery(c.Request.URL.RawQuery)
+	if wantsText(c, query) {
+		c.String(status, message)
+		return
+	}
+	respond(c, status, Response{Code: status, Message: message})
+}
+
+// wantsText reports whether GET /shorten should answer in plain text
+func wantsText(c *gin.Context, query url.Values) bool {
+	switch query.Get("format") {
+	case "text":
+		return true
+	case "json":
+		return false
+	}
+	return c.NegotiateFormat(gin.MIMEJSON, gin.MIMEPlain) == gin.MIMEPlain
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestShortenViaGETRejects tests the strict query parsing of GET /shorten;
+// every case is rejected before the service is called
+func TestShortenViaGETRejects(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &URLHandler{}
+	r := gin.New()
+	r.GET("/api/v1/shorten", h.ShortenViaGET)
+
+	for _, tc := range []struct {
+		query   string
+		status  int
+		message string
+	}{
+		{"url=https://example.com/a?b=1&c=2", http.StatusBadRequest, `Unknown query parameter \"c\"`},
+		{"url=https://example.com/a&url=https://example.com/b", http.StatusBadRequest, "must be given once"},
+		{"url=https://example.com/%zz", http.StatusBadRequest, "Malformed query string"},
+		{"url=https://example.com/a;b", http.StatusBadRequest, "Malformed query string"},
+		{"url=https://example.com/a+b", http.StatusBadRequest, "url contains a space"},
+		{"format=xml&url=https://example.com/", http.StatusBadRequest, "format must be text or json"},
+		{"format=text", http.StatusBadRequest, "url is required"},
+		{"url=https%3A%2F%2Fexample.com%2Fa%3Fb%3D1%26c%3D2", http.StatusUnauthorized, "Authentication required"},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/shorten?"+tc.query, nil))
+		assert.Equal(t, tc.status, w.Code, tc.query)
+		assert.Contains(t, w.Body.String(), tc.message, tc.query)
+	}
+
+	// Plain text when asked for, JSON otherwise
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/shorten?format=text", nil))
+	assert.Equal(t, "url is required", w.Body.String())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/shorten", nil)
+	req.Header.Set("Accept", "text/plain")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "url is required", w.Body.String())
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/shorten", nil))
+	assert.JSONEq(t, `{"code":400,"message":"url is required"}`, w.Body.String())
+}
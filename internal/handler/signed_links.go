@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// SignURLRequest represents the request body for minting a signed URL
+type SignURLRequest struct {
+	ExpiresIn int `json:"expires_in" binding:"required"` // Seconds the URL stays valid
+}
+
+// SignURLResponse represents a signed short URL
+type SignURLResponse struct {
+	ShortCode string    `json:"short_code"`
+	SignedURL string    `json:"signed_url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SignURL handles POST /admin/links/{short_code}/sign
+// Mints a short URL carrying a signature valid for expires_in seconds
+func (h *URLHandler) SignURL(c *gin.Context) {
+	var req SignURLRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	shortCode := c.Param("short_code")
+	signed, expiresAt, err := h.service.SignedURL(c.Request.Context(), shortCode, h.requestOrigin(c),
+		time.Duration(req.ExpiresIn)*time.Second)
+	switch {
+	case errors.Is(err, service.ErrInvalidSignTTL):
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	case errors.Is(err, service.ErrSigningDisabled):
+		respond(c, http.StatusConflict, Response{
+			Code:    http.StatusConflict,
+			Message: "Signed links are not enabled (signed_links.secret)",
+		})
+		return
+	case errors.Is(err, service.ErrShortCodeNotFound):
+		respond(c, http.StatusNotFound, Response{
+			Code:    http.StatusNotFound,
+			Message: "Short URL not found",
+		})
+		return
+	case err != nil:
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to sign short URL: " + err.Error(),
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: SignURLResponse{ShortCode: shortCode, SignedURL: signed, ExpiresAt: expiresAt},
+	})
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+)
+
+// siteFileMaxAge is how long browsers and crawlers may cache robots.txt and
+// the favicon, in seconds
+const siteFileMaxAge = 86400
+
+// SiteHandler serves /robots.txt and /favicon.ico, which crawlers and
+// browsers request on their own; without these routes the requests would
+// be looked up as short codes
+type SiteHandler struct {
+	robotsTxt   []byte
+	favicon     []byte
+	faviconType string
+}
+
+// NewSiteHandler creates a site handler serving robotsTxt and the icon file
+// at faviconPath (empty for none)
+func NewSiteHandler(robotsTxt, faviconPath string) (*SiteHandler, error) {
+	h := &SiteHandler{robotsTxt: []byte(robotsTxt)}
+	if faviconPath == "" {
+		return h, nil
+	}
+
+	icon, err := os.ReadFile(faviconPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read favicon: %w", err)
+	}
+	h.favicon = icon
+	h.faviconType = mime.TypeByExtension(filepath.Ext(faviconPath))
+	if h.faviconType == "" {
+		h.faviconType = http.DetectContentType(icon)
+	}
+	return h, nil
+}
+
+// RobotsTxt handles GET /robots.txt
+func (h *SiteHandler) RobotsTxt(c *gin.Context) {
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", siteFileMaxAge))
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", h.robotsTxt)
+}
+
+// Favicon handles GET /favicon.ico
+// Answers 204 when no icon is configured, so browsers stop asking
+func (h *SiteHandler) Favicon(c *gin.Context) {
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", siteFileMaxAge))
+	if h.favicon == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.Data(http.StatusOK, h.faviconType, h.favicon)
+}
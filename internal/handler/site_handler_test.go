@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSiteEngine registers the site routes next to a catch-all short code
+// route, as in main
+func newSiteEngine(h *SiteHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/robots.txt", h.RobotsTxt)
+	engine.GET("/favicon.ico", h.Favicon)
+	engine.GET("/:short_code", func(c *gin.Context) { c.String(http.StatusTeapot, c.Param("short_code")) })
+	return engine
+}
+
+// TestSiteHandler tests that robots.txt and the favicon don't reach the
+// short code route
+func TestSiteHandler(t *testing.T) {
+	h, err := NewSiteHandler("User-agent: *\nDisallow: /\n", "")
+	require.NoError(t, err)
+	engine := newSiteEngine(h)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/robots.txt", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "User-agent: *\nDisallow: /\n", w.Body.String())
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/favicon.ico", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/aB3xY9", nil))
+	assert.Equal(t, http.StatusTeapot, w.Code)
+}
+
+// TestSiteHandlerFavicon tests serving a configured icon
+func TestSiteHandlerFavicon(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "icon.png")
+	icon := []byte("\x89PNG\r\n\x1a\nicon")
+	require.NoError(t, os.WriteFile(path, icon, 0o644))
+
+	h, err := NewSiteHandler("", path)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	newSiteEngine(h).ServeHTTP(w, httptest.NewRequest("GET", "/favicon.ico", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, icon, w.Body.Bytes())
+
+	_, err = NewSiteHandler("", filepath.Join(t.TempDir(), "missing.ico"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateSummaryRequest represents the request body for subscribing to summaries
+type CreateSummaryRequest struct {
+	Frequency string `json:"frequency" binding:"required"` // daily or weekly
+	Channel   string `json:"channel" binding:"required"`   // email or webhook
+	Target    string `json:"target" binding:"required"`    // Email address or webhook URL
+	OrgID     uint   `json:"org_id"`                       // Summarize an organization's links instead of your own
+}
+
+// CreateSummarySubscription handles POST /api/v1/summaries
+func (h *URLHandler) CreateSummarySubscription(c *gin.Context) {
+	userID, ok := requireUser(c)
+	if !ok {
+		return
+	}
+	var req CreateSummaryRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	sub := &model.SummarySubscription{
+		Frequency: req.Frequency,
+		Channel:   req.Channel,
+		Target:    req.Target,
+		OrgID:     req.OrgID,
+	}
+	if err := h.service.CreateSummarySubscription(c.Request.Context(), userID, sub); err != nil {
+		writeSummaryError(c, err, "Failed to create summary subscription")
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: sub,
+	})
+}
+
+// ListSummarySubscriptions handles GET /api/v1/summaries
+func (h *URLHandler) ListSummarySubscriptions(c *gin.Context) {
+	userID, ok := requireUser(c)
+	if !ok {
+		return
+	}
+	subs, err := h.service.ListSummarySubscriptions(c.Request.Context(), userID)
+	if err != nil {
+		writeSummaryError(c, err, "Failed to list summary subscriptions")
+		return
+	}
+	if subs == nil {
+		subs = []model.SummarySubscription{}
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: subs,
+	})
+}
+
+// DeleteSummarySubscription handles DELETE /api/v1/summaries/:id
+func (h *URLHandler) DeleteSummarySubscription(c *gin.Context) {
+	userID, ok := requireUser(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid subscription ID",
+		})
+		return
+	}
+
+	if err := h.service.DeleteSummarySubscription(c.Request.Context(), userID, uint(id)); err != nil {
+		writeSummaryError(c, err, "Failed to delete summary subscription")
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code:    http.StatusOK,
+		Message: "Summary subscription deleted",
+	})
+}
+
+// writeSummaryError maps summary subscription errors to HTTP responses
+// Organization errors (creating a subscription for an organization) are
+// mapped as for the organization endpoints
+func writeSummaryError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidSubscription):
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+	case errors.Is(err, service.ErrSubscriptionNotFound):
+		respond(c, http.StatusNotFound, Response{
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+	default:
+		writeOrgError(c, err, message)
+	}
+}
@@ -1,10 +1,18 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"html/template"
+	"net"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/Monthlyaway/short-link/internal/enrich"
+	"github.com/Monthlyaway/short-link/internal/middleware"
+	"github.com/Monthlyaway/short-link/internal/model"
 	"github.com/Monthlyaway/short-link/internal/service"
 	"github.com/gin-gonic/gin"
 )
@@ -12,112 +20,424 @@ import (
 // URLHandler handles HTTP requests for URL operations
 type URLHandler struct {
 	service *service.URLService
-	baseURL string
+
+	// Proxies whose X-Forwarded-Proto/Host are trusted (see origin.go)
+	trustedProxies []*net.IPNet
+
+	// Unsafe-link warning page; nil uses the built-in one (see interstitial.go)
+	interstitial *template.Template
+	// Countdown before redirecting (see countdown.go)
+	countdown countdownSettings
 }
 
 // NewURLHandler creates a new URL handler instance
-func NewURLHandler(service *service.URLService, baseURL string) *URLHandler {
-	return &URLHandler{
-		service: service,
-		baseURL: baseURL,
-	}
+func NewURLHandler(service *service.URLService) *URLHandler {
+	return &URLHandler{service: service}
 }
 
 // CreateShortURLRequest represents the request body for creating a short URL
 type CreateShortURLRequest struct {
 	URL       string     `json:"url" binding:"required"`
+	Domain    string     `json:"domain,omitempty"` // Serving domain; defaults to the first configured domain
+	OrgID     uint       `json:"org_id,omitempty"` // Owning organization; the caller must be an editor
 	ExpiredAt *time.Time `json:"expired_at,omitempty"`
+	Tags      []string   `json:"tags,omitempty"`      // Added to the link's existing tags
+	CacheTTL  int        `json:"cache_ttl,omitempty"` // Seconds the redirect may be cached; 0 for the default
+	NoCache   bool       `json:"no_cache,omitempty"`  // Never cache; every redirect reads the database
+	// VisitDedupMinutes counts one visit per IP per this many minutes
+	VisitDedupMinutes int `json:"visit_dedup_minutes,omitempty"`
+	// NoAnalytics counts visits without logging visitor details
+	NoAnalytics bool `json:"no_analytics,omitempty"`
+	// Visibility is public (default), unlisted or private (org_id required)
+	Visibility string `json:"visibility,omitempty"`
+	// RedirectDelay shows a countdown for this many seconds before redirecting
+	RedirectDelay int `json:"redirect_delay,omitempty"`
+	// RequireSignature only redirects signed URLs (see signed_links)
+	RequireSignature bool `json:"require_signature,omitempty"`
+	// AccessRules restrict who may follow the link
+	AccessRules *model.AccessRules `json:"access_rules,omitempty"`
+	// DeepLinks open the link in an app on iOS/Android; url is the fallback
+	DeepLinks *model.DeepLinks `json:"deep_links,omitempty"`
+	// Metadata is any JSON object to store with the link
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// NoHTTPSUpgrade keeps an http:// URL as given (see destinations.upgrade_https)
+	NoHTTPSUpgrade bool `json:"no_https_upgrade,omitempty"`
+	// ReuseExisting returns an existing equivalent link instead of creating
+	// one; defaults to true
+	ReuseExisting *bool `json:"reuse_existing,omitempty"`
 }
 
 // CreateShortURLResponse represents the response for creating a short URL
 type CreateShortURLResponse struct {
-	ShortCode   string     `json:"short_code"`
-	ShortURL    string     `json:"short_url"`
-	OriginalURL string     `json:"original_url"`
-	ExpiredAt   *time.Time `json:"expired_at,omitempty"`
+	ShortCode   string                 `json:"short_code"`
+	ShortURL    string                 `json:"short_url"`
+	OriginalURL string                 `json:"original_url"`
+	DisplayURL  string                 `json:"display_url,omitempty"` // original_url as submitted, if it had to be converted to ASCII
+	Domain      string                 `json:"domain,omitempty"`
+	OrgID       uint                   `json:"org_id,omitempty"`
+	ExpiredAt   *time.Time             `json:"expired_at,omitempty"`
+	Tags        []string               `json:"tags,omitempty"`
+	CacheTTL    int                    `json:"cache_ttl,omitempty"`
+	NoCache     bool                   `json:"no_cache,omitempty"`
+	AccessRules *model.AccessRules     `json:"access_rules,omitempty"`
+	DeepLinks   *model.DeepLinks       `json:"deep_links,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	// VisitDedupMinutes counts one visit per IP per this many minutes
+	VisitDedupMinutes int `json:"visit_dedup_minutes,omitempty"`
+	// NoAnalytics counts visits without logging visitor details
+	NoAnalytics   bool   `json:"no_analytics,omitempty"`
+	Visibility    string `json:"visibility"`
+	RedirectDelay int    `json:"redirect_delay,omitempty"` // Seconds of countdown; 0 uses the global setting
+	// RequireSignature only redirects signed URLs
+	RequireSignature bool `json:"require_signature,omitempty"`
+}
+
+// ValidateURLResponse represents the response for validating a URL
+type ValidateURLResponse struct {
+	OriginalURL string `json:"original_url"`          // As it would be stored
+	DisplayURL  string `json:"display_url,omitempty"` // original_url as submitted, if it has to be converted to ASCII
+	Domain      string `json:"domain,omitempty"`
+	// ExistingShortCode is the link creating would return instead of a new one
+	ExistingShortCode string   `json:"existing_short_code,omitempty"`
+	ExistingShortURL  string   `json:"existing_short_url,omitempty"`
+	Warnings          []string `json:"warnings"` // Valid but probably unintended
 }
 
 // URLInfoResponse represents the response for URL info
 type URLInfoResponse struct {
-	ShortCode   string     `json:"short_code"`
-	OriginalURL string     `json:"original_url"`
-	VisitCount  uint64     `json:"visit_count"`
-	CreatedAt   time.Time  `json:"created_at"`
-	ExpiredAt   *time.Time `json:"expired_at,omitempty"`
+	ShortCode   string                 `json:"short_code"`
+	ShortURL    string                 `json:"short_url"`
+	OriginalURL string                 `json:"original_url"`
+	DisplayURL  string                 `json:"display_url,omitempty"` // original_url as submitted, if it had to be converted to ASCII
+	Title       string                 `json:"title,omitempty"`       // Destination page title (see link_titles)
+	Domain      string                 `json:"domain,omitempty"`
+	OrgID       uint                   `json:"org_id,omitempty"`
+	VisitCount  uint64                 `json:"visit_count"`
+	BotVisits   uint64                 `json:"bot_visit_count"`
+	RawVisits   uint64                 `json:"raw_visit_count"` // visit_count including deduplicated visits
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+	ExpiredAt   *time.Time             `json:"expired_at,omitempty"`
+	Tags        []string               `json:"tags"`
+	CacheTTL    int                    `json:"cache_ttl,omitempty"`
+	NoCache     bool                   `json:"no_cache,omitempty"`
+	AccessRules *model.AccessRules     `json:"access_rules,omitempty"`
+	DeepLinks   *model.DeepLinks       `json:"deep_links,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Warning     bool                   `json:"warning,omitempty"` // Visitors see an unsafe-link warning first
+	// VisitDedupMinutes counts one visit per IP per this many minutes
+	VisitDedupMinutes int `json:"visit_dedup_minutes,omitempty"`
+	// NoAnalytics counts visits without logging visitor details
+	NoAnalytics   bool   `json:"no_analytics,omitempty"`
+	Visibility    string `json:"visibility"`
+	RedirectDelay int    `json:"redirect_delay,omitempty"` // Seconds of countdown; 0 uses the global setting
+	// RequireSignature only redirects signed URLs
+	RequireSignature bool `json:"require_signature,omitempty"`
+	// Health is the last destination check; omitted until the link is checked
+	Health *LinkHealthResponse `json:"health,omitempty"`
+}
+
+// LinkHealthResponse represents the result of the last destination check
+type LinkHealthResponse struct {
+	Status    string    `json:"status"`         // ok, restricted, broken, timeout, tls_error or unreachable
+	Code      int       `json:"code,omitempty"` // HTTP status; 0 without a response
+	Failures  int       `json:"failures"`       // Consecutive failed checks
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// SetTagsRequest represents the request body for replacing a link's tags
+type SetTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// VisitStatsResponse represents the response for visit statistics
+// Breakdowns count human visits only
+type VisitStatsResponse struct {
+	ShortCode  string            `json:"short_code"`
+	VisitCount uint64            `json:"visit_count"`
+	BotVisits  uint64            `json:"bot_visit_count"`
+	RawVisits  uint64            `json:"raw_visit_count"`       // visit_count including deduplicated visits
+	Duplicates uint64            `json:"duplicate_visit_count"` // Repeat visits left out by visit_dedup_minutes
+	Clicks     int64             `json:"clicks"`                // Human visits in the range
+	Uniques    int64             `json:"uniques"`               // Distinct IPs per UTC day, summed
+	Countries  []model.VisitStat `json:"countries"`
+	Devices    []model.VisitStat `json:"devices"`
+	Browsers   []model.VisitStat `json:"browsers"`
+	Referrers  []model.VisitStat `json:"referrers"`
 }
 
 // Response represents a generic API response
 type Response struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message,omitempty"`
-	Data    interface{} `json:"data,omitempty"`
+	Code    int          `json:"code"`
+	Message string       `json:"message,omitempty"`
+	Data    interface{}  `json:"data,omitempty"`
+	Errors  []FieldError `json:"errors,omitempty"` // Problems with an invalid request (see validation.go)
 }
 
 // CreateShortURL handles POST /api/v1/shorten
 func (h *URLHandler) CreateShortURL(c *gin.Context) {
 	var req CreateShortURLRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, Response{
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if _, err := service.NormalizeTags(req.Tags); err != nil {
+		respond(c, http.StatusBadRequest, Response{
 			Code:    http.StatusBadRequest,
-			Message: "Invalid request: " + err.Error(),
+			Message: err.Error(),
 		})
 		return
 	}
 
-	mapping, err := h.service.CreateShortURL(c.Request.Context(), req.URL, req.ExpiredAt)
+	ctx := service.WithActor(c.Request.Context(), requestActor(c))
+	if req.OrgID != 0 {
+		err := h.service.AuthorizeOrg(ctx, req.OrgID, c.GetString(middleware.UserIDContextKey), model.RoleEditor)
+		if err != nil {
+			writeOrgError(c, err, "Failed to check permissions")
+			return
+		}
+	}
+
+	mapping, err := h.service.CreateShortURL(ctx, req.URL, req.Domain, req.ExpiredAt, req.linkOptions())
+	if isInvalidLink(err) {
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
+		respond(c, http.StatusInternalServerError, Response{
 			Code:    http.StatusInternalServerError,
 			Message: "Failed to create short URL: " + err.Error(),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, Response{
+	respond(c, http.StatusOK, Response{
 		Code: http.StatusOK,
-		Data: CreateShortURLResponse{
-			ShortCode:   mapping.ShortCode,
-			ShortURL:    h.buildShortURL(mapping.ShortCode),
-			OriginalURL: mapping.OriginalURL,
-			ExpiredAt:   mapping.ExpiredAt,
-		},
+		Data: h.createResponse(c, mapping),
 	})
 }
 
+// createResponse builds the response to a created (or reused) link
+func (h *URLHandler) createResponse(c *gin.Context, mapping *model.URLMapping) CreateShortURLResponse {
+	return CreateShortURLResponse{
+		ShortCode:         mapping.ShortCode,
+		ShortURL:          h.service.ShortURL(mapping, h.requestOrigin(c)),
+		OriginalURL:       mapping.OriginalURL,
+		DisplayURL:        mapping.DisplayURL,
+		Domain:            mapping.Domain,
+		OrgID:             mapping.OrgID,
+		ExpiredAt:         mapping.ExpiredAt,
+		Tags:              mapping.TagNames(),
+		CacheTTL:          mapping.CacheTTL,
+		NoCache:           mapping.NoCache,
+		AccessRules:       mapping.AccessRules,
+		DeepLinks:         mapping.DeepLinks,
+		Metadata:          mapping.Metadata,
+		VisitDedupMinutes: mapping.VisitDedupMinutes,
+		NoAnalytics:       mapping.NoAnalytics,
+		Visibility:        mapping.Visibility,
+		RedirectDelay:     mapping.RedirectDelay,
+		RequireSignature:  mapping.RequireSignature,
+	}
+}
+
+// ValidateURL handles POST /api/v1/validate
+// It takes the body of POST /api/v1/shorten and answers what creating the
+// link would do, without creating it
+func (h *URLHandler) ValidateURL(c *gin.Context) {
+	var req CreateShortURLRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if _, err := service.NormalizeTags(req.Tags); err != nil {
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if req.OrgID != 0 {
+		err := h.service.AuthorizeOrg(ctx, req.OrgID, c.GetString(middleware.UserIDContextKey), model.RoleEditor)
+		if err != nil {
+			writeOrgError(c, err, "Failed to check permissions")
+			return
+		}
+	}
+
+	result, err := h.service.ValidateShortURL(ctx, req.URL, req.Domain, req.ExpiredAt, req.linkOptions())
+	if isInvalidLink(err) {
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to validate URL: " + err.Error(),
+		})
+		return
+	}
+
+	resp := ValidateURLResponse{
+		OriginalURL: result.Mapping.OriginalURL,
+		DisplayURL:  result.Mapping.DisplayURL,
+		Domain:      result.Mapping.Domain,
+		Warnings:    result.Warnings,
+	}
+	if resp.Warnings == nil {
+		resp.Warnings = []string{}
+	}
+	if result.Existing != nil {
+		resp.ExistingShortCode = result.Existing.ShortCode
+		resp.ExistingShortURL = h.service.ShortURL(result.Existing, h.requestOrigin(c))
+	}
+	respond(c, http.StatusOK, Response{Code: http.StatusOK, Data: resp})
+}
+
+// linkOptions returns the options of the link to create
+func (req *CreateShortURLRequest) linkOptions() model.LinkOptions {
+	return model.LinkOptions{
+		Cache:             model.CachePolicy{TTL: req.CacheTTL, NoCache: req.NoCache},
+		OrgID:             req.OrgID,
+		AccessRules:       req.AccessRules,
+		DeepLinks:         req.DeepLinks,
+		Metadata:          req.Metadata,
+		NoHTTPSUpgrade:    req.NoHTTPSUpgrade,
+		Tags:              req.Tags,
+		ForceNew:          req.ReuseExisting != nil && !*req.ReuseExisting,
+		VisitDedupMinutes: req.VisitDedupMinutes,
+		NoAnalytics:       req.NoAnalytics,
+		Visibility:        req.Visibility,
+		RedirectDelay:     req.RedirectDelay,
+		RequireSignature:  req.RequireSignature,
+	}
+}
+
+// isInvalidLink reports whether err rejects a link to create as invalid
+func isInvalidLink(err error) bool {
+	return errors.Is(err, service.ErrInvalidURL) || errors.Is(err, service.ErrUnknownDomain) ||
+		errors.Is(err, service.ErrInvalidCachePolicy) || errors.Is(err, service.ErrInvalidAccessRules) ||
+		errors.Is(err, service.ErrInvalidDeepLinks) || errors.Is(err, service.ErrInvalidMetadata) ||
+		errors.Is(err, service.ErrInvalidVisitDedup) || errors.Is(err, service.ErrInvalidVisibility) ||
+		errors.Is(err, service.ErrInvalidRedirectDelay) || errors.Is(err, service.ErrSigningDisabled) ||
+		errors.Is(err, service.ErrRejectedByHook)
+}
+
 // RedirectToOriginalURL handles GET /{short_code}
 func (h *URLHandler) RedirectToOriginalURL(c *gin.Context) {
 	shortCode := c.Param("short_code")
 	if shortCode == "" {
-		c.JSON(http.StatusBadRequest, Response{
+		respond(c, http.StatusBadRequest, Response{
 			Code:    http.StatusBadRequest,
 			Message: "Short code is required",
 		})
 		return
 	}
+	h.redirect(c, shortCode)
+}
 
-	originalURL, err := h.service.GetOriginalURL(c.Request.Context(), shortCode)
+// redirect sends the visitor on to the link of shortCode, after the checks
+// and pages the link calls for
+func (h *URLHandler) redirect(c *gin.Context, shortCode string) {
+	visitor := model.Visitor{
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		Referrer:   c.Request.Referer(),
+		DoNotTrack: c.GetHeader("DNT") == "1" || c.GetHeader("Sec-GPC") == "1",
+		UserID:     c.GetString(middleware.UserIDContextKey),
+		Signature:  c.Query(service.SignatureParam),
+	}
+	visitor.SignatureExpires, _ = strconv.ParseInt(c.Query(service.SignatureExpiresParam), 10, 64)
+	mapping, err := h.service.ResolveLink(c.Request.Context(), c.Request.Host, shortCode, visitor)
+	if errors.Is(err, service.ErrAccessDenied) {
+		respond(c, http.StatusForbidden, Response{
+			Code:    http.StatusForbidden,
+			Message: "Access to this short URL is restricted",
+		})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidSignature) {
+		respond(c, http.StatusForbidden, Response{
+			Code:    http.StatusForbidden,
+			Message: "This short URL needs a valid, unexpired signature",
+		})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, Response{
+		// A lookup that timed out or couldn't reach MySQL says nothing about
+		// the link; don't claim it's gone
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, service.ErrDatabaseUnavailable) {
+			respond(c, http.StatusServiceUnavailable, Response{
+				Code:    http.StatusServiceUnavailable,
+				Message: "Service temporarily unavailable",
+			})
+			return
+		}
+		respond(c, http.StatusNotFound, Response{
 			Code:    http.StatusNotFound,
 			Message: "Short URL not found or expired",
 		})
 		return
 	}
 
-	// Record visit asynchronously
-	ip := c.ClientIP()
-	userAgent := c.Request.UserAgent()
-	go h.service.RecordVisit(c.Request.Context(), shortCode, ip, userAgent)
+	// Screen for abuse; throttled links are refused once over their limit
+	if err := h.service.ScreenVisit(c.Request.Context(), shortCode, visitor.IP); errors.Is(err, service.ErrLinkThrottled) {
+		c.Header("Retry-After", "60")
+		respond(c, http.StatusTooManyRequests, Response{
+			Code:    http.StatusTooManyRequests,
+			Message: "Too many requests for this short URL",
+		})
+		return
+	}
+
+	// Record visit (the writes run in the background)
+	h.service.RecordVisit(c.Request.Context(), mapping, visitor)
+
+	// Shared caches must not hand a private link's redirect to others, nor
+	// keep serving a signed one after it expires
+	if mapping.IsPrivate() || visitor.Signature != "" {
+		c.Header("Cache-Control", "private, no-store")
+	}
+
+	// Links flagged as possibly unsafe need a click-through
+	if mapping.Warning {
+		h.renderInterstitial(c, shortCode, mapping.OriginalURL)
+		return
+	}
+
+	// Mobile visitors open the app when the link has one for their platform
+	if !mapping.DeepLinks.IsEmpty() {
+		_, _, os := enrich.ParseUserAgent(visitor.UserAgent)
+		if appURL := mapping.DeepLinks.ForOS(os); appURL != "" {
+			h.renderDeepLink(c, appURL, mapping.OriginalURL)
+			return
+		}
+	}
+
+	// Links with a delay (or all, if one is configured) count down first
+	if delay := h.redirectDelay(mapping); delay > 0 {
+		h.renderCountdown(c, mapping.ShortCode, mapping.OriginalURL, delay)
+		return
+	}
 
 	// Redirect to original URL
-	c.Redirect(http.StatusFound, originalURL)
+	c.Redirect(http.StatusFound, mapping.OriginalURL)
 }
 
 // GetURLInfo handles GET /api/v1/info/{short_code}
+// Supports If-None-Match (see etag.go)
 func (h *URLHandler) GetURLInfo(c *gin.Context) {
 	shortCode := c.Param("short_code")
 	if shortCode == "" {
-		c.JSON(http.StatusBadRequest, Response{
+		respond(c, http.StatusBadRequest, Response{
 			Code:    http.StatusBadRequest,
 			Message: "Short code is required",
 		})
@@ -126,34 +446,273 @@ func (h *URLHandler) GetURLInfo(c *gin.Context) {
 
 	mapping, err := h.service.GetURLInfo(c.Request.Context(), shortCode)
 	if err != nil {
-		c.JSON(http.StatusNotFound, Response{
+		respond(c, http.StatusNotFound, Response{
 			Code:    http.StatusNotFound,
 			Message: "Short URL not found",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, Response{
+	writeJSONWithETag(c, Response{
 		Code: http.StatusOK,
-		Data: URLInfoResponse{
-			ShortCode:   mapping.ShortCode,
-			OriginalURL: mapping.OriginalURL,
-			VisitCount:  mapping.VisitCount,
-			CreatedAt:   mapping.CreatedAt,
-			ExpiredAt:   mapping.ExpiredAt,
+		Data: h.infoResponse(c, mapping),
+	})
+}
+
+// SetTags handles PUT /api/v1/urls/{short_code}/tags
+// Replaces all tags of the link; an empty list removes them
+func (h *URLHandler) SetTags(c *gin.Context) {
+	var req SetTagsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	mapping, err := h.service.SetTags(c.Request.Context(), c.Param("short_code"), req.Tags)
+	if errors.Is(err, service.ErrInvalidTag) {
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrShortCodeNotFound) {
+		respond(c, http.StatusNotFound, Response{
+			Code:    http.StatusNotFound,
+			Message: "Short URL not found",
+		})
+		return
+	}
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to set tags: " + err.Error(),
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: h.infoResponse(c, mapping),
+	})
+}
+
+// infoResponse builds the info representation of a mapping
+func (h *URLHandler) infoResponse(c *gin.Context, mapping *model.URLMapping) URLInfoResponse {
+	return URLInfoResponse{
+		ShortCode:         mapping.ShortCode,
+		ShortURL:          h.service.ShortURL(mapping, h.requestOrigin(c)),
+		OriginalURL:       mapping.OriginalURL,
+		DisplayURL:        mapping.DisplayURL,
+		Title:             mapping.Title,
+		Domain:            mapping.Domain,
+		OrgID:             mapping.OrgID,
+		VisitCount:        mapping.VisitCount,
+		BotVisits:         mapping.BotVisitCount,
+		RawVisits:         mapping.RawVisitCount(),
+		CreatedAt:         mapping.CreatedAt,
+		UpdatedAt:         mapping.UpdatedAt,
+		ExpiredAt:         mapping.ExpiredAt,
+		Tags:              mapping.TagNames(),
+		CacheTTL:          mapping.CacheTTL,
+		NoCache:           mapping.NoCache,
+		AccessRules:       mapping.AccessRules,
+		DeepLinks:         mapping.DeepLinks,
+		Metadata:          mapping.Metadata,
+		Warning:           mapping.Warning,
+		Health:            linkHealthResponse(mapping),
+		VisitDedupMinutes: mapping.VisitDedupMinutes,
+		NoAnalytics:       mapping.NoAnalytics,
+		Visibility:        mapping.Visibility,
+		RedirectDelay:     mapping.RedirectDelay,
+		RequireSignature:  mapping.RequireSignature,
+	}
+}
+
+// linkHealthResponse returns a mapping's last check, or nil if it has none
+func linkHealthResponse(mapping *model.URLMapping) *LinkHealthResponse {
+	if mapping.HealthCheckedAt == nil {
+		return nil
+	}
+	return &LinkHealthResponse{
+		Status:    mapping.HealthStatus,
+		Code:      mapping.HealthCode,
+		Failures:  mapping.HealthFailures,
+		CheckedAt: *mapping.HealthCheckedAt,
+	}
+}
+
+// GetVisitStats handles GET /api/v1/stats/{short_code}
+// Query: from and to (RFC3339 or YYYY-MM-DD) limit the breakdowns
+func (h *URLHandler) GetVisitStats(c *gin.Context) {
+	shortCode := c.Param("short_code")
+	from, err := parseExportTime(c.Query("from"))
+	if err != nil {
+		invalidParam(c, "from", err)
+		return
+	}
+	to, err := parseExportTime(c.Query("to"))
+	if err != nil {
+		invalidParam(c, "to", err)
+		return
+	}
+
+	stats, err := h.service.GetVisitStats(c.Request.Context(), shortCode, from, to)
+	if errors.Is(err, service.ErrShortCodeNotFound) {
+		respond(c, http.StatusNotFound, Response{
+			Code:    http.StatusNotFound,
+			Message: "Short URL not found",
+		})
+		return
+	}
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get visit stats: " + err.Error(),
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code: http.StatusOK,
+		Data: VisitStatsResponse{
+			ShortCode:  stats.Mapping.ShortCode,
+			VisitCount: stats.Mapping.VisitCount,
+			BotVisits:  stats.Mapping.BotVisitCount,
+			RawVisits:  stats.Mapping.RawVisitCount(),
+			Duplicates: stats.Mapping.DuplicateVisitCount,
+			Clicks:     stats.Clicks,
+			Uniques:    stats.Uniques,
+			Countries:  stats.Countries,
+			Devices:    stats.Devices,
+			Browsers:   stats.Browsers,
+			Referrers:  stats.Referrers,
 		},
 	})
 }
 
-// HealthCheck handles GET /health
-func (h *URLHandler) HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, Response{
+// DeleteURL handles DELETE /admin/links/{short_code}
+func (h *URLHandler) DeleteURL(c *gin.Context) {
+	shortCode := c.Param("short_code")
+	err := h.service.DeleteURL(c.Request.Context(), shortCode)
+	if errors.Is(err, service.ErrShortCodeNotFound) {
+		respond(c, http.StatusNotFound, Response{
+			Code:    http.StatusNotFound,
+			Message: "Short URL not found",
+		})
+		return
+	}
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to delete short URL: " + err.Error(),
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
 		Code:    http.StatusOK,
-		Message: "OK",
+		Message: "Short URL deleted",
 	})
 }
 
-// buildShortURL builds the full short URL
-func (h *URLHandler) buildShortURL(shortCode string) string {
-	return fmt.Sprintf("%s/%s", h.baseURL, shortCode)
+// RestoreURL handles POST /admin/links/{short_code}/restore
+func (h *URLHandler) RestoreURL(c *gin.Context) {
+	shortCode := c.Param("short_code")
+	mapping, err := h.service.RestoreURL(c.Request.Context(), shortCode)
+	if errors.Is(err, service.ErrShortCodeNotFound) {
+		respond(c, http.StatusNotFound, Response{
+			Code:    http.StatusNotFound,
+			Message: "No deleted short URL with this code",
+		})
+		return
+	}
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to restore short URL: " + err.Error(),
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, Response{
+		Code:    http.StatusOK,
+		Message: "Short URL restored",
+		Data:    h.infoResponse(c, mapping),
+	})
+}
+
+// exportWriteTimeout is how long each exported batch may take to write
+// Extended per batch so long exports outlive the server's WriteTimeout
+const exportWriteTimeout = 30 * time.Second
+
+// ExportVisitLogs handles GET /api/v1/export/{short_code}
+// Query: format=csv|ndjson (default csv), from and to (RFC3339 or YYYY-MM-DD)
+func (h *URLHandler) ExportVisitLogs(c *gin.Context) {
+	shortCode := c.Param("short_code")
+	if shortCode == "" {
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: "Short code is required",
+		})
+		return
+	}
+
+	from, err := parseExportTime(c.Query("from"))
+	if err != nil {
+		invalidParam(c, "from", err)
+		return
+	}
+	to, err := parseExportTime(c.Query("to"))
+	if err != nil {
+		invalidParam(c, "to", err)
+		return
+	}
+
+	format := c.DefaultQuery("format", ExportFormatCSV)
+	writer, err := newVisitLogWriter(format, c.Writer)
+	if err != nil {
+		invalidParam(c, "format", err)
+		return
+	}
+
+	if _, err := h.service.GetURLInfo(c.Request.Context(), shortCode); err != nil {
+		respond(c, http.StatusNotFound, Response{
+			Code:    http.StatusNotFound,
+			Message: "Short URL not found",
+		})
+		return
+	}
+
+	// Headers are sent before the first row; errors after this point can
+	// only be logged and the stream cut short
+	c.Header("Content-Type", writer.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s_visits.%s"`, shortCode, format))
+	c.Status(http.StatusOK)
+
+	rc := http.NewResponseController(c.Writer)
+	_ = rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+
+	if err := writer.Begin(); err != nil {
+		fmt.Printf("Failed to write export: %v\n", err)
+		return
+	}
+
+	err = h.service.ExportVisitLogs(c.Request.Context(), shortCode, from, to, func(batch []model.VisitLog) error {
+		for i := range batch {
+			if err := writer.Write(&batch[i]); err != nil {
+				return err
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		fmt.Printf("Failed to export visit logs for %s: %v\n", shortCode, err)
+	}
 }
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Monthlyaway/short-link/internal/middleware"
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ListURLsResponse represents one page of links
+type ListURLsResponse struct {
+	Items      []URLInfoResponse `json:"items"`
+	Total      int64             `json:"total"`
+	Page       int               `json:"page,omitempty"` // Omitted for cursor pages
+	PageSize   int               `json:"page_size"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// ListURLs handles GET /api/v1/urls
+// Supports If-None-Match (see etag.go)
+// Query:
+//   - tag (repeatable; links must have all), q (full-text search over original URLs)
+//   - status (active|disabled), expired (true|false)
+//   - created_from, created_to (RFC3339 or YYYY-MM-DD; to is exclusive)
+//   - updated_since (RFC3339 or YYYY-MM-DD; links changed at or after)
+//   - destination (host of the original URL)
+//   - meta.<key>=<value> (the link's metadata value for key must match; one per key)
+//   - org_id (links of an organization the caller is a member of; without
+//     it, public links without an organization; unlisted and private links
+//     are only listed to members)
+//   - sort (created_at|visit_count), order (desc|asc, default desc)
+//   - cursor (next_cursor of the previous page), or page for offset paging
+//   - page_size (default 20, max 100)
+func (h *URLHandler) ListURLs(c *gin.Context) {
+	filter, err := parseURLFilter(c)
+	if err != nil {
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	if filter.OrgID != 0 {
+		err := h.service.AuthorizeOrg(c.Request.Context(), filter.OrgID,
+			c.GetString(middleware.UserIDContextKey), model.RoleViewer)
+		if err != nil {
+			writeOrgError(c, err, "Failed to check permissions")
+			return
+		}
+	} else {
+		// Links without an organization have no members to list them to
+		filter.ListedOnly = true
+	}
+
+	page, err := h.service.ListURLs(c.Request.Context(), filter, c.Query("cursor"))
+	if errors.Is(err, service.ErrInvalidTag) || errors.Is(err, service.ErrInvalidListFilter) {
+		respond(c, http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		respond(c, http.StatusInternalServerError, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to list short URLs: " + err.Error(),
+		})
+		return
+	}
+
+	items := make([]URLInfoResponse, 0, len(page.Items))
+	for i := range page.Items {
+		items = append(items, h.infoResponse(c, &page.Items[i]))
+	}
+	writeJSONWithETag(c, Response{
+		Code: http.StatusOK,
+		Data: ListURLsResponse{
+			Items:      items,
+			Total:      page.Total,
+			Page:       page.Page,
+			PageSize:   page.PageSize,
+			NextCursor: page.NextCursor,
+		},
+	})
+}
+
+// parseURLFilter reads the list filters from the query string
+func parseURLFilter(c *gin.Context) (model.URLFilter, error) {
+	filter := model.URLFilter{
+		Tags:            c.QueryArray("tag"),
+		Query:           c.Query("q"),
+		DestinationHost: c.Query("destination"),
+		Sort:            c.Query("sort"),
+	}
+
+	var err error
+	if filter.Page, err = queryInt(c, "page"); err != nil {
+		return filter, err
+	}
+	if filter.PageSize, err = queryInt(c, "page_size"); err != nil {
+		return filter, err
+	}
+	orgID, err := queryInt(c, "org_id")
+	if err != nil || orgID < 0 {
+		return filter, fmt.Errorf("org_id must be a positive integer")
+	}
+	filter.OrgID = uint(orgID)
+
+	switch c.Query("order") {
+	case "", "desc":
+	case "asc":
+		filter.Ascending = true
+	default:
+		return filter, fmt.Errorf("order must be asc or desc")
+	}
+
+	switch c.Query("status") {
+	case "":
+	case "active":
+		active := int8(1)
+		filter.Status = &active
+	case "disabled":
+		disabled := int8(0)
+		filter.Status = &disabled
+	default:
+		return filter, fmt.Errorf("status must be active or disabled")
+	}
+
+	if value := c.Query("expired"); value != "" {
+		expired, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("expired must be true or false")
+		}
+		filter.Expired = &expired
+	}
+
+	if filter.CreatedFrom, err = parseExportTime(c.Query("created_from")); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = parseExportTime(c.Query("created_to")); err != nil {
+		return filter, err
+	}
+	if filter.UpdatedSince, err = parseExportTime(c.Query("updated_since")); err != nil {
+		return filter, err
+	}
+
+	for name, values := range c.Request.URL.Query() {
+		if key, ok := strings.CutPrefix(name, "meta."); ok && len(values) > 0 {
+			if filter.Metadata == nil {
+				filter.Metadata = make(map[string]string)
+			}
+			filter.Metadata[key] = values[0]
+		}
+	}
+	return filter, nil
+}
+
+// queryInt parses an optional integer query parameter (0 when absent)
+func queryInt(c *gin.Context, name string) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+	return n, nil
+}
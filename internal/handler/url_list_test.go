@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// filterFor parses the list filter of a request to /api/v1/urls?query
+func filterFor(query string) (model.URLFilter, error) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/api/v1/urls?"+query, nil)
+	return parseURLFilter(c)
+}
+
+// TestParseURLFilter tests query string parsing for the list endpoint
+func TestParseURLFilter(t *testing.T) {
+	filter, err := filterFor("tag=a&tag=b&status=disabled&expired=false&created_from=2024-01-01" +
+		"&destination=example.com&sort=visit_count&order=asc&page_size=50&org_id=7")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, filter.Tags)
+	assert.Equal(t, int8(0), *filter.Status)
+	assert.False(t, *filter.Expired)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), filter.CreatedFrom)
+	assert.True(t, filter.CreatedTo.IsZero())
+	assert.Equal(t, "example.com", filter.DestinationHost)
+	assert.Equal(t, model.SortVisitCount, filter.Sort)
+	assert.True(t, filter.Ascending)
+	assert.Equal(t, 50, filter.PageSize)
+	assert.Equal(t, uint(7), filter.OrgID)
+
+	filter, err = filterFor("meta.campaign_id=4711&meta.channel=email")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"campaign_id": "4711", "channel": "email"}, filter.Metadata)
+
+	filter, err = filterFor("")
+	assert.NoError(t, err)
+	assert.Nil(t, filter.Status)
+	assert.Nil(t, filter.Expired)
+	assert.False(t, filter.Ascending)
+	assert.Zero(t, filter.OrgID)
+	assert.Nil(t, filter.Metadata)
+
+	for _, query := range []string{"status=gone", "expired=maybe", "order=up", "page=x", "created_to=yesterday", "org_id=-1"} {
+		_, err := filterFor(query)
+		assert.Error(t, err, query)
+	}
+}
+
+// listRepository serves ListURLs from links, applying the visibility filter
+type listRepository struct {
+	service.Repository
+	links  []model.URLMapping
+	filter model.URLFilter
+}
+
+func (r *listRepository) ListURLs(ctx context.Context, filter model.URLFilter) ([]model.URLMapping, int64, error) {
+	r.filter = filter
+	var matches []model.URLMapping
+	for _, link := range r.links {
+		if link.OrgID == filter.OrgID && (!filter.ListedOnly || link.IsListed()) {
+			matches = append(matches, link)
+		}
+	}
+	return matches, int64(len(matches)), nil
+}
+
+// TestListURLsHidesUnlisted tests that anonymous listings leave out
+// unlisted links
+func TestListURLsHidesUnlisted(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &listRepository{links: []model.URLMapping{
+		{ID: 1, ShortCode: "public1", OriginalURL: "https://example.com/a", Visibility: model.VisibilityPublic},
+		{ID: 2, ShortCode: "unlist1", OriginalURL: "https://example.com/b", Visibility: model.VisibilityUnlisted},
+	}}
+	h := NewURLHandler(service.NewURLService(repo, nil, nil))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/api/v1/urls", nil)
+	h.ListURLs(c)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, repo.filter.ListedOnly)
+	assert.Contains(t, w.Body.String(), `"short_code":"public1"`)
+	assert.NotContains(t, w.Body.String(), "unlist1")
+	assert.Contains(t, w.Body.String(), `"total":1`)
+}
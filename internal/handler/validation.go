@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ============================================================================
+// REQUEST VALIDATION ERRORS
+// ============================================================================
+// Invalid requests get 400 with one entry per problem in "errors", so
+// clients can point at the offending field instead of parsing the message:
+//
+//   {"code": 400, "message": "Invalid request: url is required",
+//    "errors": [{"field": "url", "rule": "required", "message": "url is required"}]}
+//
+// field is the JSON path of the field (e.g. "deep_links.ios", "tags[2]"),
+// or empty for problems with the body as a whole. rule is the binding tag
+// that failed ("required", "max", ...) or one of:
+//   json    the body isn't valid JSON
+//   type    the value has the wrong JSON type
+//   format  the value has the right type but can't be parsed (times)
+//   invalid anything else
+// ============================================================================
+
+// FieldError is one problem with a request
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Rules of FieldErrors not coming from binding tags
+const (
+	RuleJSON    = "json"
+	RuleType    = "type"
+	RuleFormat  = "format"
+	RuleInvalid = "invalid"
+)
+
+// useJSONFieldNames makes the validator report fields by their JSON names
+var useJSONFieldNames sync.Once
+
+// bindJSON binds the request body into req and, if that fails, writes the
+// 400 response; it reports whether the handler should continue
+func bindJSON(c *gin.Context, req interface{}) bool {
+	useJSONFieldNames.Do(func() {
+		if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+			v.RegisterTagNameFunc(jsonFieldName)
+		}
+	})
+	if err := c.ShouldBindJSON(req); err != nil {
+		invalidRequest(c, validationErrors(err)...)
+		return false
+	}
+	return true
+}
+
+// invalidParam writes the 400 response for a query or path parameter that
+// couldn't be parsed
+func invalidParam(c *gin.Context, field string, err error) {
+	invalidRequest(c, FieldError{Field: field, Rule: RuleFormat, Message: err.Error()})
+}
+
+// invalidRequest writes the 400 response listing problems
+func invalidRequest(c *gin.Context, problems ...FieldError) {
+	messages := make([]string, len(problems))
+	for i, p := range problems {
+		messages[i] = p.Message
+	}
+	respond(c, http.StatusBadRequest, Response{
+		Code:    http.StatusBadRequest,
+		Message: "Invalid request: " + strings.Join(messages, "; "),
+		Errors:  problems,
+	})
+}
+
+// validationErrors translates a binding error into FieldErrors
+func validationErrors(err error) []FieldError {
+	var invalid validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	var timeErr *time.ParseError
+	switch {
+	case errors.As(err, &invalid):
+		problems := make([]FieldError, len(invalid))
+		for i, fe := range invalid {
+			problems[i] = tagError(fe)
+		}
+		return problems
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		return []FieldError{{
+			Field:   field,
+			Rule:    RuleType,
+			Message: fmt.Sprintf("%s must be %s", displayField(field), jsonTypeName(typeErr.Type)),
+		}}
+	case errors.Is(err, io.EOF):
+		return []FieldError{{Rule: RuleJSON, Message: "request body is required"}}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return []FieldError{{Rule: RuleJSON, Message: "malformed JSON: " + err.Error()}}
+	case errors.As(err, &timeErr):
+		return []FieldError{{Rule: RuleFormat, Message: fmt.Sprintf("invalid time %q (use RFC3339)", timeErr.Value)}}
+	default:
+		return []FieldError{{Rule: RuleInvalid, Message: err.Error()}}
+	}
+}
+
+// tagError describes a failed binding tag
+func tagError(fe validator.FieldError) FieldError {
+	// The namespace starts with the request type's name
+	field := fe.Namespace()
+	if i := strings.IndexByte(field, '.'); i >= 0 {
+		field = field[i+1:]
+	}
+	name := displayField(field)
+
+	var message string
+	switch fe.Tag() {
+	case "required":
+		message = name + " is required"
+	case "min", "gte":
+		message = fmt.Sprintf("%s must be at least %s%s", name, fe.Param(), sizeUnit(fe))
+	case "max", "lte":
+		message = fmt.Sprintf("%s must be at most %s%s", name, fe.Param(), sizeUnit(fe))
+	case "len":
+		message = fmt.Sprintf("%s must be exactly %s%s", name, fe.Param(), sizeUnit(fe))
+	case "oneof":
+		message = fmt.Sprintf("%s must be one of: %s", name, strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "url":
+		message = name + " must be a URL"
+	case "email":
+		message = name + " must be an email address"
+	default:
+		message = fmt.Sprintf("%s failed the %q check", name, fe.Tag())
+	}
+	return FieldError{Field: field, Rule: fe.Tag(), Message: message}
+}
+
+// sizeUnit names what min/max/len count for the field's kind
+func sizeUnit(fe validator.FieldError) string {
+	switch fe.Kind() {
+	case reflect.String:
+		return " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return " items"
+	default:
+		return ""
+	}
+}
+
+// displayField names a field in messages
+func displayField(field string) string {
+	if field == "" {
+		return "value"
+	}
+	return field
+}
+
+// jsonTypeName describes the JSON type a Go type is decoded from
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// jsonFieldName names a struct field by its json tag, like the client sees it
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validationRequest exercises the binding tags in use
+type validationRequest struct {
+	URL       string     `json:"url" binding:"required"`
+	Tags      []string   `json:"tags" binding:"max=2"`
+	ExpiredAt *time.Time `json:"expired_at"`
+	Owner     struct {
+		Name string `json:"name" binding:"required"`
+	} `json:"owner"`
+}
+
+// bindResponse binds body like a handler would and returns the 400 response
+func bindResponse(t *testing.T, body string) Response {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+
+	var req validationRequest
+	require.False(t, bindJSON(c, &req), body)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var resp Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+// TestBindJSONErrors tests translating binding errors into field errors
+func TestBindJSONErrors(t *testing.T) {
+	resp := bindResponse(t, `{"tags": ["a", "b", "c"]}`)
+	assert.Equal(t, []FieldError{
+		{Field: "url", Rule: "required", Message: "url is required"},
+		{Field: "tags", Rule: "max", Message: "tags must be at most 2 items"},
+		{Field: "owner.name", Rule: "required", Message: "owner.name is required"},
+	}, resp.Errors)
+	assert.Equal(t, "Invalid request: url is required; tags must be at most 2 items; owner.name is required", resp.Message)
+
+	resp = bindResponse(t, `{"url": 42}`)
+	assert.Equal(t, []FieldError{{Field: "url", Rule: RuleType, Message: "url must be a string"}}, resp.Errors)
+
+	resp = bindResponse(t, `{"url": "https://example.com", "owner": {"name": ["x"]}}`)
+	assert.Equal(t, []FieldError{{Field: "owner.name", Rule: RuleType, Message: "owner.name must be a string"}}, resp.Errors)
+
+	resp = bindResponse(t, `{"url": "https://example.com", "expired_at": "tomorrow"}`)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, RuleFormat, resp.Errors[0].Rule)
+
+	resp = bindResponse(t, `{"url": `)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, RuleJSON, resp.Errors[0].Rule)
+
+	resp = bindResponse(t, ``)
+	assert.Equal(t, []FieldError{{Rule: RuleJSON, Message: "request body is required"}}, resp.Errors)
+}
+
+// TestInvalidParam tests the response for unparsable query parameters
+func TestInvalidParam(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	invalidParam(c, "from", errors.New(`invalid time "soon"`))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"code": 400, "message": "Invalid request: invalid time \"soon\"",
+		"errors": [{"field": "from", "rule": "format", "message": "invalid time \"soon\""}]}`, w.Body.String())
+}
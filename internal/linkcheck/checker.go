@@ -0,0 +1,152 @@
+package linkcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// userAgent identifies health checks in destination servers' logs
+const userAgent = "Mozilla/5.0 (compatible; short-link-health-check/1.0)"
+
+// maxRedirects is how many redirects a check follows before giving up
+const maxRedirects = 5
+
+// Result is the outcome of checking one destination
+type Result struct {
+	Status string // One of the model.Health* statuses
+	Code   int    // HTTP status of the final response; 0 without one
+}
+
+// Checker HEAD-checks destination URLs
+type Checker struct {
+	client *http.Client
+}
+
+// NewChecker creates a checker giving up on a destination after timeout
+// Connections, including to redirect targets, are checked by dialControl
+// when set (see service.DestinationPolicy.Control).
+func NewChecker(timeout time.Duration, dialControl func(network, address string, c syscall.RawConn) error) *Checker {
+	return &Checker{client: newClient(timeout, dialControl)}
+}
+
+// newClient creates a client for requests to destinations
+func newClient(timeout time.Duration, dialControl func(network, address string, c syscall.RawConn) error) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: timeout, Control: dialControl}).DialContext
+	// Destinations are spread over many hosts; idle connections would only pile up
+	transport.DisableKeepAlives = true
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errTooManyRedirects
+			}
+			return nil
+		},
+	}
+}
+
+// errTooManyRedirects ends redirect loops
+var errTooManyRedirects = errors.New("too many redirects")
+
+// Check requests rawURL with HEAD, falling back to GET for servers that
+// don't support HEAD, and classifies the response
+func (c *Checker) Check(ctx context.Context, rawURL string) Result {
+	code, err := c.request(ctx, http.MethodHead, rawURL)
+	if err == nil && (code == http.StatusMethodNotAllowed || code == http.StatusNotImplemented) {
+		code, err = c.request(ctx, http.MethodGet, rawURL)
+	}
+	if err != nil {
+		return Result{Status: classifyError(err)}
+	}
+	return Result{Status: classifyCode(code), Code: code}
+}
+
+// HTTPSVariant returns the https:// form of an http:// URL if the
+// destination answers over https, and false otherwise
+// URLs with an explicit port are left alone, as the https port is unknown.
+func (c *Checker) HTTPSVariant(ctx context.Context, rawURL string) (string, bool) {
+	upgraded, ok := httpsURL(rawURL)
+	if !ok {
+		return "", false
+	}
+	switch c.Check(ctx, upgraded).Status {
+	case model.HealthOK, model.HealthRestricted:
+		return upgraded, true
+	}
+	return "", false
+}
+
+// httpsURL rewrites an http:// URL without a port to https://
+func httpsURL(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || !strings.EqualFold(u.Scheme, "http") || u.Port() != "" {
+		return "", false
+	}
+	// Keep the rest as given; re-encoding could change the path or query
+	return "https" + rawURL[len("http"):], true
+}
+
+// request sends one request and returns the response status; the body is
+// never read
+func (c *Checker) request(ctx context.Context, method, rawURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// classifyCode maps an HTTP status to a health status
+func classifyCode(code int) string {
+	switch {
+	case code < 400:
+		return model.HealthOK
+	case code == http.StatusUnauthorized || code == http.StatusForbidden || code == http.StatusTooManyRequests:
+		return model.HealthRestricted
+	default:
+		return model.HealthBroken
+	}
+}
+
+// classifyError maps a failed request to a health status
+func classifyError(err error) string {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return model.HealthTimeout
+	}
+
+	var verifyErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var hostnameErr x509.HostnameError
+	var authorityErr x509.UnknownAuthorityError
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &verifyErr) || errors.As(err, &recordErr) || errors.As(err, &alertErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &authorityErr) || errors.As(err, &invalidErr) {
+		return model.HealthTLSError
+	}
+
+	if errors.Is(err, errTooManyRedirects) {
+		return model.HealthBroken
+	}
+	return model.HealthUnreachable
+}
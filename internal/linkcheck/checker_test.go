@@ -0,0 +1,85 @@
+package linkcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckStatusCodes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/login":
+			w.WriteHeader(http.StatusForbidden)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case "/error":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewChecker(time.Second, nil)
+	tests := []struct {
+		path string
+		want Result
+	}{
+		{"/ok", Result{Status: model.HealthOK, Code: 200}},
+		{"/moved", Result{Status: model.HealthOK, Code: 200}},
+		{"/loop", Result{Status: model.HealthBroken}},
+		{"/login", Result{Status: model.HealthRestricted, Code: 403}},
+		{"/no-head", Result{Status: model.HealthOK, Code: 200}},
+		{"/error", Result{Status: model.HealthBroken, Code: 502}},
+		{"/removed", Result{Status: model.HealthBroken, Code: 404}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, c.Check(context.Background(), srv.URL+tt.path))
+		})
+	}
+}
+
+func TestCheckFailures(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+	tlsSrv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsSrv.Close()
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+
+	c := NewChecker(50*time.Millisecond, nil)
+	assert.Equal(t, model.HealthTimeout, c.Check(context.Background(), slow.URL).Status)
+	// The test server's certificate isn't trusted
+	assert.Equal(t, model.HealthTLSError, NewChecker(time.Second, nil).Check(context.Background(), tlsSrv.URL).Status)
+	assert.Equal(t, model.HealthUnreachable, c.Check(context.Background(), closed.URL).Status)
+}
+
+func TestHTTPSURL(t *testing.T) {
+	got, ok := httpsURL("http://example.com/spring?utm_source=mail#top")
+	assert.True(t, ok)
+	assert.Equal(t, "https://example.com/spring?utm_source=mail#top", got)
+
+	_, ok = httpsURL("https://example.com/")
+	assert.False(t, ok, "already https")
+	_, ok = httpsURL("http://example.com:8080/")
+	assert.False(t, ok, "the https port is unknown")
+}
@@ -0,0 +1,59 @@
+package linkcheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// Notifier tells link owners their destination looks dead
+type Notifier interface {
+	Notify(ctx context.Context, event *model.LinkHealthEvent) error
+}
+
+// NoopNotifier notifies nobody
+type NoopNotifier struct{}
+
+// Notify implements Notifier
+func (NoopNotifier) Notify(context.Context, *model.LinkHealthEvent) error { return nil }
+
+// WebhookNotifier POSTs events as JSON to a URL, which routes them to the
+// owner named in the event
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier posting to url, giving up on a
+// request after timeout
+func NewWebhookNotifier(url string, timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Notify implements Notifier
+func (w *WebhookNotifier) Notify(ctx context.Context, event *model.LinkHealthEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode link health event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call link health webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("link health webhook returned %s", resp.Status)
+	}
+	return nil
+}
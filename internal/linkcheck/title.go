@@ -0,0 +1,99 @@
+package linkcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// titleUserAgent identifies title fetches in destination servers' logs
+const titleUserAgent = "Mozilla/5.0 (compatible; short-link-title-fetch/1.0)"
+
+// maxTitleLength matches the url_mappings.title column, in characters
+const maxTitleLength = 255
+
+// TitleFetcher reads the <title> of destination pages
+type TitleFetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+// NewTitleFetcher creates a fetcher giving up on a page after timeout and
+// reading at most maxBytes of it; dialControl is as for NewChecker
+func NewTitleFetcher(timeout time.Duration, maxBytes int64, dialControl func(network, address string, c syscall.RawConn) error) *TitleFetcher {
+	return &TitleFetcher{client: newClient(timeout, dialControl), maxBytes: maxBytes}
+}
+
+// FetchTitle returns the title of the page at rawURL, with whitespace
+// collapsed; it is empty for pages that aren't HTML or have no title in
+// their first maxBytes
+func (f *TitleFetcher) FetchTitle(ctx context.Context, rawURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", titleUserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("destination answered HTTP %d", resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return "", nil
+	}
+	// Decodes the page's charset (header, BOM or <meta>) to UTF-8
+	body, err := charset.NewReader(io.LimitReader(resp.Body, f.maxBytes), contentType)
+	if err != nil {
+		return "", err
+	}
+	return parseTitle(body), nil
+}
+
+// parseTitle returns the cleaned text of the first <title> before <body>;
+// later ones belong to inline SVGs
+func parseTitle(r io.Reader) string {
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "title":
+				// A title holds nothing but text, in one token
+				if z.Next() != html.TextToken {
+					return ""
+				}
+				return cleanTitle(string(z.Text()))
+			case "body":
+				return ""
+			}
+		}
+	}
+}
+
+// cleanTitle collapses whitespace and cuts the title to the column size
+func cleanTitle(title string) string {
+	title = strings.Join(strings.Fields(strings.ToValidUTF8(title, "")), " ")
+	if utf8.RuneCountInString(title) <= maxTitleLength {
+		return title
+	}
+	return string([]rune(title)[:maxTitleLength])
+}
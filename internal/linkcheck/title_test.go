@@ -0,0 +1,61 @@
+package linkcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchTitle(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html><head><title>\n  Fish &amp; Chips\n  Recipes </title></head><body></body></html>"))
+		case "/latin1":
+			w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+			w.Write([]byte("<title>Caf\xe9</title>"))
+		case "/svg":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><body><svg><title>Icon</title></svg></body></html>"))
+		case "/long":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<title>" + strings.Repeat("é", 300) + "</title>"))
+		case "/late":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head>" + strings.Repeat(" ", 2048) + "<title>Too far</title>"))
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"title":"no"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	f := NewTitleFetcher(time.Second, 1024, nil)
+	tests := []struct {
+		path  string
+		title string
+	}{
+		{"/page", "Fish & Chips Recipes"},
+		{"/latin1", "Café"},
+		{"/svg", ""},
+		{"/long", strings.Repeat("é", maxTitleLength)},
+		{"/late", ""},
+		{"/json", ""},
+	}
+	for _, tt := range tests {
+		title, err := f.FetchTitle(context.Background(), srv.URL+tt.path)
+		assert.NoError(t, err, tt.path)
+		assert.Equal(t, tt.title, title, tt.path)
+	}
+
+	_, err := f.FetchTitle(context.Background(), srv.URL+"/missing")
+	assert.ErrorContains(t, err, "HTTP 404")
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// ACCESS LOG
+// ============================================================================
+// AccessLog writes one JSON line per request instead of Gin's text logger:
+//
+//   {"time":"...","level":"INFO","msg":"request","method":"GET",
+//    "path":"/abc123","route":"/:short_code","status":302,"latency_ms":1.42,
+//    "bytes":0,"ip":"203.0.113.7","user_agent":"curl/8.5.0",
+//    "trace_id":"4bf92f...","sample_rate":0.01}
+//
+// Redirects can be most of the traffic, so the share of requests logged is
+// chosen per request (e.g. 1% of redirects, every API call). Server errors
+// are always logged. Sampled lines carry their sample_rate, so counts can be
+// scaled back up.
+// ============================================================================
+
+// AccessLog logs requests to w as JSON; sampleRate returns the fraction
+// (0-1) of requests like c that are logged
+func AccessLog(w io.Writer, sampleRate func(c *gin.Context) float64) gin.HandlerFunc {
+	logger := slog.New(slog.NewJSONHandler(w, nil))
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path // Before any handler rewrites it
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		rate := 1.0
+		if status >= 500 {
+			level = slog.LevelError
+		} else {
+			rate = sampleRate(c)
+			if rate < 1 && rand.Float64() >= rate {
+				return
+			}
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", max(c.Writer.Size(), 0)),
+			slog.String("ip", c.ClientIP()),
+			slog.String("user_agent", c.Request.UserAgent()),
+		}
+		if traceID := c.Writer.Header().Get("X-Trace-Id"); traceID != "" {
+			attrs = append(attrs, slog.String("trace_id", traceID))
+		}
+		if rate < 1 {
+			attrs = append(attrs, slog.Float64("sample_rate", rate))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.Last().Error()))
+		}
+		logger.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupAccessLog logs everything but /:code, which is logged at rate
+func setupAccessLog(out *bytes.Buffer, rate float64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(AccessLog(out, func(c *gin.Context) float64 {
+		if c.FullPath() == "/:code" {
+			return rate
+		}
+		return 1
+	}))
+	r.GET("/:code", func(c *gin.Context) {
+		if c.Param("code") == "broken" {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Redirect(http.StatusFound, "https://example.com")
+	})
+	r.GET("/api/info", func(c *gin.Context) { c.String(http.StatusOK, "hello") })
+	return r
+}
+
+func logLines(out *bytes.Buffer) []map[string]any {
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err == nil {
+			lines = append(lines, entry)
+		}
+	}
+	return lines
+}
+
+// TestAccessLog tests the fields of a logged request
+func TestAccessLog(t *testing.T) {
+	var out bytes.Buffer
+	r := setupAccessLog(&out, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/info?secret=1", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := logLines(&out)
+	require.Len(t, lines, 1)
+	entry := lines[0]
+	assert.Equal(t, "request", entry["msg"])
+	assert.Equal(t, "INFO", entry["level"])
+	assert.Equal(t, "GET", entry["method"])
+	assert.Equal(t, "/api/info", entry["path"], "query strings are left out")
+	assert.Equal(t, "/api/info", entry["route"])
+	assert.Equal(t, float64(http.StatusOK), entry["status"])
+	assert.Equal(t, float64(5), entry["bytes"])
+	assert.Equal(t, "test-agent", entry["user_agent"])
+	assert.Contains(t, entry, "latency_ms")
+	assert.Contains(t, entry, "ip")
+	assert.NotContains(t, entry, "sample_rate")
+}
+
+// TestAccessLogSampling tests that sampled routes are skipped but server
+// errors are always logged
+func TestAccessLogSampling(t *testing.T) {
+	var out bytes.Buffer
+	r := setupAccessLog(&out, 0)
+
+	for i := 0; i < 10; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abc", nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/info", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/broken", nil))
+
+	lines := logLines(&out)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "/api/info", lines[0]["path"])
+	assert.Equal(t, "/broken", lines[1]["path"])
+	assert.Equal(t, "ERROR", lines[1]["level"])
+
+	out.Reset()
+	r = setupAccessLog(&out, 0.5)
+	for i := 0; i < 1000; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abc", nil))
+	}
+	lines = logLines(&out)
+	assert.InDelta(t, 500, len(lines), 100)
+	assert.Equal(t, 0.5, lines[0]["sample_rate"])
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminTokenHeader is the request header carrying the admin token
+const AdminTokenHeader = "X-Admin-Token"
+
+// AdminAuth rejects requests that don't carry the configured admin token
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := c.GetHeader(AdminTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			abortWithError(c, http.StatusUnauthorized, "Invalid or missing admin token", "unauthorized")
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyQueryParam carries the API key on routes for clients that can't
+// set headers (see APIKeyAuth)
+const APIKeyQueryParam = "api_key"
+
+// APIKeyAuth identifies callers by API key: a request whose X-API-Key is
+// one of keys gets the mapped user ID under UserIDContextKey, and the key's
+// fingerprint under APIKeyIDContextKey
+// Other requests continue anonymously; endpoints that need a user reject them
+// Requests for which queryAllowed returns true may send the key as the
+// api_key query parameter instead; it is moved to X-API-Key so rate limits
+// and tiers see it too. queryAllowed may be nil.
+func APIKeyAuth(keys map[string]string, queryAllowed func(c *gin.Context) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := c.GetHeader(APIKeyHeader)
+		if provided == "" && queryAllowed != nil && queryAllowed(c) {
+			if provided = c.Query(APIKeyQueryParam); provided != "" {
+				c.Request.Header.Set(APIKeyHeader, provided)
+			}
+		}
+		if provided != "" {
+			for key, userID := range keys {
+				if subtle.ConstantTimeCompare([]byte(provided), []byte(key)) == 1 {
+					c.Set(UserIDContextKey, userID)
+					c.Set(APIKeyIDContextKey, APIKeyFingerprint(key))
+					break
+				}
+			}
+		}
+		c.Next()
+	}
+}
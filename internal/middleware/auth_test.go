@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAPIKeyAuth tests that known keys set the user ID and others stay anonymous
+func TestAPIKeyAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(APIKeyAuth(map[string]string{"key-alice": "alice"}, nil))
+	router.GET("/whoami", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(UserIDContextKey))
+	})
+
+	for _, tc := range []struct {
+		apiKey string
+		user   string
+	}{
+		{"key-alice", "alice"},
+		{"key-unknown", ""},
+		{"", ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		if tc.apiKey != "" {
+			req.Header.Set(APIKeyHeader, tc.apiKey)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, tc.user, w.Body.String(), tc.apiKey)
+	}
+}
+
+// TestAPIKeyAuthQuery tests that the api_key parameter is only honored where
+// allowed
+func TestAPIKeyAuthQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(APIKeyAuth(map[string]string{"key-alice": "alice"}, func(c *gin.Context) bool {
+		return c.FullPath() == "/open"
+	}))
+	whoami := func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(UserIDContextKey)+" "+c.GetHeader(APIKeyHeader))
+	}
+	router.GET("/open", whoami)
+	router.GET("/closed", whoami)
+
+	for path, want := range map[string]string{
+		"/open?api_key=key-alice":   "alice key-alice",
+		"/closed?api_key=key-alice": " ",
+		"/open?api_key=key-unknown": " key-unknown",
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, want, w.Body.String(), path)
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimit rejects request bodies larger than limit bytes with 413
+// Bodies with a Content-Length over the limit are rejected before the handler
+// runs; chunked bodies are cut off at the limit, so binding fails instead of
+// buffering an unbounded body. Requests for which skip returns true (e.g.
+// uploads with their own limit) are left alone; skip may be nil.
+func BodyLimit(limit int64, skip func(c *gin.Context) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || (skip != nil && skip(c)) {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			abortWithError(c, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("Request body is larger than %d bytes", limit), "request_too_large")
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
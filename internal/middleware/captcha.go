@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Where anonymous callers send their captcha token
+const (
+	CaptchaHeader     = "X-Captcha-Token"
+	CaptchaQueryParam = "captcha_token" // For GET /shorten
+)
+
+// CaptchaVerifier checks captcha tokens (see internal/captcha)
+type CaptchaVerifier interface {
+	Verify(ctx context.Context, token, remoteIP string) (bool, error)
+}
+
+// RequireCaptcha rejects anonymous requests (no user ID from APIKeyAuth)
+// without a valid captcha token with 403; callers with an API key skip it
+// If the provider can't be asked the request is rejected with 503 rather
+// than let through. Rejections aren't kept for Idempotency-Key retries.
+func RequireCaptcha(verifier CaptchaVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(UserIDContextKey) != "" {
+			c.Next()
+			return
+		}
+
+		token := c.GetHeader(CaptchaHeader)
+		if token == "" {
+			token = c.Query(CaptchaQueryParam)
+		}
+		if token == "" {
+			releaseIdempotencyKey(c)
+			abortWithError(c, http.StatusForbidden,
+				"A captcha token ("+CaptchaHeader+") or an API key is required", "captcha_required")
+			return
+		}
+
+		ok, err := verifier.Verify(c.Request.Context(), token, c.ClientIP())
+		if err != nil {
+			log.Printf("Warning: captcha provider unavailable: %v", err)
+			abortWithError(c, http.StatusServiceUnavailable, "Captcha verification is unavailable", "captcha_unavailable")
+			return
+		}
+		if !ok {
+			releaseIdempotencyKey(c)
+			abortWithError(c, http.StatusForbidden, "Captcha verification failed", "captcha_failed")
+			return
+		}
+		c.Next()
+	}
+}
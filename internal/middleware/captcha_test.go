@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCaptcha accepts the token "good" and fails with err when set
+type fakeCaptcha struct {
+	err   error
+	calls int
+}
+
+func (f *fakeCaptcha) Verify(ctx context.Context, token, remoteIP string) (bool, error) {
+	f.calls++
+	return token == "good", f.err
+}
+
+// TestRequireCaptcha tests that anonymous callers need a valid token
+func TestRequireCaptcha(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	verifier := &fakeCaptcha{}
+	router := gin.New()
+	router.Use(APIKeyAuth(map[string]string{"key-alice": "alice"}, nil))
+	router.Use(RequireCaptcha(verifier))
+	router.POST("/shorten", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	serve := func(path string, header map[string]string) int {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("/shorten", map[string]string{APIKeyHeader: "key-alice"}))
+	assert.Equal(t, 0, verifier.calls, "callers with an API key skip the captcha")
+	assert.Equal(t, http.StatusForbidden, serve("/shorten", nil))
+	assert.Equal(t, http.StatusForbidden, serve("/shorten", map[string]string{CaptchaHeader: "forged"}))
+	assert.Equal(t, http.StatusOK, serve("/shorten", map[string]string{CaptchaHeader: "good"}))
+	assert.Equal(t, http.StatusOK, serve("/shorten?captcha_token=good", nil))
+
+	verifier.err = errors.New("timeout")
+	assert.Equal(t, http.StatusServiceUnavailable, serve("/shorten", map[string]string{CaptchaHeader: "good"}))
+}
+
+// TestCaptchaIdempotency tests that a retry with the same Idempotency-Key
+// and a valid token isn't answered with the earlier captcha rejection
+func TestCaptchaIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	created := 0
+	router := gin.New()
+	router.POST("/api/v1/shorten", Idempotency(newMemoryIdempotencyStore(), time.Hour, time.Minute),
+		RequireCaptcha(&fakeCaptcha{}), func(c *gin.Context) {
+			created++
+			c.JSON(http.StatusOK, gin.H{"created": created})
+		})
+
+	serve := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/shorten", strings.NewReader(`{"url": "https://example.com"}`))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		if token != "" {
+			req.Header.Set(CaptchaHeader, token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusForbidden, serve("").Code)
+	assert.Equal(t, http.StatusForbidden, serve("forged").Code)
+	w := serve("good")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+
+	// Once created, retries are replayed without a fresh token
+	w = serve("")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "true", w.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 1, created)
+}
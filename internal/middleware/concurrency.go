@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// CONCURRENCY LIMITING
+// ============================================================================
+// Rate limits are per caller, so a spike spread over many IPs passes them
+// all and piles up on MySQL. ConcurrencyLimiter caps the requests one
+// instance handles at a time instead:
+//
+//   - a request takes a slot if one is free
+//   - otherwise it waits up to the queue timeout for one
+//   - if none frees up it gets 503 with Retry-After, without touching any
+//     dependency
+//
+// Health checks and metrics are never limited, so an overloaded instance
+// can still be probed and scraped.
+// ============================================================================
+
+// ConcurrencyLimiter bounds the requests in flight on this instance
+type ConcurrencyLimiter struct {
+	slots        chan struct{}
+	queueTimeout time.Duration
+
+	inFlight atomic.Int64
+	queued   atomic.Int64
+	rejected atomic.Uint64
+}
+
+// ConcurrencyStats is a snapshot of a ConcurrencyLimiter
+type ConcurrencyStats struct {
+	Limit    int
+	InFlight int64  // Requests holding a slot
+	Queued   int64  // Requests waiting for a slot
+	Rejected uint64 // Requests turned away since startup
+}
+
+// NewConcurrencyLimiter creates a limiter allowing max requests at a time;
+// requests wait up to queueTimeout for a slot (0 rejects them right away)
+func NewConcurrencyLimiter(max int, queueTimeout time.Duration) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		slots:        make(chan struct{}, max),
+		queueTimeout: queueTimeout,
+	}
+}
+
+// Middleware returns the Gin middleware enforcing the limit
+func (l *ConcurrencyLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if SkipHealthCheck(c) {
+			c.Next()
+			return
+		}
+		if !l.acquire(c) {
+			l.rejected.Add(1)
+			c.Header("Retry-After", "1")
+			abortWithError(c, http.StatusServiceUnavailable,
+				"Server is busy. Please try again later.", "overloaded")
+			return
+		}
+		defer l.release()
+		c.Next()
+	}
+}
+
+// acquire takes a slot, waiting up to the queue timeout or until the client
+// goes away; it reports whether a slot was taken
+func (l *ConcurrencyLimiter) acquire(c *gin.Context) bool {
+	select {
+	case l.slots <- struct{}{}:
+		l.inFlight.Add(1)
+		return true
+	default:
+	}
+	if l.queueTimeout <= 0 {
+		return false
+	}
+
+	l.queued.Add(1)
+	defer l.queued.Add(-1)
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		l.inFlight.Add(1)
+		return true
+	case <-timer.C:
+		return false
+	case <-c.Request.Context().Done():
+		return false
+	}
+}
+
+// release frees a slot
+func (l *ConcurrencyLimiter) release() {
+	l.inFlight.Add(-1)
+	<-l.slots
+}
+
+// Stats returns the limiter's current state
+func (l *ConcurrencyLimiter) Stats() ConcurrencyStats {
+	return ConcurrencyStats{
+		Limit:    cap(l.slots),
+		InFlight: l.inFlight.Load(),
+		Queued:   l.queued.Load(),
+		Rejected: l.rejected.Load(),
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupConcurrency serves /slow, which blocks until release is closed, and
+// /healthz
+func setupConcurrency(limiter *ConcurrencyLimiter) (*gin.Engine, chan struct{}, chan struct{}) {
+	gin.SetMode(gin.TestMode)
+	started, release := make(chan struct{}, 10), make(chan struct{})
+	r := gin.New()
+	r.Use(limiter.Middleware())
+	r.GET("/slow", func(c *gin.Context) {
+		started <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	})
+	r.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r, started, release
+}
+
+func serveConcurrent(r *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+// TestConcurrencyLimiter tests that requests over the limit wait, then get 503
+func TestConcurrencyLimiter(t *testing.T) {
+	limiter := NewConcurrencyLimiter(1, 20*time.Millisecond)
+	r, started, release := setupConcurrency(limiter)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		serveConcurrent(r, "/slow")
+	}()
+	<-started
+	assert.Equal(t, int64(1), limiter.Stats().InFlight)
+
+	w := serveConcurrent(r, "/slow")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "overloaded")
+	assert.Equal(t, uint64(1), limiter.Stats().Rejected)
+
+	// Health checks don't need a slot
+	assert.Equal(t, http.StatusOK, serveConcurrent(r, "/healthz").Code)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, ConcurrencyStats{Limit: 1, Rejected: 1}, limiter.Stats())
+}
+
+// TestConcurrencyLimiterQueue tests that a queued request gets the slot
+// freed while it waits
+func TestConcurrencyLimiterQueue(t *testing.T) {
+	limiter := NewConcurrencyLimiter(1, 5*time.Second)
+	r, started, release := setupConcurrency(limiter)
+
+	codes := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() { codes <- serveConcurrent(r, "/slow").Code }()
+	}
+	<-started
+	assert.Eventually(t, func() bool { return limiter.Stats().Queued == 1 }, time.Second, time.Millisecond)
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-codes)
+	assert.Equal(t, http.StatusOK, <-codes)
+	assert.Equal(t, uint64(0), limiter.Stats().Rejected)
+}
@@ -0,0 +1,263 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// ============================================================================
+// IDEMPOTENCY KEYS
+// ============================================================================
+// A client that sends Idempotency-Key on a creation request can retry it
+// safely: the first request with a key runs and its response is kept for a
+// while; retries with the same key get that response back, marked with
+// Idempotent-Replayed: true, instead of creating another link.
+//
+//   1. Reserve the key (SET NX) with the request's fingerprint, a hash of
+//      its method, path and body
+//   2. Run the handler and capture its response
+//   3. Store the response under the key for the idempotency window; 5xx
+//      responses, and rejections by checks that don't depend on the body
+//      (a missing captcha token), release the key instead so the retry
+//      runs again
+//
+// A retry that arrives while the first request is still running gets 409;
+// reusing a key for a different request (another body, path or API
+// version) gets 422. Keys are scoped to the caller's user ID, or shared by
+// anonymous callers; the fingerprint keeps one anonymous caller from being
+// handed another's response for a different request.
+//
+// If the store is unavailable the request runs without idempotency rather
+// than failing.
+// ============================================================================
+
+// IdempotencyKeyHeader carries the client's key for a request
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader marks responses replayed for a retried key
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// maxIdempotencyKeyLength bounds the keys clients may send
+const maxIdempotencyKeyLength = 255
+
+// idempotencyReleaseKey marks a request whose response must not be stored
+// (see releaseIdempotencyKey)
+const idempotencyReleaseKey = "idempotency_release"
+
+// releaseIdempotencyKey makes Idempotency release the request's key instead
+// of storing the response; middleware rejecting a request for something
+// outside the fingerprint (headers such as X-Captcha-Token) calls it, so
+// a retry that fixes it isn't answered with the rejection
+func releaseIdempotencyKey(c *gin.Context) {
+	c.Set(idempotencyReleaseKey, true)
+}
+
+// IdempotentRecord is what an IdempotencyStore keeps for a key: the request's
+// fingerprint and, once it completed, its response
+type IdempotentRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	Done        bool   `json:"done"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// IdempotencyStore keeps IdempotentRecords
+type IdempotencyStore interface {
+	// Reserve stores record under key for ttl unless key exists, in which
+	// case it returns the existing record
+	Reserve(ctx context.Context, key string, record IdempotentRecord, ttl time.Duration) (*IdempotentRecord, error)
+	// Save replaces the record under key, keeping it for ttl
+	Save(ctx context.Context, key string, record IdempotentRecord, ttl time.Duration) error
+	// Release deletes key
+	Release(ctx context.Context, key string) error
+}
+
+// Idempotency replays the stored response of requests retried with the same
+// Idempotency-Key; responses are kept for ttl, and a key stays reserved for
+// at most lockTTL while its first request runs
+func Idempotency(store IdempotencyStore, ttl, lockTTL time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientKey := c.GetHeader(IdempotencyKeyHeader)
+		if clientKey == "" {
+			c.Next()
+			return
+		}
+		if len(clientKey) > maxIdempotencyKeyLength {
+			abortWithError(c, http.StatusBadRequest,
+				"Idempotency-Key must be at most 255 characters", "invalid_idempotency_key")
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			// Body limits and client disconnects are the handler's to report
+			c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), errReader{err}))
+			c.Next()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		key := idempotencyStoreKey(c, clientKey)
+		fingerprint := requestFingerprint(c, body)
+		existing, err := store.Reserve(ctx, key, IdempotentRecord{Fingerprint: fingerprint}, lockTTL)
+		if err != nil {
+			log.Printf("Warning: idempotency store unavailable, running request without it: %v", err)
+			c.Next()
+			return
+		}
+		if existing != nil {
+			replay(c, existing, fingerprint)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Store with a context of its own; the request's may have timed out
+		storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+		defer cancel()
+		status := c.Writer.Status()
+		if status >= http.StatusInternalServerError || c.GetBool(idempotencyReleaseKey) {
+			err = store.Release(storeCtx, key)
+		} else {
+			err = store.Save(storeCtx, key, IdempotentRecord{
+				Fingerprint: fingerprint,
+				Done:        true,
+				Status:      status,
+				ContentType: c.Writer.Header().Get("Content-Type"),
+				Body:        recorder.body.Bytes(),
+			}, ttl)
+		}
+		if err != nil {
+			log.Printf("Warning: failed to store idempotent response: %v", err)
+		}
+	}
+}
+
+// replay answers a request whose key is already taken
+func replay(c *gin.Context, record *IdempotentRecord, fingerprint string) {
+	switch {
+	case record.Fingerprint != fingerprint:
+		abortWithError(c, http.StatusUnprocessableEntity,
+			"Idempotency-Key was already used for a different request", "idempotency_key_reused")
+	case !record.Done:
+		c.Header("Retry-After", "1")
+		abortWithError(c, http.StatusConflict,
+			"A request with this Idempotency-Key is still in progress", "idempotency_key_in_use")
+	default:
+		c.Header(IdempotentReplayedHeader, "true")
+		c.Data(record.Status, record.ContentType, record.Body)
+		c.Abort()
+	}
+}
+
+// idempotencyStoreKey scopes a client's key to its user
+func idempotencyStoreKey(c *gin.Context, clientKey string) string {
+	user := c.GetString(UserIDContextKey)
+	if user == "" {
+		user = "anonymous"
+	}
+	return "idempotency:" + user + ":" + clientKey
+}
+
+// requestFingerprint hashes what makes two requests the same request
+func requestFingerprint(c *gin.Context, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, c.Request.Method+" "+c.Request.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder copies the response body as it is written
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// errReader returns err once the buffered body has been read
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+// ============================================================================
+// REDIS IDEMPOTENCY STORE
+// ============================================================================
+
+// RedisIdempotencyStore implements IdempotencyStore using Redis
+type RedisIdempotencyStore struct {
+	client *redis.Client
+}
+
+// NewRedisIdempotencyStore creates a Redis-backed idempotency store
+func NewRedisIdempotencyStore(client *redis.Client) *RedisIdempotencyStore {
+	return &RedisIdempotencyStore{client: client}
+}
+
+// Reserve implements IdempotencyStore
+func (s *RedisIdempotencyStore) Reserve(ctx context.Context, key string, record IdempotentRecord, ttl time.Duration) (*IdempotentRecord, error) {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	// The existing record can expire between SETNX and GET; try again then
+	for attempt := 0; attempt < 2; attempt++ {
+		reserved, err := s.client.SetNX(ctx, key, value, ttl).Result()
+		if err != nil {
+			return nil, err
+		}
+		if reserved {
+			return nil, nil
+		}
+		raw, err := s.client.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var existing IdempotentRecord
+		if err := json.Unmarshal(raw, &existing); err != nil {
+			return nil, err
+		}
+		return &existing, nil
+	}
+	return nil, errors.New("idempotency key changed while reserving it")
+}
+
+// Save implements IdempotencyStore
+func (s *RedisIdempotencyStore) Save(ctx context.Context, key string, record IdempotentRecord, ttl time.Duration) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
+
+// Release implements IdempotencyStore
+func (s *RedisIdempotencyStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, key).Err()
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryIdempotencyStore is an in-memory IdempotencyStore; TTLs are ignored
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]IdempotentRecord
+	err     error
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: make(map[string]IdempotentRecord)}
+}
+
+func (s *memoryIdempotencyStore) Reserve(_ context.Context, key string, record IdempotentRecord, _ time.Duration) (*IdempotentRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	if existing, ok := s.records[key]; ok {
+		return &existing, nil
+	}
+	s.records[key] = record
+	return nil, nil
+}
+
+func (s *memoryIdempotencyStore) Save(_ context.Context, key string, record IdempotentRecord, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = record
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// setupIdempotency serves POST /api/v1/shorten, which answers with the
+// number of links created so far, or 500 while failing is set
+func setupIdempotency(store IdempotencyStore) (*gin.Engine, *int, *bool) {
+	gin.SetMode(gin.TestMode)
+	created, failing := 0, false
+	r := gin.New()
+	r.POST("/api/v1/shorten", Idempotency(store, time.Hour, time.Minute), func(c *gin.Context) {
+		if failing {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500})
+			return
+		}
+		created++
+		c.JSON(http.StatusOK, gin.H{"created": created})
+	})
+	return r, &created, &failing
+}
+
+// shorten sends a creation request with key and body
+func shorten(r *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/shorten", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// TestIdempotencyReplay tests that retries get the first response back
+func TestIdempotencyReplay(t *testing.T) {
+	r, created, _ := setupIdempotency(newMemoryIdempotencyStore())
+	body := `{"url": "https://example.com"}`
+
+	first := shorten(r, "key-1", body)
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+
+	retry := shorten(r, "key-1", body)
+	assert.Equal(t, http.StatusOK, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Contains(t, retry.Header().Get("Content-Type"), "application/json")
+	assert.Equal(t, 1, *created)
+
+	// Another key, or none, creates again
+	shorten(r, "key-2", body)
+	shorten(r, "", body)
+	assert.Equal(t, 3, *created)
+}
+
+// TestIdempotencyConflicts tests keys reused for other requests or while
+// their first request runs
+func TestIdempotencyConflicts(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	r, created, _ := setupIdempotency(store)
+
+	shorten(r, "key-1", `{"url": "https://example.com"}`)
+	w := shorten(r, "key-1", `{"url": "https://example.org"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "idempotency_key_reused")
+
+	// A reservation without a response is a request still running
+	body := `{"url": "https://example.net"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/shorten", nil)
+	fingerprint := requestFingerprint(&gin.Context{Request: req}, []byte(body))
+	store.records["idempotency:anonymous:key-2"] = IdempotentRecord{Fingerprint: fingerprint}
+	w = shorten(r, "key-2", body)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	w = shorten(r, strings.Repeat("k", 256), body)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, 1, *created)
+}
+
+// TestIdempotencyServerErrors tests that failed requests can be retried and
+// that an unavailable store doesn't block creation
+func TestIdempotencyServerErrors(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	r, created, failing := setupIdempotency(store)
+	body := `{"url": "https://example.com"}`
+
+	*failing = true
+	assert.Equal(t, http.StatusInternalServerError, shorten(r, "key-1", body).Code)
+	*failing = false
+	w := shorten(r, "key-1", body)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 1, *created)
+
+	store.err = errors.New("redis down")
+	assert.Equal(t, http.StatusOK, shorten(r, "key-2", body).Code)
+	assert.Equal(t, http.StatusOK, shorten(r, "key-2", body).Code)
+	assert.Equal(t, 3, *created)
+}
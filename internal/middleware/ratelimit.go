@@ -2,9 +2,14 @@ package middleware
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"math"
+	"math/rand"
 	"net/http"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,10 +19,11 @@ import (
 // ============================================================================
 // RATE LIMITING MIDDLEWARE - EDUCATIONAL IMPLEMENTATION
 // ============================================================================
-// This middleware demonstrates three popular rate limiting algorithms:
+// This middleware demonstrates four popular rate limiting algorithms:
 // 1. Fixed Window Counter - Simple but has burst issues at window boundaries
 // 2. Sliding Window Log - Precise but memory intensive
 // 3. Token Bucket - Allows controlled bursts, most flexible
+// 4. Sliding Window Counter - Approximate sliding window with O(1) memory
 // ============================================================================
 
 // RateLimitStrategy defines the rate limiting algorithm to use
@@ -38,6 +44,24 @@ const (
 	// Pros: Allows controlled bursts, smooth rate limiting
 	// Cons: Slightly more complex logic
 	TokenBucket RateLimitStrategy = "token_bucket"
+
+	// SlidingWindowCounter weights the previous fixed window's count
+	// Pros: Near sliding-window accuracy, O(1) memory per key
+	// Cons: Approximate (assumes evenly spread traffic in the previous window)
+	SlidingWindowCounter RateLimitStrategy = "sliding_window_counter"
+)
+
+// FailureMode defines what happens when the rate limit backend is unavailable
+type FailureMode string
+
+const (
+	// FailOpen lets requests through when Redis errors
+	// If a local fallback is enabled, it is consulted instead
+	FailOpen FailureMode = "open"
+
+	// FailClosed rejects requests with 503 when Redis errors
+	// Use it for sensitive endpoints where abuse is worse than downtime
+	FailClosed FailureMode = "closed"
 )
 
 // RateLimitConfig holds configuration for the rate limiter
@@ -51,28 +75,124 @@ type RateLimitConfig struct {
 	// Window is the time period for the limit (e.g., 1 minute)
 	Window time.Duration
 
+	// Burst is the token bucket's capacity, the requests a caller can make
+	// at once after being idle; tokens still refill at Limit per Window
+	// 0 uses Limit. Other strategies ignore it.
+	Burst int
+
 	// KeyFunc generates the rate limit key (default: IP-based)
 	KeyFunc func(*gin.Context) string
 
+	// Scope namespaces keys so limiters sharing a store (e.g., a global
+	// limiter and an endpoint limiter) don't count each other's requests
+	Scope string
+
 	// ErrorHandler is called when rate limit is exceeded
 	ErrorHandler func(*gin.Context)
 
 	// SkipFunc determines if rate limiting should be skipped for this request
 	SkipFunc func(*gin.Context) bool
+
+	// FailureMode controls behavior when Redis errors (default: FailOpen)
+	FailureMode FailureMode
+
+	// LocalFallback enables a MemoryStore that takes over while
+	// Redis is unavailable (only used with FailOpen)
+	LocalFallback bool
+
+	// UnavailableHandler is called when failing closed
+	UnavailableHandler func(*gin.Context)
+
+	// TierFunc resolves the tier of the caller (e.g., "free", "pro")
+	// An empty tier or one missing from Tiers uses Limit and Window
+	TierFunc func(*gin.Context) string
+
+	// Tiers overrides Limit and Window per tier name
+	Tiers map[string]RateLimitTier
+
+	// StandardHeaders also sends the IETF RateLimit-* headers (see
+	// setStandardHeaders)
+	StandardHeaders bool
+}
+
+// RateLimitTier holds the limits for one tier of callers
+type RateLimitTier struct {
+	Limit  int
+	Window time.Duration
+	Burst  int
+}
+
+// bucketCapacity returns the token bucket capacity: Burst, or Limit if unset
+func (c *RateLimitConfig) bucketCapacity() int {
+	if c.Burst > 0 {
+		return c.Burst
+	}
+	return c.Limit
 }
 
-// RateLimiter manages rate limiting using Redis
+// stateTTL is how long a key's state is kept after its last request: 2x
+// the window, or for a token bucket bigger than Limit the time it takes to
+// refill, so an expired bucket is never fuller than a kept one would be
+func (c *RateLimitConfig) stateTTL() time.Duration {
+	ttl := c.Window * 2
+	if c.Strategy == TokenBucket && c.Limit > 0 {
+		if refill := c.Window * time.Duration(c.bucketCapacity()) / time.Duration(c.Limit); refill > ttl {
+			ttl = refill
+		}
+	}
+	return ttl
+}
+
+// Store is the storage backend that performs the check-and-update for a
+// request. Implementations must be safe for concurrent use.
+type Store interface {
+	// Check records a request for key and reports whether it is allowed
+	// Returns: (allowed bool, remaining int, resetTime int64, error)
+	Check(ctx context.Context, key string, config *RateLimitConfig) (bool, int, int64, error)
+}
+
+// RateLimiter manages rate limiting on top of a Store
+// Its configuration can be swapped at runtime with Reload
 type RateLimiter struct {
-	redis  *redis.Client
-	config *RateLimitConfig
+	store Store
+	state atomic.Pointer[limiterState]
+}
+
+// limiterState is the immutable configuration snapshot used by requests
+type limiterState struct {
+	config   *RateLimitConfig
+	fallback Store
+
+	// tierConfigs are copies of config with each tier's limits applied
+	tierConfigs map[string]*RateLimitConfig
 }
 
-// NewRateLimiter creates a new rate limiter instance
+// NewRateLimiter creates a new rate limiter instance backed by Redis
 func NewRateLimiter(redisClient *redis.Client, config *RateLimitConfig) *RateLimiter {
+	return NewRateLimiterWithStore(NewRedisStore(redisClient), config)
+}
+
+// NewRateLimiterWithStore creates a new rate limiter instance with a custom store
+// A nil config creates a disabled limiter that can be enabled later with Reload
+func NewRateLimiterWithStore(store Store, config *RateLimitConfig) *RateLimiter {
+	rl := &RateLimiter{store: store}
+	rl.Reload(config)
+	return rl
+}
+
+// Reload atomically replaces the limiter configuration
+// In-flight requests finish with the old configuration; a nil config
+// disables the limiter until the next Reload
+func (rl *RateLimiter) Reload(config *RateLimitConfig) {
+	if config == nil {
+		rl.state.Store(nil)
+		return
+	}
+
 	// Set default key function (based on client IP)
 	if config.KeyFunc == nil {
 		config.KeyFunc = func(c *gin.Context) string {
-			return fmt.Sprintf("rate_limit:%s:%s", c.ClientIP(), c.Request.URL.Path)
+			return fmt.Sprintf("rate_limit:%s:%s", c.ClientIP(), CanonicalAPIPath(c.Request.URL.Path))
 		}
 	}
 
@@ -88,20 +208,53 @@ func NewRateLimiter(redisClient *redis.Client, config *RateLimitConfig) *RateLim
 		}
 	}
 
-	return &RateLimiter{
-		redis:  redisClient,
-		config: config,
+	// Set default failure mode (fail open)
+	if config.FailureMode == "" {
+		config.FailureMode = FailOpen
 	}
+
+	// Set default unavailable handler
+	if config.UnavailableHandler == nil {
+		config.UnavailableHandler = defaultUnavailableHandler
+	}
+
+	state := &limiterState{
+		config:      config,
+		tierConfigs: make(map[string]*RateLimitConfig, len(config.Tiers)),
+	}
+
+	for name, tier := range config.Tiers {
+		tierConfig := *config
+		tierConfig.Limit = tier.Limit
+		tierConfig.Window = tier.Window
+		tierConfig.Burst = tier.Burst
+		state.tierConfigs[name] = &tierConfig
+	}
+
+	// A memory store can't fail, so it never needs a fallback
+	if _, isMemory := rl.store.(*MemoryStore); config.LocalFallback && !isMemory {
+		state.fallback = NewMemoryStore()
+	}
+
+	rl.state.Store(state)
 }
 
 // Middleware returns a Gin middleware function
 // This is the main entry point that will be used in router.Use()
 func (rl *RateLimiter) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Snapshot the configuration so a concurrent Reload can't change it
+		// halfway through the request
+		state := rl.state.Load()
+		if state == nil {
+			c.Next() // Limiter is disabled
+			return
+		}
+
 		// ====================================================================
 		// STEP 1: Check if we should skip rate limiting for this request
 		// ====================================================================
-		if rl.config.SkipFunc(c) {
+		if state.config.SkipFunc(c) {
 			c.Next() // Continue to the next handler without rate limiting
 			return
 		}
@@ -110,31 +263,53 @@ func (rl *RateLimiter) Middleware() gin.HandlerFunc {
 		// STEP 2: Generate a unique key for this client/endpoint combination
 		// ====================================================================
 		// Example key: "rate_limit:192.168.1.100:/api/v1/shorten"
-		key := rl.config.KeyFunc(c)
+		key := state.config.KeyFunc(c)
+		if state.config.Scope != "" {
+			key += ":" + state.config.Scope
+		}
+
+		// Resolve per-tier limits (e.g., free vs pro API keys)
+		config := state.configFor(c)
 
 		// ====================================================================
 		// STEP 3: Check rate limit based on configured strategy
 		// ====================================================================
-		allowed, remaining, resetTime, err := rl.checkRateLimit(c.Request.Context(), key)
+		allowed, remaining, resetTime, err := rl.store.Check(c.Request.Context(), key, config)
 
 		// ====================================================================
-		// STEP 4: Handle Redis errors gracefully (fail open)
+		// STEP 4: Handle Redis errors according to the failure mode
 		// ====================================================================
-		// If Redis is down, we allow the request to prevent total service outage
+		// Fail closed: reject the request rather than risk abuse
+		// Fail open: use the local fallback if enabled, otherwise allow
 		if err != nil {
-			// Log the error (in production, use proper logger)
-			fmt.Printf("Rate limiter error: %v (failing open)\n", err)
-			c.Next()
-			return
+			if config.FailureMode == FailClosed {
+				// Log the error (in production, use proper logger)
+				fmt.Printf("Rate limiter error: %v (failing closed)\n", err)
+				config.UnavailableHandler(c)
+				c.Abort()
+				return
+			}
+
+			if state.fallback == nil {
+				fmt.Printf("Rate limiter error: %v (failing open)\n", err)
+				c.Next()
+				return
+			}
+
+			fmt.Printf("Rate limiter error: %v (using local fallback)\n", err)
+			allowed, remaining, resetTime, _ = state.fallback.Check(c.Request.Context(), key, config)
 		}
 
 		// ====================================================================
 		// STEP 5: Set rate limit headers (RFC 6585 compliant)
 		// ====================================================================
 		// These headers inform the client about their rate limit status
-		c.Header("X-RateLimit-Limit", strconv.Itoa(rl.config.Limit))
+		c.Header("X-RateLimit-Limit", strconv.Itoa(config.Limit))
 		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
 		c.Header("X-RateLimit-Reset", strconv.FormatInt(resetTime, 10))
+		if config.StandardHeaders {
+			setStandardHeaders(c, config, remaining, resetTime)
+		}
 
 		// ====================================================================
 		// STEP 6: Either allow the request or return 429 Too Many Requests
@@ -148,7 +323,7 @@ func (rl *RateLimiter) Middleware() gin.HandlerFunc {
 			c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
 
 			// Call custom error handler
-			rl.config.ErrorHandler(c)
+			config.ErrorHandler(c)
 
 			// Abort prevents calling subsequent handlers
 			c.Abort()
@@ -162,18 +337,80 @@ func (rl *RateLimiter) Middleware() gin.HandlerFunc {
 	}
 }
 
-// checkRateLimit implements the actual rate limiting logic
+// setStandardHeaders sets the headers of the IETF RateLimit header fields
+// draft (draft-ietf-httpapi-ratelimit-headers-07):
+//
+//	RateLimit-Limit: 10             quota of the policy closest to running out
+//	RateLimit-Remaining: 3          requests left in it
+//	RateLimit-Reset: 42             seconds until it resets (not a timestamp)
+//	RateLimit-Policy: 100;w=60, 10;w=60
+//
+// A request can pass several limiters (global, then endpoint); each adds
+// its policy to RateLimit-Policy and the one with the fewest requests left
+// reports the other three.
+func setStandardHeaders(c *gin.Context, config *RateLimitConfig, remaining int, resetTime int64) {
+	header := c.Writer.Header()
+	policy := fmt.Sprintf("%d;w=%d", config.Limit, int64(config.Window.Seconds()))
+	if existing := header.Get("RateLimit-Policy"); existing != "" {
+		policy = existing + ", " + policy
+	}
+	header.Set("RateLimit-Policy", policy)
+
+	if existing, err := strconv.Atoi(header.Get("RateLimit-Remaining")); err == nil && existing <= remaining {
+		return
+	}
+	reset := resetTime - time.Now().Unix()
+	if reset < 0 {
+		reset = 0
+	}
+	header.Set("RateLimit-Limit", strconv.Itoa(config.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+	header.Set("RateLimit-Reset", strconv.FormatInt(reset, 10))
+}
+
+// configFor returns the config to apply to this request, taking the
+// caller's tier into account
+func (s *limiterState) configFor(c *gin.Context) *RateLimitConfig {
+	if s.config.TierFunc == nil {
+		return s.config
+	}
+	if tierConfig, ok := s.tierConfigs[s.config.TierFunc(c)]; ok {
+		return tierConfig
+	}
+	return s.config
+}
+
+// ============================================================================
+// REDIS STORE
+// ============================================================================
+// RedisStore keeps rate limit state in Redis so limits are shared across
+// every instance of the service. Each algorithm runs as a Lua script.
+// ============================================================================
+
+// RedisStore implements Store using Redis
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore creates a Redis-backed rate limit store
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Check implements the actual rate limiting logic
 // Returns: (allowed bool, remaining int, resetTime int64, error)
-func (rl *RateLimiter) checkRateLimit(ctx context.Context, key string) (bool, int, int64, error) {
-	switch rl.config.Strategy {
+func (s *RedisStore) Check(ctx context.Context, key string, config *RateLimitConfig) (bool, int, int64, error) {
+	switch config.Strategy {
 	case FixedWindow:
-		return rl.fixedWindowCheck(ctx, key)
+		return s.fixedWindowCheck(ctx, key, config)
 	case SlidingWindow:
-		return rl.slidingWindowCheck(ctx, key)
+		return s.slidingWindowCheck(ctx, key, config)
 	case TokenBucket:
-		return rl.tokenBucketCheck(ctx, key)
+		return s.tokenBucketCheck(ctx, key, config)
+	case SlidingWindowCounter:
+		return s.slidingWindowCounterCheck(ctx, key, config)
 	default:
-		return rl.fixedWindowCheck(ctx, key)
+		return s.fixedWindowCheck(ctx, key, config)
 	}
 }
 
@@ -197,40 +434,43 @@ func (rl *RateLimiter) checkRateLimit(ctx context.Context, key string) (bool, in
 // 10:01:00 - 5 requests ✅ (window reset)
 // → User sent 10 requests in 1 second!
 // ============================================================================
-func (rl *RateLimiter) fixedWindowCheck(ctx context.Context, key string) (bool, int, int64, error) {
+
+// fixedWindowScript increments the window counter and sets its TTL in one step
+// KEYS[1] = window key, ARGV[1] = TTL in milliseconds
+// Returns the counter value after the increment
+var fixedWindowScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+  redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`)
+
+func (s *RedisStore) fixedWindowCheck(ctx context.Context, key string, config *RateLimitConfig) (bool, int, int64, error) {
 	// Calculate current window start time
 	now := time.Now()
-	windowStart := now.Truncate(rl.config.Window).Unix()
+	windowStart := now.Truncate(config.Window).Unix()
 
 	// Redis key includes the window timestamp
 	// Example: "rate_limit:192.168.1.100:/api/v1/shorten:1696780800"
 	windowKey := fmt.Sprintf("%s:%d", key, windowStart)
 
-	// Use Redis pipeline for atomic operations
-	pipe := rl.redis.Pipeline()
-
-	// INCR command: atomically increment the counter
-	incrCmd := pipe.Incr(ctx, windowKey)
-
-	// Set expiration to prevent memory leak
+	// Run INCR + PEXPIRE atomically inside Redis
 	// TTL = 2x window to handle clock skew
-	pipe.Expire(ctx, windowKey, rl.config.Window*2)
-
-	// Execute pipeline
-	_, err := pipe.Exec(ctx)
+	count, err := fixedWindowScript.Run(ctx, s.client,
+		[]string{windowKey},
+		(config.Window * 2).Milliseconds(),
+	).Int()
 	if err != nil {
 		return false, 0, 0, err
 	}
 
-	// Get the current count
-	count := int(incrCmd.Val())
-
 	// Calculate when the window resets
-	resetTime := windowStart + int64(rl.config.Window.Seconds())
+	resetTime := windowStart + int64(config.Window.Seconds())
 
 	// Check if limit exceeded
-	allowed := count <= rl.config.Limit
-	remaining := rl.config.Limit - count
+	allowed := count <= config.Limit
+	remaining := config.Limit - count
 	if remaining < 0 {
 		remaining = 0
 	}
@@ -262,44 +502,64 @@ func (rl *RateLimiter) fixedWindowCheck(ctx context.Context, key string) (bool,
 // Pros: Precise, no boundary issues
 // Cons: Memory usage O(limit) per key
 // ============================================================================
-func (rl *RateLimiter) slidingWindowCheck(ctx context.Context, key string) (bool, int, int64, error) {
+
+// slidingWindowScript trims expired entries, then records the request only if
+// the window still has room, so rejected requests don't extend the penalty
+// KEYS[1] = sorted set key
+// ARGV[1] = now (ns), ARGV[2] = window start (ns), ARGV[3] = limit,
+// ARGV[4] = unique member, ARGV[5] = TTL in milliseconds
+// Returns {allowed (0/1), count, oldest score (ns) as string}
+var slidingWindowScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '0', ARGV[2])
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+if count < tonumber(ARGV[3]) then
+  redis.call('ZADD', KEYS[1], ARGV[1], ARGV[4])
+  count = count + 1
+  allowed = 1
+end
+redis.call('PEXPIRE', KEYS[1], ARGV[5])
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+local oldestScore = ARGV[1]
+if oldest[2] then
+  oldestScore = oldest[2]
+end
+return {allowed, count, oldestScore}
+`)
+
+func (s *RedisStore) slidingWindowCheck(ctx context.Context, key string, config *RateLimitConfig) (bool, int, int64, error) {
 	now := time.Now()
-	windowStart := now.Add(-rl.config.Window).UnixNano()
+	windowStart := now.Add(-config.Window).UnixNano()
 	nowNano := now.UnixNano()
 
-	pipe := rl.redis.Pipeline()
-
-	// Remove timestamps older than the window
-	// ZREMRANGEBYSCORE key -inf (now - window)
-	pipe.ZRemRangeByScore(ctx, key, "0", strconv.FormatInt(windowStart, 10))
-
-	// Add current request timestamp
-	// ZADD key timestamp timestamp
-	pipe.ZAdd(ctx, key, redis.Z{
-		Score:  float64(nowNano),
-		Member: nowNano, // Use timestamp as member for uniqueness
-	})
-
-	// Count total requests in the window
-	// ZCARD key
-	zcardCmd := pipe.ZCard(ctx, key)
-
-	// Set expiration
-	pipe.Expire(ctx, key, rl.config.Window*2)
-
-	// Execute pipeline
-	_, err := pipe.Exec(ctx)
+	// Member must be unique even when two requests share a nanosecond
+	member := fmt.Sprintf("%d-%d", nowNano, rand.Int63())
+
+	res, err := slidingWindowScript.Run(ctx, s.client,
+		[]string{key},
+		nowNano,
+		windowStart,
+		config.Limit,
+		member,
+		(config.Window * 2).Milliseconds(),
+	).Slice()
 	if err != nil {
 		return false, 0, 0, err
 	}
+	if len(res) != 3 {
+		return false, 0, 0, fmt.Errorf("unexpected sliding window script result: %v", res)
+	}
 
-	count := int(zcardCmd.Val())
+	allowed := toInt64(res[0]) == 1
+	count := int(toInt64(res[1]))
 
-	// Calculate reset time (when oldest request expires)
-	resetTime := now.Add(rl.config.Window).Unix()
+	// Reset time is when the oldest request in the window expires
+	resetTime := now.Add(config.Window).Unix()
+	if oldest, err := strconv.ParseFloat(fmt.Sprint(res[2]), 64); err == nil {
+		resetTime = time.Unix(0, int64(oldest)).Add(config.Window).Unix()
+	}
 
-	allowed := count <= rl.config.Limit
-	remaining := rl.config.Limit - count
+	remaining := config.Limit - count
 	if remaining < 0 {
 		remaining = 0
 	}
@@ -311,7 +571,7 @@ func (rl *RateLimiter) slidingWindowCheck(ctx context.Context, key string) (bool
 // ALGORITHM 3: TOKEN BUCKET
 // ============================================================================
 // How it works:
-// - Bucket has a capacity of tokens (= limit)
+// - Bucket has a capacity of tokens (= burst, or limit if burst is unset)
 // - Tokens refill at a constant rate (limit / window)
 // - Each request consumes 1 token
 // - If no tokens available, request is rejected
@@ -324,74 +584,171 @@ func (rl *RateLimiter) slidingWindowCheck(ctx context.Context, key string) (bool
 // 10s     6.5     Refilled 1.33 tokens
 // 10s     0.5     6 requests → exceeded by 5.5 (reject)
 //
+// With burst=50, limit=10, window=60s an idle caller can make 50 requests
+// at once but sustains only 10 a minute; without burst both are 10.
+//
 // Pros: Allows bursts up to capacity, smooth refilling
 // Cons: More complex logic
 // ============================================================================
-func (rl *RateLimiter) tokenBucketCheck(ctx context.Context, key string) (bool, int, int64, error) {
+
+// tokenBucketScript refills and consumes a token in one step so concurrent
+// requests can never spend the same token twice
+// KEYS[1] = bucket hash key (fields: tokens, last_refill)
+// ARGV[1] = capacity, ARGV[2] = refill rate (tokens/ms), ARGV[3] = now (ms),
+// ARGV[4] = TTL in milliseconds
+// Returns {allowed (0/1), tokens left as string}
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last_refill')
+local tokens = tonumber(state[1])
+local lastRefill = tonumber(state[2])
+if tokens == nil then
+  tokens = capacity
+end
+if lastRefill == nil then
+  lastRefill = now
+end
+
+local elapsed = math.max(0, now - lastRefill)
+tokens = math.min(capacity, tokens + elapsed * rate)
+
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last_refill', tostring(now))
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return {allowed, tostring(tokens)}
+`)
+
+func (s *RedisStore) tokenBucketCheck(ctx context.Context, key string, config *RateLimitConfig) (bool, int, int64, error) {
 	now := time.Now()
 
-	// Token bucket uses two Redis keys:
-	tokensKey := key + ":tokens"         // Current token count
-	lastRefillKey := key + ":last_refill" // Last refill timestamp
+	// Bucket state lives in a single hash so the script touches one key
+	bucketKey := key + ":bucket"
 
 	// Refill rate: tokens per second
-	refillRate := float64(rl.config.Limit) / rl.config.Window.Seconds()
-
-	// Get current state
-	pipe := rl.redis.Pipeline()
-	getTokensCmd := pipe.Get(ctx, tokensKey)
-	getLastRefillCmd := pipe.Get(ctx, lastRefillKey)
-	_, _ = pipe.Exec(ctx)
-
-	// Parse current tokens (default to full capacity)
-	tokens := float64(rl.config.Limit)
-	if getTokensCmd.Err() == nil {
-		if val, err := strconv.ParseFloat(getTokensCmd.Val(), 64); err == nil {
-			tokens = val
-		}
+	refillRate := float64(config.Limit) / config.Window.Seconds()
+
+	res, err := tokenBucketScript.Run(ctx, s.client,
+		[]string{bucketKey},
+		config.bucketCapacity(),
+		strconv.FormatFloat(refillRate/1000, 'f', -1, 64),
+		now.UnixMilli(),
+		config.stateTTL().Milliseconds(),
+	).Slice()
+	if err != nil {
+		return false, 0, 0, err
 	}
-
-	// Parse last refill time (default to now)
-	lastRefill := now.Unix()
-	if getLastRefillCmd.Err() == nil {
-		if val, err := strconv.ParseInt(getLastRefillCmd.Val(), 10, 64); err == nil {
-			lastRefill = val
-		}
+	if len(res) != 2 {
+		return false, 0, 0, fmt.Errorf("unexpected token bucket script result: %v", res)
 	}
 
-	// Calculate tokens to add based on time elapsed
-	elapsed := now.Unix() - lastRefill
-	tokensToAdd := float64(elapsed) * refillRate
+	allowed := toInt64(res[0]) == 1
+	tokens, err := strconv.ParseFloat(fmt.Sprint(res[1]), 64)
+	if err != nil {
+		return false, 0, 0, fmt.Errorf("invalid token count %v: %w", res[1], err)
+	}
 
-	// Refill tokens (capped at limit)
-	tokens += tokensToAdd
-	if tokens > float64(rl.config.Limit) {
-		tokens = float64(rl.config.Limit)
+	// Calculate reset time (when bucket refills to 1 token)
+	resetTime := now.Unix()
+	if tokens < 1.0 {
+		secondsUntilRefill := int64(math.Ceil((1.0 - tokens) / refillRate))
+		resetTime += secondsUntilRefill
 	}
 
-	// Try to consume 1 token
-	allowed := tokens >= 1.0
-	if allowed {
-		tokens -= 1.0
+	remaining := int(tokens)
+	if remaining < 0 {
+		remaining = 0
 	}
 
-	// Update Redis
-	pipe = rl.redis.Pipeline()
-	pipe.Set(ctx, tokensKey, fmt.Sprintf("%.2f", tokens), rl.config.Window*2)
-	pipe.Set(ctx, lastRefillKey, now.Unix(), rl.config.Window*2)
-	_, err := pipe.Exec(ctx)
+	return allowed, remaining, resetTime, nil
+}
+
+// ============================================================================
+// ALGORITHM 4: SLIDING WINDOW COUNTER (APPROXIMATE)
+// ============================================================================
+// How it works:
+// - Keep one fixed window counter for the current window and one for the
+//   previous window
+// - Estimate the sliding count by weighting the previous window by how much
+//   of it still overlaps the sliding window
+// - estimated = previous * (1 - elapsed/window) + current
+//
+// Example (limit=10, window=60s):
+// Previous window (10:00-10:01): 8 requests
+// Current window  (10:01-10:02): 3 requests so far
+// Now = 10:01:15 → 25% into the current window, 75% overlap with previous
+// estimated = 8 * 0.75 + 3 = 9 → 1 more request allowed
+//
+// Pros: O(1) memory per key (two counters), no boundary burst
+// Cons: Approximate - assumes requests in the previous window were evenly spread
+// ============================================================================
+
+// slidingWindowCounterScript estimates the sliding count from two fixed
+// window counters and only increments the current one when allowed
+// KEYS[1] = previous window key, KEYS[2] = current window key
+// ARGV[1] = limit, ARGV[2] = previous window weight (0-1),
+// ARGV[3] = TTL in milliseconds
+// Returns {allowed (0/1), estimated count as string}
+var slidingWindowCounterScript = redis.NewScript(`
+local previous = tonumber(redis.call('GET', KEYS[1]) or '0')
+local current = tonumber(redis.call('GET', KEYS[2]) or '0')
+local limit = tonumber(ARGV[1])
+local weight = tonumber(ARGV[2])
+
+local estimated = previous * weight + current
+local allowed = 0
+if estimated + 1 <= limit then
+  redis.call('INCR', KEYS[2])
+  redis.call('PEXPIRE', KEYS[2], ARGV[3])
+  estimated = estimated + 1
+  allowed = 1
+end
+return {allowed, tostring(estimated)}
+`)
+
+func (s *RedisStore) slidingWindowCounterCheck(ctx context.Context, key string, config *RateLimitConfig) (bool, int, int64, error) {
+	now := time.Now()
+	currentStart := now.Truncate(config.Window)
+	previousStart := currentStart.Add(-config.Window)
+
+	// Weight of the previous window = fraction of it still inside the sliding window
+	elapsed := now.Sub(currentStart)
+	weight := 1 - float64(elapsed)/float64(config.Window)
+
+	// Example: "rate_limit:192.168.1.100:/api/v1/shorten:swc:1696780800000"
+	previousKey := fmt.Sprintf("%s:swc:%d", key, previousStart.UnixMilli())
+	currentKey := fmt.Sprintf("%s:swc:%d", key, currentStart.UnixMilli())
+
+	res, err := slidingWindowCounterScript.Run(ctx, s.client,
+		[]string{previousKey, currentKey},
+		config.Limit,
+		strconv.FormatFloat(weight, 'f', -1, 64),
+		(config.Window * 2).Milliseconds(),
+	).Slice()
 	if err != nil {
 		return false, 0, 0, err
 	}
+	if len(res) != 2 {
+		return false, 0, 0, fmt.Errorf("unexpected sliding window counter script result: %v", res)
+	}
 
-	// Calculate reset time (when bucket refills to 1 token)
-	resetTime := now.Unix()
-	if tokens < 1.0 {
-		secondsUntilRefill := int64((1.0 - tokens) / refillRate)
-		resetTime += secondsUntilRefill
+	allowed := toInt64(res[0]) == 1
+	estimated, err := strconv.ParseFloat(fmt.Sprint(res[1]), 64)
+	if err != nil {
+		return false, 0, 0, fmt.Errorf("invalid estimated count %v: %w", res[1], err)
 	}
 
-	remaining := int(tokens)
+	// The previous window's weight reaches zero at the end of the current window
+	resetTime := currentStart.Add(config.Window).Unix()
+
+	remaining := config.Limit - int(math.Ceil(estimated))
 	if remaining < 0 {
 		remaining = 0
 	}
@@ -399,16 +756,30 @@ func (rl *RateLimiter) tokenBucketCheck(ctx context.Context, key string) (bool,
 	return allowed, remaining, resetTime, nil
 }
 
+// toInt64 converts an integer reply from a Lua script to int64
+func toInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case string:
+		i, _ := strconv.ParseInt(n, 10, 64)
+		return i
+	default:
+		return 0
+	}
+}
+
 // ============================================================================
 // DEFAULT ERROR HANDLER
 // ============================================================================
 // Returns a standard 429 Too Many Requests response
 func defaultErrorHandler(c *gin.Context) {
-	c.JSON(http.StatusTooManyRequests, gin.H{
-		"code":    http.StatusTooManyRequests,
-		"message": "Rate limit exceeded. Please try again later.",
-		"error":   "too_many_requests",
-	})
+	writeError(c, http.StatusTooManyRequests, "Rate limit exceeded. Please try again later.", "too_many_requests")
+}
+
+// defaultUnavailableHandler returns 503 when failing closed
+func defaultUnavailableHandler(c *gin.Context) {
+	writeError(c, http.StatusServiceUnavailable, "Rate limiter unavailable. Please try again later.", "service_unavailable")
 }
 
 // ============================================================================
@@ -427,10 +798,65 @@ func PathBasedKey(c *gin.Context) string {
 
 // IPAndPathKey generates a rate limit key based on both IP and path (default)
 func IPAndPathKey(c *gin.Context) string {
-	return fmt.Sprintf("rate_limit:%s:%s", c.ClientIP(), c.Request.URL.Path)
+	return fmt.Sprintf("rate_limit:%s:%s", c.ClientIP(), CanonicalAPIPath(c.Request.URL.Path))
 }
 
-// SkipHealthCheck skips rate limiting for health check endpoints
+// APIKeyHeader is the request header carrying the client's API key
+const APIKeyHeader = "X-API-Key"
+
+// UserIDContextKey is the Gin context key under which authentication
+// middleware stores the authenticated user ID
+const UserIDContextKey = "user_id"
+
+// APIKeyIDContextKey is the Gin context key under which APIKeyAuth stores
+// the fingerprint of the configured key a request authenticated with
+const APIKeyIDContextKey = "api_key_id"
+
+// APIKeyBasedKey generates a rate limit key based on the API key and path
+// Only keys APIKeyAuth accepted count; requests with a missing or unknown
+// key fall back to IP and path, so made-up keys don't get fresh buckets
+// The key is hashed so raw API keys never end up in Redis
+func APIKeyBasedKey(c *gin.Context) string {
+	keyID := c.GetString(APIKeyIDContextKey)
+	if keyID == "" {
+		return IPAndPathKey(c)
+	}
+	return fmt.Sprintf("rate_limit:apikey:%s:%s", keyID, CanonicalAPIPath(c.Request.URL.Path))
+}
+
+// UserBasedKey generates a rate limit key based on the authenticated user ID and path
+// Falls back to IP and path for unauthenticated requests
+// Unlike IP-based keys, users behind a shared NAT each get their own bucket
+func UserBasedKey(c *gin.Context) string {
+	userID := c.GetString(UserIDContextKey)
+	if userID == "" {
+		return IPAndPathKey(c)
+	}
+	return fmt.Sprintf("rate_limit:user:%s:%s", userID, CanonicalAPIPath(c.Request.URL.Path))
+}
+
+// APIKeyTierFunc returns a TierFunc that looks up the caller's API key in a
+// static key→tier map (e.g., loaded from config)
+// Unknown or missing keys resolve to the default tier
+func APIKeyTierFunc(apiKeyTiers map[string]string) func(*gin.Context) string {
+	return func(c *gin.Context) string {
+		return apiKeyTiers[c.GetHeader(APIKeyHeader)]
+	}
+}
+
+// APIKeyFingerprint returns a short, stable fingerprint of an API key
+// Used wherever a caller must be identified without storing the raw key
+func APIKeyFingerprint(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:8])
+}
+
+// SkipHealthCheck skips rate limiting for health check endpoints, and for
+// the robots.txt and favicon requests crawlers and browsers make on their own
 func SkipHealthCheck(c *gin.Context) bool {
-	return c.Request.URL.Path == "/health" || c.Request.URL.Path == "/metrics"
+	switch c.Request.URL.Path {
+	case "/health", "/healthz", "/readyz", "/metrics", "/robots.txt", "/favicon.ico":
+		return true
+	}
+	return false
 }
@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// MEMORY STORE
+// ============================================================================
+// MemoryStore keeps rate limit state in process memory. It implements the
+// same four algorithms as RedisStore without any external dependency, which
+// makes it a good fit for:
+// - Single-instance deployments
+// - Tests that don't have Redis available
+// - A local fallback while Redis is unreachable
+//
+// ⚠️ Limits are per process: N instances allow up to N times the limit.
+// ============================================================================
+
+// memorySweepInterval is how often expired entries are removed
+const memorySweepInterval = time.Minute
+
+// MemoryStore implements Store using in-process maps
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+
+	// now returns the current time (overridable in tests)
+	now func() time.Time
+}
+
+// memoryEntry holds the state for one key across all strategies
+type memoryEntry struct {
+	// Fixed window / sliding window counter
+	windowStart time.Time
+	count       int
+	prevCount   int
+
+	// Sliding window log (request timestamps, oldest first)
+	timestamps []time.Time
+
+	// Token bucket
+	tokens     float64
+	lastRefill time.Time
+
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates an in-memory rate limit store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]*memoryEntry),
+		now:     time.Now,
+	}
+}
+
+// Check implements Store
+// Returns: (allowed bool, remaining int, resetTime int64, error)
+func (m *MemoryStore) Check(ctx context.Context, key string, config *RateLimitConfig) (bool, int, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	// Keys are namespaced by strategy so two limiters with different
+	// strategies never share state
+	entryKey := string(config.Strategy) + ":" + key
+	entry, ok := m.entries[entryKey]
+	if !ok {
+		entry = &memoryEntry{}
+		m.entries[entryKey] = entry
+	}
+	// Mirror the Redis TTL
+	entry.expiresAt = now.Add(config.stateTTL())
+
+	var allowed bool
+	var remaining int
+	var resetTime int64
+
+	switch config.Strategy {
+	case SlidingWindow:
+		allowed, remaining, resetTime = m.slidingWindow(entry, now, config)
+	case TokenBucket:
+		allowed, remaining, resetTime = m.tokenBucket(entry, now, config)
+	case SlidingWindowCounter:
+		allowed, remaining, resetTime = m.slidingWindowCounter(entry, now, config)
+	default:
+		allowed, remaining, resetTime = m.fixedWindow(entry, now, config)
+	}
+
+	if remaining < 0 {
+		remaining = 0
+	}
+	return allowed, remaining, resetTime, nil
+}
+
+// fixedWindow counts every request in the current fixed window
+func (m *MemoryStore) fixedWindow(e *memoryEntry, now time.Time, config *RateLimitConfig) (bool, int, int64) {
+	windowStart := now.Truncate(config.Window)
+	if !e.windowStart.Equal(windowStart) {
+		e.windowStart = windowStart
+		e.count = 0
+	}
+	e.count++
+
+	resetTime := windowStart.Add(config.Window).Unix()
+	return e.count <= config.Limit, config.Limit - e.count, resetTime
+}
+
+// slidingWindow keeps a log of accepted request timestamps
+func (m *MemoryStore) slidingWindow(e *memoryEntry, now time.Time, config *RateLimitConfig) (bool, int, int64) {
+	cutoff := now.Add(-config.Window)
+	i := 0
+	for i < len(e.timestamps) && !e.timestamps[i].After(cutoff) {
+		i++
+	}
+	e.timestamps = e.timestamps[i:]
+
+	allowed := len(e.timestamps) < config.Limit
+	if allowed {
+		e.timestamps = append(e.timestamps, now)
+	}
+
+	resetTime := now.Add(config.Window).Unix()
+	if len(e.timestamps) > 0 {
+		resetTime = e.timestamps[0].Add(config.Window).Unix()
+	}
+	return allowed, config.Limit - len(e.timestamps), resetTime
+}
+
+// tokenBucket refills tokens continuously and consumes one per request
+func (m *MemoryStore) tokenBucket(e *memoryEntry, now time.Time, config *RateLimitConfig) (bool, int, int64) {
+	capacity := float64(config.bucketCapacity())
+	refillRate := float64(config.Limit) / config.Window.Seconds()
+
+	if e.lastRefill.IsZero() {
+		e.tokens = capacity
+		e.lastRefill = now
+	}
+
+	elapsed := now.Sub(e.lastRefill).Seconds()
+	if elapsed > 0 {
+		e.tokens = math.Min(capacity, e.tokens+elapsed*refillRate)
+	}
+	e.lastRefill = now
+
+	allowed := e.tokens >= 1.0
+	if allowed {
+		e.tokens -= 1.0
+	}
+
+	resetTime := now.Unix()
+	if e.tokens < 1.0 {
+		resetTime += int64(math.Ceil((1.0 - e.tokens) / refillRate))
+	}
+	return allowed, int(e.tokens), resetTime
+}
+
+// slidingWindowCounter weights the previous window's count by its overlap
+func (m *MemoryStore) slidingWindowCounter(e *memoryEntry, now time.Time, config *RateLimitConfig) (bool, int, int64) {
+	currentStart := now.Truncate(config.Window)
+	switch {
+	case e.windowStart.Equal(currentStart):
+		// Same window, nothing to roll over
+	case e.windowStart.Equal(currentStart.Add(-config.Window)):
+		e.prevCount = e.count
+		e.count = 0
+	default:
+		e.prevCount = 0
+		e.count = 0
+	}
+	e.windowStart = currentStart
+
+	weight := 1 - float64(now.Sub(currentStart))/float64(config.Window)
+	estimated := float64(e.prevCount)*weight + float64(e.count)
+
+	allowed := estimated+1 <= float64(config.Limit)
+	if allowed {
+		e.count++
+		estimated++
+	}
+
+	resetTime := currentStart.Add(config.Window).Unix()
+	return allowed, config.Limit - int(math.Ceil(estimated)), resetTime
+}
+
+// sweep drops expired entries at most once per memorySweepInterval
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < memorySweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for key, entry := range m.entries {
+		if now.After(entry.expiresAt) {
+			delete(m.entries, key)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupMemoryRouter creates a router backed by a MemoryStore with a fake clock
+func setupMemoryRouter(config *RateLimitConfig) (*MemoryStore, *time.Time, func() int) {
+	store := NewMemoryStore()
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	router := setupTestRouter(NewRateLimiterWithStore(store, config))
+	send := func() int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	return store, &now, send
+}
+
+// TestMemoryFixedWindow tests the fixed window algorithm in memory
+func TestMemoryFixedWindow(t *testing.T) {
+	_, now, send := setupMemoryRouter(&RateLimitConfig{
+		Strategy: FixedWindow,
+		Limit:    3,
+		Window:   time.Minute,
+	})
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, send(), "Request %d should succeed", i+1)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, send())
+
+	// Next window resets the counter
+	*now = now.Add(time.Minute)
+	assert.Equal(t, http.StatusOK, send())
+}
+
+// TestMemorySlidingWindow tests the sliding window log algorithm in memory
+func TestMemorySlidingWindow(t *testing.T) {
+	_, now, send := setupMemoryRouter(&RateLimitConfig{
+		Strategy: SlidingWindow,
+		Limit:    2,
+		Window:   10 * time.Second,
+	})
+
+	assert.Equal(t, http.StatusOK, send())
+	*now = now.Add(5 * time.Second)
+	assert.Equal(t, http.StatusOK, send())
+	assert.Equal(t, http.StatusTooManyRequests, send())
+
+	// First request leaves the window, second is still inside
+	*now = now.Add(6 * time.Second)
+	assert.Equal(t, http.StatusOK, send())
+	assert.Equal(t, http.StatusTooManyRequests, send())
+}
+
+// TestMemoryTokenBucket tests the token bucket algorithm in memory
+func TestMemoryTokenBucket(t *testing.T) {
+	_, now, send := setupMemoryRouter(&RateLimitConfig{
+		Strategy: TokenBucket,
+		Limit:    5,
+		Window:   5 * time.Second, // Refill rate: 1 token/second
+	})
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, send(), "Request %d should succeed", i+1)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, send())
+
+	*now = now.Add(time.Second)
+	assert.Equal(t, http.StatusOK, send())
+	assert.Equal(t, http.StatusTooManyRequests, send())
+}
+
+// TestMemoryTokenBucketBurst tests a bucket bigger than its refill rate
+func TestMemoryTokenBucketBurst(t *testing.T) {
+	_, now, send := setupMemoryRouter(&RateLimitConfig{
+		Strategy: TokenBucket,
+		Limit:    10,
+		Window:   time.Minute, // Refill rate: 1 token every 6 seconds
+		Burst:    50,
+	})
+
+	for i := 0; i < 50; i++ {
+		assert.Equal(t, http.StatusOK, send(), "Request %d should succeed", i+1)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, send())
+
+	// Sustained rate stays at the limit
+	*now = now.Add(6 * time.Second)
+	assert.Equal(t, http.StatusOK, send())
+	assert.Equal(t, http.StatusTooManyRequests, send())
+
+	// An idle bucket refills to the burst, not beyond; the state outlives
+	// 2x window while it refills
+	*now = now.Add(3 * time.Minute)
+	for i := 0; i < 30; i++ {
+		assert.Equal(t, http.StatusOK, send(), "Request %d should succeed", i+1)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, send())
+}
+
+// TestMemorySlidingWindowCounter tests the sliding window counter algorithm in memory
+func TestMemorySlidingWindowCounter(t *testing.T) {
+	_, now, send := setupMemoryRouter(&RateLimitConfig{
+		Strategy: SlidingWindowCounter,
+		Limit:    4,
+		Window:   time.Minute,
+	})
+
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, http.StatusOK, send(), "Request %d should succeed", i+1)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, send())
+
+	// 30s into the next window: estimated = 4 * 0.5 + 0 = 2 → 2 more allowed
+	*now = now.Add(90 * time.Second)
+	assert.Equal(t, http.StatusOK, send())
+	assert.Equal(t, http.StatusOK, send())
+	assert.Equal(t, http.StatusTooManyRequests, send())
+}
+
+// TestMemoryStoreSweep tests that expired entries are removed
+func TestMemoryStoreSweep(t *testing.T) {
+	store, now, send := setupMemoryRouter(&RateLimitConfig{
+		Strategy: FixedWindow,
+		Limit:    10,
+		Window:   time.Second,
+	})
+
+	send()
+	assert.Len(t, store.entries, 1)
+
+	*now = now.Add(2 * memorySweepInterval)
+	store.mu.Lock()
+	store.sweep(*now)
+	store.mu.Unlock()
+	assert.Empty(t, store.entries)
+}
+
+// TestAPIKeyTiers tests per-API-key keys and tier limits; only keys
+// APIKeyAuth accepts get their own bucket
+func TestAPIKeyTiers(t *testing.T) {
+	limiter := NewRateLimiterWithStore(NewMemoryStore(), &RateLimitConfig{
+		Strategy: FixedWindow,
+		Limit:    1,
+		Window:   time.Minute,
+		KeyFunc:  APIKeyBasedKey,
+		TierFunc: APIKeyTierFunc(map[string]string{"pro-key": "pro"}),
+		Tiers: map[string]RateLimitTier{
+			"pro": {Limit: 3, Window: time.Minute},
+		},
+	})
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(APIKeyAuth(map[string]string{"pro-key": "alice", "other-key": "bob"}, nil), limiter.Middleware())
+	router.GET("/test", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/test", nil)
+		if apiKey != "" {
+			req.Header.Set(APIKeyHeader, apiKey)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Pro key gets the tier limit
+	for i := 0; i < 3; i++ {
+		w := send("pro-key")
+		assert.Equal(t, http.StatusOK, w.Code, "Request %d should succeed", i+1)
+		assert.Equal(t, "3", w.Header().Get("X-RateLimit-Limit"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, send("pro-key").Code)
+
+	// Unknown key has its own bucket with the default limit
+	assert.Equal(t, http.StatusOK, send("other-key").Code)
+	assert.Equal(t, http.StatusTooManyRequests, send("other-key").Code)
+
+	// Anonymous requests fall back to the IP bucket
+	assert.Equal(t, http.StatusOK, send("").Code)
+	assert.Equal(t, http.StatusTooManyRequests, send("").Code)
+
+	// So do made-up keys, rather than getting a fresh bucket each
+	assert.Equal(t, http.StatusTooManyRequests, send("made-up-1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, send("made-up-2").Code)
+}
+
+// TestUserBasedKey tests that authenticated users get separate buckets
+func TestUserBasedKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/test", nil)
+
+	assert.Equal(t, IPAndPathKey(c), UserBasedKey(c))
+
+	c.Set(UserIDContextKey, "42")
+	assert.Equal(t, "rate_limit:user:42:/test", UserBasedKey(c))
+}
+
+// TestReload tests swapping limiter configuration at runtime
+func TestReload(t *testing.T) {
+	limiter := NewRateLimiterWithStore(NewMemoryStore(), nil)
+	router := setupTestRouter(limiter)
+
+	send := func() int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Disabled limiter lets everything through
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, send())
+	}
+
+	// Tighten the limit
+	limiter.Reload(&RateLimitConfig{
+		Strategy: FixedWindow,
+		Limit:    1,
+		Window:   time.Minute,
+	})
+	assert.Equal(t, http.StatusOK, send())
+	assert.Equal(t, http.StatusTooManyRequests, send())
+
+	// Disable again
+	limiter.Reload(nil)
+	assert.Equal(t, http.StatusOK, send())
+}
+
+// TestStandardHeaders tests the IETF RateLimit-* headers of chained limiters
+func TestStandardHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := NewMemoryStore()
+	global := NewRateLimiterWithStore(store, &RateLimitConfig{
+		Strategy: FixedWindow, Limit: 100, Window: time.Minute, Scope: "global", StandardHeaders: true,
+	})
+	endpoint := NewRateLimiterWithStore(store, &RateLimitConfig{
+		Strategy: FixedWindow, Limit: 2, Window: 10 * time.Second, Scope: "endpoint", StandardHeaders: true,
+	})
+	plain := NewRateLimiterWithStore(store, &RateLimitConfig{
+		Strategy: FixedWindow, Limit: 5, Window: time.Minute, Scope: "plain",
+	})
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/limited", global.Middleware(), endpoint.Middleware(), ok)
+	router.GET("/plain", plain.Middleware(), ok)
+
+	send := func(path string) http.Header {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Header()
+	}
+
+	h := send("/limited")
+	assert.Equal(t, "100;w=60, 2;w=10", h.Get("RateLimit-Policy"))
+	// The endpoint limit is closest to running out
+	assert.Equal(t, "2", h.Get("RateLimit-Limit"))
+	assert.Equal(t, "1", h.Get("RateLimit-Remaining"))
+	reset, err := strconv.Atoi(h.Get("RateLimit-Reset"))
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, reset, 10, "reset is in seconds, not a timestamp")
+	assert.Equal(t, "2", h.Get("X-RateLimit-Limit"))
+
+	h = send("/plain")
+	assert.Equal(t, "5", h.Get("X-RateLimit-Limit"))
+	assert.Empty(t, h.Get("RateLimit-Limit"))
+	assert.Empty(t, h.Get("RateLimit-Policy"))
+}
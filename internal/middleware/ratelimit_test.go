@@ -171,6 +171,46 @@ func TestTokenBucketStrategy(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+// TestSlidingWindowCounterStrategy tests the approximate sliding window algorithm
+func TestSlidingWindowCounterStrategy(t *testing.T) {
+	redisClient := setupTestRedis(t)
+	defer redisClient.Close()
+
+	limiter := NewRateLimiter(redisClient, &RateLimitConfig{
+		Strategy: SlidingWindowCounter,
+		Limit:    3,
+		Window:   2 * time.Second,
+	})
+
+	router := setupTestRouter(limiter)
+
+	// Send 3 requests (should all succeed)
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code, "Request %d should succeed", i+1)
+	}
+
+	// 4th request should be rate limited
+	req := httptest.NewRequest("GET", "/test", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+
+	// After two full windows the previous counter no longer contributes
+	time.Sleep(4 * time.Second)
+
+	req = httptest.NewRequest("GET", "/test", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
 // TestCustomKeyFunc tests custom key generation
 func TestCustomKeyFunc(t *testing.T) {
 	redisClient := setupTestRedis(t)
@@ -317,6 +357,87 @@ func TestConcurrentRequests(t *testing.T) {
 	}
 }
 
+// setupUnavailableRedis returns a client pointing at a port nothing listens on
+func setupUnavailableRedis() *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:        "localhost:1",
+		DialTimeout: 100 * time.Millisecond,
+		MaxRetries:  -1,
+	})
+}
+
+// TestFailOpen tests that requests pass through when Redis is down
+func TestFailOpen(t *testing.T) {
+	redisClient := setupUnavailableRedis()
+	defer redisClient.Close()
+
+	limiter := NewRateLimiter(redisClient, &RateLimitConfig{
+		Strategy: FixedWindow,
+		Limit:    1,
+		Window:   10 * time.Second,
+	})
+
+	router := setupTestRouter(limiter)
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, "Request %d should fail open", i+1)
+	}
+}
+
+// TestFailClosed tests that requests are rejected when Redis is down
+func TestFailClosed(t *testing.T) {
+	redisClient := setupUnavailableRedis()
+	defer redisClient.Close()
+
+	limiter := NewRateLimiter(redisClient, &RateLimitConfig{
+		Strategy:      FixedWindow,
+		Limit:         10,
+		Window:        10 * time.Second,
+		FailureMode:   FailClosed,
+		LocalFallback: true, // Ignored when failing closed
+	})
+
+	router := setupTestRouter(limiter)
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+// TestLocalFallback tests that the in-memory limiter takes over when Redis is down
+func TestLocalFallback(t *testing.T) {
+	redisClient := setupUnavailableRedis()
+	defer redisClient.Close()
+
+	limiter := NewRateLimiter(redisClient, &RateLimitConfig{
+		Strategy:      SlidingWindow,
+		Limit:         2,
+		Window:        10 * time.Second,
+		LocalFallback: true,
+	})
+
+	router := setupTestRouter(limiter)
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, "Request %d should succeed", i+1)
+	}
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+}
+
 // BenchmarkFixedWindow benchmarks the fixed window algorithm
 func BenchmarkFixedWindow(b *testing.B) {
 	redisClient := setupTestRedis(&testing.T{})
@@ -359,6 +480,27 @@ func BenchmarkSlidingWindow(b *testing.B) {
 	}
 }
 
+// BenchmarkSlidingWindowCounter benchmarks the sliding window counter algorithm
+func BenchmarkSlidingWindowCounter(b *testing.B) {
+	redisClient := setupTestRedis(&testing.T{})
+	defer redisClient.Close()
+
+	limiter := NewRateLimiter(redisClient, &RateLimitConfig{
+		Strategy: SlidingWindowCounter,
+		Limit:    1000000,
+		Window:   60 * time.Second,
+	})
+
+	router := setupTestRouter(limiter)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+	}
+}
+
 // BenchmarkTokenBucket benchmarks the token bucket algorithm
 func BenchmarkTokenBucket(b *testing.B) {
 	redisClient := setupTestRedis(&testing.T{})
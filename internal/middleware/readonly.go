@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadOnly rejects requests that would write (anything but GET, HEAD and
+// OPTIONS) with 503 while writable returns false, e.g. during a database
+// outage. Reads such as redirects keep being served.
+func ReadOnly(writable func() bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		if !writable() {
+			c.Header("Retry-After", "30")
+			abortWithError(c, http.StatusServiceUnavailable,
+				"Service is read-only while the database is unavailable", "read_only")
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReadOnly tests that only writes are rejected while not writable
+func TestReadOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	writable := true
+	r := gin.New()
+	r.Use(ReadOnly(func() bool { return writable }))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/abc", ok)
+	r.POST("/api/v1/shorten", ok)
+
+	serve := func(method, path string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("GET", "/abc"))
+	assert.Equal(t, http.StatusOK, serve("POST", "/api/v1/shorten"))
+
+	writable = false
+	assert.Equal(t, http.StatusOK, serve("GET", "/abc"))
+	assert.Equal(t, http.StatusServiceUnavailable, serve("POST", "/api/v1/shorten"))
+}
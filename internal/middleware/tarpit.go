@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// ============================================================================
+// ENUMERATION TARPIT
+// ============================================================================
+// Scrapers walk the short code space to find unlisted links; almost all of
+// their requests miss. Rate limits count every request alike, so instead
+// the redirect routes count each client's 404s:
+// - past Threshold misses in Window, every request of the client waits
+//   BaseDelay, doubled per further miss up to MaxDelay
+// - at BanAfter misses the client is banned for BanDuration: every code,
+//   existing or not, answers it with the same 404, so the scan learns
+//   nothing (a honeypot rather than a visible block)
+// Ordinary visitors mistyping a code never get near the threshold. Counts
+// and bans live in Redis, so they hold across instances; if Redis fails,
+// requests go through unchecked.
+//
+// Mount the tarpit before the ConcurrencyLimiter: a delayed request then
+// waits without holding a slot, so a scanner can't starve real redirects.
+// ============================================================================
+
+// TarpitConfig holds the enumeration tarpit's limits
+type TarpitConfig struct {
+	Threshold   int           // Misses in Window before requests are delayed
+	Window      time.Duration // Misses are counted from the first one in the window
+	BaseDelay   time.Duration // Delay at Threshold+1 misses, doubled per further miss
+	MaxDelay    time.Duration
+	BanAfter    int // Misses in Window that ban the client; 0 never bans
+	BanDuration time.Duration
+}
+
+// delay returns how long a client with misses recent misses waits
+func (c *TarpitConfig) delay(misses int) time.Duration {
+	over := misses - c.Threshold
+	if over <= 0 {
+		return 0
+	}
+	delay := c.BaseDelay
+	for i := 1; i < over && delay < c.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, c.MaxDelay)
+}
+
+// ScanStore keeps short code misses and bans per client
+type ScanStore interface {
+	// Status returns the client's misses in the current window and the time
+	// left on its ban (0 if not banned)
+	Status(ctx context.Context, client string) (misses int, banned time.Duration, err error)
+	// RecordMiss counts a miss; the count starts over after window, and at
+	// banAfter misses (unless 0) the client is banned for banFor
+	RecordMiss(ctx context.Context, client string, window time.Duration, banAfter int, banFor time.Duration) error
+}
+
+// Tarpit slows down and bans clients that request many unknown short
+// codes (see above); requests for which skip returns true (everything but
+// redirects) are left alone
+func Tarpit(store ScanStore, config TarpitConfig, skip func(c *gin.Context) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if skip != nil && skip(c) {
+			c.Next()
+			return
+		}
+		ctx := c.Request.Context()
+		client := c.ClientIP()
+		misses, banned, err := store.Status(ctx, client)
+		if err != nil {
+			log.Printf("Warning: tarpit store unavailable: %v", err)
+			c.Next()
+			return
+		}
+		if banned > 0 {
+			// Looks like any other miss
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"code":    http.StatusNotFound,
+				"message": "Short URL not found or expired",
+			})
+			return
+		}
+		if delay := config.delay(misses); delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+
+		if c.Writer.Status() == http.StatusNotFound {
+			if err := store.RecordMiss(ctx, client, config.Window, config.BanAfter, config.BanDuration); err != nil &&
+				!errors.Is(err, context.Canceled) {
+				log.Printf("Warning: failed to record short code miss: %v", err)
+			}
+		}
+	}
+}
+
+// Redis keys of the tarpit, followed by the client IP
+const (
+	scanMissPrefix = "short:scan:miss:"
+	scanBanPrefix  = "short:scan:ban:"
+)
+
+// recordMissScript counts a miss and bans the client at the limit
+// KEYS[1] = miss counter, KEYS[2] = ban key
+// ARGV[1] = window in ms, ARGV[2] = misses that ban (0 never),
+// ARGV[3] = ban duration in ms
+var recordMissScript = redis.NewScript(`
+local misses = redis.call('INCR', KEYS[1])
+if misses == 1 then
+  redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+local banAfter = tonumber(ARGV[2])
+if banAfter > 0 and misses >= banAfter then
+  redis.call('SET', KEYS[2], '1', 'PX', ARGV[3])
+  redis.call('DEL', KEYS[1])
+end
+return misses
+`)
+
+// RedisScanStore implements ScanStore using Redis
+type RedisScanStore struct {
+	client *redis.Client
+}
+
+// NewRedisScanStore creates a Redis-backed scan store
+func NewRedisScanStore(client *redis.Client) *RedisScanStore {
+	return &RedisScanStore{client: client}
+}
+
+// Status implements ScanStore
+func (s *RedisScanStore) Status(ctx context.Context, client string) (int, time.Duration, error) {
+	pipe := s.client.Pipeline()
+	missesCmd := pipe.Get(ctx, scanMissPrefix+client)
+	banCmd := pipe.PTTL(ctx, scanBanPrefix+client)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return 0, 0, err
+	}
+
+	misses := 0
+	if raw, err := missesCmd.Result(); err == nil {
+		misses, _ = strconv.Atoi(raw)
+	}
+	banned := banCmd.Val()
+	if banned < 0 { // -2: no ban, -1: no expiry (never set that way)
+		banned = 0
+	}
+	return misses, banned, nil
+}
+
+// RecordMiss implements ScanStore
+func (s *RedisScanStore) RecordMiss(ctx context.Context, client string, window time.Duration, banAfter int, banFor time.Duration) error {
+	return recordMissScript.Run(ctx, s.client, []string{scanMissPrefix + client, scanBanPrefix + client},
+		window.Milliseconds(), banAfter, banFor.Milliseconds()).Err()
+}
@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryScanStore is a ScanStore whose windows and bans never end
+type memoryScanStore struct {
+	mu     sync.Mutex
+	misses map[string]int
+	banned map[string]bool
+}
+
+func newMemoryScanStore() *memoryScanStore {
+	return &memoryScanStore{misses: map[string]int{}, banned: map[string]bool{}}
+}
+
+func (s *memoryScanStore) Status(ctx context.Context, client string) (int, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.banned[client] {
+		return 0, time.Hour, nil
+	}
+	return s.misses[client], 0, nil
+}
+
+func (s *memoryScanStore) RecordMiss(ctx context.Context, client string, window time.Duration, banAfter int, banFor time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.misses[client]++
+	if banAfter > 0 && s.misses[client] >= banAfter {
+		s.banned[client] = true
+		delete(s.misses, client)
+	}
+	return nil
+}
+
+// TestTarpitDelay tests the delay growth
+func TestTarpitDelay(t *testing.T) {
+	config := TarpitConfig{Threshold: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	assert.Equal(t, time.Duration(0), config.delay(10))
+	assert.Equal(t, 100*time.Millisecond, config.delay(11))
+	assert.Equal(t, 200*time.Millisecond, config.delay(12))
+	assert.Equal(t, 800*time.Millisecond, config.delay(14))
+	assert.Equal(t, time.Second, config.delay(15))
+	assert.Equal(t, time.Second, config.delay(1000))
+}
+
+// TestTarpit tests that misses slow down and then ban a client, and that
+// banned clients get 404 for existing codes too
+func TestTarpit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := newMemoryScanStore()
+	router := gin.New()
+	router.Use(Tarpit(store, TarpitConfig{
+		Threshold: 2, Window: time.Minute,
+		BaseDelay: 20 * time.Millisecond, MaxDelay: 20 * time.Millisecond,
+		BanAfter: 4, BanDuration: time.Hour,
+	}, nil))
+	router.GET("/:short_code", func(c *gin.Context) {
+		if c.Param("short_code") == "exists" {
+			c.Redirect(http.StatusFound, "https://example.com")
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"code": http.StatusNotFound, "message": "Short URL not found or expired"})
+	})
+
+	serve := func(code, ip string) (int, time.Duration) {
+		req := httptest.NewRequest(http.MethodGet, "/"+code, nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		start := time.Now()
+		router.ServeHTTP(w, req)
+		return w.Code, time.Since(start)
+	}
+
+	for i := 0; i < 3; i++ {
+		status, took := serve("miss", "203.0.113.7")
+		assert.Equal(t, http.StatusNotFound, status)
+		assert.Less(t, took, 20*time.Millisecond)
+	}
+	status, took := serve("exists", "203.0.113.7")
+	assert.Equal(t, http.StatusFound, status)
+	assert.GreaterOrEqual(t, took, 20*time.Millisecond, "past the threshold every request waits")
+
+	serve("miss", "203.0.113.7")
+	require.True(t, store.banned["203.0.113.7"])
+	status, _ = serve("exists", "203.0.113.7")
+	assert.Equal(t, http.StatusNotFound, status, "banned clients find nothing")
+
+	status, took = serve("exists", "198.51.100.1")
+	assert.Equal(t, http.StatusFound, status, "other clients are unaffected")
+	assert.Less(t, took, 20*time.Millisecond)
+}
+
+// TestTarpitBeforeConcurrencyLimit tests that a delayed scanner doesn't
+// hold a concurrency slot while it waits, and that skipped routes aren't
+// delayed
+func TestTarpitBeforeConcurrencyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := newMemoryScanStore()
+	store.misses["203.0.113.7"] = 5
+	router := gin.New()
+	router.Use(Tarpit(store, TarpitConfig{
+		Threshold: 2, Window: time.Minute, BaseDelay: 300 * time.Millisecond, MaxDelay: 300 * time.Millisecond,
+	}, func(c *gin.Context) bool { return c.FullPath() != "/:short_code" }))
+	router.Use(NewConcurrencyLimiter(1, 0).Middleware())
+	router.GET("/:short_code", func(c *gin.Context) { c.Redirect(http.StatusFound, "https://example.com") })
+	router.GET("/api/info", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	serve := func(path, ip string) (int, time.Duration) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		start := time.Now()
+		router.ServeHTTP(w, req)
+		return w.Code, time.Since(start)
+	}
+
+	done := make(chan int)
+	go func() {
+		status, _ := serve("/exists", "203.0.113.7")
+		done <- status
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	status, took := serve("/exists", "198.51.100.1")
+	assert.Equal(t, http.StatusFound, status, "the only slot is free while the scanner waits")
+	assert.Less(t, took, 100*time.Millisecond)
+	status, took = serve("/api/info", "203.0.113.7")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Less(t, took, 100*time.Millisecond, "only redirects are delayed")
+	assert.Equal(t, http.StatusFound, <-done)
+}
+
+// TestRedisScanStore tests miss counting and bans in Redis
+func TestRedisScanStore(t *testing.T) {
+	client := setupTestRedis(t)
+	defer client.Close()
+	store := NewRedisScanStore(client)
+	ctx := context.Background()
+
+	misses, banned, err := store.Status(ctx, "203.0.113.7")
+	require.NoError(t, err)
+	assert.Equal(t, 0, misses)
+	assert.Equal(t, time.Duration(0), banned)
+
+	require.NoError(t, store.RecordMiss(ctx, "203.0.113.7", time.Minute, 3, time.Hour))
+	require.NoError(t, store.RecordMiss(ctx, "203.0.113.7", time.Minute, 3, time.Hour))
+	misses, _, _ = store.Status(ctx, "203.0.113.7")
+	assert.Equal(t, 2, misses)
+
+	require.NoError(t, store.RecordMiss(ctx, "203.0.113.7", time.Minute, 3, time.Hour))
+	misses, banned, _ = store.Status(ctx, "203.0.113.7")
+	assert.Equal(t, 0, misses, "the count starts over once banned")
+	assert.InDelta(t, time.Hour, banned, float64(time.Second))
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout gives each request's context a deadline of d, so database and
+// cache calls made with it give up instead of queueing behind a slow
+// dependency. The handler still writes the response; it just sees its calls
+// fail with context.DeadlineExceeded. Requests for which skip returns true
+// (e.g. streamed exports with their own deadlines) are left alone; skip may
+// be nil.
+func Timeout(d time.Duration, skip func(c *gin.Context) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d <= 0 || (skip != nil && skip(c)) {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTimeout tests that requests get a deadline unless skipped
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Timeout(50*time.Millisecond, func(c *gin.Context) bool { return c.FullPath() == "/stream" }))
+	deadline := func(c *gin.Context) {
+		if d, ok := c.Request.Context().Deadline(); ok {
+			c.String(http.StatusOK, "%v", time.Until(d) <= 50*time.Millisecond)
+			return
+		}
+		c.String(http.StatusOK, "none")
+	}
+	r.GET("/api", deadline)
+	r.GET("/stream", deadline)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api", nil))
+	assert.Equal(t, "true", w.Body.String())
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/stream", nil))
+	assert.Equal(t, "none", w.Body.String())
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// API VERSIONS
+// ============================================================================
+// The JSON API is served under /api/v1 and /api/v2 by the same handlers; the
+// version only changes how responses are wrapped:
+//   v1  {"code": 200, "data": ...} / {"code": 4xx, "message": ..., "error": ...}
+//   v2  the payload as is on success, RFC 7807 application/problem+json on
+//       errors
+// The version is read from the path rather than set by a route group, so
+// middleware that rejects requests before routing (body and rate limits,
+// read-only mode) answers in the caller's version too.
+//
+// Rate limit rules and buckets see the versions of an endpoint as one
+// endpoint: paths are compared and keyed in their /api/v1 form (see
+// CanonicalAPIPath), so switching versions doesn't reset or double a limit.
+// ============================================================================
+
+// API versions
+const (
+	APIv1 = 1
+	APIv2 = 2
+
+	LatestAPIVersion = APIv2
+)
+
+// APIVersionHeader is set on API responses to the version that served them
+const APIVersionHeader = "API-Version"
+
+// ProblemContentType is the media type of v2 error responses (RFC 7807)
+const ProblemContentType = "application/problem+json"
+
+// apiPrefix starts the path of every versioned API route
+const apiPrefix = "/api/v"
+
+// APIVersion returns the API version of a request, from its path
+// Paths outside /api/vN (redirects, health checks, admin) count as v1.
+func APIVersion(c *gin.Context) int {
+	if c.Request == nil {
+		return APIv1
+	}
+	if version, _, ok := splitAPIPath(c.Request.URL.Path); ok {
+		return version
+	}
+	return APIv1
+}
+
+// Versioned sets the API-Version header on the responses of a route group
+func Versioned() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header(APIVersionHeader, strconv.Itoa(APIVersion(c)))
+		c.Next()
+	}
+}
+
+// CanonicalAPIPath returns path with any supported API version replaced by
+// v1, e.g. /api/v2/shorten -> /api/v1/shorten; other paths are returned as is
+func CanonicalAPIPath(path string) string {
+	if _, rest, ok := splitAPIPath(path); ok {
+		return apiPrefix + "1" + rest
+	}
+	return path
+}
+
+// splitAPIPath splits /api/vN/rest into N and /rest
+// ok is false for other paths and unsupported versions.
+func splitAPIPath(path string) (version int, rest string, ok bool) {
+	if !strings.HasPrefix(path, apiPrefix) {
+		return 0, "", false
+	}
+	number, rest, _ := strings.Cut(path[len(apiPrefix):], "/")
+	version, err := strconv.Atoi(number)
+	if err != nil || version < APIv1 || version > LatestAPIVersion {
+		return 0, "", false
+	}
+	if rest != "" || strings.HasSuffix(path, "/") {
+		rest = "/" + rest
+	}
+	return version, rest, true
+}
+
+// Problem is an RFC 7807 problem details object, the v2 error body
+type Problem struct {
+	Type     string      `json:"type"`
+	Title    string      `json:"title"`
+	Status   int         `json:"status"`
+	Detail   string      `json:"detail,omitempty"`
+	Instance string      `json:"instance,omitempty"` // Request path
+	Errors   interface{} `json:"errors,omitempty"`   // Field problems of invalid requests
+}
+
+// NewProblem returns the problem for status; kind names the error for
+// machines (e.g. "read_only") and becomes its type, empty uses about:blank
+func NewProblem(c *gin.Context, status int, kind, detail string) Problem {
+	problemType := "about:blank"
+	if kind != "" {
+		problemType = "urn:short-link:error:" + kind
+	}
+	return Problem{
+		Type:     problemType,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: c.Request.URL.Path,
+	}
+}
+
+// WriteProblem writes problem as application/problem+json
+func WriteProblem(c *gin.Context, problem Problem) {
+	c.Header("Content-Type", ProblemContentType)
+	c.JSON(problem.Status, problem)
+}
+
+// writeError writes a middleware error in the request's API version
+// kind names the error for machines, e.g. "read_only".
+func writeError(c *gin.Context, status int, message, kind string) {
+	if APIVersion(c) >= APIv2 {
+		WriteProblem(c, NewProblem(c, status, kind, message))
+		return
+	}
+	c.JSON(status, gin.H{
+		"code":    status,
+		"message": message,
+		"error":   kind,
+	})
+}
+
+// abortWithError writes a middleware error and stops the handler chain
+func abortWithError(c *gin.Context, status int, message, kind string) {
+	writeError(c, status, message, kind)
+	c.Abort()
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAPIVersion tests reading the version from the path
+func TestAPIVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for path, want := range map[string]int{
+		"/api/v1/shorten": APIv1,
+		"/api/v2/shorten": APIv2,
+		"/api/v2":         APIv2,
+		"/api/v9/shorten": APIv1,
+		"/api/version":    APIv1,
+		"/abc123":         APIv1,
+	} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, path, nil)
+		assert.Equal(t, want, APIVersion(c), path)
+	}
+}
+
+// TestCanonicalAPIPath tests mapping every version onto v1
+func TestCanonicalAPIPath(t *testing.T) {
+	assert.Equal(t, "/api/v1/shorten", CanonicalAPIPath("/api/v2/shorten"))
+	assert.Equal(t, "/api/v1/info/:short_code", CanonicalAPIPath("/api/v2/info/:short_code"))
+	assert.Equal(t, "/api/v1/shorten", CanonicalAPIPath("/api/v1/shorten"))
+	assert.Equal(t, "/api/v1/", CanonicalAPIPath("/api/v2/"))
+	assert.Equal(t, "/api/v1", CanonicalAPIPath("/api/v2"))
+	assert.Equal(t, "/api/v9/shorten", CanonicalAPIPath("/api/v9/shorten"))
+	assert.Equal(t, "/admin/links", CanonicalAPIPath("/admin/links"))
+}
+
+// TestWriteErrorVersions tests middleware errors in each API version
+func TestWriteErrorVersions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Versioned(), func(c *gin.Context) {
+		abortWithError(c, http.StatusServiceUnavailable, "Service is read-only", "read_only")
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		return w
+	}
+
+	w := serve("/api/v1/shorten")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get(APIVersionHeader))
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+	var v1 map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &v1))
+	assert.Equal(t, float64(http.StatusServiceUnavailable), v1["code"])
+	assert.Equal(t, "read_only", v1["error"])
+
+	w = serve("/api/v2/shorten")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "2", w.Header().Get(APIVersionHeader))
+	assert.Contains(t, w.Header().Get("Content-Type"), ProblemContentType)
+	var problem Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, Problem{
+		Type:     "urn:short-link:error:read_only",
+		Title:    "Service Unavailable",
+		Status:   http.StatusServiceUnavailable,
+		Detail:   "Service is read-only",
+		Instance: "/api/v2/shorten",
+	}, problem)
+}
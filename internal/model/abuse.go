@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+)
+
+// Moderation decisions on a link
+const (
+	ModerationAllow   = "allow"   // Redirect as usual
+	ModerationWarn    = "warn"    // Show an unsafe-link warning first
+	ModerationDisable = "disable" // Don't redirect
+)
+
+// AbuseFlag records a link whose traffic looked like click fraud or abuse
+type AbuseFlag struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	ShortCode string    `gorm:"type:varchar(15);not null" json:"short_code"`
+	Reason    string    `gorm:"type:varchar(32);not null" json:"reason"` // The policy that triggered, e.g. spike
+	Action    string    `gorm:"type:varchar(16);not null" json:"action"` // flag, throttle, warn or disable
+	Detail    string    `gorm:"type:varchar(255);not null;default:''" json:"detail"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName specifies the table name for AbuseFlag
+func (AbuseFlag) TableName() string {
+	return "abuse_flags"
+}
+
+// Report categories
+const (
+	ReportPhishing = "phishing"
+	ReportMalware  = "malware"
+	ReportSpam     = "spam"
+	ReportOther    = "other"
+)
+
+// AbuseReport is a visitor's report that a link is malicious
+type AbuseReport struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	ShortCode  string     `gorm:"type:varchar(15);not null" json:"short_code"`
+	Category   string     `gorm:"type:varchar(16);not null" json:"category"`
+	Details    string     `gorm:"type:varchar(1000);not null;default:''" json:"details,omitempty"`
+	ReporterIP string     `gorm:"type:varchar(45);not null" json:"reporter_ip"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"` // Set once an admin reviewed the link
+}
+
+// TableName specifies the table name for AbuseReport
+func (AbuseReport) TableName() string {
+	return "abuse_reports"
+}
+
+// ReportedLink summarizes the open reports of a link
+type ReportedLink struct {
+	ShortCode      string    `json:"short_code"`
+	Reports        int64     `json:"reports"`
+	Reporters      int64     `json:"reporters"` // Distinct reporter IPs
+	LastReportedAt time.Time `json:"last_reported_at"`
+}
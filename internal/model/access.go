@@ -0,0 +1,111 @@
+package model
+
+import (
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// DirectReferrer in AccessRules.Referrers allows visits without a referrer
+// (typed in, bookmarks, apps that don't send one)
+const DirectReferrer = "direct"
+
+// AccessRules restrict who may follow a link; a nil or empty rule allows everyone
+type AccessRules struct {
+	// Referrers are the sites (hosts, including their subdomains) whose
+	// pages may link here, and/or DirectReferrer
+	Referrers []string `json:"referrers,omitempty"`
+	// AllowedIPs, when set, are the only networks (CIDRs) visitors may come from
+	AllowedIPs []string `json:"allowed_ips,omitempty"`
+	// BlockedIPs are networks (CIDRs) visitors may not come from; they win
+	// over AllowedIPs
+	BlockedIPs []string `json:"blocked_ips,omitempty"`
+}
+
+// Visitor is who is following a link, as far as access rules care
+type Visitor struct {
+	IP        string
+	UserAgent string
+	Referrer  string // Referer header; empty for direct visits
+	// DoNotTrack is set when the request asked not to be tracked (DNT: 1
+	// or Sec-GPC: 1)
+	DoNotTrack bool
+	// UserID is the authenticated caller, empty for anonymous visitors;
+	// private links only redirect for members of their organization
+	UserID string
+	// Signature and SignatureExpires (Unix seconds) are the sig and exp
+	// query parameters of signed links; empty and 0 if absent
+	Signature        string
+	SignatureExpires int64
+}
+
+// IsEmpty reports whether the rules restrict nothing
+func (r *AccessRules) IsEmpty() bool {
+	return r == nil || (len(r.Referrers) == 0 && len(r.AllowedIPs) == 0 && len(r.BlockedIPs) == 0)
+}
+
+// Equal reports whether two rule sets are the same
+func (r *AccessRules) Equal(other *AccessRules) bool {
+	if r.IsEmpty() || other.IsEmpty() {
+		return r.IsEmpty() == other.IsEmpty()
+	}
+	return slices.Equal(r.Referrers, other.Referrers) &&
+		slices.Equal(r.AllowedIPs, other.AllowedIPs) &&
+		slices.Equal(r.BlockedIPs, other.BlockedIPs)
+}
+
+// Allows reports whether visitor may follow the link
+func (r *AccessRules) Allows(visitor Visitor) bool {
+	return r.IsEmpty() || (r.allowsIP(visitor.IP) && r.allowsReferrer(visitor.Referrer))
+}
+
+// allowsIP checks the IP rules
+// A visitor without a parsable IP passes block lists but no allow list
+func (r *AccessRules) allowsIP(ip string) bool {
+	if len(r.AllowedIPs) == 0 && len(r.BlockedIPs) == 0 {
+		return true
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return len(r.AllowedIPs) == 0
+	}
+	addr = addr.Unmap().WithZone("")
+	if containsIP(r.BlockedIPs, addr) {
+		return false
+	}
+	return len(r.AllowedIPs) == 0 || containsIP(r.AllowedIPs, addr)
+}
+
+// containsIP reports whether addr is in any of the CIDRs
+// Entries that don't parse never match (rules are validated when saved)
+func containsIP(cidrs []string, addr netip.Addr) bool {
+	for _, cidr := range cidrs {
+		if prefix, err := netip.ParsePrefix(cidr); err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsReferrer checks the referrer rule
+func (r *AccessRules) allowsReferrer(referrer string) bool {
+	if len(r.Referrers) == 0 {
+		return true
+	}
+	if referrer == "" {
+		return slices.Contains(r.Referrers, DirectReferrer)
+	}
+
+	u, err := url.Parse(referrer)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for _, allowed := range r.Referrers {
+		if allowed != DirectReferrer && (host == allowed || strings.HasSuffix(host, "."+allowed)) {
+			return true
+		}
+	}
+	return false
+}
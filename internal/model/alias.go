@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+)
+
+// LinkAlias is a friendly name for a link in a tenant's namespace, served
+// at /{namespace}/{alias}; the same alias may exist in every namespace
+type LinkAlias struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	Namespace string    `gorm:"uniqueIndex:uk_namespace_alias;type:varchar(64);not null" json:"namespace"`
+	Alias     string    `gorm:"uniqueIndex:uk_namespace_alias;type:varchar(15);not null" json:"alias"`
+	ShortCode string    `gorm:"index;type:varchar(15);not null" json:"short_code"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// TableName specifies the table name for LinkAlias
+func (LinkAlias) TableName() string {
+	return "link_aliases"
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+)
+
+// Campaign groups short links for aggregated reporting
+type Campaign struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string    `gorm:"uniqueIndex;type:varchar(128);not null" json:"name"`
+	Description string    `gorm:"type:varchar(1024);not null;default:''" json:"description,omitempty"`
+	OwnerID     string    `gorm:"type:varchar(128);not null;default:'';index" json:"owner_id"` // User who created it
+	OrgID       uint      `gorm:"not null;default:0;index" json:"org_id,omitempty"`            // Owning organization; 0 for a personal campaign
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName specifies the table name for Campaign
+func (Campaign) TableName() string {
+	return "campaigns"
+}
+
+// CampaignLink attaches a short link to a campaign
+type CampaignLink struct {
+	CampaignID uint      `gorm:"primaryKey" json:"campaign_id"`
+	ShortCode  string    `gorm:"primaryKey;type:varchar(15)" json:"short_code"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName specifies the table name for CampaignLink
+func (CampaignLink) TableName() string {
+	return "campaign_links"
+}
+
+// CampaignLinkStat is the visit counters of one link in a campaign
+type CampaignLinkStat struct {
+	ShortCode     string `json:"short_code"`
+	OriginalURL   string `json:"original_url"`
+	VisitCount    uint64 `json:"visit_count"`
+	BotVisitCount uint64 `json:"bot_visit_count"`
+}
+
+// CampaignStats summarizes visits across all links of a campaign
+type CampaignStats struct {
+	Campaign      *Campaign
+	VisitCount    uint64
+	BotVisitCount uint64
+	Links         []CampaignLinkStat
+	Countries     []VisitStat
+	Referrers     []VisitStat
+}
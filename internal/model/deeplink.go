@@ -0,0 +1,39 @@
+package model
+
+// DeepLinks open a link in a mobile app; the link's OriginalURL stays the
+// web fallback for other devices and for visitors without the app
+type DeepLinks struct {
+	// IOS is opened on iPhone, iPad and iPod, e.g. myapp://product/42
+	IOS string `json:"ios,omitempty"`
+	// Android is opened on Android, e.g. myapp://product/42 or an
+	// intent:// URL naming the app package
+	Android string `json:"android,omitempty"`
+}
+
+// IsEmpty reports whether no deep link is set
+func (d *DeepLinks) IsEmpty() bool {
+	return d == nil || (d.IOS == "" && d.Android == "")
+}
+
+// Equal reports whether two sets of deep links are the same
+func (d *DeepLinks) Equal(other *DeepLinks) bool {
+	if d.IsEmpty() || other.IsEmpty() {
+		return d.IsEmpty() == other.IsEmpty()
+	}
+	return *d == *other
+}
+
+// ForOS returns the deep link for a visitor's operating system, as named by
+// enrich.ParseUserAgent ("iOS", "Android"); empty if there is none
+func (d *DeepLinks) ForOS(os string) string {
+	if d == nil {
+		return ""
+	}
+	switch os {
+	case "iOS":
+		return d.IOS
+	case "Android":
+		return d.Android
+	}
+	return ""
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+)
+
+// Destination health statuses
+const (
+	HealthOK          = "ok"
+	HealthRestricted  = "restricted" // 401, 403 or 429: the page exists but refused the checker
+	HealthBroken      = "broken"     // 404, 410, other 4xx, 5xx or a redirect loop
+	HealthTimeout     = "timeout"
+	HealthTLSError    = "tls_error"
+	HealthUnreachable = "unreachable" // DNS failure, refused connection or blocked address
+)
+
+// HealthFailed reports whether a health status counts as a failed check
+func HealthFailed(status string) bool {
+	return status != HealthOK && status != HealthRestricted
+}
+
+// LinkHealthEvent is sent to owners when a link's destination looks dead
+type LinkHealthEvent struct {
+	ShortCode   string    `json:"short_code"`
+	OriginalURL string    `json:"original_url"`
+	OrgID       uint      `json:"org_id,omitempty"`
+	CreatedBy   string    `json:"created_by,omitempty"` // Who created the link, e.g. user:alice
+	Status      string    `json:"status"`
+	Code        int       `json:"code,omitempty"` // HTTP status, 0 without a response
+	Failures    int       `json:"failures"`       // Consecutive failed checks
+	CheckedAt   time.Time `json:"checked_at"`
+}
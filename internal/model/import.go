@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+)
+
+// Import row outcomes
+const (
+	ImportCreated = "created" // A new link was created
+	ImportExists  = "exists"  // An active link for the URL already existed and was reused
+	ImportFailed  = "failed"  // The row was rejected; see Error
+)
+
+// ImportRow is one parsed row of an import file
+type ImportRow struct {
+	Line        int // 1-based line in the file
+	OriginalURL string
+	DisplayURL  string // Set by the importer when OriginalURL was converted to ASCII
+	Alias       string // Requested short code; empty to generate one
+	ExpiredAt   *time.Time
+}
+
+// ImportResult is the outcome of one import row
+type ImportResult struct {
+	Line        int    `json:"line"`
+	OriginalURL string `json:"original_url,omitempty"`
+	ShortCode   string `json:"short_code,omitempty"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ImportReport summarizes an import with one result per data row
+type ImportReport struct {
+	Total    int            `json:"total"`
+	Created  int            `json:"created"`
+	Existing int            `json:"existing"`
+	Failed   int            `json:"failed"`
+	Rows     []ImportResult `json:"rows"`
+}
+
+// Add records a row result and updates the counters
+func (r *ImportReport) Add(result ImportResult) {
+	r.Total++
+	switch result.Status {
+	case ImportCreated:
+		r.Created++
+	case ImportExists:
+		r.Existing++
+	default:
+		r.Failed++
+	}
+	r.Rows = append(r.Rows, result)
+}
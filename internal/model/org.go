@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+)
+
+// OrgRole is what a member may do with an organization's links
+type OrgRole string
+
+// Organization roles, from least to most privileged
+const (
+	RoleViewer OrgRole = "viewer" // Read links and their stats
+	RoleEditor OrgRole = "editor" // Also create, edit and restore links
+	RoleOwner  OrgRole = "owner"  // Also manage members
+)
+
+// orgRoleRanks orders roles so a role includes every lower one
+var orgRoleRanks = map[OrgRole]int{RoleViewer: 1, RoleEditor: 2, RoleOwner: 3}
+
+// Valid reports whether r is a known role
+func (r OrgRole) Valid() bool {
+	return orgRoleRanks[r] > 0
+}
+
+// Includes reports whether r grants everything required grants
+func (r OrgRole) Includes(required OrgRole) bool {
+	return r.Valid() && orgRoleRanks[r] >= orgRoleRanks[required]
+}
+
+// Organization owns short links shared by its members
+type Organization struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name      string    `gorm:"uniqueIndex;type:varchar(128);not null" json:"name"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName specifies the table name for Organization
+func (Organization) TableName() string {
+	return "organizations"
+}
+
+// OrgMember grants a user a role in an organization
+type OrgMember struct {
+	OrgID     uint      `gorm:"primaryKey" json:"org_id"`
+	UserID    string    `gorm:"primaryKey;type:varchar(128)" json:"user_id"`
+	Role      OrgRole   `gorm:"type:varchar(16);not null" json:"role"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName specifies the table name for OrgMember
+func (OrgMember) TableName() string {
+	return "org_members"
+}
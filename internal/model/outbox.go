@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+)
+
+// Kinds of outbox events
+const (
+	OutboxCacheLink = "cache_link" // Refresh the link's cache entry from MySQL
+	OutboxWebhook   = "webhook"    // POST Payload to outbox.webhook_url
+)
+
+// OutboxEvent is a side effect of a link write, stored in the same
+// transaction and applied later by the outbox dispatcher
+type OutboxEvent struct {
+	ID            uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Kind          string     `gorm:"type:varchar(32);not null" json:"kind"`
+	ShortCode     string     `gorm:"type:varchar(15);not null" json:"short_code"`
+	Payload       string     `gorm:"type:json" json:"payload,omitempty"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null" json:"next_attempt_at"`
+	ClaimToken    string     `gorm:"type:varchar(32);not null;default:''" json:"-"`
+	ClaimedUntil  *time.Time `json:"-"`
+	DispatchedAt  *time.Time `json:"dispatched_at,omitempty"`
+	FailedAt      *time.Time `json:"failed_at,omitempty"`
+	LastError     string     `gorm:"type:varchar(512);not null;default:''" json:"last_error,omitempty"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName specifies the table name for OutboxEvent
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// LinkCreatedEvent is the webhook payload of a new link
+type LinkCreatedEvent struct {
+	Type        string     `json:"type"` // Always "link.created"
+	ShortCode   string     `json:"short_code"`
+	OriginalURL string     `json:"original_url"`
+	Domain      string     `json:"domain,omitempty"`
+	OrgID       uint       `json:"org_id,omitempty"`
+	ExpiredAt   *time.Time `json:"expired_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
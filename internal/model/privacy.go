@@ -0,0 +1,57 @@
+package model
+
+import (
+	"time"
+)
+
+// Data subject request kinds
+const (
+	DataRequestExport = "export" // Collect everything stored about the subject
+	DataRequestDelete = "delete" // Remove it
+)
+
+// DataSubject identifies whose data a request covers; at least one field
+// is set
+type DataSubject struct {
+	IP     string `json:"ip,omitempty"`      // Visitor IP: visit logs and abuse reports
+	UserID string `json:"user_id,omitempty"` // Authenticated user: the links they created
+}
+
+// DataExport counts what an export wrote to its file
+type DataExport struct {
+	VisitLogs    int64 `json:"visit_logs"`
+	AbuseReports int64 `json:"abuse_reports"`
+	Links        int64 `json:"links"` // Including soft-deleted ones
+}
+
+// Data export record types
+const (
+	DataRecordVisitLog    = "visit_log"
+	DataRecordAbuseReport = "abuse_report"
+	DataRecordLink        = "link"
+)
+
+// DataExportRecord is one line of a data export file (NDJSON)
+type DataExportRecord struct {
+	Type string      `json:"type"` // visit_log, abuse_report or link
+	Data interface{} `json:"data"`
+}
+
+// DataDeletion counts what a delete request removed
+type DataDeletion struct {
+	VisitLogs    int64 `json:"visit_logs"` // Of the subject's IP and of the deleted links
+	AbuseReports int64 `json:"abuse_reports"`
+	Links        int64 `json:"links"`
+}
+
+// DataRequestEvent is sent when a data subject request finishes
+type DataRequestEvent struct {
+	ID         string        `json:"id"`
+	Kind       string        `json:"kind"`
+	Status     string        `json:"status"` // done or failed
+	Subject    DataSubject   `json:"subject"`
+	Error      string        `json:"error,omitempty"`
+	Export     *DataExport   `json:"export,omitempty"`
+	Deleted    *DataDeletion `json:"deleted,omitempty"`
+	FinishedAt time.Time     `json:"finished_at"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+)
+
+// RecycledCode is a short code reclaimed from a long-expired link that never
+// received traffic, free to be handed out again
+type RecycledCode struct {
+	ShortCode  string    `gorm:"type:varchar(15);primaryKey"`
+	RecycledAt time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for RecycledCode
+func (RecycledCode) TableName() string {
+	return "recycled_codes"
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"time"
+)
+
+// Revision actions
+const (
+	RevisionCreate = "create"
+	RevisionImport = "import"
+	RevisionClone  = "clone"
+	RevisionUpdate = "update"
+)
+
+// URLRevision is one entry in a link's edit history
+type URLRevision struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"-"`
+	ShortCode   string     `gorm:"type:varchar(15);not null" json:"short_code"`
+	Revision    int        `gorm:"not null" json:"revision"`
+	Action      string     `gorm:"type:varchar(16);not null" json:"action"`
+	OriginalURL string     `gorm:"type:varchar(2048);not null" json:"original_url"`
+	PreviousURL string     `gorm:"type:varchar(2048);not null;default:''" json:"previous_url,omitempty"`
+	ExpiredAt   *time.Time `json:"expired_at,omitempty"`
+	ClonedFrom  string     `gorm:"type:varchar(15);not null;default:''" json:"cloned_from,omitempty"`
+	ChangedBy   string     `gorm:"type:varchar(128);not null;default:''" json:"changed_by"`
+	ChangedAt   time.Time  `gorm:"autoCreateTime" json:"changed_at"`
+}
+
+// TableName specifies the table name for URLRevision
+func (URLRevision) TableName() string {
+	return "url_mapping_revisions"
+}
+
+// URLUpdate is a partial change to a link; nil fields are left as they are
+type URLUpdate struct {
+	OriginalURL *string
+	ExpiredAt   *time.Time
+	ClearExpiry bool  // Remove the expiration; overrides ExpiredAt
+	CacheTTL    *int  // A positive TTL turns NoCache off
+	NoCache     *bool // True resets CacheTTL
+	// AccessRules replace the link's rules; empty rules remove them
+	AccessRules *AccessRules
+	// DeepLinks replace the link's deep links; empty ones remove them
+	DeepLinks *DeepLinks
+	// Metadata replaces the link's metadata when not nil; empty removes it
+	Metadata map[string]interface{}
+	// VisitDedupMinutes replaces the visit deduplication window; 0 turns it off
+	VisitDedupMinutes *int
+	// NoAnalytics turns visit logging off or back on
+	NoAnalytics *bool
+	// Visibility replaces the link's visibility
+	Visibility *string
+	// RedirectDelay replaces the countdown; 0 returns to the global setting
+	RedirectDelay *int
+	// RequireSignature turns signed-only redirects on or off
+	RequireSignature *bool
+}
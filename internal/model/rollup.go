@@ -0,0 +1,63 @@
+package model
+
+import (
+	"time"
+)
+
+// VisitDailyStat is one link's visit totals for one UTC day
+type VisitDailyStat struct {
+	ShortCode string    `gorm:"primaryKey;type:varchar(15)" json:"short_code"`
+	Day       time.Time `gorm:"primaryKey;type:date" json:"day"`
+	Clicks    uint64    `gorm:"not null;default:0" json:"clicks"` // Human visits
+	BotClicks uint64    `gorm:"not null;default:0" json:"bot_clicks"`
+	Uniques   uint64    `gorm:"not null;default:0" json:"uniques"` // Distinct IPs of human visits
+}
+
+// TableName specifies the table name for VisitDailyStat
+func (VisitDailyStat) TableName() string {
+	return "visit_daily_stats"
+}
+
+// VisitDailyBreakdown counts one link's human visits with one value of a
+// dimension (country, device_type, browser or referrer) on one UTC day
+type VisitDailyBreakdown struct {
+	ShortCode string    `gorm:"primaryKey;type:varchar(15)"`
+	Dimension string    `gorm:"primaryKey;type:varchar(16)"`
+	Day       time.Time `gorm:"primaryKey;type:date"`
+	Value     string    `gorm:"primaryKey;type:varchar(255)"`
+	Count     uint64    `gorm:"not null;default:0"`
+}
+
+// TableName specifies the table name for VisitDailyBreakdown
+func (VisitDailyBreakdown) TableName() string {
+	return "visit_daily_breakdowns"
+}
+
+// VisitRollupDay records that a UTC day has been rolled up
+type VisitRollupDay struct {
+	Day        time.Time `gorm:"primaryKey;type:date"`
+	RolledUpAt time.Time `gorm:"not null"`
+}
+
+// TableName specifies the table name for VisitRollupDay
+func (VisitRollupDay) TableName() string {
+	return "visit_rollup_days"
+}
+
+// TimeRange is a half-open interval [From, To); a zero bound is open
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// StatsSplit divides a stats query between the rollup tables and raw
+// visit logs
+type StatsSplit struct {
+	// RollupFrom and RollupTo bound the whole UTC days read from the rollup
+	// tables; RollupFrom is zero to read from the first rolled-up day
+	RollupFrom time.Time
+	RollupTo   time.Time
+	Rollup     bool // Whether any days come from the rollup tables
+	// Raw are the parts of the range read from visit_logs
+	Raw []TimeRange
+}
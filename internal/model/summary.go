@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+)
+
+// Summary frequencies
+const (
+	SummaryDaily  = "daily"
+	SummaryWeekly = "weekly"
+)
+
+// Summary delivery channels
+const (
+	SummaryEmail   = "email"
+	SummaryWebhook = "webhook"
+)
+
+// SummarySubscription is a user's request for periodic summaries of their links
+type SummarySubscription struct {
+	ID     uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID string `gorm:"type:varchar(128);not null;index" json:"user_id"`
+	// OrgID summarizes an organization's links; 0 for the links the user created
+	OrgID     uint   `gorm:"not null;default:0" json:"org_id,omitempty"`
+	Frequency string `gorm:"type:varchar(8);not null" json:"frequency"` // daily or weekly
+	Channel   string `gorm:"type:varchar(8);not null" json:"channel"`   // email or webhook
+	Target    string `gorm:"type:varchar(2048);not null" json:"target"` // Email address or webhook URL
+	// LastPeriodEnd is the end of the last period summarized, nil before the first
+	LastPeriodEnd *time.Time `json:"last_period_end,omitempty"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName specifies the table name for SummarySubscription
+func (SummarySubscription) TableName() string {
+	return "summary_subscriptions"
+}
+
+// SummaryLink is a link listed in a summary
+type SummaryLink struct {
+	ShortCode   string `json:"short_code"`
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+	Domain      string `json:"-"`      // Serving domain, for building ShortURL
+	Clicks      int64  `json:"clicks"` // Human clicks in the period
+}
+
+// Summary is one period's report for a subscription
+type Summary struct {
+	SubscriptionID uint          `json:"subscription_id"`
+	UserID         string        `json:"user_id"`
+	OrgID          uint          `json:"org_id,omitempty"`
+	Frequency      string        `json:"frequency"`
+	From           time.Time     `json:"from"`
+	To             time.Time     `json:"to"` // Exclusive
+	Clicks         int64         `json:"clicks"`
+	TopLinks       []SummaryLink `json:"top_links"`
+	NewLinkCount   int64         `json:"new_link_count"`
+	NewLinks       []SummaryLink `json:"new_links"` // The most recent ones, up to the top-links limit
+}
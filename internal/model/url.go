@@ -1,18 +1,168 @@
 package model
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // URLMapping represents a URL mapping record
 type URLMapping struct {
-	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
-	ShortCode   string     `gorm:"uniqueIndex;type:varchar(15);not null" json:"short_code"`
-	OriginalURL string     `gorm:"type:varchar(2048);not null" json:"original_url"`
-	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
-	ExpiredAt   *time.Time `gorm:"index" json:"expired_at,omitempty"`
-	VisitCount  uint64     `gorm:"default:0" json:"visit_count"`
-	Status      int8       `gorm:"default:1" json:"status"` // 1: active, 0: disabled
+	ID          uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	ShortCode   string `gorm:"uniqueIndex;type:varchar(15);not null" json:"short_code"`
+	OriginalURL string `gorm:"type:varchar(2048);not null" json:"original_url"`
+	// DisplayURL is the destination as submitted when it had to be converted
+	// to ASCII (punycode host, percent-encoded path); empty otherwise
+	DisplayURL string `gorm:"type:varchar(2048);not null;default:''" json:"display_url,omitempty"`
+	// Title is the destination page's <title>, fetched in the background
+	// after creation; empty until then or if the page has none
+	Title string `gorm:"type:varchar(255);not null;default:''" json:"title,omitempty"`
+	// DestinationHost is the lower-case host of OriginalURL, indexed for filtering
+	DestinationHost string `gorm:"type:varchar(255);not null;default:''" json:"-"`
+	// URLHash is HashURL(OriginalURL), indexed for duplicate lookups
+	URLHash string `gorm:"type:char(64);not null;default:''" json:"-"`
+	// Domain is the host the link is served on; empty resolves on any host
+	Domain string `gorm:"type:varchar(255);not null;default:''" json:"domain,omitempty"`
+	// OrgID is the organization owning the link; 0 for links without one
+	OrgID     uint      `gorm:"not null;default:0" json:"org_id,omitempty"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	// UpdatedAt is when the link was last edited, moderated, deleted or
+	// restored; visits don't touch it
+	UpdatedAt  time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+	ExpiredAt  *time.Time `gorm:"index" json:"expired_at,omitempty"`
+	VisitCount uint64     `gorm:"default:0" json:"visit_count"`
+	// BotVisitCount counts crawler and link-preview visits, excluded from VisitCount
+	BotVisitCount uint64 `gorm:"default:0" json:"bot_visit_count"`
+	// VisitDedupMinutes counts at most one visit per IP in this many
+	// minutes; 0 counts every visit
+	VisitDedupMinutes int `gorm:"not null;default:0" json:"visit_dedup_minutes,omitempty"`
+	// DuplicateVisitCount counts the human visits VisitDedupMinutes left out
+	// of VisitCount
+	DuplicateVisitCount uint64 `gorm:"not null;default:0" json:"duplicate_visit_count,omitempty"`
+	// NoAnalytics counts visits without logging visitor details
+	NoAnalytics bool `gorm:"not null;default:false" json:"no_analytics,omitempty"`
+	// Visibility is VisibilityPublic, VisibilityUnlisted or VisibilityPrivate
+	Visibility string `gorm:"type:varchar(16);not null;default:'public'" json:"visibility"`
+	// RedirectDelay shows a countdown page for this many seconds before
+	// redirecting; 0 uses the global setting
+	RedirectDelay int `gorm:"not null;default:0" json:"redirect_delay,omitempty"`
+	// RequireSignature only redirects requests carrying a valid, unexpired
+	// signature (see service.SignLink)
+	RequireSignature bool `gorm:"not null;default:false" json:"require_signature,omitempty"`
+	Status           int8 `gorm:"default:1" json:"status"` // 1: active, 0: disabled
+	// Warning shows an interstitial before redirecting, for links a
+	// moderator or abuse detection marked as possibly unsafe
+	Warning bool `gorm:"not null;default:false" json:"warning,omitempty"`
+	// CacheTTL overrides the cache TTL in seconds; 0 uses the configured TTL
+	CacheTTL int `gorm:"not null;default:0" json:"cache_ttl,omitempty"`
+	// NoCache makes every redirect resolve from MySQL
+	NoCache bool `gorm:"not null;default:false" json:"no_cache,omitempty"`
+	// AccessRules restrict who may follow the link; nil allows everyone
+	AccessRules *AccessRules `gorm:"serializer:json;type:json" json:"access_rules,omitempty"`
+	// DeepLinks open the link in a mobile app; nil always uses OriginalURL
+	DeepLinks *DeepLinks `gorm:"serializer:json;type:json" json:"deep_links,omitempty"`
+	// Metadata is a JSON object API clients store with the link (titles,
+	// notes, their own IDs); the service never interprets it
+	Metadata map[string]interface{} `gorm:"serializer:json;type:json" json:"metadata,omitempty"`
+	// Health is the result of the last destination check (see
+	// service.LinkHealthCheck); empty until the link is first checked
+	HealthStatus    string     `gorm:"type:varchar(16);not null;default:''" json:"health_status,omitempty"`
+	HealthCode      int        `gorm:"not null;default:0" json:"health_code,omitempty"`
+	HealthCheckedAt *time.Time `gorm:"index" json:"health_checked_at,omitempty"`
+	HealthFailures  int        `gorm:"not null;default:0" json:"health_failures,omitempty"` // Consecutive failed checks
+	// DeletedAt marks a soft-deleted link; GORM excludes these rows from queries
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	// Tags are only loaded where needed (info, list), never on the redirect path
+	Tags []Tag `gorm:"many2many:url_mapping_tags" json:"tags,omitempty"`
+}
+
+// Link visibility
+const (
+	// VisibilityPublic links redirect for anyone and may be listed (top links)
+	VisibilityPublic = "public"
+	// VisibilityUnlisted links redirect for anyone but are never listed
+	VisibilityUnlisted = "unlisted"
+	// VisibilityPrivate links only redirect for members of the owning
+	// organization, and are never listed
+	VisibilityPrivate = "private"
+)
+
+// IsPrivate reports whether only members of the link's organization may
+// follow it
+func (u *URLMapping) IsPrivate() bool {
+	return u.Visibility == VisibilityPrivate
+}
+
+// IsListed reports whether the link may appear in public listings
+// Links loaded without the column count as public, as they were before it
+func (u *URLMapping) IsListed() bool {
+	return u.Visibility == VisibilityPublic || u.Visibility == ""
+}
+
+// CachePolicy is how a link's redirect may be cached
+type CachePolicy struct {
+	TTL     int  // Seconds; 0 uses the configured cache TTL
+	NoCache bool // Never cache; for destinations that change constantly
+}
+
+// LinkOptions are the settings of a new link besides its destination,
+// domain and expiry
+type LinkOptions struct {
+	Cache       CachePolicy
+	OrgID       uint         // Owning organization; 0 for none
+	AccessRules *AccessRules // Who may follow the link; nil for everyone
+	DeepLinks   *DeepLinks   // App links for mobile visitors; nil for none
+	// Metadata is a client-defined JSON object; nil for none
+	Metadata map[string]interface{}
+	// NoHTTPSUpgrade keeps an http:// destination even if it answers over https
+	NoHTTPSUpgrade bool
+	// Tags are added to the link (also to an existing link that is reused)
+	Tags []string
+	// ForceNew creates a new link even if an equivalent one exists
+	ForceNew bool
+	// VisitDedupMinutes counts one visit per IP per this many minutes; 0 counts all
+	VisitDedupMinutes int
+	// NoAnalytics counts visits without logging visitor details
+	NoAnalytics bool
+	// Visibility is public (the default), unlisted or private; private
+	// links need an organization
+	Visibility string
+	// RedirectDelay is the countdown in seconds; 0 uses the global setting
+	RedirectDelay int
+	// RequireSignature only redirects signed requests
+	RequireSignature bool
+}
+
+// CachePolicy returns the link's cache settings
+func (u *URLMapping) CachePolicy() CachePolicy {
+	return CachePolicy{TTL: u.CacheTTL, NoCache: u.NoCache}
+}
+
+// RawVisitCount returns the human visits including those deduplicated
+func (u *URLMapping) RawVisitCount() uint64 {
+	return u.VisitCount + u.DuplicateVisitCount
+}
+
+// Tag is a free-form label on links
+type Tag struct {
+	ID   uint   `gorm:"primaryKey;autoIncrement" json:"-"`
+	Name string `gorm:"uniqueIndex;type:varchar(64);not null" json:"name"`
+}
+
+// TableName specifies the table name for Tag
+func (Tag) TableName() string {
+	return "tags"
+}
+
+// TagNames returns the names of the mapping's loaded tags
+func (u *URLMapping) TagNames() []string {
+	names := make([]string, 0, len(u.Tags))
+	for _, tag := range u.Tags {
+		names = append(names, tag.Name)
+	}
+	return names
 }
 
 // TableName specifies the table name for URLMapping
@@ -20,6 +170,13 @@ func (URLMapping) TableName() string {
 	return "url_mappings"
 }
 
+// HashURL returns the hex SHA-256 of a URL, as stored in url_hash
+// It matches MySQL's SHA2(original_url, 256)
+func HashURL(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return hex.EncodeToString(sum[:])
+}
+
 // IsExpired checks if the URL mapping is expired
 func (u *URLMapping) IsExpired() bool {
 	if u.ExpiredAt == nil {
@@ -40,9 +197,91 @@ type VisitLog struct {
 	VisitedAt time.Time `gorm:"autoCreateTime;index" json:"visited_at"`
 	IP        string    `gorm:"type:varchar(45)" json:"ip,omitempty"`
 	UserAgent string    `gorm:"type:varchar(512)" json:"user_agent,omitempty"`
+
+	// Enrichment, filled in at logging time
+	Referrer   string `gorm:"type:varchar(2048)" json:"referrer,omitempty"`
+	Country    string `gorm:"type:char(2);index" json:"country,omitempty"` // ISO 3166-1 alpha-2
+	City       string `gorm:"type:varchar(128)" json:"city,omitempty"`
+	DeviceType string `gorm:"type:varchar(16)" json:"device_type,omitempty"` // Computer, Phone, Tablet, ...
+	Browser    string `gorm:"type:varchar(32)" json:"browser,omitempty"`
+	OS         string `gorm:"type:varchar(32)" json:"os,omitempty"`
+	IsBot      bool   `gorm:"default:false" json:"is_bot"`
 }
 
 // TableName specifies the table name for VisitLog
 func (VisitLog) TableName() string {
 	return "visit_logs"
 }
+
+// Sort fields for listing links
+const (
+	SortCreatedAt  = "created_at"
+	SortVisitCount = "visit_count"
+)
+
+// URLFilter selects links for listing
+type URLFilter struct {
+	Tags            []string  // Links must have every tag
+	Query           string    // Full-text search over original URLs
+	Status          *int8     // 1: active, 0: disabled; nil for any
+	Expired         *bool     // Only expired (true) or unexpired (false) links; nil for any
+	CreatedFrom     time.Time // Inclusive; zero for no bound
+	CreatedTo       time.Time // Exclusive; zero for no bound
+	UpdatedSince    time.Time // Links changed at or after; zero for no bound
+	DestinationHost string    // Host of the original URL
+	OrgID           uint      // Owning organization; 0 lists links without one
+	ListedOnly      bool      // Only public links; for callers who aren't members of OrgID
+	Sort            string    // SortCreatedAt (default) or SortVisitCount
+	Ascending       bool
+	After           *URLCursor // Keyset position; when set, Page is ignored
+	Page            int        // 1-based
+	PageSize        int
+	// Metadata keys whose values (as strings; numbers and booleans in JSON
+	// form) must equal the given ones
+	Metadata map[string]string
+}
+
+// URLCursor is the position after the last link of a page: its sort value
+// and ID, which breaks ties
+type URLCursor struct {
+	CreatedAt  time.Time
+	VisitCount uint64
+	ID         uint
+}
+
+// VisitStat is one row of a visit breakdown, e.g. {"DE", 120}
+type VisitStat struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// TopLink is one entry of the top-links leaderboard
+type TopLink struct {
+	Mapping *URLMapping
+	Clicks  int64 // Human clicks in the period
+}
+
+// VisitStats summarizes human visits to a short code
+type VisitStats struct {
+	Mapping   *URLMapping
+	Clicks    int64 // Human visits in the range
+	Uniques   int64 // Distinct IPs of human visits, counted per UTC day and summed
+	Countries []VisitStat
+	Devices   []VisitStat
+	Browsers  []VisitStat
+	Referrers []VisitStat
+}
+
+// ReservedCode is a short code (or word) that may not be used for links
+type ReservedCode struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Code      string    `gorm:"uniqueIndex;type:varchar(64);not null" json:"code"`
+	Substring bool      `gorm:"default:false" json:"substring"` // Block codes containing Code, not just equal to it
+	Reason    string    `gorm:"type:varchar(255)" json:"reason,omitempty"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName specifies the table name for ReservedCode
+func (ReservedCode) TableName() string {
+	return "reserved_codes"
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// CreateAbuseFlag records a flagged link
+func (r *URLRepository) CreateAbuseFlag(ctx context.Context, flag *model.AbuseFlag) error {
+	if err := r.db.WithContext(ctx).Create(flag).Error; err != nil {
+		return fmt.Errorf("failed to create abuse flag: %w", err)
+	}
+	return nil
+}
+
+// ListAbuseFlags returns the newest abuse flags, of one link if shortCode is
+// set, at most limit
+func (r *URLRepository) ListAbuseFlags(ctx context.Context, shortCode string, limit int) ([]model.AbuseFlag, error) {
+	query := r.db.WithContext(ctx).Order("id DESC").Limit(limit)
+	if shortCode != "" {
+		query = query.Where("short_code = ?", shortCode)
+	}
+	var flags []model.AbuseFlag
+	if err := query.Find(&flags).Error; err != nil {
+		return nil, fmt.Errorf("failed to list abuse flags: %w", err)
+	}
+	return flags, nil
+}
+
+// SetModeration sets a link's status (1: active, 0: disabled) and whether
+// it redirects through an unsafe-link warning
+// Returns false if the short code doesn't exist
+func (r *URLRepository) SetModeration(ctx context.Context, shortCode string, status int8, warning bool) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.URLMapping{}).
+		Where("short_code = ?", shortCode).
+		UpdateColumns(map[string]interface{}{"status": status, "warning": warning, "updated_at": time.Now()})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to set URL mapping moderation: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+	// MySQL reports no rows for an unchanged link too
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.URLMapping{}).
+		Where("short_code = ?", shortCode).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check short code: %w", err)
+	}
+	return count > 0, nil
+}
+
+// CreateAbuseReport stores a report
+func (r *URLRepository) CreateAbuseReport(ctx context.Context, report *model.AbuseReport) error {
+	if err := r.db.WithContext(ctx).Create(report).Error; err != nil {
+		return fmt.Errorf("failed to create abuse report: %w", err)
+	}
+	return nil
+}
+
+// CountOpenReporters returns how many distinct IPs reported a link since it
+// was last reviewed
+func (r *URLRepository) CountOpenReporters(ctx context.Context, shortCode string) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.AbuseReport{}).
+		Where("short_code = ? AND resolved_at IS NULL", shortCode).
+		Distinct("reporter_ip").Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count reporters: %w", err)
+	}
+	return count, nil
+}
+
+// ListReportedLinks returns links with open reports, most reported first,
+// at most limit
+func (r *URLRepository) ListReportedLinks(ctx context.Context, limit int) ([]model.ReportedLink, error) {
+	var links []model.ReportedLink
+	if err := r.db.WithContext(ctx).Model(&model.AbuseReport{}).
+		Select("short_code, COUNT(*) AS reports, COUNT(DISTINCT reporter_ip) AS reporters, MAX(created_at) AS last_reported_at").
+		Where("resolved_at IS NULL").
+		Group("short_code").
+		Order("reports DESC, last_reported_at DESC").
+		Limit(limit).
+		Scan(&links).Error; err != nil {
+		return nil, fmt.Errorf("failed to list reported links: %w", err)
+	}
+	return links, nil
+}
+
+// ListAbuseReports returns the newest reports of a link, at most limit
+func (r *URLRepository) ListAbuseReports(ctx context.Context, shortCode string, limit int) ([]model.AbuseReport, error) {
+	var reports []model.AbuseReport
+	if err := r.db.WithContext(ctx).Where("short_code = ?", shortCode).
+		Order("id DESC").Limit(limit).Find(&reports).Error; err != nil {
+		return nil, fmt.Errorf("failed to list abuse reports: %w", err)
+	}
+	return reports, nil
+}
+
+// ResolveAbuseReports marks the open reports of a link reviewed
+func (r *URLRepository) ResolveAbuseReports(ctx context.Context, shortCode string) error {
+	if err := r.db.WithContext(ctx).Model(&model.AbuseReport{}).
+		Where("short_code = ? AND resolved_at IS NULL", shortCode).
+		UpdateColumn("resolved_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("failed to resolve abuse reports: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetAlias retrieves an alias by namespace and name
+// Returns nil if it doesn't exist
+func (r *URLRepository) GetAlias(ctx context.Context, namespace, alias string) (*model.LinkAlias, error) {
+	var found model.LinkAlias
+	if err := r.db.WithContext(ctx).
+		Where("namespace = ? AND alias = ?", namespace, alias).
+		First(&found).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get alias: %w", err)
+	}
+	return &found, nil
+}
+
+// SetAlias creates an alias or points an existing one at another link
+func (r *URLRepository) SetAlias(ctx context.Context, alias *model.LinkAlias) error {
+	if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"short_code", "updated_at"}),
+	}).Create(alias).Error; err != nil {
+		return fmt.Errorf("failed to set alias: %w", err)
+	}
+	return nil
+}
+
+// DeleteAlias removes an alias
+// Returns false if it didn't exist
+func (r *URLRepository) DeleteAlias(ctx context.Context, namespace, alias string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("namespace = ? AND alias = ?", namespace, alias).
+		Delete(&model.LinkAlias{})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to delete alias: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteAliasesTo removes every alias pointing at shortCode
+func (r *URLRepository) DeleteAliasesTo(ctx context.Context, shortCode string) (int64, error) {
+	result := r.db.WithContext(ctx).Where("short_code = ?", shortCode).Delete(&model.LinkAlias{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete aliases: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// ListAliases returns the aliases of a namespace, by name
+func (r *URLRepository) ListAliases(ctx context.Context, namespace string) ([]model.LinkAlias, error) {
+	var aliases []model.LinkAlias
+	if err := r.db.WithContext(ctx).
+		Where("namespace = ?", namespace).
+		Order("alias").
+		Find(&aliases).Error; err != nil {
+		return nil, fmt.Errorf("failed to list aliases: %w", err)
+	}
+	return aliases, nil
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateCampaign creates a campaign
+func (r *URLRepository) CreateCampaign(ctx context.Context, campaign *model.Campaign) error {
+	if err := r.db.WithContext(ctx).Create(campaign).Error; err != nil {
+		return fmt.Errorf("failed to create campaign: %w", err)
+	}
+	return nil
+}
+
+// GetCampaign retrieves a campaign by ID
+// Returns nil if it doesn't exist
+func (r *URLRepository) GetCampaign(ctx context.Context, id uint) (*model.Campaign, error) {
+	var campaign model.Campaign
+	if err := r.db.WithContext(ctx).First(&campaign, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+	return &campaign, nil
+}
+
+// GetCampaignByName retrieves a campaign by name
+// Returns nil if it doesn't exist
+func (r *URLRepository) GetCampaignByName(ctx context.Context, name string) (*model.Campaign, error) {
+	var campaign model.Campaign
+	if err := r.db.WithContext(ctx).Where("name = ?", name).First(&campaign).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+	return &campaign, nil
+}
+
+// ExistingShortCodes returns which of shortCodes belong to live links
+func (r *URLRepository) ExistingShortCodes(ctx context.Context, shortCodes []string) ([]string, error) {
+	var existing []string
+	if err := r.db.WithContext(ctx).Model(&model.URLMapping{}).
+		Where("short_code IN ?", shortCodes).
+		Pluck("short_code", &existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check short codes: %w", err)
+	}
+	return existing, nil
+}
+
+// AddCampaignLinks attaches short codes to a campaign
+// Codes already attached are ignored
+func (r *URLRepository) AddCampaignLinks(ctx context.Context, campaignID uint, shortCodes []string) error {
+	links := make([]model.CampaignLink, 0, len(shortCodes))
+	for _, code := range shortCodes {
+		links = append(links, model.CampaignLink{CampaignID: campaignID, ShortCode: code})
+	}
+	if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&links).Error; err != nil {
+		return fmt.Errorf("failed to add campaign links: %w", err)
+	}
+	return nil
+}
+
+// RemoveCampaignLink detaches a short code from a campaign
+// Returns false if it wasn't attached
+func (r *URLRepository) RemoveCampaignLink(ctx context.Context, campaignID uint, shortCode string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("campaign_id = ? AND short_code = ?", campaignID, shortCode).
+		Delete(&model.CampaignLink{})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to remove campaign link: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CampaignLinkStats returns the visit counters of every live link in a campaign,
+// most visited first
+func (r *URLRepository) CampaignLinkStats(ctx context.Context, campaignID uint) ([]model.CampaignLinkStat, error) {
+	var stats []model.CampaignLinkStat
+	if err := r.db.WithContext(ctx).Model(&model.URLMapping{}).
+		Select("url_mappings.short_code, url_mappings.original_url, url_mappings.visit_count, url_mappings.bot_visit_count").
+		Joins("JOIN campaign_links ON campaign_links.short_code = url_mappings.short_code").
+		Where("campaign_links.campaign_id = ?", campaignID).
+		Order("url_mappings.visit_count DESC").
+		Scan(&stats).Error; err != nil {
+		return nil, fmt.Errorf("failed to get campaign link stats: %w", err)
+	}
+	return stats, nil
+}
+
+// CampaignVisitBreakdown counts human visits to a campaign's links grouped by
+// column, most frequent first. from (inclusive) and to (exclusive) are ignored when zero.
+func (r *URLRepository) CampaignVisitBreakdown(ctx context.Context, campaignID uint, column string, from, to time.Time, limit int) ([]model.VisitStat, error) {
+	if !visitBreakdownColumns[column] {
+		return nil, fmt.Errorf("unsupported breakdown column: %s", column)
+	}
+
+	links := r.db.Model(&model.CampaignLink{}).Select("short_code").Where("campaign_id = ?", campaignID)
+	query := r.db.WithContext(ctx).Model(&model.VisitLog{}).
+		Select("COALESCE("+column+", '') AS value, COUNT(*) AS count").
+		Where("short_code IN (?) AND is_bot = ?", links, false)
+	if !from.IsZero() {
+		query = query.Where("visited_at >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("visited_at < ?", to)
+	}
+
+	var stats []model.VisitStat
+	if err := query.Group("value").Order("count DESC").Limit(limit).Scan(&stats).Error; err != nil {
+		return nil, fmt.Errorf("failed to get campaign visit breakdown: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"gorm.io/gorm"
+)
+
+// dataSubjectBatchSize bounds each read and DELETE of a data subject request
+const dataSubjectBatchSize = 1000
+
+// StreamVisitLogsByIP calls fn with successive batches of the visit logs
+// stored with any of ips, ordered by id (see StreamVisitLogs)
+func (r *URLRepository) StreamVisitLogsByIP(ctx context.Context, ips []string, fn func([]model.VisitLog) error) error {
+	if len(ips) == 0 {
+		return nil
+	}
+	var lastID uint
+	for {
+		var batch []model.VisitLog
+		if err := r.db.WithContext(ctx).Where("ip IN ? AND id > ?", ips, lastID).
+			Order("id").Limit(dataSubjectBatchSize).Find(&batch).Error; err != nil {
+			return fmt.Errorf("failed to read visit logs: %w", err)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < dataSubjectBatchSize {
+			return nil
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+}
+
+// DeleteVisitLogsByIP removes the visit logs stored with any of ips and
+// returns how many were removed
+func (r *URLRepository) DeleteVisitLogsByIP(ctx context.Context, ips []string) (int64, error) {
+	if len(ips) == 0 {
+		return 0, nil
+	}
+	return r.deleteInBatches(ctx, r.db.Where("ip IN ?", ips), &model.VisitLog{}, "visit logs")
+}
+
+// AbuseReportsByIP returns the abuse reports sent from any of ips
+func (r *URLRepository) AbuseReportsByIP(ctx context.Context, ips []string) ([]model.AbuseReport, error) {
+	if len(ips) == 0 {
+		return nil, nil
+	}
+	var reports []model.AbuseReport
+	if err := r.db.WithContext(ctx).Where("reporter_ip IN ?", ips).Order("id").Find(&reports).Error; err != nil {
+		return nil, fmt.Errorf("failed to get abuse reports: %w", err)
+	}
+	return reports, nil
+}
+
+// DeleteAbuseReportsByIP removes the abuse reports sent from any of ips
+func (r *URLRepository) DeleteAbuseReportsByIP(ctx context.Context, ips []string) (int64, error) {
+	if len(ips) == 0 {
+		return 0, nil
+	}
+	return r.deleteInBatches(ctx, r.db.Where("reporter_ip IN ?", ips), &model.AbuseReport{}, "abuse reports")
+}
+
+// LinksCreatedBy returns the links (with their tags, soft-deleted ones
+// included) whose first revision was made by actor, e.g. user:alice
+func (r *URLRepository) LinksCreatedBy(ctx context.Context, actor string) ([]model.URLMapping, error) {
+	created := r.db.Model(&model.URLRevision{}).Select("short_code").
+		Where("revision = 1 AND changed_by = ?", actor)
+	var mappings []model.URLMapping
+	if err := r.db.WithContext(ctx).Unscoped().
+		Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("name") }).
+		Where("short_code IN (?)", created).
+		Order("id").Find(&mappings).Error; err != nil {
+		return nil, fmt.Errorf("failed to get links by creator: %w", err)
+	}
+	return mappings, nil
+}
+
+// PurgeLinks removes links for good, with their visit logs, history, tag
+// assignments and outbox events, and returns how many visit logs and links
+// were removed
+func (r *URLRepository) PurgeLinks(ctx context.Context, mappings []model.URLMapping) (visitLogs, links int64, err error) {
+	if len(mappings) == 0 {
+		return 0, 0, nil
+	}
+	ids := make([]uint, 0, len(mappings))
+	shortCodes := make([]string, 0, len(mappings))
+	for _, mapping := range mappings {
+		ids = append(ids, mapping.ID)
+		shortCodes = append(shortCodes, mapping.ShortCode)
+	}
+
+	// Visit logs can be many; remove them in batches before the rest
+	visitLogs, err = r.deleteInBatches(ctx, r.db.Where("short_code IN ?", shortCodes), &model.VisitLog{}, "visit logs")
+	if err != nil {
+		return visitLogs, 0, err
+	}
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("short_code IN ?", shortCodes).Delete(&model.URLRevision{}).Error; err != nil {
+			return fmt.Errorf("failed to delete link history: %w", err)
+		}
+		if err := tx.Exec("DELETE FROM `url_mapping_tags` WHERE `url_mapping_id` IN ?", ids).Error; err != nil {
+			return fmt.Errorf("failed to delete link tags: %w", err)
+		}
+		// Undelivered webhooks would still send the links' URLs
+		if err := tx.Where("short_code IN ?", shortCodes).Delete(&model.OutboxEvent{}).Error; err != nil {
+			return fmt.Errorf("failed to delete link outbox events: %w", err)
+		}
+		result := tx.Unscoped().Where("id IN ?", ids).Delete(&model.URLMapping{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete URL mappings: %w", result.Error)
+		}
+		links = result.RowsAffected
+		return nil
+	})
+	return visitLogs, links, err
+}
+
+// deleteInBatches deletes the rows of query's table matching query, at most
+// dataSubjectBatchSize per statement, so no single DELETE locks for long
+func (r *URLRepository) deleteInBatches(ctx context.Context, query *gorm.DB, value interface{}, what string) (int64, error) {
+	var total int64
+	for {
+		result := query.WithContext(ctx).Limit(dataSubjectBatchSize).Delete(value)
+		if result.Error != nil {
+			return total, fmt.Errorf("failed to delete %s: %w", what, result.Error)
+		}
+
+		total += result.RowsAffected
+		if result.RowsAffected < dataSubjectBatchSize {
+			return total, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
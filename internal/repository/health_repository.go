@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"gorm.io/gorm"
+)
+
+// LinksDueForHealthCheck returns active, unexpired links not checked since
+// checkedBefore, never-checked links first, at most limit
+func (r *URLRepository) LinksDueForHealthCheck(ctx context.Context, checkedBefore, now time.Time, limit int) ([]model.URLMapping, error) {
+	var mappings []model.URLMapping
+	if err := r.db.WithContext(ctx).
+		Select("id", "short_code", "original_url", "org_id", "health_status", "health_checked_at", "health_failures").
+		Where("status = 1 AND (expired_at IS NULL OR expired_at > ?)", now).
+		Where("health_checked_at IS NULL OR health_checked_at < ?", checkedBefore).
+		Order("health_checked_at").Limit(limit).
+		Find(&mappings).Error; err != nil {
+		return nil, fmt.Errorf("failed to list links due for a health check: %w", err)
+	}
+	return mappings, nil
+}
+
+// RecordLinkHealth stores a check result unless another instance recorded
+// one since the link was read (its health_checked_at was previousCheck)
+// Reports whether this result was stored; it doesn't bump updated_at.
+func (r *URLRepository) RecordLinkHealth(ctx context.Context, id uint, previousCheck *time.Time, status string, code, failures int, checkedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.URLMapping{}).
+		Where("id = ? AND health_checked_at <=> ?", id, previousCheck).
+		UpdateColumns(map[string]interface{}{
+			"health_status":     status,
+			"health_code":       code,
+			"health_failures":   failures,
+			"health_checked_at": checkedAt,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to record link health: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// LinkCreator returns who made a link's first revision; empty for links
+// created before revisions were recorded
+func (r *URLRepository) LinkCreator(ctx context.Context, shortCode string) (string, error) {
+	var revision model.URLRevision
+	err := r.db.WithContext(ctx).Select("changed_by").
+		Where("short_code = ? AND revision = 1", shortCode).First(&revision).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get link creator: %w", err)
+	}
+	return revision.ChangedBy, nil
+}
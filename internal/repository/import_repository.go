@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"gorm.io/gorm"
+)
+
+// TakenShortCodes returns which of shortCodes are used by a link, including
+// soft-deleted ones
+func (r *URLRepository) TakenShortCodes(ctx context.Context, shortCodes []string) ([]string, error) {
+	if len(shortCodes) == 0 {
+		return nil, nil
+	}
+	var taken []string
+	if err := r.db.WithContext(ctx).Unscoped().Model(&model.URLMapping{}).
+		Where("short_code IN ?", shortCodes).
+		Pluck("short_code", &taken).Error; err != nil {
+		return nil, fmt.Errorf("failed to check short codes: %w", err)
+	}
+	return taken, nil
+}
+
+// ActiveByOriginalURLs returns the active, unexpired links on domain for any
+// of originalURLs, among links without an organization
+func (r *URLRepository) ActiveByOriginalURLs(ctx context.Context, domain string, originalURLs []string) ([]model.URLMapping, error) {
+	if len(originalURLs) == 0 {
+		return nil, nil
+	}
+	hashes := make([]string, 0, len(originalURLs))
+	for _, originalURL := range originalURLs {
+		hashes = append(hashes, model.HashURL(originalURL))
+	}
+	var mappings []model.URLMapping
+	if err := r.db.WithContext(ctx).
+		Where("url_hash IN ? AND domain = ? AND original_url IN ? AND org_id = 0 AND status = 1", hashes, domain, originalURLs).
+		Where("expired_at IS NULL OR expired_at > ?", time.Now()).
+		Find(&mappings).Error; err != nil {
+		return nil, fmt.Errorf("failed to get URL mappings: %w", err)
+	}
+	return mappings, nil
+}
+
+// CreateBatch inserts mappings in one transaction; either all or none are created
+func (r *URLRepository) CreateBatch(ctx context.Context, mappings []*model.URLMapping) error {
+	if len(mappings) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(mappings, len(mappings)).Error; err != nil {
+			return fmt.Errorf("failed to create URL mappings: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Monthlyaway/short-link/migrations"
+	"github.com/pressly/goose/v3"
+)
+
+// Migrate runs a goose migration command against the primary database
+// Supported commands include up, up-by-one, up-to, down, down-to, redo,
+// status and version (see goose documentation for arguments)
+func (r *URLRepository) Migrate(ctx context.Context, command string, args ...string) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database instance: %w", err)
+	}
+
+	goose.SetBaseFS(migrations.FS)
+	if err := goose.SetDialect("mysql"); err != nil {
+		return fmt.Errorf("failed to set migration dialect: %w", err)
+	}
+
+	if err := goose.RunContext(ctx, command, sqlDB, ".", args...); err != nil {
+		return fmt.Errorf("failed to run migration %q: %w", command, err)
+	}
+	return nil
+}
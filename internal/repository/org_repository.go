@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateOrg creates an organization with owner as its first member
+func (r *URLRepository) CreateOrg(ctx context.Context, org *model.Organization, owner string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return fmt.Errorf("failed to create organization: %w", err)
+		}
+		member := &model.OrgMember{OrgID: org.ID, UserID: owner, Role: model.RoleOwner}
+		if err := tx.Create(member).Error; err != nil {
+			return fmt.Errorf("failed to add organization owner: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetOrg retrieves an organization by ID
+// Returns nil if it doesn't exist
+func (r *URLRepository) GetOrg(ctx context.Context, id uint) (*model.Organization, error) {
+	var org model.Organization
+	if err := r.db.WithContext(ctx).First(&org, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	return &org, nil
+}
+
+// GetOrgByName retrieves an organization by name
+// Returns nil if it doesn't exist
+func (r *URLRepository) GetOrgByName(ctx context.Context, name string) (*model.Organization, error) {
+	var org model.Organization
+	if err := r.db.WithContext(ctx).Where("name = ?", name).First(&org).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	return &org, nil
+}
+
+// UserOrgs returns the organizations userID is a member of, by name
+func (r *URLRepository) UserOrgs(ctx context.Context, userID string) ([]model.Organization, error) {
+	var orgs []model.Organization
+	if err := r.db.WithContext(ctx).
+		Joins("JOIN org_members ON org_members.org_id = organizations.id").
+		Where("org_members.user_id = ?", userID).
+		Order("organizations.name").
+		Find(&orgs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get organizations: %w", err)
+	}
+	return orgs, nil
+}
+
+// GetOrgMember retrieves a user's membership of an organization
+// Returns nil if the user isn't a member
+func (r *URLRepository) GetOrgMember(ctx context.Context, orgID uint, userID string) (*model.OrgMember, error) {
+	var member model.OrgMember
+	if err := r.db.WithContext(ctx).
+		Where("org_id = ? AND user_id = ?", orgID, userID).
+		First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get organization member: %w", err)
+	}
+	return &member, nil
+}
+
+// OrgMembers returns the members of an organization, by user ID
+func (r *URLRepository) OrgMembers(ctx context.Context, orgID uint) ([]model.OrgMember, error) {
+	var members []model.OrgMember
+	if err := r.db.WithContext(ctx).
+		Where("org_id = ?", orgID).
+		Order("user_id").
+		Find(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to get organization members: %w", err)
+	}
+	return members, nil
+}
+
+// SetOrgMember adds a member or changes an existing member's role
+func (r *URLRepository) SetOrgMember(ctx context.Context, member *model.OrgMember) error {
+	if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"role"}),
+	}).Create(member).Error; err != nil {
+		return fmt.Errorf("failed to set organization member: %w", err)
+	}
+	return nil
+}
+
+// RemoveOrgMember removes a member from an organization
+// Returns false if the user wasn't a member
+func (r *URLRepository) RemoveOrgMember(ctx context.Context, orgID uint, userID string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("org_id = ? AND user_id = ?", orgID, userID).
+		Delete(&model.OrgMember{})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to remove organization member: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CountOrgOwners returns how many owners an organization has
+func (r *URLRepository) CountOrgOwners(ctx context.Context, orgID uint) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.OrgMember{}).
+		Where("org_id = ? AND role = ?", orgID, model.RoleOwner).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count organization owners: %w", err)
+	}
+	return count, nil
+}
+
+// LinkOrgID returns the organization owning a short code, including
+// soft-deleted links so they can be restored
+// found is false if no link has the code
+func (r *URLRepository) LinkOrgID(ctx context.Context, shortCode string) (orgID uint, found bool, err error) {
+	var mapping model.URLMapping
+	if err := r.db.WithContext(ctx).Unscoped().
+		Select("org_id").
+		Where("short_code = ?", shortCode).
+		First(&mapping).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to get link organization: %w", err)
+	}
+	return mapping.OrgID, true, nil
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// maxOutboxErrorLength is the size of outbox_events.last_error
+const maxOutboxErrorLength = 512
+
+// CreateWithOutbox inserts a new URL mapping and its outbox events in one
+// transaction, so the side effects are recorded exactly when the link is
+func (r *URLRepository) CreateWithOutbox(ctx context.Context, mapping *model.URLMapping, events []*model.OutboxEvent) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(mapping).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		return tx.Create(events).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create URL mapping: %w", err)
+	}
+	return nil
+}
+
+// CreateBatchWithOutbox is CreateBatch with the mappings' outbox events
+// inserted in the same transaction
+func (r *URLRepository) CreateBatchWithOutbox(ctx context.Context, mappings []*model.URLMapping, events []*model.OutboxEvent) error {
+	if len(mappings) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(mappings, len(mappings)).Error; err != nil {
+			return fmt.Errorf("failed to create URL mappings: %w", err)
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		if err := tx.CreateInBatches(events, len(events)).Error; err != nil {
+			return fmt.Errorf("failed to create outbox events: %w", err)
+		}
+		return nil
+	})
+}
+
+// ClaimOutboxEvents marks up to limit due events as held by token until
+// now+lease and returns them, oldest first
+// Events held by an expired claim (a dispatcher that went away) are due too.
+func (r *URLRepository) ClaimOutboxEvents(ctx context.Context, token string, now time.Time, lease time.Duration, limit int) ([]model.OutboxEvent, error) {
+	if err := r.db.WithContext(ctx).Exec(
+		"UPDATE outbox_events SET claim_token = ?, claimed_until = ?"+
+			" WHERE dispatched_at IS NULL AND failed_at IS NULL AND next_attempt_at <= ?"+
+			" AND (claimed_until IS NULL OR claimed_until < ?)"+
+			" ORDER BY id LIMIT ?",
+		token, now.Add(lease), now, now, limit,
+	).Error; err != nil {
+		return nil, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+
+	// Read the claims back from the primary; a replica may not have them yet
+	var events []model.OutboxEvent
+	if err := r.db.WithContext(ctx).Clauses(dbresolver.Write).
+		Where("claim_token = ? AND dispatched_at IS NULL AND failed_at IS NULL", token).
+		Order("id").
+		Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to load claimed outbox events: %w", err)
+	}
+	return events, nil
+}
+
+// CompleteOutboxEvent marks an event dispatched, if token still holds it
+// Reports false when the claim was lost to another dispatcher
+func (r *URLRepository) CompleteOutboxEvent(ctx context.Context, id uint64, token string, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.OutboxEvent{}).
+		Where("id = ? AND claim_token = ?", id, token).
+		Updates(map[string]interface{}{"dispatched_at": now, "claim_token": "", "claimed_until": nil})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to complete outbox event: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RetryOutboxEvent releases an event that failed for another attempt at
+// next, or marks it failed for good when failed is set
+func (r *URLRepository) RetryOutboxEvent(ctx context.Context, id uint64, token string, attempts int, next time.Time, lastErr string, failed bool) error {
+	if len(lastErr) > maxOutboxErrorLength {
+		lastErr = lastErr[:maxOutboxErrorLength]
+	}
+	updates := map[string]interface{}{
+		"attempts":        attempts,
+		"next_attempt_at": next,
+		"last_error":      lastErr,
+		"claim_token":     "",
+		"claimed_until":   nil,
+	}
+	if failed {
+		updates["failed_at"] = gorm.Expr("CURRENT_TIMESTAMP(3)")
+	}
+	if err := r.db.WithContext(ctx).Model(&model.OutboxEvent{}).
+		Where("id = ? AND claim_token = ?", id, token).
+		Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to release outbox event: %w", err)
+	}
+	return nil
+}
+
+// PurgeOutboxEvents removes dispatched or failed events created before
+// cutoff, batchSize rows per DELETE
+func (r *URLRepository) PurgeOutboxEvents(ctx context.Context, cutoff time.Time, batchSize int) (int64, error) {
+	var total int64
+	for {
+		result := r.db.WithContext(ctx).
+			Where("created_at < ? AND (dispatched_at IS NOT NULL OR failed_at IS NOT NULL)", cutoff).
+			Limit(batchSize).
+			Delete(&model.OutboxEvent{})
+		if result.Error != nil {
+			return total, fmt.Errorf("failed to purge outbox events: %w", result.Error)
+		}
+
+		total += result.RowsAffected
+		if result.RowsAffected < int64(batchSize) {
+			return total, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
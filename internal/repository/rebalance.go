@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
+)
+
+// rebalanceBatchSize bounds each scan and each batch of rows moved
+const rebalanceBatchSize = 1000
+
+// RebalanceStats counts the links a rebalance looked at and moved
+type RebalanceStats struct {
+	Scanned int64
+	Moved   int64 // Would be moved, on a dry run
+}
+
+// Rebalance moves every link that isn't on the shard the ring places it on,
+// with all rows stored under its code, to that shard
+//
+// Servers must run with rebalancing set until it has finished, so they find
+// links wherever they are in the meantime. A link moves in three steps:
+//  1. its row, tags, history and campaign memberships are copied while
+//     its row on the old shard is locked; from then on the new shard is the
+//     one servers use for it
+//  2. visit logs, rollups and abuse flags and reports are moved in batches
+//  3. it is removed from the old shard, adding visits counted there since
+//     step 1, and rows written there meanwhile are moved
+//
+// An interrupted rebalance can be run again; a batch of visit logs, flags
+// or reports that was copied but not yet removed is copied twice.
+func (r *ShardedRepository) Rebalance(ctx context.Context, dryRun bool) (RebalanceStats, error) {
+	var stats RebalanceStats
+	for _, s := range r.shards {
+		var lastID uint
+		for {
+			var batch []model.URLMapping
+			if err := s.Repo.db.WithContext(ctx).Clauses(dbresolver.Write).Unscoped().
+				Select("id", "short_code").Where("id > ?", lastID).
+				Order("id").Limit(rebalanceBatchSize).Find(&batch).Error; err != nil {
+				return stats, fmt.Errorf("failed to scan shard %s: %w", s.Name, err)
+			}
+
+			for _, mapping := range batch {
+				stats.Scanned++
+				owner := r.owner(mapping.ShortCode)
+				if owner == s.Repo {
+					continue
+				}
+				stats.Moved++
+				if dryRun {
+					continue
+				}
+				if err := moveLink(ctx, s.Repo, owner, mapping.ShortCode); err != nil {
+					return stats, fmt.Errorf("failed to move %s from shard %s to %s: %w",
+						mapping.ShortCode, s.Name, r.ring.Locate(mapping.ShortCode), err)
+				}
+			}
+
+			if len(batch) < rebalanceBatchSize {
+				break
+			}
+			lastID = batch[len(batch)-1].ID
+			if err := ctx.Err(); err != nil {
+				return stats, err
+			}
+		}
+	}
+	return stats, nil
+}
+
+// moveLink moves a link and its rows from source to target (see Rebalance)
+func moveLink(ctx context.Context, source, target *URLRepository, shortCode string) error {
+	copied, err := copyLink(ctx, source, target, shortCode)
+	if err != nil || copied == nil {
+		return err
+	}
+	if err := moveLinkRows(ctx, source, target, shortCode); err != nil {
+		return err
+	}
+	if err := releaseLink(ctx, source, target, copied); err != nil {
+		return err
+	}
+	// Rows written to source by requests that found the link there just
+	// before it was released
+	return moveLinkRows(ctx, source, target, shortCode)
+}
+
+// copyLink copies a link with its tags, history and campaign memberships to
+// target, holding a lock on its source row so no edit is lost in between
+// Returns the source row as copied, or nil if source doesn't have the link.
+func copyLink(ctx context.Context, source, target *URLRepository, shortCode string) (*model.URLMapping, error) {
+	var mapping model.URLMapping
+	err := source.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("short_code = ?", shortCode).First(&mapping).Error; err != nil {
+			return err
+		}
+
+		var tags []model.Tag
+		if err := tx.Model(&mapping).Association("Tags").Find(&tags); err != nil {
+			return fmt.Errorf("failed to read tags: %w", err)
+		}
+		var revisions []model.URLRevision
+		if err := tx.Where("short_code = ?", shortCode).Order("revision").Find(&revisions).Error; err != nil {
+			return fmt.Errorf("failed to read revisions: %w", err)
+		}
+		var campaignLinks []model.CampaignLink
+		if err := tx.Where("short_code = ?", shortCode).Find(&campaignLinks).Error; err != nil {
+			return fmt.Errorf("failed to read campaign links: %w", err)
+		}
+
+		return target.receiveLink(ctx, mapping, tags, revisions, campaignLinks)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &mapping, nil
+}
+
+// receiveLink stores a link moving in from another shard with its tags,
+// history and campaign memberships; the parts already stored by an
+// interrupted move are kept
+func (r *URLRepository) receiveLink(ctx context.Context, mapping model.URLMapping, tags []model.Tag, revisions []model.URLRevision, campaignLinks []model.CampaignLink) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing model.URLMapping
+		err := tx.Unscoped().Select("id").Where("short_code = ?", mapping.ShortCode).First(&existing).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			mapping.ID = 0
+			if err := tx.Omit(clause.Associations).Create(&mapping).Error; err != nil {
+				return fmt.Errorf("failed to copy URL mapping: %w", err)
+			}
+		case err != nil:
+			return fmt.Errorf("failed to get URL mapping: %w", err)
+		default:
+			mapping.ID = existing.ID
+		}
+
+		if len(tags) > 0 {
+			names := make([]string, 0, len(tags))
+			for _, tag := range tags {
+				names = append(names, tag.Name)
+			}
+			targetTags, err := ensureTags(tx, names)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&mapping).Association("Tags").Append(targetTags); err != nil {
+				return fmt.Errorf("failed to copy tags: %w", err)
+			}
+		}
+		if len(revisions) > 0 {
+			for i := range revisions {
+				revisions[i].ID = 0
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&revisions).Error; err != nil {
+				return fmt.Errorf("failed to copy revisions: %w", err)
+			}
+		}
+		if len(campaignLinks) > 0 {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&campaignLinks).Error; err != nil {
+				return fmt.Errorf("failed to copy campaign links: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// moveLinkRows moves the visit logs, rollups and abuse flags and reports of
+// a short code from source to target
+func moveLinkRows(ctx context.Context, source, target *URLRepository, shortCode string) error {
+	if err := moveRows(ctx, source, target, shortCode, func(v *model.VisitLog) *uint { return &v.ID }); err != nil {
+		return fmt.Errorf("failed to move visit logs: %w", err)
+	}
+	if err := moveRows(ctx, source, target, shortCode, func(f *model.AbuseFlag) *uint { return &f.ID }); err != nil {
+		return fmt.Errorf("failed to move abuse flags: %w", err)
+	}
+	if err := moveRows(ctx, source, target, shortCode, func(a *model.AbuseReport) *uint { return &a.ID }); err != nil {
+		return fmt.Errorf("failed to move abuse reports: %w", err)
+	}
+	// Days target rolls up again are recomputed from the moved visit logs;
+	// the others keep the copied rows
+	if err := copyRollups[model.VisitDailyStat](ctx, source, target, shortCode); err != nil {
+		return fmt.Errorf("failed to copy daily stats: %w", err)
+	}
+	if err := copyRollups[model.VisitDailyBreakdown](ctx, source, target, shortCode); err != nil {
+		return fmt.Errorf("failed to copy daily breakdowns: %w", err)
+	}
+	return nil
+}
+
+// moveRows moves the rows of a short code in the table of T, which has an
+// auto-increment ID, in batches: each batch gets new IDs on target and is
+// then deleted from source
+func moveRows[T any](ctx context.Context, source, target *URLRepository, shortCode string, id func(*T) *uint) error {
+	for {
+		var batch []T
+		if err := source.db.WithContext(ctx).Clauses(dbresolver.Write).
+			Where("short_code = ?", shortCode).Order("id").Limit(rebalanceBatchSize).
+			Find(&batch).Error; err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(batch))
+		for i := range batch {
+			ids = append(ids, *id(&batch[i]))
+			*id(&batch[i]) = 0
+		}
+		if err := target.db.WithContext(ctx).CreateInBatches(&batch, rebalanceBatchSize).Error; err != nil {
+			return err
+		}
+		if err := source.db.WithContext(ctx).Where("id IN ?", ids).Delete(new(T)).Error; err != nil {
+			return err
+		}
+		if len(batch) < rebalanceBatchSize {
+			return nil
+		}
+	}
+}
+
+// copyRollups copies the rollup rows of a short code in the table of T;
+// rows target already has for a day are kept. releaseLink deletes them
+// from source.
+func copyRollups[T any](ctx context.Context, source, target *URLRepository, shortCode string) error {
+	var rows []T
+	if err := source.db.WithContext(ctx).Clauses(dbresolver.Write).
+		Where("short_code = ?", shortCode).Find(&rows).Error; err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return target.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(&rows, rebalanceBatchSize).Error
+}
+
+// releaseLink removes a link copied to target from source, with its tags,
+// history, campaign memberships and rollups, and adds to target the visits
+// source counted after the copy
+func releaseLink(ctx context.Context, source, target *URLRepository, copied *model.URLMapping) error {
+	return source.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var mapping model.URLMapping
+		err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", copied.ID).First(&mapping).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		visits := countedSince(copied.VisitCount, mapping.VisitCount)
+		botVisits := countedSince(copied.BotVisitCount, mapping.BotVisitCount)
+		duplicates := countedSince(copied.DuplicateVisitCount, mapping.DuplicateVisitCount)
+		if visits > 0 || botVisits > 0 || duplicates > 0 {
+			if err := target.db.WithContext(ctx).Model(&model.URLMapping{}).Unscoped().
+				Where("short_code = ?", mapping.ShortCode).
+				UpdateColumns(map[string]interface{}{
+					"visit_count":           gorm.Expr("visit_count + ?", visits),
+					"bot_visit_count":       gorm.Expr("bot_visit_count + ?", botVisits),
+					"duplicate_visit_count": gorm.Expr("duplicate_visit_count + ?", duplicates),
+				}).Error; err != nil {
+				return fmt.Errorf("failed to carry over visit counts: %w", err)
+			}
+		}
+
+		if err := tx.Exec("DELETE FROM `url_mapping_tags` WHERE `url_mapping_id` = ?", mapping.ID).Error; err != nil {
+			return fmt.Errorf("failed to delete link tags: %w", err)
+		}
+		for _, value := range []interface{}{&model.URLRevision{}, &model.CampaignLink{}, &model.VisitDailyStat{}, &model.VisitDailyBreakdown{}} {
+			if err := tx.Where("short_code = ?", mapping.ShortCode).Delete(value).Error; err != nil {
+				return fmt.Errorf("failed to delete link rows: %w", err)
+			}
+		}
+		if err := tx.Unscoped().Delete(&mapping).Error; err != nil {
+			return fmt.Errorf("failed to delete URL mapping: %w", err)
+		}
+		return nil
+	})
+}
+
+// countedSince returns how much a counter grew from then to now
+func countedSince(then, now uint64) uint64 {
+	if now < then {
+		return 0
+	}
+	return now - then
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// neverVisited matches links that never received any traffic: no counted
+// or bot visits, no visit logs and no abuse flags or reports
+const neverVisited = "visit_count = 0 AND bot_visit_count = 0" +
+	" AND NOT EXISTS (SELECT 1 FROM visit_logs v WHERE v.short_code = url_mappings.short_code)" +
+	" AND NOT EXISTS (SELECT 1 FROM abuse_flags f WHERE f.short_code = url_mappings.short_code)" +
+	" AND NOT EXISTS (SELECT 1 FROM abuse_reports a WHERE a.short_code = url_mappings.short_code)"
+
+// RecyclableLinks returns links, including soft-deleted ones, that expired
+// before expiredBefore and never received traffic, at most limit
+func (r *URLRepository) RecyclableLinks(ctx context.Context, expiredBefore time.Time, limit int) ([]model.URLMapping, error) {
+	var mappings []model.URLMapping
+	if err := r.db.WithContext(ctx).Unscoped().
+		Select("id", "short_code").
+		Where("expired_at IS NOT NULL AND expired_at < ?", expiredBefore).
+		Where(neverVisited).
+		Order("expired_at").Limit(limit).
+		Find(&mappings).Error; err != nil {
+		return nil, fmt.Errorf("failed to list recyclable links: %w", err)
+	}
+	return mappings, nil
+}
+
+// RecycleLink permanently removes a link together with its tags, campaign
+// memberships and edit history, and puts its code into the free pool
+// The conditions of RecyclableLinks are checked again in the transaction,
+// so a link that was visited or extended since it was listed is kept.
+// Reports whether the link was recycled.
+func (r *URLRepository) RecycleLink(ctx context.Context, mapping *model.URLMapping, expiredBefore time.Time) (bool, error) {
+	recycled := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().
+			Where("id = ? AND expired_at IS NOT NULL AND expired_at < ?", mapping.ID, expiredBefore).
+			Where(neverVisited).
+			Delete(&model.URLMapping{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Exec("DELETE FROM url_mapping_tags WHERE url_mapping_id = ?", mapping.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("short_code = ?", mapping.ShortCode).Delete(&model.CampaignLink{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("short_code = ?", mapping.ShortCode).Delete(&model.URLRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.RecycledCode{ShortCode: mapping.ShortCode}).Error; err != nil {
+			return err
+		}
+		recycled = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to recycle link %s: %w", mapping.ShortCode, err)
+	}
+	return recycled, nil
+}
+
+// ClaimRecycledCode takes the shortest code out of the free pool
+// Returns "" when the pool is empty. Rows locked by a concurrent claim are
+// skipped, so two links never get the same code.
+func (r *URLRepository) ClaimRecycledCode(ctx context.Context) (string, error) {
+	var code model.RecycledCode
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Order("CHAR_LENGTH(short_code), recycled_at").
+			First(&code).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&code).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to claim a recycled code: %w", err)
+	}
+	return code.ShortCode, nil
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AddRevisions records first revisions, e.g. of newly created links
+func (r *URLRepository) AddRevisions(ctx context.Context, revisions []model.URLRevision) error {
+	if len(revisions) == 0 {
+		return nil
+	}
+	if err := r.db.WithContext(ctx).CreateInBatches(&revisions, 500).Error; err != nil {
+		return fmt.Errorf("failed to create revisions: %w", err)
+	}
+	return nil
+}
+
+// UpdateWithRevision applies update to a link and records the change as its
+// next revision, in one transaction
+// The link row is locked so concurrent edits get consecutive revisions.
+// Returns the updated mapping, or nil if the short code doesn't exist.
+func (r *URLRepository) UpdateWithRevision(ctx context.Context, shortCode string, update func(*model.URLMapping) *model.URLRevision) (*model.URLMapping, error) {
+	var mapping model.URLMapping
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("short_code = ?", shortCode).First(&mapping).Error; err != nil {
+			return err
+		}
+
+		revision := update(&mapping)
+		if revision == nil {
+			return nil
+		}
+		if err := tx.Model(&mapping).Select("original_url", "display_url", "destination_host", "url_hash", "expired_at", "cache_ttl", "no_cache", "access_rules", "deep_links", "metadata", "updated_at").
+			Updates(&mapping).Error; err != nil {
+			return fmt.Errorf("failed to update URL mapping: %w", err)
+		}
+
+		var last int
+		if err := tx.Model(&model.URLRevision{}).Where("short_code = ?", shortCode).
+			Select("COALESCE(MAX(revision), 0)").Scan(&last).Error; err != nil {
+			return fmt.Errorf("failed to get last revision: %w", err)
+		}
+		revision.ShortCode = shortCode
+		revision.Revision = last + 1
+		if err := tx.Create(revision).Error; err != nil {
+			return fmt.Errorf("failed to create revision: %w", err)
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &mapping, nil
+}
+
+// ListRevisions returns a link's history, newest first
+func (r *URLRepository) ListRevisions(ctx context.Context, shortCode string) ([]model.URLRevision, error) {
+	var revisions []model.URLRevision
+	if err := r.db.WithContext(ctx).Where("short_code = ?", shortCode).
+		Order("revision DESC").Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %w", err)
+	}
+	return revisions, nil
+}
@@ -0,0 +1,381 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// Queries across links, run on every shard and merged (see
+// ShardedRepository). Each shard returns its own first rows in the final
+// order, so the merged first rows are exact; counts are summed.
+
+// GetByOriginalURL retrieves a URL mapping by original URL on a domain,
+// from the first shard that has one
+func (r *ShardedRepository) GetByOriginalURL(ctx context.Context, originalURL, domain string, orgID uint) (*model.URLMapping, error) {
+	found := make([]*model.URLMapping, len(r.shards))
+	err := r.scatter(func(i int, repo *URLRepository) error {
+		var err error
+		found[i], err = repo.GetByOriginalURL(ctx, originalURL, domain, orgID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, mapping := range found {
+		if mapping != nil {
+			return mapping, nil
+		}
+	}
+	return nil, nil
+}
+
+// ActiveByOriginalURLs returns the active, unexpired links on domain for any
+// of originalURLs, among links without an organization
+func (r *ShardedRepository) ActiveByOriginalURLs(ctx context.Context, domain string, originalURLs []string) ([]model.URLMapping, error) {
+	if len(originalURLs) == 0 {
+		return nil, nil
+	}
+	return gather(r, func(repo *URLRepository) ([]model.URLMapping, error) {
+		return repo.ActiveByOriginalURLs(ctx, domain, originalURLs)
+	})
+}
+
+// ListURLs returns one page of live links matching filter, with their tags,
+// and the total number of matches on all shards
+func (r *ShardedRepository) ListURLs(ctx context.Context, filter model.URLFilter) ([]model.URLMapping, int64, error) {
+	shardFilter := filter
+	offset := 0
+	if filter.After == nil && filter.Page > 1 {
+		// Any shard may hold all links up to the end of the page
+		offset = (filter.Page - 1) * filter.PageSize
+		shardFilter.Page = 1
+		shardFilter.PageSize = offset + filter.PageSize
+	}
+
+	pages := make([][]model.URLMapping, len(r.shards))
+	totals := make([]int64, len(r.shards))
+	err := r.scatter(func(i int, repo *URLRepository) error {
+		var err error
+		pages[i], totals[i], err = repo.ListURLs(ctx, shardFilter)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var mappings []model.URLMapping
+	var total int64
+	for i := range pages {
+		mappings = append(mappings, pages[i]...)
+		total += totals[i]
+	}
+	visitCount := filter.Sort == model.SortVisitCount
+	sort.SliceStable(mappings, func(i, j int) bool {
+		a, b := &mappings[i], &mappings[j]
+		if filter.Ascending {
+			a, b = b, a
+		}
+		// Same order as the shard query: descending unless Ascending
+		switch {
+		case visitCount && a.VisitCount != b.VisitCount:
+			return a.VisitCount > b.VisitCount
+		case !visitCount && !a.CreatedAt.Equal(b.CreatedAt):
+			return a.CreatedAt.After(b.CreatedAt)
+		default:
+			return a.ID > b.ID
+		}
+	})
+	return page(mappings, offset, filter.PageSize), total, nil
+}
+
+// MostVisitedActive returns up to limit active, unexpired, cacheable links,
+// most visited first
+func (r *ShardedRepository) MostVisitedActive(ctx context.Context, limit int) ([]model.URLMapping, error) {
+	mappings, err := gather(r, func(repo *URLRepository) ([]model.URLMapping, error) {
+		return repo.MostVisitedActive(ctx, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(mappings, func(i, j int) bool { return mappings[i].VisitCount > mappings[j].VisitCount })
+	return page(mappings, 0, limit), nil
+}
+
+// StreamURLMappings calls fn with successive batches of URL mappings of
+// each shard in turn, ordered by id within a shard
+func (r *ShardedRepository) StreamURLMappings(ctx context.Context, domain string, includeDeleted bool, updatedSince time.Time, fn func([]model.URLMapping) error) error {
+	for _, s := range r.shards {
+		if err := s.Repo.StreamURLMappings(ctx, domain, includeDeleted, updatedSince, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAllShortCodes retrieves the short codes of every shard
+func (r *ShardedRepository) GetAllShortCodes(ctx context.Context) ([]string, error) {
+	return gather(r, func(repo *URLRepository) ([]string, error) {
+		return repo.GetAllShortCodes(ctx)
+	})
+}
+
+// VisitRollupWatermark returns the earliest watermark of the shards with
+// visit logs: rollups are complete on every shard up to there
+// Each shard rolls up its own visit logs (see service.VisitRollup).
+func (r *ShardedRepository) VisitRollupWatermark(ctx context.Context) (time.Time, error) {
+	watermarks := make([]time.Time, len(r.shards))
+	empty := make([]bool, len(r.shards))
+	err := r.scatter(func(i int, repo *URLRepository) error {
+		var err error
+		watermarks[i], err = repo.VisitRollupWatermark(ctx)
+		if err != nil || !watermarks[i].IsZero() {
+			return err
+		}
+		// Nothing rolled up; fine if there is nothing to roll up
+		first, err := repo.FirstVisitTime(ctx)
+		empty[i] = first.IsZero()
+		return err
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var earliest time.Time
+	for i, watermark := range watermarks {
+		switch {
+		case empty[i]:
+			continue
+		case watermark.IsZero():
+			return time.Time{}, nil
+		case earliest.IsZero() || watermark.Before(earliest):
+			earliest = watermark
+		}
+	}
+	return earliest, nil
+}
+
+// CampaignLinkStats returns the visit counters of every live link in a
+// campaign, most visited first
+func (r *ShardedRepository) CampaignLinkStats(ctx context.Context, campaignID uint) ([]model.CampaignLinkStat, error) {
+	stats, err := gather(r, func(repo *URLRepository) ([]model.CampaignLinkStat, error) {
+		return repo.CampaignLinkStats(ctx, campaignID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].VisitCount > stats[j].VisitCount })
+	return stats, nil
+}
+
+// CampaignVisitBreakdown counts human visits to a campaign's links grouped
+// by column, most frequent first
+// A value's count is spread over the shards, so they return every value.
+func (r *ShardedRepository) CampaignVisitBreakdown(ctx context.Context, campaignID uint, column string, from, to time.Time, limit int) ([]model.VisitStat, error) {
+	stats, err := gather(r, func(repo *URLRepository) ([]model.VisitStat, error) {
+		return repo.CampaignVisitBreakdown(ctx, campaignID, column, from, to, -1)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sumVisitStats(stats, limit), nil
+}
+
+// sumVisitStats adds up the counts of each value in breakdowns from several
+// shards and returns the limit most frequent values
+func sumVisitStats(shardStats []model.VisitStat, limit int) []model.VisitStat {
+	counts := make(map[string]int64)
+	for _, stat := range shardStats {
+		counts[stat.Value] += stat.Count
+	}
+	stats := make([]model.VisitStat, 0, len(counts))
+	for value, count := range counts {
+		stats = append(stats, model.VisitStat{Value: value, Count: count})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Value < stats[j].Value
+	})
+	return page(stats, 0, limit)
+}
+
+// ListAbuseFlags returns the newest abuse flags, of one link if shortCode is
+// set, at most limit
+func (r *ShardedRepository) ListAbuseFlags(ctx context.Context, shortCode string, limit int) ([]model.AbuseFlag, error) {
+	if shortCode != "" {
+		repo, err := r.locate(ctx, shortCode)
+		if err != nil {
+			return nil, err
+		}
+		return repo.ListAbuseFlags(ctx, shortCode, limit)
+	}
+
+	flags, err := gather(r, func(repo *URLRepository) ([]model.AbuseFlag, error) {
+		return repo.ListAbuseFlags(ctx, "", limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(flags, func(i, j int) bool { return flags[i].CreatedAt.After(flags[j].CreatedAt) })
+	return page(flags, 0, limit), nil
+}
+
+// ListReportedLinks returns links with open reports, most reported first,
+// at most limit
+func (r *ShardedRepository) ListReportedLinks(ctx context.Context, limit int) ([]model.ReportedLink, error) {
+	links, err := gather(r, func(repo *URLRepository) ([]model.ReportedLink, error) {
+		return repo.ListReportedLinks(ctx, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(links, func(i, j int) bool {
+		if links[i].Reports != links[j].Reports {
+			return links[i].Reports > links[j].Reports
+		}
+		return links[i].LastReportedAt.After(links[j].LastReportedAt)
+	})
+	return page(links, 0, limit), nil
+}
+
+// SummaryClicks counts human visits in [from, to) to a subscription's links
+func (r *ShardedRepository) SummaryClicks(ctx context.Context, sub *model.SummarySubscription, from, to time.Time) (int64, error) {
+	return r.sum(func(repo *URLRepository) (int64, error) {
+		return repo.SummaryClicks(ctx, sub, from, to)
+	})
+}
+
+// SummaryNewLinks counts a subscription's links created in [from, to) and
+// returns up to limit of them, newest first
+func (r *ShardedRepository) SummaryNewLinks(ctx context.Context, sub *model.SummarySubscription, from, to time.Time, limit int) (int64, []model.URLMapping, error) {
+	counts := make([]int64, len(r.shards))
+	results := make([][]model.URLMapping, len(r.shards))
+	err := r.scatter(func(i int, repo *URLRepository) error {
+		var err error
+		counts[i], results[i], err = repo.SummaryNewLinks(ctx, sub, from, to, limit)
+		return err
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	var count int64
+	var mappings []model.URLMapping
+	for i := range results {
+		count += counts[i]
+		mappings = append(mappings, results[i]...)
+	}
+	sort.SliceStable(mappings, func(i, j int) bool { return mappings[i].CreatedAt.After(mappings[j].CreatedAt) })
+	return count, page(mappings, 0, limit), nil
+}
+
+// SummaryTopLinks returns up to limit of a subscription's links with the
+// most human visits in [from, to)
+func (r *ShardedRepository) SummaryTopLinks(ctx context.Context, sub *model.SummarySubscription, from, to time.Time, limit int) ([]model.SummaryLink, error) {
+	links, err := gather(r, func(repo *URLRepository) ([]model.SummaryLink, error) {
+		return repo.SummaryTopLinks(ctx, sub, from, to, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(links, func(i, j int) bool { return links[i].Clicks > links[j].Clicks })
+	return page(links, 0, limit), nil
+}
+
+// StreamVisitLogsByIP calls fn with successive batches of the visit logs
+// stored with any of ips, one shard after the other
+func (r *ShardedRepository) StreamVisitLogsByIP(ctx context.Context, ips []string, fn func([]model.VisitLog) error) error {
+	for _, s := range r.shards {
+		if err := s.Repo.StreamVisitLogsByIP(ctx, ips, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteVisitLogsByIP removes the visit logs stored with any of ips on
+// every shard
+func (r *ShardedRepository) DeleteVisitLogsByIP(ctx context.Context, ips []string) (int64, error) {
+	return r.sum(func(repo *URLRepository) (int64, error) {
+		return repo.DeleteVisitLogsByIP(ctx, ips)
+	})
+}
+
+// AbuseReportsByIP returns the abuse reports sent from any of ips
+func (r *ShardedRepository) AbuseReportsByIP(ctx context.Context, ips []string) ([]model.AbuseReport, error) {
+	return gather(r, func(repo *URLRepository) ([]model.AbuseReport, error) {
+		return repo.AbuseReportsByIP(ctx, ips)
+	})
+}
+
+// DeleteAbuseReportsByIP removes the abuse reports sent from any of ips on
+// every shard
+func (r *ShardedRepository) DeleteAbuseReportsByIP(ctx context.Context, ips []string) (int64, error) {
+	return r.sum(func(repo *URLRepository) (int64, error) {
+		return repo.DeleteAbuseReportsByIP(ctx, ips)
+	})
+}
+
+// LinksCreatedBy returns the links, on every shard, whose first revision was
+// made by actor
+func (r *ShardedRepository) LinksCreatedBy(ctx context.Context, actor string) ([]model.URLMapping, error) {
+	return gather(r, func(repo *URLRepository) ([]model.URLMapping, error) {
+		return repo.LinksCreatedBy(ctx, actor)
+	})
+}
+
+// PurgeLinks removes links for good from their shards, with their visit
+// logs, history, tag assignments and outbox events
+func (r *ShardedRepository) PurgeLinks(ctx context.Context, mappings []model.URLMapping) (visitLogs, links int64, err error) {
+	byRepo := make(map[*URLRepository][]model.URLMapping, len(r.shards))
+	for _, mapping := range mappings {
+		repo, err := r.locate(ctx, mapping.ShortCode)
+		if err != nil {
+			return visitLogs, links, err
+		}
+		byRepo[repo] = append(byRepo[repo], mapping)
+	}
+	for _, s := range r.shards {
+		shardLogs, shardLinks, err := s.Repo.PurgeLinks(ctx, byRepo[s.Repo])
+		visitLogs += shardLogs
+		links += shardLinks
+		if err != nil {
+			return visitLogs, links, err
+		}
+	}
+	return visitLogs, links, nil
+}
+
+// gather runs a query on every shard and concatenates the results in shard
+// order
+func gather[T any](r *ShardedRepository, query func(repo *URLRepository) ([]T, error)) ([]T, error) {
+	results := make([][]T, len(r.shards))
+	err := r.scatter(func(i int, repo *URLRepository) error {
+		var err error
+		results[i], err = query(repo)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var all []T
+	for _, shardResults := range results {
+		all = append(all, shardResults...)
+	}
+	return all, nil
+}
+
+// page returns up to limit items of merged results starting at offset
+func page[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit >= 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
@@ -0,0 +1,829 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/shard"
+	"gorm.io/plugin/dbresolver"
+)
+
+// ============================================================================
+// SHARDED STORAGE
+// ============================================================================
+// ShardedRepository spreads links over several MySQL databases. A link and
+// every row stored under its short code (visit logs and rollups, history,
+// tags, campaign memberships, abuse flags and reports, outbox events) live
+// on the shard the consistent hash ring places the code on. Tables that
+// aren't per link (organizations, campaigns, aliases, summary
+// subscriptions, reserved codes, the code sequence) stay in the home
+// database, which may also be one of the shards. Every database gets the
+// full schema from the same migrations.
+//
+// Operations on one link go to its shard. Operations across links (lists,
+// search, campaign and summary reports, data subject requests) query every
+// shard concurrently and merge: results are re-sorted and cut to the limit,
+// counts are summed. Lists fetch page*size rows per shard for offset
+// pagination; keyset pagination (URLFilter.After) stays one page per shard.
+//
+// Row IDs are per shard, so two links on different shards can share an ID.
+// Outbox event IDs are made unique by carrying the shard's position in
+// their top byte (see outboxEventID).
+//
+// Adding a shard (or draining one) moves some codes to another shard on the
+// ring. Until Rebalance has moved their rows, run with rebalancing set:
+// lookups that miss a code's new shard then find it on the others.
+// ============================================================================
+
+// outboxShardShift puts the shard's position in the top byte of an
+// outbox event ID; per-shard IDs stay below 2^56
+const outboxShardShift = 56
+
+// MaxShards is the most shards a ShardedRepository can route to
+const MaxShards = 1 << (64 - outboxShardShift)
+
+// Shard is one database of a ShardedRepository
+type Shard struct {
+	Name string
+	Repo *URLRepository
+}
+
+// ShardedRepository routes link storage over shards by short code
+type ShardedRepository struct {
+	home        *URLRepository
+	ring        *shard.Ring
+	shards      []Shard // Configuration order; positions are in outbox event IDs
+	byName      map[string]*URLRepository
+	rebalancing bool
+}
+
+// NewShardedRepository creates a repository storing links on shards, placed
+// by ring, and everything else in home
+// shards must include every shard of the ring; shards missing from the
+// ring are draining: no links are placed on them, but they are still read.
+func NewShardedRepository(home *URLRepository, ring *shard.Ring, shards []Shard, rebalancing bool) (*ShardedRepository, error) {
+	if len(shards) > MaxShards {
+		return nil, fmt.Errorf("at most %d shards are supported, got %d", MaxShards, len(shards))
+	}
+	byName := make(map[string]*URLRepository, len(shards))
+	for _, s := range shards {
+		if _, ok := byName[s.Name]; ok {
+			return nil, fmt.Errorf("duplicate shard %q", s.Name)
+		}
+		byName[s.Name] = s.Repo
+	}
+	for _, name := range ring.Shards() {
+		if byName[name] == nil {
+			return nil, fmt.Errorf("shard %q is on the ring but not connected", name)
+		}
+	}
+	return &ShardedRepository{
+		home:        home,
+		ring:        ring,
+		shards:      append([]Shard(nil), shards...),
+		byName:      byName,
+		rebalancing: rebalancing,
+	}, nil
+}
+
+// Shards returns the shards in configuration order
+func (r *ShardedRepository) Shards() []Shard {
+	return append([]Shard(nil), r.shards...)
+}
+
+// owner returns the shard the ring places a short code on
+func (r *ShardedRepository) owner(shortCode string) *URLRepository {
+	return r.byName[r.ring.Locate(shortCode)]
+}
+
+// locate returns the shard holding a short code: its owner, or while
+// rebalancing, another shard that has it and the owner doesn't yet
+// Codes found nowhere belong to their owner.
+func (r *ShardedRepository) locate(ctx context.Context, shortCode string) (*URLRepository, error) {
+	owner := r.owner(shortCode)
+	if !r.rebalancing {
+		return owner, nil
+	}
+	found, err := owner.hasShortCode(ctx, shortCode)
+	if err != nil || found {
+		return owner, err
+	}
+	for _, s := range r.shards {
+		if s.Repo == owner {
+			continue
+		}
+		found, err := s.Repo.hasShortCode(ctx, shortCode)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			return s.Repo, nil
+		}
+	}
+	return owner, nil
+}
+
+// hasShortCode reports whether a link (soft-deleted or not) uses a short
+// code, reading from the primary so a link that just moved is seen
+func (r *URLRepository) hasShortCode(ctx context.Context, shortCode string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Clauses(dbresolver.Write).Unscoped().Model(&model.URLMapping{}).
+		Where("short_code = ?", shortCode).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check short code: %w", err)
+	}
+	return count > 0, nil
+}
+
+// shardCodes are the short codes of one call that live on one shard
+type shardCodes struct {
+	repo  *URLRepository
+	codes []string
+}
+
+// group splits short codes by the shard holding them (see locate), in
+// shard order
+func (r *ShardedRepository) group(ctx context.Context, shortCodes []string) ([]shardCodes, error) {
+	byRepo := make(map[*URLRepository][]string, len(r.shards))
+	for _, code := range shortCodes {
+		repo, err := r.locate(ctx, code)
+		if err != nil {
+			return nil, err
+		}
+		byRepo[repo] = append(byRepo[repo], code)
+	}
+	groups := make([]shardCodes, 0, len(byRepo))
+	for _, s := range r.shards {
+		if codes := byRepo[s.Repo]; len(codes) > 0 {
+			groups = append(groups, shardCodes{repo: s.Repo, codes: codes})
+		}
+	}
+	return groups, nil
+}
+
+// scatter calls fn for every shard concurrently and returns the error of
+// the first shard that failed
+func (r *ShardedRepository) scatter(fn func(i int, repo *URLRepository) error) error {
+	errs := make([]error, len(r.shards))
+	var wg sync.WaitGroup
+	for i, s := range r.shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn(i, s.Repo)
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("shard %s: %w", r.shards[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// Close closes the connections of the shards; home is left open
+func (r *ShardedRepository) Close() error {
+	var firstErr error
+	for _, s := range r.shards {
+		if s.Repo == r.home {
+			continue
+		}
+		if err := s.Repo.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Ping checks that the home database and every shard are reachable
+func (r *ShardedRepository) Ping(ctx context.Context) error {
+	if err := r.home.Ping(ctx); err != nil {
+		return err
+	}
+	return r.scatter(func(_ int, repo *URLRepository) error {
+		return repo.Ping(ctx)
+	})
+}
+
+// Create creates a new URL mapping on its shard
+func (r *ShardedRepository) Create(ctx context.Context, mapping *model.URLMapping) error {
+	return r.owner(mapping.ShortCode).Create(ctx, mapping)
+}
+
+// CreateBatch inserts mappings, one transaction per shard
+// A batch spanning shards isn't atomic: a failure on one shard leaves the
+// links already stored on the others.
+func (r *ShardedRepository) CreateBatch(ctx context.Context, mappings []*model.URLMapping) error {
+	for _, batch := range r.splitMappings(mappings, nil) {
+		if err := batch.repo.CreateBatch(ctx, batch.mappings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateWithOutbox inserts a new URL mapping and its outbox events on its
+// shard, in one transaction
+func (r *ShardedRepository) CreateWithOutbox(ctx context.Context, mapping *model.URLMapping, events []*model.OutboxEvent) error {
+	return r.owner(mapping.ShortCode).CreateWithOutbox(ctx, mapping, events)
+}
+
+// CreateBatchWithOutbox is CreateBatch with the mappings' outbox events
+// stored next to them, one transaction per shard
+func (r *ShardedRepository) CreateBatchWithOutbox(ctx context.Context, mappings []*model.URLMapping, events []*model.OutboxEvent) error {
+	for _, batch := range r.splitMappings(mappings, events) {
+		if err := batch.repo.CreateBatchWithOutbox(ctx, batch.mappings, batch.events); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shardMappings are the new links of one call, and their outbox events,
+// placed on one shard
+type shardMappings struct {
+	repo     *URLRepository
+	mappings []*model.URLMapping
+	events   []*model.OutboxEvent
+}
+
+// splitMappings places new links and their events on their owners, in
+// shard order
+func (r *ShardedRepository) splitMappings(mappings []*model.URLMapping, events []*model.OutboxEvent) []shardMappings {
+	byRepo := make(map[*URLRepository]*shardMappings, len(r.shards))
+	get := func(shortCode string) *shardMappings {
+		repo := r.owner(shortCode)
+		if byRepo[repo] == nil {
+			byRepo[repo] = &shardMappings{repo: repo}
+		}
+		return byRepo[repo]
+	}
+	for _, mapping := range mappings {
+		batch := get(mapping.ShortCode)
+		batch.mappings = append(batch.mappings, mapping)
+	}
+	for _, event := range events {
+		batch := get(event.ShortCode)
+		batch.events = append(batch.events, event)
+	}
+
+	batches := make([]shardMappings, 0, len(byRepo))
+	for _, s := range r.shards {
+		if batch := byRepo[s.Repo]; batch != nil {
+			batches = append(batches, *batch)
+		}
+	}
+	return batches
+}
+
+// GetByShortCode retrieves a URL mapping by short code
+func (r *ShardedRepository) GetByShortCode(ctx context.Context, shortCode string) (*model.URLMapping, error) {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetByShortCode(ctx, shortCode)
+}
+
+// GetByShortCodeFromPrimary is GetByShortCode read from the shard's primary
+func (r *ShardedRepository) GetByShortCodeFromPrimary(ctx context.Context, shortCode string) (*model.URLMapping, error) {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetByShortCodeFromPrimary(ctx, shortCode)
+}
+
+// GetByShortCodes retrieves the live URL mappings of shortCodes from their
+// shards, in no particular order; missing codes are skipped
+func (r *ShardedRepository) GetByShortCodes(ctx context.Context, shortCodes []string) ([]model.URLMapping, error) {
+	groups, err := r.group(ctx, shortCodes)
+	if err != nil {
+		return nil, err
+	}
+	var mappings []model.URLMapping
+	for _, g := range groups {
+		found, err := g.repo.GetByShortCodes(ctx, g.codes)
+		if err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, found...)
+	}
+	return mappings, nil
+}
+
+// UpdateWithRevision applies update to a link on its shard and records the
+// change as its next revision
+func (r *ShardedRepository) UpdateWithRevision(ctx context.Context, shortCode string, update func(*model.URLMapping) *model.URLRevision) (*model.URLMapping, error) {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	return repo.UpdateWithRevision(ctx, shortCode, update)
+}
+
+// Delete soft-deletes a URL mapping by short code
+func (r *ShardedRepository) Delete(ctx context.Context, shortCode string) error {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return err
+	}
+	return repo.Delete(ctx, shortCode)
+}
+
+// Restore undoes a soft delete
+func (r *ShardedRepository) Restore(ctx context.Context, shortCode string) (bool, error) {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return false, err
+	}
+	return repo.Restore(ctx, shortCode)
+}
+
+// LinkOrgID returns the organization owning a link
+func (r *ShardedRepository) LinkOrgID(ctx context.Context, shortCode string) (uint, bool, error) {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return 0, false, err
+	}
+	return repo.LinkOrgID(ctx, shortCode)
+}
+
+// LinkCreator returns who made a link's first revision
+func (r *ShardedRepository) LinkCreator(ctx context.Context, shortCode string) (string, error) {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return "", err
+	}
+	return repo.LinkCreator(ctx, shortCode)
+}
+
+// SetLinkTitle stores the destination page title of a link
+func (r *ShardedRepository) SetLinkTitle(ctx context.Context, shortCode, title string) error {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return err
+	}
+	return repo.SetLinkTitle(ctx, shortCode, title)
+}
+
+// ShortCodeTaken reports whether a short code is used, including by
+// soft-deleted links
+func (r *ShardedRepository) ShortCodeTaken(ctx context.Context, shortCode string) (bool, error) {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return false, err
+	}
+	return repo.ShortCodeTaken(ctx, shortCode)
+}
+
+// TakenShortCodes returns which of shortCodes are used by a link, including
+// soft-deleted ones
+func (r *ShardedRepository) TakenShortCodes(ctx context.Context, shortCodes []string) ([]string, error) {
+	return r.collectCodes(ctx, shortCodes, (*URLRepository).TakenShortCodes)
+}
+
+// ExistingShortCodes returns which of shortCodes belong to live links
+func (r *ShardedRepository) ExistingShortCodes(ctx context.Context, shortCodes []string) ([]string, error) {
+	return r.collectCodes(ctx, shortCodes, (*URLRepository).ExistingShortCodes)
+}
+
+// collectCodes runs a short code check on each shard for the codes it holds
+func (r *ShardedRepository) collectCodes(ctx context.Context, shortCodes []string, check func(*URLRepository, context.Context, []string) ([]string, error)) ([]string, error) {
+	groups, err := r.group(ctx, shortCodes)
+	if err != nil {
+		return nil, err
+	}
+	var found []string
+	for _, g := range groups {
+		codes, err := check(g.repo, ctx, g.codes)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, codes...)
+	}
+	return found, nil
+}
+
+// ClaimRecycledCode takes a code out of the free pool of the first shard
+// that has one
+// Each shard pools the codes of the links it recycled.
+func (r *ShardedRepository) ClaimRecycledCode(ctx context.Context) (string, error) {
+	for _, s := range r.shards {
+		code, err := s.Repo.ClaimRecycledCode(ctx)
+		if err != nil || code != "" {
+			return code, err
+		}
+	}
+	return "", nil
+}
+
+// ListReservedCodes returns all rows of the reserved_codes table
+func (r *ShardedRepository) ListReservedCodes(ctx context.Context) ([]model.ReservedCode, error) {
+	return r.home.ListReservedCodes(ctx)
+}
+
+// AddTags adds tags to a mapping on its shard
+func (r *ShardedRepository) AddTags(ctx context.Context, mapping *model.URLMapping, names []string) error {
+	repo, err := r.locate(ctx, mapping.ShortCode)
+	if err != nil {
+		return err
+	}
+	return repo.AddTags(ctx, mapping, names)
+}
+
+// SetTags replaces the tags of a mapping on its shard
+func (r *ShardedRepository) SetTags(ctx context.Context, mapping *model.URLMapping, names []string) error {
+	repo, err := r.locate(ctx, mapping.ShortCode)
+	if err != nil {
+		return err
+	}
+	return repo.SetTags(ctx, mapping, names)
+}
+
+// LoadTags fills mapping.Tags, ordered by name
+func (r *ShardedRepository) LoadTags(ctx context.Context, mapping *model.URLMapping) error {
+	repo, err := r.locate(ctx, mapping.ShortCode)
+	if err != nil {
+		return err
+	}
+	return repo.LoadTags(ctx, mapping)
+}
+
+// AddRevisions records first revisions on the shards of their links
+func (r *ShardedRepository) AddRevisions(ctx context.Context, revisions []model.URLRevision) error {
+	byRepo := make(map[*URLRepository][]model.URLRevision, len(r.shards))
+	for _, revision := range revisions {
+		repo, err := r.locate(ctx, revision.ShortCode)
+		if err != nil {
+			return err
+		}
+		byRepo[repo] = append(byRepo[repo], revision)
+	}
+	for _, s := range r.shards {
+		if err := s.Repo.AddRevisions(ctx, byRepo[s.Repo]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListRevisions returns a link's history, newest first
+func (r *ShardedRepository) ListRevisions(ctx context.Context, shortCode string) ([]model.URLRevision, error) {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	return repo.ListRevisions(ctx, shortCode)
+}
+
+// CreateVisitLog stores a visit log next to its link
+func (r *ShardedRepository) CreateVisitLog(ctx context.Context, log *model.VisitLog) error {
+	repo, err := r.locate(ctx, log.ShortCode)
+	if err != nil {
+		return err
+	}
+	return repo.CreateVisitLog(ctx, log)
+}
+
+// IncrementVisitCount increments the visit count for a short code
+func (r *ShardedRepository) IncrementVisitCount(ctx context.Context, shortCode string) error {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return err
+	}
+	return repo.IncrementVisitCount(ctx, shortCode)
+}
+
+// IncrementBotVisitCount increments the bot visit count for a short code
+func (r *ShardedRepository) IncrementBotVisitCount(ctx context.Context, shortCode string) error {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return err
+	}
+	return repo.IncrementBotVisitCount(ctx, shortCode)
+}
+
+// IncrementDuplicateVisitCount increments the count of deduplicated visits
+// for a short code
+func (r *ShardedRepository) IncrementDuplicateVisitCount(ctx context.Context, shortCode string) error {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return err
+	}
+	return repo.IncrementDuplicateVisitCount(ctx, shortCode)
+}
+
+// StreamVisitLogs calls fn with successive batches of visit logs for a
+// short code (see URLRepository.StreamVisitLogs)
+func (r *ShardedRepository) StreamVisitLogs(ctx context.Context, shortCode string, from, to time.Time, fn func([]model.VisitLog) error) error {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return err
+	}
+	return repo.StreamVisitLogs(ctx, shortCode, from, to, fn)
+}
+
+// VisitBreakdown counts human visits of a short code grouped by column
+func (r *ShardedRepository) VisitBreakdown(ctx context.Context, shortCode, column string, from, to time.Time, limit int) ([]model.VisitStat, error) {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	return repo.VisitBreakdown(ctx, shortCode, column, from, to, limit)
+}
+
+// SplitVisitBreakdown is VisitBreakdown over rollups and raw visit logs
+func (r *ShardedRepository) SplitVisitBreakdown(ctx context.Context, shortCode, column string, split model.StatsSplit, limit int) ([]model.VisitStat, error) {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	return repo.SplitVisitBreakdown(ctx, shortCode, column, split, limit)
+}
+
+// VisitTotals counts human visits of a short code and their daily uniques
+func (r *ShardedRepository) VisitTotals(ctx context.Context, shortCode string, split model.StatsSplit) (int64, int64, error) {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return 0, 0, err
+	}
+	return repo.VisitTotals(ctx, shortCode, split)
+}
+
+// CreateCampaign creates a campaign in the home database
+func (r *ShardedRepository) CreateCampaign(ctx context.Context, campaign *model.Campaign) error {
+	return r.home.CreateCampaign(ctx, campaign)
+}
+
+// GetCampaign retrieves a campaign by ID
+func (r *ShardedRepository) GetCampaign(ctx context.Context, id uint) (*model.Campaign, error) {
+	return r.home.GetCampaign(ctx, id)
+}
+
+// GetCampaignByName retrieves a campaign by name
+func (r *ShardedRepository) GetCampaignByName(ctx context.Context, name string) (*model.Campaign, error) {
+	return r.home.GetCampaignByName(ctx, name)
+}
+
+// AddCampaignLinks attaches short codes to a campaign; memberships are
+// stored on the shards of the links
+func (r *ShardedRepository) AddCampaignLinks(ctx context.Context, campaignID uint, shortCodes []string) error {
+	groups, err := r.group(ctx, shortCodes)
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if err := g.repo.AddCampaignLinks(ctx, campaignID, g.codes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveCampaignLink detaches a short code from a campaign
+func (r *ShardedRepository) RemoveCampaignLink(ctx context.Context, campaignID uint, shortCode string) (bool, error) {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return false, err
+	}
+	return repo.RemoveCampaignLink(ctx, campaignID, shortCode)
+}
+
+// CreateOrg creates an organization in the home database
+func (r *ShardedRepository) CreateOrg(ctx context.Context, org *model.Organization, owner string) error {
+	return r.home.CreateOrg(ctx, org, owner)
+}
+
+// GetOrg retrieves an organization by ID
+func (r *ShardedRepository) GetOrg(ctx context.Context, id uint) (*model.Organization, error) {
+	return r.home.GetOrg(ctx, id)
+}
+
+// GetOrgByName retrieves an organization by name
+func (r *ShardedRepository) GetOrgByName(ctx context.Context, name string) (*model.Organization, error) {
+	return r.home.GetOrgByName(ctx, name)
+}
+
+// UserOrgs returns the organizations a user belongs to
+func (r *ShardedRepository) UserOrgs(ctx context.Context, userID string) ([]model.Organization, error) {
+	return r.home.UserOrgs(ctx, userID)
+}
+
+// OrgMembers returns the members of an organization
+func (r *ShardedRepository) OrgMembers(ctx context.Context, orgID uint) ([]model.OrgMember, error) {
+	return r.home.OrgMembers(ctx, orgID)
+}
+
+// GetOrgMember retrieves a user's membership of an organization
+func (r *ShardedRepository) GetOrgMember(ctx context.Context, orgID uint, userID string) (*model.OrgMember, error) {
+	return r.home.GetOrgMember(ctx, orgID, userID)
+}
+
+// SetOrgMember adds a member or changes their role
+func (r *ShardedRepository) SetOrgMember(ctx context.Context, member *model.OrgMember) error {
+	return r.home.SetOrgMember(ctx, member)
+}
+
+// RemoveOrgMember removes a user from an organization
+func (r *ShardedRepository) RemoveOrgMember(ctx context.Context, orgID uint, userID string) (bool, error) {
+	return r.home.RemoveOrgMember(ctx, orgID, userID)
+}
+
+// CountOrgOwners counts the owners of an organization
+func (r *ShardedRepository) CountOrgOwners(ctx context.Context, orgID uint) (int64, error) {
+	return r.home.CountOrgOwners(ctx, orgID)
+}
+
+// GetAlias retrieves an alias by namespace and name
+func (r *ShardedRepository) GetAlias(ctx context.Context, namespace, alias string) (*model.LinkAlias, error) {
+	return r.home.GetAlias(ctx, namespace, alias)
+}
+
+// SetAlias creates an alias or points an existing one at another link
+func (r *ShardedRepository) SetAlias(ctx context.Context, alias *model.LinkAlias) error {
+	return r.home.SetAlias(ctx, alias)
+}
+
+// DeleteAlias removes an alias
+func (r *ShardedRepository) DeleteAlias(ctx context.Context, namespace, alias string) (bool, error) {
+	return r.home.DeleteAlias(ctx, namespace, alias)
+}
+
+// DeleteAliasesTo removes every alias pointing at shortCode
+func (r *ShardedRepository) DeleteAliasesTo(ctx context.Context, shortCode string) (int64, error) {
+	return r.home.DeleteAliasesTo(ctx, shortCode)
+}
+
+// ListAliases returns the aliases of a namespace, by name
+func (r *ShardedRepository) ListAliases(ctx context.Context, namespace string) ([]model.LinkAlias, error) {
+	return r.home.ListAliases(ctx, namespace)
+}
+
+// CreateAbuseFlag records a flagged link on its shard
+func (r *ShardedRepository) CreateAbuseFlag(ctx context.Context, flag *model.AbuseFlag) error {
+	repo, err := r.locate(ctx, flag.ShortCode)
+	if err != nil {
+		return err
+	}
+	return repo.CreateAbuseFlag(ctx, flag)
+}
+
+// CreateAbuseReport stores a report on the shard of the link
+func (r *ShardedRepository) CreateAbuseReport(ctx context.Context, report *model.AbuseReport) error {
+	repo, err := r.locate(ctx, report.ShortCode)
+	if err != nil {
+		return err
+	}
+	return repo.CreateAbuseReport(ctx, report)
+}
+
+// ListAbuseReports returns the newest reports of a link, at most limit
+func (r *ShardedRepository) ListAbuseReports(ctx context.Context, shortCode string, limit int) ([]model.AbuseReport, error) {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	return repo.ListAbuseReports(ctx, shortCode, limit)
+}
+
+// CountOpenReporters returns how many distinct IPs reported a link since it
+// was last reviewed
+func (r *ShardedRepository) CountOpenReporters(ctx context.Context, shortCode string) (int64, error) {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return 0, err
+	}
+	return repo.CountOpenReporters(ctx, shortCode)
+}
+
+// ResolveAbuseReports marks the open reports of a link reviewed
+func (r *ShardedRepository) ResolveAbuseReports(ctx context.Context, shortCode string) error {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return err
+	}
+	return repo.ResolveAbuseReports(ctx, shortCode)
+}
+
+// SetModeration sets a link's status and warning
+func (r *ShardedRepository) SetModeration(ctx context.Context, shortCode string, status int8, warning bool) (bool, error) {
+	repo, err := r.locate(ctx, shortCode)
+	if err != nil {
+		return false, err
+	}
+	return repo.SetModeration(ctx, shortCode, status, warning)
+}
+
+// CreateSummarySubscription stores a new summary subscription
+func (r *ShardedRepository) CreateSummarySubscription(ctx context.Context, sub *model.SummarySubscription) error {
+	return r.home.CreateSummarySubscription(ctx, sub)
+}
+
+// ListSummarySubscriptions returns a user's subscriptions, oldest first
+func (r *ShardedRepository) ListSummarySubscriptions(ctx context.Context, userID string) ([]model.SummarySubscription, error) {
+	return r.home.ListSummarySubscriptions(ctx, userID)
+}
+
+// DeleteSummarySubscription removes one of a user's subscriptions
+func (r *ShardedRepository) DeleteSummarySubscription(ctx context.Context, userID string, id uint) (bool, error) {
+	return r.home.DeleteSummarySubscription(ctx, userID, id)
+}
+
+// DueSummarySubscriptions returns the subscriptions of a frequency not yet
+// summarized up to periodEnd
+func (r *ShardedRepository) DueSummarySubscriptions(ctx context.Context, frequency string, periodEnd time.Time) ([]model.SummarySubscription, error) {
+	return r.home.DueSummarySubscriptions(ctx, frequency, periodEnd)
+}
+
+// ClaimSummaryPeriod marks a subscription as summarized up to periodEnd
+func (r *ShardedRepository) ClaimSummaryPeriod(ctx context.Context, id uint, periodEnd time.Time) (bool, error) {
+	return r.home.ClaimSummaryPeriod(ctx, id, periodEnd)
+}
+
+// outboxEventID makes a shard's event ID unique across shards
+func outboxEventID(shardIndex int, id uint64) uint64 {
+	return uint64(shardIndex)<<outboxShardShift | id
+}
+
+// splitOutboxEventID returns the shard position and the shard's own ID of
+// an ID made by outboxEventID
+func splitOutboxEventID(id uint64) (shardIndex int, shardID uint64) {
+	return int(id >> outboxShardShift), id & (1<<outboxShardShift - 1)
+}
+
+// outboxShard returns the shard and the shard's own ID of an outbox event
+func (r *ShardedRepository) outboxShard(id uint64) (*URLRepository, uint64, error) {
+	i, shardID := splitOutboxEventID(id)
+	if i >= len(r.shards) {
+		return nil, 0, errors.New("outbox event ID names an unknown shard")
+	}
+	return r.shards[i].Repo, shardID, nil
+}
+
+// ClaimOutboxEvents claims up to limit due events on every shard and
+// returns them with IDs unique across shards
+func (r *ShardedRepository) ClaimOutboxEvents(ctx context.Context, token string, now time.Time, lease time.Duration, limit int) ([]model.OutboxEvent, error) {
+	claimed := make([][]model.OutboxEvent, len(r.shards))
+	err := r.scatter(func(i int, repo *URLRepository) error {
+		events, err := repo.ClaimOutboxEvents(ctx, token, now, lease, limit)
+		for j := range events {
+			events[j].ID = outboxEventID(i, events[j].ID)
+		}
+		claimed[i] = events
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var events []model.OutboxEvent
+	for _, shardEvents := range claimed {
+		events = append(events, shardEvents...)
+	}
+	return events, nil
+}
+
+// CompleteOutboxEvent marks an event claimed by ClaimOutboxEvents dispatched
+func (r *ShardedRepository) CompleteOutboxEvent(ctx context.Context, id uint64, token string, now time.Time) (bool, error) {
+	repo, shardID, err := r.outboxShard(id)
+	if err != nil {
+		return false, err
+	}
+	return repo.CompleteOutboxEvent(ctx, shardID, token, now)
+}
+
+// RetryOutboxEvent releases an event claimed by ClaimOutboxEvents for
+// another attempt, or marks it failed
+func (r *ShardedRepository) RetryOutboxEvent(ctx context.Context, id uint64, token string, attempts int, next time.Time, lastErr string, failed bool) error {
+	repo, shardID, err := r.outboxShard(id)
+	if err != nil {
+		return err
+	}
+	return repo.RetryOutboxEvent(ctx, shardID, token, attempts, next, lastErr, failed)
+}
+
+// PurgeOutboxEvents removes old dispatched or failed events on every shard
+func (r *ShardedRepository) PurgeOutboxEvents(ctx context.Context, cutoff time.Time, batchSize int) (int64, error) {
+	return r.sum(func(repo *URLRepository) (int64, error) {
+		return repo.PurgeOutboxEvents(ctx, cutoff, batchSize)
+	})
+}
+
+// sum adds up a count over every shard
+func (r *ShardedRepository) sum(count func(repo *URLRepository) (int64, error)) (int64, error) {
+	counts := make([]int64, len(r.shards))
+	err := r.scatter(func(i int, repo *URLRepository) error {
+		var err error
+		counts[i], err = count(repo)
+		return err
+	})
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	return total, err
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/shard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSharded creates a ShardedRepository over unconnected repositories;
+// only routing can be used
+func newTestSharded(t *testing.T, names ...string) *ShardedRepository {
+	t.Helper()
+	ring, err := shard.NewRing(names, 0)
+	require.NoError(t, err)
+	shards := make([]Shard, 0, len(names))
+	for _, name := range names {
+		shards = append(shards, Shard{Name: name, Repo: &URLRepository{}})
+	}
+	r, err := NewShardedRepository(shards[0].Repo, ring, shards, false)
+	require.NoError(t, err)
+	return r
+}
+
+// TestNewShardedRepositoryValidation tests that shards are checked against the ring
+func TestNewShardedRepositoryValidation(t *testing.T) {
+	ring, err := shard.NewRing([]string{"a", "b"}, 0)
+	require.NoError(t, err)
+	a, b := &URLRepository{}, &URLRepository{}
+
+	_, err = NewShardedRepository(a, ring, []Shard{{Name: "a", Repo: a}}, false)
+	assert.Error(t, err, "shard on the ring but not connected")
+	_, err = NewShardedRepository(a, ring, []Shard{{Name: "a", Repo: a}, {Name: "a", Repo: b}}, false)
+	assert.Error(t, err, "duplicate shard")
+	_, err = NewShardedRepository(a, ring, []Shard{{Name: "a", Repo: a}, {Name: "b", Repo: b}, {Name: "c", Repo: &URLRepository{}}}, false)
+	assert.NoError(t, err, "draining shard")
+}
+
+// TestShardedGroup tests that codes are grouped by their owner, in shard order
+func TestShardedGroup(t *testing.T) {
+	r := newTestSharded(t, "a", "b", "c")
+	codes := make([]string, 0, 300)
+	for i := 0; i < 300; i++ {
+		codes = append(codes, fmt.Sprintf("code%d", i))
+	}
+
+	groups, err := r.group(context.Background(), codes)
+	require.NoError(t, err)
+	require.Len(t, groups, 3)
+	total := 0
+	for i, g := range groups {
+		assert.Same(t, r.shards[i].Repo, g.repo)
+		for _, code := range g.codes {
+			assert.Same(t, g.repo, r.owner(code))
+		}
+		total += len(g.codes)
+	}
+	assert.Equal(t, len(codes), total)
+}
+
+// TestShardedSplitMappings tests that links and their events go to the same shard
+func TestShardedSplitMappings(t *testing.T) {
+	r := newTestSharded(t, "a", "b")
+	var mappings []*model.URLMapping
+	var events []*model.OutboxEvent
+	for i := 0; i < 50; i++ {
+		code := fmt.Sprintf("code%d", i)
+		mappings = append(mappings, &model.URLMapping{ShortCode: code})
+		events = append(events, &model.OutboxEvent{ShortCode: code})
+	}
+
+	batches := r.splitMappings(mappings, events)
+	require.Len(t, batches, 2)
+	for _, batch := range batches {
+		require.Len(t, batch.events, len(batch.mappings))
+		for i, mapping := range batch.mappings {
+			assert.Same(t, batch.repo, r.owner(mapping.ShortCode))
+			assert.Equal(t, mapping.ShortCode, batch.events[i].ShortCode)
+		}
+	}
+}
+
+// TestOutboxEventID tests that event IDs round-trip through the shard position
+func TestOutboxEventID(t *testing.T) {
+	assert.Equal(t, uint64(42), outboxEventID(0, 42), "shard 0 keeps its IDs")
+
+	for _, shardIndex := range []int{0, 1, 7, MaxShards - 1} {
+		id := outboxEventID(shardIndex, 1<<outboxShardShift-1)
+		gotIndex, gotID := splitOutboxEventID(id)
+		assert.Equal(t, shardIndex, gotIndex)
+		assert.Equal(t, uint64(1<<outboxShardShift-1), gotID)
+	}
+
+	r := newTestSharded(t, "a", "b")
+	repo, id, err := r.outboxShard(outboxEventID(1, 9))
+	require.NoError(t, err)
+	assert.Same(t, r.shards[1].Repo, repo)
+	assert.Equal(t, uint64(9), id)
+	_, _, err = r.outboxShard(outboxEventID(2, 9))
+	assert.Error(t, err)
+}
+
+// TestPage tests offset and limit handling of merged results
+func TestPage(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	assert.Equal(t, []int{1, 2}, page(items, 0, 2))
+	assert.Equal(t, []int{4, 5}, page(items, 3, 10))
+	assert.Equal(t, []int{2, 3, 4, 5}, page(items, 1, -1))
+	assert.Nil(t, page(items, 5, 2))
+}
+
+// TestSumVisitStats tests that per-shard breakdowns are summed and ranked
+func TestSumVisitStats(t *testing.T) {
+	stats := sumVisitStats([]model.VisitStat{
+		{Value: "US", Count: 3},
+		{Value: "DE", Count: 2},
+		{Value: "US", Count: 1},
+		{Value: "FR", Count: 2},
+		{Value: "DE", Count: 2},
+	}, 2)
+	assert.Equal(t, []model.VisitStat{{Value: "DE", Count: 4}, {Value: "US", Count: 4}}, stats)
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"gorm.io/gorm"
+)
+
+// CreateSummarySubscription stores a new summary subscription
+func (r *URLRepository) CreateSummarySubscription(ctx context.Context, sub *model.SummarySubscription) error {
+	if err := r.db.WithContext(ctx).Create(sub).Error; err != nil {
+		return fmt.Errorf("failed to create summary subscription: %w", err)
+	}
+	return nil
+}
+
+// ListSummarySubscriptions returns a user's subscriptions, oldest first
+func (r *URLRepository) ListSummarySubscriptions(ctx context.Context, userID string) ([]model.SummarySubscription, error) {
+	var subs []model.SummarySubscription
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&subs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list summary subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+// DeleteSummarySubscription removes one of a user's subscriptions
+// Returns false if the user has no subscription with that ID
+func (r *URLRepository) DeleteSummarySubscription(ctx context.Context, userID string, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&model.SummarySubscription{})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to delete summary subscription: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DueSummarySubscriptions returns the subscriptions of a frequency not yet
+// summarized up to periodEnd
+func (r *URLRepository) DueSummarySubscriptions(ctx context.Context, frequency string, periodEnd time.Time) ([]model.SummarySubscription, error) {
+	var subs []model.SummarySubscription
+	if err := r.db.WithContext(ctx).
+		Where("frequency = ? AND (last_period_end IS NULL OR last_period_end < ?)", frequency, periodEnd).
+		Order("id").Find(&subs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list due summary subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+// ClaimSummaryPeriod marks a subscription as summarized up to periodEnd
+// Returns false if it already was, e.g. by another instance, so each
+// period is sent once
+func (r *URLRepository) ClaimSummaryPeriod(ctx context.Context, id uint, periodEnd time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.SummarySubscription{}).
+		Where("id = ? AND (last_period_end IS NULL OR last_period_end < ?)", id, periodEnd).
+		UpdateColumn("last_period_end", periodEnd)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to claim summary period: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// summaryLinks selects the short codes a subscription covers: its
+// organization's links, or the links its user created outside organizations
+// (revision 1 names the creator, see service.WithActor)
+func (r *URLRepository) summaryLinks(sub *model.SummarySubscription) *gorm.DB {
+	links := r.db.Model(&model.URLMapping{}).Select("short_code").Where("org_id = ?", sub.OrgID)
+	if sub.OrgID == 0 {
+		created := r.db.Model(&model.URLRevision{}).Select("short_code").
+			Where("revision = 1 AND changed_by = ?", "user:"+sub.UserID)
+		links = links.Where("short_code IN (?)", created)
+	}
+	return links
+}
+
+// SummaryClicks counts human visits in [from, to) to a subscription's links
+func (r *URLRepository) SummaryClicks(ctx context.Context, sub *model.SummarySubscription, from, to time.Time) (int64, error) {
+	var clicks int64
+	if err := r.db.WithContext(ctx).Model(&model.VisitLog{}).
+		Where("short_code IN (?) AND is_bot = ?", r.summaryLinks(sub), false).
+		Where("visited_at >= ? AND visited_at < ?", from, to).
+		Count(&clicks).Error; err != nil {
+		return 0, fmt.Errorf("failed to count summary clicks: %w", err)
+	}
+	return clicks, nil
+}
+
+// SummaryTopLinks returns up to limit of a subscription's links with the
+// most human visits in [from, to)
+func (r *URLRepository) SummaryTopLinks(ctx context.Context, sub *model.SummarySubscription, from, to time.Time, limit int) ([]model.SummaryLink, error) {
+	var links []model.SummaryLink
+	if err := r.db.WithContext(ctx).Table("visit_logs").
+		Select("visit_logs.short_code, url_mappings.original_url, url_mappings.domain, COUNT(*) AS clicks").
+		Joins("JOIN url_mappings ON url_mappings.short_code = visit_logs.short_code AND url_mappings.deleted_at IS NULL").
+		Where("visit_logs.short_code IN (?) AND visit_logs.is_bot = ?", r.summaryLinks(sub), false).
+		Where("visit_logs.visited_at >= ? AND visit_logs.visited_at < ?", from, to).
+		Group("visit_logs.short_code, url_mappings.original_url, url_mappings.domain").
+		Order("clicks DESC").
+		Limit(limit).
+		Scan(&links).Error; err != nil {
+		return nil, fmt.Errorf("failed to get summary top links: %w", err)
+	}
+	return links, nil
+}
+
+// SummaryNewLinks counts a subscription's links created in [from, to) and
+// returns up to limit of them, newest first
+func (r *URLRepository) SummaryNewLinks(ctx context.Context, sub *model.SummarySubscription, from, to time.Time, limit int) (int64, []model.URLMapping, error) {
+	query := r.db.WithContext(ctx).Model(&model.URLMapping{}).
+		Where("short_code IN (?)", r.summaryLinks(sub)).
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Session(&gorm.Session{})
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return 0, nil, fmt.Errorf("failed to count new links: %w", err)
+	}
+	var mappings []model.URLMapping
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&mappings).Error; err != nil {
+		return 0, nil, fmt.Errorf("failed to get new links: %w", err)
+	}
+	return count, mappings, nil
+}
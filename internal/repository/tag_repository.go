@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoadTags fills mapping.Tags, ordered by name
+func (r *URLRepository) LoadTags(ctx context.Context, mapping *model.URLMapping) error {
+	if err := r.db.WithContext(ctx).Model(mapping).Order("name").Association("Tags").Find(&mapping.Tags); err != nil {
+		return fmt.Errorf("failed to load tags: %w", err)
+	}
+	return nil
+}
+
+// SetTags replaces the tags of a mapping, creating tags that don't exist
+func (r *URLRepository) SetTags(ctx context.Context, mapping *model.URLMapping, names []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tags, err := ensureTags(tx, names)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(mapping).Association("Tags").Replace(tags); err != nil {
+			return fmt.Errorf("failed to set tags: %w", err)
+		}
+		return touchMapping(tx, mapping)
+	})
+}
+
+// AddTags adds tags to a mapping, keeping the ones it already has
+func (r *URLRepository) AddTags(ctx context.Context, mapping *model.URLMapping, names []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tags, err := ensureTags(tx, names)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(mapping).Association("Tags").Append(tags); err != nil {
+			return fmt.Errorf("failed to add tags: %w", err)
+		}
+		return touchMapping(tx, mapping)
+	})
+}
+
+// touchMapping sets a mapping's updated_at to now; tag changes live in
+// the join table and wouldn't update it otherwise
+func touchMapping(tx *gorm.DB, mapping *model.URLMapping) error {
+	now := time.Now()
+	if err := tx.Model(&model.URLMapping{}).Where("id = ?", mapping.ID).
+		UpdateColumn("updated_at", now).Error; err != nil {
+		return fmt.Errorf("failed to touch URL mapping: %w", err)
+	}
+	mapping.UpdatedAt = now
+	return nil
+}
+
+// ensureTags returns the tag rows for names, inserting missing ones
+func ensureTags(tx *gorm.DB, names []string) ([]model.Tag, error) {
+	if len(names) == 0 {
+		return []model.Tag{}, nil
+	}
+
+	rows := make([]model.Tag, 0, len(names))
+	for _, name := range names {
+		rows = append(rows, model.Tag{Name: name})
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to create tags: %w", err)
+	}
+
+	var tags []model.Tag
+	if err := tx.Where("name IN ?", names).Find(&tags).Error; err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+	return tags, nil
+}
@@ -3,11 +3,15 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/tracing"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
 )
 
 // URLRepository handles database operations for URL mappings
@@ -15,8 +19,20 @@ type URLRepository struct {
 	db *gorm.DB
 }
 
+// PoolConfig sizes the connection pool of the primary and of each replica
+type PoolConfig struct {
+	MaxIdleConns int
+	MaxOpenConns int
+	// ConnMaxLifetime retires connections before MySQL's wait_timeout (or a
+	// proxy's idle timeout) kills them, which surfaces as "invalid connection"
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration // Idle connections are closed after this
+}
+
 // NewURLRepository creates a new URL repository instance
-func NewURLRepository(dsn string, maxIdleConns, maxOpenConns int) (*URLRepository, error) {
+// Reads are load-balanced across replicaDSNs when given; writes and
+// transactions always go to the primary
+func NewURLRepository(dsn string, replicaDSNs []string, pool PoolConfig) (*URLRepository, error) {
 	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 	})
@@ -24,18 +40,43 @@ func NewURLRepository(dsn string, maxIdleConns, maxOpenConns int) (*URLRepositor
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	if len(replicaDSNs) > 0 {
+		replicas := make([]gorm.Dialector, 0, len(replicaDSNs))
+		for _, replicaDSN := range replicaDSNs {
+			replicas = append(replicas, mysql.Open(replicaDSN))
+		}
+
+		resolver := dbresolver.Register(dbresolver.Config{
+			Replicas: replicas,
+			Policy:   dbresolver.RandomPolicy{},
+		}).
+			SetMaxIdleConns(pool.MaxIdleConns).
+			SetMaxOpenConns(pool.MaxOpenConns).
+			SetConnMaxLifetime(pool.ConnMaxLifetime).
+			SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+
+		if err := db.Use(resolver); err != nil {
+			return nil, fmt.Errorf("failed to configure read replicas: %w", err)
+		}
+	}
+
+	// One span per query; a no-op unless tracing is enabled
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		return nil, fmt.Errorf("failed to register tracing plugin: %w", err)
+	}
+
 	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get database instance: %w", err)
 	}
 
-	sqlDB.SetMaxIdleConns(maxIdleConns)
-	sqlDB.SetMaxOpenConns(maxOpenConns)
+	sqlDB.SetMaxIdleConns(pool.MaxIdleConns)
+	sqlDB.SetMaxOpenConns(pool.MaxOpenConns)
+	sqlDB.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
 
-	// Auto-migrate tables
-	if err := db.AutoMigrate(&model.URLMapping{}, &model.VisitLog{}); err != nil {
-		return nil, fmt.Errorf("failed to migrate database: %w", err)
-	}
+	// Schema changes are applied by versioned migrations (see Migrate),
+	// not AutoMigrate, so startup never locks large tables
 
 	return &URLRepository{db: db}, nil
 }
@@ -60,10 +101,42 @@ func (r *URLRepository) GetByShortCode(ctx context.Context, shortCode string) (*
 	return &mapping, nil
 }
 
-// GetByOriginalURL retrieves a URL mapping by original URL
-func (r *URLRepository) GetByOriginalURL(ctx context.Context, originalURL string) (*model.URLMapping, error) {
+// GetByShortCodeFromPrimary is GetByShortCode read from the primary, for
+// callers that must see a write made a moment ago, which a lagging replica
+// may not have yet
+func (r *URLRepository) GetByShortCodeFromPrimary(ctx context.Context, shortCode string) (*model.URLMapping, error) {
+	var mapping model.URLMapping
+	if err := r.db.WithContext(ctx).Clauses(dbresolver.Write).Where("short_code = ?", shortCode).First(&mapping).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get URL mapping: %w", err)
+	}
+	return &mapping, nil
+}
+
+// GetByShortCodes retrieves the live URL mappings of shortCodes, in no
+// particular order; missing codes are skipped
+func (r *URLRepository) GetByShortCodes(ctx context.Context, shortCodes []string) ([]model.URLMapping, error) {
+	if len(shortCodes) == 0 {
+		return nil, nil
+	}
+	var mappings []model.URLMapping
+	if err := r.db.WithContext(ctx).Where("short_code IN ?", shortCodes).Find(&mappings).Error; err != nil {
+		return nil, fmt.Errorf("failed to get URL mappings: %w", err)
+	}
+	return mappings, nil
+}
+
+// GetByOriginalURL retrieves a URL mapping by original URL on a domain,
+// owned by orgID (0 for links without an organization)
+// Looks up the indexed url_hash; original_url is compared as well to rule
+// out hash collisions
+func (r *URLRepository) GetByOriginalURL(ctx context.Context, originalURL, domain string, orgID uint) (*model.URLMapping, error) {
 	var mapping model.URLMapping
-	if err := r.db.WithContext(ctx).Where("original_url = ?", originalURL).First(&mapping).Error; err != nil {
+	if err := r.db.WithContext(ctx).
+		Where("url_hash = ? AND domain = ? AND original_url = ? AND org_id = ?", model.HashURL(originalURL), domain, originalURL, orgID).
+		First(&mapping).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
 		}
@@ -82,6 +155,27 @@ func (r *URLRepository) IncrementVisitCount(ctx context.Context, shortCode strin
 	return nil
 }
 
+// IncrementDuplicateVisitCount increments the count of deduplicated visits
+// for a short code
+func (r *URLRepository) IncrementDuplicateVisitCount(ctx context.Context, shortCode string) error {
+	if err := r.db.WithContext(ctx).Model(&model.URLMapping{}).
+		Where("short_code = ?", shortCode).
+		UpdateColumn("duplicate_visit_count", gorm.Expr("duplicate_visit_count + ?", 1)).Error; err != nil {
+		return fmt.Errorf("failed to increment duplicate visit count: %w", err)
+	}
+	return nil
+}
+
+// IncrementBotVisitCount increments the bot visit count for a short code
+func (r *URLRepository) IncrementBotVisitCount(ctx context.Context, shortCode string) error {
+	if err := r.db.WithContext(ctx).Model(&model.URLMapping{}).
+		Where("short_code = ?", shortCode).
+		UpdateColumn("bot_visit_count", gorm.Expr("bot_visit_count + ?", 1)).Error; err != nil {
+		return fmt.Errorf("failed to increment bot visit count: %w", err)
+	}
+	return nil
+}
+
 // CreateVisitLog creates a new visit log entry
 func (r *URLRepository) CreateVisitLog(ctx context.Context, log *model.VisitLog) error {
 	if err := r.db.WithContext(ctx).Create(log).Error; err != nil {
@@ -90,6 +184,141 @@ func (r *URLRepository) CreateVisitLog(ctx context.Context, log *model.VisitLog)
 	return nil
 }
 
+// visitLogExportBatchSize is how many rows each StreamVisitLogs page reads
+const visitLogExportBatchSize = 1000
+
+// StreamVisitLogs calls fn with successive batches of visit logs for a short
+// code, ordered by id. Pages are fetched with a keyset cursor (id > last id),
+// so memory stays flat and late pages are as fast as early ones.
+// from (inclusive) and to (exclusive) are ignored when zero.
+func (r *URLRepository) StreamVisitLogs(ctx context.Context, shortCode string, from, to time.Time, fn func([]model.VisitLog) error) error {
+	var lastID uint
+	for {
+		query := r.db.WithContext(ctx).
+			Where("short_code = ? AND id > ?", shortCode, lastID)
+		if !from.IsZero() {
+			query = query.Where("visited_at >= ?", from)
+		}
+		if !to.IsZero() {
+			query = query.Where("visited_at < ?", to)
+		}
+
+		var batch []model.VisitLog
+		if err := query.Order("id").Limit(visitLogExportBatchSize).Find(&batch).Error; err != nil {
+			return fmt.Errorf("failed to read visit logs: %w", err)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < visitLogExportBatchSize {
+			return nil
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+}
+
+// mappingExportBatchSize is the number of URL mappings read per export query
+const mappingExportBatchSize = 1000
+
+// StreamURLMappings calls fn with successive batches of URL mappings (with
+// their tags), ordered by id and read with a keyset cursor like
+// StreamVisitLogs. domain limits the export to one serving domain when not
+// empty; soft-deleted links are included if includeDeleted is set. A
+// non-zero updatedSince limits it to links changed at or after that time.
+func (r *URLRepository) StreamURLMappings(ctx context.Context, domain string, includeDeleted bool, updatedSince time.Time, fn func([]model.URLMapping) error) error {
+	var lastID uint
+	for {
+		query := r.db.WithContext(ctx).Where("id > ?", lastID)
+		if includeDeleted {
+			query = query.Unscoped()
+		}
+		if domain != "" {
+			query = query.Where("domain = ?", domain)
+		}
+		if !updatedSince.IsZero() {
+			query = query.Where("updated_at >= ?", updatedSince)
+		}
+
+		var batch []model.URLMapping
+		if err := query.Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("name") }).
+			Order("id").Limit(mappingExportBatchSize).Find(&batch).Error; err != nil {
+			return fmt.Errorf("failed to read URL mappings: %w", err)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < mappingExportBatchSize {
+			return nil
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+}
+
+// visitBreakdownColumns are the visit_logs columns VisitBreakdown may group by
+var visitBreakdownColumns = map[string]bool{
+	"country":     true,
+	"device_type": true,
+	"browser":     true,
+	"referrer":    true,
+}
+
+// VisitBreakdown counts human visits of a short code grouped by column,
+// most frequent first. from (inclusive) and to (exclusive) are ignored when zero.
+func (r *URLRepository) VisitBreakdown(ctx context.Context, shortCode, column string, from, to time.Time, limit int) ([]model.VisitStat, error) {
+	if !visitBreakdownColumns[column] {
+		return nil, fmt.Errorf("unsupported breakdown column: %s", column)
+	}
+
+	query := r.db.WithContext(ctx).Model(&model.VisitLog{}).
+		Select("COALESCE("+column+", '') AS value, COUNT(*) AS count").
+		Where("short_code = ? AND is_bot = ?", shortCode, false)
+	if !from.IsZero() {
+		query = query.Where("visited_at >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("visited_at < ?", to)
+	}
+
+	var stats []model.VisitStat
+	if err := query.Group("value").Order("count DESC").Limit(limit).Scan(&stats).Error; err != nil {
+		return nil, fmt.Errorf("failed to get visit breakdown: %w", err)
+	}
+	return stats, nil
+}
+
+// NextSequence returns the next value of the short code sequence
+// REPLACE and LAST_INSERT_ID must run on the same connection
+func (r *URLRepository) NextSequence(ctx context.Context) (int64, error) {
+	var id int64
+	err := r.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("REPLACE INTO short_code_sequence (stub) VALUES ('a')").Error; err != nil {
+			return err
+		}
+		return conn.Raw("SELECT LAST_INSERT_ID()").Scan(&id).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get next sequence: %w", err)
+	}
+	return id, nil
+}
+
+// ListReservedCodes returns all rows of the reserved_codes table
+func (r *URLRepository) ListReservedCodes(ctx context.Context) ([]model.ReservedCode, error) {
+	var codes []model.ReservedCode
+	if err := r.db.WithContext(ctx).Find(&codes).Error; err != nil {
+		return nil, fmt.Errorf("failed to list reserved codes: %w", err)
+	}
+	return codes, nil
+}
+
 // GetAllShortCodes retrieves all short codes from the database
 func (r *URLRepository) GetAllShortCodes(ctx context.Context) ([]string, error) {
 	var shortCodes []string
@@ -100,6 +329,23 @@ func (r *URLRepository) GetAllShortCodes(ctx context.Context) ([]string, error)
 	return shortCodes, nil
 }
 
+// MostVisitedActive returns up to limit active, unexpired, cacheable links,
+// most visited first (scans idx_visit_count)
+// visit_count is read so results of several shards can be merged
+func (r *URLRepository) MostVisitedActive(ctx context.Context, limit int) ([]model.URLMapping, error) {
+	var mappings []model.URLMapping
+	if err := r.db.WithContext(ctx).
+		Select("short_code", "original_url", "domain", "expired_at", "visit_count", "status", "warning", "cache_ttl", "access_rules", "deep_links", "visit_dedup_minutes", "no_analytics", "visibility", "org_id", "redirect_delay", "require_signature", "updated_at").
+		Where("status = 1 AND no_cache = 0").
+		Where("expired_at IS NULL OR expired_at > ?", time.Now()).
+		Order("visit_count DESC").
+		Limit(limit).
+		Find(&mappings).Error; err != nil {
+		return nil, fmt.Errorf("failed to get most visited links: %w", err)
+	}
+	return mappings, nil
+}
+
 // Update updates a URL mapping
 func (r *URLRepository) Update(ctx context.Context, mapping *model.URLMapping) error {
 	if err := r.db.WithContext(ctx).Save(mapping).Error; err != nil {
@@ -108,14 +354,127 @@ func (r *URLRepository) Update(ctx context.Context, mapping *model.URLMapping) e
 	return nil
 }
 
-// Delete deletes a URL mapping by short code
+// SetLinkTitle stores the destination page title of a link
+// updated_at is left alone: the title isn't an edit
+func (r *URLRepository) SetLinkTitle(ctx context.Context, shortCode, title string) error {
+	if err := r.db.WithContext(ctx).Model(&model.URLMapping{}).
+		Where("short_code = ?", shortCode).
+		UpdateColumn("title", title).Error; err != nil {
+		return fmt.Errorf("failed to set link title: %w", err)
+	}
+	return nil
+}
+
+// Delete soft-deletes a URL mapping by short code
+// The row keeps its short code until purged, so it can be restored
+// updated_at is set too, so incremental exports pick up the deletion
 func (r *URLRepository) Delete(ctx context.Context, shortCode string) error {
-	if err := r.db.WithContext(ctx).Where("short_code = ?", shortCode).Delete(&model.URLMapping{}).Error; err != nil {
+	now := time.Now()
+	if err := r.db.WithContext(ctx).Model(&model.URLMapping{}).
+		Where("short_code = ?", shortCode).
+		UpdateColumns(map[string]interface{}{"deleted_at": now, "updated_at": now}).Error; err != nil {
 		return fmt.Errorf("failed to delete URL mapping: %w", err)
 	}
 	return nil
 }
 
+// Restore undoes a soft delete
+// Returns false if the short code doesn't exist or isn't deleted
+func (r *URLRepository) Restore(ctx context.Context, shortCode string) (bool, error) {
+	result := r.db.WithContext(ctx).Unscoped().Model(&model.URLMapping{}).
+		Where("short_code = ? AND deleted_at IS NOT NULL", shortCode).
+		UpdateColumns(map[string]interface{}{"deleted_at": nil, "updated_at": time.Now()})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to restore URL mapping: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ShortCodeTaken reports whether a short code is used, including by
+// soft-deleted links (their codes stay reserved until purged)
+func (r *URLRepository) ShortCodeTaken(ctx context.Context, shortCode string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Unscoped().Model(&model.URLMapping{}).
+		Where("short_code = ?", shortCode).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check short code: %w", err)
+	}
+	return count > 0, nil
+}
+
+// PurgeDeletedBefore permanently removes links soft-deleted before cutoff,
+// in batches to keep locks short, and passes each batch removed to purged
+func (r *URLRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time, batchSize int, purged func([]model.URLMapping)) (int64, error) {
+	var total int64
+	for {
+		var batch []model.URLMapping
+		err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// Locked, so a link restored meanwhile is neither purged nor reported
+			if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+				Order("id").Limit(batchSize).Find(&batch).Error; err != nil {
+				return err
+			}
+			if len(batch) == 0 {
+				return nil
+			}
+			ids := make([]uint, 0, len(batch))
+			for _, mapping := range batch {
+				ids = append(ids, mapping.ID)
+			}
+			return tx.Unscoped().Where("id IN ?", ids).Delete(&model.URLMapping{}).Error
+		})
+		if err != nil {
+			return total, fmt.Errorf("failed to purge deleted URL mappings: %w", err)
+		}
+
+		total += int64(len(batch))
+		if len(batch) > 0 && purged != nil {
+			purged(batch)
+		}
+		if len(batch) < batchSize {
+			return total, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
+
+// Ping checks that the primary database is reachable
+func (r *URLRepository) Ping(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// KeepAlive pings the primary and a replica every interval until ctx is done
+// A connection that fails the ping is discarded by the driver and replaced,
+// so broken connections are found here rather than by a user's request
+func (r *URLRepository) KeepAlive(ctx context.Context, interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		if err := r.Ping(pingCtx); err != nil {
+			fmt.Printf("MySQL keepalive ping failed: %v\n", err)
+		}
+		// Routed to a random replica, or the primary without replicas
+		if err := r.db.WithContext(pingCtx).Clauses(dbresolver.Read).Exec("SELECT 1").Error; err != nil {
+			fmt.Printf("MySQL replica keepalive ping failed: %v\n", err)
+		}
+		cancel()
+	}
+}
+
 // Close closes the database connection
 func (r *URLRepository) Close() error {
 	sqlDB, err := r.db.DB()
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"gorm.io/plugin/dbresolver"
+)
+
+// visitLogFuturePartition is the catch-all partition new days are split from
+const visitLogFuturePartition = "p_future"
+
+// visitLogPartition describes one daily partition of visit_logs
+type visitLogPartition struct {
+	Name string
+	// LessThan is the exclusive upper bound (unix seconds); 0 for MAXVALUE
+	LessThan int64
+}
+
+// IsVisitLogPartitioned reports whether visit_logs uses range partitioning
+func (r *URLRepository) IsVisitLogPartitioned(ctx context.Context) (bool, error) {
+	partitions, err := r.visitLogPartitions(ctx)
+	if err != nil {
+		return false, err
+	}
+	return len(partitions) > 0, nil
+}
+
+// EnsureVisitLogPartitions creates daily partitions from today through
+// daysAhead days in the future, so rows never land in p_future
+func (r *URLRepository) EnsureVisitLogPartitions(ctx context.Context, now time.Time, daysAhead int) error {
+	partitions, err := r.visitLogPartitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool, len(partitions))
+	for _, p := range partitions {
+		existing[p.Name] = true
+	}
+
+	today := now.UTC().Truncate(24 * time.Hour)
+	for i := 0; i <= daysAhead; i++ {
+		day := today.AddDate(0, 0, i)
+		name := visitLogPartitionName(day)
+		if existing[name] {
+			continue
+		}
+
+		stmt := fmt.Sprintf(
+			"ALTER TABLE `%s` REORGANIZE PARTITION %s INTO (PARTITION %s VALUES LESS THAN (%d), PARTITION %s VALUES LESS THAN MAXVALUE)",
+			model.VisitLog{}.TableName(), visitLogFuturePartition,
+			name, day.AddDate(0, 0, 1).Unix(), visitLogFuturePartition,
+		)
+		if err := r.db.WithContext(ctx).Clauses(dbresolver.Write).Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to create visit log partition %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// DropVisitLogPartitionsBefore drops every daily partition whose rows are all
+// older than cutoff and returns how many were dropped
+func (r *URLRepository) DropVisitLogPartitionsBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	partitions, err := r.visitLogPartitions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	dropped := 0
+	for _, p := range partitions {
+		if p.Name == visitLogFuturePartition || p.LessThan == 0 || p.LessThan > cutoff.Unix() {
+			continue
+		}
+
+		stmt := fmt.Sprintf("ALTER TABLE `%s` DROP PARTITION %s", model.VisitLog{}.TableName(), p.Name)
+		if err := r.db.WithContext(ctx).Clauses(dbresolver.Write).Exec(stmt).Error; err != nil {
+			return dropped, fmt.Errorf("failed to drop visit log partition %s: %w", p.Name, err)
+		}
+		dropped++
+	}
+	return dropped, nil
+}
+
+// DeleteVisitLogsBefore deletes visit logs older than cutoff in batches
+// Used when visit_logs isn't partitioned; small batches keep locks short
+func (r *URLRepository) DeleteVisitLogsBefore(ctx context.Context, cutoff time.Time, batchSize int) (int64, error) {
+	var total int64
+	for {
+		result := r.db.WithContext(ctx).
+			Where("visited_at < ?", cutoff).
+			Limit(batchSize).
+			Delete(&model.VisitLog{})
+		if result.Error != nil {
+			return total, fmt.Errorf("failed to delete visit logs: %w", result.Error)
+		}
+
+		total += result.RowsAffected
+		if result.RowsAffected < int64(batchSize) {
+			return total, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
+
+// visitLogPartitions lists the partitions of visit_logs
+// Returns an empty slice when the table isn't partitioned
+func (r *URLRepository) visitLogPartitions(ctx context.Context) ([]visitLogPartition, error) {
+	rows, err := r.db.WithContext(ctx).Clauses(dbresolver.Write).Raw(
+		`SELECT PARTITION_NAME, PARTITION_DESCRIPTION
+		FROM information_schema.PARTITIONS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND PARTITION_NAME IS NOT NULL
+		ORDER BY PARTITION_ORDINAL_POSITION`,
+		model.VisitLog{}.TableName(),
+	).Rows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list visit log partitions: %w", err)
+	}
+	defer rows.Close()
+
+	var partitions []visitLogPartition
+	for rows.Next() {
+		var name string
+		var description sql.NullString
+		if err := rows.Scan(&name, &description); err != nil {
+			return nil, fmt.Errorf("failed to scan visit log partition: %w", err)
+		}
+
+		p := visitLogPartition{Name: name}
+		if description.Valid && description.String != "MAXVALUE" {
+			lessThan, err := strconv.ParseInt(description.String, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid bound %q for partition %s: %w", description.String, name, err)
+			}
+			p.LessThan = lessThan
+		}
+		partitions = append(partitions, p)
+	}
+	return partitions, rows.Err()
+}
+
+// visitLogPartitionName returns the partition name holding a given UTC day
+func visitLogPartitionName(day time.Time) string {
+	return "p" + day.Format("20060102")
+}
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// visitRollupDimensions are the visit_logs columns rolled up into
+// visit_daily_breakdowns, the same ones VisitBreakdown groups by
+var visitRollupDimensions = []string{"country", "device_type", "browser", "referrer"}
+
+// rollupValue is the breakdown value of a visit_logs column, cut to the
+// width of visit_daily_breakdowns.value
+func rollupValue(column string) string {
+	return "LEFT(COALESCE(" + column + ", ''), 255)"
+}
+
+// RollupVisitDay (re)computes the rollups of one UTC day from visit_logs
+// The day's rows are replaced in one transaction, so running it again for
+// a day picks up late visits (e.g. replayed after a MySQL outage)
+func (r *URLRepository) RollupVisitDay(ctx context.Context, day time.Time) error {
+	day = day.UTC().Truncate(24 * time.Hour)
+	next := day.AddDate(0, 0, 1)
+	date := day.Format(time.DateOnly)
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("day = ?", date).Delete(&model.VisitDailyStat{}).Error; err != nil {
+			return fmt.Errorf("failed to clear daily stats: %w", err)
+		}
+		if err := tx.Exec(`INSERT INTO visit_daily_stats (short_code, day, clicks, bot_clicks, uniques)
+			SELECT short_code, ?, SUM(is_bot = 0), SUM(is_bot = 1), COUNT(DISTINCT CASE WHEN is_bot = 0 THEN ip END)
+			FROM visit_logs
+			WHERE visited_at >= ? AND visited_at < ?
+			GROUP BY short_code`, date, day, next).Error; err != nil {
+			return fmt.Errorf("failed to roll up daily stats: %w", err)
+		}
+
+		if err := tx.Where("day = ?", date).Delete(&model.VisitDailyBreakdown{}).Error; err != nil {
+			return fmt.Errorf("failed to clear daily breakdowns: %w", err)
+		}
+		for _, column := range visitRollupDimensions {
+			stmt := fmt.Sprintf(`INSERT INTO visit_daily_breakdowns (short_code, day, dimension, value, count)
+				SELECT short_code, ?, ?, %s AS value, COUNT(*)
+				FROM visit_logs
+				WHERE visited_at >= ? AND visited_at < ? AND is_bot = 0
+				GROUP BY short_code, value`, rollupValue(column))
+			if err := tx.Exec(stmt, date, column, day, next).Error; err != nil {
+				return fmt.Errorf("failed to roll up %s breakdown: %w", column, err)
+			}
+		}
+
+		if err := tx.Exec(`INSERT INTO visit_rollup_days (day, rolled_up_at) VALUES (?, ?)
+			ON DUPLICATE KEY UPDATE rolled_up_at = VALUES(rolled_up_at)`, date, time.Now()).Error; err != nil {
+			return fmt.Errorf("failed to record rollup day: %w", err)
+		}
+		return nil
+	})
+}
+
+// VisitRollupWatermark returns the start of the first UTC day after the
+// last rolled-up one, or zero if nothing has been rolled up
+func (r *URLRepository) VisitRollupWatermark(ctx context.Context) (time.Time, error) {
+	var last sql.NullTime
+	if err := r.db.WithContext(ctx).Model(&model.VisitRollupDay{}).
+		Select("MAX(day)").Scan(&last).Error; err != nil {
+		return time.Time{}, fmt.Errorf("failed to get rollup watermark: %w", err)
+	}
+	if !last.Valid {
+		return time.Time{}, nil
+	}
+	// DATE values are parsed in the connection's location; keep the calendar day
+	y, m, d := last.Time.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC), nil
+}
+
+// FirstVisitTime returns when the oldest visit log was recorded, or zero
+// if there are none
+func (r *URLRepository) FirstVisitTime(ctx context.Context) (time.Time, error) {
+	var first sql.NullTime
+	if err := r.db.WithContext(ctx).Clauses(dbresolver.Write).Model(&model.VisitLog{}).
+		Select("MIN(visited_at)").Scan(&first).Error; err != nil {
+		return time.Time{}, fmt.Errorf("failed to get first visit: %w", err)
+	}
+	return first.Time, nil
+}
+
+// VisitTotals counts human visits of a short code in split and the
+// distinct IPs among them per UTC day, summed
+func (r *URLRepository) VisitTotals(ctx context.Context, shortCode string, split model.StatsSplit) (clicks, uniques int64, err error) {
+	type totals struct {
+		Clicks  int64
+		Uniques int64
+	}
+
+	if split.Rollup {
+		var t totals
+		query := r.db.WithContext(ctx).Model(&model.VisitDailyStat{}).
+			Select("COALESCE(SUM(clicks), 0) AS clicks, COALESCE(SUM(uniques), 0) AS uniques").
+			Where("short_code = ?", shortCode)
+		query = rollupDays(query, split)
+		if err := query.Scan(&t).Error; err != nil {
+			return 0, 0, fmt.Errorf("failed to get rolled-up visit totals: %w", err)
+		}
+		clicks, uniques = t.Clicks, t.Uniques
+	}
+
+	for _, raw := range split.Raw {
+		var t totals
+		query := r.db.WithContext(ctx).Model(&model.VisitLog{}).
+			Select("COUNT(*) AS clicks, COUNT(DISTINCT FLOOR(UNIX_TIMESTAMP(visited_at) / 86400), ip) AS uniques").
+			Where("short_code = ? AND is_bot = ?", shortCode, false)
+		query = visitedIn(query, raw)
+		if err := query.Scan(&t).Error; err != nil {
+			return 0, 0, fmt.Errorf("failed to get visit totals: %w", err)
+		}
+		clicks += t.Clicks
+		uniques += t.Uniques
+	}
+	return clicks, uniques, nil
+}
+
+// SplitVisitBreakdown is VisitBreakdown over a range split between the
+// rollup tables and visit_logs; both parts are summed in one query so the
+// top values are exact. Values are cut to 255 characters like rollups.
+func (r *URLRepository) SplitVisitBreakdown(ctx context.Context, shortCode, column string, split model.StatsSplit, limit int) ([]model.VisitStat, error) {
+	if !visitBreakdownColumns[column] {
+		return nil, fmt.Errorf("unsupported breakdown column: %s", column)
+	}
+
+	var parts []string
+	var args []interface{}
+	if split.Rollup {
+		part := "SELECT value, count FROM visit_daily_breakdowns WHERE short_code = ? AND dimension = ? AND day < ?"
+		args = append(args, shortCode, column, split.RollupTo.Format(time.DateOnly))
+		if !split.RollupFrom.IsZero() {
+			part += " AND day >= ?"
+			args = append(args, split.RollupFrom.Format(time.DateOnly))
+		}
+		parts = append(parts, part)
+	}
+	for _, raw := range split.Raw {
+		part := fmt.Sprintf("SELECT %s AS value, COUNT(*) AS count FROM visit_logs WHERE short_code = ? AND is_bot = 0", rollupValue(column))
+		args = append(args, shortCode)
+		if !raw.From.IsZero() {
+			part += " AND visited_at >= ?"
+			args = append(args, raw.From)
+		}
+		if !raw.To.IsZero() {
+			part += " AND visited_at < ?"
+			args = append(args, raw.To)
+		}
+		parts = append(parts, part+" GROUP BY value")
+	}
+	if len(parts) == 0 {
+		return nil, nil
+	}
+
+	stmt := "SELECT value, SUM(count) AS count FROM (" + strings.Join(parts, " UNION ALL ") +
+		") AS parts GROUP BY value ORDER BY count DESC LIMIT ?"
+	args = append(args, limit)
+
+	var stats []model.VisitStat
+	if err := r.db.WithContext(ctx).Raw(stmt, args...).Scan(&stats).Error; err != nil {
+		return nil, fmt.Errorf("failed to get visit breakdown: %w", err)
+	}
+	return stats, nil
+}
+
+// rollupDays limits a rollup table query to the rolled-up days of split
+func rollupDays(query *gorm.DB, split model.StatsSplit) *gorm.DB {
+	query = query.Where("day < ?", split.RollupTo.Format(time.DateOnly))
+	if !split.RollupFrom.IsZero() {
+		query = query.Where("day >= ?", split.RollupFrom.Format(time.DateOnly))
+	}
+	return query
+}
+
+// visitedIn limits a visit_logs query to a time range
+func visitedIn(query *gorm.DB, raw model.TimeRange) *gorm.DB {
+	if !raw.From.IsZero() {
+		query = query.Where("visited_at >= ?", raw.From)
+	}
+	if !raw.To.IsZero() {
+		query = query.Where("visited_at < ?", raw.To)
+	}
+	return query
+}
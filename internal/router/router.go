@@ -0,0 +1,305 @@
+package router
+
+import (
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Monthlyaway/short-link/config"
+	"github.com/Monthlyaway/short-link/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// Builder registers routes on a Gin engine and attaches the rate limiters
+// declared in the rate_limit config section. Every route gets a limiter
+// (disabled when no rule matches) so Reload can change limits, including
+// adding or removing rules, without re-registering routes.
+type Builder struct {
+	engine *gin.Engine
+	store  middleware.Store
+
+	mu       sync.Mutex
+	cfg      *config.RateLimitConfig
+	global   *middleware.RateLimiter
+	limiters []*routeLimiter
+}
+
+// routeLimiter ties a registered route to its limiter
+type routeLimiter struct {
+	method  string
+	path    string
+	limiter *middleware.RateLimiter
+}
+
+// RouteGroup registers routes under a common path prefix
+type RouteGroup struct {
+	builder *Builder
+	group   *gin.RouterGroup
+}
+
+// NewBuilder creates a route builder and applies the global limiter to engine
+func NewBuilder(engine *gin.Engine, store middleware.Store, cfg *config.RateLimitConfig) *Builder {
+	b := &Builder{
+		engine: engine,
+		store:  store,
+		cfg:    cfg,
+	}
+
+	b.global = middleware.NewRateLimiterWithStore(store, globalConfig(cfg))
+	engine.Use(b.global.Middleware())
+
+	return b
+}
+
+// Group creates a route group with the given path prefix
+func (b *Builder) Group(prefix string, handlers ...gin.HandlerFunc) *RouteGroup {
+	return &RouteGroup{
+		builder: b,
+		group:   b.engine.Group(prefix, handlers...),
+	}
+}
+
+// GET registers a GET route
+func (b *Builder) GET(relativePath string, handlers ...gin.HandlerFunc) {
+	b.handle(&b.engine.RouterGroup, http.MethodGet, relativePath, handlers)
+}
+
+// POST registers a POST route
+func (b *Builder) POST(relativePath string, handlers ...gin.HandlerFunc) {
+	b.handle(&b.engine.RouterGroup, http.MethodPost, relativePath, handlers)
+}
+
+// PUT registers a PUT route
+func (b *Builder) PUT(relativePath string, handlers ...gin.HandlerFunc) {
+	b.handle(&b.engine.RouterGroup, http.MethodPut, relativePath, handlers)
+}
+
+// PATCH registers a PATCH route
+func (b *Builder) PATCH(relativePath string, handlers ...gin.HandlerFunc) {
+	b.handle(&b.engine.RouterGroup, http.MethodPatch, relativePath, handlers)
+}
+
+// DELETE registers a DELETE route
+func (b *Builder) DELETE(relativePath string, handlers ...gin.HandlerFunc) {
+	b.handle(&b.engine.RouterGroup, http.MethodDelete, relativePath, handlers)
+}
+
+// Handle registers a route with any method
+func (b *Builder) Handle(method, relativePath string, handlers ...gin.HandlerFunc) {
+	b.handle(&b.engine.RouterGroup, method, relativePath, handlers)
+}
+
+// Group creates a nested route group
+func (g *RouteGroup) Group(prefix string, handlers ...gin.HandlerFunc) *RouteGroup {
+	return &RouteGroup{
+		builder: g.builder,
+		group:   g.group.Group(prefix, handlers...),
+	}
+}
+
+// GET registers a GET route in the group
+func (g *RouteGroup) GET(relativePath string, handlers ...gin.HandlerFunc) {
+	g.builder.handle(g.group, http.MethodGet, relativePath, handlers)
+}
+
+// POST registers a POST route in the group
+func (g *RouteGroup) POST(relativePath string, handlers ...gin.HandlerFunc) {
+	g.builder.handle(g.group, http.MethodPost, relativePath, handlers)
+}
+
+// PUT registers a PUT route in the group
+func (g *RouteGroup) PUT(relativePath string, handlers ...gin.HandlerFunc) {
+	g.builder.handle(g.group, http.MethodPut, relativePath, handlers)
+}
+
+// PATCH registers a PATCH route in the group
+func (g *RouteGroup) PATCH(relativePath string, handlers ...gin.HandlerFunc) {
+	g.builder.handle(g.group, http.MethodPatch, relativePath, handlers)
+}
+
+// DELETE registers a DELETE route in the group
+func (g *RouteGroup) DELETE(relativePath string, handlers ...gin.HandlerFunc) {
+	g.builder.handle(g.group, http.MethodDelete, relativePath, handlers)
+}
+
+// Handle registers a route with any method in the group
+func (g *RouteGroup) Handle(method, relativePath string, handlers ...gin.HandlerFunc) {
+	g.builder.handle(g.group, method, relativePath, handlers)
+}
+
+// handle registers a route with its endpoint limiter in front of handlers
+func (b *Builder) handle(group *gin.RouterGroup, method, relativePath string, handlers []gin.HandlerFunc) {
+	fullPath := joinPaths(group.BasePath(), relativePath)
+
+	b.mu.Lock()
+	limiter := middleware.NewRateLimiterWithStore(b.store, endpointConfig(b.cfg, method, fullPath))
+	b.limiters = append(b.limiters, &routeLimiter{
+		method:  method,
+		path:    fullPath,
+		limiter: limiter,
+	})
+	b.mu.Unlock()
+
+	chain := append([]gin.HandlerFunc{limiter.Middleware()}, handlers...)
+	group.Handle(method, relativePath, chain...)
+}
+
+// Reload applies a new rate_limit config to the global and every route limiter
+func (b *Builder) Reload(cfg *config.RateLimitConfig) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.cfg = cfg
+	b.global.Reload(globalConfig(cfg))
+	for _, rl := range b.limiters {
+		rl.limiter.Reload(endpointConfig(cfg, rl.method, rl.path))
+	}
+}
+
+// UnmatchedRules returns endpoint rules that don't match any registered route
+// These usually indicate a typo in the path or method
+func (b *Builder) UnmatchedRules() []config.EndpointRateLimitRule {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var unmatched []config.EndpointRateLimitRule
+	for _, rule := range b.cfg.Endpoints {
+		matched := false
+		for _, rl := range b.limiters {
+			if ruleMatches(rule, rl.method, rl.path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			unmatched = append(unmatched, rule)
+		}
+	}
+	return unmatched
+}
+
+// ParseStrategy converts a strategy name from config to its enum
+func ParseStrategy(name string) middleware.RateLimitStrategy {
+	switch name {
+	case "fixed_window":
+		return middleware.FixedWindow
+	case "sliding_window":
+		return middleware.SlidingWindow
+	case "token_bucket":
+		return middleware.TokenBucket
+	case "sliding_window_counter":
+		return middleware.SlidingWindowCounter
+	default:
+		return middleware.SlidingWindow
+	}
+}
+
+// keyFuncFor chooses how callers are identified (IP by default)
+func keyFuncFor(keyBy string) func(c *gin.Context) string {
+	switch keyBy {
+	case "api_key":
+		return middleware.APIKeyBasedKey
+	case "user":
+		return middleware.UserBasedKey
+	default:
+		return middleware.IPAndPathKey
+	}
+}
+
+// globalConfig builds the limiter config applied to all routes
+// Returns nil when rate limiting is disabled
+func globalConfig(rl *config.RateLimitConfig) *middleware.RateLimitConfig {
+	if !rl.Enabled {
+		return nil
+	}
+
+	// Convert tier rules to middleware tiers
+	tiers := make(map[string]middleware.RateLimitTier, len(rl.Tiers))
+	for name, rule := range rl.Tiers {
+		tiers[name] = middleware.RateLimitTier{
+			Limit:  rule.Limit,
+			Window: time.Duration(rule.Window) * time.Second,
+			Burst:  rule.Burst,
+		}
+	}
+
+	return &middleware.RateLimitConfig{
+		Strategy:      ParseStrategy(rl.Strategy),
+		Limit:         rl.Global.Limit,
+		Window:        time.Duration(rl.Global.Window) * time.Second,
+		Burst:         rl.Global.Burst,
+		Scope:         "global",
+		SkipFunc:      middleware.SkipHealthCheck, // Don't rate limit health checks, robots.txt or favicon
+		FailureMode:   middleware.FailureMode(rl.FailureMode),
+		LocalFallback: rl.LocalFallback,
+		KeyFunc:       keyFuncFor(rl.KeyBy),
+		TierFunc:      middleware.APIKeyTierFunc(rl.APIKeyTiers),
+		Tiers:         tiers,
+
+		StandardHeaders: rl.StandardHeaders,
+	}
+}
+
+// endpointConfig builds the limiter config for a single route
+// A rule with a method beats a rule for all methods on the same path
+// Returns nil when rate limiting is disabled or no rule matches
+func endpointConfig(rl *config.RateLimitConfig, method, fullPath string) *middleware.RateLimitConfig {
+	if !rl.Enabled {
+		return nil
+	}
+
+	var match *config.EndpointRateLimitRule
+	for i := range rl.Endpoints {
+		rule := &rl.Endpoints[i]
+		if !ruleMatches(*rule, method, fullPath) {
+			continue
+		}
+		if match == nil || (match.Method == "" && rule.Method != "") {
+			match = rule
+		}
+	}
+	if match == nil {
+		return nil
+	}
+
+	strategy := rl.Strategy
+	if match.Strategy != "" {
+		strategy = match.Strategy
+	}
+
+	return &middleware.RateLimitConfig{
+		Strategy:      ParseStrategy(strategy),
+		Limit:         match.Limit,
+		Window:        time.Duration(match.Window) * time.Second,
+		Burst:         match.Burst,
+		Scope:         "endpoint:" + method,
+		FailureMode:   middleware.FailureMode(rl.EndpointFailureMode(*match)),
+		LocalFallback: rl.LocalFallback,
+		KeyFunc:       keyFuncFor(rl.KeyBy),
+
+		StandardHeaders: rl.StandardHeaders,
+	}
+}
+
+// ruleMatches reports whether an endpoint rule applies to a route
+func ruleMatches(rule config.EndpointRateLimitRule, method, fullPath string) bool {
+	// Versions of an API endpoint share its rules (see middleware/version.go)
+	if middleware.CanonicalAPIPath(rule.Path) != middleware.CanonicalAPIPath(fullPath) {
+		return false
+	}
+	return rule.Method == "" || strings.EqualFold(rule.Method, method)
+}
+
+// joinPaths joins a group base path and a relative path like Gin does
+func joinPaths(basePath, relativePath string) string {
+	if relativePath == "" {
+		return basePath
+	}
+	joined := path.Join(basePath, relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(joined, "/") {
+		return joined + "/"
+	}
+	return joined
+}
@@ -0,0 +1,116 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Monthlyaway/short-link/config"
+	"github.com/Monthlyaway/short-link/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupBuilder creates a builder with routes on an in-memory store
+func setupBuilder(cfg *config.RateLimitConfig) (*gin.Engine, *Builder) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	b := NewBuilder(engine, middleware.NewMemoryStore(), cfg)
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	api := b.Group("/api/v1")
+	api.GET("/items", ok)
+	api.POST("/items", ok)
+
+	return engine, b
+}
+
+// send performs a request and returns the status code
+func send(engine *gin.Engine, method, path string) int {
+	req := httptest.NewRequest(method, path, nil)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w.Code
+}
+
+// TestPerMethodLimits tests that rules can target a single method
+func TestPerMethodLimits(t *testing.T) {
+	engine, _ := setupBuilder(&config.RateLimitConfig{
+		Enabled:  true,
+		Strategy: "fixed_window",
+		Global:   config.RateLimitRule{Limit: 100, Window: 60},
+		Endpoints: []config.EndpointRateLimitRule{
+			{Path: "/api/v1/items", Method: "POST", Limit: 1, Window: 60},
+		},
+	})
+
+	assert.Equal(t, http.StatusOK, send(engine, "POST", "/api/v1/items"))
+	assert.Equal(t, http.StatusTooManyRequests, send(engine, "POST", "/api/v1/items"))
+
+	// GET on the same path has no endpoint rule
+	assert.Equal(t, http.StatusOK, send(engine, "GET", "/api/v1/items"))
+	assert.Equal(t, http.StatusOK, send(engine, "GET", "/api/v1/items"))
+}
+
+// TestMethodSpecificRuleWins tests rule precedence on the same path
+func TestMethodSpecificRuleWins(t *testing.T) {
+	rl := &config.RateLimitConfig{
+		Enabled:  true,
+		Strategy: "fixed_window",
+		Endpoints: []config.EndpointRateLimitRule{
+			{Path: "/api/v1/items", Method: "GET", Limit: 5, Window: 60},
+			{Path: "/api/v1/items", Limit: 1, Window: 60},
+		},
+	}
+
+	assert.Equal(t, 5, endpointConfig(rl, "GET", "/api/v1/items").Limit)
+	assert.Equal(t, 1, endpointConfig(rl, "POST", "/api/v1/items").Limit)
+	assert.Nil(t, endpointConfig(rl, "GET", "/api/v1/other"))
+}
+
+// TestBuilderReload tests that reload adds rules to existing routes
+func TestBuilderReload(t *testing.T) {
+	engine, b := setupBuilder(&config.RateLimitConfig{Enabled: false})
+
+	assert.Equal(t, http.StatusOK, send(engine, "GET", "/api/v1/items"))
+	assert.Equal(t, http.StatusOK, send(engine, "GET", "/api/v1/items"))
+
+	b.Reload(&config.RateLimitConfig{
+		Enabled:  true,
+		Strategy: "fixed_window",
+		Global:   config.RateLimitRule{Limit: 100, Window: 60},
+		Endpoints: []config.EndpointRateLimitRule{
+			{Path: "/api/v1/items", Method: "GET", Limit: 1, Window: 60},
+			{Path: "/api/v1/missing", Limit: 1, Window: 60},
+		},
+	})
+
+	assert.Equal(t, http.StatusOK, send(engine, "GET", "/api/v1/items"))
+	assert.Equal(t, http.StatusTooManyRequests, send(engine, "GET", "/api/v1/items"))
+
+	unmatched := b.UnmatchedRules()
+	assert.Len(t, unmatched, 1)
+	assert.Equal(t, "/api/v1/missing", unmatched[0].Path)
+}
+
+// TestRulesCoverAPIVersions tests that a /api/v1 rule limits every version of
+// the endpoint with one shared bucket
+func TestRulesCoverAPIVersions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	b := NewBuilder(engine, middleware.NewMemoryStore(), &config.RateLimitConfig{
+		Enabled:  true,
+		Strategy: "fixed_window",
+		Global:   config.RateLimitRule{Limit: 100, Window: 60},
+		Endpoints: []config.EndpointRateLimitRule{
+			{Path: "/api/v1/items", Method: "GET", Limit: 1, Window: 60},
+		},
+	})
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	b.Group("/api/v1").GET("/items", ok)
+	b.Group("/api/v2").GET("/items", ok)
+
+	assert.Empty(t, b.UnmatchedRules())
+	assert.Equal(t, http.StatusOK, send(engine, "GET", "/api/v1/items"))
+	assert.Equal(t, http.StatusTooManyRequests, send(engine, "GET", "/api/v2/items"))
+}
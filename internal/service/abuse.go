@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Monthlyaway/short-link/internal/abuse"
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/tracing"
+)
+
+// ============================================================================
+// ABUSE HANDLING
+// ============================================================================
+// Each redirect is screened by the abuse.Detector before it is served. When
+// a policy triggers, a flag is stored for admins (GET /admin/abuse/flags),
+// sent to the webhook, and, for the disable action, the link is turned off.
+// Throttled links get 429 once over their per-minute limit.
+//
+// Screening fails open: if Redis is unavailable, redirects go through.
+//
+// Anyone can also report a link (POST /api/v1/report/{short_code}). Reports
+// are listed for admins, and a link reported by reportThreshold different
+// IPs gets reportAction (warn or disable) and is flagged like detected
+// abuse. A moderator's decision to allow or warn resolves the reports, so
+// only new ones count towards the threshold.
+//
+// Between allowing and disabling, a link can redirect through a warning
+// page (model.URLMapping.Warning) that visitors click through.
+// ============================================================================
+
+// Abuse errors
+var (
+	ErrLinkThrottled = errors.New("link is throttled")
+	ErrInvalidReport = errors.New("invalid report")
+)
+
+// maxReportDetails matches the abuse_reports.details column
+const maxReportDetails = 1000
+
+// reportCategories are the accepted report categories
+var reportCategories = []string{model.ReportPhishing, model.ReportMalware, model.ReportSpam, model.ReportOther}
+
+// AbuseStore keeps abuse flags and reports and moderates links
+type AbuseStore interface {
+	GetByShortCode(ctx context.Context, shortCode string) (*model.URLMapping, error)
+	CreateAbuseFlag(ctx context.Context, flag *model.AbuseFlag) error
+	ListAbuseFlags(ctx context.Context, shortCode string, limit int) ([]model.AbuseFlag, error)
+	CreateAbuseReport(ctx context.Context, report *model.AbuseReport) error
+	ListAbuseReports(ctx context.Context, shortCode string, limit int) ([]model.AbuseReport, error)
+	ListReportedLinks(ctx context.Context, limit int) ([]model.ReportedLink, error)
+	CountOpenReporters(ctx context.Context, shortCode string) (int64, error)
+	ResolveAbuseReports(ctx context.Context, shortCode string) error
+	SetModeration(ctx context.Context, shortCode string, status int8, warning bool) (bool, error)
+}
+
+// SetAbuseDetection enables abuse detection
+func (s *URLService) SetAbuseDetection(detector *abuse.Detector) {
+	s.abuse = detector
+}
+
+// SetAbuseNotifier sets who is told about flagged links
+func (s *URLService) SetAbuseNotifier(notifier abuse.Notifier) {
+	s.abuseNotifier = notifier
+}
+
+// SetReportThreshold applies action (abuse.ActionWarn or ActionDisable) to
+// links once n different IPs have reported them; 0 (the default) never acts
+func (s *URLService) SetReportThreshold(n int, action string) {
+	s.reportThreshold = n
+	s.reportAction = action
+}
+
+// ScreenVisit counts a redirect for abuse detection and returns
+// ErrLinkThrottled if it must be refused
+// Flags are stored and sent in the background.
+func (s *URLService) ScreenVisit(ctx context.Context, shortCode, ip string) error {
+	if s.abuse == nil {
+		return nil
+	}
+	result, err := s.abuse.Observe(ctx, shortCode, ip)
+	if err != nil {
+		fmt.Printf("Abuse detection failed: %v\n", err)
+		return nil
+	}
+
+	if len(result.Triggers) > 0 {
+		bgCtx := tracing.Detach(ctx)
+		go func() {
+			ctx, cancel := withTimeout(bgCtx, s.timeouts.VisitWrite)
+			defer cancel()
+			for _, trigger := range result.Triggers {
+				s.handleAbuse(ctx, shortCode, trigger)
+			}
+		}()
+	}
+	if result.Throttled {
+		return ErrLinkThrottled
+	}
+	return nil
+}
+
+// handleAbuse records a triggered policy and takes its action
+func (s *URLService) handleAbuse(ctx context.Context, shortCode string, trigger abuse.Trigger) {
+	flag := &model.AbuseFlag{
+		ShortCode: shortCode,
+		Reason:    trigger.Reason,
+		Action:    trigger.Action,
+		Detail:    trigger.Detail,
+	}
+	fmt.Printf("Link %s flagged for %s (%s): %s\n", shortCode, flag.Reason, flag.Action, flag.Detail)
+
+	// Actions taken automatically are not a review: reports stay open
+	switch trigger.Action {
+	case abuse.ActionWarn:
+		if err := s.moderate(ctx, shortCode, model.ModerationWarn, false); err != nil {
+			fmt.Printf("Failed to add a warning to link %s: %v\n", shortCode, err)
+		}
+	case abuse.ActionDisable:
+		if err := s.moderate(ctx, shortCode, model.ModerationDisable, false); err != nil {
+			fmt.Printf("Failed to disable link %s: %v\n", shortCode, err)
+		}
+	}
+	if err := s.repo.CreateAbuseFlag(ctx, flag); err != nil {
+		fmt.Printf("Failed to store abuse flag: %v\n", err)
+	}
+	if err := s.abuseNotifier.Notify(ctx, flag); err != nil {
+		fmt.Printf("Failed to send abuse notification: %v\n", err)
+	}
+}
+
+// ModerateLink applies a moderator's decision (model.ModerationAllow,
+// ModerationWarn or ModerationDisable) to a link
+// Allowing or warning also resolves the link's reports and lifts an abuse
+// throttle, so a reviewed link starts afresh.
+func (s *URLService) ModerateLink(ctx context.Context, shortCode, decision string) error {
+	return s.moderate(ctx, shortCode, decision, true)
+}
+
+// moderate applies a moderation decision; reviewed resolves reports and
+// lifts throttles unless the link is disabled
+func (s *URLService) moderate(ctx context.Context, shortCode, decision string, reviewed bool) error {
+	var status int8 = 1
+	warning := false
+	switch decision {
+	case model.ModerationAllow:
+	case model.ModerationWarn:
+		warning = true
+	case model.ModerationDisable:
+		status = 0
+	default:
+		return fmt.Errorf("unknown moderation decision %q", decision)
+	}
+
+	found, err := s.repo.SetModeration(ctx, shortCode, status, warning)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrShortCodeNotFound
+	}
+	if err := s.cache.Delete(ctx, shortCode); err != nil {
+		fmt.Printf("Failed to delete cache: %v\n", err)
+	}
+	if !reviewed || decision == model.ModerationDisable {
+		return nil
+	}
+	if err := s.repo.ResolveAbuseReports(ctx, shortCode); err != nil {
+		fmt.Printf("Failed to resolve abuse reports: %v\n", err)
+	}
+	if s.abuse != nil {
+		if err := s.abuse.Unthrottle(ctx, shortCode); err != nil {
+			fmt.Printf("Failed to unthrottle link: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// ListAbuseFlags returns the newest abuse flags, of one link if shortCode is set
+func (s *URLService) ListAbuseFlags(ctx context.Context, shortCode string, limit int) ([]model.AbuseFlag, error) {
+	return s.repo.ListAbuseFlags(ctx, shortCode, limit)
+}
+
+// ReportLink stores a report that a link is malicious, warning about or
+// disabling the link once enough different IPs have reported it
+// An empty category counts as "other".
+func (s *URLService) ReportLink(ctx context.Context, shortCode, category, details, reporterIP string) error {
+	category = strings.ToLower(strings.TrimSpace(category))
+	if category == "" {
+		category = model.ReportOther
+	}
+	if !slices.Contains(reportCategories, category) {
+		return fmt.Errorf("%w: category must be one of %s", ErrInvalidReport, strings.Join(reportCategories, ", "))
+	}
+	details = strings.TrimSpace(details)
+	if utf8.RuneCountInString(details) > maxReportDetails {
+		return fmt.Errorf("%w: details must be at most %d characters", ErrInvalidReport, maxReportDetails)
+	}
+
+	mapping, err := s.repo.GetByShortCode(ctx, shortCode)
+	if err != nil {
+		return err
+	}
+	if mapping == nil {
+		return ErrShortCodeNotFound
+	}
+
+	report := &model.AbuseReport{
+		ShortCode:  shortCode,
+		Category:   category,
+		Details:    details,
+		ReporterIP: reporterIP,
+	}
+	if err := s.repo.CreateAbuseReport(ctx, report); err != nil {
+		return err
+	}
+
+	if s.reportThreshold <= 0 || mapping.Status != 1 ||
+		(s.reportAction == abuse.ActionWarn && mapping.Warning) {
+		return nil // Nothing to do, or already done
+	}
+	reporters, err := s.repo.CountOpenReporters(ctx, shortCode)
+	if err != nil {
+		fmt.Printf("Failed to count reporters: %v\n", err)
+		return nil
+	}
+	if reporters >= int64(s.reportThreshold) {
+		s.handleAbuse(ctx, shortCode, abuse.Trigger{
+			Reason: abuse.ReasonReports,
+			Action: s.reportAction,
+			Detail: fmt.Sprintf("reported by %d different IPs", reporters),
+		})
+	}
+	return nil
+}
+
+// ListReportedLinks returns links with open reports, most reported first
+func (s *URLService) ListReportedLinks(ctx context.Context, limit int) ([]model.ReportedLink, error) {
+	return s.repo.ListReportedLinks(ctx, limit)
+}
+
+// ListAbuseReports returns the newest reports of a link
+func (s *URLService) ListAbuseReports(ctx context.Context, shortCode string, limit int) ([]model.AbuseReport, error) {
+	return s.repo.ListAbuseReports(ctx, shortCode, limit)
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReportLinkValidation tests that invalid reports are rejected before
+// anything is stored
+func TestReportLinkValidation(t *testing.T) {
+	s := &URLService{}
+	ctx := context.Background()
+
+	err := s.ReportLink(ctx, "abc123", "scam", "", "203.0.113.7")
+	assert.ErrorIs(t, err, ErrInvalidReport)
+	assert.Contains(t, err.Error(), "phishing, malware, spam, other")
+
+	err = s.ReportLink(ctx, "abc123", "Phishing", strings.Repeat("x", maxReportDetails+1), "203.0.113.7")
+	assert.ErrorIs(t, err, ErrInvalidReport)
+}
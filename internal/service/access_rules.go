@@ -0,0 +1,101 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// Errors returned for per-link access rules
+var (
+	ErrAccessDenied       = errors.New("access to this link is restricted")
+	ErrInvalidAccessRules = errors.New("invalid access rules")
+)
+
+// Bounds on the number of entries in each rule list of a link
+const (
+	maxReferrerRules = 50
+	maxIPRules       = 100
+)
+
+// normalizeAccessRules validates rules and returns them in stored form:
+// lower-case hosts without scheme or path and canonical CIDRs, de-duplicated
+// Returns nil for rules that restrict nothing
+func normalizeAccessRules(rules *model.AccessRules) (*model.AccessRules, error) {
+	if rules.IsEmpty() {
+		return nil, nil
+	}
+	if len(rules.Referrers) > maxReferrerRules {
+		return nil, fmt.Errorf("%w: at most %d referrers", ErrInvalidAccessRules, maxReferrerRules)
+	}
+
+	normalized := &model.AccessRules{}
+	seen := make(map[string]bool, len(rules.Referrers))
+	for _, referrer := range rules.Referrers {
+		host, err := referrerHost(referrer)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[host] {
+			seen[host] = true
+			normalized.Referrers = append(normalized.Referrers, host)
+		}
+	}
+
+	var err error
+	if normalized.AllowedIPs, err = normalizeCIDRs("allowed_ips", rules.AllowedIPs); err != nil {
+		return nil, err
+	}
+	if normalized.BlockedIPs, err = normalizeCIDRs("blocked_ips", rules.BlockedIPs); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// normalizeCIDRs parses IPs and CIDRs into masked CIDRs (a single IP
+// becomes a /32 or /128), de-duplicated
+func normalizeCIDRs(field string, values []string) ([]string, error) {
+	if len(values) > maxIPRules {
+		return nil, fmt.Errorf("%w: at most %d %s", ErrInvalidAccessRules, maxIPRules, field)
+	}
+
+	var cidrs []string
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		var prefix netip.Prefix
+		if addr, err := netip.ParseAddr(value); err == nil {
+			addr = addr.Unmap().WithZone("")
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		} else if prefix, err = netip.ParsePrefix(value); err != nil {
+			return nil, fmt.Errorf("%w: %s entry %q must be an IP or CIDR", ErrInvalidAccessRules, field, value)
+		}
+		cidr := prefix.Masked().String()
+		if !seen[cidr] {
+			seen[cidr] = true
+			cidrs = append(cidrs, cidr)
+		}
+	}
+	return cidrs, nil
+}
+
+// referrerHost returns the host named by a referrer rule, which may be
+// given as a bare host or a URL
+func referrerHost(referrer string) (string, error) {
+	value := strings.ToLower(strings.TrimSpace(referrer))
+	if value == model.DirectReferrer {
+		return value, nil
+	}
+	if !strings.Contains(value, "://") {
+		value = "https://" + value
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Hostname() == "" || strings.ContainsAny(u.Hostname(), "*%") {
+		return "", fmt.Errorf("%w: referrer %q must be a host name, e.g. example.com", ErrInvalidAccessRules, referrer)
+	}
+	return strings.TrimSuffix(u.Hostname(), "."), nil
+}
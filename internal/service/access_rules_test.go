@@ -0,0 +1,79 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNormalizeAccessRules tests validation and normalization of referrer rules
+func TestNormalizeAccessRules(t *testing.T) {
+	rules, err := normalizeAccessRules(&model.AccessRules{
+		Referrers: []string{"Example.COM", "https://blog.example.org/posts", "direct", "example.com."},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"example.com", "blog.example.org", "direct"}, rules.Referrers)
+
+	rules, err = normalizeAccessRules(&model.AccessRules{})
+	assert.NoError(t, err)
+	assert.Nil(t, rules)
+
+	for _, referrer := range []string{"", "*.example.com", "https://"} {
+		_, err := normalizeAccessRules(&model.AccessRules{Referrers: []string{referrer}})
+		assert.ErrorIs(t, err, ErrInvalidAccessRules, referrer)
+	}
+}
+
+// TestAccessRulesAllows tests referrer matching on redirect
+func TestAccessRulesAllows(t *testing.T) {
+	var none *model.AccessRules
+	assert.True(t, none.Allows(model.Visitor{Referrer: "https://anywhere.net/"}))
+
+	sites := &model.AccessRules{Referrers: []string{"example.com"}}
+	assert.True(t, sites.Allows(model.Visitor{Referrer: "https://example.com/page"}))
+	assert.True(t, sites.Allows(model.Visitor{Referrer: "https://www.example.com/"}))
+	assert.False(t, sites.Allows(model.Visitor{Referrer: "https://notexample.com/"}))
+	assert.False(t, sites.Allows(model.Visitor{Referrer: "https://example.com.evil.net/"}))
+	assert.False(t, sites.Allows(model.Visitor{}))
+
+	directOnly := &model.AccessRules{Referrers: []string{model.DirectReferrer}}
+	assert.True(t, directOnly.Allows(model.Visitor{}))
+	assert.False(t, directOnly.Allows(model.Visitor{Referrer: "https://example.com/"}))
+}
+
+// TestNormalizeIPRules tests validation and normalization of IP rules
+func TestNormalizeIPRules(t *testing.T) {
+	rules, err := normalizeAccessRules(&model.AccessRules{
+		AllowedIPs: []string{"10.1.2.3/8", "203.0.113.7", "10.0.0.0/8", "2001:db8::1"},
+		BlockedIPs: []string{"::ffff:192.0.2.1"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.0/8", "203.0.113.7/32", "2001:db8::1/128"}, rules.AllowedIPs)
+	assert.Equal(t, []string{"192.0.2.1/32"}, rules.BlockedIPs)
+
+	for _, value := range []string{"10.0.0.0/33", "office", ""} {
+		_, err := normalizeAccessRules(&model.AccessRules{AllowedIPs: []string{value}})
+		assert.ErrorIs(t, err, ErrInvalidAccessRules, value)
+	}
+}
+
+// TestAccessRulesAllowsIP tests CIDR matching on redirect
+func TestAccessRulesAllowsIP(t *testing.T) {
+	office := &model.AccessRules{AllowedIPs: []string{"10.0.0.0/8"}, BlockedIPs: []string{"10.9.0.0/16"}}
+	assert.True(t, office.Allows(model.Visitor{IP: "10.1.2.3"}))
+	assert.True(t, office.Allows(model.Visitor{IP: "::ffff:10.1.2.3"}))
+	assert.False(t, office.Allows(model.Visitor{IP: "10.9.1.1"}))
+	assert.False(t, office.Allows(model.Visitor{IP: "203.0.113.7"}))
+	assert.False(t, office.Allows(model.Visitor{}))
+
+	blocked := &model.AccessRules{BlockedIPs: []string{"203.0.113.0/24"}}
+	assert.False(t, blocked.Allows(model.Visitor{IP: "203.0.113.7"}))
+	assert.True(t, blocked.Allows(model.Visitor{IP: "198.51.100.1"}))
+	assert.True(t, blocked.Allows(model.Visitor{}))
+
+	// Every rule must pass
+	both := &model.AccessRules{Referrers: []string{"direct"}, AllowedIPs: []string{"10.0.0.0/8"}}
+	assert.True(t, both.Allows(model.Visitor{IP: "10.0.0.1"}))
+	assert.False(t, both.Allows(model.Visitor{IP: "10.0.0.1", Referrer: "https://example.com/"}))
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ============================================================================
+// ALIAS NAMESPACES
+// ============================================================================
+// Short codes are global, so two tenants can't both have /launch. Users
+// with a namespace (auth.namespaces) can instead name any link they may
+// edit inside it:
+//
+//   PUT /api/v1/aliases/launch {"short_code":"aB3xY9"}  (user acme)
+//   GET /acme/launch -> same redirect as /aB3xY9
+//
+// Aliases are unique per namespace only, so every tenant can have its own
+// /<namespace>/launch. An alias just points at a link: the redirect, its
+// rules and its visit stats are those of the short code.
+// ============================================================================
+
+// Errors returned for aliases
+var (
+	ErrAliasNotFound = errors.New("alias not found")
+	ErrNoNamespace   = errors.New("no alias namespace is configured for this user")
+	ErrInvalidAlias  = errors.New("invalid alias")
+)
+
+// AliasStore keeps alias namespaces
+type AliasStore interface {
+	GetAlias(ctx context.Context, namespace, alias string) (*model.LinkAlias, error)
+	SetAlias(ctx context.Context, alias *model.LinkAlias) error
+	DeleteAlias(ctx context.Context, namespace, alias string) (bool, error)
+	ListAliases(ctx context.Context, namespace string) ([]model.LinkAlias, error)
+	DeleteAliasesTo(ctx context.Context, shortCode string) (int64, error)
+	LinkOrgID(ctx context.Context, shortCode string) (orgID uint, found bool, err error)
+}
+
+// SetNamespaces assigns alias namespaces to users (user ID -> namespace)
+func (s *URLService) SetNamespaces(namespaces map[string]string) {
+	s.namespaces = namespaces
+}
+
+// NamespaceOf returns the alias namespace of userID, or ErrNoNamespace
+func (s *URLService) NamespaceOf(userID string) (string, error) {
+	namespace, ok := s.namespaces[userID]
+	if userID == "" || !ok {
+		return "", ErrNoNamespace
+	}
+	return namespace, nil
+}
+
+// SetAlias points alias, in userID's namespace, at shortCode, creating it
+// if needed
+// userID must be allowed to edit the link, as for any other change to it
+func (s *URLService) SetAlias(ctx context.Context, userID, alias, shortCode string) (*model.LinkAlias, error) {
+	namespace, err := s.NamespaceOf(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateAlias(alias); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAlias, err)
+	}
+
+	orgID, found, err := s.repo.LinkOrgID(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrShortCodeNotFound
+	}
+	if orgID != 0 {
+		if err := s.AuthorizeOrg(ctx, orgID, userID, model.RoleEditor); err != nil {
+			return nil, err
+		}
+	}
+
+	entry := &model.LinkAlias{Namespace: namespace, Alias: alias, ShortCode: shortCode}
+	if err := s.repo.SetAlias(ctx, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// DeleteAlias removes alias from userID's namespace
+func (s *URLService) DeleteAlias(ctx context.Context, userID, alias string) error {
+	namespace, err := s.NamespaceOf(userID)
+	if err != nil {
+		return err
+	}
+	deleted, err := s.repo.DeleteAlias(ctx, namespace, alias)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAliasNotFound
+	}
+	return nil
+}
+
+// ListAliases returns the aliases in userID's namespace
+func (s *URLService) ListAliases(ctx context.Context, userID string) ([]model.LinkAlias, error) {
+	namespace, err := s.NamespaceOf(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListAliases(ctx, namespace)
+}
+
+// ResolveAlias returns the short code alias points at in namespace
+// Aliases aren't cached, so they don't resolve while MySQL is down
+func (s *URLService) ResolveAlias(ctx context.Context, namespace, alias string) (string, error) {
+	if !s.db.Available() {
+		return "", ErrDatabaseUnavailable
+	}
+	entry, err := s.repo.GetAlias(ctx, namespace, alias)
+	if err != nil {
+		return "", err
+	}
+	if entry == nil {
+		return "", ErrAliasNotFound
+	}
+	return entry.ShortCode, nil
+}
+
+// AliasURL returns the URL alias is served at, on requestOrigin
+func (s *URLService) AliasURL(requestOrigin string, alias *model.LinkAlias) string {
+	return strings.TrimRight(requestOrigin, "/") + s.redirectPrefix + "/" + alias.Namespace + "/" + alias.Alias
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAliasNamespaces tests that tenants can reuse an alias, each in their
+// own namespace, and only for links they may edit
+func TestAliasNamespaces(t *testing.T) {
+	repo := newFakeRepository(
+		&model.URLMapping{ShortCode: "acme123", OriginalURL: "https://acme.example.com", Status: 1},
+		&model.URLMapping{ShortCode: "glob123", OriginalURL: "https://globex.example.com", Status: 1},
+		&model.URLMapping{ShortCode: "team123", OriginalURL: "https://team.example.com", Status: 1, OrgID: 3},
+	)
+	repo.members = []model.OrgMember{{OrgID: 3, UserID: "alice", Role: model.RoleViewer}}
+	s := NewURLService(repo, newFakeCache(), newFakeFilter())
+	s.SetNamespaces(map[string]string{"alice": "acme", "bob": "globex"})
+	ctx := context.Background()
+
+	_, err := s.SetAlias(ctx, "alice", "launch", "acme123")
+	require.NoError(t, err)
+	alias, err := s.SetAlias(ctx, "bob", "launch", "glob123")
+	require.NoError(t, err)
+	assert.Equal(t, "globex", alias.Namespace)
+
+	code, err := s.ResolveAlias(ctx, "acme", "launch")
+	require.NoError(t, err)
+	assert.Equal(t, "acme123", code)
+	code, err = s.ResolveAlias(ctx, "globex", "launch")
+	require.NoError(t, err)
+	assert.Equal(t, "glob123", code)
+	_, err = s.ResolveAlias(ctx, "acme", "missing")
+	assert.ErrorIs(t, err, ErrAliasNotFound)
+
+	_, err = s.SetAlias(ctx, "carol", "launch", "acme123")
+	assert.ErrorIs(t, err, ErrNoNamespace)
+	_, err = s.SetAlias(ctx, "alice", "a b", "acme123")
+	assert.ErrorIs(t, err, ErrInvalidAlias)
+	_, err = s.SetAlias(ctx, "alice", "docs", "nope123")
+	assert.ErrorIs(t, err, ErrShortCodeNotFound)
+	_, err = s.SetAlias(ctx, "alice", "team", "team123")
+	assert.ErrorIs(t, err, ErrForbidden, "viewers can't alias their organization's links")
+
+	// Moving an alias keeps the other tenant's
+	_, err = s.SetAlias(ctx, "alice", "launch", "glob123")
+	require.NoError(t, err)
+	code, _ = s.ResolveAlias(ctx, "acme", "launch")
+	assert.Equal(t, "glob123", code)
+
+	require.NoError(t, s.DeleteAlias(ctx, "alice", "launch"))
+	assert.ErrorIs(t, s.DeleteAlias(ctx, "alice", "launch"), ErrAliasNotFound)
+	code, err = s.ResolveAlias(ctx, "globex", "launch")
+	require.NoError(t, err)
+	assert.Equal(t, "glob123", code)
+
+	assert.Equal(t, "https://s.example.com/globex/launch", s.AliasURL("https://s.example.com/", alias))
+}
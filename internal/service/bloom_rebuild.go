@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// bloomSaturationCheck is how often a filter with auto-resize is checked for
+// having more codes than its capacity
+const bloomSaturationCheck = time.Minute
+
+// BloomRebuild periodically rebuilds the bloom filter from the database
+// Deleted, purged and recycled links can't be removed from a bloom filter, so
+// without rebuilds their codes keep costing cache and MySQL lookups and use up
+// the filter's false-positive budget. With auto-resize, a filter that holds
+// more codes than it was sized for is rebuilt straight away into a larger one,
+// instead of letting its false positive rate (and MySQL load) creep up.
+type BloomRebuild struct {
+	service    *URLService
+	interval   time.Duration // Between scheduled rebuilds; 0 disables them
+	autoResize bool
+}
+
+// NewBloomRebuild creates a rebuild job
+func NewBloomRebuild(service *URLService, interval time.Duration, autoResize bool) *BloomRebuild {
+	return &BloomRebuild{service: service, interval: interval, autoResize: autoResize}
+}
+
+// Run rebuilds every interval, and on saturation with auto-resize, until ctx
+// is done. The filter was just loaded at startup, so the first rebuild waits.
+func (j *BloomRebuild) Run(ctx context.Context) {
+	tick := j.interval
+	if j.autoResize && (tick == 0 || tick > bloomSaturationCheck) {
+		tick = bloomSaturationCheck
+	}
+	lastRebuild := time.Now()
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if !j.due(now, lastRebuild) {
+				continue
+			}
+			if err := j.RunOnce(ctx); err != nil {
+				fmt.Printf("Bloom filter rebuild failed: %v\n", err)
+			}
+			lastRebuild = now
+		}
+	}
+}
+
+// due reports whether a scheduled rebuild is due or the filter is saturated
+func (j *BloomRebuild) due(now, lastRebuild time.Time) bool {
+	if j.interval > 0 && now.Sub(lastRebuild) >= j.interval {
+		return true
+	}
+	if j.autoResize {
+		if stats := j.service.BloomFilterStats(); stats.Saturated() {
+			fmt.Printf("Bloom filter saturated (~%d codes, capacity %d), rebuilding\n", stats.EstimatedEntries, stats.Capacity)
+			return true
+		}
+	}
+	return false
+}
+
+// RunOnce rebuilds the filter
+func (j *BloomRebuild) RunOnce(ctx context.Context) error {
+	start := time.Now()
+	loaded, err := j.service.RebuildBloomFilter(ctx)
+	if err != nil {
+		return err
+	}
+	stats := j.service.BloomFilterStats()
+	fmt.Printf("Rebuilt bloom filter with %d short codes (capacity %d) in %s\n",
+		loaded, stats.Capacity, time.Since(start).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ErrInvalidCachePolicy is returned for out-of-range per-link cache settings
+var ErrInvalidCachePolicy = errors.New("invalid cache settings")
+
+// MaxLinkCacheTTL is the longest per-link cache TTL, in seconds (30 days)
+const MaxLinkCacheTTL = 30 * 24 * 60 * 60
+
+// validateCachePolicy checks per-link cache settings
+func validateCachePolicy(policy model.CachePolicy) error {
+	if policy.TTL < 0 || policy.TTL > MaxLinkCacheTTL {
+		return fmt.Errorf("%w: cache_ttl must be between 0 and %d seconds", ErrInvalidCachePolicy, MaxLinkCacheTTL)
+	}
+	if policy.NoCache && policy.TTL > 0 {
+		return fmt.Errorf("%w: cache_ttl can't be combined with no_cache", ErrInvalidCachePolicy)
+	}
+	return nil
+}
+
+// cacheMapping stores a mapping in the cache as its cache policy allows
+func (s *URLService) cacheMapping(ctx context.Context, mapping *model.URLMapping) error {
+	switch {
+	case mapping.NoCache:
+		return nil
+	case mapping.CacheTTL > 0:
+		return s.cache.SetWithTTL(ctx, mapping, time.Duration(mapping.CacheTTL)*time.Second)
+	default:
+		return s.cache.Set(ctx, mapping)
+	}
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestValidateCachePolicy tests the accepted per-link cache settings
+func TestValidateCachePolicy(t *testing.T) {
+	assert.NoError(t, validateCachePolicy(model.CachePolicy{}))
+	assert.NoError(t, validateCachePolicy(model.CachePolicy{TTL: 30}))
+	assert.NoError(t, validateCachePolicy(model.CachePolicy{NoCache: true}))
+
+	for _, policy := range []model.CachePolicy{
+		{TTL: -1},
+		{TTL: MaxLinkCacheTTL + 1},
+		{TTL: 30, NoCache: true},
+	} {
+		err := validateCachePolicy(policy)
+		assert.True(t, errors.Is(err, ErrInvalidCachePolicy), "%+v: %v", policy, err)
+	}
+}
+
+// TestUpdatedCachePolicy tests that cache_ttl and no_cache exclude each other
+func TestUpdatedCachePolicy(t *testing.T) {
+	ttl := func(n int) *int { return &n }
+	flag := func(b bool) *bool { return &b }
+
+	current := model.CachePolicy{TTL: 60}
+	assert.Equal(t, current, updatedCachePolicy(current, model.URLUpdate{}))
+	assert.Equal(t, model.CachePolicy{TTL: 10}, updatedCachePolicy(current, model.URLUpdate{CacheTTL: ttl(10)}))
+	assert.Equal(t, model.CachePolicy{NoCache: true}, updatedCachePolicy(current, model.URLUpdate{NoCache: flag(true)}))
+	assert.Equal(t, current, updatedCachePolicy(current, model.URLUpdate{NoCache: flag(false)}))
+
+	noCache := model.CachePolicy{NoCache: true}
+	assert.Equal(t, model.CachePolicy{TTL: 5}, updatedCachePolicy(noCache, model.URLUpdate{CacheTTL: ttl(5)}))
+	assert.Equal(t, noCache, updatedCachePolicy(noCache, model.URLUpdate{CacheTTL: ttl(0)}))
+}
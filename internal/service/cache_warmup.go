@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// CacheWarmupStore finds the links WarmCache loads
+type CacheWarmupStore interface {
+	MostVisitedActive(ctx context.Context, limit int) ([]model.URLMapping, error)
+}
+
+// WarmCache loads the n most visited active links into Redis (and the local
+// tier) so a cold restart doesn't send a burst of cache misses to MySQL.
+// Links are written by concurrency workers; returns how many were cached.
+func (s *URLService) WarmCache(ctx context.Context, n, concurrency int) (int, error) {
+	mappings, err := s.repo.MostVisitedActive(ctx, n)
+	if err != nil {
+		return 0, err
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// Hand out the least visited first, so when the local tier is smaller
+	// than n the hottest links are the most recently used ones it keeps
+	work := make(chan *model.URLMapping)
+	go func() {
+		defer close(work)
+		for i := len(mappings) - 1; i >= 0; i-- {
+			select {
+			case work <- &mappings[i]:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var cached atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for mapping := range work {
+				if err := s.cacheMapping(ctx, mapping); err != nil {
+					fmt.Printf("Failed to warm cache for %s: %v\n", mapping.ShortCode, err)
+					continue
+				}
+				cached.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return int(cached.Load()), ctx.Err()
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// Errors returned by campaign operations
+var (
+	ErrCampaignNotFound = errors.New("campaign not found")
+	ErrCampaignExists   = errors.New("campaign already exists")
+	ErrInvalidCampaign  = errors.New("invalid campaign")
+)
+
+// maxCampaignLinksPerRequest bounds how many codes one attach call may add
+const maxCampaignLinksPerRequest = 500
+
+// CampaignStore keeps campaigns, their links and their stats
+type CampaignStore interface {
+	CreateCampaign(ctx context.Context, campaign *model.Campaign) error
+	GetCampaign(ctx context.Context, id uint) (*model.Campaign, error)
+	GetCampaignByName(ctx context.Context, name string) (*model.Campaign, error)
+	AddCampaignLinks(ctx context.Context, campaignID uint, shortCodes []string) error
+	RemoveCampaignLink(ctx context.Context, campaignID uint, shortCode string) (bool, error)
+	ExistingShortCodes(ctx context.Context, shortCodes []string) ([]string, error)
+	CampaignLinkStats(ctx context.Context, campaignID uint) ([]model.CampaignLinkStat, error)
+	CampaignVisitBreakdown(ctx context.Context, campaignID uint, column string, from, to time.Time, limit int) ([]model.VisitStat, error)
+}
+
+// CreateCampaign creates a campaign with a unique name, owned by userID or,
+// if orgID is set, by an organization in which userID is an editor
+func (s *URLService) CreateCampaign(ctx context.Context, name, description, userID string, orgID uint) (*model.Campaign, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 128 {
+		return nil, fmt.Errorf("%w: name must be 1-128 characters", ErrInvalidCampaign)
+	}
+	if len(description) > 1024 {
+		return nil, fmt.Errorf("%w: description must be at most 1024 characters", ErrInvalidCampaign)
+	}
+	if userID == "" {
+		return nil, ErrForbidden
+	}
+	if orgID != 0 {
+		if err := s.AuthorizeOrg(ctx, orgID, userID, model.RoleEditor); err != nil {
+			return nil, err
+		}
+	}
+
+	existing, err := s.repo.GetCampaignByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrCampaignExists
+	}
+
+	campaign := &model.Campaign{Name: name, Description: description, OwnerID: userID, OrgID: orgID}
+	if err := s.repo.CreateCampaign(ctx, campaign); err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// GetCampaign retrieves a campaign by ID; userID must be allowed to view it
+func (s *URLService) GetCampaign(ctx context.Context, id uint, userID string) (*model.Campaign, error) {
+	return s.authorizedCampaign(ctx, id, userID, model.RoleViewer)
+}
+
+// authorizedCampaign loads a campaign and returns ErrForbidden unless userID
+// has at least role required in its organization or, for a personal
+// campaign, is its owner
+func (s *URLService) authorizedCampaign(ctx context.Context, id uint, userID string, required model.OrgRole) (*model.Campaign, error) {
+	campaign, err := s.repo.GetCampaign(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, ErrCampaignNotFound
+	}
+	if campaign.OrgID != 0 {
+		if err := s.AuthorizeOrg(ctx, campaign.OrgID, userID, required); err != nil {
+			return nil, err
+		}
+		return campaign, nil
+	}
+	if userID == "" || campaign.OwnerID != userID {
+		return nil, ErrForbidden
+	}
+	return campaign, nil
+}
+
+// AttachLinks adds short links to a campaign userID may edit
+// userID must be allowed to view every link; returns the codes that don't
+// exist, and nothing is attached if any are missing or forbidden
+func (s *URLService) AttachLinks(ctx context.Context, campaignID uint, userID string, shortCodes []string) ([]string, error) {
+	codes := normalizeShortCodes(shortCodes)
+	if len(codes) == 0 || len(codes) > maxCampaignLinksPerRequest {
+		return nil, fmt.Errorf("%w: between 1 and %d short codes required", ErrInvalidCampaign, maxCampaignLinksPerRequest)
+	}
+	if _, err := s.authorizedCampaign(ctx, campaignID, userID, model.RoleEditor); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.ExistingShortCodes(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]bool, len(existing))
+	for _, code := range existing {
+		found[code] = true
+	}
+	var missing []string
+	for _, code := range codes {
+		if !found[code] {
+			missing = append(missing, code)
+		}
+	}
+	if len(missing) > 0 {
+		return missing, ErrShortCodeNotFound
+	}
+	for _, code := range codes {
+		if err := s.AuthorizeLink(ctx, code, userID, model.RoleViewer); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, s.repo.AddCampaignLinks(ctx, campaignID, codes)
+}
+
+// DetachLink removes a short link from a campaign userID may edit
+func (s *URLService) DetachLink(ctx context.Context, campaignID uint, userID, shortCode string) error {
+	if _, err := s.authorizedCampaign(ctx, campaignID, userID, model.RoleEditor); err != nil {
+		return err
+	}
+	removed, err := s.repo.RemoveCampaignLink(ctx, campaignID, shortCode)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrShortCodeNotFound
+	}
+	return nil
+}
+
+// GetCampaignStats returns visit totals, per-link counters and the top
+// countries and referrers across a campaign's links in [from, to)
+// Totals and per-link counters are all-time; breakdowns honor the range
+// userID must be allowed to view the campaign
+func (s *URLService) GetCampaignStats(ctx context.Context, campaignID uint, userID string, from, to time.Time) (*model.CampaignStats, error) {
+	campaign, err := s.GetCampaign(ctx, campaignID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := s.repo.CampaignLinkStats(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	stats := &model.CampaignStats{Campaign: campaign, Links: links}
+	for _, link := range links {
+		stats.VisitCount += link.VisitCount
+		stats.BotVisitCount += link.BotVisitCount
+	}
+
+	if stats.Countries, err = s.repo.CampaignVisitBreakdown(ctx, campaignID, "country", from, to, statsBreakdownLimit); err != nil {
+		return nil, err
+	}
+	if stats.Referrers, err = s.repo.CampaignVisitBreakdown(ctx, campaignID, "referrer", from, to, statsBreakdownLimit); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// normalizeShortCodes trims, drops empty and de-duplicates codes, keeping order
+func normalizeShortCodes(shortCodes []string) []string {
+	seen := make(map[string]bool, len(shortCodes))
+	codes := make([]string, 0, len(shortCodes))
+	for _, code := range shortCodes {
+		code = strings.TrimSpace(code)
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+	return codes
+}
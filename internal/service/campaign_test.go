@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNormalizeShortCodes tests trimming and de-duplication
+func TestNormalizeShortCodes(t *testing.T) {
+	assert.Equal(t, []string{"abc", "def"}, normalizeShortCodes([]string{" abc", "def", "", "abc"}))
+	assert.Empty(t, normalizeShortCodes(nil))
+}
+
+// TestCampaignAccess tests that personal campaigns are limited to their owner
+// and organization campaigns to members with the required role
+func TestCampaignAccess(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepository()
+	repo.campaigns = []model.Campaign{
+		{ID: 1, Name: "personal", OwnerID: "alice"},
+		{ID: 2, Name: "team", OwnerID: "alice", OrgID: 7},
+		{ID: 3, Name: "legacy"},
+	}
+	repo.members = []model.OrgMember{
+		{OrgID: 7, UserID: "bob", Role: model.RoleViewer},
+		{OrgID: 7, UserID: "carol", Role: model.RoleEditor},
+	}
+	s := &URLService{repo: repo}
+
+	_, err := s.GetCampaign(ctx, 1, "alice")
+	assert.NoError(t, err)
+	_, err = s.GetCampaign(ctx, 1, "bob")
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = s.GetCampaign(ctx, 1, "")
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	_, err = s.GetCampaign(ctx, 2, "bob")
+	assert.NoError(t, err)
+	_, err = s.GetCampaign(ctx, 2, "alice")
+	assert.ErrorIs(t, err, ErrForbidden, "creator who isn't a member")
+
+	_, err = s.GetCampaign(ctx, 3, "alice")
+	assert.ErrorIs(t, err, ErrForbidden, "campaign without an owner")
+	_, err = s.GetCampaign(ctx, 4, "alice")
+	assert.ErrorIs(t, err, ErrCampaignNotFound)
+
+	err = s.DetachLink(ctx, 2, "bob", "abc123")
+	assert.ErrorIs(t, err, ErrForbidden, "viewers can't edit")
+}
+
+// TestAttachLinksAuthorizesLinks tests that every attached link must be
+// visible to the caller, so links of other organizations can't be pulled into
+// a campaign to read their stats
+func TestAttachLinksAuthorizesLinks(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepository(
+		&model.URLMapping{ShortCode: "public"},
+		&model.URLMapping{ShortCode: "theirs", OrgID: 9},
+	)
+	repo.campaigns = []model.Campaign{{ID: 1, Name: "personal", OwnerID: "alice"}}
+	s := &URLService{repo: repo}
+
+	_, err := s.AttachLinks(ctx, 1, "alice", []string{"public", "theirs"})
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.Zero(t, repo.called("AddCampaignLinks"))
+
+	_, err = s.AttachLinks(ctx, 1, "mallory", []string{"public"})
+	assert.ErrorIs(t, err, ErrForbidden, "not the campaign owner")
+
+	repo.members = []model.OrgMember{{OrgID: 9, UserID: "alice", Role: model.RoleViewer}}
+	_, err = s.AttachLinks(ctx, 1, "alice", []string{"public", "theirs"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"public", "theirs"}, repo.attached[1])
+}
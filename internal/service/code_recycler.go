@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/cache"
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ============================================================================
+// SHORT CODE RECYCLING
+// ============================================================================
+// Generated codes only ever grow longer. With recycling enabled, the codes of
+// links that expired long ago and never received any traffic are reclaimed
+// into a free pool (the recycled_codes table), and new links take the
+// shortest code from the pool before a fresh one is generated.
+//
+// Safeguards against handing a code that people may still have to someone
+// else:
+//   - a link is only recycled after it has been expired for the whole
+//     quarantine period (at least 30 days, see config validation)
+//   - a code that ever received traffic is never recycled: any counted or
+//     bot visit, visit log, abuse flag or abuse report keeps it
+//   - both are checked again in the transaction that removes the link, so
+//     a link extended or visited since the job listed it is kept
+//
+// Recycling removes the link for good, with its tags, campaign memberships
+// and edit history, so the code's next owner starts from a clean slate.
+// Custom aliases are recycled like generated codes; a pooled code that was
+// reserved or taken as a custom alias in the meantime is skipped when
+// claimed (see generateShortCode).
+// ============================================================================
+
+// CodeRecyclerStore is the storage used by CodeRecycler
+type CodeRecyclerStore interface {
+	RecyclableLinks(ctx context.Context, expiredBefore time.Time, limit int) ([]model.URLMapping, error)
+	RecycleLink(ctx context.Context, mapping *model.URLMapping, expiredBefore time.Time) (bool, error)
+}
+
+// CodeRecycler periodically moves the codes of long-expired, never-visited
+// links into the free pool
+type CodeRecycler struct {
+	repo       CodeRecyclerStore
+	cache      *cache.RedisCache
+	onDelete   DeleteHook
+	quarantine time.Duration
+	interval   time.Duration
+	batchSize  int
+}
+
+// NewCodeRecycler creates a recycling job; onDelete (e.g.
+// URLService.LinkRemoved) is told about every recycled link and may be nil
+func NewCodeRecycler(repo CodeRecyclerStore, cache *cache.RedisCache, onDelete DeleteHook, quarantine, interval time.Duration, batchSize int) *CodeRecycler {
+	return &CodeRecycler{
+		repo:       repo,
+		cache:      cache,
+		onDelete:   onDelete,
+		quarantine: quarantine,
+		interval:   interval,
+		batchSize:  batchSize,
+	}
+}
+
+// Run executes the job immediately and then every interval until ctx is done
+func (j *CodeRecycler) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.RunOnce(ctx, time.Now()); err != nil {
+			fmt.Printf("Short code recycling failed: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce recycles up to one batch of links expired before now minus the
+// quarantine period
+func (j *CodeRecycler) RunOnce(ctx context.Context, now time.Time) error {
+	cutoff := now.Add(-j.quarantine)
+	links, err := j.repo.RecyclableLinks(ctx, cutoff, j.batchSize)
+	if err != nil {
+		return err
+	}
+
+	recycled := 0
+	for i := range links {
+		ok, err := j.repo.RecycleLink(ctx, &links[i], cutoff)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		recycled++
+		// The expired mapping may still be cached
+		if err := j.cache.Delete(ctx, links[i].ShortCode); err != nil {
+			fmt.Printf("Failed to evict recycled link %s from cache: %v\n", links[i].ShortCode, err)
+		}
+		if j.onDelete != nil {
+			j.onDelete(ctx, DeleteEvent{Mapping: &links[i], Reason: DeleteReasonRecycled})
+		}
+	}
+	if recycled > 0 {
+		fmt.Printf("Recycled the codes of %d links expired before %s\n", recycled, cutoff.Format(time.RFC3339))
+	}
+	return nil
+}
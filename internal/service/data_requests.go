@@ -0,0 +1,400 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ============================================================================
+// DATA SUBJECT REQUESTS
+// ============================================================================
+// Export or delete everything stored about one person, for GDPR access and
+// erasure requests:
+// - by visitor IP: their visit logs and the abuse reports they sent
+// - by user ID: the links they created (first revision by user:<id>); a
+//   delete removes them for good, with their visit logs and history, the
+//   same way as other permanent removals (see LinkRemoved)
+//
+// Requests run in the background like imports; the job is polled by ID
+// and, when configured, a webhook is told when it finishes. Exports are
+// streamed to a temporary NDJSON file rather than held in memory, and
+// downloaded from the instance that ran them. Daily rollups hold no IPs and
+// are left alone.
+// ============================================================================
+
+// Errors returned by data subject requests
+var (
+	ErrInvalidDataRequest  = errors.New("invalid data request")
+	ErrDataRequestNotFound = errors.New("data request not found")
+	ErrExportNotReady      = errors.New("data request has no finished export")
+)
+
+// Data request states
+const (
+	DataRequestRunning = "running"
+	DataRequestDone    = "done"
+	DataRequestFailed  = "failed"
+)
+
+// DataRequestStore finds and erases the data of a data subject
+type DataRequestStore interface {
+	StreamVisitLogsByIP(ctx context.Context, ips []string, fn func([]model.VisitLog) error) error
+	DeleteVisitLogsByIP(ctx context.Context, ips []string) (int64, error)
+	AbuseReportsByIP(ctx context.Context, ips []string) ([]model.AbuseReport, error)
+	DeleteAbuseReportsByIP(ctx context.Context, ips []string) (int64, error)
+	LinksCreatedBy(ctx context.Context, actor string) ([]model.URLMapping, error)
+	PurgeLinks(ctx context.Context, mappings []model.URLMapping) (visitLogs, links int64, err error)
+}
+
+// DataRequest is a snapshot of a data subject request
+type DataRequest struct {
+	ID         string              `json:"id"`
+	Kind       string              `json:"kind"` // export or delete
+	Status     string              `json:"status"`
+	Subject    model.DataSubject   `json:"subject"`
+	Error      string              `json:"error,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	FinishedAt *time.Time          `json:"finished_at,omitempty"`
+	Export     *model.DataExport   `json:"export,omitempty"`  // Set once an export finished; see OpenExport
+	Deleted    *model.DataDeletion `json:"deleted,omitempty"` // Set once a delete finished
+
+	exportPath string // File holding a finished export
+}
+
+// DataRequestNotifier is told when a data subject request finishes
+type DataRequestNotifier interface {
+	Notify(ctx context.Context, event *model.DataRequestEvent) error
+}
+
+// DataRequests runs data subject requests and tracks them in memory
+// Requests are local to this instance and forgotten, with their export
+// files, ttl after they finish
+type DataRequests struct {
+	service  *URLService
+	notifier DataRequestNotifier // nil notifies nobody
+	ttl      time.Duration
+
+	mu       sync.Mutex
+	requests map[string]*DataRequest
+}
+
+// NewDataRequests creates an empty request registry
+func NewDataRequests(service *URLService, ttl time.Duration, notifier DataRequestNotifier) *DataRequests {
+	return &DataRequests{
+		service:  service,
+		notifier: notifier,
+		ttl:      ttl,
+		requests: make(map[string]*DataRequest),
+	}
+}
+
+// Start validates a request and runs it in a new goroutine
+func (d *DataRequests) Start(kind string, subject model.DataSubject) (DataRequest, error) {
+	if err := validateDataRequest(kind, subject); err != nil {
+		return DataRequest{}, err
+	}
+	request := &DataRequest{
+		ID:        newJobID(),
+		Kind:      kind,
+		Status:    DataRequestRunning,
+		Subject:   subject,
+		CreatedAt: time.Now(),
+	}
+
+	d.mu.Lock()
+	d.expireLocked(request.CreatedAt)
+	d.requests[request.ID] = request
+	snapshot := *request
+	d.mu.Unlock()
+
+	go d.run(request.ID, kind, subject)
+	return snapshot, nil
+}
+
+// Get returns a snapshot of a request
+func (d *DataRequests) Get(id string) (DataRequest, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.expireLocked(time.Now())
+
+	request, ok := d.requests[id]
+	if !ok {
+		return DataRequest{}, false
+	}
+	return *request, true
+}
+
+// OpenExport opens the file of a finished export for reading
+// The file stays readable through the returned handle even if the request
+// expires meanwhile
+func (d *DataRequests) OpenExport(id string) (*os.File, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.expireLocked(time.Now())
+
+	request, ok := d.requests[id]
+	if !ok {
+		return nil, ErrDataRequestNotFound
+	}
+	if request.exportPath == "" {
+		return nil, ErrExportNotReady
+	}
+	file, err := os.Open(request.exportPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open data export: %w", err)
+	}
+	return file, nil
+}
+
+// run carries out a request, records the outcome and notifies
+func (d *DataRequests) run(id, kind string, subject model.DataSubject) {
+	ctx := context.Background()
+	var export *model.DataExport
+	var exportPath string
+	var deleted *model.DataDeletion
+	var err error
+	if kind == model.DataRequestExport {
+		export, exportPath, err = d.export(ctx, subject)
+	} else {
+		deleted, err = d.service.DeleteSubjectData(ctx, subject)
+	}
+
+	d.mu.Lock()
+	request := d.requests[id]
+	now := time.Now()
+	request.FinishedAt = &now
+	request.Export = export
+	request.exportPath = exportPath
+	request.Deleted = deleted
+	request.Status = DataRequestDone
+	if err != nil {
+		request.Status = DataRequestFailed
+		request.Error = err.Error()
+		fmt.Printf("Data request %s failed: %v\n", id, err)
+	}
+	event := &model.DataRequestEvent{
+		ID:         id,
+		Kind:       kind,
+		Status:     request.Status,
+		Subject:    subject,
+		Error:      request.Error,
+		Export:     export,
+		Deleted:    deleted,
+		FinishedAt: now,
+	}
+	d.mu.Unlock()
+
+	if d.notifier != nil {
+		if err := d.notifier.Notify(ctx, event); err != nil {
+			fmt.Printf("Failed to notify data request %s: %v\n", id, err)
+		}
+	}
+}
+
+// export writes subject's data to a new temporary file and returns its path
+// The file is removed if the export fails
+func (d *DataRequests) export(ctx context.Context, subject model.DataSubject) (*model.DataExport, string, error) {
+	file, err := os.CreateTemp("", "short-link-data-export-*.ndjson")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create data export file: %w", err)
+	}
+	export, err := d.service.ExportSubjectData(ctx, subject, file)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write data export file: %w", closeErr)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return nil, "", err
+	}
+	return export, file.Name(), nil
+}
+
+// expireLocked forgets requests that finished more than ttl ago and removes
+// their export files
+func (d *DataRequests) expireLocked(now time.Time) {
+	for id, request := range d.requests {
+		if request.FinishedAt != nil && now.Sub(*request.FinishedAt) > d.ttl {
+			if request.exportPath != "" {
+				if err := os.Remove(request.exportPath); err != nil && !os.IsNotExist(err) {
+					fmt.Printf("Failed to remove data export %s: %v\n", request.exportPath, err)
+				}
+			}
+			delete(d.requests, id)
+		}
+	}
+}
+
+// validateDataRequest checks a request's kind and subject
+func validateDataRequest(kind string, subject model.DataSubject) error {
+	if kind != model.DataRequestExport && kind != model.DataRequestDelete {
+		return fmt.Errorf("%w: kind must be export or delete", ErrInvalidDataRequest)
+	}
+	if subject.IP == "" && subject.UserID == "" {
+		return fmt.Errorf("%w: ip or user_id is required", ErrInvalidDataRequest)
+	}
+	if subject.IP != "" {
+		if _, err := netip.ParseAddr(subject.IP); err != nil {
+			return fmt.Errorf("%w: ip %q is not an IP address", ErrInvalidDataRequest, subject.IP)
+		}
+	}
+	if len(subject.UserID) > 128 {
+		return fmt.Errorf("%w: user_id is longer than 128 characters", ErrInvalidDataRequest)
+	}
+	return nil
+}
+
+// ExportSubjectData writes everything stored about subject to w as NDJSON,
+// one model.DataExportRecord per line, and counts what it wrote
+// Visit logs are streamed in batches, so the export is never held in memory
+func (s *URLService) ExportSubjectData(ctx context.Context, subject model.DataSubject, w io.Writer) (*model.DataExport, error) {
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+	write := func(recordType string, data interface{}) error {
+		if err := enc.Encode(model.DataExportRecord{Type: recordType, Data: data}); err != nil {
+			return fmt.Errorf("failed to write data export: %w", err)
+		}
+		return nil
+	}
+
+	export := &model.DataExport{}
+	if subject.IP != "" {
+		ips := s.subjectIPs(subject.IP)
+		err := s.repo.StreamVisitLogsByIP(ctx, ips, func(batch []model.VisitLog) error {
+			for i := range batch {
+				if err := write(model.DataRecordVisitLog, &batch[i]); err != nil {
+					return err
+				}
+			}
+			export.VisitLogs += int64(len(batch))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		reports, err := s.repo.AbuseReportsByIP(ctx, ips)
+		if err != nil {
+			return nil, err
+		}
+		for i := range reports {
+			if err := write(model.DataRecordAbuseReport, &reports[i]); err != nil {
+				return nil, err
+			}
+		}
+		export.AbuseReports = int64(len(reports))
+	}
+	if subject.UserID != "" {
+		links, err := s.repo.LinksCreatedBy(ctx, "user:"+subject.UserID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range links {
+			if err := write(model.DataRecordLink, &links[i]); err != nil {
+				return nil, err
+			}
+		}
+		export.Links = int64(len(links))
+	}
+	if err := buf.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write data export: %w", err)
+	}
+	return export, nil
+}
+
+// DeleteSubjectData removes everything stored about subject
+// Deleted links are removed for good (no restore) and then go through
+// LinkRemoved like any other removed link
+func (s *URLService) DeleteSubjectData(ctx context.Context, subject model.DataSubject) (*model.DataDeletion, error) {
+	deleted := &model.DataDeletion{}
+	if subject.IP != "" {
+		ips := s.subjectIPs(subject.IP)
+		n, err := s.repo.DeleteVisitLogsByIP(ctx, ips)
+		deleted.VisitLogs += n
+		if err != nil {
+			return deleted, err
+		}
+		if deleted.AbuseReports, err = s.repo.DeleteAbuseReportsByIP(ctx, ips); err != nil {
+			return deleted, err
+		}
+	}
+	if subject.UserID != "" {
+		links, err := s.repo.LinksCreatedBy(ctx, "user:"+subject.UserID)
+		if err != nil {
+			return deleted, err
+		}
+		visitLogs, purged, err := s.repo.PurgeLinks(ctx, links)
+		deleted.VisitLogs += visitLogs
+		deleted.Links = purged
+		if err != nil {
+			return deleted, err
+		}
+		for i := range links {
+			s.LinkRemoved(ctx, DeleteEvent{Mapping: &links[i], Reason: DeleteReasonDataRequest})
+		}
+	}
+	return deleted, nil
+}
+
+// subjectIPs returns the forms ip may be stored in: as sent, normalized,
+// and hashed when privacy.ip_storage is hash
+// Truncated IPs are shared by a whole network and aren't matched
+func (s *URLService) subjectIPs(ip string) []string {
+	ips := []string{ip}
+	if addr, err := netip.ParseAddr(ip); err == nil {
+		if normalized := addr.Unmap().WithZone("").String(); normalized != ip {
+			ips = append(ips, normalized)
+		}
+	}
+	if s.privacy.IPStorage == IPStorageHash {
+		for _, candidate := range ips {
+			ips = append(ips, s.privacy.storedIP(candidate))
+		}
+	}
+	return ips
+}
+
+// DataRequestWebhook POSTs finished data requests as JSON to a URL
+type DataRequestWebhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewDataRequestWebhook creates a notifier posting to url, giving up on a
+// request after timeout
+func NewDataRequestWebhook(url string, timeout time.Duration) *DataRequestWebhook {
+	return &DataRequestWebhook{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Notify implements DataRequestNotifier
+func (w *DataRequestWebhook) Notify(ctx context.Context, event *model.DataRequestEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode data request event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call data request webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("data request webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingDataNotifier keeps the events it is sent
+type recordingDataNotifier struct {
+	mu     sync.Mutex
+	events []model.DataRequestEvent
+}
+
+func (n *recordingDataNotifier) Notify(ctx context.Context, event *model.DataRequestEvent) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, *event)
+	return nil
+}
+
+func (n *recordingDataNotifier) sent() []model.DataRequestEvent {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]model.DataRequestEvent(nil), n.events...)
+}
+
+// TestValidateDataRequest tests the accepted kinds and subjects
+func TestValidateDataRequest(t *testing.T) {
+	assert.NoError(t, validateDataRequest(model.DataRequestExport, model.DataSubject{IP: "2001:db8::1"}))
+	assert.NoError(t, validateDataRequest(model.DataRequestDelete, model.DataSubject{UserID: "alice"}))
+	assert.ErrorIs(t, validateDataRequest("erase", model.DataSubject{UserID: "alice"}), ErrInvalidDataRequest)
+	assert.ErrorIs(t, validateDataRequest(model.DataRequestExport, model.DataSubject{}), ErrInvalidDataRequest)
+	assert.ErrorIs(t, validateDataRequest(model.DataRequestExport, model.DataSubject{IP: "203.0.113"}), ErrInvalidDataRequest)
+}
+
+// TestSubjectIPs tests that hashed visit logs are matched too
+func TestSubjectIPs(t *testing.T) {
+	s := NewURLService(newFakeRepository(), newFakeCache(), newFakeFilter())
+	assert.Equal(t, []string{"203.0.113.7"}, s.subjectIPs("203.0.113.7"))
+	assert.Equal(t, []string{"::ffff:203.0.113.7", "203.0.113.7"}, s.subjectIPs("::ffff:203.0.113.7"))
+
+	s.SetPrivacy(Privacy{IPStorage: IPStorageHash, IPHashSalt: "pepper"})
+	hashed := Privacy{IPStorage: IPStorageHash, IPHashSalt: "pepper"}.storedIP("203.0.113.7")
+	assert.Equal(t, []string{"203.0.113.7", hashed}, s.subjectIPs("203.0.113.7"))
+}
+
+// TestDataRequests tests exporting and deleting a visitor's visit logs and
+// a user's links
+func TestDataRequests(t *testing.T) {
+	repo := newFakeRepository(
+		&model.URLMapping{ShortCode: "alice1", OriginalURL: "https://example.com/a"},
+		&model.URLMapping{ShortCode: "bob1", OriginalURL: "https://example.com/b"},
+	)
+	repo.revisions = []model.URLRevision{
+		{ShortCode: "alice1", Revision: 1, ChangedBy: "user:alice"},
+		{ShortCode: "bob1", Revision: 1, ChangedBy: "user:bob"},
+		{ShortCode: "bob1", Revision: 2, ChangedBy: "user:alice"},
+	}
+	repo.visitLogs = []model.VisitLog{
+		{ShortCode: "bob1", IP: "203.0.113.7"},
+		{ShortCode: "bob1", IP: "198.51.100.1"},
+		{ShortCode: "alice1", IP: "198.51.100.1"},
+	}
+	repo.aliases = []model.LinkAlias{
+		{Namespace: "team", Alias: "alices", ShortCode: "alice1"},
+		{Namespace: "team", Alias: "bobs", ShortCode: "bob1"},
+	}
+	store := newFakeCache()
+	s := NewURLService(repo, store, newFakeFilter())
+	require.NoError(t, store.Set(context.Background(), &model.URLMapping{ShortCode: "alice1"}))
+	var hooksMu sync.Mutex
+	var deleted []DeleteEvent
+	s.OnDelete(func(ctx context.Context, e DeleteEvent) {
+		hooksMu.Lock()
+		defer hooksMu.Unlock()
+		deleted = append(deleted, e)
+	})
+	notifier := &recordingDataNotifier{}
+	requests := NewDataRequests(s, time.Hour, notifier)
+
+	wait := func(id string) DataRequest {
+		var request DataRequest
+		require.Eventually(t, func() bool {
+			request, _ = requests.Get(id)
+			return request.Status != DataRequestRunning
+		}, time.Second, 5*time.Millisecond)
+		return request
+	}
+
+	_, err := requests.Start(model.DataRequestExport, model.DataSubject{})
+	assert.ErrorIs(t, err, ErrInvalidDataRequest)
+
+	started, err := requests.Start(model.DataRequestExport, model.DataSubject{IP: "203.0.113.7", UserID: "alice"})
+	require.NoError(t, err)
+	export := wait(started.ID)
+	assert.Equal(t, DataRequestDone, export.Status)
+	assert.Equal(t, &model.DataExport{VisitLogs: 1, Links: 1}, export.Export, "only links the user created")
+	assert.Len(t, repo.visitLogs, 3, "exports change nothing")
+
+	file, err := requests.OpenExport(started.ID)
+	require.NoError(t, err)
+	var records []model.DataExportRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record model.DataExportRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.NoError(t, file.Close())
+	require.Len(t, records, 2)
+	assert.Equal(t, model.DataRecordVisitLog, records[0].Type)
+	assert.Equal(t, model.DataRecordLink, records[1].Type)
+	assert.Equal(t, "alice1", records[1].Data.(map[string]interface{})["short_code"])
+
+	_, err = requests.OpenExport("unknown")
+	assert.ErrorIs(t, err, ErrDataRequestNotFound)
+
+	started, err = requests.Start(model.DataRequestDelete, model.DataSubject{IP: "203.0.113.7", UserID: "alice"})
+	require.NoError(t, err)
+	deletion := wait(started.ID)
+	assert.Equal(t, DataRequestDone, deletion.Status)
+	assert.Equal(t, &model.DataDeletion{VisitLogs: 2, Links: 1}, deletion.Deleted)
+	assert.Equal(t, []model.LinkAlias{{Namespace: "team", Alias: "bobs", ShortCode: "bob1"}}, repo.aliases,
+		"aliases of removed links are dropped")
+	_, err = requests.OpenExport(started.ID)
+	assert.ErrorIs(t, err, ErrExportNotReady)
+	assert.Equal(t, []model.VisitLog{{ShortCode: "bob1", IP: "198.51.100.1"}}, repo.visitLogs)
+	assert.NotContains(t, repo.links, "alice1")
+	assert.Contains(t, repo.links, "bob1")
+	cached, _ := store.Get(context.Background(), "alice1")
+	assert.Nil(t, cached, "deleted links are evicted")
+	hooksMu.Lock()
+	require.Len(t, deleted, 1, "delete hooks hear about removed links")
+	assert.Equal(t, "alice1", deleted[0].Mapping.ShortCode)
+	assert.Equal(t, DeleteReasonDataRequest, deleted[0].Reason)
+	hooksMu.Unlock()
+
+	require.Eventually(t, func() bool { return len(notifier.sent()) == 2 }, time.Second, 5*time.Millisecond)
+	event := notifier.sent()[1]
+	assert.Equal(t, started.ID, event.ID)
+	assert.Equal(t, model.DataRequestDelete, event.Kind)
+	assert.Equal(t, DataRequestDone, event.Status)
+	assert.Equal(t, deletion.Deleted, event.Deleted)
+}
+
+// TestDataExportFileExpires tests that export files are removed with their
+// request
+func TestDataExportFileExpires(t *testing.T) {
+	s := NewURLService(newFakeRepository(), newFakeCache(), newFakeFilter())
+	requests := NewDataRequests(s, time.Hour, nil)
+
+	started, err := requests.Start(model.DataRequestExport, model.DataSubject{UserID: "alice"})
+	require.NoError(t, err)
+	var path string
+	require.Eventually(t, func() bool {
+		requests.mu.Lock()
+		defer requests.mu.Unlock()
+		path = requests.requests[started.ID].exportPath
+		return path != ""
+	}, time.Second, 5*time.Millisecond)
+	_, err = os.Stat(path)
+	require.NoError(t, err)
+
+	requests.mu.Lock()
+	requests.expireLocked(time.Now().Add(2 * time.Hour))
+	requests.mu.Unlock()
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "the file goes with the request")
+	_, err = requests.OpenExport(started.ID)
+	assert.ErrorIs(t, err, ErrDataRequestNotFound)
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ErrInvalidDeepLinks is returned for deep links that can't be stored
+var ErrInvalidDeepLinks = errors.New("invalid deep links")
+
+// deepLinkSchemePattern matches URL schemes (RFC 3986)
+var deepLinkSchemePattern = regexp.MustCompile(`^[a-z][a-z0-9+.-]*$`)
+
+// blockedDeepLinkSchemes run code or read local data instead of opening an app
+var blockedDeepLinkSchemes = map[string]bool{
+	"javascript": true,
+	"vbscript":   true,
+	"data":       true,
+	"file":       true,
+	"blob":       true,
+	"about":      true,
+}
+
+// normalizeDeepLinks validates deep links and returns them in stored form
+// Returns nil for deep links that set nothing
+func normalizeDeepLinks(links *model.DeepLinks) (*model.DeepLinks, error) {
+	if links.IsEmpty() {
+		return nil, nil
+	}
+	normalized := &model.DeepLinks{}
+	var err error
+	if normalized.IOS, err = normalizeDeepLink("ios", links.IOS); err != nil {
+		return nil, err
+	}
+	if normalized.Android, err = normalizeDeepLink("android", links.Android); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// normalizeDeepLink checks one deep link: an absolute URL with an app
+// scheme (or https, for universal and app links); intent:// is only
+// accepted for Android
+func normalizeDeepLink(platform, rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", nil
+	}
+	if len(rawURL) > MaxURLLength {
+		return "", fmt.Errorf("%w: %s link is longer than %d characters", ErrInvalidDeepLinks, platform, MaxURLLength)
+	}
+	for _, r := range rawURL {
+		if unicode.IsControl(r) || r == ' ' {
+			return "", fmt.Errorf("%w: %s link contains spaces or control characters", ErrInvalidDeepLinks, platform)
+		}
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" {
+		return "", fmt.Errorf("%w: %s link must be an absolute URL like myapp://path", ErrInvalidDeepLinks, platform)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if !deepLinkSchemePattern.MatchString(scheme) || blockedDeepLinkSchemes[scheme] {
+		return "", fmt.Errorf("%w: %s link may not use the %s scheme", ErrInvalidDeepLinks, platform, scheme)
+	}
+	if scheme == "http" {
+		return "", fmt.Errorf("%w: %s link must use https or an app scheme", ErrInvalidDeepLinks, platform)
+	}
+	if scheme == "intent" && platform != "android" {
+		return "", fmt.Errorf("%w: intent:// links only work on Android", ErrInvalidDeepLinks)
+	}
+	return rawURL, nil
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNormalizeDeepLinks tests which deep links are accepted
+func TestNormalizeDeepLinks(t *testing.T) {
+	links, err := normalizeDeepLinks(&model.DeepLinks{
+		IOS:     " myapp://product/42 ",
+		Android: "intent://product/42#Intent;scheme=myapp;package=com.example.app;end",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "myapp://product/42", links.IOS)
+
+	links, err = normalizeDeepLinks(&model.DeepLinks{})
+	assert.NoError(t, err)
+	assert.Nil(t, links)
+
+	for _, invalid := range []model.DeepLinks{
+		{IOS: "javascript:alert(1)"},
+		{Android: "data:text/html,hi"},
+		{IOS: "product/42"},
+		{IOS: "http://example.com/app"},
+		{IOS: "intent://product/42#Intent;end"},
+	} {
+		_, err := normalizeDeepLinks(&invalid)
+		assert.ErrorIs(t, err, ErrInvalidDeepLinks, invalid)
+	}
+}
+
+// TestDeepLinksForOS tests picking the deep link for a visitor
+func TestDeepLinksForOS(t *testing.T) {
+	links := &model.DeepLinks{IOS: "myapp://a"}
+	assert.Equal(t, "myapp://a", links.ForOS("iOS"))
+	assert.Empty(t, links.ForOS("Android"))
+	assert.Empty(t, links.ForOS("MacOSX"))
+	var none *model.DeepLinks
+	assert.Empty(t, none.ForOS("iOS"))
+}
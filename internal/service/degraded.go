@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/cache"
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ============================================================================
+// DEGRADED MODE
+// ============================================================================
+// When MySQL is unreachable the service keeps doing what it can without it:
+// - redirects are served from the local and Redis caches; cache misses fail
+//   fast with ErrDatabaseUnavailable instead of waiting on MySQL
+// - visits are queued in Redis (cache.VisitQueueKey) and replayed into
+//   MySQL by VisitReplay once it's back
+// - writes (creating, editing, deleting links) are rejected with 503 by the
+//   middleware.ReadOnly in front of the API
+//
+// DatabaseMonitor decides when MySQL is down by pinging it periodically.
+// ============================================================================
+
+// ErrDatabaseUnavailable is returned when an operation needs MySQL while
+// it is down
+var ErrDatabaseUnavailable = errors.New("database unavailable")
+
+// visitReplayBatchSize is how many queued visits are replayed per batch
+const visitReplayBatchSize = 500
+
+// VisitStore records visits, directly or replayed from the Redis queue
+type VisitStore interface {
+	CreateVisitLog(ctx context.Context, log *model.VisitLog) error
+	IncrementVisitCount(ctx context.Context, shortCode string) error
+	IncrementBotVisitCount(ctx context.Context, shortCode string) error
+	IncrementDuplicateVisitCount(ctx context.Context, shortCode string) error
+}
+
+// DatabaseMonitor tracks whether MySQL is reachable
+type DatabaseMonitor struct {
+	ping     func(ctx context.Context) error
+	interval time.Duration
+	timeout  time.Duration
+	down     atomic.Bool
+}
+
+// NewDatabaseMonitor creates a monitor that pings every interval; a ping
+// that fails or takes longer than timeout marks the database down
+func NewDatabaseMonitor(ping func(ctx context.Context) error, interval, timeout time.Duration) *DatabaseMonitor {
+	return &DatabaseMonitor{ping: ping, interval: interval, timeout: timeout}
+}
+
+// Available reports whether MySQL answered the last ping
+// A nil monitor always reports available
+func (m *DatabaseMonitor) Available() bool {
+	return m == nil || !m.down.Load()
+}
+
+// Run checks immediately and then every interval until ctx is done
+func (m *DatabaseMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check pings MySQL once and records the result
+func (m *DatabaseMonitor) Check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	err := m.ping(ctx)
+	wasDown := m.down.Swap(err != nil)
+	switch {
+	case err != nil && !wasDown:
+		fmt.Printf("MySQL is unavailable, entering degraded mode: %v\n", err)
+	case err == nil && wasDown:
+		fmt.Printf("MySQL is available again, leaving degraded mode\n")
+	}
+}
+
+// SetDatabaseMonitor enables degraded mode, driven by monitor
+func (s *URLService) SetDatabaseMonitor(monitor *DatabaseMonitor) {
+	s.db = monitor
+}
+
+// DatabaseAvailable reports whether MySQL is believed to be reachable
+func (s *URLService) DatabaseAvailable() bool {
+	return s.db.Available()
+}
+
+// writeVisit stores a visit in MySQL, marking it counted once its counter
+// has been incremented so a retry doesn't count it twice
+func (s *URLService) writeVisit(ctx context.Context, visit *cache.PendingVisit) error {
+	if !visit.Counted {
+		increment := s.repo.IncrementVisitCount
+		switch {
+		case visit.Log.IsBot:
+			increment = s.repo.IncrementBotVisitCount
+		case visit.Duplicate:
+			increment = s.repo.IncrementDuplicateVisitCount
+		}
+		if err := increment(ctx, visit.Log.ShortCode); err != nil {
+			return err
+		}
+		visit.Counted = true
+	}
+	if visit.Untracked {
+		return nil
+	}
+	return s.repo.CreateVisitLog(ctx, &visit.Log)
+}
+
+// ReplayQueuedVisits writes visits queued during an outage to MySQL and
+// returns how many were written
+// Stops at the first failure, leaving the rest queued
+func (s *URLService) ReplayQueuedVisits(ctx context.Context) (int, error) {
+	replayed := 0
+	for s.db.Available() {
+		visits, err := s.cache.DequeueVisits(ctx, visitReplayBatchSize)
+		if err != nil || len(visits) == 0 {
+			return replayed, err
+		}
+
+		for i := range visits {
+			if err := s.writeVisit(ctx, &visits[i]); err != nil {
+				if requeueErr := s.cache.RequeueVisits(context.WithoutCancel(ctx), visits[i:]); requeueErr != nil {
+					fmt.Printf("Lost %d queued visits: %v\n", len(visits)-i, requeueErr)
+				}
+				return replayed, err
+			}
+			replayed++
+		}
+	}
+	return replayed, nil
+}
+
+// VisitReplay periodically replays visits queued while MySQL was down
+type VisitReplay struct {
+	service  *URLService
+	interval time.Duration
+}
+
+// NewVisitReplay creates a replay job
+func NewVisitReplay(service *URLService, interval time.Duration) *VisitReplay {
+	return &VisitReplay{service: service, interval: interval}
+}
+
+// Run replays immediately and then every interval until ctx is done
+func (j *VisitReplay) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		replayed, err := j.service.ReplayQueuedVisits(ctx)
+		if replayed > 0 {
+			fmt.Printf("Replayed %d visits queued during a MySQL outage\n", replayed)
+		}
+		if err != nil {
+			fmt.Printf("Visit replay failed: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
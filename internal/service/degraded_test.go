@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDatabaseMonitor tests that availability follows the last ping
+func TestDatabaseMonitor(t *testing.T) {
+	var nilMonitor *DatabaseMonitor
+	assert.True(t, nilMonitor.Available())
+
+	var pingErr error
+	m := NewDatabaseMonitor(func(ctx context.Context) error { return pingErr }, time.Second, time.Second)
+	assert.True(t, m.Available())
+
+	pingErr = errors.New("connection refused")
+	m.Check(context.Background())
+	assert.False(t, m.Available())
+
+	pingErr = nil
+	m.Check(context.Background())
+	assert.True(t, m.Available())
+
+	// A ping slower than the timeout counts as down
+	slow := NewDatabaseMonitor(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, time.Second, 10*time.Millisecond)
+	slow.Check(context.Background())
+	assert.False(t, slow.Available())
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/cache"
+	"github.com/Monthlyaway/short-link/internal/filter"
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/repository"
+)
+
+// ============================================================================
+// DEPENDENCIES
+// ============================================================================
+// URLService talks to MySQL, Redis and the bloom filter through the
+// interfaces below rather than the concrete types, so its logic can be unit
+// tested with in-memory fakes (see fakes_test.go). Each feature declares the
+// storage it needs next to its code (AbuseStore in abuse.go, OrgStore in
+// orgs.go, ...), listing only the methods it calls; Repository combines
+// them. The concrete implementations are checked against them at compile
+// time.
+//
+// Background jobs (purging, rollups, recycling, health checks) take their
+// own store interfaces (VisitRollupStore, ...) and are run once per MySQL
+// database holding links.
+// ============================================================================
+
+// Repository is the storage used by URLService: the stores of all its
+// features
+type Repository interface {
+	LinkStore
+	OutboxStore
+	VisitStore
+	URLListStore
+	CacheWarmupStore
+	LeaderboardStore
+	TitleStore
+	ReservedCodeStore
+	ImportStore
+	TagStore
+	RevisionStore
+	CampaignStore
+	OrgStore
+	AliasStore
+	AbuseStore
+	SummaryStore
+	DataRequestStore
+}
+
+// Cache is the link cache and the Redis queues used by URLService
+type Cache interface {
+	Get(ctx context.Context, shortCode string) (*model.URLMapping, error)
+	Set(ctx context.Context, mapping *model.URLMapping) error
+	SetWithTTL(ctx context.Context, mapping *model.URLMapping, ttl time.Duration) error
+	Delete(ctx context.Context, shortCode string) error
+	Stats() cache.LookupStats
+	RedisStats() cache.RedisStats
+
+	// Visit deduplication (see cache/visit_dedup.go)
+	FirstVisit(ctx context.Context, shortCode, visitor string, window time.Duration) (bool, error)
+
+	// Visit queue (see cache/visit_queue.go)
+	QueueVisit(ctx context.Context, visit *cache.PendingVisit) error
+	DequeueVisits(ctx context.Context, n int) ([]cache.PendingVisit, error)
+	RequeueVisits(ctx context.Context, visits []cache.PendingVisit) error
+
+	// Creation queue (see cache/creation_queue.go)
+	QueueCreation(ctx context.Context, creation *cache.PendingCreation) error
+	ClaimCreation(ctx context.Context) (*cache.PendingCreation, string, error)
+	CompleteCreation(ctx context.Context, raw string) error
+	RetryCreation(ctx context.Context, raw string, creation *cache.PendingCreation) error
+	FailCreation(ctx context.Context, raw string) error
+	ProcessingCreations(ctx context.Context) ([]string, error)
+	RequeueStaleCreation(ctx context.Context, raw string) (bool, error)
+	QueuedCreationCodes(ctx context.Context) ([]string, error)
+	PendingCreations(ctx context.Context) (int64, error)
+}
+
+// Filter is the short code membership filter used by URLService
+type Filter interface {
+	Add(shortCode string)
+	Test(shortCode string) bool
+	Rebuild(load func() ([]string, error)) (int, error)
+	Stats() filter.Stats
+}
+
+var (
+	_ Repository = (*repository.URLRepository)(nil)
+	_ Repository = (*repository.ShardedRepository)(nil)
+
+	_ VisitRollupStore       = (*repository.URLRepository)(nil)
+	_ VisitLogRetentionStore = (*repository.URLRepository)(nil)
+	_ LinkPurgeStore         = (*repository.URLRepository)(nil)
+	_ LinkHealthStore        = (*repository.URLRepository)(nil)
+	_ CodeRecyclerStore      = (*repository.URLRepository)(nil)
+
+	_ Cache  = (*cache.RedisCache)(nil)
+	_ Filter = (*filter.BloomFilter)(nil)
+)
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ============================================================================
+// DESTINATION POLICY
+// ============================================================================
+// Links must not point into the network the service runs in. A link to
+// http://169.254.169.254/ is harmless in a visitor's browser, but anything
+// on our side that fetches destinations (previews, link checks) would read
+// cloud credentials or internal pages on the link creator's behalf.
+//
+// With a policy set, the destination host is resolved and every address it
+// resolves to must be public. Loopback, private, link-local (which covers
+// 169.254.169.254), shared (CGNAT), multicast and unspecified addresses are
+// rejected, as are well-known metadata host names. Hosts and networks can be
+// allowed explicitly, e.g. an intranet wiki.
+//
+// A check at creation time does not stop DNS rebinding (the host may resolve
+// differently later), so code that fetches destinations should also dial
+// through Control.
+// ============================================================================
+
+// blockedHostNames never resolve to a public address
+var blockedHostNames = []string{"localhost", "metadata", "metadata.google.internal"}
+
+// blockedPrefixes are non-public ranges not covered by the netip predicates
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // Shared address space (CGNAT, Alibaba metadata)
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),   // Benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),     // Reserved, includes broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, embeds an IPv4 address
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // Local-use NAT64
+	netip.MustParsePrefix("2001:db8::/32"),   // Documentation
+	netip.MustParsePrefix("100::/64"),        // Discard-only
+	netip.MustParsePrefix("fec0::/10"),       // Deprecated site-local
+	netip.MustParsePrefix("2002::/16"),       // 6to4, embeds an IPv4 address
+	netip.MustParsePrefix("2001::/32"),       // Teredo, embeds an IPv4 address
+	netip.MustParsePrefix("::ffff:0:0:0/96"), // IPv4-translated
+}
+
+// DestinationPolicy decides which hosts links may point to
+type DestinationPolicy struct {
+	allowedHosts map[string]bool
+	allowedNets  []netip.Prefix
+	timeout      time.Duration
+
+	// lookup resolves a host name (replaced in tests)
+	lookup func(ctx context.Context, host string) ([]netip.Addr, error)
+}
+
+// NewDestinationPolicy creates a policy that rejects non-public destinations
+// except allowedHosts (exact names) and allowedCIDRs; each DNS lookup is
+// limited to timeout
+func NewDestinationPolicy(allowedHosts, allowedCIDRs []string, timeout time.Duration) (*DestinationPolicy, error) {
+	p := &DestinationPolicy{
+		allowedHosts: make(map[string]bool, len(allowedHosts)),
+		timeout:      timeout,
+		lookup: func(ctx context.Context, host string) ([]netip.Addr, error) {
+			return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+		},
+	}
+	for _, host := range allowedHosts {
+		p.allowedHosts[normalizeHostName(host)] = true
+	}
+	for _, cidr := range allowedCIDRs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed network %q: %w", cidr, err)
+		}
+		p.allowedNets = append(p.allowedNets, prefix.Masked())
+	}
+	return p, nil
+}
+
+// SetDestinationPolicy sets which hosts new and edited links may point to
+// nil (the default) allows any host
+func (s *URLService) SetDestinationPolicy(policy *DestinationPolicy) {
+	s.destinations = policy
+}
+
+// Check returns an error if host (a name or IP literal, without port) is
+// not an allowed destination
+func (p *DestinationPolicy) Check(ctx context.Context, host string) error {
+	name := normalizeHostName(host)
+	if p.allowedHosts[name] {
+		return nil
+	}
+
+	if addr, err := netip.ParseAddr(name); err == nil {
+		return p.CheckIP(addr)
+	}
+	for _, blocked := range blockedHostNames {
+		if name == blocked || strings.HasSuffix(name, "."+blocked) {
+			return fmt.Errorf("host %s is not a public address", host)
+		}
+	}
+
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	addrs, err := p.lookup(ctx, name)
+	if err != nil {
+		return fmt.Errorf("host %s could not be resolved", host)
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("host %s has no addresses", host)
+	}
+	// Every address must pass: a client may connect to any of them
+	for _, addr := range addrs {
+		if err := p.CheckIP(addr); err != nil {
+			return fmt.Errorf("host %s resolves to %s, which is not a public address", host, addr.Unmap())
+		}
+	}
+	return nil
+}
+
+// CheckIP returns an error if ip is not an allowed destination address
+func (p *DestinationPolicy) CheckIP(ip netip.Addr) error {
+	ip = ip.Unmap().WithZone("")
+	for _, prefix := range p.allowedNets {
+		if prefix.Contains(ip) {
+			return nil
+		}
+	}
+	if !isPublicIP(ip) {
+		return fmt.Errorf("%s is not a public address", ip)
+	}
+	return nil
+}
+
+// Control can be used as net.Dialer.Control so outgoing connections to
+// destinations re-check the address actually dialed
+func (p *DestinationPolicy) Control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("failed to parse dial address %q: %w", address, err)
+	}
+	return p.CheckIP(addrPort.Addr())
+}
+
+// isPublicIP reports whether ip is a globally routable unicast address
+func isPublicIP(ip netip.Addr) bool {
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsMulticast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeHostName lowercases a host and strips IPv6 brackets and a
+// trailing dot
+func normalizeHostName(host string) string {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDestinationPolicy returns a policy resolving names from a fixed table
+func newTestDestinationPolicy(t *testing.T, allowedHosts, allowedCIDRs []string) *DestinationPolicy {
+	p, err := NewDestinationPolicy(allowedHosts, allowedCIDRs, time.Second)
+	require.NoError(t, err)
+	records := map[string][]string{
+		"example.com":       {"93.184.215.14", "2606:2800:21f:cb07:6820:80da:af6b:8b2c"},
+		"internal.corp":     {"10.1.2.3"},
+		"mixed.example.com": {"93.184.215.14", "127.0.0.1"},
+		"wiki.corp":         {"10.20.0.5"},
+	}
+	p.lookup = func(ctx context.Context, host string) ([]netip.Addr, error) {
+		ips, ok := records[host]
+		if !ok {
+			return nil, errors.New("no such host")
+		}
+		addrs := make([]netip.Addr, 0, len(ips))
+		for _, ip := range ips {
+			addrs = append(addrs, netip.MustParseAddr(ip))
+		}
+		return addrs, nil
+	}
+	return p
+}
+
+// TestDestinationPolicyCheck tests which hosts are accepted
+func TestDestinationPolicyCheck(t *testing.T) {
+	p := newTestDestinationPolicy(t, nil, nil)
+	ctx := context.Background()
+
+	for _, host := range []string{"example.com", "EXAMPLE.com.", "8.8.8.8", "[2606:4700::1111]"} {
+		assert.NoError(t, p.Check(ctx, host), host)
+	}
+	for _, host := range []string{
+		"127.0.0.1", "10.0.0.1", "172.16.5.5", "192.168.1.1", "0.0.0.0",
+		"169.254.169.254", "100.100.100.200", "[::1]", "[fe80::1]", "[fd00:ec2::254]",
+		"[::ffff:127.0.0.1]", "[64:ff9b::a00:1]", "224.0.0.1", "255.255.255.255",
+		"localhost", "api.localhost", "metadata.google.internal",
+		"internal.corp", "mixed.example.com", "unknown.invalid",
+	} {
+		assert.Error(t, p.Check(ctx, host), host)
+	}
+}
+
+// TestDestinationPolicyAllowList tests exempt hosts and networks
+func TestDestinationPolicyAllowList(t *testing.T) {
+	p := newTestDestinationPolicy(t, []string{"Internal.Corp"}, []string{"10.20.0.0/16"})
+	ctx := context.Background()
+
+	assert.NoError(t, p.Check(ctx, "internal.corp"))
+	assert.NoError(t, p.Check(ctx, "wiki.corp"))
+	assert.NoError(t, p.Check(ctx, "10.20.99.1"))
+	assert.Error(t, p.Check(ctx, "10.21.0.1"))
+	assert.Error(t, p.Check(ctx, "169.254.169.254"))
+
+	_, err := NewDestinationPolicy(nil, []string{"10.0.0.0"}, time.Second)
+	assert.Error(t, err)
+}
+
+// TestDestinationPolicyControl tests the dial-time check
+func TestDestinationPolicyControl(t *testing.T) {
+	p := newTestDestinationPolicy(t, nil, nil)
+
+	assert.NoError(t, p.Control("tcp4", "93.184.215.14:443", nil))
+	assert.Error(t, p.Control("tcp4", "169.254.169.254:80", nil))
+	assert.Error(t, p.Control("tcp6", "[::1]:8080", nil))
+}
+
+// TestValidateURLDestinationPolicy tests that blocked hosts are invalid URLs
+func TestValidateURLDestinationPolicy(t *testing.T) {
+	s := &URLService{}
+	ctx := context.Background()
+	assert.NoError(t, s.validateURL(ctx, "http://169.254.169.254/latest/meta-data/"))
+
+	s.SetDestinationPolicy(newTestDestinationPolicy(t, nil, nil))
+	assert.NoError(t, s.validateURL(ctx, "https://example.com/page"))
+	err := s.validateURL(ctx, "http://169.254.169.254/latest/meta-data/")
+	assert.True(t, errors.Is(err, ErrInvalidURL), "%v", err)
+	err = s.validateURL(ctx, "http://localhost:6379/")
+	assert.True(t, errors.Is(err, ErrInvalidURL), "%v", err)
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ============================================================================
+// SERVING DOMAINS
+// ============================================================================
+// Links can be served on several hosts (e.g. s.example.com for product links,
+// promo.example.com for campaigns). Each link is assigned a domain at
+// creation; the redirect only resolves on that host, and short URLs are
+// built from it. Short codes stay globally unique.
+//
+// Links with an empty domain (created before domains existed, or when no
+// domains are configured) resolve on any host, and their short URLs are
+// built from the origin of the request that asked for them.
+//
+// Redirects may also be served under a path prefix (e.g. /r), which short
+// URLs then include after the domain's own path.
+// ============================================================================
+
+// Domain is a host short links can be served on
+type Domain struct {
+	Host   string // e.g. s.example.com or localhost:8080
+	Scheme string // http or https
+	Path   string // Optional prefix when served under a path, e.g. /s
+}
+
+// ParseBaseURL parses a public base URL such as https://example.com/s
+func ParseBaseURL(raw string) (Domain, error) {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return Domain{}, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return Domain{}, fmt.Errorf("base URL must use http or https: %s", raw)
+	}
+	if parsed.Host == "" {
+		return Domain{}, fmt.Errorf("base URL must have a host: %s", raw)
+	}
+	return Domain{Host: parsed.Host, Scheme: parsed.Scheme, Path: parsed.Path}, nil
+}
+
+// SetDomains sets the serving domains; the first is the default for new links
+func (s *URLService) SetDomains(domains []Domain) {
+	normalized := make([]Domain, 0, len(domains))
+	for _, d := range domains {
+		scheme := d.Scheme
+		if scheme == "" {
+			scheme = "https"
+		}
+		path := strings.TrimRight(d.Path, "/")
+		if path != "" && !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		normalized = append(normalized, Domain{Host: normalizeHost(d.Host), Scheme: scheme, Path: path})
+	}
+	s.domains = normalized
+}
+
+// SetRedirectPrefix sets the path redirects are served under, e.g. /r;
+// empty (the default) serves them at the root
+func (s *URLService) SetRedirectPrefix(prefix string) {
+	s.redirectPrefix = strings.TrimRight(prefix, "/")
+}
+
+// ShortURL builds the full short URL of a mapping on its domain
+// requestOrigin (e.g. https://s.example.com) is used when neither the link
+// nor the config names a domain; without it the URL is relative
+func (s *URLService) ShortURL(mapping *model.URLMapping, requestOrigin string) string {
+	domain, ok := s.findDomain(mapping.Domain)
+	switch {
+	case ok:
+	case mapping.Domain != "":
+		// Domain was removed from config; keep the link's host
+		domain = Domain{Host: mapping.Domain, Scheme: "https"}
+		if len(s.domains) > 0 {
+			domain.Scheme = s.domains[0].Scheme
+		}
+	case len(s.domains) > 0:
+		domain = s.domains[0]
+	default:
+		return strings.TrimRight(requestOrigin, "/") + s.redirectPrefix + "/" + mapping.ShortCode
+	}
+	return fmt.Sprintf("%s://%s%s%s/%s", domain.Scheme, domain.Host, domain.Path, s.redirectPrefix, mapping.ShortCode)
+}
+
+// CheckDomain returns ErrUnknownDomain if name isn't a configured domain
+// An empty name selects the default domain and is always valid
+func (s *URLService) CheckDomain(name string) error {
+	_, err := s.resolveDomain(name)
+	return err
+}
+
+// resolveDomain returns the configured domain for a creation request
+// An empty name selects the default domain
+func (s *URLService) resolveDomain(name string) (Domain, error) {
+	if name == "" {
+		if len(s.domains) == 0 {
+			return Domain{}, nil
+		}
+		return s.domains[0], nil
+	}
+	if domain, ok := s.findDomain(name); ok {
+		return domain, nil
+	}
+	return Domain{}, fmt.Errorf("%w: %s", ErrUnknownDomain, name)
+}
+
+// findDomain looks up a configured domain by host
+func (s *URLService) findDomain(host string) (Domain, bool) {
+	for _, d := range s.domains {
+		if hostsMatch(d.Host, host) {
+			return d, true
+		}
+	}
+	return Domain{}, false
+}
+
+// servesHost reports whether a mapping may be resolved on a request host
+// An empty host (e.g. gRPC) matches every mapping
+func servesHost(mapping *model.URLMapping, host string) bool {
+	return mapping.Domain == "" || host == "" || hostsMatch(mapping.Domain, host)
+}
+
+// hostsMatch compares hosts case-insensitively; when either side has no
+// port, only the hostnames are compared
+func hostsMatch(a, b string) bool {
+	a, b = normalizeHost(a), normalizeHost(b)
+	if a == b {
+		return true
+	}
+	aName, aPort := splitHostPort(a)
+	bName, bPort := splitHostPort(b)
+	return aName == bName && (aPort == "" || bPort == "")
+}
+
+// normalizeHost lowercases a host and drops a trailing dot
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+// splitHostPort splits host:port, tolerating hosts without a port
+func splitHostPort(host string) (string, string) {
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		return host, ""
+	}
+	return name, port
+}
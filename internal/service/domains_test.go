@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// newDomainService creates a service with only domains configured
+func newDomainService() *URLService {
+	s := &URLService{}
+	s.SetDomains([]Domain{
+		{Host: "s.example.com", Scheme: "https"},
+		{Host: "Promo.Example.com"},
+		{Host: "localhost:8080", Scheme: "http"},
+	})
+	return s
+}
+
+// TestShortURL tests building short URLs from the link's domain
+func TestShortURL(t *testing.T) {
+	s := newDomainService()
+
+	assert.Equal(t, "https://promo.example.com/abc123",
+		s.ShortURL(&model.URLMapping{ShortCode: "abc123", Domain: "promo.example.com"}, ""))
+	assert.Equal(t, "http://localhost:8080/abc123",
+		s.ShortURL(&model.URLMapping{ShortCode: "abc123", Domain: "localhost:8080"}, ""))
+
+	// Legacy links use the default domain
+	assert.Equal(t, "https://s.example.com/abc123",
+		s.ShortURL(&model.URLMapping{ShortCode: "abc123"}, "http://ignored.example.com"))
+
+	// Removed domains keep their host
+	assert.Equal(t, "https://old.example.com/abc123",
+		s.ShortURL(&model.URLMapping{ShortCode: "abc123", Domain: "old.example.com"}, ""))
+}
+
+// TestShortURLWithoutDomains tests falling back to the request origin
+func TestShortURLWithoutDomains(t *testing.T) {
+	s := &URLService{}
+	mapping := &model.URLMapping{ShortCode: "abc123"}
+
+	assert.Equal(t, "https://s.example.com/abc123", s.ShortURL(mapping, "https://s.example.com/"))
+	assert.Equal(t, "/abc123", s.ShortURL(mapping, ""))
+}
+
+// TestShortURLWithRedirectPrefix tests that short URLs include the prefix
+// redirects are served under
+func TestShortURLWithRedirectPrefix(t *testing.T) {
+	s := newDomainService()
+	s.SetRedirectPrefix("/r")
+
+	assert.Equal(t, "https://promo.example.com/r/abc123",
+		s.ShortURL(&model.URLMapping{ShortCode: "abc123", Domain: "promo.example.com"}, ""))
+
+	s = &URLService{}
+	s.SetRedirectPrefix("/r/")
+	s.SetDomains([]Domain{{Host: "example.com", Path: "/s"}})
+	assert.Equal(t, "https://example.com/s/r/abc123", s.ShortURL(&model.URLMapping{ShortCode: "abc123"}, ""))
+
+	s = &URLService{}
+	s.SetRedirectPrefix("/r")
+	assert.Equal(t, "http://localhost:8080/r/abc123", s.ShortURL(&model.URLMapping{ShortCode: "abc123"}, "http://localhost:8080"))
+}
+
+// TestParseBaseURL tests base URLs with and without a path prefix
+func TestParseBaseURL(t *testing.T) {
+	d, err := ParseBaseURL("https://example.com/s/")
+	assert.NoError(t, err)
+
+	s := &URLService{}
+	s.SetDomains([]Domain{d})
+	assert.Equal(t, "https://example.com/s/abc123", s.ShortURL(&model.URLMapping{ShortCode: "abc123"}, ""))
+
+	_, err = ParseBaseURL("example.com")
+	assert.Error(t, err)
+	_, err = ParseBaseURL("ftp://example.com")
+	assert.Error(t, err)
+}
+
+// TestResolveDomain tests domain selection at creation
+func TestResolveDomain(t *testing.T) {
+	s := newDomainService()
+
+	d, err := s.resolveDomain("")
+	assert.NoError(t, err)
+	assert.Equal(t, "s.example.com", d.Host)
+
+	d, err = s.resolveDomain("PROMO.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "promo.example.com", d.Host)
+
+	_, err = s.resolveDomain("evil.example.com")
+	assert.True(t, errors.Is(err, ErrUnknownDomain))
+}
+
+// TestServesHost tests that links only resolve on their own host
+func TestServesHost(t *testing.T) {
+	mapping := &model.URLMapping{Domain: "s.example.com"}
+	assert.True(t, servesHost(mapping, "s.example.com"))
+	assert.True(t, servesHost(mapping, "S.EXAMPLE.COM:443"))
+	assert.True(t, servesHost(mapping, ""))
+	assert.False(t, servesHost(mapping, "promo.example.com"))
+
+	assert.True(t, servesHost(&model.URLMapping{}, "anything.example.com"))
+	assert.False(t, servesHost(&model.URLMapping{Domain: "localhost:8080"}, "localhost:9090"))
+}
@@ -0,0 +1,581 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/cache"
+	"github.com/Monthlyaway/short-link/internal/filter"
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// In-memory fakes of the service's dependencies (see deps.go)
+// Each embeds its interface, so a method a test doesn't fake panics on the
+// nil embedded value instead of silently doing nothing. fakeRepository
+// serves all URLService stores; background jobs get fakes of their own
+// store.
+
+// fakeRepository keeps links in a map
+type fakeRepository struct {
+	Repository
+
+	mu        sync.Mutex
+	links     map[string]*model.URLMapping
+	revisions []model.URLRevision
+	visitLogs []model.VisitLog // Only read by the data subject methods
+	members   []model.OrgMember
+	aliases   []model.LinkAlias
+	outbox    []model.OutboxEvent
+	campaigns []model.Campaign
+	attached  map[uint][]string // Campaign links by campaign ID
+	// replicaLag makes GetByShortCode miss every link, like a replica that
+	// hasn't caught up; GetByShortCodeFromPrimary still finds them
+	replicaLag bool
+	recycled   []string // Pool handed out by ClaimRecycledCode, in order
+	calls      map[string]int
+}
+
+func newFakeRepository(links ...*model.URLMapping) *fakeRepository {
+	r := &fakeRepository{links: make(map[string]*model.URLMapping), calls: make(map[string]int)}
+	for _, link := range links {
+		r.links[link.ShortCode] = link
+	}
+	return r
+}
+
+func (r *fakeRepository) called(method string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls[method]
+}
+
+func (r *fakeRepository) Create(ctx context.Context, mapping *model.URLMapping) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls["Create"]++
+	r.links[mapping.ShortCode] = mapping
+	return nil
+}
+
+func (r *fakeRepository) CreateWithOutbox(ctx context.Context, mapping *model.URLMapping, events []*model.OutboxEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls["CreateWithOutbox"]++
+	r.links[mapping.ShortCode] = mapping
+	for _, event := range events {
+		event.ID = uint64(len(r.outbox) + 1)
+		r.outbox = append(r.outbox, *event)
+	}
+	return nil
+}
+
+func (r *fakeRepository) CreateBatchWithOutbox(ctx context.Context, mappings []*model.URLMapping, events []*model.OutboxEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls["CreateBatchWithOutbox"]++
+	for _, mapping := range mappings {
+		r.links[mapping.ShortCode] = mapping
+	}
+	for _, event := range events {
+		event.ID = uint64(len(r.outbox) + 1)
+		r.outbox = append(r.outbox, *event)
+	}
+	return nil
+}
+
+func (r *fakeRepository) TakenShortCodes(ctx context.Context, shortCodes []string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var taken []string
+	for _, code := range shortCodes {
+		if _, ok := r.links[code]; ok {
+			taken = append(taken, code)
+		}
+	}
+	return taken, nil
+}
+
+func (r *fakeRepository) ActiveByOriginalURLs(ctx context.Context, domain string, originalURLs []string) ([]model.URLMapping, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var active []model.URLMapping
+	for _, link := range r.links {
+		for _, u := range originalURLs {
+			if link.OriginalURL == u && link.Domain == domain && link.Status == 1 {
+				active = append(active, *link)
+			}
+		}
+	}
+	return active, nil
+}
+
+func (r *fakeRepository) ClaimOutboxEvents(ctx context.Context, token string, now time.Time, lease time.Duration, limit int) ([]model.OutboxEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var claimed []model.OutboxEvent
+	for i := range r.outbox {
+		e := &r.outbox[i]
+		if len(claimed) == limit || e.DispatchedAt != nil || e.FailedAt != nil || e.NextAttemptAt.After(now) ||
+			(e.ClaimedUntil != nil && !e.ClaimedUntil.Before(now)) {
+			continue
+		}
+		until := now.Add(lease)
+		e.ClaimToken, e.ClaimedUntil = token, &until
+		claimed = append(claimed, *e)
+	}
+	return claimed, nil
+}
+
+func (r *fakeRepository) CompleteOutboxEvent(ctx context.Context, id uint64, token string, now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.outbox {
+		if e := &r.outbox[i]; e.ID == id && e.ClaimToken == token {
+			e.DispatchedAt, e.ClaimToken, e.ClaimedUntil = &now, "", nil
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRepository) RetryOutboxEvent(ctx context.Context, id uint64, token string, attempts int, next time.Time, lastErr string, failed bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.outbox {
+		if e := &r.outbox[i]; e.ID == id && e.ClaimToken == token {
+			e.Attempts, e.NextAttemptAt, e.LastError, e.ClaimToken, e.ClaimedUntil = attempts, next, lastErr, "", nil
+			if failed {
+				e.FailedAt = &next
+			}
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepository) SetLinkTitle(ctx context.Context, shortCode, title string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if link, ok := r.links[shortCode]; ok {
+		// Stored links may be shared with callers; replace rather than modify
+		copied := *link
+		copied.Title = title
+		r.links[shortCode] = &copied
+	}
+	return nil
+}
+
+func (r *fakeRepository) GetByShortCode(ctx context.Context, shortCode string) (*model.URLMapping, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls["GetByShortCode"]++
+	if r.replicaLag {
+		return nil, nil
+	}
+	if link, ok := r.links[shortCode]; ok {
+		copied := *link
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeRepository) GetByShortCodeFromPrimary(ctx context.Context, shortCode string) (*model.URLMapping, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls["GetByShortCodeFromPrimary"]++
+	if link, ok := r.links[shortCode]; ok {
+		copied := *link
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeRepository) GetByOriginalURL(ctx context.Context, originalURL, domain string, orgID uint) (*model.URLMapping, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, link := range r.links {
+		if link.OriginalURL == originalURL && link.Domain == domain && link.OrgID == orgID {
+			copied := *link
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepository) ShortCodeTaken(ctx context.Context, shortCode string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, taken := r.links[shortCode]
+	return taken, nil
+}
+
+func (r *fakeRepository) ClaimRecycledCode(ctx context.Context) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.recycled) == 0 {
+		return "", nil
+	}
+	shortCode := r.recycled[0]
+	r.recycled = r.recycled[1:]
+	return shortCode, nil
+}
+
+func (r *fakeRepository) AddRevisions(ctx context.Context, revisions []model.URLRevision) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.revisions = append(r.revisions, revisions...)
+	return nil
+}
+
+func (r *fakeRepository) LoadTags(ctx context.Context, mapping *model.URLMapping) error {
+	return nil
+}
+
+func (r *fakeRepository) IncrementVisitCount(ctx context.Context, shortCode string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls["IncrementVisitCount"]++
+	return nil
+}
+
+func (r *fakeRepository) IncrementDuplicateVisitCount(ctx context.Context, shortCode string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls["IncrementDuplicateVisitCount"]++
+	return nil
+}
+
+func (r *fakeRepository) CreateVisitLog(ctx context.Context, log *model.VisitLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls["CreateVisitLog"]++
+	return nil
+}
+
+func (r *fakeRepository) StreamVisitLogsByIP(ctx context.Context, ips []string, fn func([]model.VisitLog) error) error {
+	r.mu.Lock()
+	var matched []model.VisitLog
+	for _, log := range r.visitLogs {
+		if slices.Contains(ips, log.IP) {
+			matched = append(matched, log)
+		}
+	}
+	r.mu.Unlock()
+	if len(matched) == 0 {
+		return nil
+	}
+	return fn(matched)
+}
+
+func (r *fakeRepository) DeleteVisitLogsByIP(ctx context.Context, ips []string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	before := len(r.visitLogs)
+	r.visitLogs = slices.DeleteFunc(r.visitLogs, func(log model.VisitLog) bool { return slices.Contains(ips, log.IP) })
+	return int64(before - len(r.visitLogs)), nil
+}
+
+func (r *fakeRepository) GetOrgMember(ctx context.Context, orgID uint, userID string) (*model.OrgMember, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls["GetOrgMember"]++
+	for i := range r.members {
+		if r.members[i].OrgID == orgID && r.members[i].UserID == userID {
+			member := r.members[i]
+			return &member, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepository) Delete(ctx context.Context, shortCode string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls["Delete"]++
+	delete(r.links, shortCode)
+	return nil
+}
+
+func (r *fakeRepository) LinkCreator(ctx context.Context, shortCode string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, revision := range r.revisions {
+		if revision.ShortCode == shortCode && revision.Revision == 1 {
+			return revision.ChangedBy, nil
+		}
+	}
+	return "", nil
+}
+
+func (r *fakeRepository) GetCampaign(ctx context.Context, id uint) (*model.Campaign, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.campaigns {
+		if r.campaigns[i].ID == id {
+			campaign := r.campaigns[i]
+			return &campaign, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepository) ExistingShortCodes(ctx context.Context, shortCodes []string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var existing []string
+	for _, code := range shortCodes {
+		if _, ok := r.links[code]; ok {
+			existing = append(existing, code)
+		}
+	}
+	return existing, nil
+}
+
+func (r *fakeRepository) AddCampaignLinks(ctx context.Context, campaignID uint, shortCodes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls["AddCampaignLinks"]++
+	if r.attached == nil {
+		r.attached = make(map[uint][]string)
+	}
+	r.attached[campaignID] = append(r.attached[campaignID], shortCodes...)
+	return nil
+}
+
+func (r *fakeRepository) LinkOrgID(ctx context.Context, shortCode string) (uint, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	link, ok := r.links[shortCode]
+	if !ok {
+		return 0, false, nil
+	}
+	return link.OrgID, true, nil
+}
+
+func (r *fakeRepository) DeleteAliasesTo(ctx context.Context, shortCode string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	before := len(r.aliases)
+	r.aliases = slices.DeleteFunc(r.aliases, func(alias model.LinkAlias) bool { return alias.ShortCode == shortCode })
+	return int64(before - len(r.aliases)), nil
+}
+
+func (r *fakeRepository) GetAlias(ctx context.Context, namespace, alias string) (*model.LinkAlias, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls["GetAlias"]++
+	for i := range r.aliases {
+		if r.aliases[i].Namespace == namespace && r.aliases[i].Alias == alias {
+			found := r.aliases[i]
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepository) SetAlias(ctx context.Context, alias *model.LinkAlias) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.aliases {
+		if r.aliases[i].Namespace == alias.Namespace && r.aliases[i].Alias == alias.Alias {
+			r.aliases[i].ShortCode = alias.ShortCode
+			return nil
+		}
+	}
+	r.aliases = append(r.aliases, *alias)
+	return nil
+}
+
+func (r *fakeRepository) DeleteAlias(ctx context.Context, namespace, alias string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.aliases {
+		if r.aliases[i].Namespace == namespace && r.aliases[i].Alias == alias {
+			r.aliases = append(r.aliases[:i], r.aliases[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRepository) AbuseReportsByIP(ctx context.Context, ips []string) ([]model.AbuseReport, error) {
+	return nil, nil
+}
+
+func (r *fakeRepository) DeleteAbuseReportsByIP(ctx context.Context, ips []string) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakeRepository) LinksCreatedBy(ctx context.Context, actor string) ([]model.URLMapping, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var links []model.URLMapping
+	for _, revision := range r.revisions {
+		if link, ok := r.links[revision.ShortCode]; ok && revision.Revision == 1 && revision.ChangedBy == actor {
+			links = append(links, *link)
+		}
+	}
+	return links, nil
+}
+
+func (r *fakeRepository) PurgeLinks(ctx context.Context, mappings []model.URLMapping) (int64, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var visitLogs, links int64
+	for _, mapping := range mappings {
+		if _, ok := r.links[mapping.ShortCode]; ok {
+			delete(r.links, mapping.ShortCode)
+			links++
+		}
+		before := len(r.visitLogs)
+		r.visitLogs = slices.DeleteFunc(r.visitLogs, func(log model.VisitLog) bool { return log.ShortCode == mapping.ShortCode })
+		visitLogs += int64(before - len(r.visitLogs))
+	}
+	return visitLogs, links, nil
+}
+
+// fakeVisitRollupStore records the days rolled up
+type fakeVisitRollupStore struct {
+	VisitRollupStore
+
+	watermark  time.Time
+	firstVisit time.Time
+	rolled     []time.Time
+}
+
+func (r *fakeVisitRollupStore) VisitRollupWatermark(ctx context.Context) (time.Time, error) {
+	return r.watermark, nil
+}
+
+func (r *fakeVisitRollupStore) FirstVisitTime(ctx context.Context) (time.Time, error) {
+	return r.firstVisit, nil
+}
+
+func (r *fakeVisitRollupStore) RollupVisitDay(ctx context.Context, day time.Time) error {
+	r.rolled = append(r.rolled, day)
+	r.watermark = day.AddDate(0, 0, 1)
+	return nil
+}
+
+// fakeLinkPurgeStore records the cutoffs purged and purges deleted
+type fakeLinkPurgeStore struct {
+	LinkPurgeStore
+
+	cutoffs []time.Time
+	deleted []model.URLMapping // Purged by the next call
+}
+
+func (r *fakeLinkPurgeStore) PurgeDeletedBefore(ctx context.Context, cutoff time.Time, batchSize int, purged func([]model.URLMapping)) (int64, error) {
+	r.cutoffs = append(r.cutoffs, cutoff)
+	batch := r.deleted
+	r.deleted = nil
+	if len(batch) > 0 {
+		purged(batch)
+	}
+	return int64(len(batch)), nil
+}
+
+// fakeCache keeps cached links in a map
+type fakeCache struct {
+	Cache
+
+	mu    sync.Mutex
+	links map[string]*model.URLMapping
+	ttls  map[string]time.Duration
+	seen  map[string]bool // Visitors marked by FirstVisit; windows never end
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{
+		links: make(map[string]*model.URLMapping),
+		ttls:  make(map[string]time.Duration),
+		seen:  make(map[string]bool),
+	}
+}
+
+func (c *fakeCache) Get(ctx context.Context, shortCode string) (*model.URLMapping, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.links[shortCode], nil
+}
+
+func (c *fakeCache) Set(ctx context.Context, mapping *model.URLMapping) error {
+	return c.SetWithTTL(ctx, mapping, 0)
+}
+
+func (c *fakeCache) SetWithTTL(ctx context.Context, mapping *model.URLMapping, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.links[mapping.ShortCode] = mapping
+	c.ttls[mapping.ShortCode] = ttl
+	return nil
+}
+
+func (c *fakeCache) Delete(ctx context.Context, shortCode string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.links, shortCode)
+	return nil
+}
+
+func (c *fakeCache) FirstVisit(ctx context.Context, shortCode, visitor string, window time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := shortCode + ":" + visitor
+	if c.seen[key] {
+		return false, nil
+	}
+	c.seen[key] = true
+	return true, nil
+}
+
+func (c *fakeCache) Stats() cache.LookupStats {
+	return cache.LookupStats{}
+}
+
+// fakeFilter is an exact set, so it never reports false positives
+type fakeFilter struct {
+	mu    sync.Mutex
+	codes map[string]bool
+}
+
+func newFakeFilter(codes ...string) *fakeFilter {
+	f := &fakeFilter{codes: make(map[string]bool)}
+	for _, code := range codes {
+		f.codes[code] = true
+	}
+	return f
+}
+
+func (f *fakeFilter) Add(shortCode string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.codes[shortCode] = true
+}
+
+func (f *fakeFilter) Test(shortCode string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.codes[shortCode]
+}
+
+func (f *fakeFilter) Rebuild(load func() ([]string, error)) (int, error) {
+	codes, err := load()
+	if err != nil {
+		return 0, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.codes = make(map[string]bool, len(codes))
+	for _, code := range codes {
+		f.codes[code] = true
+	}
+	return len(codes), nil
+}
+
+func (f *fakeFilter) Stats() filter.Stats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return filter.Stats{EstimatedEntries: uint(len(f.codes))}
+}
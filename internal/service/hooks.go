@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ============================================================================
+// HOOKS AND PLUGINS
+// ============================================================================
+// Deployments can add validation, logging sinks or enrichment without
+// forking the service: a plugin package registers itself from init and is
+// compiled in with a blank import in cmd/server:
+//
+//   package audit
+//
+//   func init() { service.RegisterPlugin(auditPlugin{}) }
+//
+//   func (auditPlugin) Name() string { return "audit" }
+//   func (auditPlugin) Register(s *service.URLService) error {
+//       s.OnDelete(func(ctx context.Context, e service.DeleteEvent) {
+//           log.Printf("link %s deleted", e.Mapping.ShortCode)
+//       })
+//       return nil
+//   }
+//
+// At startup LoadPlugins calls Register on each plugin, by name, which adds
+// hooks for any of these points:
+//
+//   OnCreate    a new link (created, cloned, imported) is about to be
+//               stored, its short code assigned; the hook may change its
+//               other fields, or reject it by returning an error
+//   OnRedirect  a visitor was redirected; runs in the background after the
+//               response, bounded by the visit write timeout
+//   OnExpire    a visitor asked for a link past its expiry; runs once per
+//               link and expiry within expireHookWindow, not per request
+//   OnDelete    a link was deleted: soft-deleted, purged after the grace
+//               period, recycled, or removed on a data deletion request
+//               (see DeleteEvent.Reason); a soft-deleted link is reported
+//               again when it is purged
+//
+// Hooks of a point run in the order they were added. Only OnCreate hooks
+// can fail an operation; the others are notifications, run on the request's
+// or job's goroutine (except OnRedirect) and should return quickly. A
+// panicking hook is recovered and logged rather than taking the request
+// down.
+// ============================================================================
+
+// ErrRejectedByHook wraps the error an OnCreate hook rejected a link with
+var ErrRejectedByHook = errors.New("rejected")
+
+// expireHookWindow is how long OnExpire hooks stay quiet for a link after
+// reporting it; a link given a new expiry is reported again when it passes
+const expireHookWindow = 30 * 24 * time.Hour
+
+// Reasons a link was deleted, in DeleteEvent.Reason
+const (
+	DeleteReasonDeleted     = "deleted"      // Soft-deleted; may still be restored
+	DeleteReasonPurged      = "purged"       // Removed for good after the grace period
+	DeleteReasonRecycled    = "recycled"     // Removed for good, its code put in the free pool
+	DeleteReasonDataRequest = "data_request" // Removed for good on a data deletion request
+)
+
+// CreateEvent is passed to OnCreate hooks
+type CreateEvent struct {
+	// Mapping is the link about to be stored, with its short code; changes
+	// made by the hook are stored, except that the short code and
+	// OriginalURL, which were validated, hashed and checked for collisions
+	// already, must not change: doing so fails the creation
+	Mapping *model.URLMapping
+	// Action is how the link came about: model.RevisionCreate, Clone or Import
+	Action string
+}
+
+// RedirectEvent is passed to OnRedirect hooks
+type RedirectEvent struct {
+	Mapping   *model.URLMapping // May hold only the cached fields (see ResolveLink)
+	Visitor   model.Visitor
+	VisitedAt time.Time
+}
+
+// ExpireEvent is passed to OnExpire hooks
+type ExpireEvent struct {
+	Mapping     *model.URLMapping
+	RequestedAt time.Time
+}
+
+// DeleteEvent is passed to OnDelete hooks
+type DeleteEvent struct {
+	Mapping *model.URLMapping // The link as it was before the delete
+	Reason  string            // DeleteReasonDeleted, Purged, Recycled or DataRequest
+}
+
+// CreateHook validates or enriches a link before it's stored
+type CreateHook func(ctx context.Context, event *CreateEvent) error
+
+// RedirectHook observes a redirect
+type RedirectHook func(ctx context.Context, event RedirectEvent)
+
+// ExpireHook observes a request for an expired link
+type ExpireHook func(ctx context.Context, event ExpireEvent)
+
+// DeleteHook observes a deleted link
+type DeleteHook func(ctx context.Context, event DeleteEvent)
+
+// hooks holds the hooks added to a service
+type hooks struct {
+	create   []CreateHook
+	redirect []RedirectHook
+	expire   []ExpireHook
+	delete   []DeleteHook
+}
+
+// OnCreate adds a hook run before each new link is stored
+// Hooks must be added before the service starts serving
+func (s *URLService) OnCreate(hook CreateHook) {
+	s.hooks.create = append(s.hooks.create, hook)
+}
+
+// OnRedirect adds a hook run after each redirect
+func (s *URLService) OnRedirect(hook RedirectHook) {
+	s.hooks.redirect = append(s.hooks.redirect, hook)
+}
+
+// OnExpire adds a hook run when a visitor first asks for an expired link
+func (s *URLService) OnExpire(hook ExpireHook) {
+	s.hooks.expire = append(s.hooks.expire, hook)
+}
+
+// OnDelete adds a hook run after each link is deleted, however it was
+func (s *URLService) OnDelete(hook DeleteHook) {
+	s.hooks.delete = append(s.hooks.delete, hook)
+}
+
+// runCreateHooks runs the OnCreate hooks on mapping, stopping at the first
+// that rejects it
+// The hooks run after validation, so one changing the short code or the
+// destination, or the fields derived from them, fails the creation
+func (s *URLService) runCreateHooks(ctx context.Context, mapping *model.URLMapping, action string) error {
+	event := &CreateEvent{Mapping: mapping, Action: action}
+	shortCode, originalURL := mapping.ShortCode, mapping.OriginalURL
+	urlHash, destination := mapping.URLHash, mapping.DestinationHost
+	for _, hook := range s.hooks.create {
+		if err := callCreateHook(ctx, hook, event); err != nil {
+			return err
+		}
+		if event.Mapping != mapping || mapping.ShortCode != shortCode || mapping.OriginalURL != originalURL ||
+			mapping.URLHash != urlHash || mapping.DestinationHost != destination {
+			return errors.New("create hook changed the short code or destination, which hooks must not change")
+		}
+	}
+	return nil
+}
+
+// callCreateHook calls one OnCreate hook; a panic fails the creation
+func callCreateHook(ctx context.Context, hook CreateHook, event *CreateEvent) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("create hook panicked: %v", r)
+		}
+	}()
+	if err := hook(ctx, event); err != nil {
+		return fmt.Errorf("%w: %v", ErrRejectedByHook, err)
+	}
+	return nil
+}
+
+// runRedirectHooks runs the OnRedirect hooks
+func (s *URLService) runRedirectHooks(ctx context.Context, event RedirectEvent) {
+	for _, hook := range s.hooks.redirect {
+		runHook("redirect", func() { hook(ctx, event) })
+	}
+}
+
+// runExpireHooks runs the OnExpire hooks if mapping is past its expiry
+// (rather than disabled) and they haven't reported that expiry within
+// expireHookWindow
+// The window is kept in Redis, so it holds across instances; if Redis
+// can't be asked the hooks run rather than be skipped
+func (s *URLService) runExpireHooks(ctx context.Context, mapping *model.URLMapping) {
+	if len(s.hooks.expire) == 0 || !mapping.IsExpired() {
+		return
+	}
+	first, err := s.cache.FirstVisit(ctx, mapping.ShortCode, "expired:"+strconv.FormatInt(mapping.ExpiredAt.Unix(), 10), expireHookWindow)
+	if err != nil {
+		fmt.Printf("Failed to deduplicate expire hooks: %v\n", err)
+	} else if !first {
+		return
+	}
+	event := ExpireEvent{Mapping: mapping, RequestedAt: time.Now()}
+	for _, hook := range s.hooks.expire {
+		runHook("expire", func() { hook(ctx, event) })
+	}
+}
+
+// runDeleteHooks runs the OnDelete hooks (see LinkRemoved)
+func (s *URLService) runDeleteHooks(ctx context.Context, event DeleteEvent) {
+	for _, hook := range s.hooks.delete {
+		runHook("delete", func() { hook(ctx, event) })
+	}
+}
+
+// runHook calls a notification hook, logging instead of propagating a panic
+func runHook(point string, call func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("%s hook panicked: %v\n", point, r)
+		}
+	}()
+	call()
+}
+
+// Plugin adds hooks to a service; compiled-in plugins make themselves
+// known with RegisterPlugin
+type Plugin interface {
+	// Name identifies the plugin in logs and errors
+	Name() string
+	// Register adds the plugin's hooks; an error stops startup
+	Register(s *URLService) error
+}
+
+var (
+	pluginsMu sync.Mutex
+	plugins   = map[string]Plugin{}
+)
+
+// RegisterPlugin makes a plugin available to LoadPlugins; it is meant to be
+// called from the plugin package's init function
+// Registering two plugins with the same name panics
+func RegisterPlugin(p Plugin) {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	if _, dup := plugins[p.Name()]; dup {
+		panic("service: plugin " + p.Name() + " registered twice")
+	}
+	plugins[p.Name()] = p
+}
+
+// LoadPlugins registers the hooks of every plugin, in name order, and
+// returns their names
+func (s *URLService) LoadPlugins() ([]string, error) {
+	pluginsMu.Lock()
+	loaded := make([]Plugin, 0, len(plugins))
+	for _, p := range plugins {
+		loaded = append(loaded, p)
+	}
+	pluginsMu.Unlock()
+	sort.Slice(loaded, func(i, j int) bool { return loaded[i].Name() < loaded[j].Name() })
+
+	names := make([]string, 0, len(loaded))
+	for _, p := range loaded {
+		if err := p.Register(s); err != nil {
+			return nil, fmt.Errorf("failed to load plugin %s: %w", p.Name(), err)
+		}
+		names = append(names, p.Name())
+	}
+	return names, nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCreateHooks tests that OnCreate hooks see the new code, can change
+// the link and can reject it before it's stored
+func TestCreateHooks(t *testing.T) {
+	repo := newFakeRepository()
+	s := NewURLService(repo, newFakeCache(), newFakeFilter())
+	ctx := context.Background()
+
+	var seen []string
+	s.OnCreate(func(ctx context.Context, e *CreateEvent) error {
+		if strings.Contains(e.Mapping.OriginalURL, "blocked") {
+			return errors.New("destination not allowed by policy")
+		}
+		seen = append(seen, e.Action+":"+e.Mapping.ShortCode)
+		e.Mapping.Metadata = map[string]interface{}{"source": "plugin"}
+		return nil
+	})
+	s.OnCreate(func(ctx context.Context, e *CreateEvent) error {
+		if e.Mapping.Metadata["source"] != "plugin" {
+			panic("hooks ran out of order")
+		}
+		return nil
+	})
+
+	mapping, err := s.CreateShortURL(ctx, "https://example.com/page", "", nil, model.LinkOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"create:" + mapping.ShortCode}, seen)
+	assert.Equal(t, "plugin", repo.links[mapping.ShortCode].Metadata["source"], "changes are stored")
+
+	_, err = s.CreateShortURL(ctx, "https://example.com/blocked", "", nil, model.LinkOptions{})
+	assert.ErrorIs(t, err, ErrRejectedByHook)
+	assert.ErrorContains(t, err, "destination not allowed by policy")
+	assert.Equal(t, 1, repo.called("Create"))
+
+	s.OnCreate(func(ctx context.Context, e *CreateEvent) error {
+		if strings.Contains(e.Mapping.OriginalURL, "swap") {
+			e.Mapping.OriginalURL = "https://evil.example/"
+		}
+		return nil
+	})
+	_, err = s.CreateShortURL(ctx, "https://example.com/swap", "", nil, model.LinkOptions{})
+	assert.ErrorContains(t, err, "must not change", "the destination was validated already")
+	assert.Equal(t, 1, repo.called("Create"))
+
+	s.OnCreate(func(ctx context.Context, e *CreateEvent) error { panic("boom") })
+	_, err = s.CreateShortURL(ctx, "https://example.com/other", "", nil, model.LinkOptions{})
+	assert.ErrorContains(t, err, "create hook panicked", "a panic fails the creation, not the process")
+}
+
+// TestExpireAndDeleteHooks tests the notification hooks
+func TestExpireAndDeleteHooks(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	repo := newFakeRepository(
+		&model.URLMapping{ShortCode: "old1234", OriginalURL: "https://example.com/old", Status: 1, ExpiredAt: &past},
+		&model.URLMapping{ShortCode: "off1234", OriginalURL: "https://example.com/off", Status: 0},
+		&model.URLMapping{ShortCode: "live123", OriginalURL: "https://example.com/live", Status: 1},
+	)
+	s := NewURLService(repo, newFakeCache(), newFakeFilter("old1234", "off1234", "live123"))
+	ctx := context.Background()
+
+	var expired, deleted []string
+	s.OnExpire(func(ctx context.Context, e ExpireEvent) { expired = append(expired, e.Mapping.ShortCode) })
+	s.OnDelete(func(ctx context.Context, e DeleteEvent) { deleted = append(deleted, e.Reason+":"+e.Mapping.OriginalURL) })
+	s.OnDelete(func(ctx context.Context, e DeleteEvent) { panic("boom") })
+
+	_, err := s.ResolveLink(ctx, "", "old1234", model.Visitor{})
+	assert.ErrorIs(t, err, ErrShortCodeInactive)
+	_, err = s.ResolveLink(ctx, "", "old1234", model.Visitor{})
+	assert.ErrorIs(t, err, ErrShortCodeInactive, "served from cache this time")
+	_, err = s.ResolveLink(ctx, "", "off1234", model.Visitor{})
+	assert.ErrorIs(t, err, ErrShortCodeInactive)
+	assert.Equal(t, []string{"old1234"}, expired, "reported once, and disabled links aren't expired")
+
+	require.NoError(t, s.DeleteURL(ctx, "live123"))
+	assert.Equal(t, []string{"deleted:https://example.com/live"}, deleted, "a panicking hook doesn't stop the others")
+}
+
+// testPlugin adds an OnDelete hook counting deletes
+type testPlugin struct {
+	name    string
+	deletes *int
+}
+
+func (p testPlugin) Name() string { return p.name }
+
+func (p testPlugin) Register(s *URLService) error {
+	s.OnDelete(func(ctx context.Context, e DeleteEvent) { *p.deletes++ })
+	return nil
+}
+
+// TestLoadPlugins tests that registered plugins add their hooks
+func TestLoadPlugins(t *testing.T) {
+	var deletes int
+	RegisterPlugin(testPlugin{name: "test-counter", deletes: &deletes})
+	assert.Panics(t, func() { RegisterPlugin(testPlugin{name: "test-counter"}) })
+
+	repo := newFakeRepository(&model.URLMapping{ShortCode: "live123", OriginalURL: "https://example.com", Status: 1})
+	s := NewURLService(repo, newFakeCache(), newFakeFilter("live123"))
+	names, err := s.LoadPlugins()
+	require.NoError(t, err)
+	assert.Contains(t, names, "test-counter")
+
+	require.NoError(t, s.DeleteURL(context.Background(), "live123"))
+	assert.Equal(t, 1, deletes)
+}
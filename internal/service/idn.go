@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+)
+
+// ============================================================================
+// INTERNATIONALIZED URLS
+// ============================================================================
+// Browsers accept URLs like https://bücher.example/straße, but a Location
+// header must be ASCII and DNS only knows the punycode form of the host
+// (xn--bcher-kva.example). Such URLs are stored in canonical form: the host
+// converted to punycode and every non-ASCII byte after it percent-encoded.
+// Existing escapes are kept as they are, so %2F in a path stays %2F.
+//
+// Redirects, duplicate detection and destination checks use the canonical
+// URL. The URL as submitted is kept as the display URL for API responses;
+// ASCII URLs are stored unchanged and have no display URL.
+// ============================================================================
+
+// canonicalURL converts an internationalized URL to its ASCII form
+// display is rawURL if it differed, and empty for URLs that were already
+// ASCII. URLs without a scheme are returned unchanged for validateURL to
+// reject.
+func canonicalURL(rawURL string) (canonical, display string, err error) {
+	if isASCII(rawURL) {
+		return rawURL, "", nil
+	}
+	// Control characters would hide behind percent-encoding
+	for _, r := range rawURL {
+		if unicode.IsControl(r) {
+			return "", "", fmt.Errorf("%w: URL contains control characters", ErrInvalidURL)
+		}
+	}
+
+	scheme, rest, ok := strings.Cut(rawURL, "://")
+	if !ok {
+		return rawURL, "", nil
+	}
+	end := strings.IndexAny(rest, "/?#")
+	if end < 0 {
+		end = len(rest)
+	}
+	authority, tail := rest[:end], rest[end:]
+
+	if !isASCII(authority) {
+		u, err := url.Parse(scheme + "://" + authority)
+		if err != nil {
+			return "", "", fmt.Errorf("%w: %v", ErrInvalidURL, err)
+		}
+		host, err := idna.Lookup.ToASCII(u.Hostname())
+		if err != nil {
+			return "", "", fmt.Errorf("%w: invalid internationalized domain name %q", ErrInvalidURL, u.Hostname())
+		}
+		if port := u.Port(); port != "" {
+			host += ":" + port
+		}
+		// Credentials are kept so validateURL can reject them
+		if at := strings.LastIndex(authority, "@"); at >= 0 {
+			host = authority[:at+1] + host
+		}
+		authority = host
+	}
+
+	canonical = scheme + "://" + authority + percentEncodeNonASCII(tail)
+	if canonical == rawURL {
+		return canonical, "", nil
+	}
+	return canonical, rawURL, nil
+}
+
+// percentEncodeNonASCII escapes every byte outside ASCII, leaving the rest
+// (including existing escapes) untouched
+func percentEncodeNonASCII(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c >= 0x80 {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// isASCII reports whether s has only ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		canonical string
+	}{
+		{"ascii unchanged", "https://Example.com/a%2Fb?q=1", "https://Example.com/a%2Fb?q=1"},
+		{"unicode host", "https://bücher.example/", "https://xn--bcher-kva.example/"},
+		{"unicode host with port", "http://bücher.example:8080", "http://xn--bcher-kva.example:8080"},
+		{"unicode path keeps escapes", "https://example.com/straße/a%2Fb", "https://example.com/stra%C3%9Fe/a%2Fb"},
+		{"unicode query and fragment", "https://例え.jp/検索?q=日本#目次",
+			"https://xn--r8jz45g.jp/%E6%A4%9C%E7%B4%A2?q=%E6%97%A5%E6%9C%AC#%E7%9B%AE%E6%AC%A1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canonical, display, err := canonicalURL(tt.raw)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.canonical, canonical)
+			if tt.canonical == tt.raw {
+				assert.Empty(t, display)
+			} else {
+				assert.Equal(t, tt.raw, display)
+			}
+		})
+	}
+
+	_, _, err := canonicalURL("https://example.com/\u0085")
+	assert.ErrorIs(t, err, ErrInvalidURL, "unicode control characters are rejected before encoding")
+	_, _, err = canonicalURL("https://bad\u00a0host.example/")
+	assert.ErrorIs(t, err, ErrInvalidURL)
+}
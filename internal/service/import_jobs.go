@@ -0,0 +1,111 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// Import job states
+const (
+	ImportJobRunning = "running"
+	ImportJobDone    = "done"
+	ImportJobFailed  = "failed"
+)
+
+// ImportJob is a snapshot of a background import
+type ImportJob struct {
+	ID         string              `json:"id"`
+	Status     string              `json:"status"`
+	Processed  int                 `json:"processed"` // Rows processed so far
+	Error      string              `json:"error,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	FinishedAt *time.Time          `json:"finished_at,omitempty"`
+	Report     *model.ImportReport `json:"report,omitempty"` // Set once finished
+}
+
+// ImportJobs tracks background imports in memory
+// Jobs are local to this instance and forgotten ttl after they finish
+type ImportJobs struct {
+	mu   sync.Mutex
+	jobs map[string]*ImportJob
+	ttl  time.Duration
+}
+
+// NewImportJobs creates an empty job registry
+func NewImportJobs(ttl time.Duration) *ImportJobs {
+	return &ImportJobs{
+		jobs: make(map[string]*ImportJob),
+		ttl:  ttl,
+	}
+}
+
+// Start runs fn in a new goroutine as a job and returns the job's ID
+// fn reports progress through the callback it is given
+func (j *ImportJobs) Start(fn func(progress func(int)) (*model.ImportReport, error)) string {
+	id := newJobID()
+
+	j.mu.Lock()
+	j.expireLocked(time.Now())
+	j.jobs[id] = &ImportJob{ID: id, Status: ImportJobRunning, CreatedAt: time.Now()}
+	j.mu.Unlock()
+
+	go func() {
+		report, err := fn(func(processed int) {
+			j.mu.Lock()
+			defer j.mu.Unlock()
+			j.jobs[id].Processed = processed
+		})
+
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		job := j.jobs[id]
+		now := time.Now()
+		job.FinishedAt = &now
+		job.Report = report
+		job.Status = ImportJobDone
+		if err != nil {
+			job.Status = ImportJobFailed
+			job.Error = err.Error()
+			fmt.Printf("Import job %s failed: %v\n", id, err)
+		}
+		if report != nil {
+			job.Processed = report.Total
+		}
+	}()
+
+	return id
+}
+
+// Get returns a snapshot of a job
+func (j *ImportJobs) Get(id string) (ImportJob, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.expireLocked(time.Now())
+
+	job, ok := j.jobs[id]
+	if !ok {
+		return ImportJob{}, false
+	}
+	return *job, true
+}
+
+// expireLocked forgets jobs that finished more than ttl ago
+func (j *ImportJobs) expireLocked(now time.Time) {
+	for id, job := range j.jobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > j.ttl {
+			delete(j.jobs, id)
+		}
+	}
+}
+
+// newJobID returns a random 128-bit hex ID
+func newJobID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,381 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ============================================================================
+// Bulk Import
+// ============================================================================
+// Links are imported from CSV with one link per row:
+//
+//	original_url[,alias[,expired_at]]
+//
+// An optional header row names the columns instead, so exports from other
+// shorteners can be imported as-is (e.g. bit.ly's long_url and custom_alias
+// columns); unknown columns are ignored.
+//
+// Rows are streamed and processed in batches of importBatchSize:
+// 1. validate each row (URL, alias format, reserved codes, expiry)
+// 2. one query for aliases already taken, one for URLs that already have a
+//    link on the domain (those rows reuse it, like POST /shorten does)
+// 3. insert the batch in a transaction; if that fails, retry row by row so
+//    the error is reported on the row that caused it
+//
+// A bad row never stops the import; it is reported as failed.
+// ============================================================================
+
+// importBatchSize is the number of rows validated and inserted together
+const importBatchSize = 500
+
+// Alias length limits; short_code is VARCHAR(15)
+const (
+	minAliasLength = 3
+	maxAliasLength = 15
+)
+
+// importColumnNames maps accepted header names to columns
+var importColumnNames = map[string]string{
+	"original_url": "url", "url": "url", "long_url": "url", "destination": "url",
+	"alias": "alias", "short_code": "alias", "custom_alias": "alias", "keyword": "alias",
+	"expired_at": "expiry", "expires_at": "expiry", "expiry": "expiry", "expiration": "expiry",
+}
+
+// ImportStore checks imported rows against existing links
+type ImportStore interface {
+	ActiveByOriginalURLs(ctx context.Context, domain string, originalURLs []string) ([]model.URLMapping, error)
+	TakenShortCodes(ctx context.Context, shortCodes []string) ([]string, error)
+}
+
+// ReadImportCSV parses rows from r and calls fn for each data row
+// Rows that can't be parsed are passed with a non-nil rowErr (and their line);
+// fn returning an error, or r failing, stops reading
+func ReadImportCSV(r io.Reader, fn func(row model.ImportRow, rowErr error) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	columns := map[string]int{"url": 0, "alias": 1, "expiry": 2}
+	first := true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			if err := fn(model.ImportRow{Line: parseErr.StartLine}, parseErr.Err); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		if first {
+			first = false
+			if header, ok := importHeader(record); ok {
+				if _, ok := header["url"]; !ok {
+					return fmt.Errorf("CSV header has no original_url column")
+				}
+				columns = header
+				continue
+			}
+		}
+
+		row, rowErr := parseImportRecord(record, columns)
+		row.Line = line
+		if err := fn(row, rowErr); err != nil {
+			return err
+		}
+	}
+}
+
+// importHeader returns the column positions if record is a header row
+func importHeader(record []string) (map[string]int, bool) {
+	columns := make(map[string]int)
+	for i, name := range record {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if column, ok := importColumnNames[name]; ok {
+			if _, seen := columns[column]; !seen {
+				columns[column] = i
+			}
+		}
+	}
+	return columns, len(columns) > 0
+}
+
+// parseImportRecord converts a CSV record to a row
+func parseImportRecord(record []string, columns map[string]int) (model.ImportRow, error) {
+	field := func(column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	row := model.ImportRow{
+		OriginalURL: field("url"),
+		Alias:       field("alias"),
+	}
+	if expiry := field("expiry"); expiry != "" {
+		expiredAt, err := parseImportTime(expiry)
+		if err != nil {
+			return row, err
+		}
+		row.ExpiredAt = &expiredAt
+	}
+	return row, nil
+}
+
+// parseImportTime parses an expiry: RFC3339, "YYYY-MM-DD HH:MM:SS" or
+// YYYY-MM-DD (UTC)
+func parseImportTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid expiry %q (use RFC3339 or YYYY-MM-DD)", value)
+}
+
+// validateAlias checks the format of a requested short code
+func validateAlias(alias string) error {
+	if len(alias) < minAliasLength || len(alias) > maxAliasLength {
+		return fmt.Errorf("alias must be %d-%d characters", minAliasLength, maxAliasLength)
+	}
+	for _, c := range alias {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return fmt.Errorf("alias may only contain letters, digits, - and _")
+		}
+	}
+	return nil
+}
+
+// pendingImportRow is a row waiting for its batch
+type pendingImportRow struct {
+	row model.ImportRow
+	err error
+}
+
+// importer holds the state of one import across batches
+type importer struct {
+	s       *URLService
+	domain  string
+	report  *model.ImportReport
+	aliases map[string]int    // Alias -> line that used it
+	urls    map[string]string // Original URL -> short code, for rows without alias
+	pending []pendingImportRow
+}
+
+// ImportURLs creates links from a CSV stream on domain (empty for the
+// default domain) and returns a report with one result per row
+// progress, if not nil, is called with the number of rows processed after
+// each batch. On error the partial report is returned with it.
+func (s *URLService) ImportURLs(ctx context.Context, r io.Reader, domain string, progress func(processed int)) (*model.ImportReport, error) {
+	serving, err := s.resolveDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	imp := &importer{
+		s:       s,
+		domain:  serving.Host,
+		report:  &model.ImportReport{Rows: []model.ImportResult{}},
+		aliases: make(map[string]int),
+		urls:    make(map[string]string),
+	}
+	flush := func() error {
+		if err := imp.flush(ctx); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(imp.report.Total)
+		}
+		return nil
+	}
+
+	err = ReadImportCSV(r, func(row model.ImportRow, rowErr error) error {
+		if rowErr == nil {
+			var canonical string
+			if canonical, row.DisplayURL, rowErr = canonicalURL(row.OriginalURL); rowErr == nil {
+				row.OriginalURL = canonical
+			}
+		}
+		imp.pending = append(imp.pending, pendingImportRow{row: row, err: rowErr})
+		if len(imp.pending) < importBatchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil {
+		err = flush()
+	}
+	return imp.report, err
+}
+
+// validateRow checks a row without touching the database
+func (imp *importer) validateRow(ctx context.Context, p pendingImportRow) error {
+	if p.err != nil {
+		return p.err
+	}
+	if err := imp.s.validateURL(ctx, p.row.OriginalURL); err != nil {
+		return err
+	}
+	if p.row.ExpiredAt != nil && !p.row.ExpiredAt.After(time.Now()) {
+		return fmt.Errorf("expiry is in the past")
+	}
+	if p.row.Alias == "" {
+		return nil
+	}
+	if err := validateAlias(p.row.Alias); err != nil {
+		return err
+	}
+	if err := imp.s.reserved.Check(p.row.Alias); err != nil {
+		return err
+	}
+	if line, ok := imp.aliases[p.row.Alias]; ok {
+		return fmt.Errorf("alias already used on line %d", line)
+	}
+	return nil
+}
+
+// flush validates, deduplicates and inserts the pending rows
+func (imp *importer) flush(ctx context.Context) error {
+	if len(imp.pending) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s := imp.s
+	rows := imp.pending
+	imp.pending = nil
+
+	results := make([]model.ImportResult, len(rows))
+	fail := func(i int, err error) {
+		results[i].Status = model.ImportFailed
+		results[i].Error = err.Error()
+	}
+
+	// Validate rows on their own
+	var aliases, urls []string
+	for i, p := range rows {
+		results[i] = model.ImportResult{Line: p.row.Line, OriginalURL: p.row.OriginalURL}
+		if err := imp.validateRow(ctx, p); err != nil {
+			fail(i, err)
+			continue
+		}
+		if p.row.Alias == "" {
+			urls = append(urls, p.row.OriginalURL)
+			continue
+		}
+		imp.aliases[p.row.Alias] = p.row.Line
+		aliases = append(aliases, p.row.Alias)
+	}
+
+	// Look up existing links for the whole batch
+	taken, err := s.repo.TakenShortCodes(ctx, aliases)
+	if err != nil {
+		return err
+	}
+	takenSet := make(map[string]bool, len(taken))
+	for _, code := range taken {
+		takenSet[code] = true
+	}
+	existing, err := s.repo.ActiveByOriginalURLs(ctx, imp.domain, urls)
+	if err != nil {
+		return err
+	}
+	for _, mapping := range existing {
+		if _, ok := imp.urls[mapping.OriginalURL]; !ok {
+			imp.urls[mapping.OriginalURL] = mapping.ShortCode
+		}
+	}
+
+	// Build the mappings to create
+	var creates []*model.URLMapping
+	var createRows []int
+	for i, p := range rows {
+		if results[i].Status != "" {
+			continue
+		}
+		shortCode := p.row.Alias
+		if shortCode == "" {
+			if code, ok := imp.urls[p.row.OriginalURL]; ok {
+				results[i].ShortCode = code
+				results[i].Status = model.ImportExists
+				continue
+			}
+			if shortCode, err = s.generateShortCode(ctx); err != nil {
+				fail(i, err)
+				continue
+			}
+			imp.urls[p.row.OriginalURL] = shortCode
+		} else if takenSet[shortCode] {
+			fail(i, fmt.Errorf("alias %q is already taken", shortCode))
+			continue
+		}
+
+		mapping := &model.URLMapping{
+			ShortCode:       shortCode,
+			OriginalURL:     p.row.OriginalURL,
+			DisplayURL:      p.row.DisplayURL,
+			DestinationHost: destinationHost(p.row.OriginalURL),
+			URLHash:         model.HashURL(p.row.OriginalURL),
+			Domain:          imp.domain,
+			ExpiredAt:       p.row.ExpiredAt,
+			Status:          1,
+		}
+		if err := s.runCreateHooks(ctx, mapping, model.RevisionImport); err != nil {
+			fail(i, err)
+			if p.row.Alias == "" {
+				delete(imp.urls, p.row.OriginalURL)
+			}
+			continue
+		}
+		creates = append(creates, mapping)
+		createRows = append(createRows, i)
+	}
+
+	// Insert together, or one by one to find the failing rows
+	if err := s.storeMappings(ctx, creates); err != nil {
+		fmt.Printf("Import batch insert failed, retrying rows individually: %v\n", err)
+		for j, mapping := range creates {
+			if err := s.storeMapping(ctx, mapping); err != nil {
+				fail(createRows[j], err)
+				if rows[createRows[j]].row.Alias == "" {
+					delete(imp.urls, mapping.OriginalURL)
+				}
+				creates[j] = nil
+			}
+		}
+	}
+	created := make([]*model.URLMapping, 0, len(creates))
+	for j, mapping := range creates {
+		if mapping == nil {
+			continue
+		}
+		s.bloom.Add(mapping.ShortCode)
+		results[createRows[j]].ShortCode = mapping.ShortCode
+		results[createRows[j]].Status = model.ImportCreated
+		created = append(created, mapping)
+	}
+	s.recordFirstRevisions(ctx, model.RevisionImport, "", created...)
+
+	for _, result := range results {
+		imp.report.Add(result)
+	}
+	return nil
+}
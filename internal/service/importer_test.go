@@ -0,0 +1,109 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// readImport collects the rows and row errors of a CSV
+func readImport(t *testing.T, data string) ([]model.ImportRow, []error) {
+	var rows []model.ImportRow
+	var errs []error
+	err := ReadImportCSV(strings.NewReader(data), func(row model.ImportRow, rowErr error) error {
+		rows = append(rows, row)
+		errs = append(errs, rowErr)
+		return nil
+	})
+	assert.NoError(t, err)
+	return rows, errs
+}
+
+// TestReadImportCSVPositional tests files without a header row
+func TestReadImportCSVPositional(t *testing.T) {
+	rows, errs := readImport(t, "https://a.example/x\nhttps://b.example/y, promo,2030-01-02\n")
+
+	assert.Len(t, rows, 2)
+	assert.Equal(t, model.ImportRow{Line: 1, OriginalURL: "https://a.example/x"}, rows[0])
+	assert.Nil(t, errs[0])
+
+	assert.Equal(t, 2, rows[1].Line)
+	assert.Equal(t, "promo", rows[1].Alias)
+	assert.Equal(t, time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC), *rows[1].ExpiredAt)
+}
+
+// TestReadImportCSVHeader tests named columns, as in other shorteners' exports
+func TestReadImportCSVHeader(t *testing.T) {
+	data := "\ufeffid,Long_URL,title,custom_alias\n" +
+		"1,https://a.example/x,Home,home1\n" +
+		"2,https://b.example/y,Blog,\n"
+	rows, errs := readImport(t, data)
+
+	assert.Len(t, rows, 2)
+	assert.Equal(t, model.ImportRow{Line: 2, OriginalURL: "https://a.example/x", Alias: "home1"}, rows[0])
+	assert.Equal(t, model.ImportRow{Line: 3, OriginalURL: "https://b.example/y"}, rows[1])
+	assert.Equal(t, []error{nil, nil}, errs)
+
+	err := ReadImportCSV(strings.NewReader("title,alias\nx,y\n"), func(model.ImportRow, error) error { return nil })
+	assert.Error(t, err)
+}
+
+// TestReadImportCSVRowErrors tests that bad rows are reported and reading continues
+func TestReadImportCSVRowErrors(t *testing.T) {
+	rows, errs := readImport(t, "https://a.example/,,tomorrow\n\"bad\"quote\nhttps://c.example/\n")
+
+	assert.Len(t, rows, 3)
+	assert.Error(t, errs[0])
+	assert.Equal(t, 1, rows[0].Line)
+	assert.Error(t, errs[1])
+	assert.Equal(t, 2, rows[1].Line)
+	assert.Nil(t, errs[2])
+	assert.Equal(t, "https://c.example/", rows[2].OriginalURL)
+}
+
+// TestValidateAlias tests the accepted alias format
+func TestValidateAlias(t *testing.T) {
+	assert.NoError(t, validateAlias("spring_Sale-24"))
+	assert.Error(t, validateAlias("ab"))
+	assert.Error(t, validateAlias(strings.Repeat("a", 16)))
+	assert.Error(t, validateAlias("has space"))
+	assert.Error(t, validateAlias("émoji"))
+}
+
+// TestImportJobs tests job progress and completion
+func TestImportJobs(t *testing.T) {
+	jobs := NewImportJobs(time.Hour)
+	release := make(chan struct{})
+	id := jobs.Start(func(progress func(int)) (*model.ImportReport, error) {
+		progress(500)
+		<-release
+		report := &model.ImportReport{}
+		report.Add(model.ImportResult{Status: model.ImportCreated})
+		report.Add(model.ImportResult{Status: model.ImportFailed, Error: "invalid URL"})
+		return report, nil
+	})
+
+	assert.Eventually(t, func() bool {
+		job, ok := jobs.Get(id)
+		return ok && job.Processed == 500
+	}, time.Second, 5*time.Millisecond)
+	job, _ := jobs.Get(id)
+	assert.Equal(t, ImportJobRunning, job.Status)
+	assert.Nil(t, job.Report)
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		job, _ := jobs.Get(id)
+		return job.Status == ImportJobDone
+	}, time.Second, 5*time.Millisecond)
+	job, _ = jobs.Get(id)
+	assert.Equal(t, 2, job.Processed)
+	assert.Equal(t, 1, job.Report.Created)
+	assert.Equal(t, 1, job.Report.Failed)
+
+	_, ok := jobs.Get("unknown")
+	assert.False(t, ok)
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/cache"
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ErrInvalidPeriod is returned for a leaderboard period other than 24h, 7d or 30d
+var ErrInvalidPeriod = errors.New("period must be 24h, 7d or 30d")
+
+// LeaderboardStore loads the links of a leaderboard
+type LeaderboardStore interface {
+	GetByShortCodes(ctx context.Context, shortCodes []string) ([]model.URLMapping, error)
+}
+
+// SetLeaderboard enables counting clicks for the top-links leaderboard
+func (s *URLService) SetLeaderboard(leaderboard *cache.Leaderboard) {
+	s.leaderboard = leaderboard
+}
+
+// TopLinks returns up to limit links with the most human clicks in period
+// Links of organizations, unlisted and private links, deleted links and
+// disabled links are left out
+func (s *URLService) TopLinks(ctx context.Context, period string, limit int) ([]model.TopLink, error) {
+	switch period {
+	case cache.Period24h, cache.Period7d, cache.Period30d:
+	default:
+		return nil, ErrInvalidPeriod
+	}
+
+	// Read more than asked for, as some entries may be filtered out
+	entries, err := s.leaderboard.Top(ctx, period, time.Now(), limit*2)
+	if err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		codes = append(codes, entry.ShortCode)
+	}
+	mappings, err := s.repo.GetByShortCodes(ctx, codes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load top links: %w", err)
+	}
+	byCode := make(map[string]*model.URLMapping, len(mappings))
+	for i := range mappings {
+		byCode[mappings[i].ShortCode] = &mappings[i]
+	}
+
+	top := make([]model.TopLink, 0, limit)
+	for _, entry := range entries {
+		mapping := byCode[entry.ShortCode]
+		if mapping == nil || mapping.OrgID != 0 || !mapping.IsListed() || mapping.Status != 1 {
+			continue
+		}
+		top = append(top, model.TopLink{Mapping: mapping, Clicks: entry.Clicks})
+		if len(top) == limit {
+			break
+		}
+	}
+	return top, nil
+}
+
+// LeaderboardTrim periodically trims the leaderboard buckets to their top
+// entries so they don't grow with every link ever clicked
+type LeaderboardTrim struct {
+	leaderboard *cache.Leaderboard
+	interval    time.Duration
+}
+
+// NewLeaderboardTrim creates a trim job
+func NewLeaderboardTrim(leaderboard *cache.Leaderboard, interval time.Duration) *LeaderboardTrim {
+	return &LeaderboardTrim{leaderboard: leaderboard, interval: interval}
+}
+
+// Run trims every interval until ctx is done
+func (j *LeaderboardTrim) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := j.leaderboard.Trim(ctx, time.Now()); err != nil {
+			fmt.Printf("Leaderboard trim failed: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/linkcheck"
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ============================================================================
+// LINK HEALTH
+// ============================================================================
+// Old campaign links often point at pages that have since been removed. The
+// health check job HEAD-checks the destinations of active links and records
+// the result on the mapping (shown by the info API): ok, restricted (the
+// page exists but refused the checker), broken, timeout, tls_error or
+// unreachable, plus the HTTP status and how many checks in a row failed.
+//
+// Each run checks the links checked longest ago (never-checked ones first),
+// so every link is rechecked roughly once per recheck period as long as the
+// batches keep up. Once a link has failed notifyAfter checks in a row its
+// owner is notified, once per outage; a passing check resets the count.
+//
+// Results are recorded only if no other instance checked the link in the
+// meantime, so running several instances doesn't double notifications.
+// ============================================================================
+
+// LinkHealthStore is the storage used by LinkHealthCheck
+type LinkHealthStore interface {
+	LinksDueForHealthCheck(ctx context.Context, checkedBefore, now time.Time, limit int) ([]model.URLMapping, error)
+	RecordLinkHealth(ctx context.Context, id uint, previousCheck *time.Time, status string, code, failures int, checkedAt time.Time) (bool, error)
+	LinkCreator(ctx context.Context, shortCode string) (string, error)
+}
+
+// LinkHealthOptions are the settings of the link health check job
+type LinkHealthOptions struct {
+	Interval    time.Duration // Between runs
+	Recheck     time.Duration // Minimum time between checks of a link
+	BatchSize   int           // Links checked per run
+	Concurrency int           // Destinations checked at once
+	NotifyAfter int           // Consecutive failures before notifying; 0 never
+}
+
+// LinkHealthCheck periodically checks that link destinations still answer
+type LinkHealthCheck struct {
+	repo     LinkHealthStore
+	checker  *linkcheck.Checker
+	notifier linkcheck.Notifier
+	opts     LinkHealthOptions
+}
+
+// NewLinkHealthCheck creates a health check job; notifier may be nil
+func NewLinkHealthCheck(repo LinkHealthStore, checker *linkcheck.Checker, notifier linkcheck.Notifier, opts LinkHealthOptions) *LinkHealthCheck {
+	if notifier == nil {
+		notifier = linkcheck.NoopNotifier{}
+	}
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	return &LinkHealthCheck{repo: repo, checker: checker, notifier: notifier, opts: opts}
+}
+
+// Run executes the job immediately and then every interval until ctx is done
+func (j *LinkHealthCheck) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.RunOnce(ctx, time.Now()); err != nil {
+			fmt.Printf("Link health check failed: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce checks the next batch of links due for a check
+func (j *LinkHealthCheck) RunOnce(ctx context.Context, now time.Time) error {
+	links, err := j.repo.LinksDueForHealthCheck(ctx, now.Add(-j.opts.Recheck), now, j.opts.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	work := make(chan *model.URLMapping)
+	var wg sync.WaitGroup
+	for i := 0; i < j.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for link := range work {
+				if err := j.check(ctx, link); err != nil {
+					fmt.Printf("Failed to check link %s: %v\n", link.ShortCode, err)
+				}
+			}
+		}()
+	}
+	for i := range links {
+		if ctx.Err() != nil {
+			break
+		}
+		work <- &links[i]
+	}
+	close(work)
+	wg.Wait()
+	return ctx.Err()
+}
+
+// check checks one link's destination, records the result and notifies
+// the owner when the link has just reached NotifyAfter failures
+func (j *LinkHealthCheck) check(ctx context.Context, link *model.URLMapping) error {
+	result := j.checker.Check(ctx, link.OriginalURL)
+	if ctx.Err() != nil {
+		return nil // Shutting down; the timeout isn't the destination's fault
+	}
+	failures := healthFailures(link.HealthFailures, result.Status)
+	checkedAt := time.Now()
+
+	recorded, err := j.repo.RecordLinkHealth(ctx, link.ID, link.HealthCheckedAt,
+		result.Status, result.Code, failures, checkedAt)
+	if err != nil || !recorded || !shouldNotifyHealth(failures, j.opts.NotifyAfter) {
+		return err
+	}
+
+	createdBy, err := j.repo.LinkCreator(ctx, link.ShortCode)
+	if err != nil {
+		return err
+	}
+	return j.notifier.Notify(ctx, &model.LinkHealthEvent{
+		ShortCode:   link.ShortCode,
+		OriginalURL: link.OriginalURL,
+		OrgID:       link.OrgID,
+		CreatedBy:   createdBy,
+		Status:      result.Status,
+		Code:        result.Code,
+		Failures:    failures,
+		CheckedAt:   checkedAt,
+	})
+}
+
+// healthFailures returns the consecutive failure count after a check
+func healthFailures(previous int, status string) int {
+	if !model.HealthFailed(status) {
+		return 0
+	}
+	return previous + 1
+}
+
+// shouldNotifyHealth reports whether a link that has failed this many
+// checks in a row has just become worth notifying about
+func shouldNotifyHealth(failures, notifyAfter int) bool {
+	return notifyAfter > 0 && failures == notifyAfter
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthFailures(t *testing.T) {
+	assert.Equal(t, 1, healthFailures(0, model.HealthBroken))
+	assert.Equal(t, 3, healthFailures(2, model.HealthTimeout))
+	assert.Equal(t, 0, healthFailures(2, model.HealthOK))
+	assert.Equal(t, 0, healthFailures(2, model.HealthRestricted), "a refused checker isn't a dead page")
+}
+
+func TestShouldNotifyHealth(t *testing.T) {
+	assert.False(t, shouldNotifyHealth(2, 3))
+	assert.True(t, shouldNotifyHealth(3, 3))
+	assert.False(t, shouldNotifyHealth(4, 3), "owners are notified once per outage")
+	assert.False(t, shouldNotifyHealth(1, 0), "0 never notifies")
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// linkPurgeBatchSize bounds each DELETE of the purge job
+const linkPurgeBatchSize = 1000
+
+// LinkPurgeStore is the storage used by LinkPurge
+type LinkPurgeStore interface {
+	PurgeDeletedBefore(ctx context.Context, cutoff time.Time, batchSize int, purged func([]model.URLMapping)) (int64, error)
+}
+
+// LinkPurge periodically removes soft-deleted links for good once they've
+// been deleted for longer than the grace period; until then they can be
+// restored
+type LinkPurge struct {
+	repo     LinkPurgeStore
+	onDelete DeleteHook
+	after    time.Duration
+	interval time.Duration
+}
+
+// NewLinkPurge creates a purge job; onDelete (e.g. URLService.LinkRemoved)
+// is told about every purged link and may be nil
+func NewLinkPurge(repo LinkPurgeStore, onDelete DeleteHook, after, interval time.Duration) *LinkPurge {
+	return &LinkPurge{
+		repo:     repo,
+		onDelete: onDelete,
+		after:    after,
+		interval: interval,
+	}
+}
+
+// Run executes the job immediately and then every interval until ctx is done
+func (j *LinkPurge) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.RunOnce(ctx, time.Now()); err != nil {
+			fmt.Printf("Deleted link purge failed: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce purges links deleted before now minus the grace period
+func (j *LinkPurge) RunOnce(ctx context.Context, now time.Time) error {
+	cutoff := now.Add(-j.after)
+	purged, err := j.repo.PurgeDeletedBefore(ctx, cutoff, linkPurgeBatchSize, func(batch []model.URLMapping) {
+		if j.onDelete == nil {
+			return
+		}
+		for i := range batch {
+			j.onDelete(ctx, DeleteEvent{Mapping: &batch[i], Reason: DeleteReasonPurged})
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if purged > 0 {
+		fmt.Printf("Purged %d links deleted before %s\n", purged, cutoff.Format(time.RFC3339))
+	}
+	return nil
+}
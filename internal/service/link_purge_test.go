@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLinkPurgeRunOnce tests that links are purged once the grace period is over
+func TestLinkPurgeRunOnce(t *testing.T) {
+	store := &fakeLinkPurgeStore{deleted: []model.URLMapping{{ShortCode: "gone123"}}}
+	var events []DeleteEvent
+	job := NewLinkPurge(store, func(ctx context.Context, e DeleteEvent) { events = append(events, e) }, 30*24*time.Hour, time.Hour)
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, job.RunOnce(context.Background(), now))
+	assert.Equal(t, []time.Time{time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}, store.cutoffs)
+	require.Len(t, events, 1, "delete hooks hear about purged links")
+	assert.Equal(t, "gone123", events[0].Mapping.ShortCode)
+	assert.Equal(t, DeleteReasonPurged, events[0].Reason)
+
+	store.deleted = []model.URLMapping{{ShortCode: "gone456"}}
+	require.NoError(t, NewLinkPurge(store, nil, time.Hour, time.Hour).RunOnce(context.Background(), now), "hooks are optional")
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// ErrInvalidMetadata is returned for link metadata that can't be stored
+var ErrInvalidMetadata = errors.New("invalid metadata")
+
+// Bounds on a link's metadata
+const (
+	maxMetadataKeys  = 50
+	maxMetadataBytes = 8192 // Encoded as JSON
+)
+
+// metadataKeyPattern matches metadata keys; they end up in JSON paths of
+// list filters, so quotes and dots are not allowed
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// normalizeMetadata validates a link's metadata: a JSON object of at most
+// maxMetadataKeys keys, with any values, encoding to at most
+// maxMetadataBytes. Returns nil for empty metadata.
+func normalizeMetadata(metadata map[string]interface{}) (map[string]interface{}, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	if len(metadata) > maxMetadataKeys {
+		return nil, fmt.Errorf("%w: at most %d keys", ErrInvalidMetadata, maxMetadataKeys)
+	}
+	for key := range metadata {
+		if !metadataKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("%w: key %q must be 1-64 characters of A-Z a-z 0-9 _ -", ErrInvalidMetadata, key)
+		}
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+	}
+	if len(encoded) > maxMetadataBytes {
+		return nil, fmt.Errorf("%w: larger than %d bytes", ErrInvalidMetadata, maxMetadataBytes)
+	}
+	return metadata, nil
+}
+
+// sameMetadata reports whether two metadata objects hold the same values
+func sameMetadata(a, b map[string]interface{}) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	// Maps encode with sorted keys
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(encodedA) == string(encodedB)
+}
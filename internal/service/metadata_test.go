@@ -0,0 +1,44 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNormalizeMetadata tests the limits on link metadata
+func TestNormalizeMetadata(t *testing.T) {
+	metadata, err := normalizeMetadata(map[string]interface{}{
+		"title":       "Spring sale",
+		"campaign_id": 4711,
+		"owners":      []interface{}{"alice", "bob"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, metadata, 3)
+
+	metadata, err = normalizeMetadata(map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Nil(t, metadata)
+
+	for _, invalid := range []map[string]interface{}{
+		{"a.b": 1},
+		{`"`: 1},
+		{"": 1},
+		{"notes": strings.Repeat("x", maxMetadataBytes)},
+	} {
+		_, err := normalizeMetadata(invalid)
+		assert.ErrorIs(t, err, ErrInvalidMetadata, invalid)
+	}
+}
+
+// TestSameMetadata tests comparing metadata for link reuse
+func TestSameMetadata(t *testing.T) {
+	assert.True(t, sameMetadata(nil, map[string]interface{}{}))
+	assert.True(t, sameMetadata(
+		map[string]interface{}{"a": 1.0, "b": "x"},
+		map[string]interface{}{"b": "x", "a": 1.0},
+	))
+	assert.False(t, sameMetadata(map[string]interface{}{"a": 1.0}, map[string]interface{}{"a": 2.0}))
+	assert.False(t, sameMetadata(nil, map[string]interface{}{"a": 1.0}))
+}
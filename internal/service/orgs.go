@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ============================================================================
+// ORGANIZATIONS
+// ============================================================================
+// Links may belong to an organization, so a team sharing a branded domain
+// manages its links together. Members have one role each:
+// - viewer: read links, their stats, history and visit logs
+// - editor: also create, edit and clone links
+// - owner: also add, change and remove members
+//
+// Users are identified by the ID the authentication middleware puts in the
+// request context (middleware.UserIDContextKey). Links without an
+// organization can be read by anyone, as before organizations existed, but
+// only their creator may change them.
+// ============================================================================
+
+// Errors returned by organization operations
+var (
+	ErrOrgNotFound    = errors.New("organization not found")
+	ErrOrgExists      = errors.New("organization already exists")
+	ErrInvalidOrg     = errors.New("invalid organization")
+	ErrMemberNotFound = errors.New("user is not a member of the organization")
+	ErrLastOwner      = errors.New("organization must keep at least one owner")
+	ErrForbidden      = errors.New("permission denied")
+)
+
+// maxUserIDLength matches org_members.user_id
+const maxUserIDLength = 128
+
+// OrgStore keeps organizations and their members
+type OrgStore interface {
+	CreateOrg(ctx context.Context, org *model.Organization, owner string) error
+	GetOrg(ctx context.Context, id uint) (*model.Organization, error)
+	GetOrgByName(ctx context.Context, name string) (*model.Organization, error)
+	UserOrgs(ctx context.Context, userID string) ([]model.Organization, error)
+	OrgMembers(ctx context.Context, orgID uint) ([]model.OrgMember, error)
+	GetOrgMember(ctx context.Context, orgID uint, userID string) (*model.OrgMember, error)
+	SetOrgMember(ctx context.Context, member *model.OrgMember) error
+	RemoveOrgMember(ctx context.Context, orgID uint, userID string) (bool, error)
+	CountOrgOwners(ctx context.Context, orgID uint) (int64, error)
+	LinkOrgID(ctx context.Context, shortCode string) (orgID uint, found bool, err error)
+	LinkCreator(ctx context.Context, shortCode string) (string, error)
+}
+
+// OrgDetails is an organization with its members
+type OrgDetails struct {
+	*model.Organization
+	Members []model.OrgMember `json:"members"`
+}
+
+// ParseOrgRole returns the role named by s
+func ParseOrgRole(s string) (model.OrgRole, error) {
+	role := model.OrgRole(strings.ToLower(strings.TrimSpace(s)))
+	if !role.Valid() {
+		return "", fmt.Errorf("%w: role must be owner, editor or viewer", ErrInvalidOrg)
+	}
+	return role, nil
+}
+
+// CreateOrg creates an organization with a unique name, owned by userID
+func (s *URLService) CreateOrg(ctx context.Context, name, userID string) (*model.Organization, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 128 {
+		return nil, fmt.Errorf("%w: name must be 1-128 characters", ErrInvalidOrg)
+	}
+	if userID == "" {
+		return nil, ErrForbidden
+	}
+
+	existing, err := s.repo.GetOrgByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrOrgExists
+	}
+
+	org := &model.Organization{Name: name}
+	if err := s.repo.CreateOrg(ctx, org, userID); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// ListUserOrgs returns the organizations userID is a member of
+func (s *URLService) ListUserOrgs(ctx context.Context, userID string) ([]model.Organization, error) {
+	if userID == "" {
+		return []model.Organization{}, nil
+	}
+	return s.repo.UserOrgs(ctx, userID)
+}
+
+// GetOrg returns an organization and its members; userID must be a member
+func (s *URLService) GetOrg(ctx context.Context, orgID uint, userID string) (*OrgDetails, error) {
+	if err := s.AuthorizeOrg(ctx, orgID, userID, model.RoleViewer); err != nil {
+		return nil, err
+	}
+	org, err := s.repo.GetOrg(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		return nil, ErrOrgNotFound
+	}
+	members, err := s.repo.OrgMembers(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return &OrgDetails{Organization: org, Members: members}, nil
+}
+
+// SetOrgMember adds memberID to an organization or changes their role
+// userID must be an owner; the last owner can't demote themselves
+func (s *URLService) SetOrgMember(ctx context.Context, orgID uint, userID, memberID string, role model.OrgRole) (*model.OrgMember, error) {
+	memberID = strings.TrimSpace(memberID)
+	if memberID == "" || len(memberID) > maxUserIDLength {
+		return nil, fmt.Errorf("%w: user ID must be 1-%d characters", ErrInvalidOrg, maxUserIDLength)
+	}
+	if !role.Valid() {
+		return nil, fmt.Errorf("%w: role must be owner, editor or viewer", ErrInvalidOrg)
+	}
+	if err := s.AuthorizeOrg(ctx, orgID, userID, model.RoleOwner); err != nil {
+		return nil, err
+	}
+
+	if role != model.RoleOwner {
+		if err := s.checkKeepsOwner(ctx, orgID, memberID); err != nil {
+			return nil, err
+		}
+	}
+
+	member := &model.OrgMember{OrgID: orgID, UserID: memberID, Role: role}
+	if err := s.repo.SetOrgMember(ctx, member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveOrgMember removes memberID from an organization
+// userID must be an owner, or memberID themselves leaving
+func (s *URLService) RemoveOrgMember(ctx context.Context, orgID uint, userID, memberID string) error {
+	required := model.RoleOwner
+	if userID == memberID {
+		required = model.RoleViewer
+	}
+	if err := s.AuthorizeOrg(ctx, orgID, userID, required); err != nil {
+		return err
+	}
+	if err := s.checkKeepsOwner(ctx, orgID, memberID); err != nil {
+		return err
+	}
+
+	removed, err := s.repo.RemoveOrgMember(ctx, orgID, memberID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrMemberNotFound
+	}
+	return nil
+}
+
+// checkKeepsOwner returns ErrLastOwner if memberID is the only owner of an
+// organization, so they may neither leave nor be demoted
+func (s *URLService) checkKeepsOwner(ctx context.Context, orgID uint, memberID string) error {
+	member, err := s.repo.GetOrgMember(ctx, orgID, memberID)
+	if err != nil || member == nil || member.Role != model.RoleOwner {
+		return err
+	}
+	owners, err := s.repo.CountOrgOwners(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// AuthorizeOrg returns ErrForbidden unless userID has at least role required
+// in an organization
+// Unknown organizations are forbidden too, so callers can't probe which exist
+func (s *URLService) AuthorizeOrg(ctx context.Context, orgID uint, userID string, required model.OrgRole) error {
+	if userID == "" {
+		return ErrForbidden
+	}
+	member, err := s.repo.GetOrgMember(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if member == nil || !member.Role.Includes(required) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// AuthorizeLink returns ErrForbidden unless userID has at least role
+// required in the organization owning shortCode
+// Links without an organization, and unknown codes (left for the operation
+// itself to report), are allowed
+func (s *URLService) AuthorizeLink(ctx context.Context, shortCode, userID string, required model.OrgRole) error {
+	orgID, found, err := s.repo.LinkOrgID(ctx, shortCode)
+	if err != nil {
+		return err
+	}
+	if !found || orgID == 0 {
+		return nil
+	}
+	return s.AuthorizeOrg(ctx, orgID, userID, required)
+}
+
+// AuthorizeLinkEdit returns ErrForbidden unless userID may change shortCode:
+// an editor in the organization owning it or, for links without one, the
+// user who created it
+// Links created anonymously can't be changed. Unknown codes are allowed,
+// left for the operation itself to report.
+func (s *URLService) AuthorizeLinkEdit(ctx context.Context, shortCode, userID string) error {
+	if userID == "" {
+		return ErrForbidden
+	}
+	orgID, found, err := s.repo.LinkOrgID(ctx, shortCode)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return nil
+	}
+	if orgID != 0 {
+		return s.AuthorizeOrg(ctx, orgID, userID, model.RoleEditor)
+	}
+	creator, err := s.repo.LinkCreator(ctx, shortCode)
+	if err != nil {
+		return err
+	}
+	if creator != "user:"+userID {
+		return ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseOrgRole tests role name parsing
+func TestParseOrgRole(t *testing.T) {
+	role, err := ParseOrgRole(" Editor ")
+	assert.NoError(t, err)
+	assert.Equal(t, model.RoleEditor, role)
+
+	for _, name := range []string{"", "admin", "owners"} {
+		_, err := ParseOrgRole(name)
+		assert.ErrorIs(t, err, ErrInvalidOrg, name)
+	}
+}
+
+// TestOrgRoleIncludes tests that each role grants the ones below it
+func TestOrgRoleIncludes(t *testing.T) {
+	assert.True(t, model.RoleOwner.Includes(model.RoleEditor))
+	assert.True(t, model.RoleOwner.Includes(model.RoleViewer))
+	assert.True(t, model.RoleEditor.Includes(model.RoleEditor))
+	assert.True(t, model.RoleEditor.Includes(model.RoleViewer))
+	assert.False(t, model.RoleEditor.Includes(model.RoleOwner))
+	assert.False(t, model.RoleViewer.Includes(model.RoleEditor))
+	assert.False(t, model.OrgRole("admin").Includes(model.RoleViewer))
+}
+
+// TestAuthorizeLinkEdit tests that organization links need an editor and
+// other links their creator
+func TestAuthorizeLinkEdit(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepository(
+		&model.URLMapping{ShortCode: "mine"},
+		&model.URLMapping{ShortCode: "anon"},
+		&model.URLMapping{ShortCode: "team", OrgID: 7},
+	)
+	repo.revisions = []model.URLRevision{
+		{ShortCode: "mine", Revision: 1, ChangedBy: "user:alice"},
+		{ShortCode: "anon", Revision: 1, ChangedBy: "ip:203.0.113.7"},
+		{ShortCode: "team", Revision: 1, ChangedBy: "user:alice"},
+	}
+	repo.members = []model.OrgMember{
+		{OrgID: 7, UserID: "bob", Role: model.RoleViewer},
+		{OrgID: 7, UserID: "carol", Role: model.RoleEditor},
+	}
+	s := &URLService{repo: repo}
+
+	for _, tc := range []struct {
+		code, user string
+		allowed    bool
+	}{
+		{"mine", "alice", true},
+		{"mine", "bob", false},
+		{"mine", "", false},
+		{"anon", "alice", false},
+		{"team", "carol", true},
+		{"team", "bob", false},
+		{"team", "alice", false}, // Creator who isn't a member
+		{"missing", "alice", true},
+	} {
+		err := s.AuthorizeLinkEdit(ctx, tc.code, tc.user)
+		if tc.allowed {
+			assert.NoError(t, err, tc.code+" "+tc.user)
+		} else {
+			assert.ErrorIs(t, err, ErrForbidden, tc.code+" "+tc.user)
+		}
+	}
+}
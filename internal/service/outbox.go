@@ -0,0 +1,315 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ============================================================================
+// TRANSACTIONAL OUTBOX
+// ============================================================================
+// Without the outbox, creating a link inserts it into MySQL and then fills
+// the cache on a best-effort basis; a Redis error (or a crash in between)
+// is only logged. With outbox.enabled the side effects are written as rows
+// of outbox_events in the same transaction as the link (or an import's
+// batch of links), so they exist exactly when the link does:
+//
+//   cache_link  refresh the link's cache entry from the MySQL primary
+//   webhook     POST {"type":"link.created",...} to outbox.webhook_url
+//
+// The cache is still filled right away, as before; the cache_link event
+// repairs it if that failed. The OutboxDispatcher job, on every instance,
+// claims due events for a lease (claim_token/claimed_until), applies them
+// and marks them dispatched; failures are retried with exponential backoff
+// until max_attempts, then marked failed for an operator. Events of a
+// dispatcher that dies are claimed again when its lease runs out.
+//
+// Delivery is at least once, and each effect is idempotent, so each event
+// takes effect once: the cache is refreshed from MySQL (SetIfNewer keeps
+// newer entries), and webhooks carry the event ID in an Idempotency-Key
+// header for receivers to drop the rare redelivery (a dispatcher that lost
+// its lease mid-call). The bloom filter is in-process memory on each
+// instance and can't fail; an instance that crashes before adding a code
+// reloads the filter from MySQL on startup, so it needs no event.
+// ============================================================================
+
+const (
+	// outboxRetryBase is the delay before an event's first retry; it
+	// doubles with every further attempt, up to outboxRetryMax
+	outboxRetryBase = time.Second
+	outboxRetryMax  = time.Hour
+	// outboxPurgeInterval is how often dispatched and failed events past
+	// the retention period are removed
+	outboxPurgeInterval = time.Hour
+	// outboxPurgeBatchSize bounds each DELETE of the purge
+	outboxPurgeBatchSize = 1000
+	// LinkCreatedEventType is the type of the webhook payload of new links
+	LinkCreatedEventType = "link.created"
+)
+
+// OutboxStore writes links with their outbox events and hands the events to
+// the dispatcher
+type OutboxStore interface {
+	Create(ctx context.Context, mapping *model.URLMapping) error
+	CreateBatch(ctx context.Context, mappings []*model.URLMapping) error
+	CreateWithOutbox(ctx context.Context, mapping *model.URLMapping, events []*model.OutboxEvent) error
+	CreateBatchWithOutbox(ctx context.Context, mappings []*model.URLMapping, events []*model.OutboxEvent) error
+	GetByShortCodeFromPrimary(ctx context.Context, shortCode string) (*model.URLMapping, error)
+	ClaimOutboxEvents(ctx context.Context, token string, now time.Time, lease time.Duration, limit int) ([]model.OutboxEvent, error)
+	CompleteOutboxEvent(ctx context.Context, id uint64, token string, now time.Time) (bool, error)
+	RetryOutboxEvent(ctx context.Context, id uint64, token string, attempts int, next time.Time, lastErr string, failed bool) error
+	PurgeOutboxEvents(ctx context.Context, cutoff time.Time, batchSize int) (int64, error)
+}
+
+// OutboxOptions are the settings of the outbox dispatcher
+type OutboxOptions struct {
+	Interval    time.Duration // Between polls for due events
+	BatchSize   int           // Events claimed per poll
+	MaxAttempts int           // Attempts before an event is marked failed
+	Lease       time.Duration // How long a claim holds events
+	Retention   time.Duration // Dispatched and failed events are kept this long; 0 keeps them
+}
+
+// SetOutbox makes link creation record its side effects in the outbox;
+// webhook adds a webhook event per link
+func (s *URLService) SetOutbox(enabled, webhook bool) {
+	s.outbox = enabled
+	s.outboxWebhook = webhook
+}
+
+// storeMapping inserts a new link, with its outbox events when the outbox
+// is enabled
+func (s *URLService) storeMapping(ctx context.Context, mapping *model.URLMapping) error {
+	if !s.outbox {
+		return s.repo.Create(ctx, mapping)
+	}
+	events, err := s.outboxEvents(mapping, time.Now())
+	if err != nil {
+		return err
+	}
+	return s.repo.CreateWithOutbox(ctx, mapping, events)
+}
+
+// storeMappings inserts new links in one transaction, with their outbox
+// events when the outbox is enabled
+func (s *URLService) storeMappings(ctx context.Context, mappings []*model.URLMapping) error {
+	if !s.outbox {
+		return s.repo.CreateBatch(ctx, mappings)
+	}
+	now := time.Now()
+	var events []*model.OutboxEvent
+	for _, mapping := range mappings {
+		mappingEvents, err := s.outboxEvents(mapping, now)
+		if err != nil {
+			return err
+		}
+		events = append(events, mappingEvents...)
+	}
+	return s.repo.CreateBatchWithOutbox(ctx, mappings, events)
+}
+
+// outboxEvents returns the side effects of creating mapping
+func (s *URLService) outboxEvents(mapping *model.URLMapping, now time.Time) ([]*model.OutboxEvent, error) {
+	var events []*model.OutboxEvent
+	if !mapping.NoCache {
+		events = append(events, &model.OutboxEvent{Kind: model.OutboxCacheLink, ShortCode: mapping.ShortCode, NextAttemptAt: now})
+	}
+	if s.outboxWebhook {
+		payload, err := json.Marshal(model.LinkCreatedEvent{
+			Type:        LinkCreatedEventType,
+			ShortCode:   mapping.ShortCode,
+			OriginalURL: mapping.OriginalURL,
+			Domain:      mapping.Domain,
+			OrgID:       mapping.OrgID,
+			ExpiredAt:   mapping.ExpiredAt,
+			CreatedAt:   now.UTC(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode link event: %w", err)
+		}
+		events = append(events, &model.OutboxEvent{Kind: model.OutboxWebhook, ShortCode: mapping.ShortCode, Payload: string(payload), NextAttemptAt: now})
+	}
+	return events, nil
+}
+
+// OutboxWebhook POSTs webhook events as JSON to a URL
+type OutboxWebhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewOutboxWebhook creates a webhook sender posting to url, giving up on a
+// request after timeout
+func NewOutboxWebhook(url string, timeout time.Duration) *OutboxWebhook {
+	return &OutboxWebhook{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Send posts an event's payload; receivers should drop repeated
+// Idempotency-Keys
+func (w *OutboxWebhook) Send(ctx context.Context, event *model.OutboxEvent) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader([]byte(event.Payload)))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", strconv.FormatUint(event.ID, 10))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call outbox webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("outbox webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// OutboxDispatcher applies the side effects recorded in the outbox
+type OutboxDispatcher struct {
+	service *URLService
+	webhook *OutboxWebhook
+	opts    OutboxOptions
+
+	// now returns the current time (overridable in tests)
+	now func() time.Time
+}
+
+// NewOutboxDispatcher creates the dispatcher job; webhook may be nil, in
+// which case webhook events are dropped as dispatched
+func NewOutboxDispatcher(service *URLService, webhook *OutboxWebhook, opts OutboxOptions) *OutboxDispatcher {
+	return &OutboxDispatcher{service: service, webhook: webhook, opts: opts, now: time.Now}
+}
+
+// Run dispatches due events every interval, and purges old ones every
+// outboxPurgeInterval, until ctx is done
+func (d *OutboxDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.opts.Interval)
+	defer ticker.Stop()
+	var lastPurge time.Time
+
+	for {
+		// Keep going while whole batches come back
+		for {
+			n, err := d.RunOnce(ctx)
+			if err != nil {
+				fmt.Printf("Outbox dispatch failed: %v\n", err)
+			}
+			if err != nil || n < d.opts.BatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+		if d.opts.Retention > 0 && d.now().Sub(lastPurge) >= outboxPurgeInterval {
+			d.purge(ctx)
+			lastPurge = d.now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce claims a batch of due events and applies them; it returns how
+// many were claimed
+func (d *OutboxDispatcher) RunOnce(ctx context.Context) (int, error) {
+	repo := d.service.repo
+	token := newJobID()
+	claimedAt := d.now()
+	events, err := repo.ClaimOutboxEvents(ctx, token, claimedAt, d.opts.Lease, d.opts.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range events {
+		event := &events[i]
+		// Leave the rest for the next claim rather than run past the lease
+		if d.now().Sub(claimedAt) >= d.opts.Lease/2 {
+			break
+		}
+
+		applyErr := d.apply(ctx, event)
+		if applyErr == nil {
+			completed, err := repo.CompleteOutboxEvent(ctx, event.ID, token, d.now())
+			if err != nil {
+				return len(events), err
+			}
+			if !completed {
+				fmt.Printf("Outbox event %d was claimed by another dispatcher before it completed\n", event.ID)
+			}
+			continue
+		}
+
+		attempts := event.Attempts + 1
+		failed := attempts >= d.opts.MaxAttempts
+		if failed {
+			fmt.Printf("Giving up on outbox event %d (%s %s) after %d attempts: %v\n",
+				event.ID, event.Kind, event.ShortCode, attempts, applyErr)
+		}
+		next := d.now().Add(outboxBackoff(attempts))
+		if err := repo.RetryOutboxEvent(ctx, event.ID, token, attempts, next, applyErr.Error(), failed); err != nil {
+			return len(events), err
+		}
+	}
+	return len(events), nil
+}
+
+// apply runs one event's side effect
+func (d *OutboxDispatcher) apply(ctx context.Context, event *model.OutboxEvent) error {
+	switch event.Kind {
+	case model.OutboxCacheLink:
+		s := d.service
+		// A replica may not have the link yet, which would read as deleted
+		mapping, err := s.repo.GetByShortCodeFromPrimary(ctx, event.ShortCode)
+		if err != nil {
+			return err
+		}
+		if mapping == nil {
+			// Deleted (or recycled) since; don't bring it back
+			return s.cache.Delete(ctx, event.ShortCode)
+		}
+		return s.cacheMapping(ctx, mapping)
+	case model.OutboxWebhook:
+		if d.webhook == nil {
+			return nil
+		}
+		return d.webhook.Send(ctx, event)
+	default:
+		return fmt.Errorf("unknown outbox event kind %q", event.Kind)
+	}
+}
+
+// purge removes dispatched and failed events past the retention period
+func (d *OutboxDispatcher) purge(ctx context.Context) {
+	cutoff := d.now().Add(-d.opts.Retention)
+	purged, err := d.service.repo.PurgeOutboxEvents(ctx, cutoff, outboxPurgeBatchSize)
+	if err != nil {
+		fmt.Printf("Outbox purge failed: %v\n", err)
+		return
+	}
+	if purged > 0 {
+		fmt.Printf("Purged %d outbox events created before %s\n", purged, cutoff.Format(time.RFC3339))
+	}
+}
+
+// outboxBackoff is the delay before retrying an event that failed attempts
+// times
+func outboxBackoff(attempts int) time.Duration {
+	delay := outboxRetryBase
+	for i := 1; i < attempts && delay < outboxRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > outboxRetryMax {
+		delay = outboxRetryMax
+	}
+	return delay
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOutboxBackoff tests the retry delays
+func TestOutboxBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, outboxBackoff(1))
+	assert.Equal(t, 8*time.Second, outboxBackoff(4))
+	assert.Equal(t, time.Hour, outboxBackoff(30))
+}
+
+// TestOutboxDispatch tests that link creation records its side effects with
+// the link and that the dispatcher applies them, retrying failures
+func TestOutboxDispatch(t *testing.T) {
+	var mu sync.Mutex
+	var keys, bodies []string
+	failing := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		bodies = append(bodies, string(body))
+	}))
+	defer server.Close()
+
+	repo, cache := newFakeRepository(), newFakeCache()
+	s := NewURLService(repo, cache, newFakeFilter())
+	s.SetOutbox(true, true)
+	ctx := context.Background()
+
+	mapping, err := s.CreateShortURL(ctx, "https://example.com/page", "", nil, model.LinkOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, repo.called("Create"))
+	assert.Equal(t, 1, repo.called("CreateWithOutbox"), "written in one transaction")
+	require.Len(t, repo.outbox, 2)
+	assert.Equal(t, model.OutboxCacheLink, repo.outbox[0].Kind)
+	assert.Equal(t, model.OutboxWebhook, repo.outbox[1].Kind)
+
+	// The cache write on creation was lost, and replicas haven't seen the
+	// link yet
+	require.NoError(t, cache.Delete(ctx, mapping.ShortCode))
+	repo.replicaLag = true
+
+	now := time.Now()
+	d := NewOutboxDispatcher(s, NewOutboxWebhook(server.URL, time.Second), OutboxOptions{
+		BatchSize: 10, MaxAttempts: 3, Lease: time.Minute,
+	})
+	d.now = func() time.Time { return now }
+
+	n, err := d.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.NotNil(t, cache.links[mapping.ShortCode], "the cache is repaired")
+	assert.NotNil(t, repo.outbox[0].DispatchedAt)
+	assert.Nil(t, repo.outbox[1].DispatchedAt)
+	assert.Equal(t, 1, repo.outbox[1].Attempts)
+	assert.Contains(t, repo.outbox[1].LastError, "503")
+
+	n, err = d.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "not due before its backoff")
+
+	mu.Lock()
+	failing = false
+	mu.Unlock()
+	now = now.Add(outboxBackoff(1))
+	n, err = d.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.NotNil(t, repo.outbox[1].DispatchedAt)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "2", keys[0], "the event ID lets receivers drop redeliveries")
+	assert.Contains(t, bodies[0], `"type":"link.created"`)
+	assert.Contains(t, bodies[0], `"short_code":"`+mapping.ShortCode+`"`)
+
+	n, err = d.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "dispatched events aren't claimed again")
+}
+
+// TestOutboxImport tests that imported links get their outbox events in
+// the transaction that inserts them
+func TestOutboxImport(t *testing.T) {
+	repo := newFakeRepository()
+	s := NewURLService(repo, newFakeCache(), newFakeFilter())
+	s.SetOutbox(true, true)
+
+	csv := "https://example.com/one,one111\nhttps://example.com/two,two222\n"
+	report, err := s.ImportURLs(context.Background(), strings.NewReader(csv), "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Created)
+	assert.Equal(t, 1, repo.called("CreateBatchWithOutbox"))
+
+	kinds := map[string][]string{}
+	for _, event := range repo.outbox {
+		kinds[event.ShortCode] = append(kinds[event.ShortCode], event.Kind)
+	}
+	want := []string{model.OutboxCacheLink, model.OutboxWebhook}
+	assert.Equal(t, map[string][]string{"one111": want, "two222": want}, kinds)
+}
+
+// TestOutboxGivesUp tests that events are marked failed after max attempts
+func TestOutboxGivesUp(t *testing.T) {
+	repo := newFakeRepository()
+	s := NewURLService(repo, newFakeCache(), newFakeFilter())
+	repo.outbox = []model.OutboxEvent{{ID: 1, Kind: "unknown", ShortCode: "abc123", NextAttemptAt: time.Now()}}
+
+	d := NewOutboxDispatcher(s, nil, OutboxOptions{BatchSize: 10, MaxAttempts: 1, Lease: time.Minute})
+	_, err := d.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.NotNil(t, repo.outbox[0].FailedAt)
+	assert.Contains(t, repo.outbox[0].LastError, "unknown outbox event kind")
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"net/netip"
+
+	"github.com/Monthlyaway/short-link/internal/events"
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ============================================================================
+// PRIVACY
+// ============================================================================
+// Deployments that must minimize personal data can:
+// - store visitor IPs truncated (IPv4 to /24, IPv6 to /48) or as salted
+//   hashes instead of in full (privacy.ip_storage)
+// - leave visits sent with DNT: 1 or Sec-GPC: 1 out of the visit logs
+//   and click events (privacy.respect_dnt)
+// - turn analytics off per link (no_analytics)
+//
+// Untracked visits still increment the link's counters, so basic counts
+// stay available; nothing else about the visitor is kept.
+// ============================================================================
+
+// Visitor IP storage modes
+const (
+	IPStorageFull     = "full"
+	IPStorageTruncate = "truncate"
+	IPStorageHash     = "hash"
+)
+
+// storedIPHashLength is how many hex digits of an IP hash are kept, to fit
+// the visit_logs.ip column
+const storedIPHashLength = 40
+
+// Privacy is what the service keeps about visitors
+type Privacy struct {
+	IPStorage  string // IPStorageFull (default), IPStorageTruncate or IPStorageHash
+	IPHashSalt string // Salt of IPStorageHash
+	RespectDNT bool   // Don't log visits that ask not to be tracked
+}
+
+// SetPrivacy sets what visit logs keep about visitors
+func (s *URLService) SetPrivacy(privacy Privacy) {
+	s.privacy = privacy
+}
+
+// tracked reports whether a visit may be logged with visitor details
+func (s *URLService) tracked(mapping *model.URLMapping, visitor model.Visitor) bool {
+	return !mapping.NoAnalytics && !(s.privacy.RespectDNT && visitor.DoNotTrack)
+}
+
+// storedIP returns ip as the visit log keeps it
+func (p Privacy) storedIP(ip string) string {
+	switch p.IPStorage {
+	case IPStorageTruncate:
+		return truncateIP(ip)
+	case IPStorageHash:
+		return events.HashIP(ip, p.IPHashSalt)[:storedIPHashLength]
+	}
+	return ip
+}
+
+// truncateIP zeroes the host part of an IP: IPv4 to /24, IPv6 to /48
+// Unparseable addresses are dropped
+func truncateIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap().WithZone("")
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ""
+	}
+	return prefix.Addr().String()
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Monthlyaway/short-link/internal/cache"
+	"github.com/Monthlyaway/short-link/internal/events"
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStoredIP tests the IP storage modes
+func TestStoredIP(t *testing.T) {
+	full := Privacy{}
+	assert.Equal(t, "203.0.113.77", full.storedIP("203.0.113.77"))
+
+	truncate := Privacy{IPStorage: IPStorageTruncate}
+	assert.Equal(t, "203.0.113.0", truncate.storedIP("203.0.113.77"))
+	assert.Equal(t, "203.0.113.0", truncate.storedIP("::ffff:203.0.113.77"), "IPv4-mapped addresses stay IPv4")
+	assert.Equal(t, "2001:db8:85a3::", truncate.storedIP("2001:db8:85a3:8d3:1319:8a2e:370:7348"))
+	assert.Equal(t, "", truncate.storedIP("not-an-ip"))
+
+	hash := Privacy{IPStorage: IPStorageHash, IPHashSalt: "pepper"}
+	stored := hash.storedIP("203.0.113.77")
+	assert.Len(t, stored, storedIPHashLength, "fits visit_logs.ip")
+	assert.Equal(t, events.HashIP("203.0.113.77", "pepper")[:storedIPHashLength], stored)
+	assert.NotEqual(t, stored, Privacy{IPStorage: IPStorageHash, IPHashSalt: "salt"}.storedIP("203.0.113.77"))
+}
+
+// TestTracked tests which visits are logged
+func TestTracked(t *testing.T) {
+	s := NewURLService(newFakeRepository(), newFakeCache(), newFakeFilter())
+	link := &model.URLMapping{ShortCode: "abc123"}
+	dnt := model.Visitor{IP: "203.0.113.7", DoNotTrack: true}
+
+	assert.True(t, s.tracked(link, dnt), "DNT is ignored unless respect_dnt is set")
+	s.SetPrivacy(Privacy{RespectDNT: true})
+	assert.False(t, s.tracked(link, dnt))
+	assert.True(t, s.tracked(link, model.Visitor{IP: "203.0.113.7"}))
+	assert.False(t, s.tracked(&model.URLMapping{ShortCode: "xyz789", NoAnalytics: true}, model.Visitor{}))
+}
+
+// TestWriteUntrackedVisit tests that untracked visits are counted but not
+// logged
+func TestWriteUntrackedVisit(t *testing.T) {
+	repo := newFakeRepository()
+	s := NewURLService(repo, newFakeCache(), newFakeFilter())
+
+	require.NoError(t, s.writeVisit(context.Background(), &cache.PendingVisit{Log: model.VisitLog{ShortCode: "abc123"}, Untracked: true}))
+	assert.Equal(t, 1, repo.called("IncrementVisitCount"))
+	assert.Equal(t, 0, repo.called("CreateVisitLog"))
+}
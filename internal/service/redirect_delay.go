@@ -0,0 +1,20 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidRedirectDelay is returned for an out-of-range redirect delay
+var ErrInvalidRedirectDelay = errors.New("invalid redirect delay")
+
+// MaxRedirectDelay is the longest countdown a link may show, in seconds
+const MaxRedirectDelay = 60
+
+// validateRedirectDelay checks a per-link redirect delay
+func validateRedirectDelay(seconds int) error {
+	if seconds < 0 || seconds > MaxRedirectDelay {
+		return fmt.Errorf("%w: redirect_delay must be between 0 and %d seconds", ErrInvalidRedirectDelay, MaxRedirectDelay)
+	}
+	return nil
+}
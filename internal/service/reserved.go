@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ============================================================================
+// Reserved Short Codes
+// ============================================================================
+// Short codes share the URL space with the service's own routes, so a link
+// at /api or /healthz would shadow (or be shadowed by) them. Codes are
+// also checked against a word blocklist so generated codes never spell
+// something offensive.
+//
+// Sources, merged:
+// - DefaultReservedCodes (route and operational names, always reserved)
+// - config: short_codes.reserved (exact) and short_codes.blocked_words (substring)
+// - the reserved_codes table (reloaded on SIGHUP)
+//
+// Matching is case-insensitive.
+// ============================================================================
+
+// DefaultReservedCodes are always reserved
+var DefaultReservedCodes = []string{
+	"api", "admin", "health", "healthz", "readyz", "metrics", "debug",
+	"docs", "swagger", "static", "assets", "favicon.ico", "robots.txt",
+	"login", "logout", "signup", "dashboard", "www",
+}
+
+// ReservedCodeStore lists the reserved codes added by admins
+type ReservedCodeStore interface {
+	ListReservedCodes(ctx context.Context) ([]model.ReservedCode, error)
+}
+
+// ReservedCodes is a concurrency-safe set of reserved codes and blocked words
+type ReservedCodes struct {
+	mu    sync.RWMutex
+	exact map[string]bool
+	words []string
+}
+
+// NewReservedCodes creates a set from exact codes and substring-blocked words
+func NewReservedCodes(exact, words []string) *ReservedCodes {
+	r := &ReservedCodes{}
+	r.Replace(exact, words)
+	return r
+}
+
+// Replace swaps in new lists atomically
+func (r *ReservedCodes) Replace(exact, words []string) {
+	exactSet := make(map[string]bool, len(exact))
+	for _, code := range exact {
+		if code = strings.ToLower(strings.TrimSpace(code)); code != "" {
+			exactSet[code] = true
+		}
+	}
+	lowered := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			lowered = append(lowered, word)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exact = exactSet
+	r.words = lowered
+}
+
+// Check returns ErrReservedShortCode if code is reserved or contains a blocked word
+func (r *ReservedCodes) Check(code string) error {
+	lower := strings.ToLower(code)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.exact[lower] {
+		return fmt.Errorf("%w: %q", ErrReservedShortCode, code)
+	}
+	for _, word := range r.words {
+		if strings.Contains(lower, word) {
+			return fmt.Errorf("%w: %q contains a blocked word", ErrReservedShortCode, code)
+		}
+	}
+	return nil
+}
+
+// SetReservedCodes sets the configured reserved codes and blocked words
+// Call ReloadReservedCodes afterwards to merge in the database list
+func (s *URLService) SetReservedCodes(reserved, blockedWords []string) {
+	s.configReserved = reserved
+	s.configBlockedWords = blockedWords
+	s.reserved.Replace(append(append([]string{}, DefaultReservedCodes...), reserved...), blockedWords)
+}
+
+// ReloadReservedCodes rebuilds the reserved set from the defaults, config
+// and the reserved_codes table
+func (s *URLService) ReloadReservedCodes(ctx context.Context) error {
+	rows, err := s.repo.ListReservedCodes(ctx)
+	if err != nil {
+		return err
+	}
+
+	exact := append(append([]string{}, DefaultReservedCodes...), s.configReserved...)
+	words := append([]string{}, s.configBlockedWords...)
+	for _, row := range rows {
+		if row.Substring {
+			words = append(words, row.Code)
+		} else {
+			exact = append(exact, row.Code)
+		}
+	}
+	s.reserved.Replace(exact, words)
+	return nil
+}
+
+// CheckShortCode returns ErrReservedShortCode if code may not be used
+func (s *URLService) CheckShortCode(code string) error {
+	return s.reserved.Check(code)
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReservedCodes tests exact and substring matching
+func TestReservedCodes(t *testing.T) {
+	r := NewReservedCodes(append(DefaultReservedCodes, "promo"), []string{"Bad"})
+
+	assert.True(t, errors.Is(r.Check("api"), ErrReservedShortCode))
+	assert.True(t, errors.Is(r.Check("HealthZ"), ErrReservedShortCode))
+	assert.True(t, errors.Is(r.Check("promo"), ErrReservedShortCode))
+	assert.True(t, errors.Is(r.Check("xxBADxx"), ErrReservedShortCode))
+
+	assert.NoError(t, r.Check("apiX"))
+	assert.NoError(t, r.Check("aB3xY9"))
+
+	// Replace swaps both lists
+	r.Replace([]string{"new"}, nil)
+	assert.NoError(t, r.Check("api"))
+	assert.NoError(t, r.Check("bad"))
+	assert.Error(t, r.Check("NEW"))
+}
@@ -0,0 +1,323 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ============================================================================
+// Link History
+// ============================================================================
+// Each link keeps a list of revisions in url_mapping_revisions:
+// - revision 1 records how the link was made (create, import or clone)
+// - every later change of destination or expiry adds the next revision
+//   in the same transaction as the change itself
+//
+// Revisions name the caller that made the change (see WithActor), so a link
+// whose destination changed after it was widely shared can be audited.
+// Links created before history was introduced start at their first edit,
+// whose previous_url still shows the old destination.
+// ============================================================================
+
+// RevisionStore edits links and keeps their history
+type RevisionStore interface {
+	GetByShortCode(ctx context.Context, shortCode string) (*model.URLMapping, error)
+	LinkOrgID(ctx context.Context, shortCode string) (orgID uint, found bool, err error)
+	LoadTags(ctx context.Context, mapping *model.URLMapping) error
+	UpdateWithRevision(ctx context.Context, shortCode string, update func(*model.URLMapping) *model.URLRevision) (*model.URLMapping, error)
+	AddRevisions(ctx context.Context, revisions []model.URLRevision) error
+	ListRevisions(ctx context.Context, shortCode string) ([]model.URLRevision, error)
+}
+
+// actorKey is the context key for the caller making a change
+type actorKey struct{}
+
+// systemActor is recorded when no caller is known (e.g. jobs)
+const systemActor = "system"
+
+// WithActor returns a context that attributes changes to actor
+// e.g. "user:42", "api_key:<fingerprint>" or "ip:203.0.113.7"
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// actorFrom returns the caller recorded by WithActor
+func actorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return systemActor
+}
+
+// recordFirstRevisions records revision 1 of new links
+// History is best effort here: the links already exist, so a failure is
+// logged rather than returned
+func (s *URLService) recordFirstRevisions(ctx context.Context, action, clonedFrom string, mappings ...*model.URLMapping) {
+	actor := actorFrom(ctx)
+	revisions := make([]model.URLRevision, 0, len(mappings))
+	for _, mapping := range mappings {
+		revisions = append(revisions, model.URLRevision{
+			ShortCode:   mapping.ShortCode,
+			Revision:    1,
+			Action:      action,
+			OriginalURL: mapping.OriginalURL,
+			ExpiredAt:   mapping.ExpiredAt,
+			ClonedFrom:  clonedFrom,
+			ChangedBy:   actor,
+		})
+	}
+	if err := s.repo.AddRevisions(ctx, revisions); err != nil {
+		fmt.Printf("Failed to record link history: %v\n", err)
+	}
+}
+
+// UpdateURL changes the destination, expiry, cache settings, access rules
+// and/or other settings of a link and records the change in its history
+// Returns the link unchanged (and records nothing) if update changes nothing
+func (s *URLService) UpdateURL(ctx context.Context, shortCode string, update model.URLUpdate) (*model.URLMapping, error) {
+	var displayURL string
+	if update.OriginalURL != nil {
+		canonical, display, err := canonicalURL(*update.OriginalURL)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.validateURL(ctx, canonical); err != nil {
+			return nil, err
+		}
+		update.OriginalURL, displayURL = &canonical, display
+	}
+	if update.CacheTTL != nil || update.NoCache != nil {
+		requested := model.CachePolicy{}
+		if update.CacheTTL != nil {
+			requested.TTL = *update.CacheTTL
+		}
+		if update.NoCache != nil {
+			requested.NoCache = *update.NoCache
+		}
+		if err := validateCachePolicy(requested); err != nil {
+			return nil, err
+		}
+	}
+	if update.VisitDedupMinutes != nil {
+		if err := validateVisitDedup(*update.VisitDedupMinutes); err != nil {
+			return nil, err
+		}
+	}
+	if update.RedirectDelay != nil {
+		if err := validateRedirectDelay(*update.RedirectDelay); err != nil {
+			return nil, err
+		}
+	}
+	if update.RequireSignature != nil && *update.RequireSignature && s.signing.Secret == "" {
+		return nil, ErrSigningDisabled
+	}
+	var rules *model.AccessRules
+	if update.AccessRules != nil {
+		var err error
+		if rules, err = normalizeAccessRules(update.AccessRules); err != nil {
+			return nil, err
+		}
+	}
+	var deepLinks *model.DeepLinks
+	if update.DeepLinks != nil {
+		var err error
+		if deepLinks, err = normalizeDeepLinks(update.DeepLinks); err != nil {
+			return nil, err
+		}
+	}
+	var metadata map[string]interface{}
+	if update.Metadata != nil {
+		var err error
+		if metadata, err = normalizeMetadata(update.Metadata); err != nil {
+			return nil, err
+		}
+	}
+
+	var visibility string
+	if update.Visibility != nil {
+		// A link's organization never changes, so it can be checked up front
+		orgID, found, err := s.repo.LinkOrgID(ctx, shortCode)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, ErrShortCodeNotFound
+		}
+		if visibility, err = normalizeVisibility(*update.Visibility, orgID); err != nil {
+			return nil, err
+		}
+	}
+
+	actor := actorFrom(ctx)
+	mapping, err := s.repo.UpdateWithRevision(ctx, shortCode, func(mapping *model.URLMapping) *model.URLRevision {
+		previousURL := mapping.OriginalURL
+		changed := false
+		if update.OriginalURL != nil && *update.OriginalURL != mapping.OriginalURL {
+			mapping.OriginalURL = *update.OriginalURL
+			mapping.DisplayURL = displayURL
+			mapping.DestinationHost = destinationHost(mapping.OriginalURL)
+			mapping.URLHash = model.HashURL(mapping.OriginalURL)
+			changed = true
+		}
+		if update.ClearExpiry {
+			changed = changed || mapping.ExpiredAt != nil
+			mapping.ExpiredAt = nil
+		} else if update.ExpiredAt != nil && !sameTime(mapping.ExpiredAt, update.ExpiredAt) {
+			mapping.ExpiredAt = update.ExpiredAt
+			changed = true
+		}
+		if policy := updatedCachePolicy(mapping.CachePolicy(), update); policy != mapping.CachePolicy() {
+			mapping.CacheTTL = policy.TTL
+			mapping.NoCache = policy.NoCache
+			changed = true
+		}
+		if update.AccessRules != nil && !mapping.AccessRules.Equal(rules) {
+			mapping.AccessRules = rules
+			changed = true
+		}
+		if update.DeepLinks != nil && !mapping.DeepLinks.Equal(deepLinks) {
+			mapping.DeepLinks = deepLinks
+			changed = true
+		}
+		if update.Metadata != nil && !sameMetadata(mapping.Metadata, metadata) {
+			mapping.Metadata = metadata
+			changed = true
+		}
+		if update.VisitDedupMinutes != nil && *update.VisitDedupMinutes != mapping.VisitDedupMinutes {
+			mapping.VisitDedupMinutes = *update.VisitDedupMinutes
+			changed = true
+		}
+		if update.NoAnalytics != nil && *update.NoAnalytics != mapping.NoAnalytics {
+			mapping.NoAnalytics = *update.NoAnalytics
+			changed = true
+		}
+		if update.Visibility != nil && visibility != mapping.Visibility {
+			mapping.Visibility = visibility
+			changed = true
+		}
+		if update.RedirectDelay != nil && *update.RedirectDelay != mapping.RedirectDelay {
+			mapping.RedirectDelay = *update.RedirectDelay
+			changed = true
+		}
+		if update.RequireSignature != nil && *update.RequireSignature != mapping.RequireSignature {
+			mapping.RequireSignature = *update.RequireSignature
+			changed = true
+		}
+		if !changed {
+			return nil
+		}
+
+		revision := &model.URLRevision{
+			Action:      model.RevisionUpdate,
+			OriginalURL: mapping.OriginalURL,
+			ExpiredAt:   mapping.ExpiredAt,
+			ChangedBy:   actor,
+		}
+		if previousURL != mapping.OriginalURL {
+			revision.PreviousURL = previousURL
+		}
+		return revision
+	})
+	if err != nil {
+		return nil, err
+	}
+	if mapping == nil {
+		return nil, ErrShortCodeNotFound
+	}
+
+	// Redirects must not keep serving the old destination or settings
+	if err := s.cache.Delete(ctx, shortCode); err != nil {
+		fmt.Printf("Failed to delete cache: %v\n", err)
+	}
+	if err := s.repo.LoadTags(ctx, mapping); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// CloneURL creates a new link with the destination, tags, cache settings,
+// access rules and organization of an existing one, on domain (empty for the source's domain) with the
+// given expiry
+func (s *URLService) CloneURL(ctx context.Context, shortCode, domain string, expiredAt *time.Time) (*model.URLMapping, error) {
+	source, err := s.GetURLInfo(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	host := source.Domain
+	if domain != "" {
+		serving, err := s.resolveDomain(domain)
+		if err != nil {
+			return nil, err
+		}
+		host = serving.Host
+	}
+
+	clone := &model.URLMapping{
+		OriginalURL:       source.OriginalURL,
+		DisplayURL:        source.DisplayURL,
+		Domain:            host,
+		OrgID:             source.OrgID,
+		ExpiredAt:         expiredAt,
+		CacheTTL:          source.CacheTTL,
+		NoCache:           source.NoCache,
+		AccessRules:       source.AccessRules,
+		DeepLinks:         source.DeepLinks,
+		Metadata:          source.Metadata,
+		VisitDedupMinutes: source.VisitDedupMinutes,
+		NoAnalytics:       source.NoAnalytics,
+		Visibility:        source.Visibility,
+		RedirectDelay:     source.RedirectDelay,
+		RequireSignature:  source.RequireSignature,
+		Warning:           source.Warning, // Cloning must not get around an unsafe-link warning
+	}
+	revision := model.URLRevision{Action: model.RevisionClone, ClonedFrom: source.ShortCode}
+	if err := s.createMapping(ctx, clone, revision); err != nil {
+		return nil, err
+	}
+	if err := s.AddTags(ctx, clone, source.TagNames()); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+// GetURLHistory returns the revisions of a link, newest first
+func (s *URLService) GetURLHistory(ctx context.Context, shortCode string) ([]model.URLRevision, error) {
+	mapping, err := s.repo.GetByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if mapping == nil {
+		return nil, ErrShortCodeNotFound
+	}
+	return s.repo.ListRevisions(ctx, shortCode)
+}
+
+// updatedCachePolicy applies the cache fields of update to policy
+// The two settings exclude each other, so setting one can reset the other
+func updatedCachePolicy(policy model.CachePolicy, update model.URLUpdate) model.CachePolicy {
+	if update.CacheTTL != nil {
+		policy.TTL = *update.CacheTTL
+		if policy.TTL > 0 {
+			policy.NoCache = false
+		}
+	}
+	if update.NoCache != nil {
+		policy.NoCache = *update.NoCache
+		if policy.NoCache {
+			policy.TTL = 0
+		}
+	}
+	return policy
+}
+
+// sameTime reports whether two optional times are equal
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
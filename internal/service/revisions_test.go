@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestActor tests that the caller is carried through the context
+func TestActor(t *testing.T) {
+	assert.Equal(t, "system", actorFrom(context.Background()))
+	assert.Equal(t, "user:42", actorFrom(WithActor(context.Background(), "user:42")))
+	assert.Equal(t, "system", actorFrom(WithActor(context.Background(), "")))
+}
+
+// TestSameTime tests comparison of optional expiry times
+func TestSameTime(t *testing.T) {
+	a := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := a.In(time.FixedZone("CET", 3600))
+	c := a.Add(time.Second)
+
+	assert.True(t, sameTime(nil, nil))
+	assert.True(t, sameTime(&a, &b))
+	assert.False(t, sameTime(&a, &c))
+	assert.False(t, sameTime(&a, nil))
+	assert.False(t, sameTime(nil, &a))
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSigningSecret = "0123456789abcdef0123456789abcdef"
+
+// TestCheckSignature tests which signatures let a flagged link redirect
+func TestCheckSignature(t *testing.T) {
+	s := NewURLService(newFakeRepository(), newFakeCache(), newFakeFilter())
+	link := &model.URLMapping{ShortCode: "abc123", RequireSignature: true}
+	now := time.Unix(1700000000, 0)
+	exp := now.Add(time.Hour).Unix()
+	signed := model.Visitor{Signature: SignLink(testSigningSecret, "abc123", exp), SignatureExpires: exp}
+
+	assert.ErrorIs(t, s.checkSignature(link, signed, now), ErrInvalidSignature, "flagged links don't redirect while signing is off")
+	assert.NoError(t, s.checkSignature(&model.URLMapping{ShortCode: "xyz789"}, model.Visitor{}, now))
+
+	s.SetLinkSigning(LinkSigning{Secret: testSigningSecret, MaxTTL: 24 * time.Hour})
+	assert.NoError(t, s.checkSignature(link, signed, now))
+	assert.ErrorIs(t, s.checkSignature(link, model.Visitor{}, now), ErrInvalidSignature)
+	assert.ErrorIs(t, s.checkSignature(link, signed, now.Add(2*time.Hour)), ErrInvalidSignature, "expired")
+
+	extended := signed
+	extended.SignatureExpires += 3600
+	assert.ErrorIs(t, s.checkSignature(link, extended, now), ErrInvalidSignature, "the expiry is signed")
+	other := &model.URLMapping{ShortCode: "abc124", RequireSignature: true}
+	assert.ErrorIs(t, s.checkSignature(other, signed, now), ErrInvalidSignature, "so is the code")
+
+	farExp := now.Add(48 * time.Hour).Unix()
+	far := model.Visitor{Signature: SignLink(testSigningSecret, "abc123", farExp), SignatureExpires: farExp}
+	assert.ErrorIs(t, s.checkSignature(link, far, now), ErrInvalidSignature, "beyond max_ttl")
+
+	s.SetLinkSigning(LinkSigning{Secret: testSigningSecret, MaxTTL: 24 * time.Hour, RequireAll: true})
+	assert.ErrorIs(t, s.checkSignature(&model.URLMapping{ShortCode: "xyz789"}, model.Visitor{}, now), ErrInvalidSignature)
+}
+
+// TestSignedURL tests minting signed URLs that ResolveLink accepts
+func TestSignedURL(t *testing.T) {
+	repo := newFakeRepository(&model.URLMapping{ShortCode: "abc1234", OriginalURL: "https://example.com", Status: 1, RequireSignature: true})
+	s := NewURLService(repo, newFakeCache(), newFakeFilter("abc1234"))
+	ctx := context.Background()
+
+	_, _, err := s.SignedURL(ctx, "abc1234", "http://localhost:8080", time.Hour)
+	assert.ErrorIs(t, err, ErrSigningDisabled)
+
+	s.SetLinkSigning(LinkSigning{Secret: testSigningSecret, MaxTTL: 24 * time.Hour})
+	_, _, err = s.SignedURL(ctx, "abc1234", "http://localhost:8080", 48*time.Hour)
+	assert.ErrorIs(t, err, ErrInvalidSignTTL)
+	_, _, err = s.SignedURL(ctx, "nope123", "http://localhost:8080", time.Hour)
+	assert.ErrorIs(t, err, ErrShortCodeNotFound)
+
+	signed, expiresAt, err := s.SignedURL(ctx, "abc1234", "http://localhost:8080", time.Hour)
+	require.NoError(t, err)
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.Equal(t, "/abc1234", u.Path)
+	assert.Equal(t, strconv.FormatInt(expiresAt.Unix(), 10), u.Query().Get(SignatureExpiresParam))
+
+	visitor := model.Visitor{Signature: u.Query().Get(SignatureParam), SignatureExpires: expiresAt.Unix()}
+	_, err = s.ResolveLink(ctx, "", "abc1234", visitor)
+	require.NoError(t, err)
+	_, err = s.ResolveLink(ctx, "", "abc1234", model.Visitor{})
+	assert.ErrorIs(t, err, ErrInvalidSignature, "checked on cache hits too")
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/summary"
+)
+
+// Errors returned by summary subscriptions (see internal/summary)
+var (
+	ErrInvalidSubscription  = errors.New("invalid summary subscription")
+	ErrSubscriptionNotFound = errors.New("summary subscription not found")
+)
+
+// SummaryStore keeps summary subscriptions and computes their reports
+type SummaryStore interface {
+	CreateSummarySubscription(ctx context.Context, sub *model.SummarySubscription) error
+	ListSummarySubscriptions(ctx context.Context, userID string) ([]model.SummarySubscription, error)
+	DeleteSummarySubscription(ctx context.Context, userID string, id uint) (bool, error)
+	DueSummarySubscriptions(ctx context.Context, frequency string, periodEnd time.Time) ([]model.SummarySubscription, error)
+	ClaimSummaryPeriod(ctx context.Context, id uint, periodEnd time.Time) (bool, error)
+	SummaryClicks(ctx context.Context, sub *model.SummarySubscription, from, to time.Time) (int64, error)
+	SummaryNewLinks(ctx context.Context, sub *model.SummarySubscription, from, to time.Time, limit int) (int64, []model.URLMapping, error)
+	SummaryTopLinks(ctx context.Context, sub *model.SummarySubscription, from, to time.Time, limit int) ([]model.SummaryLink, error)
+}
+
+// SetSummaryOptions sets how many links summaries list and whether they
+// can be sent by email
+func (s *URLService) SetSummaryOptions(topLinks int, email bool) {
+	s.summaryTopLinks = topLinks
+	s.summaryEmail = email
+}
+
+// CreateSummarySubscription subscribes userID to periodic summaries
+// Summaries of an organization's links need the user to be a viewer of it
+func (s *URLService) CreateSummarySubscription(ctx context.Context, userID string, sub *model.SummarySubscription) error {
+	if userID == "" {
+		return ErrForbidden
+	}
+	if sub.Frequency != model.SummaryDaily && sub.Frequency != model.SummaryWeekly {
+		return fmt.Errorf("%w: frequency must be daily or weekly", ErrInvalidSubscription)
+	}
+
+	switch sub.Channel {
+	case model.SummaryEmail:
+		if !s.summaryEmail {
+			return fmt.Errorf("%w: email summaries are not configured", ErrInvalidSubscription)
+		}
+		addr, err := mail.ParseAddress(sub.Target)
+		if err != nil {
+			return fmt.Errorf("%w: target must be an email address", ErrInvalidSubscription)
+		}
+		sub.Target = addr.Address
+	case model.SummaryWebhook:
+		u, err := url.Parse(sub.Target)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") || len(sub.Target) > 2048 {
+			return fmt.Errorf("%w: target must be an absolute http(s) URL", ErrInvalidSubscription)
+		}
+		if s.destinations != nil {
+			if err := s.destinations.Check(ctx, u.Hostname()); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidSubscription, err)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: channel must be email or webhook", ErrInvalidSubscription)
+	}
+
+	if sub.OrgID != 0 {
+		if err := s.AuthorizeOrg(ctx, sub.OrgID, userID, model.RoleViewer); err != nil {
+			return err
+		}
+	}
+
+	sub.ID = 0
+	sub.UserID = userID
+	sub.LastPeriodEnd = nil
+	return s.repo.CreateSummarySubscription(ctx, sub)
+}
+
+// ListSummarySubscriptions returns userID's subscriptions
+func (s *URLService) ListSummarySubscriptions(ctx context.Context, userID string) ([]model.SummarySubscription, error) {
+	if userID == "" {
+		return nil, ErrForbidden
+	}
+	return s.repo.ListSummarySubscriptions(ctx, userID)
+}
+
+// DeleteSummarySubscription removes one of userID's subscriptions
+func (s *URLService) DeleteSummarySubscription(ctx context.Context, userID string, id uint) error {
+	if userID == "" {
+		return ErrForbidden
+	}
+	deleted, err := s.repo.DeleteSummarySubscription(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+// BuildSummary gathers the clicks, top links and new links of a
+// subscription in [from, to)
+func (s *URLService) BuildSummary(ctx context.Context, sub *model.SummarySubscription, from, to time.Time) (*model.Summary, error) {
+	sum := &model.Summary{
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		OrgID:          sub.OrgID,
+		Frequency:      sub.Frequency,
+		From:           from,
+		To:             to,
+	}
+
+	var err error
+	if sum.Clicks, err = s.repo.SummaryClicks(ctx, sub, from, to); err != nil {
+		return nil, err
+	}
+	if sum.TopLinks, err = s.repo.SummaryTopLinks(ctx, sub, from, to, s.summaryTopLinks); err != nil {
+		return nil, err
+	}
+	for i := range sum.TopLinks {
+		link := &sum.TopLinks[i]
+		link.ShortURL = s.ShortURL(&model.URLMapping{ShortCode: link.ShortCode, Domain: link.Domain}, "")
+	}
+
+	count, mappings, err := s.repo.SummaryNewLinks(ctx, sub, from, to, s.summaryTopLinks)
+	if err != nil {
+		return nil, err
+	}
+	sum.NewLinkCount = count
+	sum.NewLinks = make([]model.SummaryLink, 0, len(mappings))
+	for i := range mappings {
+		sum.NewLinks = append(sum.NewLinks, model.SummaryLink{
+			ShortCode:   mappings[i].ShortCode,
+			ShortURL:    s.ShortURL(&mappings[i], ""),
+			OriginalURL: mappings[i].OriginalURL,
+			Clicks:      int64(mappings[i].VisitCount),
+		})
+	}
+	return sum, nil
+}
+
+// SummaryScheduler sends summaries once their period has ended
+type SummaryScheduler struct {
+	service  *URLService
+	sender   *summary.Sender
+	schedule summary.Schedule
+	interval time.Duration
+}
+
+// NewSummaryScheduler creates a scheduler checking for due summaries every interval
+func NewSummaryScheduler(service *URLService, sender *summary.Sender, schedule summary.Schedule, interval time.Duration) *SummaryScheduler {
+	return &SummaryScheduler{service: service, sender: sender, schedule: schedule, interval: interval}
+}
+
+// Run checks immediately and then every interval until ctx is done
+func (j *SummaryScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.RunOnce(ctx, time.Now()); err != nil {
+			fmt.Printf("Summary scheduler failed: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends every summary whose latest period hasn't been sent yet
+// Subscriptions created after a period ended start with the next one
+func (j *SummaryScheduler) RunOnce(ctx context.Context, now time.Time) error {
+	for _, frequency := range []string{model.SummaryDaily, model.SummaryWeekly} {
+		from, to := j.schedule.LastPeriod(frequency, now)
+		subs, err := j.service.repo.DueSummarySubscriptions(ctx, frequency, to)
+		if err != nil {
+			return err
+		}
+
+		for i := range subs {
+			sub := &subs[i]
+			if sub.LastPeriodEnd == nil && sub.CreatedAt.After(to) {
+				continue
+			}
+			claimed, err := j.service.repo.ClaimSummaryPeriod(ctx, sub.ID, to)
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				continue // Another instance is sending it
+			}
+			if err := j.send(ctx, sub, from, to); err != nil {
+				fmt.Printf("Failed to send %s summary %d: %v\n", frequency, sub.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// send builds and delivers one summary
+func (j *SummaryScheduler) send(ctx context.Context, sub *model.SummarySubscription, from, to time.Time) error {
+	if sub.OrgID != 0 {
+		// The user may have left the organization since subscribing
+		if err := j.service.AuthorizeOrg(ctx, sub.OrgID, sub.UserID, model.RoleViewer); err != nil {
+			return err
+		}
+	}
+	sum, err := j.service.BuildSummary(ctx, sub, from, to)
+	if err != nil {
+		return err
+	}
+	return j.sender.Send(ctx, sub, sum)
+}
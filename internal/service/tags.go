@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ErrInvalidTag is returned for tags that fail validation
+var ErrInvalidTag = errors.New("invalid tag")
+
+// Tag limits
+const (
+	maxTagsPerLink = 20
+	maxTagLength   = 64
+)
+
+// TagStore reads and writes the tags of links
+type TagStore interface {
+	GetByShortCode(ctx context.Context, shortCode string) (*model.URLMapping, error)
+	LoadTags(ctx context.Context, mapping *model.URLMapping) error
+	AddTags(ctx context.Context, mapping *model.URLMapping, names []string) error
+	SetTags(ctx context.Context, mapping *model.URLMapping, names []string) error
+}
+
+// NormalizeTags lower-cases, trims and de-duplicates tags
+// Tags may contain letters, digits and - _ . : /
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxTagLength {
+			return nil, fmt.Errorf("%w: %q is longer than %d characters", ErrInvalidTag, tag, maxTagLength)
+		}
+		for _, c := range tag {
+			if !isTagChar(c) {
+				return nil, fmt.Errorf("%w: %q contains %q", ErrInvalidTag, tag, c)
+			}
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxTagsPerLink {
+		return nil, fmt.Errorf("%w: at most %d tags per link", ErrInvalidTag, maxTagsPerLink)
+	}
+	return normalized, nil
+}
+
+// isTagChar reports whether c may appear in a tag
+func isTagChar(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || strings.ContainsRune("-_.:/", c)
+}
+
+// SetTags replaces the tags of a short code
+func (s *URLService) SetTags(ctx context.Context, shortCode string, tags []string) (*model.URLMapping, error) {
+	names, err := NormalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := s.repo.GetByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if mapping == nil {
+		return nil, ErrShortCodeNotFound
+	}
+
+	if err := s.repo.SetTags(ctx, mapping, names); err != nil {
+		return nil, err
+	}
+	if err := s.repo.LoadTags(ctx, mapping); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// AddTags adds tags to a mapping, keeping its existing ones
+func (s *URLService) AddTags(ctx context.Context, mapping *model.URLMapping, tags []string) error {
+	names, err := NormalizeTags(tags)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	if err := s.repo.AddTags(ctx, mapping, names); err != nil {
+		return err
+	}
+	return s.repo.LoadTags(ctx, mapping)
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNormalizeTags tests tag cleanup and validation
+func TestNormalizeTags(t *testing.T) {
+	tags, err := NormalizeTags([]string{" Spring-Sale ", "email", "EMAIL", "", "utm:source/x"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"spring-sale", "email", "utm:source/x"}, tags)
+
+	_, err = NormalizeTags([]string{"has space"})
+	assert.True(t, errors.Is(err, ErrInvalidTag))
+
+	_, err = NormalizeTags([]string{strings.Repeat("a", 65)})
+	assert.True(t, errors.Is(err, ErrInvalidTag))
+
+	many := make([]string, 21)
+	for i := range many {
+		many[i] = strings.Repeat("t", i+1)
+	}
+	_, err = NormalizeTags(many)
+	assert.True(t, errors.Is(err, ErrInvalidTag))
+}
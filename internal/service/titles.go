@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/Monthlyaway/short-link/internal/tracing"
+)
+
+// ============================================================================
+// LINK TITLES
+// ============================================================================
+// Lists are easier to read with page names than with raw URLs. With a title
+// fetcher set, the destination page of each new link is fetched in the
+// background once the link is created, and its <title> is stored on the
+// link (shown by the info and list APIs).
+//
+// Creation never waits for the fetch, and fetches are bounded: at most
+// titleSlots run at once, and a creation finding them all busy skips its
+// fetch rather than queueing one. The fetcher bounds each page's time and
+// size. A failed fetch leaves the title empty.
+// ============================================================================
+
+// titleWriteTimeout bounds storing a fetched title
+const titleWriteTimeout = 5 * time.Second
+
+// TitleStore saves fetched page titles
+type TitleStore interface {
+	SetLinkTitle(ctx context.Context, shortCode, title string) error
+}
+
+// TitleFetcher reads the title of a destination page (see
+// linkcheck.TitleFetcher)
+type TitleFetcher interface {
+	FetchTitle(ctx context.Context, rawURL string) (string, error)
+}
+
+// SetTitleFetcher makes new links fetch their destination page's title,
+// at most concurrency at a time; nil (the default) disables it
+func (s *URLService) SetTitleFetcher(fetcher TitleFetcher, concurrency int) {
+	s.titles = fetcher
+	s.titleSlots = make(chan struct{}, concurrency)
+}
+
+// fetchTitle stores the title of mapping's destination in the background
+func (s *URLService) fetchTitle(ctx context.Context, mapping *model.URLMapping) {
+	if s.titles == nil {
+		return
+	}
+	select {
+	case s.titleSlots <- struct{}{}:
+	default:
+		return // All fetches busy; the link stays without a title
+	}
+
+	// The fetch outlives the request; keep it in its trace
+	bgCtx := tracing.Detach(ctx)
+	shortCode, rawURL := mapping.ShortCode, mapping.OriginalURL
+	go func() {
+		defer func() { <-s.titleSlots }()
+
+		title, err := s.titles.FetchTitle(bgCtx, rawURL)
+		if err != nil {
+			fmt.Printf("Failed to fetch title of %s: %v\n", shortCode, err)
+			return
+		}
+		if title == "" {
+			return
+		}
+		ctx, cancel := context.WithTimeout(bgCtx, titleWriteTimeout)
+		defer cancel()
+		if err := s.repo.SetLinkTitle(ctx, shortCode, title); err != nil {
+			fmt.Printf("Failed to store title of %s: %v\n", shortCode, err)
+		}
+	}()
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTitleFetcher returns titles[url], blocking until release is closed
+type fakeTitleFetcher struct {
+	titles  map[string]string
+	release chan struct{}
+}
+
+func (f *fakeTitleFetcher) FetchTitle(ctx context.Context, rawURL string) (string, error) {
+	<-f.release
+	return f.titles[rawURL], nil
+}
+
+// TestFetchTitle tests that titles are stored in the background and that
+// creations beyond the fetch limit skip theirs
+func TestFetchTitle(t *testing.T) {
+	repo := newFakeRepository()
+	s := NewURLService(repo, newFakeCache(), newFakeFilter())
+	fetcher := &fakeTitleFetcher{
+		titles: map[string]string{
+			"https://example.com/a": "Page A",
+			"https://example.com/b": "Page B",
+		},
+		release: make(chan struct{}),
+	}
+	s.SetTitleFetcher(fetcher, 1)
+	ctx := context.Background()
+
+	a, err := s.CreateShortURL(ctx, "https://example.com/a", "", nil, model.LinkOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, a.Title, "creation doesn't wait for the title")
+	b, err := s.CreateShortURL(ctx, "https://example.com/b", "", nil, model.LinkOptions{})
+	require.NoError(t, err)
+
+	close(fetcher.release)
+	title := func(code string) string {
+		link, _ := repo.GetByShortCode(ctx, code)
+		return link.Title
+	}
+	assert.Eventually(t, func() bool { return title(a.ShortCode) == "Page A" }, time.Second, 5*time.Millisecond)
+	assert.Empty(t, title(b.ShortCode), "the only fetch slot was taken")
+}
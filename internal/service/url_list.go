@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"golang.org/x/net/idna"
+)
+
+// ErrInvalidListFilter is returned for unknown sort fields and malformed cursors
+var ErrInvalidListFilter = errors.New("invalid list filter")
+
+// Listing limits
+const (
+	defaultPageSize    = 20
+	maxPageSize        = 100
+	maxMetadataFilters = 10
+)
+
+// URLListStore pages through links for ListURLs
+type URLListStore interface {
+	ListURLs(ctx context.Context, filter model.URLFilter) ([]model.URLMapping, int64, error)
+}
+
+// URLPage is one page of links
+type URLPage struct {
+	Items    []model.URLMapping
+	Total    int64 // Matches across all pages
+	Page     int   // 0 when the page was read with a cursor
+	PageSize int
+	// NextCursor continues after the last item; empty on the last page
+	NextCursor string
+}
+
+// ListURLs returns a page of links matching filter
+// cursor, when not empty, is a NextCursor from a previous page with the same
+// filter and sort; it takes precedence over filter.Page
+func (s *URLService) ListURLs(ctx context.Context, filter model.URLFilter, cursor string) (*URLPage, error) {
+	tags, err := NormalizeTags(filter.Tags)
+	if err != nil {
+		return nil, err
+	}
+	filter.Tags = tags
+	filter.Query = strings.TrimSpace(filter.Query)
+	filter.DestinationHost = strings.ToLower(strings.TrimSpace(filter.DestinationHost))
+	if !isASCII(filter.DestinationHost) {
+		// Hosts are stored in punycode (see idn.go)
+		if host, err := idna.Lookup.ToASCII(filter.DestinationHost); err == nil {
+			filter.DestinationHost = host
+		}
+	}
+
+	if len(filter.Metadata) > maxMetadataFilters {
+		return nil, fmt.Errorf("%w: at most %d metadata filters", ErrInvalidListFilter, maxMetadataFilters)
+	}
+	for key := range filter.Metadata {
+		if !metadataKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("%w: invalid metadata key %q", ErrInvalidListFilter, key)
+		}
+	}
+
+	switch filter.Sort {
+	case "":
+		filter.Sort = model.SortCreatedAt
+	case model.SortCreatedAt, model.SortVisitCount:
+	default:
+		return nil, fmt.Errorf("%w: unknown sort field %q", ErrInvalidListFilter, filter.Sort)
+	}
+
+	if cursor != "" {
+		after, err := DecodeURLCursor(cursor, filter.Sort)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = after
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = defaultPageSize
+	}
+	if filter.PageSize > maxPageSize {
+		filter.PageSize = maxPageSize
+	}
+
+	mappings, total, err := s.repo.ListURLs(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &URLPage{Items: mappings, Total: total, PageSize: filter.PageSize}
+	if filter.After == nil {
+		page.Page = filter.Page
+	}
+	if len(mappings) == filter.PageSize {
+		page.NextCursor = EncodeURLCursor(&mappings[len(mappings)-1], filter.Sort)
+	}
+	return page, nil
+}
+
+// EncodeURLCursor returns the opaque cursor positioned after mapping
+// The cursor is "<sort>:<value>:<id>" in URL-safe base64
+func EncodeURLCursor(mapping *model.URLMapping, sort string) string {
+	value := strconv.FormatInt(mapping.CreatedAt.UnixNano(), 10)
+	if sort == model.SortVisitCount {
+		value = strconv.FormatUint(mapping.VisitCount, 10)
+	}
+	raw := sort + ":" + value + ":" + strconv.FormatUint(uint64(mapping.ID), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeURLCursor parses a cursor from EncodeURLCursor
+// The cursor must have been created for the same sort field
+func DecodeURLCursor(cursor, sort string) (*model.URLCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidListFilter)
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidListFilter)
+	}
+	if parts[0] != sort {
+		return nil, fmt.Errorf("%w: cursor was created for sort %q", ErrInvalidListFilter, parts[0])
+	}
+
+	value, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidListFilter)
+	}
+	id, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidListFilter)
+	}
+
+	after := &model.URLCursor{ID: uint(id)}
+	if sort == model.SortVisitCount {
+		after.VisitCount = value
+	} else {
+		after.CreatedAt = time.Unix(0, int64(value))
+	}
+	return after, nil
+}
+
+// destinationHost returns the lower-case host of a URL, without port
+func destinationHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestURLCursorRoundTrip tests that cursors decode to the encoded position
+func TestURLCursorRoundTrip(t *testing.T) {
+	mapping := &model.URLMapping{
+		ID:         42,
+		CreatedAt:  time.Date(2024, 3, 1, 12, 0, 0, 500, time.UTC),
+		VisitCount: 1234,
+	}
+
+	after, err := DecodeURLCursor(EncodeURLCursor(mapping, model.SortCreatedAt), model.SortCreatedAt)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(42), after.ID)
+	assert.True(t, mapping.CreatedAt.Equal(after.CreatedAt))
+
+	after, err = DecodeURLCursor(EncodeURLCursor(mapping, model.SortVisitCount), model.SortVisitCount)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(42), after.ID)
+	assert.Equal(t, uint64(1234), after.VisitCount)
+}
+
+// TestDecodeURLCursorInvalid tests that bad cursors are rejected
+func TestDecodeURLCursorInvalid(t *testing.T) {
+	mapping := &model.URLMapping{ID: 1, CreatedAt: time.Now()}
+
+	// Created for another sort
+	_, err := DecodeURLCursor(EncodeURLCursor(mapping, model.SortCreatedAt), model.SortVisitCount)
+	assert.True(t, errors.Is(err, ErrInvalidListFilter))
+
+	for _, cursor := range []string{"!!!", "Zm9v", "Y3JlYXRlZF9hdDp4OjE"} {
+		_, err := DecodeURLCursor(cursor, model.SortCreatedAt)
+		assert.True(t, errors.Is(err, ErrInvalidListFilter), cursor)
+	}
+}
+
+// TestDestinationHost tests host extraction from original URLs
+func TestDestinationHost(t *testing.T) {
+	assert.Equal(t, "example.com", destinationHost("https://Example.COM/path?q=1"))
+	assert.Equal(t, "example.com", destinationHost("http://user:pw@example.com:8080/"))
+	assert.Equal(t, "", destinationHost("::not a url"))
+}
@@ -2,198 +2,863 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
+	"strings"
 	"time"
+	"unicode"
 
+	"github.com/Monthlyaway/short-link/internal/abuse"
 	"github.com/Monthlyaway/short-link/internal/cache"
+	"github.com/Monthlyaway/short-link/internal/enrich"
+	"github.com/Monthlyaway/short-link/internal/events"
 	"github.com/Monthlyaway/short-link/internal/filter"
+	"github.com/Monthlyaway/short-link/internal/linkcheck"
 	"github.com/Monthlyaway/short-link/internal/model"
-	"github.com/Monthlyaway/short-link/internal/repository"
+	"github.com/Monthlyaway/short-link/internal/tracing"
 	"github.com/Monthlyaway/short-link/internal/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Errors returned by URLService, for mapping to HTTP/gRPC status codes
+var (
+	ErrShortCodeNotFound = errors.New("short code not found")
+	ErrShortCodeInactive = errors.New("short code is expired or disabled")
+	ErrInvalidURL        = errors.New("invalid URL")
+	ErrUnknownDomain     = errors.New("unknown domain")
+	ErrReservedShortCode = errors.New("short code is reserved")
+)
+
+// LinkStore is the storage behind creating, resolving and deleting links
+// and their visit stats
+type LinkStore interface {
+	GetByShortCode(ctx context.Context, shortCode string) (*model.URLMapping, error)
+	GetByOriginalURL(ctx context.Context, originalURL, domain string, orgID uint) (*model.URLMapping, error)
+	StreamURLMappings(ctx context.Context, domain string, includeDeleted bool, updatedSince time.Time, fn func([]model.URLMapping) error) error
+	Delete(ctx context.Context, shortCode string) error
+	Restore(ctx context.Context, shortCode string) (bool, error)
+	LoadTags(ctx context.Context, mapping *model.URLMapping) error
+	ShortCodeTaken(ctx context.Context, shortCode string) (bool, error)
+	GetAllShortCodes(ctx context.Context) ([]string, error)
+	ClaimRecycledCode(ctx context.Context) (string, error)
+	StreamVisitLogs(ctx context.Context, shortCode string, from, to time.Time, fn func([]model.VisitLog) error) error
+	VisitBreakdown(ctx context.Context, shortCode, column string, from, to time.Time, limit int) ([]model.VisitStat, error)
+	SplitVisitBreakdown(ctx context.Context, shortCode, column string, split model.StatsSplit, limit int) ([]model.VisitStat, error)
+	VisitTotals(ctx context.Context, shortCode string, split model.StatsSplit) (clicks, uniques int64, err error)
+	VisitRollupWatermark(ctx context.Context) (time.Time, error)
+}
+
 // URLService handles business logic for URL shortening
 type URLService struct {
-	repo   *repository.URLRepository
-	cache  *cache.RedisCache
-	bloom  *filter.BloomFilter
+	// Storage, cache and bloom filter (see deps.go)
+	repo  Repository
+	cache Cache
+	bloom Filter
+
+	// Click events emitted on every recorded visit
+	events     events.Publisher
+	ipHashSalt string
+
+	// Derives referrer, geo and client fields for visit logs
+	enricher *enrich.Enricher
+	// What visit logs keep about visitors (see privacy.go)
+	privacy Privacy
+	// Secret and lifetime of signed links (see signed_links.go)
+	signing LinkSigning
+	// User ID -> alias namespace (see aliases.go)
+	namespaces map[string]string
+	// Added by plugins (see hooks.go)
+	hooks hooks
+
+	// Serving domains; the first is the default (see domains.go)
+	domains        []Domain
+	redirectPrefix string
+
+	// Produces candidate short codes (see utils/idgen.go)
+	idGen utils.IDGenerator
+	// Take codes from the recycled pool first (see code_recycler.go)
+	recycledCodes bool
+	// Queue inserts of new links instead of waiting for them (see write_behind.go)
+	writeBehind bool
+	// Record the side effects of new links in the outbox (see outbox.go)
+	outbox        bool
+	outboxWebhook bool
+
+	// Hosts links may point to; nil allows any (see destinations.go)
+	destinations *DestinationPolicy
+	// Probes http:// destinations of new links over https; nil disables it
+	httpsUpgrade *linkcheck.Checker
+	// Fetches the page titles of new links; nil disables it (see titles.go)
+	titles     TitleFetcher
+	titleSlots chan struct{}
+
+	// Deadlines for redirects and the visit writes they trigger
+	timeouts Timeouts
+
+	// Tracks MySQL outages for degraded mode; nil disables it (see degraded.go)
+	db *DatabaseMonitor
+
+	// Codes that may not be used for links (see reserved.go)
+	reserved           *ReservedCodes
+	configReserved     []string
+	configBlockedWords []string
+
+	// Flags links with anomalous traffic; nil disables it (see abuse.go)
+	abuse           *abuse.Detector
+	abuseNotifier   abuse.Notifier
+	reportThreshold int
+	reportAction    string
+
+	// Ranks links by recent clicks; nil disables it (see leaderboard.go)
+	leaderboard *cache.Leaderboard
+
+	// Summary reports (see summaries.go)
+	summaryTopLinks int
+	summaryEmail    bool
 }
 
 // NewURLService creates a new URL service instance
-func NewURLService(repo *repository.URLRepository, cache *cache.RedisCache, bloom *filter.BloomFilter) *URLService {
+func NewURLService(repo Repository, cache Cache, bloom Filter) *URLService {
 	return &URLService{
-		repo:  repo,
-		cache: cache,
-		bloom: bloom,
+		repo:          repo,
+		cache:         cache,
+		bloom:         bloom,
+		events:        events.NoopPublisher{},
+		enricher:      enrich.NewEnricher(nil),
+		abuseNotifier: abuse.NoopNotifier{},
+		reserved:      NewReservedCodes(DefaultReservedCodes, nil),
+		idGen:         utils.NewRandomGenerator(7),
 	}
 }
 
+// Timeouts bounds operations that must not wait on a slow MySQL or Redis
+// Zero leaves an operation bounded only by its caller's context
+type Timeouts struct {
+	Redirect   time.Duration // Resolving a short code (cache and MySQL)
+	VisitWrite time.Duration // Each background visit count / visit log write
+}
+
+// SetTimeouts sets the deadlines of redirects and visit writes
+func (s *URLService) SetTimeouts(timeouts Timeouts) {
+	s.timeouts = timeouts
+}
+
+// withTimeout bounds ctx by d unless d is zero
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// SetIDGenerator sets how new short codes are generated
+func (s *URLService) SetIDGenerator(gen utils.IDGenerator) {
+	s.idGen = gen
+}
+
+// SetCodeRecycling makes new links take codes from the recycled pool before
+// generating fresh ones
+func (s *URLService) SetCodeRecycling(enabled bool) {
+	s.recycledCodes = enabled
+}
+
+// SetGeoLocator enables country/city lookups for visit logs
+func (s *URLService) SetGeoLocator(geo enrich.GeoLocator) {
+	s.enricher = enrich.NewEnricher(geo)
+}
+
+// SetEventPublisher sets where click events are published
+// IPs are hashed with ipHashSalt before leaving the service
+func (s *URLService) SetEventPublisher(publisher events.Publisher, ipHashSalt string) {
+	s.events = publisher
+	s.ipHashSalt = ipHashSalt
+}
+
 // CreateShortURL creates a new short URL
-func (s *URLService) CreateShortURL(ctx context.Context, originalURL string, expiredAt *time.Time) (*model.URLMapping, error) {
-	// Validate URL
-	if err := s.validateURL(originalURL); err != nil {
+// domain selects the serving host; empty uses the default domain
+// Unless opts.ForceNew is set, an existing link to the same URL is returned
+// if it belongs to the same organization and has the same cache settings,
+// access rules, deep links and metadata
+func (s *URLService) CreateShortURL(ctx context.Context, originalURL, domain string, expiredAt *time.Time, opts model.LinkOptions) (_ *model.URLMapping, err error) {
+	ctx, span := tracing.Start(ctx, "URLService.CreateShortURL")
+	defer func() { tracing.EndSpan(span, err) }()
+
+	mapping, err := s.prepareMapping(ctx, originalURL, domain, expiredAt, opts)
+	if err != nil {
 		return nil, err
 	}
 
-	// Check if the URL already exists
-	existing, err := s.repo.GetByOriginalURL(ctx, originalURL)
+	// Check if the URL already exists on this domain
+	existing, err := s.findEquivalent(ctx, mapping, opts.ForceNew)
 	if err != nil {
 		return nil, err
 	}
-	if existing != nil && existing.IsActive() {
+	if existing != nil {
+		if err := s.AddTags(ctx, existing, opts.Tags); err != nil {
+			return nil, err
+		}
 		return existing, nil
 	}
 
-	// Generate short code
-	shortCode, err := utils.GenerateShortCode()
+	if s.writeBehind {
+		if err := s.queueMapping(ctx, mapping, opts.Tags); err != nil {
+			return nil, err
+		}
+		return mapping, nil
+	}
+	if err := s.createMapping(ctx, mapping, model.URLRevision{Action: model.RevisionCreate}); err != nil {
+		return nil, err
+	}
+	if err := s.AddTags(ctx, mapping, opts.Tags); err != nil {
+		return nil, err
+	}
+	s.fetchTitle(ctx, mapping)
+	return mapping, nil
+}
+
+// URLValidation is what CreateShortURL would do with a link (see
+// ValidateShortURL)
+type URLValidation struct {
+	Mapping  *model.URLMapping // The link as it would be created, without a short code
+	Existing *model.URLMapping // The link returned instead of a new one, if any
+	Warnings []string          // Valid but probably unintended
+}
+
+// ValidateShortURL runs the checks and normalization of CreateShortURL
+// without storing anything, so clients can show problems before submitting;
+// it returns the same errors CreateShortURL would
+func (s *URLService) ValidateShortURL(ctx context.Context, originalURL, domain string, expiredAt *time.Time, opts model.LinkOptions) (_ *URLValidation, err error) {
+	ctx, span := tracing.Start(ctx, "URLService.ValidateShortURL")
+	defer func() { tracing.EndSpan(span, err) }()
+
+	mapping, err := s.prepareMapping(ctx, originalURL, domain, expiredAt, opts)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.findEquivalent(ctx, mapping, opts.ForceNew)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate short code: %w", err)
+		return nil, err
 	}
 
-	// Check for collision (very unlikely with snowflake)
-	for i := 0; i < 3; i++ {
-		exists, err := s.repo.GetByShortCode(ctx, shortCode)
-		if err != nil {
-			return nil, err
-		}
-		if exists == nil {
-			break
-		}
-		// Generate a new short code if collision detected
-		shortCode, err = utils.GenerateShortCode()
-		if err != nil {
-			return nil, fmt.Errorf("failed to generate short code: %w", err)
+	result := &URLValidation{Mapping: mapping, Existing: existing}
+	if mapping.DisplayURL != "" {
+		result.Warnings = append(result.Warnings, "URL contains non-ASCII characters and is stored as "+mapping.OriginalURL)
+	}
+	if strings.HasPrefix(originalURL, "http://") && strings.HasPrefix(mapping.OriginalURL, "https://") {
+		result.Warnings = append(result.Warnings, "URL is upgraded to https")
+	}
+	if expiredAt != nil && !expiredAt.After(time.Now()) {
+		result.Warnings = append(result.Warnings, "expired_at is in the past; the link would never redirect")
+	}
+	if existing != nil {
+		result.Warnings = append(result.Warnings, "An equivalent link exists; creating returns it instead of a new one")
+	}
+	return result, nil
+}
+
+// prepareMapping validates and normalizes a link to create, without a short
+// code; it stores nothing
+func (s *URLService) prepareMapping(ctx context.Context, originalURL, domain string, expiredAt *time.Time, opts model.LinkOptions) (*model.URLMapping, error) {
+	originalURL, displayURL, err := canonicalURL(originalURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateURL(ctx, originalURL); err != nil {
+		return nil, err
+	}
+	if err := validateCachePolicy(opts.Cache); err != nil {
+		return nil, err
+	}
+	if err := validateVisitDedup(opts.VisitDedupMinutes); err != nil {
+		return nil, err
+	}
+	if err := validateRedirectDelay(opts.RedirectDelay); err != nil {
+		return nil, err
+	}
+	if opts.RequireSignature && s.signing.Secret == "" {
+		return nil, ErrSigningDisabled
+	}
+	if !opts.NoHTTPSUpgrade {
+		if upgraded := s.upgradeHTTPS(ctx, originalURL); upgraded != originalURL {
+			originalURL = upgraded
+			if displayURL != "" {
+				displayURL = "https" + displayURL[len("http"):]
+			}
 		}
 	}
+	rules, err := normalizeAccessRules(opts.AccessRules)
+	if err != nil {
+		return nil, err
+	}
+	deepLinks, err := normalizeDeepLinks(opts.DeepLinks)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := normalizeMetadata(opts.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	visibility, err := normalizeVisibility(opts.Visibility, opts.OrgID)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create URL mapping
-	mapping := &model.URLMapping{
-		ShortCode:   shortCode,
-		OriginalURL: originalURL,
-		ExpiredAt:   expiredAt,
-		Status:      1,
+	serving, err := s.resolveDomain(domain)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.repo.Create(ctx, mapping); err != nil {
+	return &model.URLMapping{
+		OriginalURL:       originalURL,
+		DisplayURL:        displayURL,
+		Domain:            serving.Host,
+		OrgID:             opts.OrgID,
+		ExpiredAt:         expiredAt,
+		CacheTTL:          opts.Cache.TTL,
+		NoCache:           opts.Cache.NoCache,
+		AccessRules:       rules,
+		DeepLinks:         deepLinks,
+		Metadata:          metadata,
+		VisitDedupMinutes: opts.VisitDedupMinutes,
+		NoAnalytics:       opts.NoAnalytics,
+		Visibility:        visibility,
+		RedirectDelay:     opts.RedirectDelay,
+		RequireSignature:  opts.RequireSignature,
+	}, nil
+}
+
+// findEquivalent returns the active link CreateShortURL would return instead
+// of creating mapping, or nil if there is none or forceNew is set
+func (s *URLService) findEquivalent(ctx context.Context, mapping *model.URLMapping, forceNew bool) (*model.URLMapping, error) {
+	if forceNew {
+		return nil, nil
+	}
+	existing, err := s.repo.GetByOriginalURL(ctx, mapping.OriginalURL, mapping.Domain, mapping.OrgID)
+	if err != nil {
 		return nil, err
 	}
+	if existing != nil && existing.IsActive() && existing.CachePolicy() == mapping.CachePolicy() &&
+		existing.AccessRules.Equal(mapping.AccessRules) && existing.DeepLinks.Equal(mapping.DeepLinks) &&
+		sameMetadata(existing.Metadata, mapping.Metadata) && existing.VisitDedupMinutes == mapping.VisitDedupMinutes &&
+		existing.NoAnalytics == mapping.NoAnalytics && existing.Visibility == mapping.Visibility &&
+		existing.RedirectDelay == mapping.RedirectDelay && existing.RequireSignature == mapping.RequireSignature {
+		return existing, nil
+	}
+	return nil, nil
+}
+
+// createMapping stores a new active link under a generated short code and
+// records revision (action and source) as its first revision
+func (s *URLService) createMapping(ctx context.Context, mapping *model.URLMapping, revision model.URLRevision) error {
+	shortCode, err := s.generateShortCode(ctx)
+	if err != nil {
+		return err
+	}
+	mapping.ShortCode = shortCode
+	mapping.DestinationHost = destinationHost(mapping.OriginalURL)
+	mapping.URLHash = model.HashURL(mapping.OriginalURL)
+	mapping.Status = 1
+	if err := s.runCreateHooks(ctx, mapping, revision.Action); err != nil {
+		return err
+	}
+
+	if err := s.storeMapping(ctx, mapping); err != nil {
+		return err
+	}
+	s.recordFirstRevisions(ctx, revision.Action, revision.ClonedFrom, mapping)
 
 	// Update cache and bloom filter
-	if err := s.cache.Set(ctx, shortCode, originalURL); err != nil {
+	if err := s.cacheMapping(ctx, mapping); err != nil {
 		// Log error but don't fail the request
 		fmt.Printf("Failed to set cache: %v\n", err)
 	}
 	s.bloom.Add(shortCode)
+	return nil
+}
 
-	return mapping, nil
+// shortCodeAttempts bounds how often generation re-rolls a reserved or taken code
+const shortCodeAttempts = 5
+
+// generateShortCode returns a new short code that is neither reserved nor
+// used by another link (including soft-deleted ones)
+func (s *URLService) generateShortCode(ctx context.Context) (string, error) {
+	if s.recycledCodes {
+		shortCode, err := s.claimRecycledCode(ctx)
+		if err != nil {
+			// Fresh codes work just as well
+			fmt.Printf("Failed to claim a recycled code: %v\n", err)
+		} else if shortCode != "" {
+			return shortCode, nil
+		}
+	}
+
+	for i := 0; i < shortCodeAttempts; i++ {
+		shortCode, err := s.idGen.NextCode(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate short code: %w", err)
+		}
+		if s.reserved.Check(shortCode) != nil {
+			continue
+		}
+
+		// Collisions are very unlikely with snowflake, but check anyway
+		taken, err := s.repo.ShortCodeTaken(ctx, shortCode)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return shortCode, nil
+		}
+	}
+	return "", fmt.Errorf("failed to generate short code: no free code after %d attempts", shortCodeAttempts)
 }
 
-// GetOriginalURL retrieves the original URL by short code
-// Uses cascade: Bloom filter -> Redis -> MySQL
-func (s *URLService) GetOriginalURL(ctx context.Context, shortCode string) (string, error) {
+// claimRecycledCode takes a code from the recycled pool, skipping codes that
+// were reserved or used as a custom alias since they were recycled
+// Returns "" when no usable code is left within shortCodeAttempts.
+func (s *URLService) claimRecycledCode(ctx context.Context) (string, error) {
+	for i := 0; i < shortCodeAttempts; i++ {
+		shortCode, err := s.repo.ClaimRecycledCode(ctx)
+		if err != nil || shortCode == "" {
+			return "", err
+		}
+		if s.reserved.Check(shortCode) != nil {
+			continue
+		}
+		taken, err := s.repo.ShortCodeTaken(ctx, shortCode)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return shortCode, nil
+		}
+	}
+	return "", nil
+}
+
+// ResolveLink looks up the link a visitor is redirected through
+// Uses cascade: Bloom filter -> Local LRU -> Redis -> MySQL
+// host is the request host; links assigned to another domain are not found.
+// An empty host skips the domain check.
+// Returns ErrAccessDenied if the link's access rules don't admit visitor.
+// A link from the cache only has the fields needed to redirect (see
+// cache.CachedMapping).
+func (s *URLService) ResolveLink(ctx context.Context, host, shortCode string, visitor model.Visitor) (_ *model.URLMapping, err error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Redirect)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "URLService.ResolveLink", trace.WithAttributes(attribute.String("short_code", shortCode)))
+	defer func() {
+		// Unknown and inactive codes are expected outcomes, not span errors
+		spanErr := err
+		if errors.Is(err, ErrShortCodeNotFound) || errors.Is(err, ErrShortCodeInactive) ||
+			errors.Is(err, ErrAccessDenied) || errors.Is(err, ErrInvalidSignature) {
+			span.SetAttributes(attribute.String("short_link.result", err.Error()))
+			spanErr = nil
+		}
+		tracing.EndSpan(span, spanErr)
+	}()
+
 	// Check bloom filter first
-	if !s.bloom.Test(shortCode) {
-		return "", fmt.Errorf("short code not found")
+	_, bloomSpan := tracing.Start(ctx, "bloom_filter.test")
+	mayExist := s.bloom.Test(shortCode)
+	bloomSpan.SetAttributes(attribute.Bool("bloom_filter.may_exist", mayExist))
+	bloomSpan.End()
+	if !mayExist {
+		return nil, ErrShortCodeNotFound
 	}
 
-	// Check Redis cache
-	originalURL, err := s.cache.Get(ctx, shortCode)
+	// Check cache (local tier, then Redis)
+	cached, err := s.cache.Get(ctx, shortCode)
 	if err != nil {
 		fmt.Printf("Failed to get from cache: %v\n", err)
 	}
-	if originalURL != "" {
-		return originalURL, nil
+	if cached != nil {
+		if !servesHost(cached, host) {
+			return nil, ErrShortCodeNotFound
+		}
+		// Before anything that would tell non-members the link exists
+		if err := s.authorizeVisit(ctx, cached, visitor); err != nil {
+			return nil, err
+		}
+		if !cached.IsActive() {
+			s.runExpireHooks(ctx, cached)
+			return nil, ErrShortCodeInactive
+		}
+		if !cached.AccessRules.Allows(visitor) {
+			return nil, ErrAccessDenied
+		}
+		if err := s.checkSignature(cached, visitor, time.Now()); err != nil {
+			return nil, err
+		}
+		return cached, nil
 	}
 
-	// Check database
+	// Check database, unless it's known to be down
+	if !s.db.Available() {
+		return nil, ErrDatabaseUnavailable
+	}
 	mapping, err := s.repo.GetByShortCode(ctx, shortCode)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if mapping == nil {
-		return "", fmt.Errorf("short code not found")
+		return nil, ErrShortCodeNotFound
+	}
+
+	// Update cache (disabled links are cached too, so they don't hit MySQL)
+	if err := s.cacheMapping(ctx, mapping); err != nil {
+		fmt.Printf("Failed to set cache: %v\n", err)
+	}
+
+	if !servesHost(mapping, host) {
+		return nil, ErrShortCodeNotFound
+	}
+	// Before anything that would tell non-members the link exists
+	if err := s.authorizeVisit(ctx, mapping, visitor); err != nil {
+		return nil, err
 	}
 
 	// Check if active
 	if !mapping.IsActive() {
-		return "", fmt.Errorf("short code is expired or disabled")
+		s.runExpireHooks(ctx, mapping)
+		return nil, ErrShortCodeInactive
 	}
-
-	// Update cache
-	if err := s.cache.Set(ctx, shortCode, mapping.OriginalURL); err != nil {
-		fmt.Printf("Failed to set cache: %v\n", err)
+	if !mapping.AccessRules.Allows(visitor) {
+		return nil, ErrAccessDenied
+	}
+	if err := s.checkSignature(mapping, visitor, time.Now()); err != nil {
+		return nil, err
 	}
 
-	return mapping.OriginalURL, nil
+	return mapping, nil
 }
 
-// GetURLInfo retrieves URL mapping information by short code
+// GetURLInfo retrieves URL mapping information, including tags, by short code
 func (s *URLService) GetURLInfo(ctx context.Context, shortCode string) (*model.URLMapping, error) {
 	mapping, err := s.repo.GetByShortCode(ctx, shortCode)
 	if err != nil {
 		return nil, err
 	}
 	if mapping == nil {
-		return nil, fmt.Errorf("short code not found")
+		return nil, ErrShortCodeNotFound
+	}
+	if err := s.repo.LoadTags(ctx, mapping); err != nil {
+		return nil, err
 	}
 	return mapping, nil
 }
 
-// RecordVisit records a visit to a short URL
-func (s *URLService) RecordVisit(ctx context.Context, shortCode, ip, userAgent string) error {
-	// Increment visit count asynchronously
+// RecordVisit records a visit to a resolved link
+// It returns immediately: the writes run in the background, detached from
+// ctx (which ends with the request) but bounded by the visit write timeout,
+// so a slow MySQL can't pile up goroutines indefinitely
+// Untracked visits (see privacy.go) are only counted
+func (s *URLService) RecordVisit(ctx context.Context, mapping *model.URLMapping, visitor model.Visitor) {
+	shortCode := mapping.ShortCode
+	// The writes below outlive the request; keep them in its trace
+	bgCtx := tracing.Detach(ctx)
+
+	// Enrich once so the visit log and click event agree
+	log := &model.VisitLog{
+		ShortCode: shortCode,
+		VisitedAt: time.Now().UTC(),
+		IP:        visitor.IP,
+		UserAgent: visitor.UserAgent,
+	}
+	s.enricher.Enrich(log, visitor.Referrer)
+	tracked := s.tracked(mapping, visitor)
+	visit := &cache.PendingVisit{Log: *log, Untracked: !tracked}
+	if tracked {
+		visit.Log.IP = s.privacy.storedIP(visitor.IP)
+	} else {
+		visit.Log = model.VisitLog{ShortCode: shortCode, VisitedAt: log.VisitedAt, IsBot: log.IsBot}
+	}
+
+	// Write the visit asynchronously; while MySQL is down (or the write
+	// fails) it is queued in Redis and replayed later
 	go func() {
-		if err := s.repo.IncrementVisitCount(context.Background(), shortCode); err != nil {
-			fmt.Printf("Failed to increment visit count: %v\n", err)
+		if mapping.VisitDedupMinutes > 0 {
+			ctx, cancel := withTimeout(bgCtx, s.timeouts.VisitWrite)
+			visit.Duplicate = s.duplicateVisit(ctx, mapping, visitor.IP, log.IsBot)
+			cancel()
 		}
-	}()
 
-	// Create visit log asynchronously
-	go func() {
-		log := &model.VisitLog{
-			ShortCode: shortCode,
-			IP:        ip,
-			UserAgent: userAgent,
+		if s.leaderboard != nil && !log.IsBot && !visit.Duplicate {
+			ctx, cancel := withTimeout(bgCtx, s.timeouts.VisitWrite)
+			if err := s.leaderboard.Record(ctx, shortCode, log.VisitedAt); err != nil {
+				fmt.Printf("Failed to update leaderboard: %v\n", err)
+			}
+			cancel()
 		}
-		if err := s.repo.CreateVisitLog(context.Background(), log); err != nil {
-			fmt.Printf("Failed to create visit log: %v\n", err)
+
+		if s.db.Available() {
+			ctx, cancel := withTimeout(bgCtx, s.timeouts.VisitWrite)
+			err := s.writeVisit(ctx, visit)
+			cancel()
+			if err == nil {
+				return
+			}
+			fmt.Printf("Failed to record visit: %v\n", err)
+			if s.db == nil {
+				return // Nothing replays the queue without degraded mode
+			}
+		}
+
+		ctx, cancel := withTimeout(bgCtx, s.timeouts.VisitWrite)
+		defer cancel()
+		if err := s.cache.QueueVisit(ctx, visit); err != nil {
+			fmt.Printf("Failed to queue visit: %v\n", err)
 		}
 	}()
 
+	if len(s.hooks.redirect) > 0 {
+		go func() {
+			ctx, cancel := withTimeout(bgCtx, s.timeouts.VisitWrite)
+			defer cancel()
+			s.runRedirectHooks(ctx, RedirectEvent{Mapping: mapping, Visitor: visitor, VisitedAt: log.VisitedAt})
+		}()
+	}
+
+	if !tracked {
+		return
+	}
+	// Publish click event (non-blocking; the publisher batches in the background)
+	event := &events.ClickEvent{
+		ShortCode: shortCode,
+		Timestamp: log.VisitedAt,
+		IPHash:    events.HashIP(visitor.IP, s.ipHashSalt),
+		UserAgent: visitor.UserAgent,
+		Referrer:  log.Referrer,
+		Country:   log.Country,
+		IsBot:     log.IsBot,
+	}
+	if err := s.events.Publish(bgCtx, event); err != nil {
+		fmt.Printf("Failed to publish click event: %v\n", err)
+	}
+}
+
+// statsBreakdownLimit is how many rows each stats breakdown returns
+const statsBreakdownLimit = 10
+
+// GetVisitStats returns visit counters and the top countries, devices,
+// browsers and referrers of a short code in [from, to)
+// Rolled-up days are read from the rollup tables (see visit_rollup.go)
+func (s *URLService) GetVisitStats(ctx context.Context, shortCode string, from, to time.Time) (*model.VisitStats, error) {
+	mapping, err := s.GetURLInfo(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	rolledUntil, err := s.repo.VisitRollupWatermark(ctx)
+	if err != nil {
+		return nil, err
+	}
+	split := splitStatsRange(from, to, rolledUntil)
+
+	stats := &model.VisitStats{Mapping: mapping}
+	if stats.Clicks, stats.Uniques, err = s.repo.VisitTotals(ctx, shortCode, split); err != nil {
+		return nil, err
+	}
+	breakdowns := []struct {
+		column string
+		dest   *[]model.VisitStat
+	}{
+		{"country", &stats.Countries},
+		{"device_type", &stats.Devices},
+		{"browser", &stats.Browsers},
+		{"referrer", &stats.Referrers},
+	}
+	for _, b := range breakdowns {
+		var rows []model.VisitStat
+		if split.Rollup {
+			rows, err = s.repo.SplitVisitBreakdown(ctx, shortCode, b.column, split, statsBreakdownLimit)
+		} else {
+			rows, err = s.repo.VisitBreakdown(ctx, shortCode, b.column, from, to, statsBreakdownLimit)
+		}
+		if err != nil {
+			return nil, err
+		}
+		*b.dest = rows
+	}
+	return stats, nil
+}
+
+// DeleteURL soft-deletes a short URL and evicts it from the cache
+// It can be restored with RestoreURL until the purge job removes it.
+// The Bloom filter can't remove entries; lookups fall through to MySQL
+func (s *URLService) DeleteURL(ctx context.Context, shortCode string) error {
+	mapping, err := s.repo.GetByShortCode(ctx, shortCode)
+	if err != nil {
+		return err
+	}
+	if mapping == nil {
+		return ErrShortCodeNotFound
+	}
+
+	if err := s.repo.Delete(ctx, shortCode); err != nil {
+		return err
+	}
+	s.LinkRemoved(ctx, DeleteEvent{Mapping: mapping, Reason: DeleteReasonDeleted})
 	return nil
 }
 
+// LinkRemoved finishes removing a link already deleted from MySQL, however
+// it was: it evicts the link from the cache, drops the aliases pointing at it
+// once it's gone for good (so they can't follow a recycled code) and runs the
+// OnDelete hooks
+// Jobs that remove links outside the service (purge, recycling) are given
+// it as their DeleteHook. Failures are logged; the link is gone either way.
+func (s *URLService) LinkRemoved(ctx context.Context, event DeleteEvent) {
+	shortCode := event.Mapping.ShortCode
+	if err := s.cache.Delete(ctx, shortCode); err != nil {
+		fmt.Printf("Failed to delete cache: %v\n", err)
+	}
+	if event.Reason != DeleteReasonDeleted {
+		if _, err := s.repo.DeleteAliasesTo(ctx, shortCode); err != nil {
+			fmt.Printf("Failed to delete aliases of %s: %v\n", shortCode, err)
+		}
+	}
+	s.runDeleteHooks(ctx, event)
+}
+
+// RestoreURL undoes a soft delete
+func (s *URLService) RestoreURL(ctx context.Context, shortCode string) (*model.URLMapping, error) {
+	restored, err := s.repo.Restore(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if !restored {
+		return nil, ErrShortCodeNotFound
+	}
+
+	// Re-add in case the filter was rebuilt while the link was deleted
+	s.bloom.Add(shortCode)
+	return s.GetURLInfo(ctx, shortCode)
+}
+
+// ExportVisitLogs streams the visit logs of a short code in [from, to) to fn
+// in batches; zero times leave that side of the range open
+func (s *URLService) ExportVisitLogs(ctx context.Context, shortCode string, from, to time.Time, fn func([]model.VisitLog) error) error {
+	return s.repo.StreamVisitLogs(ctx, shortCode, from, to, fn)
+}
+
+// ExportURLMappings streams all URL mappings in batches to fn
+// domain, when not empty, limits the export to one serving domain
+// A non-zero updatedSince exports only links changed since then, including
+// soft-deleted ones, so a copy can be kept in sync incrementally
+func (s *URLService) ExportURLMappings(ctx context.Context, domain string, includeDeleted bool, updatedSince time.Time, fn func([]model.URLMapping) error) error {
+	if !updatedSince.IsZero() {
+		includeDeleted = true
+	}
+	return s.repo.StreamURLMappings(ctx, domain, includeDeleted, updatedSince, fn)
+}
+
 // InitBloomFilter initializes the bloom filter with all existing short codes
 func (s *URLService) InitBloomFilter(ctx context.Context) error {
-	shortCodes, err := s.repo.GetAllShortCodes(ctx)
+	loaded, err := s.RebuildBloomFilter(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get all short codes: %w", err)
+		return err
 	}
-
-	s.bloom.AddBatch(shortCodes)
-	fmt.Printf("Initialized bloom filter with %d short codes\n", len(shortCodes))
+	fmt.Printf("Initialized bloom filter with %d short codes\n", loaded)
 
 	return nil
 }
 
+// RebuildBloomFilter replaces the bloom filter with one built from the links
+// in the database, so codes of deleted links stop passing it
+// With auto-resize, the capacity grows if the links no longer fit.
+func (s *URLService) RebuildBloomFilter(ctx context.Context) (int, error) {
+	return s.bloom.Rebuild(func() ([]string, error) {
+		shortCodes, err := s.repo.GetAllShortCodes(ctx)
+		if err != nil || !s.writeBehind {
+			return shortCodes, err
+		}
+		// Links still queued for insert redirect already
+		queued, err := s.cache.QueuedCreationCodes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return append(shortCodes, queued...), nil
+	})
+}
+
+// CacheStats returns the cache hits and misses of short code lookups since startup
+func (s *URLService) CacheStats() cache.LookupStats {
+	return s.cache.Stats()
+}
+
+// RedisStats returns the Redis client's command, latency and pool metrics
+func (s *URLService) RedisStats() cache.RedisStats {
+	return s.cache.RedisStats()
+}
+
+// BloomFilterStats returns how full the bloom filter is
+func (s *URLService) BloomFilterStats() filter.Stats {
+	return s.bloom.Stats()
+}
+
+// MaxURLLength is the longest accepted original URL (the original_url column)
+const MaxURLLength = 2048
+
+// SetHTTPSUpgrade makes new links to http:// destinations that also answer
+// over https point to the https variant; nil (the default) disables it
+func (s *URLService) SetHTTPSUpgrade(checker *linkcheck.Checker) {
+	s.httpsUpgrade = checker
+}
+
+// upgradeHTTPS returns the https variant of rawURL if upgrades are enabled
+// and the destination answers over https, and rawURL otherwise
+func (s *URLService) upgradeHTTPS(ctx context.Context, rawURL string) string {
+	if s.httpsUpgrade == nil {
+		return rawURL
+	}
+	if upgraded, ok := s.httpsUpgrade.HTTPSVariant(ctx, rawURL); ok && len(upgraded) <= MaxURLLength {
+		return upgraded
+	}
+	return rawURL
+}
+
 // validateURL validates the URL format
-func (s *URLService) validateURL(rawURL string) error {
+// Besides being an absolute http(s) URL, it must fit the original_url column,
+// contain no control characters (header injection, log forging) and carry no
+// credentials (user:pass@host is a common phishing disguise); with a
+// destination policy, the host must also be public
+func (s *URLService) validateURL(ctx context.Context, rawURL string) error {
 	if rawURL == "" {
-		return fmt.Errorf("URL cannot be empty")
+		return fmt.Errorf("%w: URL cannot be empty", ErrInvalidURL)
+	}
+	if len(rawURL) > MaxURLLength {
+		return fmt.Errorf("%w: URL is longer than %d characters", ErrInvalidURL, MaxURLLength)
+	}
+	for _, r := range rawURL {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("%w: URL contains control characters", ErrInvalidURL)
+		}
 	}
 
 	parsedURL, err := url.ParseRequestURI(rawURL)
 	if err != nil {
-		return fmt.Errorf("invalid URL format: %w", err)
+		return fmt.Errorf("%w: %v", ErrInvalidURL, err)
 	}
 
 	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-		return fmt.Errorf("URL must use http or https scheme")
+		return fmt.Errorf("%w: URL must use http or https scheme", ErrInvalidURL)
 	}
 
 	if parsedURL.Host == "" {
-		return fmt.Errorf("URL must have a valid host")
+		return fmt.Errorf("%w: URL must have a valid host", ErrInvalidURL)
+	}
+
+	if parsedURL.User != nil {
+		return fmt.Errorf("%w: URL must not contain credentials", ErrInvalidURL)
+	}
+
+	if s.destinations != nil {
+		if err := s.destinations.Check(ctx, parsedURL.Hostname()); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidURL, err)
+		}
 	}
 
 	return nil
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ============================================================================
+// LINK VISIBILITY
+// ============================================================================
+// Every link is public, unlisted or private:
+// - public: redirects for anyone; may be listed (top links)
+// - unlisted: redirects for anyone with the link; never listed
+// - private: redirects only for members (any role) of the organization
+//   owning the link, identified by API key; never listed
+//
+// Private links need an organization. Visibility and the organization are
+// part of the cached entry, so the check runs on cache hits as well; only
+// the membership lookup for private links goes to MySQL. Other visitors
+// get ErrShortCodeNotFound, so a private link's existence isn't revealed.
+// ============================================================================
+
+// ErrInvalidVisibility is returned for an unknown visibility, or a private
+// link without an organization
+var ErrInvalidVisibility = errors.New("invalid visibility")
+
+// normalizeVisibility validates a link's visibility; empty means public
+func normalizeVisibility(visibility string, orgID uint) (string, error) {
+	switch visibility = strings.ToLower(strings.TrimSpace(visibility)); visibility {
+	case "":
+		return model.VisibilityPublic, nil
+	case model.VisibilityPublic, model.VisibilityUnlisted:
+		return visibility, nil
+	case model.VisibilityPrivate:
+		if orgID == 0 {
+			return "", fmt.Errorf("%w: private links must belong to an organization", ErrInvalidVisibility)
+		}
+		return visibility, nil
+	}
+	return "", fmt.Errorf("%w: visibility must be public, unlisted or private", ErrInvalidVisibility)
+}
+
+// authorizeVisit returns ErrShortCodeNotFound if mapping is private and the
+// visitor isn't a member of its organization
+func (s *URLService) authorizeVisit(ctx context.Context, mapping *model.URLMapping, visitor model.Visitor) error {
+	if !mapping.IsPrivate() {
+		return nil
+	}
+	err := s.AuthorizeOrg(ctx, mapping.OrgID, visitor.UserID, model.RoleViewer)
+	if errors.Is(err, ErrForbidden) {
+		return ErrShortCodeNotFound
+	}
+	return err
+}
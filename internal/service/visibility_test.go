@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNormalizeVisibility tests visibility validation
+func TestNormalizeVisibility(t *testing.T) {
+	visibility, err := normalizeVisibility("", 0)
+	require.NoError(t, err)
+	assert.Equal(t, model.VisibilityPublic, visibility)
+
+	visibility, err = normalizeVisibility(" Unlisted ", 0)
+	require.NoError(t, err)
+	assert.Equal(t, model.VisibilityUnlisted, visibility)
+
+	_, err = normalizeVisibility("private", 0)
+	assert.ErrorIs(t, err, ErrInvalidVisibility, "private links need an organization")
+	visibility, err = normalizeVisibility("private", 3)
+	require.NoError(t, err)
+	assert.Equal(t, model.VisibilityPrivate, visibility)
+
+	_, err = normalizeVisibility("secret", 3)
+	assert.ErrorIs(t, err, ErrInvalidVisibility)
+}
+
+// TestResolvePrivateLink tests that private links only redirect for members
+// of their organization, on cache misses and hits alike
+func TestResolvePrivateLink(t *testing.T) {
+	repo := newFakeRepository(
+		&model.URLMapping{ShortCode: "priv123", OriginalURL: "https://example.com/p", Status: 1, OrgID: 3, Visibility: model.VisibilityPrivate},
+		&model.URLMapping{ShortCode: "unli123", OriginalURL: "https://example.com/u", Status: 1, Visibility: model.VisibilityUnlisted},
+	)
+	repo.members = []model.OrgMember{{OrgID: 3, UserID: "alice", Role: model.RoleViewer}}
+	cache := newFakeCache()
+	s := NewURLService(repo, cache, newFakeFilter("priv123", "unli123"))
+	ctx := context.Background()
+
+	_, err := s.ResolveLink(ctx, "", "priv123", model.Visitor{})
+	assert.ErrorIs(t, err, ErrShortCodeNotFound, "anonymous visitors don't learn it exists")
+	assert.NotNil(t, cache.links["priv123"], "cached even when refused")
+
+	_, err = s.ResolveLink(ctx, "", "priv123", model.Visitor{UserID: "mallory"})
+	assert.ErrorIs(t, err, ErrShortCodeNotFound)
+
+	mapping, err := s.ResolveLink(ctx, "", "priv123", model.Visitor{UserID: "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/p", mapping.OriginalURL)
+	assert.Equal(t, 1, repo.called("GetByShortCode"), "later lookups were cache hits")
+
+	_, err = s.ResolveLink(ctx, "", "unli123", model.Visitor{})
+	require.NoError(t, err, "unlisted links redirect for anyone")
+}
+
+// TestResolvePrivateLinkHidesRefusals tests that non-members get not found
+// for a private link even when its access rules or expiry would refuse them
+// anyway, so the refusal doesn't reveal the link exists
+func TestResolvePrivateLinkHidesRefusals(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	repo := newFakeRepository(
+		&model.URLMapping{ShortCode: "rule123", OriginalURL: "https://example.com/r", Status: 1, OrgID: 3,
+			Visibility: model.VisibilityPrivate, AccessRules: &model.AccessRules{Referrers: []string{"intranet.example"}}},
+		&model.URLMapping{ShortCode: "gone123", OriginalURL: "https://example.com/g", Status: 1, OrgID: 3,
+			Visibility: model.VisibilityPrivate, ExpiredAt: &past},
+	)
+	repo.members = []model.OrgMember{{OrgID: 3, UserID: "alice", Role: model.RoleViewer}}
+	s := NewURLService(repo, newFakeCache(), newFakeFilter("rule123", "gone123"))
+	ctx := context.Background()
+
+	// Twice each: a cache miss, then a hit
+	for i := 0; i < 2; i++ {
+		_, err := s.ResolveLink(ctx, "", "rule123", model.Visitor{UserID: "mallory"})
+		assert.ErrorIs(t, err, ErrShortCodeNotFound)
+		_, err = s.ResolveLink(ctx, "", "gone123", model.Visitor{})
+		assert.ErrorIs(t, err, ErrShortCodeNotFound)
+	}
+
+	_, err := s.ResolveLink(ctx, "", "rule123", model.Visitor{UserID: "alice"})
+	assert.ErrorIs(t, err, ErrAccessDenied, "members still get the rules")
+	_, err = s.ResolveLink(ctx, "", "gone123", model.Visitor{UserID: "alice"})
+	assert.ErrorIs(t, err, ErrShortCodeInactive)
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/events"
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ============================================================================
+// VISIT DEDUPLICATION
+// ============================================================================
+// A link with visit_dedup_minutes set counts at most one human visit per IP
+// in that many minutes, so refreshing a page doesn't inflate visit_count.
+// The first counted visit sets a Redis key (keyed by the hashed IP) that
+// expires after the window; visits while it exists are counted in
+// duplicate_visit_count instead, so the raw count stays available. Visit
+// logs, and the clicks computed from them, still include every visit.
+// ============================================================================
+
+// ErrInvalidVisitDedup is returned for an out-of-range deduplication window
+var ErrInvalidVisitDedup = errors.New("invalid visit deduplication window")
+
+// MaxVisitDedupMinutes is the longest deduplication window (1 day)
+const MaxVisitDedupMinutes = 24 * 60
+
+// validateVisitDedup checks a per-link deduplication window
+func validateVisitDedup(minutes int) error {
+	if minutes < 0 || minutes > MaxVisitDedupMinutes {
+		return fmt.Errorf("%w: visit_dedup_minutes must be between 0 and %d", ErrInvalidVisitDedup, MaxVisitDedupMinutes)
+	}
+	return nil
+}
+
+// duplicateVisit reports whether a visit repeats one counted from the same
+// IP within the link's window
+// Bot visits aren't deduplicated, and a visit is counted if Redis fails
+func (s *URLService) duplicateVisit(ctx context.Context, mapping *model.URLMapping, ip string, isBot bool) bool {
+	if mapping.VisitDedupMinutes <= 0 || isBot {
+		return false
+	}
+	window := time.Duration(mapping.VisitDedupMinutes) * time.Minute
+	first, err := s.cache.FirstVisit(ctx, mapping.ShortCode, events.HashIP(ip, s.ipHashSalt), window)
+	if err != nil {
+		fmt.Printf("Failed to deduplicate visit: %v\n", err)
+		return false
+	}
+	return !first
+}
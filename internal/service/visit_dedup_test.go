@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Monthlyaway/short-link/internal/cache"
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidateVisitDedup tests the accepted deduplication windows
+func TestValidateVisitDedup(t *testing.T) {
+	assert.NoError(t, validateVisitDedup(0))
+	assert.NoError(t, validateVisitDedup(MaxVisitDedupMinutes))
+	assert.ErrorIs(t, validateVisitDedup(-1), ErrInvalidVisitDedup)
+	assert.ErrorIs(t, validateVisitDedup(MaxVisitDedupMinutes+1), ErrInvalidVisitDedup)
+
+	s := NewURLService(newFakeRepository(), newFakeCache(), newFakeFilter())
+	_, err := s.CreateShortURL(context.Background(), "https://example.com", "", nil, model.LinkOptions{VisitDedupMinutes: -5})
+	assert.ErrorIs(t, err, ErrInvalidVisitDedup)
+}
+
+// TestVisitDedup tests that repeat visits from one IP are counted as
+// duplicates within the window
+func TestVisitDedup(t *testing.T) {
+	repo := newFakeRepository()
+	s := NewURLService(repo, newFakeCache(), newFakeFilter())
+	ctx := context.Background()
+	link := &model.URLMapping{ShortCode: "abc123", VisitDedupMinutes: 30}
+
+	visit := func(mapping *model.URLMapping, ip string, bot bool) bool {
+		return s.duplicateVisit(ctx, mapping, ip, bot)
+	}
+	assert.False(t, visit(link, "203.0.113.7", false), "first visit counts")
+	assert.True(t, visit(link, "203.0.113.7", false), "repeat is a duplicate")
+	assert.False(t, visit(link, "198.51.100.1", false), "other IPs count")
+	assert.False(t, visit(link, "203.0.113.7", true), "bot visits aren't deduplicated")
+	other := &model.URLMapping{ShortCode: "xyz789"}
+	assert.False(t, visit(other, "203.0.113.7", false))
+	assert.False(t, visit(other, "203.0.113.7", false), "links without a window count every visit")
+
+	require.NoError(t, s.writeVisit(ctx, &cache.PendingVisit{Log: model.VisitLog{ShortCode: "abc123"}, Duplicate: true}))
+	require.NoError(t, s.writeVisit(ctx, &cache.PendingVisit{Log: model.VisitLog{ShortCode: "abc123"}}))
+	assert.Equal(t, 1, repo.called("IncrementDuplicateVisitCount"))
+	assert.Equal(t, 1, repo.called("IncrementVisitCount"))
+	assert.Equal(t, 2, repo.called("CreateVisitLog"), "duplicates are still logged")
+}
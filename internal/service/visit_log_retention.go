@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// visitLogDeleteBatchSize bounds each DELETE when visit_logs isn't partitioned
+const visitLogDeleteBatchSize = 1000
+
+// VisitLogRetentionStore is the storage used by VisitLogRetention
+type VisitLogRetentionStore interface {
+	IsVisitLogPartitioned(ctx context.Context) (bool, error)
+	EnsureVisitLogPartitions(ctx context.Context, now time.Time, daysAhead int) error
+	DropVisitLogPartitionsBefore(ctx context.Context, cutoff time.Time) (int, error)
+	DeleteVisitLogsBefore(ctx context.Context, cutoff time.Time, batchSize int) (int64, error)
+}
+
+// VisitLogRetention periodically removes visit logs older than the retention
+// window. On a partitioned table it drops whole daily partitions (cheap, no
+// row locks) and pre-creates upcoming ones; otherwise it deletes in batches.
+type VisitLogRetention struct {
+	repo      VisitLogRetentionStore
+	retention time.Duration
+	daysAhead int
+	interval  time.Duration
+}
+
+// NewVisitLogRetention creates a retention job
+// retention <= 0 keeps visit logs forever (partitions are still maintained)
+func NewVisitLogRetention(repo VisitLogRetentionStore, retention time.Duration, daysAhead int, interval time.Duration) *VisitLogRetention {
+	return &VisitLogRetention{
+		repo:      repo,
+		retention: retention,
+		daysAhead: daysAhead,
+		interval:  interval,
+	}
+}
+
+// Run executes the job immediately and then every interval until ctx is done
+func (j *VisitLogRetention) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.RunOnce(ctx, time.Now()); err != nil {
+			fmt.Printf("Visit log retention failed: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce performs one maintenance pass
+func (j *VisitLogRetention) RunOnce(ctx context.Context, now time.Time) error {
+	partitioned, err := j.repo.IsVisitLogPartitioned(ctx)
+	if err != nil {
+		return err
+	}
+
+	if partitioned {
+		if err := j.repo.EnsureVisitLogPartitions(ctx, now, j.daysAhead); err != nil {
+			return err
+		}
+	}
+
+	if j.retention <= 0 {
+		return nil
+	}
+	cutoff := now.Add(-j.retention)
+
+	if partitioned {
+		dropped, err := j.repo.DropVisitLogPartitionsBefore(ctx, cutoff)
+		if err != nil {
+			return err
+		}
+		if dropped > 0 {
+			fmt.Printf("Dropped %d visit log partitions older than %s\n", dropped, cutoff.Format(time.RFC3339))
+		}
+		return nil
+	}
+
+	deleted, err := j.repo.DeleteVisitLogsBefore(ctx, cutoff, visitLogDeleteBatchSize)
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		fmt.Printf("Deleted %d visit logs older than %s\n", deleted, cutoff.Format(time.RFC3339))
+	}
+	return nil
+}
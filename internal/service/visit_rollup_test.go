@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSplitStatsRange tests which parts of a stats range come from rollups
+func TestSplitStatsRange(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2024, 3, d, h, 0, 0, 0, time.UTC) }
+	rolledUntil := day(10, 0)
+
+	tests := []struct {
+		name        string
+		from, to    time.Time
+		rolledUntil time.Time
+		want        model.StatsSplit
+	}{
+		{
+			name:        "nothing rolled up",
+			from:        day(1, 0),
+			to:          day(5, 0),
+			rolledUntil: time.Time{},
+			want:        model.StatsSplit{Raw: []model.TimeRange{{From: day(1, 0), To: day(5, 0)}}},
+		},
+		{
+			name:        "open range",
+			rolledUntil: rolledUntil,
+			want: model.StatsSplit{
+				RollupTo: rolledUntil, Rollup: true,
+				Raw: []model.TimeRange{{From: rolledUntil}},
+			},
+		},
+		{
+			name:        "whole days before the watermark",
+			from:        day(2, 0),
+			to:          day(5, 0),
+			rolledUntil: rolledUntil,
+			want:        model.StatsSplit{RollupFrom: day(2, 0), RollupTo: day(5, 0), Rollup: true},
+		},
+		{
+			name:        "partial days at both edges",
+			from:        day(2, 6),
+			to:          day(5, 18),
+			rolledUntil: rolledUntil,
+			want: model.StatsSplit{
+				RollupFrom: day(3, 0), RollupTo: day(5, 0), Rollup: true,
+				Raw: []model.TimeRange{{From: day(2, 6), To: day(3, 0)}, {From: day(5, 0), To: day(5, 18)}},
+			},
+		},
+		{
+			name:        "range past the watermark",
+			from:        day(8, 0),
+			to:          day(12, 0),
+			rolledUntil: rolledUntil,
+			want: model.StatsSplit{
+				RollupFrom: day(8, 0), RollupTo: rolledUntil, Rollup: true,
+				Raw: []model.TimeRange{{From: rolledUntil, To: day(12, 0)}},
+			},
+		},
+		{
+			name:        "within one day",
+			from:        day(3, 6),
+			to:          day(3, 18),
+			rolledUntil: rolledUntil,
+			want:        model.StatsSplit{Raw: []model.TimeRange{{From: day(3, 6), To: day(3, 18)}}},
+		},
+		{
+			name:        "after the watermark",
+			from:        day(11, 0),
+			rolledUntil: rolledUntil,
+			want:        model.StatsSplit{Raw: []model.TimeRange{{From: day(11, 0)}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, splitStatsRange(tt.from, tt.to, tt.rolledUntil))
+		})
+	}
+}
+
+// TestVisitRollupRunOnce tests that the first run backfills from the oldest
+// visit and later runs re-roll the lookback days up to yesterday
+func TestVisitRollupRunOnce(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	store := &fakeVisitRollupStore{firstVisit: day(2).Add(15 * time.Hour)}
+	job := NewVisitRollup(store, 2, time.Hour)
+
+	require.NoError(t, job.RunOnce(context.Background(), day(5).Add(9*time.Hour)))
+	assert.Equal(t, []time.Time{day(2), day(3), day(4)}, store.rolled)
+
+	store.rolled = nil
+	require.NoError(t, job.RunOnce(context.Background(), day(7).Add(time.Hour)))
+	assert.Equal(t, []time.Time{day(3), day(4), day(5), day(6)}, store.rolled)
+
+	// Nothing to roll up without visits
+	empty := &fakeVisitRollupStore{}
+	require.NoError(t, NewVisitRollup(empty, 2, time.Hour).RunOnce(context.Background(), day(5)))
+	assert.Empty(t, empty.rolled)
+}
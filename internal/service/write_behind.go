@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/cache"
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ============================================================================
+// WRITE-BEHIND CREATION
+// ============================================================================
+// In write-behind mode CreateShortURL doesn't wait for the MySQL insert: the
+// new link is cached in Redis and added to the bloom filter, so it redirects
+// straight away, and queued in a Redis list (cache.CreationQueueKey) for
+// CreationFlush workers to insert. Creating links then stays fast while
+// MySQL is slow or busy; only the dedup lookup and the code check still
+// read from it.
+//
+// Until its insert, a link redirects but the info, list and edit APIs don't
+// see it yet, and creating the same URL again makes a second link.
+//
+// Durability: a worker moves an entry to a processing list (LMOVE) before
+// inserting it and removes it afterwards, so a crash mid-insert leaves it
+// there. Reconciliation puts entries that stay in the processing list for a
+// whole reconcile interval back into the queue. Inserts are idempotent: an
+// entry whose link is already in MySQL (same code and destination) counts
+// as done. Failed inserts go back to the end of the queue; after
+// maxAttempts, or if the code was taken by another link in the meantime,
+// the entry is moved to cache.CreationFailedKey for an operator and the
+// cached mapping is dropped.
+// ============================================================================
+
+// WriteBehindOptions are the settings of the write-behind creation queue
+type WriteBehindOptions struct {
+	Workers           int           // Concurrent inserts per instance
+	PollInterval      time.Duration // Wait after finding the queue empty
+	MaxAttempts       int           // Inserts tried before an entry is moved to the failed list
+	ReconcileInterval time.Duration // Processing entries older than this are requeued
+}
+
+// SetWriteBehind makes CreateShortURL queue inserts instead of waiting for them
+func (s *URLService) SetWriteBehind(enabled bool) {
+	s.writeBehind = enabled
+}
+
+// queueMapping fills in a new link like createMapping does, caches it and
+// queues its insert; it falls back to a synchronous insert if the queue is
+// unavailable
+func (s *URLService) queueMapping(ctx context.Context, mapping *model.URLMapping, tags []string) error {
+	shortCode, err := s.generateShortCode(ctx)
+	if err != nil {
+		return err
+	}
+	mapping.ShortCode = shortCode
+	mapping.Status = 1
+	mapping.DestinationHost = destinationHost(mapping.OriginalURL)
+	if err := s.runCreateHooks(ctx, mapping, model.RevisionCreate); err != nil {
+		return err
+	}
+	now := time.Now()
+	mapping.CreatedAt = now
+	mapping.UpdatedAt = now
+
+	creation := &cache.PendingCreation{
+		Mapping:  *mapping,
+		Tags:     tags,
+		Actor:    actorFrom(ctx),
+		QueuedAt: now,
+	}
+	// The queue comes first: a cached link without a queued insert would
+	// redirect until its cache entry expired and then vanish
+	if err := s.cache.QueueCreation(ctx, creation); err != nil {
+		fmt.Printf("Write-behind queue unavailable, inserting %s directly: %v\n", shortCode, err)
+		return s.insertMapping(ctx, mapping, tags)
+	}
+
+	if err := s.cacheMapping(ctx, mapping); err != nil {
+		fmt.Printf("Failed to set cache: %v\n", err)
+	}
+	s.bloom.Add(shortCode)
+	mapping.Tags = tagsFromNames(tags)
+	return nil
+}
+
+// insertMapping stores a link created with queueMapping synchronously
+func (s *URLService) insertMapping(ctx context.Context, mapping *model.URLMapping, tags []string) error {
+	mapping.DestinationHost = destinationHost(mapping.OriginalURL)
+	mapping.URLHash = model.HashURL(mapping.OriginalURL)
+	if err := s.storeMapping(ctx, mapping); err != nil {
+		return err
+	}
+	s.recordFirstRevisions(ctx, model.RevisionCreate, "", mapping)
+	if err := s.cacheMapping(ctx, mapping); err != nil {
+		fmt.Printf("Failed to set cache: %v\n", err)
+	}
+	s.bloom.Add(mapping.ShortCode)
+	return s.AddTags(ctx, mapping, tags)
+}
+
+// tagsFromNames returns unsaved tags for a queued link's response
+func tagsFromNames(names []string) []model.Tag {
+	normalized, err := NormalizeTags(names)
+	if err != nil || len(normalized) == 0 {
+		return nil
+	}
+	tags := make([]model.Tag, len(normalized))
+	for i, name := range normalized {
+		tags[i] = model.Tag{Name: name}
+	}
+	return tags
+}
+
+// FlushQueuedCreation inserts the oldest queued link into MySQL
+// Reports whether there was one.
+func (s *URLService) FlushQueuedCreation(ctx context.Context, maxAttempts int) (bool, error) {
+	creation, raw, err := s.cache.ClaimCreation(ctx)
+	if err != nil || creation == nil {
+		return false, err
+	}
+	// Finish the entry even if ctx is cancelled mid-insert
+	ctx = context.WithoutCancel(ctx)
+
+	err = s.writeQueuedCreation(ctx, creation)
+	switch {
+	case err == nil:
+		return true, s.cache.CompleteCreation(ctx, raw)
+	case creation.Attempts+1 >= maxAttempts || errors.Is(err, errCodeConflict):
+		fmt.Printf("Giving up on queued link %s after %d attempts: %v\n", creation.Mapping.ShortCode, creation.Attempts+1, err)
+		if err := s.cache.Delete(ctx, creation.Mapping.ShortCode); err != nil {
+			fmt.Printf("Failed to evict link %s from cache: %v\n", creation.Mapping.ShortCode, err)
+		}
+		return true, s.cache.FailCreation(ctx, raw)
+	default:
+		if requeueErr := s.cache.RetryCreation(ctx, raw, creation); requeueErr != nil {
+			return true, requeueErr
+		}
+		return true, err
+	}
+}
+
+// errCodeConflict marks a queued link whose code is used by another link
+var errCodeConflict = errors.New("short code taken by another link")
+
+// writeQueuedCreation inserts a queued link, treating a link already in
+// MySQL with the same code and destination as a previous successful insert
+func (s *URLService) writeQueuedCreation(ctx context.Context, creation *cache.PendingCreation) error {
+	mapping := creation.Mapping
+	mapping.DestinationHost = destinationHost(mapping.OriginalURL)
+	mapping.URLHash = model.HashURL(mapping.OriginalURL)
+
+	if err := s.storeMapping(ctx, &mapping); err != nil {
+		existing, lookupErr := s.repo.GetByShortCode(ctx, mapping.ShortCode)
+		if lookupErr != nil || existing == nil {
+			return err
+		}
+		if existing.URLHash != mapping.URLHash {
+			return errCodeConflict
+		}
+		// Inserted before, by a worker that went away before completing it
+		return nil
+	}
+
+	ctx = WithActor(ctx, creation.Actor)
+	s.recordFirstRevisions(ctx, model.RevisionCreate, "", &mapping)
+	if err := s.AddTags(ctx, &mapping, creation.Tags); err != nil {
+		// The link exists; retrying the insert wouldn't add the tags
+		fmt.Printf("Failed to tag queued link %s: %v\n", mapping.ShortCode, err)
+	}
+	return nil
+}
+
+// ReconcileQueuedCreations requeues entries that were in the processing list
+// at the previous reconciliation (seen) and still are, and returns the
+// entries to compare against next time
+func (s *URLService) ReconcileQueuedCreations(ctx context.Context, seen map[string]bool) (map[string]bool, int, error) {
+	processing, err := s.cache.ProcessingCreations(ctx)
+	if err != nil {
+		return seen, 0, err
+	}
+
+	requeued := 0
+	current := make(map[string]bool, len(processing))
+	for _, raw := range processing {
+		if !seen[raw] {
+			current[raw] = true
+			continue
+		}
+		ok, err := s.cache.RequeueStaleCreation(ctx, raw)
+		if err != nil {
+			return current, requeued, err
+		}
+		if ok {
+			requeued++
+		}
+	}
+	return current, requeued, nil
+}
+
+// QueuedCreations returns the number of links waiting for their insert, or
+// 0 without write-behind
+func (s *URLService) QueuedCreations(ctx context.Context) (int64, error) {
+	if !s.writeBehind {
+		return 0, nil
+	}
+	return s.cache.PendingCreations(ctx)
+}
+
+// CreationFlush inserts links queued in write-behind mode into MySQL
+type CreationFlush struct {
+	service *URLService
+	opts    WriteBehindOptions
+}
+
+// NewCreationFlush creates the write-behind workers
+func NewCreationFlush(service *URLService, opts WriteBehindOptions) *CreationFlush {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	return &CreationFlush{service: service, opts: opts}
+}
+
+// Run starts the workers and the reconciliation loop and returns when ctx
+// is done and the workers have finished their current insert
+func (j *CreationFlush) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < j.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			j.work(ctx)
+		}()
+	}
+	j.reconcile(ctx)
+	wg.Wait()
+}
+
+// work flushes queued links until ctx is done, pausing while the queue is
+// empty or MySQL fails
+func (j *CreationFlush) work(ctx context.Context) {
+	for {
+		flushed, err := j.service.FlushQueuedCreation(ctx, j.opts.MaxAttempts)
+		if err != nil {
+			fmt.Printf("Write-behind insert failed: %v\n", err)
+		}
+		if !flushed || err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(j.opts.PollInterval):
+			}
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// reconcile requeues stale processing entries every reconcile interval
+func (j *CreationFlush) reconcile(ctx context.Context) {
+	ticker := time.NewTicker(j.opts.ReconcileInterval)
+	defer ticker.Stop()
+
+	seen := map[string]bool{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var requeued int
+		var err error
+		seen, requeued, err = j.service.ReconcileQueuedCreations(ctx, seen)
+		if requeued > 0 {
+			fmt.Printf("Requeued %d write-behind inserts left unfinished\n", requeued)
+		}
+		if err != nil {
+			fmt.Printf("Write-behind reconciliation failed: %v\n", err)
+		}
+	}
+}
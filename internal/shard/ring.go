@@ -0,0 +1,98 @@
+// Package shard maps short codes to storage shards with consistent hashing.
+//
+// Each shard is placed on a hash ring at many points (virtual nodes); a key
+// belongs to the first shard point at or after the key's hash. Adding a shard
+// to N existing ones moves only about 1/(N+1) of the keys, all of them to the
+// new shard, so a rebalance copies the moved links and nothing else.
+package shard
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+// DefaultVirtualNodes spreads keys within a few percent of even for up to
+// dozens of shards
+const DefaultVirtualNodes = 160
+
+// Ring is an immutable consistent hash ring of shard names
+type Ring struct {
+	hashes []uint64          // Sorted points on the ring
+	owners map[uint64]string // Shard of each point
+	shards []string
+}
+
+// NewRing builds a ring of the named shards, each placed virtualNodes times
+// Names must be unique and non-empty; the ring depends only on the names,
+// not their order, so every instance computes the same placement.
+func NewRing(shards []string, virtualNodes int) (*Ring, error) {
+	if len(shards) == 0 {
+		return nil, errors.New("shard ring needs at least one shard")
+	}
+	if virtualNodes < 1 {
+		virtualNodes = DefaultVirtualNodes
+	}
+
+	r := &Ring{
+		hashes: make([]uint64, 0, len(shards)*virtualNodes),
+		owners: make(map[uint64]string, len(shards)*virtualNodes),
+		shards: append([]string(nil), shards...),
+	}
+	seen := make(map[string]bool, len(shards))
+	for _, name := range shards {
+		if name == "" {
+			return nil, errors.New("shard names must not be empty")
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate shard %q", name)
+		}
+		seen[name] = true
+
+		for i := 0; i < virtualNodes; i++ {
+			h := hashKey(fmt.Sprintf("%s#%d", name, i))
+			// On the (unlikely) collision the smaller name wins, keeping
+			// placement independent of shard order
+			if owner, ok := r.owners[h]; ok {
+				if name < owner {
+					r.owners[h] = name
+				}
+				continue
+			}
+			r.owners[h] = name
+			r.hashes = append(r.hashes, h)
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+	return r, nil
+}
+
+// Locate returns the shard a short code belongs to
+func (r *Ring) Locate(shortCode string) string {
+	h := hashKey(shortCode)
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0 // Wrap around
+	}
+	return r.owners[r.hashes[i]]
+}
+
+// Shards returns the shard names in configuration order
+func (r *Ring) Shards() []string {
+	return append([]string(nil), r.shards...)
+}
+
+// hashKey is 64-bit FNV-1a followed by the splitmix64 finalizer; FNV alone
+// leaves similar short keys (c1, c2, ...) clustered on the ring
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
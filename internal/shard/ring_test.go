@@ -0,0 +1,56 @@
+package shard
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRingPlacement tests that placement is stable, even and independent of order
+func TestRingPlacement(t *testing.T) {
+	ring, err := NewRing([]string{"s0", "s1", "s2", "s3"}, 0)
+	require.NoError(t, err)
+	reordered, err := NewRing([]string{"s3", "s1", "s0", "s2"}, 0)
+	require.NoError(t, err)
+
+	counts := map[string]int{}
+	for i := 0; i < 40000; i++ {
+		code := fmt.Sprintf("c%d", i)
+		shard := ring.Locate(code)
+		assert.Equal(t, shard, reordered.Locate(code))
+		counts[shard]++
+	}
+	for shard, n := range counts {
+		assert.InDelta(t, 10000, n, 2000, shard)
+	}
+}
+
+// TestRingGrowth tests that adding a shard only moves keys to the new shard
+func TestRingGrowth(t *testing.T) {
+	before, err := NewRing([]string{"s0", "s1", "s2"}, 0)
+	require.NoError(t, err)
+	after, err := NewRing([]string{"s0", "s1", "s2", "s3"}, 0)
+	require.NoError(t, err)
+
+	moved := 0
+	for i := 0; i < 40000; i++ {
+		code := fmt.Sprintf("c%d", i)
+		if from, to := before.Locate(code), after.Locate(code); from != to {
+			assert.Equal(t, "s3", to)
+			moved++
+		}
+	}
+	assert.InDelta(t, 10000, moved, 2000, "about a quarter of the keys move")
+}
+
+// TestNewRingErrors tests invalid shard lists
+func TestNewRingErrors(t *testing.T) {
+	_, err := NewRing(nil, 0)
+	assert.Error(t, err)
+	_, err = NewRing([]string{"s0", "s0"}, 0)
+	assert.Error(t, err)
+	_, err = NewRing([]string{""}, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,64 @@
+package summary
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// ============================================================================
+// SUMMARY REPORTS
+// ============================================================================
+// Users subscribe to daily or weekly summaries of their links (clicks, top
+// links, new links), delivered by email (SMTP) or as a JSON webhook.
+//
+// Periods are fixed UTC windows ending at the configured time of day (and,
+// for weekly summaries, day of the week): with daily_at 08:00 the daily
+// summary sent on March 10 covers March 9 08:00 to March 10 08:00. The
+// scheduler checks every minute or so which subscriptions haven't had the
+// latest period yet, claims each one in MySQL so only one instance sends
+// it, then builds and delivers it. A period missed while the service was
+// down is sent late; older missed periods are skipped.
+// ============================================================================
+
+// Schedule decides when summary periods end
+type Schedule struct {
+	At      time.Duration // Offset from midnight UTC
+	Weekday time.Weekday  // Day weekly periods end on
+}
+
+// ParseSchedule parses a time of day (HH:MM, UTC) and a weekday name
+func ParseSchedule(at, weekday string) (Schedule, error) {
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("invalid time of day %q (use HH:MM)", at)
+	}
+	s := Schedule{At: time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute}
+
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(weekday, d.String()) {
+			s.Weekday = d
+			return s, nil
+		}
+	}
+	return Schedule{}, fmt.Errorf("invalid weekday %q", weekday)
+}
+
+// LastPeriod returns the latest period of a frequency that ended at or
+// before now
+func (s Schedule) LastPeriod(frequency string, now time.Time) (from, to time.Time) {
+	now = now.UTC()
+	to = now.Truncate(24 * time.Hour).Add(s.At)
+	if to.After(now) {
+		to = to.AddDate(0, 0, -1)
+	}
+
+	if frequency == model.SummaryWeekly {
+		back := (int(to.Weekday()) - int(s.Weekday) + 7) % 7
+		to = to.AddDate(0, 0, -back)
+		return to.AddDate(0, 0, -7), to
+	}
+	return to.AddDate(0, 0, -1), to
+}
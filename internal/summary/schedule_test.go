@@ -0,0 +1,84 @@
+package summary
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseSchedule tests parsing the time of day and weekday
+func TestParseSchedule(t *testing.T) {
+	s, err := ParseSchedule("08:30", "Monday")
+	assert.NoError(t, err)
+	assert.Equal(t, 8*time.Hour+30*time.Minute, s.At)
+	assert.Equal(t, time.Monday, s.Weekday)
+
+	_, err = ParseSchedule("8am", "monday")
+	assert.Error(t, err)
+	_, err = ParseSchedule("08:00", "someday")
+	assert.Error(t, err)
+}
+
+// TestLastPeriod tests which period was last completed
+func TestLastPeriod(t *testing.T) {
+	s := Schedule{At: 8 * time.Hour, Weekday: time.Monday}
+	day := func(d, h int) time.Time { return time.Date(2024, 3, d, h, 0, 0, 0, time.UTC) }
+
+	// Sunday March 10, after 08:00: the day that just ended
+	from, to := s.LastPeriod(model.SummaryDaily, day(10, 9))
+	assert.Equal(t, day(9, 8), from)
+	assert.Equal(t, day(10, 8), to)
+
+	// Before 08:00 the previous day is still the latest
+	from, to = s.LastPeriod(model.SummaryDaily, day(10, 7))
+	assert.Equal(t, day(8, 8), from)
+	assert.Equal(t, day(9, 8), to)
+
+	// Exactly at 08:00 the period has just ended
+	_, to = s.LastPeriod(model.SummaryDaily, day(10, 8))
+	assert.Equal(t, day(10, 8), to)
+
+	// Weekly periods end on Monday 08:00
+	from, to = s.LastPeriod(model.SummaryWeekly, day(10, 9))
+	assert.Equal(t, day(4, 8), to)
+	assert.Equal(t, day(4, 8).AddDate(0, 0, -7), from)
+
+	from, to = s.LastPeriod(model.SummaryWeekly, day(11, 9))
+	assert.Equal(t, day(4, 8), from)
+	assert.Equal(t, day(11, 8), to)
+}
+
+// TestBuildMessage tests the email headers and CRLF line endings
+func TestBuildMessage(t *testing.T) {
+	msg := string(buildMessage("links@example.com", "me@example.com", "Your daily summary", "Clicks: 3\nNew links: 1\n"))
+
+	assert.Contains(t, msg, "From: links@example.com\r\n")
+	assert.Contains(t, msg, "To: me@example.com\r\n")
+	assert.Contains(t, msg, "Subject: Your daily summary\r\n")
+	assert.True(t, strings.HasSuffix(msg, "\r\n\r\nClicks: 3\r\nNew links: 1\r\n"))
+}
+
+// TestDefaultTemplate tests rendering the built-in email body
+func TestDefaultTemplate(t *testing.T) {
+	sender, err := NewSender(nil, "", time.Second, nil)
+	assert.NoError(t, err)
+	assert.False(t, sender.EmailEnabled())
+
+	var body strings.Builder
+	err = sender.tmpl.Execute(&body, &model.Summary{
+		Frequency:    model.SummaryDaily,
+		From:         time.Date(2024, 3, 9, 8, 0, 0, 0, time.UTC),
+		To:           time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC),
+		Clicks:       42,
+		TopLinks:     []model.SummaryLink{{ShortURL: "http://s.example/aB3", OriginalURL: "https://example.com", Clicks: 40}},
+		NewLinkCount: 1,
+		NewLinks:     []model.SummaryLink{{ShortURL: "http://s.example/x7K", OriginalURL: "https://example.org"}},
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, body.String(), "Clicks: 42")
+	assert.Contains(t, body.String(), "http://s.example/aB3  40 clicks  -> https://example.com")
+	assert.Contains(t, body.String(), "http://s.example/x7K  -> https://example.org")
+}
@@ -0,0 +1,192 @@
+package summary
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/Monthlyaway/short-link/internal/model"
+)
+
+// defaultTemplate is the plain-text email body, executed with a model.Summary
+const defaultTemplate = `Your {{.Frequency}} short link summary
+{{.From.Format "Jan 2 15:04"}} - {{.To.Format "Jan 2 15:04 MST"}}
+
+Clicks: {{.Clicks}}
+New links: {{.NewLinkCount}}
+
+Top links:
+{{range .TopLinks}}  {{.ShortURL}}  {{.Clicks}} clicks  -> {{.OriginalURL}}
+{{else}}  No clicks in this period.
+{{end}}{{if .NewLinks}}
+New links:
+{{range .NewLinks}}  {{.ShortURL}}  -> {{.OriginalURL}}
+{{end}}{{end}}`
+
+// SMTPConfig is how summary emails are sent
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string // PLAIN auth when set
+	Password string
+	From     string
+}
+
+// Sender delivers summaries by email or webhook
+type Sender struct {
+	smtp    *SMTPConfig // nil when email isn't configured
+	tmpl    *template.Template
+	client  *http.Client
+	timeout time.Duration
+}
+
+// NewSender creates a sender; smtpConfig may be nil to disable email
+// templatePath replaces the built-in email body when not empty. Webhook
+// connections are checked by dialControl when set (see
+// service.DestinationPolicy.Control), as users choose the webhook URLs.
+func NewSender(smtpConfig *SMTPConfig, templatePath string, timeout time.Duration, dialControl func(network, address string, c syscall.RawConn) error) (*Sender, error) {
+	text := defaultTemplate
+	if templatePath != "" {
+		data, err := os.ReadFile(templatePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read summary template: %w", err)
+		}
+		text = string(data)
+	}
+	tmpl, err := template.New("summary").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse summary template: %w", err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: timeout, Control: dialControl}).DialContext
+	return &Sender{
+		smtp:    smtpConfig,
+		tmpl:    tmpl,
+		client:  &http.Client{Timeout: timeout, Transport: transport},
+		timeout: timeout,
+	}, nil
+}
+
+// EmailEnabled reports whether summaries can be sent by email
+func (s *Sender) EmailEnabled() bool {
+	return s.smtp != nil
+}
+
+// Send delivers a summary through the subscription's channel
+func (s *Sender) Send(ctx context.Context, sub *model.SummarySubscription, summary *model.Summary) error {
+	switch sub.Channel {
+	case model.SummaryEmail:
+		return s.sendEmail(ctx, sub.Target, summary)
+	case model.SummaryWebhook:
+		return s.sendWebhook(ctx, sub.Target, summary)
+	default:
+		return fmt.Errorf("unsupported summary channel %q", sub.Channel)
+	}
+}
+
+// sendWebhook POSTs the summary as JSON
+func (s *Sender) sendWebhook(ctx context.Context, url string, summary *model.Summary) error {
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to encode summary: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call summary webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("summary webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// sendEmail renders the template and sends it as a plain-text email
+// net/smtp has no deadlines of its own, so the connection gets one
+func (s *Sender) sendEmail(ctx context.Context, to string, summary *model.Summary) error {
+	if s.smtp == nil {
+		return fmt.Errorf("email summaries are not configured")
+	}
+	var body bytes.Buffer
+	if err := s.tmpl.Execute(&body, summary); err != nil {
+		return fmt.Errorf("failed to render summary: %w", err)
+	}
+	msg := buildMessage(s.smtp.From, to, fmt.Sprintf("Your %s short link summary", summary.Frequency), body.String())
+
+	addr := net.JoinHostPort(s.smtp.Host, strconv.Itoa(s.smtp.Port))
+	dialer := &net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(s.timeout))
+
+	client, err := smtp.NewClient(conn, s.smtp.Host)
+	if err != nil {
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.smtp.Host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if s.smtp.Username != "" {
+		auth := smtp.PlainAuth("", s.smtp.Username, s.smtp.Password, s.smtp.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("failed to authenticate to SMTP server: %w", err)
+		}
+	}
+	if err := client.Mail(s.smtp.From); err != nil {
+		return fmt.Errorf("failed to send summary email: %w", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("failed to send summary email: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to send summary email: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to send summary email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send summary email: %w", err)
+	}
+	return client.Quit()
+}
+
+// buildMessage assembles a plain-text email with CRLF line endings
+func buildMessage(from, to, subject, body string) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return msg.Bytes()
+}
@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ============================================================================
+// Distributed Tracing (OpenTelemetry)
+// ============================================================================
+// A redirect crosses several layers; each one opens a span so a slow
+// request shows where the time went:
+//
+//   GET /:short_code                (HTTP middleware)
+//   └── URLService.ResolveLink
+//       ├── bloom_filter.test
+//       ├── cache.get               (local LRU, then Redis)
+//       └── mysql SELECT            (GORM callbacks, on cache miss)
+//
+// Trace context is propagated with W3C traceparent/baggage headers (HTTP)
+// and metadata (gRPC). Spans are exported over OTLP/gRPC to a collector.
+// When tracing is disabled the global no-op tracer is used, so the
+// instrumentation costs next to nothing.
+// ============================================================================
+
+// InstrumentationName identifies spans created by this service
+const InstrumentationName = "github.com/Monthlyaway/short-link"
+
+// Config represents tracing setup options
+type Config struct {
+	ServiceName string
+	Endpoint    string  // OTLP/gRPC collector address, e.g. localhost:4317
+	Insecure    bool    // Plaintext connection to the collector
+	SampleRatio float64 // Fraction of new traces recorded, 0..1
+}
+
+// Setup installs the global tracer provider and propagator
+// Returns a shutdown function that flushes pending spans
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		// Honor the caller's sampling decision; sample new traces by ratio
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	SetupPropagation()
+
+	return provider.Shutdown, nil
+}
+
+// SetupPropagation installs the W3C trace context and baggage propagators
+func SetupPropagation() {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+}
+
+// Tracer returns the service's tracer from the global provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// Start starts a span as a child of the span in ctx
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// Detach returns a background context carrying ctx's span, for work that
+// outlives the request (async writes) but should stay in the same trace
+func Detach(ctx context.Context) context.Context {
+	return trace.ContextWithSpan(context.Background(), trace.SpanFromContext(ctx))
+}
+
+// EndSpan records err (if any) on span and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
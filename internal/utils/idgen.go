@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+// ============================================================================
+// Short Code Generation Strategies
+// ============================================================================
+// - snowflake: time-ordered 64-bit IDs; needs a unique datacenter/worker
+//              ID per instance. Codes reveal creation order.
+// - random:    random Base62 strings from crypto/rand; no coordination,
+//              nothing to enumerate. Collisions are caught by the caller,
+//              which checks the database and re-rolls.
+// - sequence:  a MySQL auto-increment counter plus an offset (so the first
+//              codes aren't "1", "2", ...). Shortest codes, single source
+//              of truth, one extra write per link.
+// ============================================================================
+
+// Generation strategies selectable in config
+const (
+	StrategySnowflake = "snowflake"
+	StrategyRandom    = "random"
+	StrategySequence  = "sequence"
+)
+
+// IDGenerator produces candidate short codes
+// Callers must still check that a code is free (collisions, reserved words)
+type IDGenerator interface {
+	NextCode(ctx context.Context) (string, error)
+}
+
+// RandomGenerator generates random fixed-length Base62 codes
+type RandomGenerator struct {
+	length int
+}
+
+// NewRandomGenerator creates a random generator for codes of length characters
+// 7 characters give 62^7 ≈ 3.5e12 codes, so collisions stay rare for
+// billions of links
+func NewRandomGenerator(length int) *RandomGenerator {
+	return &RandomGenerator{length: length}
+}
+
+// NextCode implements IDGenerator
+func (g *RandomGenerator) NextCode(ctx context.Context) (string, error) {
+	code := make([]byte, g.length)
+	max := big.NewInt(int64(len(base62Chars)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to read random bytes: %w", err)
+		}
+		code[i] = base62Chars[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// SequenceSource returns the next value of a monotonically increasing counter
+type SequenceSource interface {
+	NextSequence(ctx context.Context) (int64, error)
+}
+
+// SequenceGenerator generates codes from a database sequence plus an offset
+type SequenceGenerator struct {
+	source SequenceSource
+	offset int64
+	perm   *Permutation
+}
+
+// NewSequenceGenerator creates a sequence generator
+// An offset of 62^5 (916132832) makes every code at least 6 characters long
+func NewSequenceGenerator(source SequenceSource, offset int64) *SequenceGenerator {
+	return &SequenceGenerator{source: source, offset: offset}
+}
+
+// SetPermutation scrambles sequence values before encoding so codes don't
+// reveal creation order; offset plus sequence must stay inside its domain
+func (g *SequenceGenerator) SetPermutation(perm *Permutation) {
+	g.perm = perm
+}
+
+// NextCode implements IDGenerator
+func (g *SequenceGenerator) NextCode(ctx context.Context) (string, error) {
+	seq, err := g.source.NextSequence(ctx)
+	if err != nil {
+		return "", err
+	}
+	return encodeID(seq+g.offset, g.perm)
+}
+
+// encodeID Base62-encodes id, permuting it first when perm is set
+func encodeID(id int64, perm *Permutation) (string, error) {
+	if perm == nil {
+		return EncodeBase62(id), nil
+	}
+	permuted, err := perm.Apply(uint64(id))
+	if err != nil {
+		return "", fmt.Errorf("failed to obfuscate ID: %w", err)
+	}
+	return EncodeBase62(int64(permuted)), nil
+}
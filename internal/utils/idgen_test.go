@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSnowflakeGenerator tests that snowflake codes are unique and decodable
+func TestSnowflakeGenerator(t *testing.T) {
+	gen, err := NewSnowflakeGenerator(1, 1, time.Time{})
+	assert.NoError(t, err)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		code, err := gen.NextCode(context.Background())
+		assert.NoError(t, err)
+		assert.False(t, seen[code])
+		seen[code] = true
+		assert.Equal(t, code, EncodeBase62(DecodeBase62(code)))
+	}
+
+	_, err = NewSnowflakeGenerator(32, 1, time.Time{})
+	assert.ErrorContains(t, err, "datacenter ID 32 is out of range 0-31")
+	_, err = NewSnowflakeGenerator(1, -1, time.Time{})
+	assert.ErrorContains(t, err, "worker ID -1 is out of range 0-31")
+}
+
+// TestSnowflakeEpoch tests that IDs count from the configured epoch
+func TestSnowflakeEpoch(t *testing.T) {
+	gen, err := NewSnowflakeGenerator(1, 1, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	code, err := gen.NextCode(context.Background())
+	assert.NoError(t, err)
+	millis := DecodeBase62(code) >> 22 // Above the node and sequence bits
+	assert.InDelta(t, time.Hour.Milliseconds(), millis, float64(time.Minute.Milliseconds()))
+
+	_, err = NewSnowflakeGenerator(1, 1, time.Now().Add(time.Hour))
+	assert.Error(t, err)
+}
+
+// fakeLease is a WorkerLease held while held is true
+type fakeLease struct{ held bool }
+
+func (l *fakeLease) Held() bool { return l.held }
+
+// TestSnowflakeLease tests that no codes are generated without the lease
+func TestSnowflakeLease(t *testing.T) {
+	gen, err := NewSnowflakeGenerator(1, 1, time.Time{})
+	assert.NoError(t, err)
+	lease := &fakeLease{held: true}
+	gen.SetLease(lease)
+
+	_, err = gen.NextCode(context.Background())
+	assert.NoError(t, err)
+	lease.held = false
+	_, err = gen.NextCode(context.Background())
+	assert.ErrorIs(t, err, ErrWorkerIDLost)
+}
+
+// TestSnowflakeClockBehind tests that codes are held back until the clock
+// reaches the last recorded ID time
+func TestSnowflakeClockBehind(t *testing.T) {
+	gen, err := NewSnowflakeGenerator(1, 1, time.Time{})
+	assert.NoError(t, err)
+	assert.True(t, gen.LastTimestamp().IsZero())
+
+	gen.SetNotBefore(time.Now().Add(time.Hour))
+	_, err = gen.NextCode(context.Background())
+	assert.ErrorIs(t, err, ErrClockBehind)
+
+	gen.SetNotBefore(time.Now().Add(-time.Second))
+	_, err = gen.NextCode(context.Background())
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), gen.LastTimestamp(), time.Second)
+}
+
+// TestRandomGenerator tests code length and alphabet
+func TestRandomGenerator(t *testing.T) {
+	gen := NewRandomGenerator(7)
+	for i := 0; i < 100; i++ {
+		code, err := gen.NextCode(context.Background())
+		assert.NoError(t, err)
+		assert.Len(t, code, 7)
+		for _, c := range code {
+			assert.Contains(t, base62Chars, string(c))
+		}
+	}
+}
+
+// fakeSequence counts up from 1
+type fakeSequence struct{ n int64 }
+
+func (f *fakeSequence) NextSequence(ctx context.Context) (int64, error) {
+	f.n++
+	return f.n, nil
+}
+
+// TestSequenceGenerator tests that the offset is applied
+func TestSequenceGenerator(t *testing.T) {
+	gen := NewSequenceGenerator(&fakeSequence{}, 916132832)
+
+	first, err := gen.NextCode(context.Background())
+	assert.NoError(t, err)
+	second, err := gen.NextCode(context.Background())
+	assert.NoError(t, err)
+
+	assert.Len(t, first, 6)
+	assert.Equal(t, int64(916132833), DecodeBase62(first))
+	assert.Equal(t, int64(916132834), DecodeBase62(second))
+}
+
+// TestObfuscatedSequence tests that permuted codes are unique and unordered
+func TestObfuscatedSequence(t *testing.T) {
+	perm, err := NewPermutation("secret", 36)
+	assert.NoError(t, err)
+	gen := NewSequenceGenerator(&fakeSequence{}, 916132832)
+	gen.SetPermutation(perm)
+
+	seen := make(map[string]bool)
+	var previous int64
+	increasing := 0
+	for i := 0; i < 1000; i++ {
+		code, err := gen.NextCode(context.Background())
+		assert.NoError(t, err)
+		assert.False(t, seen[code])
+		seen[code] = true
+
+		value := DecodeBase62(code)
+		if value > previous {
+			increasing++
+		}
+		previous = value
+	}
+	// Ordered codes would increase every time
+	assert.Less(t, increasing, 700)
+}
+
+// BenchmarkSnowflakeGenerator measures producing a code for a new link
+func BenchmarkSnowflakeGenerator(b *testing.B) {
+	gen, err := NewSnowflakeGenerator(1, 1, time.Time{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := gen.NextCode(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkRandomGenerator measures producing a random code
+func BenchmarkRandomGenerator(b *testing.B) {
+	gen := NewRandomGenerator(7)
+	ctx := context.Background()
+	for i := 0; i < b.N; i++ {
+		if _, err := gen.NextCode(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// ============================================================================
+// ID Obfuscation (Feistel Permutation)
+// ============================================================================
+// Snowflake and sequence IDs increase over time, so their codes can be
+// enumerated: decode a fresh code, subtract a little, encode again. A keyed
+// permutation of the ID space scrambles the order while staying one-to-one:
+// distinct IDs still give distinct codes, so there is nothing to retry.
+//
+// A Feistel network is a bijection for any round function. It works on an
+// even number of bits; for other domain sizes we "cycle-walk": re-apply the
+// permutation until the result falls back inside the domain.
+//
+// This hides ordering from casual enumeration; it is not encryption, and
+// codes of existing links are unaffected when the key changes.
+// ============================================================================
+
+// feistelRounds is enough rounds for good mixing
+const feistelRounds = 6
+
+// Permutation is a keyed bijection on [0, 2^bits)
+type Permutation struct {
+	bits     uint
+	halfBits uint
+	halfMask uint64
+	keys     [feistelRounds]uint64
+}
+
+// NewPermutation creates a permutation of [0, 2^bits) keyed by secret
+func NewPermutation(secret string, bits int) (*Permutation, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("permutation key must not be empty")
+	}
+	if bits < 2 || bits > 64 {
+		return nil, fmt.Errorf("permutation bits must be between 2 and 64, got %d", bits)
+	}
+
+	p := &Permutation{bits: uint(bits)}
+	p.halfBits = (p.bits + 1) / 2
+	p.halfMask = 1<<p.halfBits - 1
+
+	// Derive independent round keys from the secret
+	for i := range p.keys {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d", secret, i)))
+		p.keys[i] = binary.BigEndian.Uint64(sum[:8])
+	}
+	return p, nil
+}
+
+// Apply maps x to its permuted value
+// x must be below 2^bits
+func (p *Permutation) Apply(x uint64) (uint64, error) {
+	if !p.inDomain(x) {
+		return 0, fmt.Errorf("value %d exceeds %d-bit permutation domain", x, p.bits)
+	}
+	for {
+		x = p.feistel(x, false)
+		if p.inDomain(x) {
+			return x, nil
+		}
+	}
+}
+
+// Invert maps a permuted value back to the original
+func (p *Permutation) Invert(y uint64) (uint64, error) {
+	if !p.inDomain(y) {
+		return 0, fmt.Errorf("value %d exceeds %d-bit permutation domain", y, p.bits)
+	}
+	for {
+		y = p.feistel(y, true)
+		if p.inDomain(y) {
+			return y, nil
+		}
+	}
+}
+
+// inDomain reports whether x is below 2^bits
+func (p *Permutation) inDomain(x uint64) bool {
+	return p.bits == 64 || x < 1<<p.bits
+}
+
+// feistel runs the network over 2*halfBits bits, forwards or in reverse
+func (p *Permutation) feistel(x uint64, reverse bool) uint64 {
+	left := (x >> p.halfBits) & p.halfMask
+	right := x & p.halfMask
+
+	for i := 0; i < feistelRounds; i++ {
+		if reverse {
+			left, right = right^p.round(left, p.keys[feistelRounds-1-i]), left
+		} else {
+			left, right = right, left^p.round(right, p.keys[i])
+		}
+	}
+	return left<<p.halfBits | right
+}
+
+// round is the Feistel round function (splitmix64 finalizer of value^key)
+func (p *Permutation) round(value, key uint64) uint64 {
+	z := value ^ key
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+	return z & p.halfMask
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPermutationRoundTrip tests that Invert undoes Apply
+func TestPermutationRoundTrip(t *testing.T) {
+	for _, bits := range []int{9, 36, 63, 64} {
+		p, err := NewPermutation("secret", bits)
+		assert.NoError(t, err)
+
+		for _, x := range []uint64{0, 1, 2, 255, 1 << (bits - 1)} {
+			y, err := p.Apply(x)
+			assert.NoError(t, err)
+			back, err := p.Invert(y)
+			assert.NoError(t, err)
+			assert.Equal(t, x, back, "bits=%d x=%d", bits, x)
+		}
+	}
+}
+
+// TestPermutationIsBijection tests that a small domain maps onto itself one-to-one
+func TestPermutationIsBijection(t *testing.T) {
+	p, err := NewPermutation("secret", 11)
+	assert.NoError(t, err)
+
+	seen := make(map[uint64]bool)
+	for x := uint64(0); x < 1<<11; x++ {
+		y, err := p.Apply(x)
+		assert.NoError(t, err)
+		assert.Less(t, y, uint64(1<<11))
+		assert.False(t, seen[y])
+		seen[y] = true
+	}
+}
+
+// TestPermutationScramblesOrder tests that consecutive IDs don't give consecutive values
+func TestPermutationScramblesOrder(t *testing.T) {
+	p, _ := NewPermutation("secret", 36)
+	a, _ := p.Apply(1000)
+	b, _ := p.Apply(1001)
+	assert.NotEqual(t, a+1, b)
+
+	other, _ := NewPermutation("other", 36)
+	c, _ := other.Apply(1000)
+	assert.NotEqual(t, a, c)
+}
+
+// TestPermutationErrors tests key and domain validation
+func TestPermutationErrors(t *testing.T) {
+	_, err := NewPermutation("", 36)
+	assert.Error(t, err)
+	_, err = NewPermutation("secret", 65)
+	assert.Error(t, err)
+
+	p, _ := NewPermutation("secret", 8)
+	_, err = p.Apply(256)
+	assert.Error(t, err)
+}
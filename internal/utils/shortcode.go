@@ -6,15 +6,6 @@ import (
 
 const base62Chars = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 
-// GenerateShortCode generates a short code from a snowflake ID using Base62 encoding
-func GenerateShortCode() (string, error) {
-	id, err := GenerateID()
-	if err != nil {
-		return "", err
-	}
-	return EncodeBase62(id), nil
-}
-
 // EncodeBase62 converts a decimal number to Base62 encoding
 func EncodeBase62(num int64) string {
 	if num == 0 {
@@ -1,39 +1,148 @@
 package utils
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/bwmarrin/snowflake"
 )
 
-var (
-	node *snowflake.Node
-	once sync.Once
-)
+// SnowflakeGenerator generates codes from snowflake IDs
+// Codes are unique across instances as long as every instance has its own
+// datacenter/worker ID pair, but they increase with creation time.
+type SnowflakeGenerator struct {
+	node  *snowflake.Node
+	perm  *Permutation
+	lease WorkerLease
+	epoch time.Time
 
-// InitSnowflake initializes the snowflake node with datacenter and worker IDs
-func InitSnowflake(datacenterID, workerID int64) error {
-	var err error
-	once.Do(func() {
-		// Combine datacenter ID and worker ID into a single node ID
-		// DatacenterID uses 5 bits (0-31), WorkerID uses 5 bits (0-31)
-		nodeID := (datacenterID << 5) | workerID
+	// notBefore holds back IDs after a clock rollback (see SetNotBefore)
+	notBefore time.Time
+	// lastMillis is the timestamp of the newest ID, in Unix milliseconds
+	lastMillis atomic.Int64
+}
 
-		node, err = snowflake.NewNode(nodeID)
-	})
-	return err
+// WorkerLease is a worker ID leased from a registry shared by all instances
+// (see cache.WorkerIDLease) rather than set in config
+type WorkerLease interface {
+	// Held reports whether this instance still holds the worker ID
+	Held() bool
 }
 
-// GenerateID generates a unique snowflake ID
-func GenerateID() (int64, error) {
-	if node == nil {
-		return 0, fmt.Errorf("snowflake node not initialized")
+// ErrClockBehind is returned while the clock is behind the time an earlier
+// process generated IDs at with the same node ID
+var ErrClockBehind = errors.New("clock is behind the last snowflake ID")
+
+// MaxSnowflakeID is the largest datacenter or worker ID (5 bits each)
+const MaxSnowflakeID = 31
+
+// ErrWorkerIDLost is returned while the generator's worker ID lease isn't
+// held; another instance may be using the ID
+var ErrWorkerIDLost = errors.New("snowflake worker ID lease lost")
+
+// DefaultSnowflakeEpoch is the Twitter snowflake epoch used when none is set
+var DefaultSnowflakeEpoch = time.UnixMilli(snowflake.Epoch)
+
+// snowflakeEpochMu guards the snowflake package's global Epoch, which
+// NewNode reads
+var snowflakeEpochMu sync.Mutex
+
+// NewSnowflakeGenerator creates a snowflake generator for a datacenter and
+// worker; IDs count milliseconds from epoch (zero uses
+// DefaultSnowflakeEpoch)
+func NewSnowflakeGenerator(datacenterID, workerID int64, epoch time.Time) (*SnowflakeGenerator, error) {
+	// Out of range IDs spill into each other's bits: datacenter 0 with
+	// worker 40 would be datacenter 1 with worker 8
+	if datacenterID < 0 || datacenterID > MaxSnowflakeID {
+		return nil, fmt.Errorf("datacenter ID %d is out of range 0-%d", datacenterID, MaxSnowflakeID)
+	}
+	if workerID < 0 || workerID > MaxSnowflakeID {
+		return nil, fmt.Errorf("worker ID %d is out of range 0-%d", workerID, MaxSnowflakeID)
+	}
+
+	// Combine datacenter ID and worker ID into a single node ID
+	// DatacenterID uses 5 bits (0-31), WorkerID uses 5 bits (0-31)
+	nodeID := SnowflakeNodeID(datacenterID, workerID)
+
+	if epoch.IsZero() {
+		epoch = DefaultSnowflakeEpoch
+	}
+	if epoch.After(time.Now()) {
+		return nil, fmt.Errorf("snowflake epoch %s is in the future", epoch.Format(time.RFC3339))
+	}
+
+	snowflakeEpochMu.Lock()
+	defer snowflakeEpochMu.Unlock()
+	previous := snowflake.Epoch
+	snowflake.Epoch = epoch.UnixMilli()
+	node, err := snowflake.NewNode(nodeID)
+	snowflake.Epoch = previous
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snowflake node: %w", err)
 	}
-	return node.Generate().Int64(), nil
+	return &SnowflakeGenerator{node: node, epoch: epoch}, nil
+}
+
+// SnowflakeNodeID combines a datacenter and worker ID into a node ID
+func SnowflakeNodeID(datacenterID, workerID int64) int64 {
+	return datacenterID<<5 | workerID
 }
 
-// GetNode returns the snowflake node instance
-func GetNode() *snowflake.Node {
-	return node
+// SnowflakeBits is the size of the snowflake ID space (IDs are positive int64)
+const SnowflakeBits = 63
+
+// SetPermutation scrambles IDs before encoding so codes don't reveal
+// creation order; perm must cover SnowflakeBits
+func (g *SnowflakeGenerator) SetPermutation(perm *Permutation) {
+	g.perm = perm
+}
+
+// SetLease makes the generator refuse to produce codes while lease isn't
+// held, so two instances never generate with the same worker ID
+func (g *SnowflakeGenerator) SetLease(lease WorkerLease) {
+	g.lease = lease
+}
+
+// SetNotBefore makes the generator refuse to produce IDs until the clock
+// reaches t, the newest ID an earlier process generated with this node ID;
+// IDs from a clock behind that could repeat its IDs
+func (g *SnowflakeGenerator) SetNotBefore(t time.Time) {
+	g.notBefore = t
+}
+
+// LastTimestamp returns the time of the newest ID generated, or the zero
+// time if there is none
+func (g *SnowflakeGenerator) LastTimestamp() time.Time {
+	millis := g.lastMillis.Load()
+	if millis == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(millis)
+}
+
+// NextCode implements IDGenerator
+func (g *SnowflakeGenerator) NextCode(ctx context.Context) (string, error) {
+	if g.lease != nil && !g.lease.Held() {
+		return "", ErrWorkerIDLost
+	}
+	if behind := time.Until(g.notBefore); behind > 0 {
+		return "", fmt.Errorf("%w by %v", ErrClockBehind, behind.Round(time.Millisecond))
+	}
+	id := g.node.Generate().Int64()
+	g.recordTimestamp(g.epoch.UnixMilli() + id>>(snowflake.NodeBits+snowflake.StepBits))
+	return encodeID(id, g.perm)
+}
+
+// recordTimestamp raises lastMillis to millis
+func (g *SnowflakeGenerator) recordTimestamp(millis int64) {
+	for {
+		last := g.lastMillis.Load()
+		if millis <= last || g.lastMillis.CompareAndSwap(last, millis) {
+			return
+		}
+	}
 }
@@ -1,7 +1,4 @@
--- Create database
-CREATE DATABASE IF NOT EXISTS url_shortener CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
-USE url_shortener;
-
+-- +goose Up
 -- Short link mapping table
 CREATE TABLE IF NOT EXISTS `url_mappings` (
   `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'Auto-increment ID',
@@ -28,3 +25,7 @@ CREATE TABLE IF NOT EXISTS `visit_logs` (
   KEY `idx_short_code` (`short_code`),
   KEY `idx_visited_at` (`visited_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Visit log table';
+
+-- +goose Down
+DROP TABLE IF EXISTS `visit_logs`;
+DROP TABLE IF EXISTS `url_mappings`;
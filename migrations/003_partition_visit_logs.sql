@@ -0,0 +1,25 @@
+-- Partition visit_logs by day so old data can be dropped partition by partition
+-- instead of with slow, lock-heavy DELETEs.
+-- MySQL requires the partitioning column to be part of every unique key,
+-- so the primary key becomes (id, visited_at).
+-- Daily partitions are created ahead of time by the retention job, which
+-- splits them off p_future.
+
+-- +goose Up
+ALTER TABLE `visit_logs`
+  MODIFY COLUMN `visited_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  DROP PRIMARY KEY,
+  ADD PRIMARY KEY (`id`, `visited_at`);
+
+ALTER TABLE `visit_logs`
+  PARTITION BY RANGE (UNIX_TIMESTAMP(`visited_at`)) (
+    PARTITION p_future VALUES LESS THAN MAXVALUE
+  );
+
+-- +goose Down
+ALTER TABLE `visit_logs` REMOVE PARTITIONING;
+
+ALTER TABLE `visit_logs`
+  DROP PRIMARY KEY,
+  ADD PRIMARY KEY (`id`),
+  MODIFY COLUMN `visited_at` TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP;
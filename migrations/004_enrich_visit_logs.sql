@@ -0,0 +1,22 @@
+-- Add referrer, geo and client fields to visit_logs
+-- Populated at logging time from the Referer header, GeoIP and User-Agent
+
+-- +goose Up
+ALTER TABLE `visit_logs`
+  ADD COLUMN `referrer` VARCHAR(2048) DEFAULT NULL AFTER `user_agent`,
+  ADD COLUMN `country` CHAR(2) DEFAULT NULL COMMENT 'ISO 3166-1 alpha-2' AFTER `referrer`,
+  ADD COLUMN `city` VARCHAR(128) DEFAULT NULL AFTER `country`,
+  ADD COLUMN `device_type` VARCHAR(16) DEFAULT NULL AFTER `city`,
+  ADD COLUMN `browser` VARCHAR(32) DEFAULT NULL AFTER `device_type`,
+  ADD COLUMN `os` VARCHAR(32) DEFAULT NULL AFTER `browser`,
+  ADD KEY `idx_country` (`country`);
+
+-- +goose Down
+ALTER TABLE `visit_logs`
+  DROP KEY `idx_country`,
+  DROP COLUMN `os`,
+  DROP COLUMN `browser`,
+  DROP COLUMN `device_type`,
+  DROP COLUMN `city`,
+  DROP COLUMN `country`,
+  DROP COLUMN `referrer`;
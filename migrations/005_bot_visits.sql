@@ -0,0 +1,13 @@
+-- Count bot/crawler/link-preview visits separately from human visits
+
+-- +goose Up
+ALTER TABLE `url_mappings`
+  ADD COLUMN `bot_visit_count` BIGINT UNSIGNED DEFAULT 0 COMMENT 'Bot and link-preview visits' AFTER `visit_count`;
+
+ALTER TABLE `visit_logs`
+  ADD COLUMN `is_bot` TINYINT(1) NOT NULL DEFAULT 0 AFTER `os`;
+
+-- +goose Down
+ALTER TABLE `visit_logs` DROP COLUMN `is_bot`;
+
+ALTER TABLE `url_mappings` DROP COLUMN `bot_visit_count`;
@@ -0,0 +1,12 @@
+-- Serve links on multiple domains
+-- Short codes stay globally unique; domain restricts which host resolves them
+
+-- +goose Up
+ALTER TABLE `url_mappings`
+  ADD COLUMN `domain` VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'Serving host; empty matches any host' AFTER `original_url`,
+  ADD KEY `idx_domain_original_url` (`domain`, `original_url`(255));
+
+-- +goose Down
+ALTER TABLE `url_mappings`
+  DROP KEY `idx_domain_original_url`,
+  DROP COLUMN `domain`;
@@ -0,0 +1,13 @@
+-- Soft delete for short links
+-- Deleted links keep their row (and short code) until purged, so they can be restored
+
+-- +goose Up
+ALTER TABLE `url_mappings`
+  ADD COLUMN `deleted_at` TIMESTAMP NULL DEFAULT NULL COMMENT 'Soft delete time; NULL if not deleted',
+  ADD KEY `idx_deleted_at` (`deleted_at`);
+
+-- +goose Down
+DELETE FROM `url_mappings` WHERE `deleted_at` IS NOT NULL;
+ALTER TABLE `url_mappings`
+  DROP KEY `idx_deleted_at`,
+  DROP COLUMN `deleted_at`;
@@ -0,0 +1,16 @@
+-- Reserved short codes and blocked words
+-- Managed by operators; merged with the built-in and configured lists at startup and on SIGHUP
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `reserved_codes` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `code` VARCHAR(64) NOT NULL COMMENT 'Reserved code or blocked word (case-insensitive)',
+  `substring` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '1: block codes containing it, 0: exact match only',
+  `reason` VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'Why it is reserved',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_code` (`code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Reserved short codes';
+
+-- +goose Down
+DROP TABLE IF EXISTS `reserved_codes`;
@@ -0,0 +1,13 @@
+-- Counter for the "sequence" short code strategy
+-- Single-row ticket table: REPLACE bumps AUTO_INCREMENT without growing the table
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `short_code_sequence` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `stub` CHAR(1) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_stub` (`stub`)
+) ENGINE=InnoDB COMMENT='Short code sequence';
+
+-- +goose Down
+DROP TABLE IF EXISTS `short_code_sequence`;
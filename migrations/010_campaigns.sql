@@ -0,0 +1,24 @@
+-- Campaigns group short links for aggregated reporting
+-- A link may belong to several campaigns
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `campaigns` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `name` VARCHAR(128) NOT NULL COMMENT 'Unique campaign name',
+  `description` VARCHAR(1024) NOT NULL DEFAULT '',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Campaigns';
+
+CREATE TABLE IF NOT EXISTS `campaign_links` (
+  `campaign_id` BIGINT UNSIGNED NOT NULL,
+  `short_code` VARCHAR(15) NOT NULL,
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`campaign_id`, `short_code`),
+  KEY `idx_short_code` (`short_code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Campaign membership';
+
+-- +goose Down
+DROP TABLE IF EXISTS `campaign_links`;
+DROP TABLE IF EXISTS `campaigns`;
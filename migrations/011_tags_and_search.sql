@@ -0,0 +1,25 @@
+-- Free-form tags on links, and full-text search over original URLs
+-- The ngram parser indexes substrings, so URL fragments like "utm" or "blog/2024" match
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `tags` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `name` VARCHAR(64) NOT NULL COMMENT 'Lower-case tag name',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Tags';
+
+CREATE TABLE IF NOT EXISTS `url_mapping_tags` (
+  `url_mapping_id` BIGINT UNSIGNED NOT NULL,
+  `tag_id` BIGINT UNSIGNED NOT NULL,
+  PRIMARY KEY (`url_mapping_id`, `tag_id`),
+  KEY `idx_tag_id` (`tag_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Link tags';
+
+ALTER TABLE `url_mappings`
+  ADD FULLTEXT KEY `ft_original_url` (`original_url`) WITH PARSER ngram;
+
+-- +goose Down
+ALTER TABLE `url_mappings` DROP KEY `ft_original_url`;
+DROP TABLE IF EXISTS `url_mapping_tags`;
+DROP TABLE IF EXISTS `tags`;
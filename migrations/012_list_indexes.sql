@@ -0,0 +1,33 @@
+-- Listing links: destination host filter and indexes for each sort
+-- Secondary indexes end with the primary key, so (visit_count) already orders
+-- by (visit_count, id) as keyset pagination needs; the same holds for idx_created_at
+
+-- +goose Up
+ALTER TABLE `url_mappings`
+  ADD COLUMN `destination_host` VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'Lower-case host of original_url' AFTER `original_url`,
+  ADD KEY `idx_visit_count` (`visit_count`),
+  ADD KEY `idx_status_created_at` (`status`, `created_at`),
+  ADD KEY `idx_destination_host_created_at` (`destination_host`, `created_at`);
+
+-- Backfill: strip scheme, userinfo, path, query, fragment and port
+UPDATE `url_mappings`
+SET `destination_host` = LOWER(
+  SUBSTRING_INDEX(
+    SUBSTRING_INDEX(
+      SUBSTRING_INDEX(
+        SUBSTRING_INDEX(
+          SUBSTRING_INDEX(
+            SUBSTRING_INDEX(`original_url`, '://', -1),
+          '/', 1),
+        '?', 1),
+      '#', 1),
+    '@', -1),
+  ':', 1)
+);
+
+-- +goose Down
+ALTER TABLE `url_mappings`
+  DROP KEY `idx_destination_host_created_at`,
+  DROP KEY `idx_status_created_at`,
+  DROP KEY `idx_visit_count`,
+  DROP COLUMN `destination_host`;
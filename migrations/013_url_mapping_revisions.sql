@@ -0,0 +1,21 @@
+-- Edit history of links: every change of destination or expiry is recorded
+-- with who made it, so a widely shared link can be audited after it changed
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `url_mapping_revisions` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `short_code` VARCHAR(15) NOT NULL,
+  `revision` INT UNSIGNED NOT NULL COMMENT '1 for the first recorded state, then +1 per change',
+  `action` VARCHAR(16) NOT NULL COMMENT 'create, import, clone, update',
+  `original_url` VARCHAR(2048) NOT NULL COMMENT 'Destination after the change',
+  `previous_url` VARCHAR(2048) NOT NULL DEFAULT '' COMMENT 'Destination before the change',
+  `expired_at` TIMESTAMP NULL DEFAULT NULL COMMENT 'Expiration after the change',
+  `cloned_from` VARCHAR(15) NOT NULL DEFAULT '' COMMENT 'Source short code of a clone',
+  `changed_by` VARCHAR(128) NOT NULL DEFAULT '' COMMENT 'user:<id>, api_key:<fingerprint> or ip:<address>',
+  `changed_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_short_code_revision` (`short_code`, `revision`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Link edit history';
+
+-- +goose Down
+DROP TABLE IF EXISTS `url_mapping_revisions`;
@@ -0,0 +1,15 @@
+-- Duplicate detection: original_url is a VARCHAR(2048) too long to index,
+-- so lookups by URL scanned the table. url_hash (hex SHA-256) is indexed instead.
+
+-- +goose Up
+ALTER TABLE `url_mappings`
+  ADD COLUMN `url_hash` CHAR(64) CHARACTER SET ascii NOT NULL DEFAULT '' COMMENT 'SHA-256 of original_url' AFTER `destination_host`,
+  ADD KEY `idx_url_hash_domain` (`url_hash`, `domain`);
+
+-- Backfill; SHA2 hashes the UTF-8 bytes, like model.HashURL
+UPDATE `url_mappings` SET `url_hash` = SHA2(`original_url`, 256);
+
+-- +goose Down
+ALTER TABLE `url_mappings`
+  DROP KEY `idx_url_hash_domain`,
+  DROP COLUMN `url_hash`;
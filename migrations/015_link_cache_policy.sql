@@ -0,0 +1,12 @@
+-- Per-link cache settings: a shorter TTL for destinations that change often,
+-- or no caching at all
+
+-- +goose Up
+ALTER TABLE `url_mappings`
+  ADD COLUMN `cache_ttl` INT NOT NULL DEFAULT 0 COMMENT 'Cache TTL in seconds; 0 uses the configured TTL' AFTER `status`,
+  ADD COLUMN `no_cache` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '1: always resolve from the database' AFTER `cache_ttl`;
+
+-- +goose Down
+ALTER TABLE `url_mappings`
+  DROP COLUMN `no_cache`,
+  DROP COLUMN `cache_ttl`;
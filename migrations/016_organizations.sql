@@ -0,0 +1,32 @@
+-- Organizations own links on behalf of their members, so a team sharing one
+-- branded domain can manage links together. Links without an organization
+-- (org_id 0) keep working as before.
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `organizations` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `name` VARCHAR(128) NOT NULL COMMENT 'Unique organization name',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Organizations';
+
+CREATE TABLE IF NOT EXISTS `org_members` (
+  `org_id` BIGINT UNSIGNED NOT NULL,
+  `user_id` VARCHAR(128) NOT NULL COMMENT 'ID set by the authentication middleware',
+  `role` VARCHAR(16) NOT NULL COMMENT 'owner, editor or viewer',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`org_id`, `user_id`),
+  KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Organization membership';
+
+ALTER TABLE `url_mappings`
+  ADD COLUMN `org_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT 'Owning organization; 0 for none' AFTER `domain`,
+  ADD KEY `idx_org_created` (`org_id`, `created_at`, `id`);
+
+-- +goose Down
+ALTER TABLE `url_mappings`
+  DROP KEY `idx_org_created`,
+  DROP COLUMN `org_id`;
+DROP TABLE IF EXISTS `org_members`;
+DROP TABLE IF EXISTS `organizations`;
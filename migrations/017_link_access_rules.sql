@@ -0,0 +1,10 @@
+-- Per-link access rules, e.g. the sites allowed to link to a short URL
+-- NULL (the default) allows everyone
+
+-- +goose Up
+ALTER TABLE `url_mappings`
+  ADD COLUMN `access_rules` JSON NULL DEFAULT NULL COMMENT 'Who may follow the link, see model.AccessRules' AFTER `no_cache`;
+
+-- +goose Down
+ALTER TABLE `url_mappings`
+  DROP COLUMN `access_rules`;
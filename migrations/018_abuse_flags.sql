@@ -0,0 +1,18 @@
+-- Links flagged by abuse detection (traffic spikes, one IP hammering a link,
+-- traffic from datacenters), kept for admins to review
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `abuse_flags` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `short_code` VARCHAR(15) NOT NULL,
+  `reason` VARCHAR(32) NOT NULL COMMENT 'Policy that triggered: spike, single_ip or datacenter',
+  `action` VARCHAR(16) NOT NULL COMMENT 'flag, throttle or disable',
+  `detail` VARCHAR(255) NOT NULL DEFAULT '',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_short_code` (`short_code`, `id`),
+  KEY `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Abuse detection flags';
+
+-- +goose Down
+DROP TABLE IF EXISTS `abuse_flags`;
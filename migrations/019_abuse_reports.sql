@@ -0,0 +1,19 @@
+-- Reports from visitors that a link is malicious, for the takedown queue
+-- in the admin API
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `abuse_reports` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `short_code` VARCHAR(15) NOT NULL,
+  `category` VARCHAR(16) NOT NULL COMMENT 'phishing, malware, spam or other',
+  `details` VARCHAR(1000) NOT NULL DEFAULT '',
+  `reporter_ip` VARCHAR(45) NOT NULL,
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  `resolved_at` TIMESTAMP NULL DEFAULT NULL COMMENT 'Set once an admin reviewed the link',
+  PRIMARY KEY (`id`),
+  KEY `idx_short_code_resolved` (`short_code`, `resolved_at`),
+  KEY `idx_resolved_created` (`resolved_at`, `created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Abuse reports';
+
+-- +goose Down
+DROP TABLE IF EXISTS `abuse_reports`;
@@ -0,0 +1,10 @@
+-- Links a moderator or abuse detection marked as possibly unsafe redirect
+-- through a warning page instead of being disabled
+
+-- +goose Up
+ALTER TABLE `url_mappings`
+  ADD COLUMN `warning` TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'Show an unsafe-link warning before redirecting' AFTER `status`;
+
+-- +goose Down
+ALTER TABLE `url_mappings`
+  DROP COLUMN `warning`;
@@ -0,0 +1,14 @@
+-- When a link was last changed, for API responses, cache versioning and
+-- incremental exports. Existing links start out at their creation time.
+
+-- +goose Up
+ALTER TABLE `url_mappings`
+  ADD COLUMN `updated_at` TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) COMMENT 'Last edit, moderation, delete or restore' AFTER `created_at`,
+  ADD KEY `idx_updated_at` (`updated_at`);
+
+UPDATE `url_mappings` SET `updated_at` = COALESCE(`deleted_at`, `created_at`);
+
+-- +goose Down
+ALTER TABLE `url_mappings`
+  DROP KEY `idx_updated_at`,
+  DROP COLUMN `updated_at`;
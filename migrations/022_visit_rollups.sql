@@ -0,0 +1,34 @@
+-- Daily per-link visit rollups, so stats read pre-aggregated rows instead
+-- of scanning visit_logs. Filled by the rollup job (stats_rollup.interval).
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `visit_daily_stats` (
+  `short_code` VARCHAR(15) NOT NULL,
+  `day` DATE NOT NULL COMMENT 'UTC day',
+  `clicks` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT 'Human visits',
+  `bot_clicks` BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  `uniques` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT 'Distinct IPs of human visits',
+  PRIMARY KEY (`short_code`, `day`),
+  KEY `idx_day` (`day`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Daily visit totals per link';
+
+CREATE TABLE IF NOT EXISTS `visit_daily_breakdowns` (
+  `short_code` VARCHAR(15) NOT NULL,
+  `day` DATE NOT NULL COMMENT 'UTC day',
+  `dimension` VARCHAR(16) NOT NULL COMMENT 'country, device_type, browser or referrer',
+  `value` VARCHAR(255) NOT NULL COMMENT 'Referrers are cut to 255 characters',
+  `count` BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  PRIMARY KEY (`short_code`, `dimension`, `day`, `value`),
+  KEY `idx_day` (`day`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Daily human visit breakdowns per link';
+
+CREATE TABLE IF NOT EXISTS `visit_rollup_days` (
+  `day` DATE NOT NULL COMMENT 'UTC day',
+  `rolled_up_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`day`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Days present in the rollup tables';
+
+-- +goose Down
+DROP TABLE IF EXISTS `visit_rollup_days`;
+DROP TABLE IF EXISTS `visit_daily_breakdowns`;
+DROP TABLE IF EXISTS `visit_daily_stats`;
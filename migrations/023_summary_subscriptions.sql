@@ -0,0 +1,20 @@
+-- Users subscribe to daily or weekly summaries of their links' clicks,
+-- sent by email or webhook by the reports scheduler
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `summary_subscriptions` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `user_id` VARCHAR(128) NOT NULL,
+  `org_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT 'Summarize this organization''s links; 0 for links the user created',
+  `frequency` VARCHAR(8) NOT NULL COMMENT 'daily or weekly',
+  `channel` VARCHAR(8) NOT NULL COMMENT 'email or webhook',
+  `target` VARCHAR(2048) NOT NULL COMMENT 'Email address or webhook URL',
+  `last_period_end` TIMESTAMP NULL DEFAULT NULL COMMENT 'End of the last period summarized',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_user_id` (`user_id`),
+  KEY `idx_frequency` (`frequency`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Summary report subscriptions';
+
+-- +goose Down
+DROP TABLE IF EXISTS `summary_subscriptions`;
@@ -0,0 +1,18 @@
+-- Results of the periodic destination checks: whether each link's
+-- destination still answers, and how many checks in a row have failed
+
+-- +goose Up
+ALTER TABLE `url_mappings`
+  ADD COLUMN `health_status` VARCHAR(16) NOT NULL DEFAULT '' COMMENT 'ok, restricted, broken, timeout, tls_error or unreachable; empty until checked',
+  ADD COLUMN `health_code` INT NOT NULL DEFAULT 0 COMMENT 'HTTP status of the last check; 0 without a response',
+  ADD COLUMN `health_checked_at` TIMESTAMP NULL DEFAULT NULL,
+  ADD COLUMN `health_failures` INT NOT NULL DEFAULT 0 COMMENT 'Consecutive failed checks',
+  ADD KEY `idx_health_checked_at` (`health_checked_at`);
+
+-- +goose Down
+ALTER TABLE `url_mappings`
+  DROP KEY `idx_health_checked_at`,
+  DROP COLUMN `health_failures`,
+  DROP COLUMN `health_checked_at`,
+  DROP COLUMN `health_code`,
+  DROP COLUMN `health_status`;
@@ -0,0 +1,11 @@
+-- The destination as submitted, for links whose URL had to be converted to
+-- ASCII (punycode host, percent-encoded path); original_url holds the
+-- canonical form redirects use
+
+-- +goose Up
+ALTER TABLE `url_mappings`
+  ADD COLUMN `display_url` VARCHAR(2048) NOT NULL DEFAULT '' COMMENT 'Destination as submitted when it was not ASCII; empty otherwise' AFTER `original_url`;
+
+-- +goose Down
+ALTER TABLE `url_mappings`
+  DROP COLUMN `display_url`;
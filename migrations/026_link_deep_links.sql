@@ -0,0 +1,10 @@
+-- App links opened instead of the web destination on iOS/Android
+-- NULL (the default) always redirects to original_url
+
+-- +goose Up
+ALTER TABLE `url_mappings`
+  ADD COLUMN `deep_links` JSON NULL DEFAULT NULL COMMENT 'App links per platform, see model.DeepLinks' AFTER `access_rules`;
+
+-- +goose Down
+ALTER TABLE `url_mappings`
+  DROP COLUMN `deep_links`;
@@ -0,0 +1,10 @@
+-- Client-defined JSON object stored with each link (titles, notes,
+-- external campaign IDs); NULL for none
+
+-- +goose Up
+ALTER TABLE `url_mappings`
+  ADD COLUMN `metadata` JSON NULL DEFAULT NULL COMMENT 'Client-defined JSON object, see model.URLMapping.Metadata' AFTER `deep_links`;
+
+-- +goose Down
+ALTER TABLE `url_mappings`
+  DROP COLUMN `metadata`;
@@ -0,0 +1,12 @@
+-- Free pool of short codes reclaimed from long-expired links that never
+-- received traffic; new links take the shortest codes from here first
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `recycled_codes` (
+  `short_code` VARCHAR(15) NOT NULL,
+  `recycled_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`short_code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Recycled short codes';
+
+-- +goose Down
+DROP TABLE IF EXISTS `recycled_codes`;
@@ -0,0 +1,10 @@
+-- Title of the destination page, fetched after a link is created so lists
+-- can show a name instead of the raw URL
+
+-- +goose Up
+ALTER TABLE `url_mappings`
+  ADD COLUMN `title` VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'Destination page <title>; empty until fetched or if it has none' AFTER `display_url`;
+
+-- +goose Down
+ALTER TABLE `url_mappings`
+  DROP COLUMN `title`;
@@ -0,0 +1,12 @@
+-- Per-link visit deduplication: repeat visits from one IP within the
+-- window are counted in duplicate_visit_count instead of visit_count
+
+-- +goose Up
+ALTER TABLE `url_mappings`
+  ADD COLUMN `visit_dedup_minutes` INT NOT NULL DEFAULT 0 COMMENT 'Count one visit per IP per this many minutes; 0 counts every visit' AFTER `bot_visit_count`,
+  ADD COLUMN `duplicate_visit_count` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT 'Human visits left out of visit_count by visit_dedup_minutes' AFTER `visit_dedup_minutes`;
+
+-- +goose Down
+ALTER TABLE `url_mappings`
+  DROP COLUMN `duplicate_visit_count`,
+  DROP COLUMN `visit_dedup_minutes`;
@@ -0,0 +1,10 @@
+-- Per-link opt-out of analytics: visits to these links are counted but
+-- not logged
+
+-- +goose Up
+ALTER TABLE `url_mappings`
+  ADD COLUMN `no_analytics` TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'Count visits without logging visitor details' AFTER `duplicate_visit_count`;
+
+-- +goose Down
+ALTER TABLE `url_mappings`
+  DROP COLUMN `no_analytics`;
@@ -0,0 +1,11 @@
+-- Link visibility: public links may be listed (top links), unlisted links
+-- are never listed and private links only redirect for members of the
+-- owning organization
+
+-- +goose Up
+ALTER TABLE `url_mappings`
+  ADD COLUMN `visibility` VARCHAR(16) NOT NULL DEFAULT 'public' COMMENT 'public, unlisted or private' AFTER `no_analytics`;
+
+-- +goose Down
+ALTER TABLE `url_mappings`
+  DROP COLUMN `visibility`;
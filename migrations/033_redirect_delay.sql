@@ -0,0 +1,10 @@
+-- Per-link redirect delay: visitors see a countdown page for this many
+-- seconds before being redirected
+
+-- +goose Up
+ALTER TABLE `url_mappings`
+  ADD COLUMN `redirect_delay` INT NOT NULL DEFAULT 0 COMMENT 'Seconds of countdown before redirecting; 0 uses the global setting' AFTER `visibility`;
+
+-- +goose Down
+ALTER TABLE `url_mappings`
+  DROP COLUMN `redirect_delay`;
@@ -0,0 +1,10 @@
+-- Signed links: these links only redirect requests carrying a valid,
+-- unexpired HMAC signature
+
+-- +goose Up
+ALTER TABLE `url_mappings`
+  ADD COLUMN `require_signature` TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'Only redirect signed requests' AFTER `redirect_delay`;
+
+-- +goose Down
+ALTER TABLE `url_mappings`
+  DROP COLUMN `require_signature`;
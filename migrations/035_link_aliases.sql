@@ -0,0 +1,19 @@
+-- Per-tenant alias namespaces: each namespace (auth.namespaces) has its own
+-- aliases, served at /{namespace}/{alias}, so tenants can reuse the same
+-- friendly name
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `link_aliases` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `namespace` VARCHAR(64) NOT NULL COMMENT 'Tenant namespace, the first path segment',
+  `alias` VARCHAR(15) NOT NULL COMMENT 'Friendly name, unique within the namespace',
+  `short_code` VARCHAR(15) NOT NULL COMMENT 'Link the alias redirects through',
+  `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_namespace_alias` (`namespace`, `alias`),
+  KEY `idx_short_code` (`short_code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Link aliases per tenant namespace';
+
+-- +goose Down
+DROP TABLE IF EXISTS `link_aliases`;
@@ -0,0 +1,25 @@
+-- Transactional outbox: side effects of new links (cache fill, webhook) are
+-- written in the same transaction as the link and applied by the outbox
+-- dispatcher, with retries
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `outbox_events` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `kind` VARCHAR(32) NOT NULL COMMENT 'Side effect: cache_link or webhook',
+  `short_code` VARCHAR(15) NOT NULL,
+  `payload` JSON NULL COMMENT 'Webhook body',
+  `attempts` INT NOT NULL DEFAULT 0,
+  `next_attempt_at` TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+  `claim_token` VARCHAR(32) NOT NULL DEFAULT '' COMMENT 'Dispatcher run holding the event',
+  `claimed_until` TIMESTAMP(3) NULL,
+  `dispatched_at` TIMESTAMP(3) NULL,
+  `failed_at` TIMESTAMP(3) NULL COMMENT 'Given up after max_attempts',
+  `last_error` VARCHAR(512) NOT NULL DEFAULT '',
+  `created_at` TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+  PRIMARY KEY (`id`),
+  KEY `idx_pending` (`dispatched_at`, `failed_at`, `next_attempt_at`),
+  KEY `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Side effects of link writes, applied by the outbox dispatcher';
+
+-- +goose Down
+DROP TABLE IF EXISTS `outbox_events`;
@@ -0,0 +1,17 @@
+-- Campaigns belong to the user who created them, or to an organization, so
+-- only they can read or change them. Campaigns created before this have
+-- neither and stay inaccessible until an operator sets an owner.
+
+-- +goose Up
+ALTER TABLE `campaigns`
+  ADD COLUMN `owner_id` VARCHAR(128) NOT NULL DEFAULT '' COMMENT 'User who created the campaign' AFTER `description`,
+  ADD COLUMN `org_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT 'Owning organization; 0 for a personal campaign' AFTER `owner_id`,
+  ADD KEY `idx_owner_id` (`owner_id`),
+  ADD KEY `idx_org_id` (`org_id`);
+
+-- +goose Down
+ALTER TABLE `campaigns`
+  DROP KEY `idx_org_id`,
+  DROP KEY `idx_owner_id`,
+  DROP COLUMN `org_id`,
+  DROP COLUMN `owner_id`;
@@ -0,0 +1,14 @@
+// Package migrations embeds the versioned SQL schema migrations so the
+// server binary can apply them without the files on disk.
+//
+// Files follow goose's format: NNN_description.sql with "-- +goose Up" and
+// "-- +goose Down" sections. Never edit a migration that has been released;
+// add a new one instead.
+package migrations
+
+import "embed"
+
+// FS contains every *.sql migration in this directory
+//
+//go:embed *.sql
+var FS embed.FS